@@ -0,0 +1,59 @@
+package bench_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/bench"
+	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/genjidb/genji/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	for _, ds := range bench.Datasets {
+		t.Run(ds.Name, func(t *testing.T) {
+			db := testutil.NewDB(t, nil)
+
+			err := bench.Load(context.Background(), db, ds, 100)
+			require.NoError(t, err)
+
+			testutil.RequireQuery(t, db, `[{"n": 100, "min": 0, "max": 99}]`,
+				"SELECT COUNT(*) AS n, MIN(id) AS min, MAX(id) AS max FROM bench")
+			testutil.RequireQuery(t, db, `[]`, "SELECT id FROM bench WHERE k < 0 OR k >= 100 OR v IS NULL")
+		})
+	}
+
+	// the datasets are reproducible
+	for i := 0; i < 2; i++ {
+		db := testutil.NewDB(t, nil)
+		err := bench.Load(context.Background(), db, bench.Nested, 10)
+		require.NoError(t, err)
+		testutil.RequireGolden(t, db, "testdata/nested.golden", "SELECT * FROM bench")
+	}
+}
+
+func TestWorkloads(t *testing.T) {
+	for _, w := range bench.Workloads {
+		t.Run(w.Name, func(t *testing.T) {
+			db, err := genji.New(memoryengine.NewEngine())
+			require.NoError(t, err)
+			defer db.Close()
+
+			err = bench.Load(context.Background(), db, bench.Uniform, 100)
+			require.NoError(t, err)
+
+			err = w.Run(&testing.B{N: 10}, db, 100)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func BenchmarkMemoryEngine(b *testing.B) {
+	bench.Benchmark(b, func() (engine.Engine, func()) {
+		ng := memoryengine.NewEngine()
+		return ng, func() { ng.Close() }
+	}, 1000)
+}
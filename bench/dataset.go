@@ -0,0 +1,120 @@
+// Package bench loads synthetic datasets and runs canonical workloads against Genji databases.
+// It can be used to measure the impact of a change locally, or by users to compare engines:
+//
+//	func BenchmarkMyEngine(b *testing.B) {
+//		bench.Benchmark(b, func() (engine.Engine, func()) {
+//			ng := myengine.New()
+//			return ng, func() { ng.Close() }
+//		}, 10000)
+//	}
+//
+// Datasets and workloads are generated from a fixed seed,
+// so that successive runs are comparable.
+package bench
+
+import (
+	"context"
+	"math/rand"
+	"strconv"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/document"
+)
+
+// TableName is the name of the table created by Load.
+const TableName = "bench"
+
+// Seed is used to generate the datasets and the arguments of the workloads.
+const Seed = 42
+
+// A Dataset generates the documents of the table used by the workloads.
+// Every document has the following fields:
+//   - id: a unique integer from 0 to the size of the dataset, which is the primary key
+//   - k: an integer between 0 and the size of the dataset, distributed according to the dataset
+//   - v: a text of 32 characters
+type Dataset struct {
+	Name string
+	// Generate returns the i-th document of a dataset of n documents.
+	Generate func(rnd *rand.Rand, i, n int) document.Document
+}
+
+var (
+	// Uniform is a dataset whose k values are uniformly distributed.
+	Uniform = Dataset{
+		Name: "Uniform",
+		Generate: func(rnd *rand.Rand, i, n int) document.Document {
+			return newDocument(rnd, i, rnd.Intn(n))
+		},
+	}
+
+	// Zipfian is a dataset whose k values follow a Zipf distribution:
+	// a few values are very frequent while most of them are rare.
+	Zipfian = Dataset{
+		Name: "Zipfian",
+		Generate: func(rnd *rand.Rand, i, n int) document.Document {
+			// the generator is deterministic as long as the documents are generated in order
+			z := rand.NewZipf(rnd, 1.1, 1, uint64(n-1))
+			return newDocument(rnd, i, int(z.Uint64()))
+		},
+	}
+
+	// Nested is a dataset with uniformly distributed k values, whose documents also contain
+	// a field n holding a document with an array and a nested document:
+	//	{"n": {"a": k % 100, "tags": ["t1", "t2"], "child": {"b": v}}}
+	Nested = Dataset{
+		Name: "Nested",
+		Generate: func(rnd *rand.Rand, i, n int) document.Document {
+			k := rnd.Intn(n)
+			fb := newDocument(rnd, i, k)
+			v, _ := fb.GetByField("v")
+
+			tags := document.NewValueBuffer()
+			for j := rnd.Intn(4); j >= 0; j-- {
+				tags = tags.Append(document.NewTextValue("t" + strconv.Itoa(rnd.Intn(10))))
+			}
+
+			return fb.Add("n", document.NewDocumentValue(document.NewFieldBuffer().
+				Add("a", document.NewIntegerValue(int64(k%100))).
+				Add("tags", document.NewArrayValue(tags)).
+				Add("child", document.NewDocumentValue(document.NewFieldBuffer().Add("b", v)))))
+		},
+	}
+
+	// Datasets lists the datasets used by Benchmark.
+	Datasets = []Dataset{Uniform, Zipfian, Nested}
+)
+
+const letters = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+func newDocument(rnd *rand.Rand, id, k int) *document.FieldBuffer {
+	v := make([]byte, 32)
+	for i := range v {
+		v[i] = letters[rnd.Intn(len(letters))]
+	}
+
+	return document.NewFieldBuffer().
+		Add("id", document.NewIntegerValue(int64(id))).
+		Add("k", document.NewIntegerValue(int64(k))).
+		Add("v", document.NewTextValue(string(v)))
+}
+
+// Load creates the table TableName and inserts n documents of the dataset, in a single transaction.
+func Load(ctx context.Context, db *genji.DB, ds Dataset, n int) error {
+	rnd := rand.New(rand.NewSource(Seed))
+
+	return db.Update(func(tx *genji.Tx) error {
+		err := tx.Exec(ctx, "CREATE TABLE "+TableName+" (id INTEGER PRIMARY KEY)")
+		if err != nil {
+			return err
+		}
+
+		for i := 0; i < n; i++ {
+			err = tx.Exec(ctx, "INSERT INTO "+TableName+" VALUES ?", ds.Generate(rnd, i, n))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
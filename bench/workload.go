@@ -0,0 +1,161 @@
+package bench
+
+import (
+	"context"
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine"
+)
+
+// A Workload is a query run repeatedly against a loaded dataset.
+type Workload struct {
+	Name string
+	// Setup is run once after loading the dataset, before the workload is measured. Optional.
+	Setup string
+	Query string
+	// Args returns the arguments of the i-th run of the query, for a dataset of n documents.
+	// Optional.
+	Args func(rnd *rand.Rand, i, n int) []interface{}
+}
+
+var (
+	// PointLookup selects a document by primary key.
+	PointLookup = Workload{
+		Name:  "PointLookup",
+		Query: "SELECT * FROM " + TableName + " WHERE id = ?",
+		Args: func(rnd *rand.Rand, i, n int) []interface{} {
+			return []interface{}{rnd.Intn(n)}
+		},
+	}
+
+	// IndexLookup selects the documents with a given value of k, using an index.
+	IndexLookup = Workload{
+		Name:  "IndexLookup",
+		Setup: "CREATE INDEX bench_k ON " + TableName + " (k)",
+		Query: "SELECT * FROM " + TableName + " WHERE k = ?",
+		Args: func(rnd *rand.Rand, i, n int) []interface{} {
+			return []interface{}{rnd.Intn(n)}
+		},
+	}
+
+	// RangeScan selects 100 consecutive documents by primary key.
+	RangeScan = Workload{
+		Name:  "RangeScan",
+		Query: "SELECT * FROM " + TableName + " WHERE id >= ? AND id < ?",
+		Args: func(rnd *rand.Rand, i, n int) []interface{} {
+			start := rnd.Intn(n)
+			return []interface{}{start, start + 100}
+		},
+	}
+
+	// FullScan filters all the documents on a field without index.
+	FullScan = Workload{
+		Name:  "FullScan",
+		Query: "SELECT id FROM " + TableName + " WHERE v = 'none'",
+	}
+
+	// Aggregate groups all the documents by k.
+	Aggregate = Workload{
+		Name:  "Aggregate",
+		Query: "SELECT k, COUNT(*) FROM " + TableName + " GROUP BY k",
+	}
+
+	// Insert inserts a document.
+	Insert = Workload{
+		Name:  "Insert",
+		Query: "INSERT INTO " + TableName + " (id, k, v) VALUES (?, ?, ?)",
+		Args: func(rnd *rand.Rand, i, n int) []interface{} {
+			return []interface{}{n + i, rnd.Intn(n), "inserted-" + strconv.Itoa(i)}
+		},
+	}
+
+	// Update changes a field of a document selected by primary key.
+	Update = Workload{
+		Name:  "Update",
+		Query: "UPDATE " + TableName + " SET v = ? WHERE id = ?",
+		Args: func(rnd *rand.Rand, i, n int) []interface{} {
+			return []interface{}{"updated-" + strconv.Itoa(i), rnd.Intn(n)}
+		},
+	}
+
+	// Workloads lists the workloads run by Benchmark.
+	Workloads = []Workload{PointLookup, IndexLookup, RangeScan, FullScan, Aggregate, Insert, Update}
+)
+
+// Builder creates an engine on demand and returns a function cleaning up any created state.
+type Builder func() (engine.Engine, func())
+
+// Benchmark runs every workload against every dataset of size documents,
+// using a new database for each of them, as sub-benchmarks named "dataset/workload".
+func Benchmark(b *testing.B, builder Builder, size int) {
+	for _, ds := range Datasets {
+		for _, w := range Workloads {
+			b.Run(ds.Name+"/"+w.Name, func(b *testing.B) {
+				ng, cleanup := builder()
+				defer cleanup()
+
+				db, err := genji.New(ng)
+				if err != nil {
+					b.Fatal(err)
+				}
+				defer db.Close()
+
+				err = Load(context.Background(), db, ds, size)
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				err = w.Run(b, db, size)
+				if err != nil {
+					b.Fatal(err)
+				}
+			})
+		}
+	}
+}
+
+// Run runs the setup of the workload, then measures b.N runs of its query against db,
+// which contains a dataset of n documents. The results are read entirely.
+func (w Workload) Run(b *testing.B, db *genji.DB, n int) error {
+	ctx := context.Background()
+
+	if w.Setup != "" {
+		err := db.Exec(ctx, w.Setup)
+		if err != nil {
+			return err
+		}
+	}
+
+	rnd := rand.New(rand.NewSource(Seed))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var args []interface{}
+		if w.Args != nil {
+			args = w.Args(rnd, i, n)
+		}
+
+		res, err := db.Query(ctx, w.Query, args...)
+		if err != nil {
+			return err
+		}
+
+		err = res.Iterate(func(d document.Document) error { return nil })
+		if err != nil {
+			res.Close()
+			return err
+		}
+
+		err = res.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
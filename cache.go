@@ -0,0 +1,247 @@
+package genji
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/parser"
+	"github.com/genjidb/genji/sql/planner"
+	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// defaultResultCacheMaxDocuments is the number of documents above which
+// a result is not cached, if Options.ResultCacheMaxDocuments is not set.
+const defaultResultCacheMaxDocuments = 1000
+
+// resultCache keeps the documents returned by the most recently used
+// SELECT statements, along with the versions of the tables they read.
+// An entry is only used if none of its tables were modified since it was stored.
+type resultCache struct {
+	size    int
+	maxDocs int
+
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key      string
+	versions map[string]uint64
+	docs     []document.Document
+}
+
+func newResultCache(size, maxDocs int) *resultCache {
+	if maxDocs == 0 {
+		maxDocs = defaultResultCacheMaxDocuments
+	}
+
+	return &resultCache{
+		size:    size,
+		maxDocs: maxDocs,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get returns the documents stored under key if they are still valid.
+func (c *resultCache) get(db *database.Database, key string) ([]document.Document, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	e := el.Value.(*cacheEntry)
+	for name, v := range e.versions {
+		if db.TableVersion(name) != v {
+			c.lru.Remove(el)
+			delete(c.entries, key)
+			return nil, false
+		}
+	}
+
+	c.lru.MoveToFront(el)
+	return e.docs, true
+}
+
+func (c *resultCache) put(e *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[e.key]; ok {
+		el.Value = e
+		c.lru.MoveToFront(el)
+		return
+	}
+
+	c.entries[e.key] = c.lru.PushFront(e)
+	for c.lru.Len() > c.size {
+		el := c.lru.Back()
+		c.lru.Remove(el)
+		delete(c.entries, el.Value.(*cacheEntry).key)
+	}
+}
+
+// cacheKey returns the key identifying the result of the query,
+// or false if the result can't be cached.
+// Only queries made of a single deterministic SELECT statement reading tables
+// of the database, and without custom operators, are cached.
+// The key is made of the normalized statement and the values of the parameters.
+func cacheKey(pq query.Query, params []expr.Param) (string, []string, bool) {
+	key, tables, ok := statementCacheKey(pq)
+	if !ok {
+		return "", nil, false
+	}
+
+	key, ok = paramsCacheKey(key, params)
+	return key, tables, ok
+}
+
+// statementCacheKey returns the part of the cache key identifying the statement of the query,
+// along with the tables it reads, or false if the result can't be cached.
+// It must be computed before the statement is run, which optimizes it.
+func statementCacheKey(pq query.Query) (string, []string, bool) {
+	if len(pq.Statements) != 1 {
+		return "", nil, false
+	}
+
+	t, ok := pq.Statements[0].(*planner.Tree)
+	if !ok || parser.StatementType(t) != "SELECT" {
+		return "", nil, false
+	}
+
+	for n := t.Root; n != nil; n = n.Left() {
+		if n.Operation() == planner.Custom {
+			return "", nil, false
+		}
+	}
+
+	if !isDeterministic(t) {
+		return "", nil, false
+	}
+
+	tables := t.Tables()
+	for _, name := range tables {
+		// system tables and tables of attached databases are not versioned
+		if strings.HasPrefix(name, "__genji") || strings.Contains(name, ".") {
+			return "", nil, false
+		}
+	}
+
+	return parser.Format(t), tables, true
+}
+
+// isDeterministic reports whether t returns the same documents every time
+// it is run on the same data. Statements reading the clock, like NOW(),
+// or sampling documents at random without a seed are not.
+func isDeterministic(t *planner.Tree) bool {
+	deterministic := true
+	parser.Inspect(t, func(n interface{}) bool {
+		switch x := n.(type) {
+		case expr.NowFunc, *expr.NowFunc:
+			deterministic = false
+		case planner.Node:
+			if !planner.IsRepeatable(x) {
+				deterministic = false
+			}
+		}
+		return deterministic
+	})
+
+	return deterministic
+}
+
+// paramsCacheKey appends the values of the parameters to the key of a statement.
+func paramsCacheKey(key string, params []expr.Param) (string, bool) {
+	var b strings.Builder
+	b.WriteString(key)
+	for _, p := range params {
+		v, err := document.NewValue(p.Value)
+		if err != nil {
+			return "", false
+		}
+
+		data, err := v.MarshalJSON()
+		if err != nil {
+			return "", false
+		}
+
+		fmt.Fprintf(&b, "\x00%s\x00%d\x00%s", p.Name, v.Type, data)
+	}
+
+	return b.String(), true
+}
+
+// query returns the cached result of the query, or runs it and returns
+// a result that stores its documents in the cache once it has been fully read.
+func (c *resultCache) query(ctx context.Context, db *database.Database, key string, tables []string, pq query.Query, params []expr.Param) (*query.Result, error) {
+	// the result of the arithmetic operations depends on the overflow mode
+	key = fmt.Sprintf("%d\x00%s", db.DefaultIntegerOverflowMode(), key)
+
+	if docs, ok := c.get(db, key); ok {
+		return &query.Result{
+			Stream: document.NewStream(document.NewIterator(docs...)),
+		}, nil
+	}
+
+	// versions are read before running the query: if a table is modified
+	// while the query runs, the entry is considered stale.
+	e := cacheEntry{
+		key:      key,
+		versions: make(map[string]uint64, len(tables)),
+	}
+	for _, name := range tables {
+		e.versions[name] = db.TableVersion(name)
+	}
+
+	res, err := pq.Run(ctx, db, params)
+	if err != nil {
+		return nil, err
+	}
+
+	st := res.Stream
+	var stored bool
+	res.Stream = document.NewStream(document.IteratorFunc(func(fn func(d document.Document) error) error {
+		record := !stored
+		if record {
+			e.docs = e.docs[:0]
+		}
+
+		err := st.Iterate(func(d document.Document) error {
+			if record {
+				if len(e.docs) < c.maxDocs {
+					var fb document.FieldBuffer
+					err := fb.Copy(d)
+					if err != nil {
+						return err
+					}
+					e.docs = append(e.docs, &fb)
+				} else {
+					record = false
+				}
+			}
+
+			return fn(d)
+		})
+		if err != nil {
+			return err
+		}
+
+		if record {
+			stored = true
+			c.put(&e)
+		}
+		return nil
+	}))
+
+	return res, nil
+}
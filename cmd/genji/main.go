@@ -86,6 +86,85 @@ $ curl https://api.github.com/repos/genjidb/genji/issues | genji insert --db my.
 				return runInsertCommand(c.Context, engine, dbPath, table, c.Bool("auto"), args)
 			},
 		},
+		{
+			Name:      "serve",
+			Usage:     "Serve a database over HTTP",
+			UsageText: "genji serve [options]",
+			Description: `
+The serve command exposes a database over HTTP, with a JSON API:
+
+GET  /api/tables   lists the tables
+GET  /api/indexes  lists the indexes and their statistics
+POST /api/query    runs a query: {"query": "SELECT * FROM foo WHERE a > ?", "params": [10]}
+GET  /healthz      liveness probe
+GET  /readyz       readiness probe, fails if the database can't begin a read transaction
+
+With the --ui flag, it also serves a web UI for browsing tables, running queries,
+viewing EXPLAIN output and index statistics:
+
+$ genji serve --db my.db --ui
+
+Queries must be sent with the "Content-Type: application/json" header. Requests addressed
+to another host than the one the server listens on, or sent from web pages of other origins,
+are rejected.
+
+HTTPS is served when a certificate and its key are given with --tls-cert and --tls-key.
+They are loaded again when their files are modified, to renew them without restarting the server.
+With --client-ca, clients must also present a certificate signed by one of the given authorities.
+
+Otherwise, there is no authentication: anyone reaching the address can read and modify the database.
+With the --read-only flag, only the statements that don't modify it can be run.
+If no database path is given, an in-memory database is used.`,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:    "engine",
+					Aliases: []string{"e"},
+					Usage:   "name of the engine to use, options are 'bolt' or 'badger'",
+					Value:   "bolt",
+				},
+				&cli.StringFlag{
+					Name:  "db",
+					Usage: "path of the database file",
+				},
+				&cli.StringFlag{
+					Name:  "addr",
+					Usage: "address to listen on",
+					Value: "localhost:8080",
+				},
+				&cli.BoolFlag{
+					Name:  "ui",
+					Usage: "serve the web UI",
+				},
+				&cli.BoolFlag{
+					Name:  "read-only",
+					Usage: "reject the statements modifying the database",
+				},
+				&cli.StringFlag{
+					Name:  "tls-cert",
+					Usage: "path of the PEM encoded certificate used to serve HTTPS, reloaded when modified",
+				},
+				&cli.StringFlag{
+					Name:  "tls-key",
+					Usage: "path of the PEM encoded private key of the certificate, reloaded when modified",
+				},
+				&cli.StringFlag{
+					Name:  "client-ca",
+					Usage: "path of the PEM encoded certificate authorities that must have signed the certificates of the clients",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return runServeCommand(c.Context, serveOptions{
+					engine:   c.String("engine"),
+					dbPath:   c.String("db"),
+					addr:     c.String("addr"),
+					ui:       c.Bool("ui"),
+					readOnly: c.Bool("read-only"),
+					tlsCert:  c.String("tls-cert"),
+					tlsKey:   c.String("tls-key"),
+					clientCA: c.String("client-ca"),
+				})
+			},
+		},
 	}
 
 	// Root command
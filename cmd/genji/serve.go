@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/cmd/genji/server"
+	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/engine/badgerengine"
+	"github.com/genjidb/genji/engine/boltengine"
+	"github.com/genjidb/genji/engine/memoryengine"
+)
+
+// serveOptions holds the flags of the serve command.
+type serveOptions struct {
+	engine   string
+	dbPath   string
+	addr     string
+	ui       bool
+	readOnly bool
+	// TLS is enabled if tlsCert and tlsKey are set
+	tlsCert  string
+	tlsKey   string
+	clientCA string
+}
+
+func runServeCommand(ctx context.Context, opts serveOptions) error {
+	var ng engine.Engine
+	var err error
+
+	switch {
+	case opts.dbPath == "":
+		ng = memoryengine.NewEngine()
+	case opts.engine == "bolt":
+		ng, err = boltengine.NewEngine(opts.dbPath, 0660, nil)
+	case opts.engine == "badger":
+		ng, err = badgerengine.NewEngine(badger.DefaultOptions(opts.dbPath).WithLogger(nil))
+	default:
+		err = fmt.Errorf("unknown engine %q", opts.engine)
+	}
+	if err != nil {
+		return err
+	}
+
+	var tlsConfig *tls.Config
+	if opts.tlsCert != "" || opts.tlsKey != "" || opts.clientCA != "" {
+		tlsConfig, err = newTLSConfig(opts.tlsCert, opts.tlsKey, opts.clientCA)
+		if err != nil {
+			return err
+		}
+	}
+
+	var dbOpts genji.Options
+	if opts.readOnly {
+		dbOpts.AllowedStatements = genji.ReadOnlyStatements
+	}
+
+	db, err := genji.NewWithOptions(ng, &dbOpts)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	l, err := net.Listen("tcp", opts.addr)
+	if err != nil {
+		return err
+	}
+
+	hosts, err := listenerHosts(opts.addr, l.Addr().(*net.TCPAddr))
+	if err != nil {
+		return err
+	}
+
+	srv := http.Server{
+		Handler:   server.NewHandler(db, &server.Options{UI: opts.ui, Hosts: hosts}),
+		TLSConfig: tlsConfig,
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	defer signal.Stop(sig)
+	go func() {
+		select {
+		case <-sig:
+		case <-ctx.Done():
+		}
+		srv.Shutdown(context.Background())
+	}()
+
+	scheme := "http"
+	if tlsConfig != nil {
+		scheme = "https"
+	}
+	if opts.ui {
+		fmt.Fprintf(os.Stderr, "Serving the web UI on %s://%s\n", scheme, l.Addr())
+	} else {
+		fmt.Fprintf(os.Stderr, "Serving the API on %s://%s/api\n", scheme, l.Addr())
+	}
+
+	if tlsConfig != nil {
+		// the certificate is provided by the configuration
+		err = srv.ServeTLS(l, "", "")
+	} else {
+		err = srv.Serve(l)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// listenerHosts returns the values of the Host header designating the address
+// the server listens on: the host given by the user, the IP addresses
+// of the listener, or of all the interfaces if it listens on all of them,
+// and localhost for loopback addresses.
+func listenerHosts(addr string, la *net.TCPAddr) ([]string, error) {
+	port := strconv.Itoa(la.Port)
+	var hosts []string
+	seen := make(map[string]bool)
+	add := func(host string) {
+		h := net.JoinHostPort(host, port)
+		if !seen[h] {
+			seen[h] = true
+			hosts = append(hosts, h)
+		}
+	}
+
+	if host, _, err := net.SplitHostPort(addr); err == nil && host != "" {
+		add(host)
+	}
+
+	ips := []net.IP{la.IP}
+	if la.IP.IsUnspecified() {
+		addrs, err := net.InterfaceAddrs()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, a := range addrs {
+			if ipn, ok := a.(*net.IPNet); ok {
+				ips = append(ips, ipn.IP)
+			}
+		}
+	}
+
+	for _, ip := range ips {
+		if ip.IsUnspecified() {
+			continue
+		}
+
+		add(ip.String())
+		if ip.IsLoopback() {
+			add("localhost")
+		}
+	}
+
+	return hosts, nil
+}
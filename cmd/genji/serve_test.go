@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenerHosts(t *testing.T) {
+	tests := []struct {
+		name     string
+		addr     string
+		la       *net.TCPAddr
+		expected []string
+	}{
+		{"Localhost", "localhost:8080", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8080}, []string{"localhost:8080", "127.0.0.1:8080"}},
+		{"IPv6", "[::1]:0", &net.TCPAddr{IP: net.IPv6loopback, Port: 1234}, []string{"[::1]:1234", "localhost:1234"}},
+		{"IP", "10.0.0.1:80", &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 80}, []string{"10.0.0.1:80"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hosts, err := listenerHosts(test.addr, test.la)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, hosts)
+		})
+	}
+
+	t.Run("All interfaces", func(t *testing.T) {
+		hosts, err := listenerHosts(":8080", &net.TCPAddr{IP: net.IPv4zero, Port: 8080})
+		require.NoError(t, err)
+		require.Contains(t, hosts, "127.0.0.1:8080")
+		require.Contains(t, hosts, "localhost:8080")
+		require.NotContains(t, hosts, "0.0.0.0:8080")
+	})
+}
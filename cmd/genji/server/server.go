@@ -0,0 +1,305 @@
+// Package server exposes a Genji database over HTTP, with a JSON API
+// and an optional web UI for browsing tables and running queries.
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/parser"
+	"github.com/genjidb/genji/sql/query"
+)
+
+// Options configures the handler returned by NewHandler.
+type Options struct {
+	// UI serves the web UI on / if true.
+	UI bool
+	// Hosts lists the values of the Host header accepted by the handler, like "localhost:8080".
+	// Requests addressed to other hosts are rejected, which prevents web pages from
+	// reaching the handler through DNS rebinding. If empty, any host is accepted.
+	Hosts []string
+	// ReadyTimeout bounds the duration of the readiness check of /readyz,
+	// after which the database is reported as not ready. Defaults to 5 seconds.
+	ReadyTimeout time.Duration
+}
+
+const defaultReadyTimeout = 5 * time.Second
+
+// NewHandler returns a handler serving the following endpoints:
+//
+//	GET  /api/tables   names of the tables of the database, as a JSON array
+//	GET  /api/indexes  configuration and statistics of the indexes, as a JSON array
+//	POST /api/query    runs the query of the JSON request body {"query": "...", "params": [...]}
+//	                   and returns its documents as a JSON array. Each query runs in its own
+//	                   transaction: BEGIN, COMMIT, ROLLBACK and savepoints are rejected.
+//	GET  /healthz      liveness probe, always returns 200
+//	GET  /readyz       readiness probe, returns 200 if the database can begin a read transaction
+//	                   and 503 otherwise. See genji.DB.Ping.
+//
+// If opts.UI is true, the web UI is also served on /.
+// Errors are returned as {"error": "..."}.
+// Except for the probes, requests sent by browsers from pages of another origin are rejected,
+// and queries must be sent with the application/json content type,
+// which browsers don't allow in cross-origin requests without asking the handler first.
+// The handler doesn't implement any authentication: anyone reaching it
+// can read and modify the whole database, unless the statements of db are restricted
+// with genji.Options.AllowedStatements.
+func NewHandler(db *genji.DB, opts *Options) http.Handler {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	s := server{db: db, readyTimeout: opts.ReadyTimeout}
+	if s.readyTimeout == 0 {
+		s.readyTimeout = defaultReadyTimeout
+	}
+	if len(opts.Hosts) > 0 {
+		s.hosts = make(map[string]bool, len(opts.Hosts))
+		for _, h := range opts.Hosts {
+			s.hosts[h] = true
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/tables", s.handleTables)
+	mux.HandleFunc("/api/indexes", s.handleIndexes)
+	mux.HandleFunc("/api/query", s.handleQuery)
+	if opts.UI {
+		mux.HandleFunc("/", handleUI)
+	}
+
+	// probes don't disclose anything and are usually sent
+	// by orchestrators to the IP address of the server
+	root := http.NewServeMux()
+	root.HandleFunc("/healthz", s.handleHealthz)
+	root.HandleFunc("/readyz", s.handleReadyz)
+	root.Handle("/", s.checkOrigin(mux))
+
+	return root
+}
+
+type server struct {
+	db *genji.DB
+	// accepted values of the Host header, or nil to accept any
+	hosts        map[string]bool
+	readyTimeout time.Duration
+}
+
+// checkOrigin rejects the requests addressed to an unknown host,
+// or sent from a page whose origin isn't the one of the handler.
+func (s *server) checkOrigin(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.hosts != nil && !s.hosts[r.Host] {
+			writeError(w, http.StatusForbidden, "unknown host "+r.Host)
+			return
+		}
+
+		if origin := r.Header.Get("Origin"); origin != "" {
+			u, err := url.Parse(origin)
+			if err != nil || u.Host != r.Host {
+				writeError(w, http.StatusForbidden, "cross-origin requests are not allowed")
+				return
+			}
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+func (s *server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	writeJSON(w, map[string]string{"status": "ok"})
+}
+
+func (s *server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.readyTimeout)
+	defer cancel()
+
+	err := s.db.Ping(ctx)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+
+	writeJSON(w, map[string]string{"status": "ok"})
+}
+
+func (s *server) handleTables(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var tables []string
+	err := s.db.View(func(tx *genji.Tx) error {
+		for _, name := range tx.ListTables() {
+			if !database.IsSystemTable(name) {
+				tables = append(tables, name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if tables == nil {
+		tables = []string{}
+	}
+	writeJSON(w, tables)
+}
+
+func (s *server) handleIndexes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	indexes := []json.RawMessage{}
+	err := s.db.View(func(tx *genji.Tx) error {
+		list, err := tx.ListIndexes()
+		if err != nil {
+			return err
+		}
+
+		for _, idx := range list {
+			data, err := document.MarshalJSON(idx.ToDocument())
+			if err != nil {
+				return err
+			}
+			indexes = append(indexes, data)
+		}
+		return nil
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, indexes)
+}
+
+// queryRequest is the body of a request to /api/query.
+type queryRequest struct {
+	Query  string            `json:"query"`
+	Params []json.RawMessage `json:"params"`
+}
+
+func (s *server) handleQuery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mt != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, "content type must be application/json")
+		return
+	}
+
+	var req queryRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+	if req.Query == "" {
+		writeError(w, http.StatusBadRequest, "missing query")
+		return
+	}
+
+	params := make([]interface{}, len(req.Params))
+	for i, p := range req.Params {
+		// parse the parameters like document fields, to distinguish integers from doubles
+		d, err := document.NewFromJSON(append(append([]byte(`{"v":`), p...), '}'))
+		var v document.Value
+		if err == nil {
+			v, err = d.GetByField("v")
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid parameter: "+err.Error())
+			return
+		}
+		params[i] = v.V
+	}
+
+	s.query(w, r, req.Query, params)
+}
+
+// transactionStatements lists the types of the statements controlling the transaction
+// attached to the database. Requests don't belong to any session: a transaction started
+// by a client would be used by the queries of all the others.
+var transactionStatements = map[string]bool{
+	"BEGIN": true, "COMMIT": true, "ROLLBACK": true,
+	"SAVEPOINT": true, "ROLLBACK TO SAVEPOINT": true, "RELEASE SAVEPOINT": true,
+}
+
+// query runs q and writes its documents. The whole result is read
+// before writing the response, to report errors with the right status.
+// Each query runs in its own transaction, statements controlling transactions are rejected.
+func (s *server) query(w http.ResponseWriter, r *http.Request, q string, params []interface{}) {
+	pq, err := s.db.ParseQuery(r.Context(), q)
+	if err == nil {
+		for _, stmt := range pq.Statements {
+			if typ := parser.StatementType(stmt); transactionStatements[typ] {
+				err = fmt.Errorf("%w: %s is not supported over HTTP", database.ErrStatementNotAllowed, typ)
+				break
+			}
+		}
+	}
+
+	var res *query.Result
+	if err == nil {
+		res, err = s.db.Query(r.Context(), q, params...)
+	}
+	if errors.Is(err, database.ErrStatementNotAllowed) {
+		writeError(w, http.StatusForbidden, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer res.Close()
+
+	var buf bytes.Buffer
+	err = res.WriteJSON(&buf)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(buf.Bytes())
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
@@ -0,0 +1,265 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/genjidb/genji"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec(context.Background(), `
+		CREATE TABLE foo;
+		CREATE INDEX idx_foo_a ON foo(a);
+		INSERT INTO foo (a, b) VALUES (1, 'x'), (2, 'y');
+		ANALYZE;
+	`)
+	require.NoError(t, err)
+
+	srv := httptest.NewUnstartedServer(nil)
+	srv.Config.Handler = NewHandler(db, &Options{
+		UI:           true,
+		Hosts:        []string{srv.Listener.Addr().String()},
+		ReadyTimeout: 50 * time.Millisecond,
+	})
+	srv.Start()
+	defer srv.Close()
+
+	// send sends the request and returns the status and the body of the response
+	send := func(t *testing.T, req *http.Request) (int, string) {
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		data, err := ioutil.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res.StatusCode, string(data)
+	}
+
+	newRequest := func(t *testing.T, method, path, body string) *http.Request {
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		return req
+	}
+
+	do := func(t *testing.T, method, path, body string) (int, string) {
+		return send(t, newRequest(t, method, path, body))
+	}
+
+	tests := []struct {
+		name           string
+		method, path   string
+		body           string
+		expectedStatus int
+		expected       string
+	}{
+		{"Tables", "GET", "/api/tables", "", 200, `["foo"]` + "\n"},
+		{"Indexes", "GET", "/api/indexes", "", 200, `[{"unique":false,"index_name":"idx_foo_a","table_name":"foo","path":["a"],"stats":{"count":2,"distinct":2}}]` + "\n"},
+		{"Query", "POST", "/api/query", `{"query": "SELECT b FROM foo WHERE a > ?", "params": [1]}`, 200, `[{"b": "y"}]`},
+		{"Query/Double param", "POST", "/api/query", `{"query": "SELECT b FROM foo WHERE a < ?", "params": [1.5]}`, 200, `[{"b": "x"}]`},
+		{"Query/Write", "POST", "/api/query", `{"query": "INSERT INTO foo (a) VALUES (3)"}`, 200, `[]`},
+		{"Query/Invalid", "POST", "/api/query", `{"query": "SELEC"}`, 400, ``},
+		{"Query/Missing", "POST", "/api/query", `{}`, 400, `{"error":"missing query"}` + "\n"},
+		{"Query/Method", "GET", "/api/query", ``, 405, `{"error":"method not allowed"}` + "\n"},
+		{"Not found", "GET", "/foo", ``, 404, ``},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status, body := do(t, test.method, test.path, test.body)
+			require.Equal(t, test.expectedStatus, status, body)
+			if test.expected != "" {
+				require.Equal(t, test.expected, body)
+			}
+		})
+	}
+
+	t.Run("Explain", func(t *testing.T) {
+		status, body := do(t, "POST", "/api/query", `{"query": "EXPLAIN SELECT * FROM foo WHERE a = 1"}`)
+		require.Equal(t, 200, status)
+		require.Contains(t, body, "idx_foo_a")
+		require.Contains(t, body, "fingerprint")
+	})
+
+	t.Run("UI", func(t *testing.T) {
+		status, body := do(t, "GET", "/", "")
+		require.Equal(t, 200, status)
+		require.Contains(t, body, "<title>Genji</title>")
+
+		// the UI is only served if requested
+		srv := httptest.NewServer(NewHandler(db, nil))
+		defer srv.Close()
+		res, err := http.Get(srv.URL)
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, 404, res.StatusCode)
+	})
+
+	t.Run("Probes", func(t *testing.T) {
+		status, body := do(t, "GET", "/healthz", "")
+		require.Equal(t, 200, status)
+		require.Equal(t, `{"status":"ok"}`+"\n", body)
+
+		status, body = do(t, "GET", "/readyz", "")
+		require.Equal(t, 200, status)
+		require.Equal(t, `{"status":"ok"}`+"\n", body)
+
+		status, _ = do(t, "POST", "/readyz", "")
+		require.Equal(t, 405, status)
+
+		// probes are accepted whatever the host
+		req := newRequest(t, "GET", "/readyz", "")
+		req.Host = "10.0.0.1:8080"
+		status, _ = send(t, req)
+		require.Equal(t, 200, status)
+
+		// the memory engine doesn't let transactions begin while a read-write one is running
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		status, body = do(t, "GET", "/readyz", "")
+		require.NoError(t, tx.Rollback())
+		require.Equal(t, 503, status)
+		require.Equal(t, `{"error":"context deadline exceeded"}`+"\n", body)
+
+		status, _ = do(t, "GET", "/healthz", "")
+		require.Equal(t, 200, status)
+
+		// a closed database is not ready
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		srv := httptest.NewServer(NewHandler(db, nil))
+		defer srv.Close()
+		require.NoError(t, db.Close())
+		res, err := http.Get(srv.URL + "/readyz")
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, 503, res.StatusCode)
+	})
+
+	t.Run("Content type", func(t *testing.T) {
+		req := newRequest(t, "POST", "/api/query", `{"query": "SELECT * FROM foo"}`)
+		req.Header.Set("Content-Type", "text/plain")
+		status, _ := send(t, req)
+		require.Equal(t, 415, status)
+
+		req.Header.Del("Content-Type")
+		status, _ = send(t, req)
+		require.Equal(t, 415, status)
+
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		status, _ = send(t, req)
+		require.Equal(t, 200, status)
+	})
+
+	t.Run("Hosts", func(t *testing.T) {
+		req := newRequest(t, "GET", "/api/tables", "")
+		req.Host = "evil.com"
+		status, body := send(t, req)
+		require.Equal(t, 403, status)
+		require.Equal(t, `{"error":"unknown host evil.com"}`+"\n", body)
+
+		// any host is accepted if none is configured
+		srv := httptest.NewServer(NewHandler(db, nil))
+		defer srv.Close()
+		req, err := http.NewRequest("GET", srv.URL+"/api/tables", nil)
+		require.NoError(t, err)
+		req.Host = "evil.com"
+		status, _ = send(t, req)
+		require.Equal(t, 200, status)
+	})
+
+	t.Run("Origin", func(t *testing.T) {
+		tests := []struct {
+			origin         string
+			expectedStatus int
+		}{
+			{srv.URL, 200},
+			{"http://evil.com", 403},
+			{"http://" + srv.Listener.Addr().String() + ".evil.com", 403},
+			{"null", 403},
+		}
+
+		for _, test := range tests {
+			req := newRequest(t, "POST", "/api/query", `{"query": "SELECT * FROM foo"}`)
+			req.Header.Set("Origin", test.origin)
+			status, _ := send(t, req)
+			require.Equal(t, test.expectedStatus, status, test.origin)
+		}
+	})
+
+	t.Run("Transactions", func(t *testing.T) {
+		// each client gets its own connection, requests must not share any transaction
+		clientA := &http.Client{Transport: &http.Transport{}}
+		clientB := &http.Client{Transport: &http.Transport{}}
+
+		query := func(t *testing.T, c *http.Client, q string) (int, string) {
+			req := newRequest(t, "POST", "/api/query", `{"query": "`+q+`"}`)
+			res, err := c.Do(req)
+			require.NoError(t, err)
+			defer res.Body.Close()
+			data, err := ioutil.ReadAll(res.Body)
+			require.NoError(t, err)
+			return res.StatusCode, string(data)
+		}
+
+		for _, q := range []string{
+			"BEGIN",
+			"BEGIN READ ONLY",
+			"COMMIT",
+			"ROLLBACK",
+			"SAVEPOINT sp",
+			"ROLLBACK TO SAVEPOINT sp",
+			"RELEASE SAVEPOINT sp",
+			"INSERT INTO foo (a) VALUES (10); BEGIN",
+		} {
+			status, body := query(t, clientA, q)
+			require.Equal(t, 403, status, q)
+			require.Contains(t, body, "not supported over HTTP", q)
+			require.Nil(t, db.DB.GetAttachedTx(), q)
+		}
+
+		// the statements of a rejected query are not run
+		status, body := query(t, clientB, "SELECT COUNT(*) FROM foo WHERE a = 10")
+		require.Equal(t, 200, status)
+		require.Equal(t, `[{"COUNT(*)": 0}]`, body)
+
+		// the writes of a client are committed and seen by the other
+		status, _ = query(t, clientA, "INSERT INTO foo (a) VALUES (11)")
+		require.Equal(t, 200, status)
+		status, body = query(t, clientB, "SELECT COUNT(*) FROM foo WHERE a = 11")
+		require.Equal(t, 200, status)
+		require.Equal(t, `[{"COUNT(*)": 1}]`, body)
+	})
+
+	t.Run("Read-only", func(t *testing.T) {
+		db, err := genji.OpenWithOptions(":memory:", &genji.Options{AllowedStatements: genji.ReadOnlyStatements})
+		require.NoError(t, err)
+		defer db.Close()
+
+		srv := httptest.NewServer(NewHandler(db, nil))
+		defer srv.Close()
+
+		query := func(q string) int {
+			req, err := http.NewRequest("POST", srv.URL+"/api/query", strings.NewReader(`{"query": "`+q+`"}`))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			status, _ := send(t, req)
+			return status
+		}
+
+		require.Equal(t, 403, query("CREATE TABLE foo"))
+		require.Equal(t, 200, query("SELECT 1"))
+	})
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"io"
+	"net/http"
+)
+
+func handleUI(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = io.WriteString(w, uiPage)
+}
+
+// uiPage is a single page application using the JSON API.
+// Results are displayed as a table whose columns are the fields of the documents,
+// nested documents and arrays being displayed as JSON.
+const uiPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Genji</title>
+<style>
+body { margin: 0; font-family: sans-serif; font-size: 14px; display: flex; height: 100vh; }
+nav { width: 220px; padding: 12px; background: #f4f4f4; overflow: auto; }
+nav h2 { font-size: 13px; text-transform: uppercase; color: #666; }
+nav a { display: block; padding: 2px 0; color: #0366d6; cursor: pointer; text-decoration: none; }
+main { flex: 1; padding: 12px; overflow: auto; }
+textarea { width: 100%; height: 100px; font-family: monospace; font-size: 13px; box-sizing: border-box; }
+table { border-collapse: collapse; margin-top: 12px; }
+th, td { border: 1px solid #ddd; padding: 4px 8px; text-align: left; vertical-align: top; font-family: monospace; }
+th { background: #f4f4f4; }
+pre { background: #f4f4f4; padding: 8px; }
+.error { color: #c00; margin-top: 12px; white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<nav>
+<h2>Tables</h2>
+<div id="tables"></div>
+<h2>Indexes</h2>
+<a id="indexes">Index statistics</a>
+</nav>
+<main>
+<textarea id="query" placeholder="SELECT * FROM ..."></textarea>
+<div>
+<button id="run">Run</button>
+<button id="explain">Explain</button>
+</div>
+<div id="result"></div>
+</main>
+<script>
+var result = document.getElementById("result");
+var query = document.getElementById("query");
+
+function text(tag, s) {
+	var el = document.createElement(tag);
+	el.textContent = s;
+	return el;
+}
+
+function display(docs) {
+	result.innerHTML = "";
+	if (docs.length === 1 && typeof docs[0].plan === "string") {
+		result.appendChild(text("pre", docs[0].plan));
+		result.appendChild(text("div", "fingerprint: " + docs[0].fingerprint));
+		return;
+	}
+
+	var fields = [];
+	docs.forEach(function(d) {
+		Object.keys(d).forEach(function(f) {
+			if (fields.indexOf(f) < 0) fields.push(f);
+		});
+	});
+
+	var table = document.createElement("table");
+	var tr = document.createElement("tr");
+	fields.forEach(function(f) { tr.appendChild(text("th", f)); });
+	table.appendChild(tr);
+	docs.forEach(function(d) {
+		var tr = document.createElement("tr");
+		fields.forEach(function(f) {
+			var v = d[f];
+			tr.appendChild(text("td", v === undefined ? "" : typeof v === "object" && v !== null ? JSON.stringify(v) : String(v)));
+		});
+		table.appendChild(tr);
+	});
+	result.appendChild(table);
+	result.appendChild(text("div", docs.length + " document(s)"));
+}
+
+function handle(res) {
+	return res.json().then(function(body) {
+		if (!res.ok) throw new Error(body.error);
+		return body;
+	});
+}
+
+function fail(err) {
+	result.innerHTML = "";
+	result.appendChild(text("div", err.message)).className = "error";
+}
+
+function run(q) {
+	query.value = q;
+	fetch("api/query", {
+		method: "POST",
+		headers: {"Content-Type": "application/json"},
+		body: JSON.stringify({query: q})
+	})
+		.then(handle).then(display).catch(fail);
+}
+
+function loadTables() {
+	fetch("api/tables").then(handle).then(function(tables) {
+		var el = document.getElementById("tables");
+		el.innerHTML = "";
+		tables.forEach(function(name) {
+			var a = text("a", name);
+			a.onclick = function() { run("SELECT * FROM " + name + " LIMIT 100"); };
+			el.appendChild(a);
+		});
+	}).catch(fail);
+}
+
+document.getElementById("run").onclick = function() {
+	run(query.value);
+	loadTables();
+};
+document.getElementById("explain").onclick = function() {
+	var q = query.value;
+	run(/^\s*explain\s/i.test(q) ? q : "EXPLAIN " + q);
+};
+document.getElementById("indexes").onclick = function() {
+	fetch("api/indexes").then(handle).then(display).catch(fail);
+};
+loadTables();
+</script>
+</body>
+</html>
+`
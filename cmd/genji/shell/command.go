@@ -184,6 +184,18 @@ func dumpTable(tx *genji.Tx, tableName string, w io.Writer) error {
 		if fc.IsNotNull {
 			buf.WriteString(" NOT NULL")
 		}
+
+		if fc.IsUnique {
+			buf.WriteString(" UNIQUE")
+		}
+
+		if fc.DefaultValue != nil {
+			buf.WriteString(" DEFAULT " + fc.DefaultValue.String())
+		}
+
+		if fc.Check != nil {
+			buf.WriteString(" CHECK (" + fc.Check.String() + ")")
+		}
 	}
 
 	// Fields constraints close parenthesis.
@@ -206,6 +218,11 @@ func dumpTable(tx *genji.Tx, tableName string, w io.Writer) error {
 	}
 
 	for _, index := range indexes {
+		// indexes of unique fields are created with their table
+		if index.Opts.FieldConstraint {
+			continue
+		}
+
 		u := ""
 		if index.Opts.Unique {
 			u = " UNIQUE"
@@ -0,0 +1,88 @@
+package shell
+
+import (
+	"context"
+	"strings"
+
+	"github.com/c-bata/go-prompt"
+	"github.com/genjidb/genji/sql/parser"
+	"github.com/genjidb/genji/sql/query/expr"
+	"github.com/genjidb/genji/sql/scanner"
+)
+
+// completion suggests keywords, functions, tables and indexes
+// depending on what the parser expects at the cursor position.
+type completion struct {
+	// tables and indexes return the names of the tables and indexes of the database.
+	tables  func() []string
+	indexes func() []string
+}
+
+// suggest returns the suggestions for the word being typed at the end of text.
+func (c *completion) suggest(text string) []prompt.Suggest {
+	word := text
+	if i := strings.LastIndexAny(text, " \t\n(,"); i >= 0 {
+		word = text[i+1:]
+	}
+
+	// parse what was typed before the current word to determine
+	// what can be written next.
+	_, err := parser.ParseQuery(context.Background(), text[:len(text)-len(word)])
+	if err == nil {
+		// the statement is complete, there can be another clause
+		// but there is no way to know which one. Avoid suggesting
+		// every keyword until something is typed.
+		if word == "" {
+			return nil
+		}
+		return prompt.FilterHasPrefix(c.keywords(), word, true)
+	}
+
+	perr, ok := err.(*parser.ParseError)
+	if !ok || perr.Found != scanner.EOF.String() {
+		// the error is located before the cursor
+		return nil
+	}
+
+	var suggestions []prompt.Suggest
+	for _, e := range perr.Expected {
+		switch e {
+		case "table_name":
+			suggestions = append(suggestions, toSuggestions(c.tables(), "table")...)
+		case "index_name":
+			suggestions = append(suggestions, toSuggestions(c.indexes(), "index")...)
+		case "identifier", "path":
+			// names of fields are not known.
+		case "string", "number":
+		case "bool":
+			suggestions = append(suggestions, toSuggestions([]string{"true", "false", "NULL"}, "literal")...)
+			suggestions = append(suggestions, c.functions()...)
+			suggestions = append(suggestions, prompt.Suggest{Text: scanner.CAST.String(), Description: "keyword"})
+		default:
+			suggestions = append(suggestions, prompt.Suggest{Text: e, Description: "keyword"})
+		}
+	}
+
+	if word == "" {
+		return suggestions
+	}
+
+	return prompt.FilterHasPrefix(suggestions, word, true)
+}
+
+func (c *completion) keywords() []prompt.Suggest {
+	return toSuggestions(scanner.Keywords(), "keyword")
+}
+
+func (c *completion) functions() []prompt.Suggest {
+	return toSuggestions(expr.Functions(), "function")
+}
+
+func toSuggestions(list []string, description string) []prompt.Suggest {
+	suggestions := make([]prompt.Suggest, len(list))
+	for i, s := range list {
+		suggestions[i] = prompt.Suggest{Text: s, Description: description}
+	}
+
+	return suggestions
+}
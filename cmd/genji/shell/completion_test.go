@@ -0,0 +1,42 @@
+package shell
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompletion(t *testing.T) {
+	c := completion{
+		tables:  func() []string { return []string{"foo", "bar"} },
+		indexes: func() []string { return []string{"idx_foo"} },
+	}
+
+	tests := []struct {
+		text     string
+		expected []string
+	}{
+		{"", nil},
+		{"sel", []string{"SELECT"}},
+		{"SELECT * FROM ", []string{"foo", "bar"}},
+		{"SELECT * FROM b", []string{"bar"}},
+		{"SELECT * FROM foo ", nil},
+		{"SELECT * FROM foo wh", []string{"WHERE"}},
+		{"SELECT * FROM foo WHERE a > co", []string{"count"}},
+		{"SELECT * FROM foo WHERE a > tr", []string{"true"}},
+		{"INSERT ", []string{"INTO"}},
+		{"SELECT * FROM foo GROUP ", []string{"BY"}},
+		{"SELECT * FROM foo ORDER ", []string{"BY"}},
+		{"DELETE FROM foo WHERE ) ", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			var texts []string
+			for _, s := range c.suggest(test.text) {
+				texts = append(texts, s.Text)
+			}
+			require.Equal(t, test.expected, texts)
+		})
+	}
+}
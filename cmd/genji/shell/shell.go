@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -238,6 +239,12 @@ func (sh *Shell) execute(in string) {
 	err := sh.executeInput(in)
 	if err != nil {
 		fmt.Println(err)
+
+		// show where syntax errors occurred
+		var perr *parser.ParseError
+		if errors.As(err, &perr) && perr.Snippet() != "" {
+			fmt.Println(perr.Snippet())
+		}
 	}
 }
 
@@ -401,59 +408,44 @@ func (sh *Shell) changelivePrefix() (string, bool) {
 	return sh.livePrefix, sh.multiLine
 }
 
-func (sh *Shell) getAllIndexes() ([]string, error) {
+// getAllIndexes returns the names of all the indexes of the database.
+func (sh *Shell) getAllIndexes() []string {
 	db, err := sh.getDB()
 	if err != nil {
-		return nil, err
+		return nil
 	}
 
-	var listName []string
-	err = db.View(func(tx *genji.Tx) error {
+	var names []string
+	_ = db.View(func(tx *genji.Tx) error {
 		indexes, err := tx.ListIndexes()
 		if err != nil {
 			return err
 		}
 
 		for _, idx := range indexes {
-			listName = append(listName, idx.IndexName)
+			names = append(names, idx.IndexName)
 		}
 
 		return nil
 	})
 
-	if len(listName) == 0 {
-		listName = append(listName, "index_name")
-	}
-
-	return listName, err
+	return names
 }
 
-// getTables returns all the tables of the database
-func (sh *Shell) getAllTables() ([]string, error) {
-	var tables []string
-	db, _ := sh.getDB()
-	res, err := db.Query(context.Background(), "SELECT table_name FROM __genji_tables")
+// getAllTables returns the names of all the tables of the database.
+func (sh *Shell) getAllTables() []string {
+	db, err := sh.getDB()
 	if err != nil {
-		return nil, err
+		return nil
 	}
-	defer res.Close()
 
-	err = res.Iterate(func(d document.Document) error {
-		var tableName string
-		err = document.Scan(d, &tableName)
-		if err != nil {
-			return err
-		}
-		tables = append(tables, tableName)
+	var names []string
+	_ = db.View(func(tx *genji.Tx) error {
+		names = tx.ListTables()
 		return nil
 	})
 
-	// if there is no table return table as a suggestion
-	if len(tables) == 0 {
-		tables = append(tables, "table_name")
-	}
-
-	return tables, nil
+	return names
 }
 
 func (sh *Shell) completer(in prompt.Document) []prompt.Suggest {
@@ -461,38 +453,10 @@ func (sh *Shell) completer(in prompt.Document) []prompt.Suggest {
 		return prompt.FilterHasPrefix(sh.cmdSuggestions, in.Text, true)
 	}
 
-	_, err := parser.NewParser(strings.NewReader(in.Text)).ParseQuery(context.Background())
-	if err != nil {
-		e, ok := err.(*parser.ParseError)
-		if !ok {
-			return []prompt.Suggest{}
-		}
-		expected := e.Expected
-		switch expected[0] {
-		case "table_name":
-			expected, err = sh.getAllTables()
-			if err != nil {
-				return []prompt.Suggest{}
-			}
-		case "index_name":
-			expected, err = sh.getAllIndexes()
-			if err != nil {
-				return []prompt.Suggest{}
-			}
-		}
-
-		suggestions := make([]prompt.Suggest, len(expected))
-		for i, e := range expected {
-			suggestions[i].Text = e
-		}
-
-		w := in.GetWordBeforeCursor()
-		if w == "" {
-			return suggestions
-		}
-
-		return prompt.FilterHasPrefix(suggestions, w, true)
+	c := completion{
+		tables:  sh.getAllTables,
+		indexes: sh.getAllIndexes,
 	}
 
-	return []prompt.Suggest{}
+	return c.suggest(in.TextBeforeCursor())
 }
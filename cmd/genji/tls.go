@@ -0,0 +1,99 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// newTLSConfig returns the TLS configuration of a server using the certificate
+// and the key stored in the given files, which are loaded again when they are modified.
+// If clientCAFile is not empty, clients must present a certificate signed
+// by one of the certificate authorities it contains.
+func newTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("both a certificate and a key are required")
+	}
+
+	r := certificateReloader{certFile: certFile, keyFile: keyFile}
+	err := r.reload()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.GetCertificate,
+	}
+
+	if clientCAFile != "" {
+		data, err := ioutil.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificate found in %s", clientCAFile)
+		}
+
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return &cfg, nil
+}
+
+// certificateReloader loads a certificate and its key from files and loads them again
+// whenever one of them is modified, which lets certificates be renewed without
+// restarting the server.
+type certificateReloader struct {
+	certFile, keyFile string
+
+	mu              sync.Mutex
+	cert            *tls.Certificate
+	certMod, keyMod time.Time
+}
+
+// GetCertificate returns the certificate, after reloading it if the files were modified.
+// If they can't be loaded, while being replaced for instance, the previous certificate
+// is returned and loading them is attempted again during the next handshake.
+// It implements the GetCertificate function of tls.Config.
+func (r *certificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_ = r.reload()
+	return r.cert, nil
+}
+
+// reload loads the certificate if it was never loaded or if its files were modified.
+func (r *certificateReloader) reload() error {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return err
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	if r.cert != nil && certInfo.ModTime().Equal(r.certMod) && keyInfo.ModTime().Equal(r.keyMod) {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	r.cert = &cert
+	r.certMod = certInfo.ModTime()
+	r.keyMod = keyInfo.ModTime()
+	return nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testCertificate is a certificate signed by a test certificate authority.
+type testCertificate struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	tls  tls.Certificate
+}
+
+// newTestCertificate creates a certificate signed by ca, or a self-signed
+// certificate authority if ca is nil.
+func newTestCertificate(t *testing.T, serial int64, ca *testCertificate) *testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "genji"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	parent, parentKey := &tmpl, key
+	if ca == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+	} else {
+		parent, parentKey = ca.cert, ca.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCertificate{
+		cert: cert,
+		key:  key,
+		tls:  tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key},
+	}
+}
+
+// write writes the certificate and its key in the given files,
+// and sets their modification time to mod.
+func (c *testCertificate) write(t *testing.T, certFile, keyFile string, mod time.Time) {
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0600)
+	require.NoError(t, err)
+	err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	require.NoError(t, err)
+
+	require.NoError(t, os.Chtimes(certFile, mod, mod))
+	require.NoError(t, os.Chtimes(keyFile, mod, mod))
+}
+
+func TestTLSConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "genji")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	caFile := filepath.Join(dir, "ca.pem")
+
+	ca := newTestCertificate(t, 1, nil)
+	now := time.Now()
+	ca.write(t, caFile, filepath.Join(dir, "ca-key.pem"), now)
+	newTestCertificate(t, 2, ca).write(t, certFile, keyFile, now)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	// start serves HTTPS with the given configuration and returns its URL
+	start := func(t *testing.T, cfg *tls.Config) string {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+
+		srv := http.Server{
+			Handler:   http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+			TLSConfig: cfg,
+			ErrorLog:  log.New(ioutil.Discard, "", 0),
+		}
+		go srv.ServeTLS(l, "", "")
+		t.Cleanup(func() { srv.Close() })
+		return "https://" + l.Addr().String()
+	}
+
+	// get returns the serial number of the certificate of the server
+	get := func(url string, clientCerts ...tls.Certificate) (int64, error) {
+		client := http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: clientCerts},
+		}}
+		defer client.CloseIdleConnections()
+
+		res, err := client.Get(url)
+		if err != nil {
+			return 0, err
+		}
+		res.Body.Close()
+		return res.TLS.PeerCertificates[0].SerialNumber.Int64(), nil
+	}
+
+	t.Run("Missing key", func(t *testing.T) {
+		_, err := newTLSConfig(certFile, "", "")
+		require.Error(t, err)
+		_, err = newTLSConfig("", "", caFile)
+		require.Error(t, err)
+	})
+
+	t.Run("Reload", func(t *testing.T) {
+		cfg, err := newTLSConfig(certFile, keyFile, "")
+		require.NoError(t, err)
+		url := start(t, cfg)
+
+		serial, err := get(url)
+		require.NoError(t, err)
+		require.EqualValues(t, 2, serial)
+
+		// the certificate is renewed
+		newTestCertificate(t, 3, ca).write(t, certFile, keyFile, now.Add(time.Minute))
+		serial, err = get(url)
+		require.NoError(t, err)
+		require.EqualValues(t, 3, serial)
+
+		// an invalid key keeps the previous certificate
+		err = ioutil.WriteFile(keyFile, []byte("foo"), 0600)
+		require.NoError(t, err)
+		require.NoError(t, os.Chtimes(keyFile, now.Add(2*time.Minute), now.Add(2*time.Minute)))
+		serial, err = get(url)
+		require.NoError(t, err)
+		require.EqualValues(t, 3, serial)
+	})
+
+	t.Run("Client certificates", func(t *testing.T) {
+		newTestCertificate(t, 4, ca).write(t, certFile, keyFile, now.Add(3*time.Minute))
+		cfg, err := newTLSConfig(certFile, keyFile, caFile)
+		require.NoError(t, err)
+		url := start(t, cfg)
+
+		_, err = get(url)
+		require.Error(t, err)
+
+		// certificates signed by other authorities are rejected
+		other := newTestCertificate(t, 5, nil)
+		_, err = get(url, newTestCertificate(t, 6, other).tls)
+		require.Error(t, err)
+
+		serial, err := get(url, newTestCertificate(t, 7, ca).tls)
+		require.NoError(t, err)
+		require.EqualValues(t, 4, serial)
+	})
+}
@@ -0,0 +1,199 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// An attachment is a database attached to another one, whose tables
+// can be referenced by prefixing their name with the name of the attachment.
+type attachment struct {
+	db       *Database
+	readOnly bool
+	// if true, db was opened by Database.AttachPath and is closed
+	// when detached.
+	owned bool
+}
+
+// Attach makes the tables of other available to the transactions of db, under the given name.
+// Tables are referenced by prefixing their name with the name of the attachment and a dot,
+// like "other.users". If readOnly is true, the attached tables can't be modified.
+// Transactions of db start a transaction on other the first time one of its tables is used,
+// which is committed or rolled back with them, and with their savepoints.
+// Committing the transactions of both databases is not atomic: if committing other fails,
+// db is not committed, but if committing db fails, the changes made to other remain.
+// other is not closed by db.
+func (db *Database) Attach(name string, other *Database, readOnly bool) error {
+	return db.attach(name, attachment{db: other, readOnly: readOnly})
+}
+
+// AttachPath opens the database at the given path using the OpenAttached function of db
+// and attaches it under the given name. The opened database is closed when detached,
+// or when db is closed.
+func (db *Database) AttachPath(name, path string, readOnly bool) error {
+	if db.OpenAttached == nil {
+		return NewError(CodeFeatureNotSupported, "attaching databases by path is not supported")
+	}
+
+	other, err := db.OpenAttached(path)
+	if err != nil {
+		return fmt.Errorf("failed to attach database %q: %w", path, err)
+	}
+
+	err = db.attach(name, attachment{db: other, readOnly: readOnly, owned: true})
+	if err != nil {
+		other.Close()
+		return err
+	}
+
+	return nil
+}
+
+func (db *Database) attach(name string, a attachment) error {
+	if name == "" || strings.Contains(name, ".") {
+		return NewError(CodeInvalidName, fmt.Sprintf("invalid attachment name %q", name))
+	}
+	if a.db == db {
+		return NewError(CodeFeatureNotSupported, "a database can't be attached to itself")
+	}
+
+	db.attachmentsMu.Lock()
+	defer db.attachmentsMu.Unlock()
+
+	if _, ok := db.attachments[name]; ok {
+		return NewError(CodeDuplicateObject, fmt.Sprintf("database %q is already attached", name))
+	}
+
+	if db.attachments == nil {
+		db.attachments = make(map[string]attachment)
+	}
+	db.attachments[name] = a
+	db.bumpCatalogVersion()
+	return nil
+}
+
+// Detach removes the database attached under the given name.
+// Transactions that already use it are not affected.
+func (db *Database) Detach(name string) error {
+	db.attachmentsMu.Lock()
+	a, ok := db.attachments[name]
+	delete(db.attachments, name)
+	db.attachmentsMu.Unlock()
+	db.bumpCatalogVersion()
+
+	if !ok {
+		return NewError(CodeUndefinedObject, fmt.Sprintf("database %q is not attached", name))
+	}
+
+	if a.owned {
+		return a.db.Close()
+	}
+
+	return nil
+}
+
+// AttachedDatabases returns the names of the attached databases, in alphabetical order.
+func (db *Database) AttachedDatabases() []string {
+	db.attachmentsMu.Lock()
+	defer db.attachmentsMu.Unlock()
+
+	names := make([]string, 0, len(db.attachments))
+	for name := range db.attachments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// closeAttachments detaches all the databases and closes the ones opened by db.
+func (db *Database) closeAttachments() error {
+	db.attachmentsMu.Lock()
+	defer db.attachmentsMu.Unlock()
+
+	var err error
+	for name, a := range db.attachments {
+		delete(db.attachments, name)
+		if !a.owned {
+			continue
+		}
+
+		if e := a.db.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	return err
+}
+
+// attachedTable returns the transaction of the attached database referenced
+// by a qualified table or index name, and the name without the attachment prefix.
+// It returns a nil transaction if name doesn't reference an attached database.
+func (tx *Transaction) attachedTable(name string) (*Transaction, string, error) {
+	i := strings.IndexByte(name, '.')
+	if i <= 0 {
+		return nil, "", nil
+	}
+
+	prefix := name[:i]
+	if atx, ok := tx.attached[prefix]; ok {
+		return atx, name[i+1:], nil
+	}
+
+	tx.db.attachmentsMu.Lock()
+	a, ok := tx.db.attachments[prefix]
+	tx.db.attachmentsMu.Unlock()
+	if !ok {
+		return nil, "", nil
+	}
+
+	atx, err := a.db.Begin(tx.writable && !a.readOnly)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to use attached database %q: %w", prefix, err)
+	}
+
+	// the savepoints of tx cover the changes made to the attached database
+	for _, sp := range tx.openSavepoints {
+		err = sp.attach(prefix, atx)
+		if err != nil {
+			atx.Rollback()
+			return nil, "", fmt.Errorf("failed to use attached database %q: %w", prefix, err)
+		}
+	}
+
+	if tx.attached == nil {
+		tx.attached = make(map[string]*Transaction)
+	}
+	tx.attached[prefix] = atx
+	return atx, name[i+1:], nil
+}
+
+// commitAttached commits the transactions started on the attached databases,
+// before the transaction of tx is committed.
+// Read-only transactions and the ones remaining after a failure are rolled back.
+// The commits are not atomic: if one of them fails, the attached databases
+// committed before it keep their changes while tx is rolled back.
+func (tx *Transaction) commitAttached() error {
+	var err error
+	for name, atx := range tx.attached {
+		if err == nil && atx.writable {
+			err = atx.Commit()
+			if err != nil {
+				err = fmt.Errorf("failed to commit attached database %q: %w", name, err)
+			}
+		} else {
+			atx.Rollback()
+		}
+		delete(tx.attached, name)
+	}
+
+	return err
+}
+
+// rollbackAttached rolls back the transactions started on the attached databases.
+func (tx *Transaction) rollbackAttached() {
+	for name, atx := range tx.attached {
+		atx.Rollback()
+		delete(tx.attached, name)
+	}
+}
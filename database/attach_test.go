@@ -0,0 +1,186 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/document/encoding/msgpack"
+	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/stretchr/testify/require"
+)
+
+func newAttachTestDB(t testing.TB) *database.Database {
+	db, err := database.New(memoryengine.NewEngine(), database.Options{Codec: msgpack.NewCodec()})
+	require.NoError(t, err)
+	return db
+}
+
+func TestAttach(t *testing.T) {
+	db := newAttachTestDB(t)
+	defer db.Close()
+	other := newAttachTestDB(t)
+	defer other.Close()
+
+	require.NoError(t, db.Attach("other", other, false))
+	require.Equal(t, []string{"other"}, db.AttachedDatabases())
+
+	t.Run("Invalid", func(t *testing.T) {
+		require.Error(t, db.Attach("other", other, false))
+		require.Error(t, db.Attach("", other, false))
+		require.Error(t, db.Attach("a.b", other, false))
+		require.Error(t, db.Attach("self", db, false))
+	})
+
+	t.Run("Commit", func(t *testing.T) {
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		err = tx.CreateTable("other.test", nil)
+		require.NoError(t, err)
+		tb, err := tx.GetTable("other.test")
+		require.NoError(t, err)
+		_, err = tb.Insert(document.NewFieldBuffer().Add("a", document.NewIntegerValue(1)))
+		require.NoError(t, err)
+
+		// the table is created in the attached database
+		_, err = tx.GetTable("test")
+		require.Equal(t, database.ErrTableNotFound, err)
+		require.NoError(t, tx.Commit())
+
+		otx, err := other.Begin(false)
+		require.NoError(t, err)
+		defer otx.Rollback()
+		tb, err = otx.GetTable("test")
+		require.NoError(t, err)
+		var count int
+		err = tb.Iterate(func(d document.Document) error {
+			count++
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 1, count)
+	})
+
+	t.Run("Rollback", func(t *testing.T) {
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		err = tx.CreateTable("other.foo", nil)
+		require.NoError(t, err)
+		require.NoError(t, tx.Rollback())
+
+		otx, err := other.Begin(false)
+		require.NoError(t, err)
+		defer otx.Rollback()
+		_, err = otx.GetTable("foo")
+		require.Equal(t, database.ErrTableNotFound, err)
+	})
+
+	t.Run("Savepoint", func(t *testing.T) {
+		count := func(tx *database.Transaction, name string) int {
+			tb, err := tx.GetTable(name)
+			require.NoError(t, err)
+			var n int
+			err = tb.Iterate(func(d document.Document) error {
+				n++
+				return nil
+			})
+			require.NoError(t, err)
+			return n
+		}
+
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		// the attached database is used after the savepoint is created
+		sp, err := tx.Savepoint()
+		require.NoError(t, err)
+		require.NoError(t, tx.CreateTable("other.sp1", nil))
+		require.NoError(t, sp.Rollback())
+		_, err = tx.GetTable("other.sp1")
+		require.Equal(t, database.ErrTableNotFound, err)
+
+		// the attached database is used before the savepoint is created
+		require.NoError(t, tx.CreateTable("other.sp2", nil))
+		sp, err = tx.Savepoint()
+		require.NoError(t, err)
+		tb, err := tx.GetTable("other.sp2")
+		require.NoError(t, err)
+		_, err = tb.Insert(document.NewFieldBuffer().Add("a", document.NewIntegerValue(1)))
+		require.NoError(t, err)
+		require.Equal(t, 1, count(tx, "other.sp2"))
+		require.NoError(t, sp.Rollback())
+		require.Equal(t, 0, count(tx, "other.sp2"))
+
+		// released savepoints keep the changes
+		sp, err = tx.Savepoint()
+		require.NoError(t, err)
+		_, err = tb.Insert(document.NewFieldBuffer().Add("a", document.NewIntegerValue(2)))
+		require.NoError(t, err)
+		sp.Release()
+		require.NoError(t, tx.Commit())
+
+		otx, err := other.Begin(false)
+		require.NoError(t, err)
+		defer otx.Rollback()
+		_, err = otx.GetTable("sp1")
+		require.Equal(t, database.ErrTableNotFound, err)
+		require.Equal(t, 1, count(otx, "sp2"))
+	})
+
+	t.Run("Detach", func(t *testing.T) {
+		require.NoError(t, db.Detach("other"))
+		require.Error(t, db.Detach("other"))
+		require.Empty(t, db.AttachedDatabases())
+
+		tx, err := db.Begin(false)
+		require.NoError(t, err)
+		defer tx.Rollback()
+		_, err = tx.GetTable("other.test")
+		require.Equal(t, database.ErrTableNotFound, err)
+	})
+}
+
+func TestAttachReadOnly(t *testing.T) {
+	db := newAttachTestDB(t)
+	defer db.Close()
+	other := newAttachTestDB(t)
+	defer other.Close()
+
+	otx, err := other.Begin(true)
+	require.NoError(t, err)
+	require.NoError(t, otx.CreateTable("test", nil))
+	require.NoError(t, otx.Commit())
+
+	require.NoError(t, db.Attach("other", other, true))
+
+	tx, err := db.Begin(true)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	tb, err := tx.GetTable("other.test")
+	require.NoError(t, err)
+	_, err = tb.Insert(document.NewFieldBuffer().Add("a", document.NewIntegerValue(1)))
+	require.Equal(t, engine.ErrTransactionReadOnly, err)
+}
+
+func TestAttachPath(t *testing.T) {
+	db := newAttachTestDB(t)
+	defer db.Close()
+
+	err := db.AttachPath("other", "foo.db", false)
+	require.Equal(t, database.CodeFeatureNotSupported, database.CodeOf(err))
+
+	var opened []string
+	db.OpenAttached = func(path string) (*database.Database, error) {
+		opened = append(opened, path)
+		return newAttachTestDB(t), nil
+	}
+
+	require.NoError(t, db.AttachPath("other", "foo.db", false))
+	require.Equal(t, []string{"foo.db"}, opened)
+	require.NoError(t, db.Detach("other"))
+}
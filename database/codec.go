@@ -0,0 +1,847 @@
+package database
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/document/encoding"
+	"github.com/genjidb/genji/document/encoding/custom"
+	"github.com/genjidb/genji/engine"
+)
+
+// compactCodec is a codec used by tables whose field constraints
+// only target top-level fields.
+// Since the fields declared by the constraints are known in advance,
+// their values are encoded positionally, without their name, in the order of the constraints.
+// Any other field of the document is encoded after them using the database codec.
+// Documents whose fields are not in that order, for example when they were inserted with
+// INSERT INTO t (b, a), are entirely encoded using the database codec, to preserve their order.
+//
+// An encoded document is composed of:
+//   - the number of positional values, as an uvarint, or 0 if the document isn't encoded positionally
+//   - for each positional value, its type as an uvarint (0 if the field is missing),
+//     followed by the size of the encoded value as an uvarint and the value itself
+//   - the remaining fields of the document, encoded using the database codec, if any
+type compactCodec struct {
+	fields []string
+	codec  encoding.Codec
+}
+
+var _ encoding.InterningCodec = (*compactCodec)(nil)
+
+func newCompactCodec(info *TableInfo, codec encoding.Codec) *compactCodec {
+	c := compactCodec{
+		fields: make([]string, len(info.FieldConstraints)),
+		codec:  codec,
+	}
+
+	for i, fc := range info.FieldConstraints {
+		c.fields[i] = fc.Path[0].FieldName
+	}
+
+	return &c
+}
+
+// NewEncoder implements the encoding.Codec interface.
+func (c *compactCodec) NewEncoder(w io.Writer) encoding.Encoder {
+	return &compactEncoder{c: c, w: w}
+}
+
+// NewDocument implements the encoding.Codec interface.
+func (c *compactCodec) NewDocument(data []byte) document.Document {
+	return &compactDocument{c: c, data: data}
+}
+
+// NewInterningDocument implements the encoding.InterningCodec interface.
+func (c *compactCodec) NewInterningDocument(data []byte, in *document.Interner) document.Document {
+	return &compactDocument{c: c, data: data, interner: in}
+}
+
+// fieldPosition returns the position of the given field
+// or -1 if it's not declared by the table.
+func (c *compactCodec) fieldPosition(field string) int {
+	for i := range c.fields {
+		if c.fields[i] == field {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// isOrdered reports whether the fields declared by the table come first in the document,
+// in the order of the constraints, which is the order in which a positionally
+// encoded document returns its fields.
+func (c *compactCodec) isOrdered(d document.Document) (bool, error) {
+	last := -1
+	ordered := true
+	err := d.Iterate(func(f string, v document.Value) error {
+		pos := c.fieldPosition(f)
+		switch {
+		case pos == -1:
+			last = len(c.fields)
+		case pos <= last:
+			ordered = false
+			return errStop
+		default:
+			last = pos
+		}
+		return nil
+	})
+	if err != nil && err != errStop {
+		return false, err
+	}
+
+	return ordered, nil
+}
+
+type compactEncoder struct {
+	c *compactCodec
+	w io.Writer
+}
+
+func (e *compactEncoder) EncodeDocument(d document.Document) error {
+	var buf bytes.Buffer
+	intBuf := make([]byte, binary.MaxVarintLen64)
+
+	ordered, err := e.c.isOrdered(d)
+	if err != nil {
+		return err
+	}
+	if !ordered {
+		buf.WriteByte(0)
+		err = e.c.codec.NewEncoder(&buf).EncodeDocument(d)
+		if err != nil {
+			return err
+		}
+
+		_, err = buf.WriteTo(e.w)
+		return err
+	}
+
+	n := binary.PutUvarint(intBuf, uint64(len(e.c.fields)))
+	buf.Write(intBuf[:n])
+
+	for _, f := range e.c.fields {
+		v, err := d.GetByField(f)
+		if err == document.ErrFieldNotFound {
+			buf.WriteByte(0)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		data, err := custom.EncodeValue(v)
+		if err != nil {
+			return err
+		}
+
+		n = binary.PutUvarint(intBuf, uint64(v.Type))
+		buf.Write(intBuf[:n])
+		n = binary.PutUvarint(intBuf, uint64(len(data)))
+		buf.Write(intBuf[:n])
+		buf.Write(data)
+	}
+
+	// encode the fields that were not declared by the table
+	var fb document.FieldBuffer
+	err = d.Iterate(func(f string, v document.Value) error {
+		if e.c.fieldPosition(f) == -1 {
+			fb.Add(f, v)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if fb.Len() > 0 {
+		err = e.c.codec.NewEncoder(&buf).EncodeDocument(&fb)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = buf.WriteTo(e.w)
+	return err
+}
+
+// compactDocument implements the document.Document interface
+// on top of a document encoded by a compactCodec.
+type compactDocument struct {
+	c        *compactCodec
+	data     []byte
+	interner *document.Interner
+}
+
+var errCannotDecode = errors.New("cannot decode data")
+
+// iterate goes through the positional values of the document
+// and calls fn with the position, the type and the encoded data of each of them.
+// Missing fields are skipped. It returns the remaining data.
+func (d *compactDocument) iterate(fn func(i int, t document.ValueType, data []byte) (bool, error)) ([]byte, error) {
+	data := d.data
+
+	l, n := binary.Uvarint(data)
+	if n <= 0 {
+		return nil, errCannotDecode
+	}
+	data = data[n:]
+
+	for i := 0; i < int(l); i++ {
+		t, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, errCannotDecode
+		}
+		data = data[n:]
+
+		if t == 0 {
+			continue
+		}
+
+		size, n := binary.Uvarint(data)
+		if n <= 0 || uint64(len(data)-n) < size {
+			return nil, errCannotDecode
+		}
+		data = data[n:]
+
+		stop, err := fn(i, document.ValueType(t), data[:size])
+		if err != nil || stop {
+			return nil, err
+		}
+		data = data[size:]
+	}
+
+	return data, nil
+}
+
+func (d *compactDocument) GetByField(field string) (document.Value, error) {
+	pos := d.c.fieldPosition(field)
+
+	var v document.Value
+	var found bool
+	rest, err := d.iterate(func(i int, t document.ValueType, data []byte) (bool, error) {
+		if i != pos {
+			return false, nil
+		}
+
+		var err error
+		v, err = d.decodeValue(t, data)
+		found = err == nil
+		return true, err
+	})
+	if err != nil {
+		return document.Value{}, err
+	}
+	if found {
+		return v, nil
+	}
+	// declared fields are only encoded with the database codec
+	// if the document isn't encoded positionally
+	if len(rest) == 0 || (pos != -1 && d.data[0] != 0) {
+		return document.Value{}, document.ErrFieldNotFound
+	}
+
+	return newDocument(d.c.codec, rest, d.interner).GetByField(field)
+}
+
+func (d *compactDocument) Iterate(fn func(field string, value document.Value) error) error {
+	rest, err := d.iterate(func(i int, t document.ValueType, data []byte) (bool, error) {
+		if i >= len(d.c.fields) {
+			return false, errCannotDecode
+		}
+
+		v, err := d.decodeValue(t, data)
+		if err != nil {
+			return false, err
+		}
+
+		return false, fn(d.c.fields[i], v)
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(rest) == 0 {
+		return nil
+	}
+
+	return newDocument(d.c.codec, rest, d.interner).Iterate(fn)
+}
+
+// decodeValue decodes a positional value, sharing texts through the interner of the document.
+func (d *compactDocument) decodeValue(t document.ValueType, data []byte) (document.Value, error) {
+	if t == document.TextValue && d.interner != nil {
+		return d.interner.NewTextValue(data), nil
+	}
+
+	return custom.DecodeValue(t, data)
+}
+
+// Encoding returns the encoded representation of the document,
+// if the database codec exposes one.
+// It implements the document.EncodedDocument interface.
+func (d *compactDocument) Encoding() (string, []byte) {
+	name := encodingName(d.c.codec)
+	if name == "" {
+		return "", nil
+	}
+
+	// the fields are part of the name since their values are encoded positionally
+	return fmt.Sprintf("compact%q/%s", d.c.fields, name), d.data
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (d *compactDocument) MarshalJSON() ([]byte, error) {
+	return document.MarshalJSON(d)
+}
+
+// encodingName returns the name of the encoding of the documents decoded by codec,
+// or an empty string if they don't expose their encoded representation.
+func encodingName(codec encoding.Codec) string {
+	if ed, ok := codec.NewDocument(nil).(document.EncodedDocument); ok {
+		name, _ := ed.Encoding()
+		return name
+	}
+
+	return ""
+}
+
+// newDocument returns a document decoding data with the given codec,
+// which shares its values through in if the codec supports it and in is not nil.
+func newDocument(codec encoding.Codec, data []byte, in *document.Interner) document.Document {
+	if ic, ok := codec.(encoding.InterningCodec); ok && in != nil {
+		return ic.NewInterningDocument(data, in)
+	}
+
+	return codec.NewDocument(data)
+}
+
+// errUnknownField is returned by the field dictionary when a field name or id isn't registered.
+var errUnknownField = errors.New("unknown field")
+
+// fieldDictionary assigns a short identifier to every top-level field name
+// found in the documents of a table. Identifiers are stored in a dedicated store
+// and are never reused nor removed.
+// Lookups are cached for the lifetime of the table instance.
+type fieldDictionary struct {
+	st engine.Store
+	// field name -> encoded identifier
+	ids map[string]string
+	// encoded identifier -> field name
+	names map[string]string
+}
+
+// keys of the dictionary store are prefixed
+// to store both directions of the mapping.
+const (
+	dictionaryNamePrefix = 'n'
+	dictionaryIDPrefix   = 'i'
+)
+
+func newFieldDictionary(st engine.Store) *fieldDictionary {
+	return &fieldDictionary{
+		st:    st,
+		ids:   make(map[string]string),
+		names: make(map[string]string),
+	}
+}
+
+// id returns the identifier of the given field name.
+// If the field isn't registered and create is true, a new identifier
+// is generated, otherwise errUnknownField is returned.
+func (f *fieldDictionary) id(name string, create bool) (string, error) {
+	if id, ok := f.ids[name]; ok {
+		return id, nil
+	}
+
+	k := append([]byte{dictionaryNamePrefix}, name...)
+	v, err := f.st.Get(k)
+	if err == nil {
+		f.cache(name, string(v))
+		return string(v), nil
+	}
+	if err != engine.ErrKeyNotFound {
+		return "", err
+	}
+	if !create {
+		return "", errUnknownField
+	}
+
+	seq, err := f.st.NextSequence()
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, binary.MaxVarintLen64)
+	id := buf[:binary.PutUvarint(buf, seq)]
+
+	err = f.st.Put(k, id)
+	if err != nil {
+		return "", err
+	}
+	err = f.st.Put(append([]byte{dictionaryIDPrefix}, id...), []byte(name))
+	if err != nil {
+		return "", err
+	}
+
+	f.cache(name, string(id))
+	return string(id), nil
+}
+
+// name returns the field name associated with the given identifier.
+func (f *fieldDictionary) name(id string) (string, error) {
+	if name, ok := f.names[id]; ok {
+		return name, nil
+	}
+
+	v, err := f.st.Get(append([]byte{dictionaryIDPrefix}, id...))
+	if err == engine.ErrKeyNotFound {
+		return "", errUnknownField
+	}
+	if err != nil {
+		return "", err
+	}
+
+	f.cache(string(v), id)
+	return string(v), nil
+}
+
+func (f *fieldDictionary) cache(name, id string) {
+	f.ids[name] = id
+	f.names[id] = name
+}
+
+// dictionaryCodec replaces the top-level field names of the documents
+// by their identifier in the field dictionary of the table, then encodes
+// them using the database codec.
+// Decoded documents translate the identifiers back into field names transparently.
+type dictionaryCodec struct {
+	dict  *fieldDictionary
+	codec encoding.Codec
+	// id identifies the dictionary in the encoding of the documents,
+	// which can only be compared with documents using the same dictionary.
+	// If empty, the encoding is not exposed.
+	id string
+}
+
+var _ encoding.InterningCodec = (*dictionaryCodec)(nil)
+
+// NewEncoder implements the encoding.Codec interface.
+func (c *dictionaryCodec) NewEncoder(w io.Writer) encoding.Encoder {
+	return &dictionaryEncoder{c: c, w: w}
+}
+
+// NewDocument implements the encoding.Codec interface.
+func (c *dictionaryCodec) NewDocument(data []byte) document.Document {
+	return &dictionaryDocument{id: c.id, dict: c.dict, d: c.codec.NewDocument(data)}
+}
+
+// NewInterningDocument implements the encoding.InterningCodec interface.
+// Field names are already shared by the dictionary.
+func (c *dictionaryCodec) NewInterningDocument(data []byte, in *document.Interner) document.Document {
+	return &dictionaryDocument{id: c.id, dict: c.dict, d: newDocument(c.codec, data, in)}
+}
+
+type dictionaryEncoder struct {
+	c *dictionaryCodec
+	w io.Writer
+}
+
+func (e *dictionaryEncoder) EncodeDocument(d document.Document) error {
+	var fb document.FieldBuffer
+
+	err := d.Iterate(func(f string, v document.Value) error {
+		id, err := e.c.dict.id(f, true)
+		if err != nil {
+			return err
+		}
+
+		fb.Add(id, v)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return e.c.codec.NewEncoder(e.w).EncodeDocument(&fb)
+}
+
+// dictionaryDocument implements the document.Document interface
+// on top of a document encoded by a dictionaryCodec.
+type dictionaryDocument struct {
+	id   string
+	dict *fieldDictionary
+	d    document.Document
+}
+
+func (d *dictionaryDocument) GetByField(field string) (document.Value, error) {
+	id, err := d.dict.id(field, false)
+	if err == errUnknownField {
+		// no document of the table contains this field
+		return document.Value{}, document.ErrFieldNotFound
+	}
+	if err != nil {
+		return document.Value{}, err
+	}
+
+	return d.d.GetByField(id)
+}
+
+func (d *dictionaryDocument) Iterate(fn func(field string, value document.Value) error) error {
+	return d.d.Iterate(func(id string, v document.Value) error {
+		name, err := d.dict.name(id)
+		if err != nil {
+			return err
+		}
+
+		return fn(name, v)
+	})
+}
+
+// Encoding returns the encoded representation of the document, if any.
+// Since identifiers are never reused, documents using the same dictionary
+// with the same encoding have the same fields.
+// It implements the document.EncodedDocument interface.
+func (d *dictionaryDocument) Encoding() (string, []byte) {
+	ed, ok := d.d.(document.EncodedDocument)
+	if !ok || d.id == "" {
+		return "", nil
+	}
+
+	name, data := ed.Encoding()
+	if name == "" {
+		return "", nil
+	}
+
+	return "dictionary(" + d.id + ")/" + name, data
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (d *dictionaryDocument) MarshalJSON() ([]byte, error) {
+	return document.MarshalJSON(d)
+}
+
+// overflowCodec moves the top-level text and blob values whose size exceeds
+// the MaxInlineValueSize of the table to a dedicated store, and only keeps a reference
+// to them in the document. These values are only fetched when requested,
+// which keeps decoding the other fields fast.
+//
+// An encoded document is composed of:
+//   - the number of values stored in the overflow store, as an uvarint
+//   - for each of them, its position in the document, its type, the name of its field
+//     and its key in the overflow store, prefixed by their size
+//   - the other fields of the document, encoded using the underlying codec
+type overflowCodec struct {
+	st      engine.Store
+	maxSize int
+	codec   encoding.Codec
+	// id identifies the overflow store in the encoding of the documents,
+	// which reference the values of that store.
+	// If empty, the encoding is not exposed.
+	id string
+}
+
+var _ encoding.InterningCodec = (*overflowCodec)(nil)
+
+// NewEncoder implements the encoding.Codec interface.
+func (c *overflowCodec) NewEncoder(w io.Writer) encoding.Encoder {
+	return &overflowEncoder{c: c, w: w}
+}
+
+// NewDocument implements the encoding.Codec interface.
+func (c *overflowCodec) NewDocument(data []byte) document.Document {
+	return &overflowDocument{c: c, data: data}
+}
+
+// NewInterningDocument implements the encoding.InterningCodec interface.
+// Overflown values are too large to be interned.
+func (c *overflowCodec) NewInterningDocument(data []byte, in *document.Interner) document.Document {
+	return &overflowDocument{c: c, data: data, interner: in}
+}
+
+// free deletes the values referenced by the given encoded document
+// from the overflow store.
+func (c *overflowCodec) free(data []byte) error {
+	refs, _, err := decodeOverflowRefs(data)
+	if err != nil {
+		return err
+	}
+
+	for _, ref := range refs {
+		err = c.st.Delete(ref.key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// load fetches the value referenced by ref from the overflow store.
+func (c *overflowCodec) load(ref *overflowRef) (document.Value, error) {
+	data, err := c.st.Get(ref.key)
+	if err != nil {
+		return document.Value{}, err
+	}
+
+	switch ref.typ {
+	case document.TextValue:
+		return document.NewTextValue(string(data)), nil
+	case document.BlobValue:
+		buf := make([]byte, len(data))
+		copy(buf, data)
+		return document.NewBlobValue(buf), nil
+	}
+
+	return document.Value{}, errCannotDecode
+}
+
+// overflowRef references a value stored in the overflow store.
+type overflowRef struct {
+	pos   int
+	typ   document.ValueType
+	field string
+	key   []byte
+}
+
+func decodeOverflowRefs(data []byte) ([]overflowRef, []byte, error) {
+	l, n := binary.Uvarint(data)
+	if n <= 0 {
+		return nil, nil, errCannotDecode
+	}
+	data = data[n:]
+
+	if l == 0 {
+		return nil, data, nil
+	}
+
+	readBytes := func() ([]byte, bool) {
+		size, n := binary.Uvarint(data)
+		if n <= 0 || uint64(len(data)-n) < size {
+			return nil, false
+		}
+		b := data[n : n+int(size)]
+		data = data[n+int(size):]
+		return b, true
+	}
+
+	refs := make([]overflowRef, l)
+	for i := range refs {
+		pos, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, nil, errCannotDecode
+		}
+		data = data[n:]
+
+		typ, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, nil, errCannotDecode
+		}
+		data = data[n:]
+
+		field, ok := readBytes()
+		if !ok {
+			return nil, nil, errCannotDecode
+		}
+		key, ok := readBytes()
+		if !ok {
+			return nil, nil, errCannotDecode
+		}
+
+		refs[i] = overflowRef{
+			pos:   int(pos),
+			typ:   document.ValueType(typ),
+			field: string(field),
+			key:   key,
+		}
+	}
+
+	return refs, data, nil
+}
+
+type overflowEncoder struct {
+	c *overflowCodec
+	w io.Writer
+}
+
+func (e *overflowEncoder) EncodeDocument(d document.Document) error {
+	var buf bytes.Buffer
+	var fb document.FieldBuffer
+	var refs []overflowRef
+	intBuf := make([]byte, binary.MaxVarintLen64)
+
+	var i int
+	err := d.Iterate(func(f string, v document.Value) error {
+		defer func() { i++ }()
+
+		var data []byte
+		switch v.Type {
+		case document.TextValue:
+			data = []byte(v.V.(string))
+		case document.BlobValue:
+			data = v.V.([]byte)
+		}
+
+		if e.c.maxSize <= 0 || len(data) <= e.c.maxSize {
+			fb.Add(f, v)
+			return nil
+		}
+
+		seq, err := e.c.st.NextSequence()
+		if err != nil {
+			return err
+		}
+		key := make([]byte, binary.MaxVarintLen64)
+		key = key[:binary.PutUvarint(key, seq)]
+
+		err = e.c.st.Put(key, data)
+		if err != nil {
+			return err
+		}
+
+		refs = append(refs, overflowRef{pos: i, typ: v.Type, field: f, key: key})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	buf.Write(intBuf[:binary.PutUvarint(intBuf, uint64(len(refs)))])
+	for _, ref := range refs {
+		buf.Write(intBuf[:binary.PutUvarint(intBuf, uint64(ref.pos))])
+		buf.Write(intBuf[:binary.PutUvarint(intBuf, uint64(ref.typ))])
+		buf.Write(intBuf[:binary.PutUvarint(intBuf, uint64(len(ref.field)))])
+		buf.WriteString(ref.field)
+		buf.Write(intBuf[:binary.PutUvarint(intBuf, uint64(len(ref.key)))])
+		buf.Write(ref.key)
+	}
+
+	err = e.c.codec.NewEncoder(&buf).EncodeDocument(&fb)
+	if err != nil {
+		return err
+	}
+
+	_, err = buf.WriteTo(e.w)
+	return err
+}
+
+// overflowDocument implements the document.Document interface
+// on top of a document encoded by an overflowCodec.
+type overflowDocument struct {
+	c        *overflowCodec
+	data     []byte
+	interner *document.Interner
+
+	decoded bool
+	refs    []overflowRef
+	d       document.Document
+}
+
+func (d *overflowDocument) decode() error {
+	if d.decoded {
+		return nil
+	}
+
+	refs, rest, err := decodeOverflowRefs(d.data)
+	if err != nil {
+		return err
+	}
+
+	d.refs = refs
+	d.d = newDocument(d.c.codec, rest, d.interner)
+	d.decoded = true
+	return nil
+}
+
+func (d *overflowDocument) GetByField(field string) (document.Value, error) {
+	err := d.decode()
+	if err != nil {
+		return document.Value{}, err
+	}
+
+	for i := range d.refs {
+		if d.refs[i].field == field {
+			return d.c.load(&d.refs[i])
+		}
+	}
+
+	return d.d.GetByField(field)
+}
+
+func (d *overflowDocument) Iterate(fn func(field string, value document.Value) error) error {
+	err := d.decode()
+	if err != nil {
+		return err
+	}
+
+	// pos is the position of the next field to yield
+	// and next the index of the next reference to yield
+	var pos, next int
+
+	// yield the overflown values located at the current position,
+	// or all of the remaining ones if all is true
+	flush := func(all bool) error {
+		for next < len(d.refs) && (all || d.refs[next].pos <= pos) {
+			v, err := d.c.load(&d.refs[next])
+			if err != nil {
+				return err
+			}
+
+			err = fn(d.refs[next].field, v)
+			if err != nil {
+				return err
+			}
+			next++
+			pos++
+		}
+
+		return nil
+	}
+
+	err = d.d.Iterate(func(field string, value document.Value) error {
+		err := flush(false)
+		if err != nil {
+			return err
+		}
+
+		pos++
+		return fn(field, value)
+	})
+	if err != nil {
+		return err
+	}
+
+	return flush(true)
+}
+
+// Encoding returns the encoded representation of the document, if any.
+// Since the keys of the overflow store are never reused, documents with
+// the same encoding reference the same values.
+// It implements the document.EncodedDocument interface.
+func (d *overflowDocument) Encoding() (string, []byte) {
+	if d.c.id == "" || d.decode() != nil {
+		return "", nil
+	}
+
+	ed, ok := d.d.(document.EncodedDocument)
+	if !ok {
+		return "", nil
+	}
+
+	name, _ := ed.Encoding()
+	if name == "" {
+		return "", nil
+	}
+
+	return "overflow(" + d.c.id + ")/" + name, d.data
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (d *overflowDocument) MarshalJSON() ([]byte, error) {
+	return document.MarshalJSON(d)
+}
@@ -0,0 +1,591 @@
+package database
+
+import (
+	"bytes"
+	"testing"
+	"unsafe"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/document/encoding"
+	"github.com/genjidb/genji/document/encoding/encodingtest"
+	"github.com/genjidb/genji/document/encoding/msgpack"
+	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCompactCodec() encoding.Codec {
+	return newCompactCodec(&TableInfo{
+		FieldConstraints: []FieldConstraint{
+			{Path: document.ValuePath{{FieldName: "age"}}, Type: document.IntegerValue},
+			{Path: document.ValuePath{{FieldName: "name"}}},
+			{Path: document.ValuePath{{FieldName: "score"}}, Type: document.DoubleValue},
+		},
+	}, msgpack.NewCodec())
+}
+
+func TestCompactCodec(t *testing.T) {
+	encodingtest.TestCodec(t, newTestCompactCodec)
+
+	t.Run("Missing and extra fields", func(t *testing.T) {
+		codec := newTestCompactCodec()
+
+		d := document.NewFieldBuffer().
+			Add("name", document.NewTextValue("john")).
+			Add("extra", document.NewBoolValue(true)).
+			Add("score", document.NewDoubleValue(1.5))
+
+		var buf bytes.Buffer
+		err := codec.NewEncoder(&buf).EncodeDocument(d)
+		require.NoError(t, err)
+
+		ed := codec.NewDocument(buf.Bytes())
+		_, err = ed.GetByField("age")
+		require.Equal(t, document.ErrFieldNotFound, err)
+		_, err = ed.GetByField("foo")
+		require.Equal(t, document.ErrFieldNotFound, err)
+
+		v, err := ed.GetByField("extra")
+		require.NoError(t, err)
+		require.Equal(t, document.NewBoolValue(true), v)
+
+		data, err := document.MarshalJSON(ed)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"name": "john", "score": 1.5, "extra": true}`, string(data))
+	})
+
+	t.Run("Field order", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			fields   []string
+			expected string
+		}{
+			{"Declared order", []string{"age", "name", "score"}, `{"age": 1, "name": "n", "score": 1.5}`},
+			{"Declared order, then extra fields", []string{"age", "score", "x", "y"}, `{"age": 1, "score": 1.5, "x": true, "y": true}`},
+			{"Other order", []string{"score", "name", "age"}, `{"score": 1.5, "name": "n", "age": 1}`},
+			{"Extra fields first", []string{"x", "age", "name"}, `{"x": true, "age": 1, "name": "n"}`},
+		}
+
+		values := map[string]document.Value{
+			"age":   document.NewIntegerValue(1),
+			"name":  document.NewTextValue("n"),
+			"score": document.NewDoubleValue(1.5),
+			"x":     document.NewBoolValue(true),
+			"y":     document.NewBoolValue(true),
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				codec := newTestCompactCodec()
+
+				d := document.NewFieldBuffer()
+				for _, f := range test.fields {
+					d.Add(f, values[f])
+				}
+
+				var buf bytes.Buffer
+				err := codec.NewEncoder(&buf).EncodeDocument(d)
+				require.NoError(t, err)
+
+				ed := codec.NewDocument(buf.Bytes())
+				data, err := document.MarshalJSON(ed)
+				require.NoError(t, err)
+				require.Equal(t, test.expected, string(data))
+
+				for _, f := range test.fields {
+					v, err := ed.GetByField(f)
+					require.NoError(t, err)
+					require.Equal(t, values[f], v)
+				}
+				_, err = ed.GetByField("foo")
+				require.Equal(t, document.ErrFieldNotFound, err)
+			})
+		}
+	})
+
+	t.Run("Smaller than the database codec", func(t *testing.T) {
+		d := document.NewFieldBuffer().
+			Add("age", document.NewIntegerValue(10)).
+			Add("name", document.NewTextValue("john")).
+			Add("score", document.NewDoubleValue(1.5))
+
+		var compact, regular bytes.Buffer
+		err := newTestCompactCodec().NewEncoder(&compact).EncodeDocument(d)
+		require.NoError(t, err)
+		err = msgpack.NewCodec().NewEncoder(&regular).EncodeDocument(d)
+		require.NoError(t, err)
+
+		require.Less(t, compact.Len(), regular.Len())
+	})
+}
+
+func newTestDictionaryCodec(t *testing.T) (func() encoding.Codec, engine.Store) {
+	ng := memoryengine.NewEngine()
+	t.Cleanup(func() { ng.Close() })
+
+	tx, err := ng.Begin(true)
+	require.NoError(t, err)
+	t.Cleanup(func() { tx.Rollback() })
+
+	err = tx.CreateStore([]byte("dict"))
+	require.NoError(t, err)
+	st, err := tx.GetStore([]byte("dict"))
+	require.NoError(t, err)
+
+	return func() encoding.Codec {
+		return &dictionaryCodec{dict: newFieldDictionary(st), codec: msgpack.NewCodec()}
+	}, st
+}
+
+func TestDictionaryCodec(t *testing.T) {
+	codecBuilder, st := newTestDictionaryCodec(t)
+
+	encodingtest.TestCodec(t, codecBuilder)
+
+	t.Run("Field names are replaced", func(t *testing.T) {
+		codec := codecBuilder()
+
+		d := document.NewFieldBuffer().
+			Add("a-very-long-field-name", document.NewIntegerValue(10)).
+			Add("name", document.NewTextValue("john"))
+
+		var buf bytes.Buffer
+		err := codec.NewEncoder(&buf).EncodeDocument(d)
+		require.NoError(t, err)
+
+		// the document is stored without the field names
+		require.NotContains(t, buf.String(), "a-very-long-field-name")
+		_, err = msgpack.NewCodec().NewDocument(buf.Bytes()).GetByField("name")
+		require.Equal(t, document.ErrFieldNotFound, err)
+
+		// another instance of the codec must use the same dictionary
+		ed := codecBuilder().NewDocument(buf.Bytes())
+		v, err := ed.GetByField("a-very-long-field-name")
+		require.NoError(t, err)
+		require.Equal(t, document.NewIntegerValue(10), v)
+		_, err = ed.GetByField("unknown")
+		require.Equal(t, document.ErrFieldNotFound, err)
+
+		data, err := document.MarshalJSON(ed)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"a-very-long-field-name": 10, "name": "john"}`, string(data))
+
+		_, err = st.Get(append([]byte{dictionaryNamePrefix}, "name"...))
+		require.NoError(t, err)
+	})
+}
+
+func newTestOverflowCodec(t *testing.T, maxSize int) func() encoding.Codec {
+	ng := memoryengine.NewEngine()
+	t.Cleanup(func() { ng.Close() })
+
+	tx, err := ng.Begin(true)
+	require.NoError(t, err)
+	t.Cleanup(func() { tx.Rollback() })
+
+	err = tx.CreateStore([]byte("overflow"))
+	require.NoError(t, err)
+	st, err := tx.GetStore([]byte("overflow"))
+	require.NoError(t, err)
+
+	return func() encoding.Codec {
+		return &overflowCodec{st: st, maxSize: maxSize, codec: msgpack.NewCodec()}
+	}
+}
+
+func TestOverflowCodec(t *testing.T) {
+	codecBuilder := newTestOverflowCodec(t, 3)
+
+	encodingtest.TestCodec(t, codecBuilder)
+
+	t.Run("Large values", func(t *testing.T) {
+		codec := codecBuilder()
+
+		d := document.NewFieldBuffer().
+			Add("a", document.NewTextValue("foo")).
+			Add("b", document.NewTextValue("foobar")).
+			Add("c", document.NewIntegerValue(123456)).
+			Add("d", document.NewBlobValue([]byte("barbaz"))).
+			Add("e", document.NewBlobValue([]byte("bar")))
+
+		var buf bytes.Buffer
+		err := codec.NewEncoder(&buf).EncodeDocument(d)
+		require.NoError(t, err)
+
+		// large values are not stored in the document
+		require.NotContains(t, buf.String(), "foobar")
+		require.NotContains(t, buf.String(), "barbaz")
+
+		ed := codec.NewDocument(buf.Bytes())
+		v, err := ed.GetByField("b")
+		require.NoError(t, err)
+		require.Equal(t, document.NewTextValue("foobar"), v)
+		v, err = ed.GetByField("c")
+		require.NoError(t, err)
+		require.Equal(t, document.NewIntegerValue(123456), v)
+
+		// the order of the fields is preserved
+		var fields []string
+		err = ed.Iterate(func(f string, v document.Value) error {
+			fields = append(fields, f)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"a", "b", "c", "d", "e"}, fields)
+
+		// freeing the document removes the large values
+		err = codec.(*overflowCodec).free(buf.Bytes())
+		require.NoError(t, err)
+		_, err = ed.GetByField("b")
+		require.Equal(t, engine.ErrKeyNotFound, err)
+	})
+
+	t.Run("Trailing large values", func(t *testing.T) {
+		codec := codecBuilder()
+
+		d := document.NewFieldBuffer().
+			Add("a", document.NewTextValue("foobar")).
+			Add("b", document.NewTextValue("barbaz"))
+
+		var buf bytes.Buffer
+		err := codec.NewEncoder(&buf).EncodeDocument(d)
+		require.NoError(t, err)
+
+		data, err := document.MarshalJSON(codec.NewDocument(buf.Bytes()))
+		require.NoError(t, err)
+		require.Equal(t, `{"a": "foobar", "b": "barbaz"}`, string(data))
+	})
+}
+
+func TestTableFieldDictionary(t *testing.T) {
+	ng := memoryengine.NewEngine()
+	defer ng.Close()
+
+	db, err := New(ng, Options{Codec: msgpack.NewCodec()})
+	require.NoError(t, err)
+	defer db.Close()
+
+	tx, err := db.Begin(true)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	err = tx.CreateTable("plain", nil)
+	require.NoError(t, err)
+	err = tx.CreateTable("dict", &TableInfo{FieldDictionary: true})
+	require.NoError(t, err)
+
+	// the dictionary is opt-in
+	plain, err := tx.GetTable("plain")
+	require.NoError(t, err)
+	ti, err := plain.Info()
+	require.NoError(t, err)
+	_, err = tx.tx.GetStore(ti.dictionaryStoreName())
+	require.Equal(t, engine.ErrStoreNotFound, err)
+
+	for _, name := range []string{"plain", "dict"} {
+		tb, err := tx.GetTable(name)
+		require.NoError(t, err)
+		_, err = tb.Insert(document.NewFieldBuffer().Add("a", document.NewTextValue("foo")))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tx.Commit())
+
+	// the documents of both tables are decoded by later transactions,
+	// which read the encoding of each table from the catalog
+	tx, err = db.Begin(false)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	for _, name := range []string{"plain", "dict"} {
+		tb, err := tx.GetTable(name)
+		require.NoError(t, err)
+		ti, err := tb.Info()
+		require.NoError(t, err)
+		require.Equal(t, name == "dict", ti.FieldDictionary)
+
+		err = tb.Iterate(func(d document.Document) error {
+			data, err := document.MarshalJSON(d)
+			require.NoError(t, err)
+			require.JSONEq(t, `{"a": "foo"}`, string(data))
+			return nil
+		})
+		require.NoError(t, err)
+	}
+}
+
+func TestTableOverflow(t *testing.T) {
+	ng := memoryengine.NewEngine()
+	defer ng.Close()
+
+	db, err := New(ng, Options{Codec: msgpack.NewCodec(), MaxInlineValueSize: 5})
+	require.NoError(t, err)
+	defer db.Close()
+
+	tx, err := db.Begin(true)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	err = tx.CreateTable("test", nil)
+	require.NoError(t, err)
+	tb, err := tx.GetTable("test")
+	require.NoError(t, err)
+	ti, err := tb.Info()
+	require.NoError(t, err)
+	st, err := tx.tx.GetStore(ti.overflowStoreName())
+	require.NoError(t, err)
+
+	countOverflow := func() int {
+		var i int
+		it := st.NewIterator(engine.IteratorConfig{})
+		defer it.Close()
+		for it.Seek(nil); it.Valid(); it.Next() {
+			i++
+		}
+		return i
+	}
+
+	key, err := tb.Insert(document.NewFieldBuffer().
+		Add("a", document.NewTextValue("hello world")).
+		Add("b", document.NewIntegerValue(1)))
+	require.NoError(t, err)
+	require.Equal(t, 1, countOverflow())
+
+	err = tb.Replace(key, document.NewFieldBuffer().
+		Add("a", document.NewTextValue("hello")).
+		Add("b", document.NewBlobValue([]byte("hello world"))))
+	require.NoError(t, err)
+	require.Equal(t, 1, countOverflow())
+
+	d, err := tb.GetDocument(key)
+	require.NoError(t, err)
+	data, err := document.MarshalJSON(d)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"a": "hello", "b": "aGVsbG8gd29ybGQ="}`, string(data))
+
+	err = tb.Delete(key)
+	require.NoError(t, err)
+	require.Equal(t, 0, countOverflow())
+}
+
+func TestTableMaxInlineValueSize(t *testing.T) {
+	ng := memoryengine.NewEngine()
+	defer ng.Close()
+
+	db, err := New(ng, Options{Codec: msgpack.NewCodec()})
+	require.NoError(t, err)
+	defer db.Close()
+
+	tx, err := db.Begin(true)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	// without threshold, the codec of the table is not wrapped
+	err = tx.CreateTable("inline", nil)
+	require.NoError(t, err)
+	tb, err := tx.GetTable("inline")
+	require.NoError(t, err)
+	_, ok := tb.codec.(*overflowCodec)
+	require.False(t, ok)
+	ti, err := tb.Info()
+	require.NoError(t, err)
+	_, err = tx.tx.GetStore(ti.overflowStoreName())
+	require.Equal(t, engine.ErrStoreNotFound, err)
+
+	err = tx.CreateTable("test", &TableInfo{MaxInlineValueSize: 5})
+	require.NoError(t, err)
+	require.Error(t, tx.CreateTable("invalid", &TableInfo{MaxInlineValueSize: -1}))
+	require.NoError(t, tx.Commit())
+
+	tx, err = db.Begin(true)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	tb, err = tx.GetTable("test")
+	require.NoError(t, err)
+	ti, err = tb.Info()
+	require.NoError(t, err)
+	require.Equal(t, 5, ti.MaxInlineValueSize)
+	st, err := tx.tx.GetStore(ti.overflowStoreName())
+	require.NoError(t, err)
+
+	countOverflow := func() int {
+		var i int
+		it := st.NewIterator(engine.IteratorConfig{})
+		defer it.Close()
+		for it.Seek(nil); it.Valid(); it.Next() {
+			i++
+		}
+		return i
+	}
+
+	tests := []struct {
+		value    document.Value
+		overflow int
+	}{
+		{document.NewTextValue("hello"), 0},
+		{document.NewTextValue("hello!"), 1},
+		{document.NewBlobValue([]byte("hello")), 0},
+		{document.NewBlobValue([]byte("hello!")), 1},
+	}
+
+	for _, test := range tests {
+		key, err := tb.Insert(document.NewFieldBuffer().Add("a", test.value))
+		require.NoError(t, err)
+		require.Equal(t, test.overflow, countOverflow(), test.value)
+
+		d, err := tb.GetDocument(key)
+		require.NoError(t, err)
+		v, err := d.GetByField("a")
+		require.NoError(t, err)
+		require.Equal(t, test.value, v)
+
+		require.NoError(t, tb.Delete(key))
+		require.Equal(t, 0, countOverflow())
+	}
+}
+
+func TestTableInterning(t *testing.T) {
+	ng := memoryengine.NewEngine()
+	defer ng.Close()
+
+	db, err := New(ng, Options{Codec: msgpack.NewCodec(), MaxInlineValueSize: 10})
+	require.NoError(t, err)
+	defer db.Close()
+
+	tx, err := db.Begin(true)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	err = tx.CreateTable("test", &TableInfo{
+		FieldConstraints: []FieldConstraint{
+			{Path: document.ValuePath{{FieldName: "a"}}, Type: document.TextValue},
+		},
+		FieldDictionary: true,
+	})
+	require.NoError(t, err)
+	tb, err := tx.GetTable("test")
+	require.NoError(t, err)
+
+	// the codec of the table wraps the database codec in the overflow,
+	// compact and dictionary codecs
+	oc, ok := tb.codec.(*overflowCodec)
+	require.True(t, ok)
+	cc, ok := oc.codec.(*compactCodec)
+	require.True(t, ok)
+	_, ok = cc.codec.(*dictionaryCodec)
+	require.True(t, ok)
+
+	for i := 0; i < 3; i++ {
+		_, err = tb.Insert(document.NewFieldBuffer().
+			Add("a", document.NewTextValue("foo")).
+			Add("b", document.NewTextValue("bar")).
+			Add("c", document.NewTextValue("hello world")))
+		require.NoError(t, err)
+	}
+
+	stringData := func(v document.Value) uintptr {
+		s := v.V.(string)
+		return *(*uintptr)(unsafe.Pointer(&s))
+	}
+
+	// texts decoded from the positional values and from the remaining fields are shared
+	ptrs := make(map[string]map[uintptr]bool)
+	err = tb.Iterate(func(d document.Document) error {
+		return d.Iterate(func(f string, v document.Value) error {
+			if ptrs[f] == nil {
+				ptrs[f] = make(map[uintptr]bool)
+			}
+			ptrs[f][stringData(v)] = true
+			return nil
+		})
+	})
+	require.NoError(t, err)
+
+	require.Len(t, ptrs["a"], 1)
+	require.Len(t, ptrs["b"], 1)
+	// overflown values are not interned
+	require.Len(t, ptrs["c"], 3)
+}
+
+func TestTableEncoding(t *testing.T) {
+	ng := memoryengine.NewEngine()
+	defer ng.Close()
+
+	db, err := New(ng, Options{Codec: msgpack.NewCodec(), MaxInlineValueSize: 10})
+	require.NoError(t, err)
+	defer db.Close()
+
+	tx, err := db.Begin(true)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	// insert creates a table and inserts the given documents in it
+	insert := func(name string, docs ...*document.FieldBuffer) (*Table, [][]byte) {
+		err := tx.CreateTable(name, &TableInfo{
+			FieldConstraints: []FieldConstraint{
+				{Path: document.ValuePath{{FieldName: "a"}}, Type: document.IntegerValue},
+			},
+			FieldDictionary: true,
+		})
+		require.NoError(t, err)
+		tb, err := tx.GetTable(name)
+		require.NoError(t, err)
+
+		var keys [][]byte
+		for _, d := range docs {
+			k, err := tb.Insert(d)
+			require.NoError(t, err)
+			keys = append(keys, k)
+		}
+		return tb, keys
+	}
+
+	// encoding returns the encoded representation of the document
+	encoding := func(tb *Table, key []byte) (document.Document, string) {
+		d, err := tb.GetDocument(key)
+		require.NoError(t, err)
+		ed, ok := d.(document.EncodedDocument)
+		require.True(t, ok)
+		name, data := ed.Encoding()
+		require.NotEmpty(t, name)
+		return d, name + ":" + string(data)
+	}
+
+	short := document.NewFieldBuffer().
+		Add("a", document.NewIntegerValue(1)).
+		Add("b", document.NewTextValue("bar"))
+	long := document.NewFieldBuffer().
+		Add("a", document.NewIntegerValue(1)).
+		Add("c", document.NewTextValue("hello world"))
+
+	foo, fooKeys := insert("foo", short, short, long, long)
+	bar, barKeys := insert("bar", short)
+
+	d1, e1 := encoding(foo, fooKeys[0])
+	d2, e2 := encoding(foo, fooKeys[1])
+	require.Equal(t, e1, e2)
+
+	// overflown values are stored under different keys
+	_, e3 := encoding(foo, fooKeys[2])
+	_, e4 := encoding(foo, fooKeys[3])
+	require.NotEqual(t, e3, e4)
+
+	// the identifiers of the fields depend on the dictionary of the table
+	_, e := encoding(bar, barKeys[0])
+	require.NotEqual(t, e1, e)
+
+	// documents with the same encoding are compared without being decoded:
+	// once the dictionary is emptied, they can't be decoded anymore.
+	require.NoError(t, tx.tx.CreateStore([]byte("empty")))
+	st, err := tx.tx.GetStore([]byte("empty"))
+	require.NoError(t, err)
+	dict := foo.codec.(*overflowCodec).codec.(*compactCodec).codec.(*dictionaryCodec).dict
+	*dict = *newFieldDictionary(st)
+
+	_, err = document.MarshalJSON(d1)
+	require.Error(t, err)
+
+	c, err := document.CompareDocuments(d1, d2)
+	require.NoError(t, err)
+	require.Zero(t, c)
+}
@@ -0,0 +1,262 @@
+package database
+
+import (
+	"bytes"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/document/encoding/custom"
+	"github.com/genjidb/genji/engine"
+)
+
+// prefix of the names of the stores of columnar tables.
+const columnStorePrefix = 'c'
+
+// columnsStoreName returns the name of the store listing the columns of a columnar table.
+func (ti *TableInfo) columnsStoreName() []byte {
+	return append([]byte{columnStorePrefix}, ti.storeName...)
+}
+
+// columnStoreName returns the name of the store containing the values of the given
+// top-level field for every document of a columnar table.
+func (ti *TableInfo) columnStoreName(field string) []byte {
+	name := append(ti.columnsStoreName(), 0)
+	return append(name, field...)
+}
+
+// columnStore maintains the columns of a columnar table:
+// for each top-level field, a store associating the key of every document
+// containing that field with its value.
+// A store listing the fields is used to find the columns of the table.
+type columnStore struct {
+	tx     engine.Transaction
+	info   *TableInfo
+	fields engine.Store
+	// columns that were already fetched or created.
+	columns map[string]engine.Store
+}
+
+func newColumnStore(tx engine.Transaction, ti *TableInfo) (*columnStore, error) {
+	fields, err := tx.GetStore(ti.columnsStoreName())
+	if err != nil {
+		return nil, err
+	}
+
+	return &columnStore{
+		tx:      tx,
+		info:    ti,
+		fields:  fields,
+		columns: make(map[string]engine.Store),
+	}, nil
+}
+
+// column returns the store of the given field.
+// If it doesn't exist and create is false, it returns nil.
+func (c *columnStore) column(field string, create bool) (engine.Store, error) {
+	if st, ok := c.columns[field]; ok {
+		return st, nil
+	}
+
+	_, err := c.fields.Get([]byte(field))
+	if err == engine.ErrKeyNotFound {
+		if !create {
+			return nil, nil
+		}
+
+		err = c.fields.Put([]byte(field), nil)
+		if err != nil {
+			return nil, err
+		}
+		err = c.tx.CreateStore(c.info.columnStoreName(field))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	st, err := c.tx.GetStore(c.info.columnStoreName(field))
+	if err != nil {
+		return nil, err
+	}
+
+	c.columns[field] = st
+	return st, nil
+}
+
+// listFields returns the fields having a column.
+func (c *columnStore) listFields() ([]string, error) {
+	var fields []string
+
+	it := c.fields.NewIterator(engine.IteratorConfig{})
+	defer it.Close()
+
+	for it.Seek(nil); it.Valid(); it.Next() {
+		fields = append(fields, string(it.Item().Key()))
+	}
+
+	return fields, nil
+}
+
+// put stores the top-level fields of d in their column.
+func (c *columnStore) put(key []byte, d document.Document) error {
+	return d.Iterate(func(field string, v document.Value) error {
+		st, err := c.column(field, true)
+		if err != nil {
+			return err
+		}
+
+		enc, err := custom.EncodeValue(v)
+		if err != nil {
+			return err
+		}
+
+		return st.Put(key, append([]byte{byte(v.Type)}, enc...))
+	})
+}
+
+// delete removes the top-level fields of d from their column.
+func (c *columnStore) delete(key []byte, d document.Document) error {
+	return d.Iterate(func(field string, _ document.Value) error {
+		st, err := c.column(field, false)
+		if err != nil || st == nil {
+			return err
+		}
+
+		err = st.Delete(key)
+		if err == engine.ErrKeyNotFound {
+			return nil
+		}
+		return err
+	})
+}
+
+// truncate removes the values of every column.
+func (c *columnStore) truncate() error {
+	fields, err := c.listFields()
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		st, err := c.column(f, false)
+		if err != nil {
+			return err
+		}
+
+		err = st.Truncate()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// drop deletes the stores of the columns.
+func (c *columnStore) drop() error {
+	fields, err := c.listFields()
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		err = c.tx.DropStore(c.info.columnStoreName(f))
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.tx.DropStore(c.info.columnsStoreName())
+}
+
+// IsColumnar returns whether the table maintains a columnar copy of its documents.
+// See TableInfo.Columnar.
+func (t *Table) IsColumnar() bool {
+	return t.columns != nil
+}
+
+// Columns returns the top-level fields having a column in a columnar table,
+// in lexicographic order. It returns nil if the table is not columnar.
+func (t *Table) Columns() ([]string, error) {
+	if t.columns == nil {
+		return nil, nil
+	}
+
+	return t.columns.listFields()
+}
+
+// IterateColumns goes through all the documents of a columnar table, in the order of their keys,
+// but only reads the given top-level fields from their columns instead of decoding the documents.
+// The documents only contain the fields they have among the selected ones, in the same order.
+// If the table is not columnar, it behaves like Iterate on a table projected on the fields.
+func (t *Table) IterateColumns(fields []string, fn func(d document.Document) error) error {
+	if t.columns == nil {
+		return t.Project(fields...).Iterate(fn)
+	}
+
+	// the keys are read from the table itself, to return the documents
+	// that have none of the fields.
+	it := t.Store.NewIterator(engine.IteratorConfig{})
+	defer it.Close()
+
+	cits := make([]engine.Iterator, len(fields))
+	for i, f := range fields {
+		st, err := t.columns.column(f, false)
+		if err != nil {
+			return err
+		}
+		if st == nil {
+			continue
+		}
+
+		cits[i] = st.NewIterator(engine.IteratorConfig{})
+		defer cits[i].Close()
+		cits[i].Seek(nil)
+	}
+
+	var fb document.FieldBuffer
+	d := encodedDocumentWithKey{Document: &fb}
+
+	for it.Seek(nil); it.Valid(); it.Next() {
+		err := t.tx.checkContext()
+		if err != nil {
+			return err
+		}
+
+		fb.Reset()
+		d.key = it.Item().Key()
+
+		for i, cit := range cits {
+			// columns only contain keys of the table, in the same order,
+			// so the iterator is either positioned on the key or after it.
+			if cit == nil || !cit.Valid() || !bytes.Equal(cit.Item().Key(), d.key) {
+				continue
+			}
+
+			v, err := decodeColumnValue(cit.Item())
+			if err != nil {
+				return err
+			}
+			fb.Add(fields[i], v)
+
+			cit.Next()
+		}
+
+		err = fn(&d)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func decodeColumnValue(item engine.Item) (document.Value, error) {
+	data, err := item.ValueCopy(nil)
+	if err != nil {
+		return document.Value{}, err
+	}
+	if len(data) == 0 {
+		return document.Value{}, errCannotDecode
+	}
+
+	return custom.DecodeValue(document.ValueType(data[0]), data[1:])
+}
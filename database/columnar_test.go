@@ -0,0 +1,129 @@
+package database_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/stretchr/testify/require"
+)
+
+func TestColumnarTable(t *testing.T) {
+	newColumnarTable := func(t *testing.T) (*database.Transaction, *database.Table, func()) {
+		tx, cleanup := newTestDB(t)
+
+		err := tx.CreateTable("test", &database.TableInfo{Columnar: true})
+		require.NoError(t, err)
+		tb, err := tx.GetTable("test")
+		require.NoError(t, err)
+		require.True(t, tb.IsColumnar())
+
+		return tx, tb, cleanup
+	}
+
+	iterateColumns := func(t *testing.T, tb *database.Table, fields ...string) string {
+		var docs []string
+		err := tb.IterateColumns(fields, func(d document.Document) error {
+			data, err := document.MarshalJSON(d)
+			if err != nil {
+				return err
+			}
+			docs = append(docs, string(data))
+			return nil
+		})
+		require.NoError(t, err)
+
+		return "[" + strings.Join(docs, ",") + "]"
+	}
+
+	t.Run("Iterate", func(t *testing.T) {
+		_, tb, cleanup := newColumnarTable(t)
+		defer cleanup()
+
+		for _, d := range []string{`{"a": 1, "b": "x"}`, `{"b": "y", "c": [1, 2]}`, `{"d": {"e": true}}`} {
+			fb, err := document.NewFromJSON([]byte(d))
+			require.NoError(t, err)
+			_, err = tb.Insert(fb)
+			require.NoError(t, err)
+		}
+
+		columns, err := tb.Columns()
+		require.NoError(t, err)
+		require.Equal(t, []string{"a", "b", "c", "d"}, columns)
+
+		require.JSONEq(t, `[{"a": 1, "b": "x"}, {"b": "y"}, {}]`, iterateColumns(t, tb, "a", "b"))
+		require.JSONEq(t, `[{}, {"c": [1, 2]}, {"d": {"e": true}}]`, iterateColumns(t, tb, "c", "d"))
+		require.JSONEq(t, `[{}, {}, {}]`, iterateColumns(t, tb, "unknown"))
+	})
+
+	t.Run("Writes", func(t *testing.T) {
+		_, tb, cleanup := newColumnarTable(t)
+		defer cleanup()
+
+		k1, err := tb.Insert(document.NewFieldBuffer().Add("a", document.NewIntegerValue(1)).Add("b", document.NewIntegerValue(2)))
+		require.NoError(t, err)
+		k2, err := tb.Insert(document.NewFieldBuffer().Add("a", document.NewIntegerValue(3)))
+		require.NoError(t, err)
+
+		err = tb.Replace(k1, document.NewFieldBuffer().Add("a", document.NewIntegerValue(10)))
+		require.NoError(t, err)
+		require.JSONEq(t, `[{"a": 10}, {"a": 3}]`, iterateColumns(t, tb, "a", "b"))
+
+		err = tb.Merge(k2, document.NewFieldBuffer().Add("b", document.NewIntegerValue(4)))
+		require.NoError(t, err)
+		require.JSONEq(t, `[{"a": 10}, {"a": 3, "b": 4}]`, iterateColumns(t, tb, "a", "b"))
+
+		err = tb.Delete(k1)
+		require.NoError(t, err)
+		require.JSONEq(t, `[{"a": 3, "b": 4}]`, iterateColumns(t, tb, "a", "b"))
+
+		err = tb.Truncate()
+		require.NoError(t, err)
+		require.JSONEq(t, `[]`, iterateColumns(t, tb, "a", "b"))
+	})
+
+	t.Run("Drop", func(t *testing.T) {
+		tx, tb, cleanup := newColumnarTable(t)
+		defer cleanup()
+
+		_, err := tb.Insert(document.NewFieldBuffer().Add("a", document.NewIntegerValue(1)))
+		require.NoError(t, err)
+
+		err = tx.DropTable("test")
+		require.NoError(t, err)
+
+		// the columns of the dropped table must not be reused
+		err = tx.CreateTable("test", &database.TableInfo{Columnar: true})
+		require.NoError(t, err)
+		tb, err = tx.GetTable("test")
+		require.NoError(t, err)
+		columns, err := tb.Columns()
+		require.NoError(t, err)
+		require.Empty(t, columns)
+	})
+
+	t.Run("Not columnar", func(t *testing.T) {
+		tb, cleanup := newTestTable(t)
+		defer cleanup()
+
+		require.False(t, tb.IsColumnar())
+		_, err := tb.Insert(document.NewFieldBuffer().Add("a", document.NewIntegerValue(1)).Add("b", document.NewIntegerValue(2)))
+		require.NoError(t, err)
+		require.JSONEq(t, `[{"b": 2}]`, iterateColumns(t, tb, "b"))
+	})
+
+	t.Run("Partitioned", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		err := tx.CreateTable("test", &database.TableInfo{
+			FieldConstraints: []database.FieldConstraint{
+				{Path: parsePath(t, "a"), Type: document.IntegerValue, IsPrimaryKey: true},
+			},
+			Partitioning: &database.Partitioning{Method: database.PartitionByHash, Path: parsePath(t, "a"), Count: 2},
+			Columnar:     true,
+		})
+		require.Error(t, err)
+	})
+}
@@ -3,6 +3,7 @@ package database
 import (
 	"bytes"
 	"encoding/binary"
+	"fmt"
 	"sync"
 
 	"github.com/genjidb/genji/document"
@@ -10,7 +11,11 @@ import (
 	"github.com/genjidb/genji/index"
 )
 
-const storePrefix = 't'
+const (
+	storePrefix           = 't'
+	dictionaryStorePrefix = 'd'
+	overflowStorePrefix   = 'o'
+)
 
 // FieldConstraint describes constraints on a particular field.
 type FieldConstraint struct {
@@ -18,6 +23,55 @@ type FieldConstraint struct {
 	Type         document.ValueType
 	IsPrimaryKey bool
 	IsNotNull    bool
+	// IsUnique reports whether the values of the field must be unique.
+	// The constraint is enforced by a unique index created with the table.
+	IsUnique bool
+	// Check is the expression of the CHECK constraint of the field, if any.
+	Check Expr
+	// DefaultValue is the expression evaluated to set the field
+	// when inserting documents without it, if any.
+	DefaultValue Expr
+}
+
+// An Expr is an expression of a field constraint, like the expression of a CHECK constraint
+// or of a DEFAULT value. Expressions are implemented by the sql packages:
+// the table only stores their SQL representation and uses Database.ParseExpr
+// to parse them back.
+type Expr interface {
+	// Eval evaluates the expression against d, which is nil for DEFAULT values.
+	Eval(tx *Transaction, d document.Document) (document.Value, error)
+	// String returns the SQL representation of the expression.
+	String() string
+}
+
+// storedExpr is an expression read from the catalog,
+// which is parsed the first time it is evaluated.
+type storedExpr struct {
+	sql string
+
+	once sync.Once
+	e    Expr
+	err  error
+}
+
+func (e *storedExpr) Eval(tx *Transaction, d document.Document) (document.Value, error) {
+	e.once.Do(func() {
+		if tx.db.ParseExpr == nil {
+			e.err = fmt.Errorf("cannot parse expression %s", e.sql)
+			return
+		}
+
+		e.e, e.err = tx.db.ParseExpr(e.sql)
+	})
+	if e.err != nil {
+		return document.Value{}, e.err
+	}
+
+	return e.e.Eval(tx, d)
+}
+
+func (e *storedExpr) String() string {
+	return e.sql
 }
 
 // ToDocument returns a document from f.
@@ -28,6 +82,15 @@ func (f *FieldConstraint) ToDocument() document.Document {
 	buf.Add("type", document.NewIntegerValue(int64(f.Type)))
 	buf.Add("is_primary_key", document.NewBoolValue(f.IsPrimaryKey))
 	buf.Add("is_not_null", document.NewBoolValue(f.IsNotNull))
+	if f.IsUnique {
+		buf.Add("is_unique", document.NewBoolValue(true))
+	}
+	if f.Check != nil {
+		buf.Add("check", document.NewTextValue(f.Check.String()))
+	}
+	if f.DefaultValue != nil {
+		buf.Add("default_value", document.NewTextValue(f.DefaultValue.String()))
+	}
 	return buf
 }
 
@@ -60,6 +123,36 @@ func (f *FieldConstraint) ScanDocument(d document.Document) error {
 		return err
 	}
 	f.IsNotNull = v.V.(bool)
+
+	v, err = d.GetByField("is_unique")
+	switch err {
+	case nil:
+		f.IsUnique = v.V.(bool)
+	case document.ErrFieldNotFound:
+	default:
+		return err
+	}
+
+	// constraints created before CHECK constraints and DEFAULT values
+	// were supported have none of these fields
+	v, err = d.GetByField("check")
+	switch err {
+	case nil:
+		f.Check = &storedExpr{sql: v.V.(string)}
+	case document.ErrFieldNotFound:
+	default:
+		return err
+	}
+
+	v, err = d.GetByField("default_value")
+	switch err {
+	case nil:
+		f.DefaultValue = &storedExpr{sql: v.V.(string)}
+	case document.ErrFieldNotFound:
+	default:
+		return err
+	}
+
 	return nil
 }
 
@@ -73,8 +166,33 @@ type TableInfo struct {
 	// if non-zero, this tableInfo has been created during the current transaction.
 	// it will be removed if the transaction is rolled back or set to false if its commited.
 	transactionID int64
+	// if true, documents are encoded using the compact encoding.
+	compactEncoding bool
+	// if true, large values are stored in a separate store.
+	// Tables created before MaxInlineValueSize was stored use the threshold of the database.
+	overflow bool
 
 	FieldConstraints []FieldConstraint
+	// Partitioning of the table, nil if the table is not partitioned.
+	Partitioning *Partitioning
+	// if true, the top-level fields of the documents are also stored in one store per field,
+	// which can be read by queries that only need a few fields of wide documents.
+	// Columnar tables cannot be partitioned.
+	Columnar bool
+	// if true, the table maintains an index of the top-level field names
+	// of its documents, which lists the documents containing a given field
+	// without reading the others.
+	FieldIndex bool
+	// if true, top-level field names are stored in a dictionary
+	// and documents only contain their identifier, which saves space
+	// for tables with many documents sharing the same long field names.
+	FieldDictionary bool
+	// MaxInlineValueSize is the maximum size of the top-level text and blob values
+	// stored along with the other fields of a document. Larger values are stored
+	// in a separate store and only fetched when requested.
+	// If zero when the table is created, the MaxInlineValueSize of the database is used,
+	// and if that is zero too, all values are stored inline.
+	MaxInlineValueSize int
 }
 
 // GetPrimaryKey returns the field constraint of the primary key.
@@ -89,6 +207,18 @@ func (ti *TableInfo) GetPrimaryKey() *FieldConstraint {
 	return nil
 }
 
+// FieldType returns the type of the field constraint of the given path,
+// or 0 if the path has no constraint or if its constraint has no type.
+func (ti *TableInfo) FieldType(path document.ValuePath) document.ValueType {
+	for _, fc := range ti.FieldConstraints {
+		if fc.Path.IsEqual(path) {
+			return fc.Type
+		}
+	}
+
+	return 0
+}
+
 // ToDocument turns ti into a document.
 func (ti *TableInfo) ToDocument() document.Document {
 	buf := document.NewFieldBuffer()
@@ -104,6 +234,21 @@ func (ti *TableInfo) ToDocument() document.Document {
 	buf.Add("field_constraints", document.NewArrayValue(vbuf))
 
 	buf.Add("read_only", document.NewBoolValue(ti.readOnly))
+	buf.Add("compact_encoding", document.NewBoolValue(ti.compactEncoding))
+	buf.Add("field_dictionary", document.NewBoolValue(ti.FieldDictionary))
+	buf.Add("overflow", document.NewBoolValue(ti.overflow))
+	if ti.MaxInlineValueSize > 0 {
+		buf.Add("max_inline_value_size", document.NewIntegerValue(int64(ti.MaxInlineValueSize)))
+	}
+	if ti.Partitioning != nil {
+		buf.Add("partitioning", document.NewDocumentValue(ti.Partitioning.ToDocument()))
+	}
+	if ti.Columnar {
+		buf.Add("columnar", document.NewBoolValue(true))
+	}
+	if ti.FieldIndex {
+		buf.Add("field_index", document.NewBoolValue(true))
+	}
 	return buf
 }
 
@@ -148,9 +293,101 @@ func (ti *TableInfo) ScanDocument(d document.Document) error {
 	}
 
 	ti.readOnly = v.V.(bool)
+
+	// tables created by previous versions don't have this field
+	v, err = d.GetByField("compact_encoding")
+	if err != nil && err != document.ErrFieldNotFound {
+		return err
+	}
+	if err == nil {
+		ti.compactEncoding = v.V.(bool)
+	}
+
+	v, err = d.GetByField("field_dictionary")
+	if err != nil && err != document.ErrFieldNotFound {
+		return err
+	}
+	if err == nil {
+		ti.FieldDictionary = v.V.(bool)
+	}
+
+	v, err = d.GetByField("overflow")
+	if err != nil && err != document.ErrFieldNotFound {
+		return err
+	}
+	if err == nil {
+		ti.overflow = v.V.(bool)
+	}
+
+	ti.MaxInlineValueSize = 0
+	v, err = d.GetByField("max_inline_value_size")
+	if err != nil && err != document.ErrFieldNotFound {
+		return err
+	}
+	if err == nil {
+		ti.MaxInlineValueSize = int(v.V.(int64))
+	}
+
+	ti.Partitioning = nil
+	v, err = d.GetByField("partitioning")
+	if err != nil && err != document.ErrFieldNotFound {
+		return err
+	}
+	if err == nil {
+		ti.Partitioning = new(Partitioning)
+		err = ti.Partitioning.ScanDocument(v.V.(document.Document))
+		if err != nil {
+			return err
+		}
+	}
+
+	ti.Columnar = false
+	v, err = d.GetByField("columnar")
+	if err != nil && err != document.ErrFieldNotFound {
+		return err
+	}
+	if err == nil {
+		ti.Columnar = v.V.(bool)
+	}
+
+	ti.FieldIndex = false
+	v, err = d.GetByField("field_index")
+	if err != nil && err != document.ErrFieldNotFound {
+		return err
+	}
+	if err == nil {
+		ti.FieldIndex = v.V.(bool)
+	}
+
 	return nil
 }
 
+// dictionaryStoreName returns the name of the store containing the field dictionary of the table.
+func (ti *TableInfo) dictionaryStoreName() []byte {
+	return append([]byte{dictionaryStorePrefix}, ti.storeName...)
+}
+
+// overflowStoreName returns the name of the store containing the large values of the table.
+func (ti *TableInfo) overflowStoreName() []byte {
+	return append([]byte{overflowStorePrefix}, ti.storeName...)
+}
+
+// canUseCompactEncoding returns whether the documents of the table can be encoded
+// positionally, which requires at least one field constraint, all of them on top-level fields.
+func (ti *TableInfo) canUseCompactEncoding() bool {
+	if len(ti.FieldConstraints) == 0 {
+		return false
+	}
+
+	for _, fc := range ti.FieldConstraints {
+		if len(fc.Path) != 1 || fc.Path[0].IsArrayIndex() {
+			return false
+		}
+	}
+
+	return true
+}
+
 // tableInfoStore manages table information.
 // It loads table information during database startup
 // and holds it in memory.
@@ -316,6 +553,67 @@ func (t *tableInfoStore) loadAllTableInfo(tx engine.Transaction) error {
 			},
 		},
 	}
+
+	t.tableInfos[procedureStoreName] = TableInfo{
+		storeName: []byte(procedureStoreName),
+		readOnly:  true,
+		FieldConstraints: []FieldConstraint{
+			{
+				Path: document.ValuePath{
+					document.ValuePathFragment{
+						FieldName: "procedure_name",
+					},
+				},
+				IsPrimaryKey: true,
+			},
+		},
+	}
+
+	t.tableInfos[eventStoreName] = TableInfo{
+		storeName: []byte(eventStoreName),
+		readOnly:  true,
+		FieldConstraints: []FieldConstraint{
+			{
+				Path: document.ValuePath{
+					document.ValuePathFragment{
+						FieldName: "event_name",
+					},
+				},
+				IsPrimaryKey: true,
+			},
+		},
+	}
+
+	t.tableInfos[settingStoreName] = TableInfo{
+		storeName: []byte(settingStoreName),
+		readOnly:  true,
+		FieldConstraints: []FieldConstraint{
+			{
+				Path: document.ValuePath{
+					document.ValuePathFragment{
+						FieldName: "name",
+					},
+				},
+				IsPrimaryKey: true,
+			},
+		},
+	}
+
+	t.tableInfos[transactionsTableName] = TableInfo{
+		storeName: []byte(transactionsTableName),
+		readOnly:  true,
+		FieldConstraints: []FieldConstraint{
+			{
+				Path: document.ValuePath{
+					document.ValuePathFragment{
+						FieldName: "id",
+					},
+				},
+				Type:         document.IntegerValue,
+				IsPrimaryKey: true,
+			},
+		},
+	}
 	return nil
 }
 
@@ -360,6 +658,14 @@ func (t *tableInfoStore) GetTableInfo() map[string]TableInfo {
 	return ti
 }
 
+// restore replaces all the table information by the given ones.
+func (t *tableInfoStore) restore(ti map[string]TableInfo) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.tableInfos = ti
+}
+
 // IndexConfig holds the configuration of an index.
 type IndexConfig struct {
 	TableName string
@@ -371,6 +677,14 @@ type IndexConfig struct {
 
 	// If set, the index is typed and only accepts that type
 	Type document.ValueType
+
+	// If set to true, the index was created for the UNIQUE constraint of a field.
+	// It can't be dropped without its table.
+	FieldConstraint bool
+
+	// Statistics about the values of the index, collected by Analyze.
+	// Nil if the index was never analyzed.
+	Stats *index.Stats
 }
 
 // ToDocument creates a document from an IndexConfig.
@@ -384,6 +698,14 @@ func (i *IndexConfig) ToDocument() document.Document {
 	if i.Type != 0 {
 		buf.Add("type", document.NewIntegerValue(int64(i.Type)))
 	}
+	if i.FieldConstraint {
+		buf.Add("field_constraint", document.NewBoolValue(true))
+	}
+	if i.Stats != nil {
+		buf.Add("stats", document.NewDocumentValue(document.NewFieldBuffer().
+			Add("count", document.NewIntegerValue(i.Stats.Count)).
+			Add("distinct", document.NewIntegerValue(i.Stats.Distinct))))
+	}
 	return buf
 }
 
@@ -421,12 +743,67 @@ func (i *IndexConfig) ScanDocument(d document.Document) error {
 		return err
 	}
 	if err == nil {
-		i.Type = document.ValueType(v.V.(int64))
+		t, ok := v.V.(int64)
+		if !ok {
+			return fmt.Errorf("invalid index type: expected integer, got %s", v.Type)
+		}
+		i.Type = document.ValueType(t)
+	}
+
+	v, err = d.GetByField("field_constraint")
+	if err != nil && err != document.ErrFieldNotFound {
+		return err
+	}
+	if err == nil {
+		fc, ok := v.V.(bool)
+		if !ok {
+			return fmt.Errorf("invalid index field_constraint: expected bool, got %s", v.Type)
+		}
+		i.FieldConstraint = fc
+	}
+
+	v, err = d.GetByField("stats")
+	if err != nil && err != document.ErrFieldNotFound {
+		return err
+	}
+	if err == nil {
+		sd, ok := v.V.(document.Document)
+		if !ok {
+			return fmt.Errorf("invalid index stats: expected document, got %s", v.Type)
+		}
+
+		var stats index.Stats
+		stats.Count, err = scanStat(sd, "count")
+		if err != nil {
+			return err
+		}
+
+		stats.Distinct, err = scanStat(sd, "distinct")
+		if err != nil {
+			return err
+		}
+
+		i.Stats = &stats
 	}
 
 	return nil
 }
 
+// scanStat returns the integer value of the given field of the statistics of an index.
+func scanStat(d document.Document, field string) (int64, error) {
+	v, err := d.GetByField(field)
+	if err != nil {
+		return 0, err
+	}
+
+	n, ok := v.V.(int64)
+	if !ok {
+		return 0, fmt.Errorf("invalid index stats %s: expected integer, got %s", field, v.Type)
+	}
+
+	return n, nil
+}
+
 // Index of a table field. Contains information about
 // the index configuration and provides methods to manipulate the index.
 type Index struct {
@@ -434,6 +811,19 @@ type Index struct {
 	Opts IndexConfig
 }
 
+// IsSparse reports whether the documents whose indexed value is null or missing
+// are left out of the index: typed indexes only store values of their type,
+// and, like in SQL, the indexes of UNIQUE fields let any number of documents
+// have a null value. Other indexes store a null value for these documents.
+func (idx *Index) IsSparse() bool {
+	return idx.Opts.Type != 0 || idx.Opts.FieldConstraint
+}
+
+// indexes reports whether v is stored in the index.
+func (idx *Index) indexes(v document.Value) bool {
+	return v.Type != document.NullValue || !idx.IsSparse()
+}
+
 type indexStore struct {
 	db *Database
 	st engine.Store
@@ -6,6 +6,7 @@ import (
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/document/encoding/msgpack"
 	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/genjidb/genji/index"
 	"github.com/stretchr/testify/require"
 )
 
@@ -29,6 +30,19 @@ func TestTableInfo(t *testing.T) {
 	var res TableInfo
 	err := res.ScanDocument(doc)
 	require.NoError(t, err)
+
+	t.Run("With partitioning", func(t *testing.T) {
+		info.Partitioning = &Partitioning{
+			Method: PartitionByRange,
+			Path:   newValuePath("k"),
+			Bounds: []document.Value{document.NewDoubleValue(10), document.NewDoubleValue(20)},
+		}
+
+		var res TableInfo
+		err := res.ScanDocument(info.ToDocument())
+		require.NoError(t, err)
+		require.Equal(t, info.Partitioning, res.Partitioning)
+	})
 }
 
 func TestTableInfoStore(t *testing.T) {
@@ -208,3 +222,73 @@ func TestIndexStore(t *testing.T) {
 		require.Len(t, list, len(idxcfgs)-1)
 	})
 }
+
+func TestTableInfoCompactEncoding(t *testing.T) {
+	tests := []struct {
+		name     string
+		paths    []document.ValuePath
+		expected bool
+	}{
+		{"no constraints", nil, false},
+		{"top-level fields", []document.ValuePath{{{FieldName: "a"}}, {{FieldName: "b"}}}, true},
+		{"nested field", []document.ValuePath{{{FieldName: "a"}}, {{FieldName: "b"}, {FieldName: "c"}}}, false},
+		{"array index", []document.ValuePath{{{FieldName: "a"}, {ArrayIndex: 0}}}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var info TableInfo
+			for _, p := range test.paths {
+				info.FieldConstraints = append(info.FieldConstraints, FieldConstraint{Path: p})
+			}
+
+			require.Equal(t, test.expected, info.canUseCompactEncoding())
+		})
+	}
+}
+
+func TestIndexConfigScanDocument(t *testing.T) {
+	cfg := IndexConfig{
+		TableName:       "test",
+		IndexName:       "idx_test",
+		Path:            newValuePath("k"),
+		Type:            document.IntegerValue,
+		FieldConstraint: true,
+		Stats:           &index.Stats{Count: 10, Distinct: 3},
+	}
+
+	var got IndexConfig
+	err := got.ScanDocument(cfg.ToDocument())
+	require.NoError(t, err)
+	require.Equal(t, cfg, got)
+
+	stats := func(count, distinct document.Value) document.Value {
+		return document.NewDocumentValue(document.NewFieldBuffer().Add("count", count).Add("distinct", distinct))
+	}
+
+	tests := []struct {
+		name  string
+		field string
+		value document.Value
+	}{
+		{"Type", "type", document.NewTextValue("integer")},
+		{"FieldConstraint", "field_constraint", document.NewIntegerValue(1)},
+		{"Stats", "stats", document.NewIntegerValue(1)},
+		{"Stats count", "stats", stats(document.NewTextValue("10"), document.NewIntegerValue(3))},
+		{"Stats distinct", "stats", stats(document.NewIntegerValue(10), document.NewDoubleValue(3))},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var fb document.FieldBuffer
+			err := fb.Copy(cfg.ToDocument())
+			require.NoError(t, err)
+			err = fb.Replace(test.field, test.value)
+			require.NoError(t, err)
+
+			var got IndexConfig
+			err = got.ScanDocument(&fb)
+			require.Error(t, err)
+		})
+	}
+}
@@ -3,14 +3,22 @@ package database
 
 import (
 	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
 	"sync"
 	"sync/atomic"
+	"time"
 
+	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/document/encoding"
 	"github.com/genjidb/genji/engine"
 )
 
 // A Database manages a list of tables in an engine.
+// It is safe for concurrent use by multiple goroutines.
+// Read-only transactions can run concurrently, if supported by the engine,
+// while read-write transactions are run one at a time, according to the BusyMode.
 type Database struct {
 	ng engine.Engine
 
@@ -29,12 +37,204 @@ type Database struct {
 	attachedTransaction *Transaction
 	attachedTxMu        sync.Mutex
 
+	// writer is used as a semaphore to make sure
+	// only one read-write transaction is running at a time.
+	writer chan struct{}
+
+	// txs contains the transactions currently open, by id.
+	txs   map[int64]*Transaction
+	txsMu sync.Mutex
+
+	// now returns the current time and rnd generates random numbers.
+	// See Options.Clock and engine.Deterministic.
+	now func() time.Time
+	rnd *rand.Rand
+
 	// Codec used to encode documents. Defaults to MessagePack.
 	Codec encoding.Codec
+
+	// KeyGenerator used to generate the keys of documents inserted
+	// in tables without primary key. Defaults to SequenceKeyGenerator.
+	KeyGenerator KeyGenerator
+
+	// MaxInlineValueSize is the maximum size of the text and blob values
+	// stored along with the other fields of a document.
+	// Top-level values exceeding it are stored separately and only fetched when requested.
+	// It is used by the tables created without a MaxInlineValueSize of their own.
+	// If zero, all values are stored inline.
+	MaxInlineValueSize int
+
+	// The following fields can be changed at runtime using SetSetting
+	// or PersistSetting, which must be preferred to modifying them directly
+	// once the database is in use.
+	settingsMu sync.RWMutex
+	// names of the settings whose value is stored in the database.
+	persistedSettings map[string]struct{}
+
+	// BusyMode defines what happens when a read-write transaction is started
+	// while another one is running. Defaults to BusyWait.
+	BusyMode BusyMode
+
+	// BusyTimeout is the maximum duration a read-write transaction waits for the running one
+	// to be closed when using BusyWait, after which ErrBusy is returned.
+	// If zero, it waits indefinitely.
+	BusyTimeout time.Duration
+
+	// MaxTransactionAge is the maximum duration a transaction can stay open.
+	// Transactions exceeding it are rolled back the next time they are used
+	// and return an error wrapping ErrTransactionTooOld.
+	// Read-write transactions exceeding it are also rolled back as soon as
+	// another one is waiting to begin, since they would otherwise block it.
+	// If zero, transactions can stay open indefinitely.
+	MaxTransactionAge time.Duration
+
+	// MaxTransactionIdle is the maximum duration a transaction can stay open
+	// without being used to begin an operation, like getting a table.
+	// Transactions exceeding it are rolled back the next time they are used
+	// and return an error wrapping ErrTransactionIdle.
+	// If zero, transactions can stay idle indefinitely.
+	MaxTransactionIdle time.Duration
+
+	// MaxQueryMemory is the approximate number of bytes a statement can hold in memory
+	// while sorting or grouping documents, after which it fails with an error
+	// wrapping ErrMemoryLimitExceeded. If zero, memory is not limited.
+	MaxQueryMemory int64
+
+	// MaxRecursion is the maximum number of iterations of a recursive common table expression,
+	// after which the statement fails with an error wrapping ErrRecursionLimitExceeded.
+	// If zero, DefaultMaxRecursion is used. If negative, the number of iterations is not limited.
+	MaxRecursion int
+
+	// StatementTimeout is the maximum duration of a statement, including the time spent
+	// reading its result, after which it fails with an error wrapping ErrStatementTimeout.
+	// It can be overridden for the statements of a query by SET statement_timeout.
+	// If zero, statements can run indefinitely.
+	StatementTimeout time.Duration
+
+	// ScanPrefetch is the maximum number of documents read ahead by the scans of tables
+	// and indexes, which fetch them from the engine in growing batches instead of one by one,
+	// to hide the latency of remote or compressed engines. See engine.NewPrefetchIterator.
+	// If lower than 2, documents are read one at a time.
+	ScanPrefetch int
+
+	// MaxWriteRate is the maximum number of read-write transactions started per second,
+	// after which Begin fails with ErrThrottled. Up to one second worth of them
+	// can be started at once. If zero, they are not limited.
+	MaxWriteRate int
+
+	// MaxStatementRate is the maximum number of statements run per second,
+	// as counted by ThrottleStatement. If zero, they are not limited.
+	MaxStatementRate int
+
+	// ThrottleTimeout is the maximum duration a transaction or statement waits
+	// for the rate limits to allow it before failing with ErrThrottled.
+	// If zero, it fails immediately.
+	ThrottleTimeout time.Duration
+
+	// IntegerOverflowMode determines the result of the arithmetic operations on integers
+	// that overflow, which are either promoted to doubles or fail with document.ErrIntegerOverflow.
+	// It is copied by the transactions when they begin, see Transaction.SetIntegerOverflowMode.
+	// Defaults to document.OverflowPromote.
+	IntegerOverflowMode document.OverflowMode
+
+	writeLimiter     rateLimiter
+	statementLimiter rateLimiter
+
+	// OpenAttached opens the database located at the given path,
+	// to be attached by AttachPath. If nil, AttachPath returns an error.
+	OpenAttached func(path string) (*Database, error)
+
+	// ParseExpr parses the SQL representation of the expressions of the field constraints,
+	// like CHECK constraints and DEFAULT values, as stored in the catalog. If nil, the expressions
+	// read from the catalog cannot be evaluated and writing to their tables returns an error.
+	ParseExpr func(s string) (Expr, error)
+
+	// attachments contains the attached databases, by name.
+	attachments   map[string]attachment
+	attachmentsMu sync.Mutex
+
+	// tableVersions counts the committed transactions that modified each table.
+	tableVersions   map[string]uint64
+	tableVersionsMu sync.Mutex
+	// closed and reset when a table version is bumped, if requested.
+	tableChanges chan struct{}
+	// incremented every time the catalog is modified, see CatalogVersion.
+	catalogVersion uint64
+
+	// functions registered by OnDDL.
+	ddlListeners ddlListeners
+}
+
+// BusyMode defines the behaviour of the database when a read-write transaction
+// is started while another one is running.
+type BusyMode int
+
+const (
+	// BusyWait blocks until the running read-write transaction is closed,
+	// or until the BusyTimeout is reached.
+	// Waiting transactions are started in the order they were requested.
+	BusyWait BusyMode = iota
+	// BusyError returns ErrBusy immediately.
+	BusyError
+)
+
+// DefaultMaxRecursion is the maximum number of iterations of a recursive
+// common table expression, and of nested procedure calls, if not configured.
+const DefaultMaxRecursion = 1000
+
+// RecursionLimit returns the maximum number of iterations of a recursive
+// common table expression, and of nested procedure calls,
+// or a negative number if it is not limited.
+func (db *Database) RecursionLimit() int {
+	db.settingsMu.RLock()
+	defer db.settingsMu.RUnlock()
+
+	if db.MaxRecursion == 0 {
+		return DefaultMaxRecursion
+	}
+
+	return db.MaxRecursion
 }
 
 type Options struct {
 	Codec encoding.Codec
+	// KeyGenerator is optional. If nil, SequenceKeyGenerator is used.
+	KeyGenerator KeyGenerator
+	// MaxInlineValueSize is optional. If zero, all values of the tables created
+	// without a MaxInlineValueSize of their own are stored inline.
+	MaxInlineValueSize int
+	// BusyMode is optional. Defaults to BusyWait.
+	BusyMode BusyMode
+	// BusyTimeout is optional. If zero, BusyWait waits indefinitely.
+	BusyTimeout time.Duration
+	// MaxTransactionAge is optional. If zero, transactions can stay open indefinitely.
+	MaxTransactionAge time.Duration
+	// MaxTransactionIdle is optional. If zero, transactions can stay idle indefinitely.
+	MaxTransactionIdle time.Duration
+	// MaxQueryMemory is optional. If zero, memory is not limited.
+	MaxQueryMemory int64
+	// MaxRecursion is optional. If zero, DefaultMaxRecursion is used.
+	MaxRecursion int
+	// StatementTimeout is optional. If zero, statements can run indefinitely.
+	StatementTimeout time.Duration
+	// ScanPrefetch is optional. If zero, scans don't read ahead.
+	ScanPrefetch int
+	// MaxWriteRate is optional. If zero, read-write transactions are not throttled.
+	MaxWriteRate int
+	// MaxStatementRate is optional. If zero, statements are not throttled.
+	MaxStatementRate int
+	// ThrottleTimeout is optional. If zero, throttled operations fail immediately.
+	ThrottleTimeout time.Duration
+	// IntegerOverflowMode is optional. Defaults to document.OverflowPromote.
+	IntegerOverflowMode document.OverflowMode
+	// Clock is optional. If nil, the clock of the engine is used if it implements
+	// engine.Deterministic, otherwise the system one.
+	Clock Clock
+	// OpenAttached is optional. If nil, databases can't be attached by path.
+	OpenAttached func(path string) (*Database, error)
+	// ParseExpr is optional. If nil, the tables with CHECK constraints or DEFAULT values
+	// read from the catalog cannot be written to.
+	ParseExpr func(s string) (Expr, error)
 }
 
 // New initializes the DB using the given engine.
@@ -43,10 +243,33 @@ func New(ng engine.Engine, opts Options) (*Database, error) {
 		return nil, errors.New("missing codec")
 	}
 
+	if opts.KeyGenerator == nil {
+		opts.KeyGenerator = SequenceKeyGenerator
+	}
+
 	db := Database{
-		ng:    ng,
-		Codec: opts.Codec,
+		ng:                  ng,
+		Codec:               opts.Codec,
+		KeyGenerator:        opts.KeyGenerator,
+		MaxInlineValueSize:  opts.MaxInlineValueSize,
+		BusyMode:            opts.BusyMode,
+		BusyTimeout:         opts.BusyTimeout,
+		MaxTransactionAge:   opts.MaxTransactionAge,
+		MaxTransactionIdle:  opts.MaxTransactionIdle,
+		MaxQueryMemory:      opts.MaxQueryMemory,
+		MaxRecursion:        opts.MaxRecursion,
+		StatementTimeout:    opts.StatementTimeout,
+		ScanPrefetch:        opts.ScanPrefetch,
+		MaxWriteRate:        opts.MaxWriteRate,
+		MaxStatementRate:    opts.MaxStatementRate,
+		ThrottleTimeout:     opts.ThrottleTimeout,
+		IntegerOverflowMode: opts.IntegerOverflowMode,
+		OpenAttached:        opts.OpenAttached,
+		ParseExpr:           opts.ParseExpr,
+		writer:              make(chan struct{}, 1),
+		txs:                 make(map[int64]*Transaction),
 	}
+	db.initEntropy(opts.Clock)
 
 	ntx, err := db.ng.Begin(true)
 	if err != nil {
@@ -59,16 +282,35 @@ func New(ng engine.Engine, opts Options) (*Database, error) {
 		return nil, err
 	}
 
+	// persisted settings override the options
+	applySettings, err := db.readPersistedSettings(ntx)
+	if err != nil {
+		return nil, err
+	}
+	applySettings()
+
 	db.tableInfoStore, err = newTableInfoStore(&db, ntx)
 	if err != nil {
 		return nil, err
 	}
 
+	upgrade, err := db.checkKeyFormat(ntx)
+	if err != nil {
+		return nil, err
+	}
+
 	err = ntx.Commit()
 	if err != nil {
 		return nil, err
 	}
 
+	if upgrade {
+		err = db.upgradeKeyFormat()
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &db, nil
 }
 
@@ -85,11 +327,40 @@ func (db *Database) initInternalStores(tx engine.Transaction) error {
 	if err == engine.ErrStoreNotFound {
 		err = tx.CreateStore([]byte(indexStoreName))
 	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.GetStore([]byte(procedureStoreName))
+	if err == engine.ErrStoreNotFound {
+		err = tx.CreateStore([]byte(procedureStoreName))
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.GetStore([]byte(eventStoreName))
+	if err == engine.ErrStoreNotFound {
+		err = tx.CreateStore([]byte(eventStoreName))
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.GetStore([]byte(settingStoreName))
+	if err == engine.ErrStoreNotFound {
+		err = tx.CreateStore([]byte(settingStoreName))
+	}
 	return err
 }
 
-// Close the underlying engine.
+// Close the underlying engine and the databases attached by path.
 func (db *Database) Close() error {
+	err := db.closeAttachments()
+	if err != nil {
+		return err
+	}
+
 	return db.ng.Close()
 }
 
@@ -110,28 +381,61 @@ func (db *Database) BeginTx(opts *TxOptions) (*Transaction, error) {
 		opts = new(TxOptions)
 	}
 
-	db.attachedTxMu.Lock()
-	defer db.attachedTxMu.Unlock()
+	tx := Transaction{
+		db:             db,
+		writable:       !opts.ReadOnly,
+		tableInfoStore: db.tableInfoStore,
+		statement:      opts.Statement,
+	}
 
-	if db.attachedTransaction != nil {
-		return nil, errors.New("cannot open a transaction within a transaction")
+	if db.GetAttachedTx() != nil {
+		return nil, errTxInProgress
+	}
+
+	// waiting for the writer must be done without holding attachedTxMu,
+	// which is required to close the running transaction
+	if tx.writable {
+		err := db.throttleWrite()
+		if err != nil {
+			return nil, err
+		}
+
+		err = db.acquireWriter()
+		if err != nil {
+			return nil, err
+		}
+		tx.releaseWriter = db.releaseWriter
 	}
 
-	ntx, err := db.ng.Begin(!opts.ReadOnly)
+	// so is beginning the transaction of the engine, which may wait
+	// for the running transactions to be closed
+	var err error
+	tx.tx, err = db.ng.Begin(tx.writable)
 	if err != nil {
+		tx.release()
 		return nil, err
 	}
 
-	tx := Transaction{
-		id:             atomic.AddInt64(&db.lastTransactionID, 1),
-		db:             db,
-		tx:             ntx,
-		writable:       !opts.ReadOnly,
-		tableInfoStore: db.tableInfoStore,
+	db.attachedTxMu.Lock()
+	defer db.attachedTxMu.Unlock()
+
+	if db.attachedTransaction != nil {
+		tx.tx.Rollback()
+		tx.release()
+		return nil, errTxInProgress
+	}
+
+	tx.id = atomic.AddInt64(&db.lastTransactionID, 1)
+
+	if tx.writable {
+		tx.journal = &journal{Transaction: tx.tx, memory: NewMemoryAccount(db.QueryMemoryLimit())}
+		tx.tx = tx.journal
 	}
 
 	tx.indexStore, err = tx.getIndexStore()
 	if err != nil {
+		tx.tx.Rollback()
+		tx.release()
 		return nil, err
 	}
 
@@ -139,9 +443,182 @@ func (db *Database) BeginTx(opts *TxOptions) (*Transaction, error) {
 		db.attachedTransaction = &tx
 	}
 
+	tx.overflowMode = db.DefaultIntegerOverflowMode()
+
+	tx.startedAt = db.Now()
+	tx.lastUsed = tx.startedAt.UnixNano()
+	db.txsMu.Lock()
+	db.txs[tx.id] = &tx
+	db.txsMu.Unlock()
+
 	return &tx, nil
 }
 
+// untrack removes tx from the list of open transactions.
+func (db *Database) untrack(tx *Transaction) {
+	db.txsMu.Lock()
+	delete(db.txs, tx.id)
+	db.txsMu.Unlock()
+}
+
+// TxInfo describes an open transaction.
+type TxInfo struct {
+	ID        int64
+	Writable  bool
+	Attached  bool
+	StartedAt time.Time
+	// LastUsedAt is the last time the transaction was used to begin an operation.
+	LastUsedAt time.Time
+	// Statement that started the transaction, if known.
+	Statement string
+	// Killed is true if the transaction has been killed
+	// but not used since, and is thus still open.
+	Killed bool
+}
+
+// Transactions returns information about the transactions currently open,
+// ordered by id.
+// They can also be queried using the read-only __genji_transactions table.
+func (db *Database) Transactions() []TxInfo {
+	attached := db.GetAttachedTx()
+
+	db.txsMu.Lock()
+	infos := make([]TxInfo, 0, len(db.txs))
+	for _, tx := range db.txs {
+		infos = append(infos, TxInfo{
+			ID:         tx.id,
+			Writable:   tx.writable,
+			Attached:   tx == attached,
+			StartedAt:  tx.startedAt,
+			LastUsedAt: time.Unix(0, atomic.LoadInt64(&tx.lastUsed)),
+			Statement:  tx.statement,
+			Killed:     atomic.LoadInt32(&tx.killed) != 0,
+		})
+	}
+	db.txsMu.Unlock()
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ID < infos[j].ID
+	})
+
+	return infos
+}
+
+// KillTransaction aborts the open transaction with the given id, as listed by Transactions.
+// Since transactions are not thread safe, it is rolled back by the next operation using it,
+// which returns an error wrapping ErrTransactionKilled.
+// It returns an error wrapping ErrTransactionNotFound if there is no such transaction.
+func (db *Database) KillTransaction(id int64) error {
+	db.txsMu.Lock()
+	defer db.txsMu.Unlock()
+
+	tx, ok := db.txs[id]
+	if !ok {
+		return fmt.Errorf("%w: %d", ErrTransactionNotFound, id)
+	}
+
+	atomic.StoreInt32(&tx.killed, 1)
+	return nil
+}
+
+var errTxInProgress = NewError(CodeActiveTransaction, "cannot open a transaction within a transaction")
+
+// acquireWriter makes sure no other read-write transaction is running,
+// by waiting or returning ErrBusy depending on the BusyMode and BusyTimeout.
+// The read-write transaction running for longer than MaxTransactionAge is aborted,
+// even if it isn't used anymore.
+func (db *Database) acquireWriter() error {
+	select {
+	case db.writer <- struct{}{}:
+		return nil
+	default:
+	}
+
+	db.settingsMu.RLock()
+	mode, timeout := db.BusyMode, db.BusyTimeout
+	db.settingsMu.RUnlock()
+
+	var busy <-chan time.Time
+	if mode != BusyError && timeout > 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		busy = t.C
+	}
+
+	for {
+		deadline := db.abortExpiredWriters()
+
+		if mode == BusyError {
+			select {
+			case db.writer <- struct{}{}:
+				return nil
+			default:
+				return ErrBusy
+			}
+		}
+
+		// wait until the running read-write transaction expires, if it does
+		var expired <-chan time.Time
+		if !deadline.IsZero() {
+			// the clock of the database may not be the wall clock
+			d := deadline.Sub(db.Now())
+			if d < time.Millisecond {
+				d = time.Millisecond
+			}
+			t := time.NewTimer(d)
+			defer t.Stop()
+			expired = t.C
+		}
+
+		select {
+		case db.writer <- struct{}{}:
+			return nil
+		case <-busy:
+			return ErrBusy
+		case <-expired:
+		}
+	}
+}
+
+// abortExpiredWriters aborts the read-write transactions open for longer than MaxTransactionAge.
+// Otherwise, they would only be aborted once used again and would hold the writer until then.
+// It returns the time at which the remaining ones expire, or the zero time if there is
+// no maximum age or no read-write transaction.
+func (db *Database) abortExpiredWriters() time.Time {
+	db.settingsMu.RLock()
+	max := db.MaxTransactionAge
+	db.settingsMu.RUnlock()
+
+	if max <= 0 {
+		return time.Time{}
+	}
+
+	// the transactions are aborted without holding txsMu,
+	// which is required to untrack them
+	var writers []*Transaction
+	db.txsMu.Lock()
+	for _, tx := range db.txs {
+		if tx.writable {
+			writers = append(writers, tx)
+		}
+	}
+	db.txsMu.Unlock()
+
+	var next time.Time
+	for _, tx := range writers {
+		deadline := tx.expire(max)
+		if !deadline.IsZero() && (next.IsZero() || deadline.Before(next)) {
+			next = deadline
+		}
+	}
+
+	return next
+}
+
+func (db *Database) releaseWriter() {
+	<-db.writer
+}
+
 // TxOptions are passed to Begin to configure transactions.
 type TxOptions struct {
 	// Open a read-only transaction.
@@ -150,6 +627,9 @@ type TxOptions struct {
 	// Any queries run by the database will use that transaction until it is
 	// rolled back or commited.
 	Attached bool
+	// Statement that starts the transaction. It is optional
+	// and only used for diagnostics.
+	Statement string
 }
 
 // GetAttachedTx returns the transaction attached to the database. It returns nil if there is no
@@ -0,0 +1,100 @@
+package database
+
+import "sync"
+
+// Operations of a DDLChange.
+const (
+	DDLCreate = "CREATE"
+	DDLAlter  = "ALTER"
+	DDLDrop   = "DROP"
+)
+
+// Types of the objects modified by a DDLChange.
+const (
+	DDLTable     = "TABLE"
+	DDLIndex     = "INDEX"
+	DDLEvent     = "EVENT"
+	DDLProcedure = "PROCEDURE"
+)
+
+// A DDLChange describes a change made to the catalog of the database.
+type DDLChange struct {
+	// Operation is one of DDLCreate, DDLAlter or DDLDrop.
+	Operation string
+	// ObjectType is one of DDLTable, DDLIndex, DDLEvent or DDLProcedure.
+	ObjectType string
+	// Name of the object.
+	Name string
+	// NewName is the new name of a renamed table, empty otherwise.
+	NewName string
+}
+
+type ddlListener struct {
+	id int
+	fn func(DDLChange)
+}
+
+type ddlListeners struct {
+	mu        sync.Mutex
+	lastID    int
+	listeners []ddlListener
+}
+
+// OnDDL registers fn to be called for every change made to the catalog by a transaction,
+// once that transaction is committed. Changes are reported in the order they were made,
+// and listeners in the order they were registered. Dropping a table also reports
+// the drop of each of its indexes.
+// fn is called synchronously by the goroutine committing the transaction,
+// after the database locks have been released: it must not block for long,
+// but it may start new transactions.
+// The returned function unregisters fn and can be called multiple times.
+func (db *Database) OnDDL(fn func(DDLChange)) (cancel func()) {
+	l := &db.ddlListeners
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.lastID++
+	id := l.lastID
+	l.listeners = append(l.listeners, ddlListener{id: id, fn: fn})
+
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		for i, dl := range l.listeners {
+			if dl.id == id {
+				// copy the slice as it may be read by notifyDDL
+				l.listeners = append(l.listeners[:i:i], l.listeners[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+func (db *Database) notifyDDL(changes []DDLChange) {
+	if len(changes) == 0 {
+		return
+	}
+
+	l := &db.ddlListeners
+	l.mu.Lock()
+	listeners := l.listeners
+	l.mu.Unlock()
+
+	for _, c := range changes {
+		for _, dl := range listeners {
+			dl.fn(c)
+		}
+	}
+}
+
+// recordDDL records a change made to the catalog by tx,
+// to notify the listeners when tx is committed.
+func (tx *Transaction) recordDDL(op, objectType, name string) {
+	tx.db.bumpCatalogVersion()
+	tx.ddl = append(tx.ddl, DDLChange{
+		Operation:  op,
+		ObjectType: objectType,
+		Name:       name,
+	})
+}
@@ -0,0 +1,134 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/document/encoding/msgpack"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnDDL(t *testing.T) {
+	newDB := func(t *testing.T) (*database.Database, *[]database.DDLChange) {
+		db, err := database.New(memoryengine.NewEngine(), database.Options{Codec: msgpack.NewCodec()})
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+
+		var changes []database.DDLChange
+		db.OnDDL(func(c database.DDLChange) {
+			changes = append(changes, c)
+		})
+		return db, &changes
+	}
+
+	t.Run("Commit", func(t *testing.T) {
+		db, changes := newDB(t)
+
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		require.NoError(t, tx.CreateTable("foo", nil))
+		require.NoError(t, tx.CreateIndex(database.IndexConfig{
+			IndexName: "idx_foo_a",
+			TableName: "foo",
+			Path:      document.ValuePath{{FieldName: "a"}},
+		}))
+		require.NoError(t, tx.RenameTable("foo", "bar"))
+		require.Empty(t, *changes)
+		require.NoError(t, tx.Commit())
+
+		require.Equal(t, []database.DDLChange{
+			{Operation: database.DDLCreate, ObjectType: database.DDLTable, Name: "foo"},
+			{Operation: database.DDLCreate, ObjectType: database.DDLIndex, Name: "idx_foo_a"},
+			{Operation: database.DDLAlter, ObjectType: database.DDLTable, Name: "foo", NewName: "bar"},
+		}, *changes)
+
+		*changes = nil
+		tx, err = db.Begin(true)
+		require.NoError(t, err)
+		require.NoError(t, tx.DropTable("bar"))
+		require.NoError(t, tx.Commit())
+
+		require.Equal(t, []database.DDLChange{
+			{Operation: database.DDLDrop, ObjectType: database.DDLIndex, Name: "idx_foo_a"},
+			{Operation: database.DDLDrop, ObjectType: database.DDLTable, Name: "bar"},
+		}, *changes)
+	})
+
+	t.Run("Rollback", func(t *testing.T) {
+		db, changes := newDB(t)
+
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		require.NoError(t, tx.CreateTable("foo", nil))
+		require.NoError(t, tx.Rollback())
+		require.Empty(t, *changes)
+
+		// failed operations are not reported
+		tx, err = db.Begin(true)
+		require.NoError(t, err)
+		require.Error(t, tx.DropTable("foo"))
+		require.NoError(t, tx.Commit())
+		require.Empty(t, *changes)
+	})
+
+	t.Run("Savepoint", func(t *testing.T) {
+		db, changes := newDB(t)
+
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		require.NoError(t, tx.CreateTable("foo", nil))
+
+		sp, err := tx.Savepoint()
+		require.NoError(t, err)
+		require.NoError(t, tx.CreateTable("bar", nil))
+		require.NoError(t, sp.Rollback())
+
+		require.NoError(t, tx.Commit())
+		require.Equal(t, []database.DDLChange{
+			{Operation: database.DDLCreate, ObjectType: database.DDLTable, Name: "foo"},
+		}, *changes)
+	})
+
+	t.Run("Cancel", func(t *testing.T) {
+		db, changes := newDB(t)
+
+		var n int
+		cancel := db.OnDDL(func(c database.DDLChange) {
+			n++
+		})
+
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		require.NoError(t, tx.CreateTable("foo", nil))
+		require.NoError(t, tx.Commit())
+		require.Equal(t, 1, n)
+
+		cancel()
+		cancel()
+
+		tx, err = db.Begin(true)
+		require.NoError(t, err)
+		require.NoError(t, tx.DropTable("foo"))
+		require.NoError(t, tx.Commit())
+		require.Equal(t, 1, n)
+		// other listeners are still called
+		require.Len(t, *changes, 2)
+	})
+
+	t.Run("Events and procedures", func(t *testing.T) {
+		db, changes := newDB(t)
+
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		require.NoError(t, tx.CreateProcedure(database.ProcedureConfig{ProcedureName: "p", Body: "SELECT 1"}))
+		require.NoError(t, tx.DropProcedure("p"))
+		require.NoError(t, tx.Commit())
+
+		require.Equal(t, []database.DDLChange{
+			{Operation: database.DDLCreate, ObjectType: database.DDLProcedure, Name: "p"},
+			{Operation: database.DDLDrop, ObjectType: database.DDLProcedure, Name: "p"},
+		}, *changes)
+	})
+}
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/genjidb/genji/engine"
+)
+
+// A Clock returns the current time, as seen by the database.
+// It is used by the NOW() function, to generate ULID keys
+// and to measure the age of the transactions.
+type Clock interface {
+	Now() time.Time
+}
+
+// initEntropy sets the clock and the random number generator of the database,
+// using the ones of the engine if it implements engine.Deterministic.
+// If not nil, clock overrides the one of the engine.
+func (db *Database) initEntropy(clock Clock) {
+	seed := time.Now().UnixNano()
+	db.now = time.Now
+
+	if ng, ok := db.ng.(engine.Deterministic); ok {
+		seed = ng.Seed()
+		db.now = ng.Now
+	}
+
+	if clock != nil {
+		db.now = clock.Now
+	}
+
+	db.rnd = rand.New(&lockedSource{src: rand.NewSource(seed).(rand.Source64)})
+}
+
+// Now returns the current time, as seen by the database.
+// It is provided by the Clock of the options or by the engine if it implements engine.Deterministic.
+func (db *Database) Now() time.Time {
+	return db.now()
+}
+
+// Rand returns the random number generator of the database, which is safe for concurrent use,
+// except for its Read method. It is seeded by the engine if it implements engine.Deterministic,
+// in which case the same numbers are generated every time the database is opened.
+func (db *Database) Rand() *rand.Rand {
+	return db.rnd
+}
+
+// lockedSource is a source of random numbers that is safe for concurrent use.
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source64
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.src.Uint64()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.src.Seed(seed)
+}
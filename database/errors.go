@@ -2,27 +2,281 @@ package database
 
 import (
 	"errors"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/index"
 )
 
 var (
 	// ErrTableNotFound is returned when the targeted table doesn't exist.
-	ErrTableNotFound = errors.New("table not found")
+	ErrTableNotFound = NewError(CodeUndefinedTable, "table not found")
 
 	// ErrTableAlreadyExists is returned when attempting to create a table with the
 	// same name as an existing one.
-	ErrTableAlreadyExists = errors.New("table already exists")
+	ErrTableAlreadyExists = NewError(CodeDuplicateTable, "table already exists")
 
 	// ErrIndexNotFound is returned when the targeted index doesn't exist.
-	ErrIndexNotFound = errors.New("index not found")
+	ErrIndexNotFound = NewError(CodeUndefinedObject, "index not found")
 
 	// ErrIndexAlreadyExists is returned when attempting to create an index with the
 	// same name as an existing one.
-	ErrIndexAlreadyExists = errors.New("index already exists")
+	ErrIndexAlreadyExists = NewError(CodeDuplicateObject, "index already exists")
+
+	// ErrProcedureNotFound is returned when the targeted procedure doesn't exist.
+	ErrProcedureNotFound = NewError(CodeUndefinedFunction, "procedure not found")
+
+	// ErrProcedureAlreadyExists is returned when attempting to create a procedure with the
+	// same name as an existing one.
+	ErrProcedureAlreadyExists = NewError(CodeDuplicateFunction, "procedure already exists")
+
+	// ErrEventNotFound is returned when the targeted event doesn't exist.
+	ErrEventNotFound = NewError(CodeUndefinedObject, "event not found")
+
+	// ErrEventAlreadyExists is returned when attempting to create an event with the
+	// same name as an existing one.
+	ErrEventAlreadyExists = NewError(CodeDuplicateObject, "event already exists")
+
+	// ErrSettingNotFound is returned when the targeted setting doesn't exist.
+	ErrSettingNotFound = NewError(CodeUndefinedObject, "setting not found")
+
+	// ErrInvalidSettingValue is returned when a setting is changed to a value
+	// of the wrong type or out of its range.
+	ErrInvalidSettingValue = NewError(CodeInvalidParameterValue, "invalid setting value")
 
 	// ErrDocumentNotFound is returned when no document is associated with the provided key.
-	ErrDocumentNotFound = errors.New("document not found")
+	ErrDocumentNotFound = NewError(CodeNoData, "document not found")
+
+	// ErrKeyNotFound is returned when no value is associated with the provided key in a KVStore.
+	ErrKeyNotFound = NewError(CodeNoData, "key not found")
 
 	// ErrDuplicateDocument is returned when another document is already associated with a given key, primary key,
-	// or if there is a unique index violation.
-	ErrDuplicateDocument = errors.New("duplicate document")
+	// or if there is a unique index violation. It is wrapped by a ConstraintViolationError
+	// and must be checked with errors.Is.
+	ErrDuplicateDocument = NewError(CodeUniqueViolation, "duplicate document")
+
+	// ErrReadOnlyTable is returned when attempting to modify a read-only table.
+	ErrReadOnlyTable = NewError(CodeReadOnly, "cannot write to read-only table")
+
+	// ErrStatementPanic is returned when a statement panics, for example because of a bug
+	// in a function or in a custom operator. The changes of the statement are rolled back.
+	ErrStatementPanic = NewError(CodeInternalError, "statement panicked")
+
+	// ErrBusy is returned when attempting to start a read-write transaction while another one is running
+	// and the database is configured not to wait, or if the busy timeout is reached.
+	ErrBusy = NewError(CodeLockNotAvailable, "database is busy")
+
+	// ErrTransactionTooOld is returned when using a transaction that has been open for longer
+	// than the maximum transaction age of the database. The transaction is rolled back.
+	ErrTransactionTooOld = NewError(CodeTransactionTimeout, "transaction too old")
+
+	// ErrTransactionIdle is returned when using a transaction that hasn't been used for longer
+	// than the maximum idle duration of the database. The transaction is rolled back.
+	ErrTransactionIdle = NewError(CodeTransactionTimeout, "transaction idle for too long")
+
+	// ErrTransactionKilled is returned when using a transaction that has been killed
+	// using KillTransaction or the KILL statement. The transaction is rolled back.
+	ErrTransactionKilled = NewError(CodeQueryCanceled, "transaction killed")
+
+	// ErrThrottled is returned when starting a read-write transaction or running a statement
+	// would exceed the rate limits of the database.
+	ErrThrottled = NewError(CodeConfigurationLimitExceeded, "too many requests, throttled")
+
+	// ErrStatementTimeout is returned when a statement runs for longer than
+	// the statement timeout of the database or of its query.
+	ErrStatementTimeout = NewError(CodeQueryCanceled, "statement timeout")
+
+	// ErrTransactionNotFound is returned when the targeted transaction is not open.
+	ErrTransactionNotFound = NewError(CodeUndefinedObject, "transaction not found")
+
+	// ErrSavepointNotFound is returned when rolling back to or releasing a savepoint
+	// that doesn't exist in the transaction.
+	ErrSavepointNotFound = NewError(CodeInvalidSavepoint, "savepoint not found")
+
+	// ErrMemoryLimitExceeded is returned when a statement holds more memory than
+	// the maximum allowed by the database.
+	ErrMemoryLimitExceeded = NewError(CodeOutOfMemory, "memory limit exceeded")
+
+	// ErrRecursionLimitExceeded is returned when a recursive common table expression
+	// iterates more times than allowed by the database, or when procedure calls
+	// are nested deeper than that.
+	ErrRecursionLimitExceeded = NewError(CodeProgramLimitExceeded, "recursion limit exceeded")
+
+	// ErrStatementNotAllowed is returned when running a statement whose type
+	// has been disallowed by the configuration of the database.
+	ErrStatementNotAllowed = NewError(CodeInsufficientPrivilege, "statement not allowed")
 )
+
+// A Code is a machine-readable error code, for use by drivers and servers.
+// Codes are five characters long and follow the SQLSTATE conventions:
+// the first two characters define the class of the error.
+type Code string
+
+// Error codes.
+const (
+	CodeInternalError                Code = "XX000"
+	CodeNoData                       Code = "02000"
+	CodeFeatureNotSupported          Code = "0A000"
+	CodeNumericValueOutOfRange       Code = "22003"
+	CodeInvalidParameterValue        Code = "22023"
+	CodeIntegrityConstraintViolation Code = "23000"
+	CodeNotNullViolation             Code = "23502"
+	CodeUniqueViolation              Code = "23505"
+	CodeCheckViolation               Code = "23514"
+	CodeInvalidCursorState           Code = "24000"
+	CodeActiveTransaction            Code = "25001"
+	CodeDependentObjectsStillExist   Code = "2BP01"
+	CodeInvalidSavepoint             Code = "3B001"
+	CodeReadOnly                     Code = "25006"
+	CodeNoActiveTransaction          Code = "25P01"
+	CodeTransactionTimeout           Code = "25P03"
+	CodeSerializationFailure         Code = "40001"
+	CodeInsufficientPrivilege        Code = "42501"
+	CodeSyntaxError                  Code = "42601"
+	CodeInvalidName                  Code = "42602"
+	CodeUndefinedObject              Code = "42704"
+	CodeDatatypeMismatch             Code = "42804"
+	CodeUndefinedFunction            Code = "42883"
+	CodeUndefinedTable               Code = "42P01"
+	CodeUndefinedParameter           Code = "42P02"
+	CodeDuplicateTable               Code = "42P07"
+	CodeInvalidObjectDefinition      Code = "42P17"
+	CodeDuplicateObject              Code = "42710"
+	CodeDuplicateAlias               Code = "42712"
+	CodeAmbiguousColumn              Code = "42702"
+	CodeDuplicateFunction            Code = "42723"
+	CodeOutOfMemory                  Code = "53200"
+	CodeConfigurationLimitExceeded   Code = "53400"
+	CodeProgramLimitExceeded         Code = "54000"
+	CodeQueryCanceled                Code = "57014"
+	CodeObjectNotInPrerequisiteState Code = "55000"
+	CodeLockNotAvailable             Code = "55P03"
+)
+
+// Class returns the class of the code, made of its first two characters.
+func (c Code) Class() string {
+	if len(c) < 2 {
+		return ""
+	}
+
+	return string(c[:2])
+}
+
+// A Coder is an error carrying a machine-readable code.
+type Coder interface {
+	error
+
+	Code() Code
+}
+
+// foreignCodes are the codes of the errors exported by the packages
+// the database depends on, which can't implement Coder.
+var foreignCodes = []struct {
+	err  error
+	code Code
+}{
+	{engine.ErrTransactionReadOnly, CodeReadOnly},
+	{engine.ErrStoreNotFound, CodeUndefinedObject},
+	{engine.ErrStoreAlreadyExists, CodeDuplicateObject},
+	{engine.ErrKeyNotFound, CodeNoData},
+	{engine.ErrTransactionConflict, CodeSerializationFailure},
+	{document.ErrFieldNotFound, CodeNoData},
+	{document.ErrValueNotFound, CodeNoData},
+	{document.ErrStreamClosed, CodeObjectNotInPrerequisiteState},
+	{document.ErrIntegerOverflow, CodeNumericValueOutOfRange},
+	{index.ErrDuplicate, CodeUniqueViolation},
+}
+
+// CodeOf returns the code of the first error of the chain implementing Coder,
+// or of the first one exported by the packages the database depends on, like engine.ErrTransactionConflict.
+// It returns CodeInternalError if there are none, or an empty code if err is nil.
+func CodeOf(err error) Code {
+	if err == nil {
+		return ""
+	}
+
+	var c Coder
+	if errors.As(err, &c) {
+		return c.Code()
+	}
+
+	for _, fc := range foreignCodes {
+		if errors.Is(err, fc.err) {
+			return fc.code
+		}
+	}
+
+	return CodeInternalError
+}
+
+// Error is an error with a code.
+// Errors returned by this package can be compared with errors.Is
+// and their code retrieved with CodeOf.
+type Error struct {
+	code Code
+	msg  string
+}
+
+// NewError returns an error with the given code and message.
+func NewError(code Code, msg string) error {
+	return &Error{code: code, msg: msg}
+}
+
+// Error returns the message of the error.
+func (e *Error) Error() string {
+	return e.msg
+}
+
+// Code returns the code of the error.
+func (e *Error) Code() Code {
+	return e.code
+}
+
+// Constraints of a table.
+const (
+	ConstraintNotNull    = "NOT NULL"
+	ConstraintPrimaryKey = "PRIMARY KEY"
+	ConstraintCheck      = "CHECK"
+	ConstraintUnique     = "UNIQUE"
+)
+
+// ConstraintViolationError is returned when a document doesn't
+// satisfy a constraint of a table.
+type ConstraintViolationError struct {
+	// Constraint that is violated, e.g. ConstraintNotNull.
+	Constraint string
+	// Path of the value violating the constraint.
+	Path document.ValuePath
+	// Value violating the constraint, if known.
+	// It is only set for ConstraintUnique.
+	Value document.Value
+
+	code Code
+	msg  string
+	err  error
+}
+
+// Error returns the message of the error.
+func (e *ConstraintViolationError) Error() string {
+	return e.msg
+}
+
+// Code returns the code of the error.
+func (e *ConstraintViolationError) Code() Code {
+	return e.code
+}
+
+// Unwrap returns the error wrapped by e, like ErrDuplicateDocument
+// for ConstraintUnique and duplicate primary keys.
+func (e *ConstraintViolationError) Unwrap() error {
+	return e.err
+}
+
+func newConstraintViolationError(code Code, constraint string, path document.ValuePath, msg string) error {
+	return &ConstraintViolationError{
+		Constraint: constraint,
+		Path:       path,
+		code:       code,
+		msg:        msg,
+	}
+}
@@ -0,0 +1,198 @@
+package database_test
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/index"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodeOf(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected database.Code
+	}{
+		{"nil", nil, ""},
+		{"sentinel", database.ErrTableNotFound, database.CodeUndefinedTable},
+		{"wrapped", fmt.Errorf("foo: %w", database.ErrDuplicateDocument), database.CodeUniqueViolation},
+		{"custom", database.NewError(database.CodeSyntaxError, "foo"), database.CodeSyntaxError},
+		{"other", errors.New("foo"), database.CodeInternalError},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, database.CodeOf(test.err))
+		})
+	}
+
+	require.Equal(t, "23", database.CodeUniqueViolation.Class())
+}
+
+func TestErrorCodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected database.Code
+	}{
+		{"ErrTableNotFound", database.ErrTableNotFound, database.CodeUndefinedTable},
+		{"ErrTableAlreadyExists", database.ErrTableAlreadyExists, database.CodeDuplicateTable},
+		{"ErrIndexNotFound", database.ErrIndexNotFound, database.CodeUndefinedObject},
+		{"ErrIndexAlreadyExists", database.ErrIndexAlreadyExists, database.CodeDuplicateObject},
+		{"ErrProcedureNotFound", database.ErrProcedureNotFound, database.CodeUndefinedFunction},
+		{"ErrProcedureAlreadyExists", database.ErrProcedureAlreadyExists, database.CodeDuplicateFunction},
+		{"ErrEventNotFound", database.ErrEventNotFound, database.CodeUndefinedObject},
+		{"ErrEventAlreadyExists", database.ErrEventAlreadyExists, database.CodeDuplicateObject},
+		{"ErrSettingNotFound", database.ErrSettingNotFound, database.CodeUndefinedObject},
+		{"ErrInvalidSettingValue", database.ErrInvalidSettingValue, database.CodeInvalidParameterValue},
+		{"ErrDocumentNotFound", database.ErrDocumentNotFound, database.CodeNoData},
+		{"ErrKeyNotFound", database.ErrKeyNotFound, database.CodeNoData},
+		{"ErrDuplicateDocument", database.ErrDuplicateDocument, database.CodeUniqueViolation},
+		{"ErrReadOnlyTable", database.ErrReadOnlyTable, database.CodeReadOnly},
+		{"ErrStatementPanic", database.ErrStatementPanic, database.CodeInternalError},
+		{"ErrBusy", database.ErrBusy, database.CodeLockNotAvailable},
+		{"ErrTransactionTooOld", database.ErrTransactionTooOld, database.CodeTransactionTimeout},
+		{"ErrTransactionIdle", database.ErrTransactionIdle, database.CodeTransactionTimeout},
+		{"ErrTransactionKilled", database.ErrTransactionKilled, database.CodeQueryCanceled},
+		{"ErrThrottled", database.ErrThrottled, database.CodeConfigurationLimitExceeded},
+		{"ErrStatementTimeout", database.ErrStatementTimeout, database.CodeQueryCanceled},
+		{"ErrTransactionNotFound", database.ErrTransactionNotFound, database.CodeUndefinedObject},
+		{"ErrSavepointNotFound", database.ErrSavepointNotFound, database.CodeInvalidSavepoint},
+		{"ErrMemoryLimitExceeded", database.ErrMemoryLimitExceeded, database.CodeOutOfMemory},
+		{"ErrRecursionLimitExceeded", database.ErrRecursionLimitExceeded, database.CodeProgramLimitExceeded},
+		{"ErrStatementNotAllowed", database.ErrStatementNotAllowed, database.CodeInsufficientPrivilege},
+
+		{"engine.ErrTransactionReadOnly", engine.ErrTransactionReadOnly, database.CodeReadOnly},
+		{"engine.ErrStoreNotFound", engine.ErrStoreNotFound, database.CodeUndefinedObject},
+		{"engine.ErrStoreAlreadyExists", engine.ErrStoreAlreadyExists, database.CodeDuplicateObject},
+		{"engine.ErrKeyNotFound", engine.ErrKeyNotFound, database.CodeNoData},
+		{"engine.ErrTransactionConflict", engine.ErrTransactionConflict, database.CodeSerializationFailure},
+		{"document.ErrFieldNotFound", document.ErrFieldNotFound, database.CodeNoData},
+		{"document.ErrValueNotFound", document.ErrValueNotFound, database.CodeNoData},
+		{"document.ErrStreamClosed", document.ErrStreamClosed, database.CodeObjectNotInPrerequisiteState},
+		{"document.ErrIntegerOverflow", document.ErrIntegerOverflow, database.CodeNumericValueOutOfRange},
+		{"index.ErrDuplicate", index.ErrDuplicate, database.CodeUniqueViolation},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, database.CodeOf(test.err))
+			require.Equal(t, test.expected, database.CodeOf(fmt.Errorf("foo: %w", test.err)))
+		})
+	}
+
+	// every error exported by the package must be listed above
+	tested := make(map[string]bool)
+	for _, test := range tests {
+		tested[test.name] = true
+	}
+
+	pkgs, err := parser.ParseDir(token.NewFileSet(), ".", func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	require.NoError(t, err)
+
+	for _, f := range pkgs["database"].Files {
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.VAR {
+				continue
+			}
+
+			for _, spec := range gd.Specs {
+				for _, name := range spec.(*ast.ValueSpec).Names {
+					if name.IsExported() && strings.HasPrefix(name.Name, "Err") {
+						require.True(t, tested[name.Name], "%s is not tested", name.Name)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestDuplicateDocumentError(t *testing.T) {
+	tx, cleanup := newTestDB(t)
+	defer cleanup()
+
+	path := document.ValuePath{document.ValuePathFragment{FieldName: "a"}}
+	err := tx.CreateTable("test", &database.TableInfo{
+		FieldConstraints: []database.FieldConstraint{
+			{Path: path, IsPrimaryKey: true},
+		},
+	})
+	require.NoError(t, err)
+	err = tx.CreateIndex(database.IndexConfig{TableName: "test", IndexName: "idx_b", Path: parsePath(t, "b"), Unique: true})
+	require.NoError(t, err)
+
+	tb, err := tx.GetTable("test")
+	require.NoError(t, err)
+
+	_, err = tb.Insert(document.NewFieldBuffer().Add("a", document.NewIntegerValue(1)).Add("b", document.NewIntegerValue(1)))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		d          document.Document
+		constraint string
+		path       string
+	}{
+		{"primary key", document.NewFieldBuffer().Add("a", document.NewIntegerValue(1)).Add("b", document.NewIntegerValue(2)), database.ConstraintPrimaryKey, "a"},
+		{"unique index", document.NewFieldBuffer().Add("a", document.NewIntegerValue(2)).Add("b", document.NewIntegerValue(1)), database.ConstraintUnique, "b"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := tb.Insert(test.d)
+			require.True(t, errors.Is(err, database.ErrDuplicateDocument))
+			require.Equal(t, database.CodeUniqueViolation, database.CodeOf(err))
+
+			var cerr *database.ConstraintViolationError
+			require.True(t, errors.As(err, &cerr))
+			require.Equal(t, test.constraint, cerr.Constraint)
+			require.Equal(t, parsePath(t, test.path), cerr.Path)
+		})
+	}
+}
+
+func TestConstraintViolationError(t *testing.T) {
+	tx, cleanup := newTestDB(t)
+	defer cleanup()
+
+	path := document.ValuePath{document.ValuePathFragment{FieldName: "a"}}
+	err := tx.CreateTable("test", &database.TableInfo{
+		FieldConstraints: []database.FieldConstraint{
+			{Path: path, IsNotNull: true},
+		},
+	})
+	require.NoError(t, err)
+
+	tb, err := tx.GetTable("test")
+	require.NoError(t, err)
+
+	_, err = tb.Insert(document.NewFieldBuffer().Add("b", document.NewIntegerValue(1)))
+	var cerr *database.ConstraintViolationError
+	require.True(t, errors.As(err, &cerr))
+	require.Equal(t, database.ConstraintNotNull, cerr.Constraint)
+	require.Equal(t, path, cerr.Path)
+	require.Equal(t, database.CodeNotNullViolation, database.CodeOf(err))
+	require.EqualError(t, err, `field "a" is required and must be not null`)
+
+	err = tx.CreateTable("__genji_foo", nil)
+	require.Equal(t, database.CodeInvalidName, database.CodeOf(err))
+
+	tb, err = tx.GetTable("__genji_tables")
+	require.NoError(t, err)
+	_, err = tb.Insert(document.NewFieldBuffer())
+	require.True(t, errors.Is(err, database.ErrReadOnlyTable))
+	require.Equal(t, database.CodeReadOnly, database.CodeOf(err))
+}
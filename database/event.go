@@ -0,0 +1,161 @@
+package database
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine"
+)
+
+// EventConfig holds the definition of an event, which is a statement
+// run periodically by the scheduler of the database.
+type EventConfig struct {
+	EventName string
+	// Interval between two runs of the statement.
+	Interval time.Duration
+	// Statement is the SQL source of the statement run by the event.
+	Statement string
+}
+
+// ToDocument creates a document from an EventConfig.
+func (e *EventConfig) ToDocument() document.Document {
+	return document.NewFieldBuffer().
+		Add("event_name", document.NewTextValue(e.EventName)).
+		Add("interval", document.NewTextValue(e.Interval.String())).
+		Add("statement", document.NewTextValue(e.Statement))
+}
+
+// ScanDocument implements the document.Scanner interface.
+func (e *EventConfig) ScanDocument(d document.Document) error {
+	v, err := d.GetByField("event_name")
+	if err != nil {
+		return err
+	}
+	e.EventName = v.V.(string)
+
+	v, err = d.GetByField("interval")
+	if err != nil {
+		return err
+	}
+	e.Interval, err = time.ParseDuration(v.V.(string))
+	if err != nil {
+		return err
+	}
+
+	v, err = d.GetByField("statement")
+	if err != nil {
+		return err
+	}
+	e.Statement = v.V.(string)
+
+	return nil
+}
+
+// CreateEvent stores the definition of an event.
+// It returns ErrEventAlreadyExists if an event with the same name exists.
+func (tx *Transaction) CreateEvent(cfg EventConfig) error {
+	if err := tx.checkAge(); err != nil {
+		return err
+	}
+
+	st, err := tx.tx.GetStore([]byte(eventStoreName))
+	if err != nil {
+		return err
+	}
+
+	key := []byte(cfg.EventName)
+	_, err = st.Get(key)
+	if err == nil {
+		return ErrEventAlreadyExists
+	}
+	if err != engine.ErrKeyNotFound {
+		return err
+	}
+
+	var buf bytes.Buffer
+	err = tx.db.Codec.NewEncoder(&buf).EncodeDocument(cfg.ToDocument())
+	if err != nil {
+		return err
+	}
+
+	err = st.Put(key, buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	// the scheduler reloads the events when the version of the table changes
+	tx.markWritten(eventStoreName)
+	tx.recordDDL(DDLCreate, DDLEvent, cfg.EventName)
+	return nil
+}
+
+// DropEvent deletes the definition of an event.
+func (tx *Transaction) DropEvent(name string) error {
+	if err := tx.checkAge(); err != nil {
+		return err
+	}
+
+	st, err := tx.tx.GetStore([]byte(eventStoreName))
+	if err != nil {
+		return err
+	}
+
+	err = st.Delete([]byte(name))
+	if err == engine.ErrKeyNotFound {
+		return ErrEventNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	tx.markWritten(eventStoreName)
+	tx.recordDDL(DDLDrop, DDLEvent, name)
+	return nil
+}
+
+// ListEvents returns the definitions of all the events, sorted by name.
+func (tx *Transaction) ListEvents() ([]EventConfig, error) {
+	if err := tx.checkAge(); err != nil {
+		return nil, err
+	}
+
+	st, err := tx.tx.GetStore([]byte(eventStoreName))
+	if err != nil {
+		return nil, err
+	}
+
+	var events []EventConfig
+	it := st.NewIterator(engine.IteratorConfig{})
+
+	var buf []byte
+	for it.Seek(nil); it.Valid(); it.Next() {
+		buf, err = it.Item().ValueCopy(buf)
+		if err != nil {
+			it.Close()
+			return nil, err
+		}
+
+		var cfg EventConfig
+		err = cfg.ScanDocument(tx.db.Codec.NewDocument(buf))
+		if err != nil {
+			it.Close()
+			return nil, err
+		}
+
+		events = append(events, cfg)
+	}
+
+	err = it.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// EventsVersion returns the version of the table holding the events,
+// which changes every time an event is created or dropped.
+func (db *Database) EventsVersion() uint64 {
+	return db.TableVersion(eventStoreName)
+}
@@ -0,0 +1,96 @@
+package database_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/document/encoding/msgpack"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxEvents(t *testing.T) {
+	e1 := database.EventConfig{EventName: "e1", Interval: time.Hour, Statement: "DELETE FROM test"}
+	e2 := database.EventConfig{EventName: "e2", Interval: 90 * time.Second, Statement: "CALL p()"}
+
+	t.Run("Create, list and drop", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		events, err := tx.ListEvents()
+		require.NoError(t, err)
+		require.Empty(t, events)
+
+		require.NoError(t, tx.CreateEvent(e2))
+		require.NoError(t, tx.CreateEvent(e1))
+
+		err = tx.CreateEvent(e1)
+		require.Equal(t, database.ErrEventAlreadyExists, err)
+
+		events, err = tx.ListEvents()
+		require.NoError(t, err)
+		require.Equal(t, []database.EventConfig{e1, e2}, events)
+
+		err = tx.DropEvent("e1")
+		require.NoError(t, err)
+
+		err = tx.DropEvent("e1")
+		require.Equal(t, database.ErrEventNotFound, err)
+
+		events, err = tx.ListEvents()
+		require.NoError(t, err)
+		require.Equal(t, []database.EventConfig{e2}, events)
+	})
+
+	t.Run("System table", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		err := tx.CreateEvent(e1)
+		require.NoError(t, err)
+
+		tb, err := tx.GetTable("__genji_events")
+		require.NoError(t, err)
+
+		var events []database.EventConfig
+		err = tb.Iterate(func(d document.Document) error {
+			var e database.EventConfig
+			err := e.ScanDocument(d)
+			events = append(events, e)
+			return err
+		})
+		require.NoError(t, err)
+		require.Equal(t, []database.EventConfig{e1}, events)
+
+		err = tb.Delete([]byte("e1"))
+		require.Equal(t, database.ErrReadOnlyTable, err)
+	})
+
+	t.Run("Version", func(t *testing.T) {
+		db, err := database.New(memoryengine.NewEngine(), database.Options{Codec: msgpack.NewCodec()})
+		require.NoError(t, err)
+
+		version := db.EventsVersion()
+		changes := db.TableChanges()
+
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		require.NoError(t, tx.CreateEvent(e1))
+		require.NoError(t, tx.Rollback())
+		require.Equal(t, version, db.EventsVersion())
+
+		tx, err = db.Begin(true)
+		require.NoError(t, err)
+		require.NoError(t, tx.CreateEvent(e1))
+		require.NoError(t, tx.Commit())
+		require.NotEqual(t, version, db.EventsVersion())
+
+		select {
+		case <-changes:
+		default:
+			t.Fatal("expected the changes channel to be closed")
+		}
+	})
+}
@@ -0,0 +1,153 @@
+package database
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine"
+)
+
+// prefix of the names of the stores of field indexes.
+const fieldIndexStorePrefix = 'f'
+
+// fieldIndexStoreName returns the name of the store containing the field index of the table.
+func (ti *TableInfo) fieldIndexStoreName() []byte {
+	return append([]byte{fieldIndexStorePrefix}, ti.storeName...)
+}
+
+// fieldIndex lists the documents containing each top-level field name of a table.
+// Its keys are made of the field name, a zero byte and the key of the document,
+// so that the documents containing a field are stored next to each other,
+// in the order of their keys.
+type fieldIndex struct {
+	st engine.Store
+}
+
+func fieldIndexKey(field string, key []byte) []byte {
+	k := make([]byte, 0, len(field)+1+len(key))
+	k = append(k, field...)
+	k = append(k, 0)
+	return append(k, key...)
+}
+
+// put adds the top-level fields of d to the index.
+func (fi *fieldIndex) put(key []byte, d document.Document) error {
+	return d.Iterate(func(field string, _ document.Value) error {
+		return fi.st.Put(fieldIndexKey(field, key), nil)
+	})
+}
+
+// delete removes the top-level fields of d from the index.
+func (fi *fieldIndex) delete(key []byte, d document.Document) error {
+	return d.Iterate(func(field string, _ document.Value) error {
+		err := fi.st.Delete(fieldIndexKey(field, key))
+		if err == engine.ErrKeyNotFound {
+			return nil
+		}
+		return err
+	})
+}
+
+// fieldNames returns the distinct field names of the index, in lexicographic order.
+func (fi *fieldIndex) fieldNames() ([]string, error) {
+	var names []string
+
+	it := fi.st.NewIterator(engine.IteratorConfig{})
+	defer it.Close()
+
+	for it.Seek(nil); it.Valid(); {
+		k := it.Item().Key()
+		i := bytes.IndexByte(k, 0)
+		if i < 0 {
+			return nil, errCannotDecode
+		}
+
+		name := string(k[:i])
+		names = append(names, name)
+
+		// skip the other documents containing the field
+		it.Seek(append([]byte(name), 1))
+	}
+
+	return names, nil
+}
+
+// HasFieldIndex returns whether the table maintains an index of its field names.
+// See TableInfo.FieldIndex.
+func (t *Table) HasFieldIndex() bool {
+	return t.fieldIndex != nil
+}
+
+// FieldNames returns the distinct top-level field names of the documents of the table,
+// in lexicographic order. If the table doesn't have a field index, every document is read.
+func (t *Table) FieldNames() ([]string, error) {
+	if t.fieldIndex != nil {
+		return t.fieldIndex.fieldNames()
+	}
+
+	set := make(map[string]struct{})
+	err := t.Iterate(func(d document.Document) error {
+		return d.Iterate(func(field string, _ document.Value) error {
+			set[field] = struct{}{}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// IterateField goes through the documents of the table containing the given top-level field,
+// in the order of their keys. If the table has a field index, only these documents are read,
+// otherwise every document is.
+func (t *Table) IterateField(field string, fn func(d document.Document) error) error {
+	if t.fieldIndex == nil {
+		return t.Iterate(func(d document.Document) error {
+			_, err := d.GetByField(field)
+			if err == document.ErrFieldNotFound {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			return fn(d)
+		})
+	}
+
+	prefix := fieldIndexKey(field, nil)
+
+	it := engine.NewPrefetchIterator(t.fieldIndex.st.NewIterator(engine.IteratorConfig{}), t.tx.db.scanPrefetch())
+	defer it.Close()
+
+	var key []byte
+	for it.Seek(prefix); it.Valid(); it.Next() {
+		k := it.Item().Key()
+		if !bytes.HasPrefix(k, prefix) {
+			break
+		}
+
+		// the key of the item is only valid until the next call to Next
+		key = append(key[:0], k[len(prefix):]...)
+		d, err := t.GetDocument(key)
+		if err != nil {
+			return err
+		}
+
+		err = fn(d)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
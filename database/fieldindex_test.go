@@ -0,0 +1,116 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFieldIndex(t *testing.T) {
+	newTable := func(t *testing.T, fieldIndex bool) (*database.Transaction, *database.Table, func()) {
+		tx, cleanup := newTestDB(t)
+
+		err := tx.CreateTable("test", &database.TableInfo{FieldIndex: fieldIndex})
+		require.NoError(t, err)
+		tb, err := tx.GetTable("test")
+		require.NoError(t, err)
+		require.Equal(t, fieldIndex, tb.HasFieldIndex())
+
+		return tx, tb, cleanup
+	}
+
+	iterateField := func(t *testing.T, tb *database.Table, field string) []int64 {
+		var values []int64
+		err := tb.IterateField(field, func(d document.Document) error {
+			v, err := d.GetByField("id")
+			if err != nil {
+				return err
+			}
+			values = append(values, v.V.(int64))
+			return nil
+		})
+		require.NoError(t, err)
+
+		return values
+	}
+
+	insert := func(t *testing.T, tb *database.Table, docs ...string) [][]byte {
+		var keys [][]byte
+		for _, d := range docs {
+			fb, err := document.NewFromJSON([]byte(d))
+			require.NoError(t, err)
+			k, err := tb.Insert(fb)
+			require.NoError(t, err)
+			keys = append(keys, k)
+		}
+		return keys
+	}
+
+	// without a field index, the documents are scanned
+	for name, fieldIndex := range map[string]bool{"Read": true, "Read without index": false} {
+		t.Run(name, func(t *testing.T) {
+			_, tb, cleanup := newTable(t, fieldIndex)
+			defer cleanup()
+
+			insert(t, tb, `{"id": 1, "a": 1}`, `{"id": 2, "b": {"a": 1}}`, `{"id": 3, "a": null, "ab": 2}`)
+
+			names, err := tb.FieldNames()
+			require.NoError(t, err)
+			require.Equal(t, []string{"a", "ab", "b", "id"}, names)
+
+			require.Equal(t, []int64{1, 3}, iterateField(t, tb, "a"))
+			require.Equal(t, []int64{3}, iterateField(t, tb, "ab"))
+			require.Equal(t, []int64{1, 2, 3}, iterateField(t, tb, "id"))
+			require.Empty(t, iterateField(t, tb, "c"))
+		})
+	}
+
+	t.Run("Writes", func(t *testing.T) {
+		_, tb, cleanup := newTable(t, true)
+		defer cleanup()
+
+		keys := insert(t, tb, `{"id": 1, "a": 1}`, `{"id": 2, "b": 1}`)
+
+		err := tb.Replace(keys[0], document.NewFieldBuffer().Add("id", document.NewIntegerValue(1)).Add("c", document.NewIntegerValue(1)))
+		require.NoError(t, err)
+		require.Empty(t, iterateField(t, tb, "a"))
+		require.Equal(t, []int64{1}, iterateField(t, tb, "c"))
+
+		err = tb.Merge(keys[1], document.NewFieldBuffer().Add("c", document.NewIntegerValue(2)))
+		require.NoError(t, err)
+		require.Equal(t, []int64{1, 2}, iterateField(t, tb, "c"))
+
+		err = tb.Delete(keys[0])
+		require.NoError(t, err)
+		require.Equal(t, []int64{2}, iterateField(t, tb, "c"))
+		names, err := tb.FieldNames()
+		require.NoError(t, err)
+		require.Equal(t, []string{"b", "c", "id"}, names)
+
+		err = tb.Truncate()
+		require.NoError(t, err)
+		names, err = tb.FieldNames()
+		require.NoError(t, err)
+		require.Empty(t, names)
+	})
+
+	t.Run("Drop", func(t *testing.T) {
+		tx, tb, cleanup := newTable(t, true)
+		defer cleanup()
+
+		insert(t, tb, `{"id": 1, "a": 1}`)
+
+		err := tx.DropTable("test")
+		require.NoError(t, err)
+
+		err = tx.CreateTable("test", &database.TableInfo{FieldIndex: true})
+		require.NoError(t, err)
+		tb, err = tx.GetTable("test")
+		require.NoError(t, err)
+		names, err := tb.FieldNames()
+		require.NoError(t, err)
+		require.Empty(t, names)
+	})
+}
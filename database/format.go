@@ -0,0 +1,149 @@
+package database
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine"
+)
+
+// KeyFormatVersion is the version of the encoding of the primary keys and of the indexed values,
+// as implemented by the key package. It is stored in the database when it is created,
+// and checked every time it is opened.
+//
+// Version 1 orders integers and doubles by value and -0 right before 0.
+// Databases created before, which have no version, are upgraded when opened:
+// their indexes are rebuilt, but tables whose keys changed can't be upgraded in place
+// and must be dumped with the previous version of Genji and restored.
+const KeyFormatVersion = 1
+
+var (
+	metaStoreName = internalPrefix + "meta"
+	keyFormatKey  = []byte("key_format")
+)
+
+// checkKeyFormat returns true if the database was created with a previous
+// key format and must be upgraded by upgradeKeyFormat.
+// The current version is stored in new databases.
+func (db *Database) checkKeyFormat(tx engine.Transaction) (bool, error) {
+	st, err := tx.GetStore([]byte(metaStoreName))
+	if err == engine.ErrStoreNotFound {
+		err = tx.CreateStore([]byte(metaStoreName))
+		if err != nil {
+			return false, err
+		}
+		st, err = tx.GetStore([]byte(metaStoreName))
+	}
+	if err != nil {
+		return false, err
+	}
+
+	v, err := st.Get(keyFormatKey)
+	if err == engine.ErrKeyNotFound {
+		// databases without tables have nothing to upgrade
+		for name := range db.tableInfoStore.GetTableInfo() {
+			if !IsSystemTable(name) {
+				return true, nil
+			}
+		}
+
+		return false, putKeyFormat(st)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	version, n := binary.Uvarint(v)
+	if n <= 0 {
+		return false, errors.New("invalid key format version")
+	}
+	if version > KeyFormatVersion {
+		return false, NewError(CodeFeatureNotSupported,
+			fmt.Sprintf("the keys of the database use the format version %d, which is newer than the supported version %d", version, KeyFormatVersion))
+	}
+
+	return version < KeyFormatVersion, nil
+}
+
+func putKeyFormat(st engine.Store) error {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], KeyFormatVersion)
+	return st.Put(keyFormatKey, buf[:n])
+}
+
+// errStop stops an iteration early.
+var errStop = errors.New("stop iteration")
+
+// upgradeKeyFormat upgrades a database created before the key format was versioned.
+// Indexes are rebuilt, which is enough unless the primary keys of a table were encoded
+// differently, in which case an error is returned and the database is left untouched.
+func (db *Database) upgradeKeyFormat() error {
+	tx, err := db.Begin(true)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, name := range tx.ListTables() {
+		if IsSystemTable(name) {
+			continue
+		}
+
+		tb, err := tx.GetTable(name)
+		if err != nil {
+			return err
+		}
+		info, err := tb.Info()
+		if err != nil {
+			return err
+		}
+
+		pk := info.GetPrimaryKey()
+		changed := false
+		switch {
+		case pk == nil || pk.Type == document.IntegerValue || pk.Type == document.TextValue ||
+			pk.Type == document.BlobValue || pk.Type == document.BoolValue:
+			// the encoding of these keys is unchanged
+		case pk.Type == document.DoubleValue:
+			// only the encoding of -0 changed, it was 8 zero bytes
+			_, err = tb.GetDocument(make([]byte, 8))
+			if err == nil {
+				changed = true
+			} else if err != ErrDocumentNotFound {
+				return err
+			}
+		default:
+			// numbers are encoded differently when the primary key has no type
+			err = tb.Iterate(func(d document.Document) error {
+				changed = true
+				return errStop
+			})
+			if err != nil && err != errStop {
+				return err
+			}
+		}
+
+		if changed {
+			return NewError(CodeObjectNotInPrerequisiteState,
+				fmt.Sprintf("the primary keys of table %q use a previous format: dump the database with the previous version of Genji and restore it", name))
+		}
+	}
+
+	err = tx.ReIndexAll()
+	if err != nil {
+		return err
+	}
+
+	st, err := tx.tx.GetStore([]byte(metaStoreName))
+	if err != nil {
+		return err
+	}
+	err = putKeyFormat(st)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,152 @@
+package database_test
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/document/encoding/msgpack"
+	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/stretchr/testify/require"
+)
+
+// setKeyFormat stores the given key format version in the database,
+// or removes it if version is 0, as in databases created before it was versioned.
+func setKeyFormat(t *testing.T, ng engine.Engine, version uint64) {
+	tx, err := ng.Begin(true)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	st, err := tx.GetStore([]byte("__genji_meta"))
+	require.NoError(t, err)
+
+	if version == 0 {
+		err = st.Delete([]byte("key_format"))
+	} else {
+		var buf [binary.MaxVarintLen64]byte
+		err = st.Put([]byte("key_format"), buf[:binary.PutUvarint(buf[:], version)])
+	}
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+}
+
+func TestKeyFormat(t *testing.T) {
+	opts := database.Options{Codec: msgpack.NewCodec()}
+
+	// createDB creates a database with a table and an index, and returns the engine
+	// so that the database can be opened again.
+	createDB := func(t *testing.T, pk *database.FieldConstraint) engine.Engine {
+		ng := memoryengine.NewEngine()
+		db, err := database.New(ng, opts)
+		require.NoError(t, err)
+
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		var info database.TableInfo
+		if pk != nil {
+			info.FieldConstraints = []database.FieldConstraint{*pk}
+		}
+		require.NoError(t, tx.CreateTable("test", &info))
+		require.NoError(t, tx.CreateIndex(database.IndexConfig{
+			IndexName: "idx_b",
+			TableName: "test",
+			Path:      parsePath(t, "b"),
+		}))
+
+		tb, err := tx.GetTable("test")
+		require.NoError(t, err)
+		for _, a := range []float64{-1.5, -0.5, 1} {
+			_, err = tb.Insert(document.NewFieldBuffer().
+				Add("a", document.NewDoubleValue(a)).
+				Add("b", document.NewDoubleValue(a)))
+			require.NoError(t, err)
+		}
+		require.NoError(t, tx.Commit())
+
+		return ng
+	}
+
+	verify := func(t *testing.T, db *database.Database) *database.IndexReport {
+		tx, err := db.Begin(false)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		tb, err := tx.GetTable("test")
+		require.NoError(t, err)
+		idx, err := tx.GetIndex("idx_b")
+		require.NoError(t, err)
+		report, err := idx.Verify(tb)
+		require.NoError(t, err)
+		return report
+	}
+
+	t.Run("Current", func(t *testing.T) {
+		ng := createDB(t, nil)
+
+		db, err := database.New(ng, opts)
+		require.NoError(t, err)
+		require.True(t, verify(t, db).OK())
+	})
+
+	t.Run("Newer", func(t *testing.T) {
+		ng := createDB(t, nil)
+		setKeyFormat(t, ng, database.KeyFormatVersion+1)
+
+		_, err := database.New(ng, opts)
+		require.Error(t, err)
+		require.Equal(t, database.CodeFeatureNotSupported, database.CodeOf(err))
+	})
+
+	t.Run("Legacy", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			pk    *database.FieldConstraint
+			fails bool
+		}{
+			{"No primary key", nil, false},
+			{"Double primary key", &database.FieldConstraint{Path: parsePath(t, "a"), Type: document.DoubleValue, IsPrimaryKey: true}, false},
+			{"Untyped primary key", &database.FieldConstraint{Path: parsePath(t, "a"), IsPrimaryKey: true}, true},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				ng := createDB(t, test.pk)
+				// simulate an index entry encoded with the previous format
+				db, err := database.New(ng, opts)
+				require.NoError(t, err)
+				tx, err := db.Begin(true)
+				require.NoError(t, err)
+				idx, err := tx.GetIndex("idx_b")
+				require.NoError(t, err)
+				require.NoError(t, idx.Set(document.NewDoubleValue(2), []byte("unknown")))
+				require.NoError(t, tx.Commit())
+				setKeyFormat(t, ng, 0)
+
+				db, err = database.New(ng, opts)
+				if test.fails {
+					require.Error(t, err)
+					require.Equal(t, database.CodeObjectNotInPrerequisiteState, database.CodeOf(err))
+					return
+				}
+				require.NoError(t, err)
+				require.True(t, verify(t, db).OK(), "%+v", verify(t, db))
+
+				// the version is stored once upgraded
+				tx, err = db.Begin(true)
+				require.NoError(t, err)
+				idx, err = tx.GetIndex("idx_b")
+				require.NoError(t, err)
+				require.NoError(t, idx.Set(document.NewDoubleValue(2), []byte("unknown")))
+				require.NoError(t, tx.Commit())
+
+				db, err = database.New(ng, opts)
+				require.NoError(t, err)
+				require.False(t, verify(t, db).OK())
+			})
+		}
+	})
+}
@@ -0,0 +1,176 @@
+package database
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/genjidb/genji/document"
+)
+
+// A KeyGenerator generates the keys of documents inserted
+// in tables that don't have a primary key.
+type KeyGenerator interface {
+	// NextKey returns the key of a new document of the table.
+	NextKey(t *Table, d document.Document) ([]byte, error)
+	// KeyToValue converts a generated key into a value,
+	// which is returned by the pk() function.
+	KeyToValue(k []byte) (document.Value, error)
+}
+
+var (
+	// SequenceKeyGenerator generates keys from an autoincremented integer,
+	// encoded as an uvarint.
+	// It is the default key generator.
+	SequenceKeyGenerator KeyGenerator = sequenceKeyGenerator{}
+
+	// BinarySequenceKeyGenerator generates keys from an autoincremented integer,
+	// encoded as a fixed size big endian integer.
+	// Keys are bigger than the ones generated by SequenceKeyGenerator
+	// but documents are always stored in insertion order.
+	BinarySequenceKeyGenerator KeyGenerator = binarySequenceKeyGenerator{}
+
+	// RandomKeyGenerator generates 16 bytes random keys, using the random number
+	// generator of the database. They are returned as blobs by the pk() function.
+	RandomKeyGenerator KeyGenerator = randomKeyGenerator{}
+)
+
+type sequenceKeyGenerator struct{}
+
+func (sequenceKeyGenerator) NextKey(t *Table, d document.Document) ([]byte, error) {
+	docid, err := t.Store.NextSequence()
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, docid)
+	return buf[:n], nil
+}
+
+func (sequenceKeyGenerator) KeyToValue(k []byte) (document.Value, error) {
+	i, n := binary.Uvarint(k)
+	if n <= 0 {
+		return document.Value{}, errors.New("cannot decode key")
+	}
+
+	return document.NewIntegerValue(int64(i)), nil
+}
+
+type binarySequenceKeyGenerator struct{}
+
+func (binarySequenceKeyGenerator) NextKey(t *Table, d document.Document) ([]byte, error) {
+	docid, err := t.Store.NextSequence()
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, docid)
+	return buf, nil
+}
+
+func (binarySequenceKeyGenerator) KeyToValue(k []byte) (document.Value, error) {
+	if len(k) != 8 {
+		return document.Value{}, errors.New("cannot decode key")
+	}
+
+	return document.NewIntegerValue(int64(binary.BigEndian.Uint64(k))), nil
+}
+
+type randomKeyGenerator struct{}
+
+func (randomKeyGenerator) NextKey(t *Table, d document.Document) ([]byte, error) {
+	rnd := t.tx.db.Rand()
+
+	buf := make([]byte, 16)
+	binary.BigEndian.PutUint64(buf, rnd.Uint64())
+	binary.BigEndian.PutUint64(buf[8:], rnd.Uint64())
+	return buf, nil
+}
+
+func (randomKeyGenerator) KeyToValue(k []byte) (document.Value, error) {
+	return document.NewBlobValue(k), nil
+}
+
+// NewULIDKeyGenerator returns a generator of ULID keys: 16 bytes made of the number of
+// milliseconds since the Unix epoch, according to the clock of the database, followed by
+// random bytes. Keys generated within the same millisecond by the generator are incremented,
+// so that documents are always stored in insertion order.
+// The keys are returned as texts encoded in Crockford's base32 by the pk() function.
+func NewULIDKeyGenerator() KeyGenerator {
+	return &ulidKeyGenerator{}
+}
+
+type ulidKeyGenerator struct {
+	mu         sync.Mutex
+	lastTime   uint64
+	lastRandom [10]byte
+}
+
+func (g *ulidKeyGenerator) NextKey(t *Table, d document.Document) ([]byte, error) {
+	db := t.tx.db
+	ms := uint64(db.Now().UnixNano() / int64(time.Millisecond))
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if ms <= g.lastTime {
+		// increment the random part of the last key
+		ms = g.lastTime
+		i := len(g.lastRandom) - 1
+		for ; i >= 0; i-- {
+			g.lastRandom[i]++
+			if g.lastRandom[i] != 0 {
+				break
+			}
+		}
+		if i < 0 {
+			return nil, errors.New("too many keys generated within the same millisecond")
+		}
+	} else {
+		rnd := db.Rand()
+		binary.BigEndian.PutUint64(g.lastRandom[:], rnd.Uint64())
+		binary.BigEndian.PutUint16(g.lastRandom[8:], uint16(rnd.Uint64()))
+	}
+	g.lastTime = ms
+
+	buf := make([]byte, 16)
+	binary.BigEndian.PutUint64(buf, ms<<16)
+	copy(buf[6:], g.lastRandom[:])
+	return buf, nil
+}
+
+// crockfordAlphabet is the base32 alphabet used to encode ULIDs.
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+func (g *ulidKeyGenerator) KeyToValue(k []byte) (document.Value, error) {
+	if len(k) != 16 {
+		return document.Value{}, errors.New("cannot decode key")
+	}
+
+	hi, lo := binary.BigEndian.Uint64(k), binary.BigEndian.Uint64(k[8:])
+	var buf [26]byte
+	for i := len(buf) - 1; i >= 0; i-- {
+		buf[i] = crockfordAlphabet[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+
+	return document.NewTextValue(string(buf[:])), nil
+}
+
+// KeyGeneratorFunc turns a function into a KeyGenerator.
+// The keys are returned as blobs by the pk() function.
+type KeyGeneratorFunc func(t *Table, d document.Document) ([]byte, error)
+
+// NextKey calls fn(t, d).
+func (fn KeyGeneratorFunc) NextKey(t *Table, d document.Document) ([]byte, error) {
+	return fn(t, d)
+}
+
+// KeyToValue returns k as a blob value.
+func (fn KeyGeneratorFunc) KeyToValue(k []byte) (document.Value, error) {
+	return document.NewBlobValue(k), nil
+}
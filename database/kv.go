@@ -0,0 +1,182 @@
+package database
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/genjidb/genji/engine"
+)
+
+// A KVStore holds raw key-value pairs in a namespace separated from the tables,
+// for applications that need to store a few values that are not documents.
+// Changes are part of the transaction, like changes made to tables,
+// and are rolled back with it or with its savepoints.
+// A KVStore is only valid for the lifetime of the transaction that returned it.
+type KVStore struct {
+	tx   *Transaction
+	name []byte
+	// nil until the first write, if the namespace doesn't exist yet.
+	st engine.Store
+}
+
+// Store returns the key-value namespace with the given name.
+// The namespace is created by the first write to it.
+func (tx *Transaction) Store(name string) (*KVStore, error) {
+	if err := tx.checkAge(); err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		return nil, NewError(CodeInvalidName, "store name must not be empty")
+	}
+
+	s := KVStore{
+		tx:   tx,
+		name: []byte(kvStorePrefix + name),
+	}
+
+	var err error
+	s.st, err = tx.tx.GetStore(s.name)
+	if err != nil && err != engine.ErrStoreNotFound {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// Get returns a copy of the value associated with k.
+// If k doesn't exist, it returns ErrKeyNotFound.
+func (s *KVStore) Get(k []byte) ([]byte, error) {
+	if err := s.tx.checkAge(); err != nil {
+		return nil, err
+	}
+
+	if s.st == nil {
+		return nil, ErrKeyNotFound
+	}
+
+	v, err := s.st.Get(k)
+	if err == engine.ErrKeyNotFound {
+		return nil, ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte{}, v...), nil
+}
+
+// Put associates v with k, replacing any existing value. k must not be empty.
+func (s *KVStore) Put(k, v []byte) error {
+	if err := s.tx.checkAge(); err != nil {
+		return err
+	}
+
+	if len(k) == 0 {
+		return errors.New("empty key")
+	}
+
+	err := s.create()
+	if err != nil {
+		return err
+	}
+
+	return s.st.Put(k, v)
+}
+
+// Delete the value associated with k.
+// If k doesn't exist, it returns ErrKeyNotFound.
+func (s *KVStore) Delete(k []byte) error {
+	if err := s.tx.checkAge(); err != nil {
+		return err
+	}
+
+	if !s.tx.writable {
+		return engine.ErrTransactionReadOnly
+	}
+
+	if s.st == nil {
+		return ErrKeyNotFound
+	}
+
+	err := s.st.Delete(k)
+	if err == engine.ErrKeyNotFound {
+		return ErrKeyNotFound
+	}
+	return err
+}
+
+// Truncate deletes all the key-value pairs of the namespace.
+func (s *KVStore) Truncate() error {
+	if err := s.tx.checkAge(); err != nil {
+		return err
+	}
+
+	if !s.tx.writable {
+		return engine.ErrTransactionReadOnly
+	}
+
+	if s.st == nil {
+		return nil
+	}
+
+	return s.st.Truncate()
+}
+
+// Iterate calls fn for each key starting with prefix, in lexicographic order.
+// If prefix is empty, all the keys are iterated upon.
+// k and v are only valid until fn returns and must be copied to be kept.
+// If fn returns an error, the iteration stops and the error is returned.
+func (s *KVStore) Iterate(prefix []byte, fn func(k, v []byte) error) error {
+	if err := s.tx.checkAge(); err != nil {
+		return err
+	}
+
+	if s.st == nil {
+		return nil
+	}
+
+	it := s.st.NewIterator(engine.IteratorConfig{})
+	defer it.Close()
+
+	var buf []byte
+	var err error
+	for it.Seek(prefix); it.Valid(); it.Next() {
+		item := it.Item()
+		k := item.Key()
+		if !bytes.HasPrefix(k, prefix) {
+			break
+		}
+
+		buf, err = item.ValueCopy(buf)
+		if err != nil {
+			return err
+		}
+
+		err = fn(k, buf)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// create the namespace if it doesn't exist.
+func (s *KVStore) create() error {
+	if s.st != nil {
+		return nil
+	}
+
+	if !s.tx.writable {
+		return engine.ErrTransactionReadOnly
+	}
+
+	err := s.tx.tx.CreateStore(s.name)
+	if err != nil {
+		return err
+	}
+
+	s.st, err = s.tx.tx.GetStore(s.name)
+	return err
+}
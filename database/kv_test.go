@@ -0,0 +1,153 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document/encoding/msgpack"
+	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKVStore(t *testing.T) {
+	t.Run("Get/Put/Delete", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		st, err := tx.Store("foo")
+		require.NoError(t, err)
+
+		_, err = st.Get([]byte("a"))
+		require.Equal(t, database.ErrKeyNotFound, err)
+		require.Equal(t, database.ErrKeyNotFound, st.Delete([]byte("a")))
+
+		require.NoError(t, st.Put([]byte("a"), []byte("1")))
+		v, err := st.Get([]byte("a"))
+		require.NoError(t, err)
+		require.Equal(t, []byte("1"), v)
+
+		require.NoError(t, st.Put([]byte("a"), []byte("2")))
+		v, err = st.Get([]byte("a"))
+		require.NoError(t, err)
+		require.Equal(t, []byte("2"), v)
+
+		require.NoError(t, st.Delete([]byte("a")))
+		_, err = st.Get([]byte("a"))
+		require.Equal(t, database.ErrKeyNotFound, err)
+
+		require.Error(t, st.Put(nil, []byte("1")))
+		_, err = tx.Store("")
+		require.Error(t, err)
+	})
+
+	t.Run("Namespaces", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		foo, err := tx.Store("foo")
+		require.NoError(t, err)
+		bar, err := tx.Store("bar")
+		require.NoError(t, err)
+
+		require.NoError(t, foo.Put([]byte("a"), []byte("1")))
+		_, err = bar.Get([]byte("a"))
+		require.Equal(t, database.ErrKeyNotFound, err)
+
+		// namespaces are not tables
+		require.NoError(t, tx.CreateTable("foo", nil))
+		for _, name := range tx.ListTables() {
+			require.NotContains(t, name, "kv_")
+		}
+	})
+
+	t.Run("Iterate", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		st, err := tx.Store("foo")
+		require.NoError(t, err)
+
+		for _, k := range []string{"b2", "a", "b1", "c"} {
+			require.NoError(t, st.Put([]byte(k), []byte("v"+k)))
+		}
+
+		var keys, values []string
+		err = st.Iterate([]byte("b"), func(k, v []byte) error {
+			keys = append(keys, string(k))
+			values = append(values, string(v))
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"b1", "b2"}, keys)
+		require.Equal(t, []string{"vb1", "vb2"}, values)
+
+		keys = nil
+		err = st.Iterate(nil, func(k, v []byte) error {
+			keys = append(keys, string(k))
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"a", "b1", "b2", "c"}, keys)
+
+		require.NoError(t, st.Truncate())
+		err = st.Iterate(nil, func(k, v []byte) error {
+			t.Fatal("expected no keys")
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("Transactions", func(t *testing.T) {
+		db, err := database.New(memoryengine.NewEngine(), database.Options{Codec: msgpack.NewCodec()})
+		require.NoError(t, err)
+		defer db.Close()
+
+		put := func(t *testing.T, tx *database.Transaction, k string) {
+			st, err := tx.Store("foo")
+			require.NoError(t, err)
+			require.NoError(t, st.Put([]byte(k), []byte(k)))
+		}
+
+		get := func(t *testing.T, k string) error {
+			tx, err := db.Begin(false)
+			require.NoError(t, err)
+			defer tx.Rollback()
+
+			st, err := tx.Store("foo")
+			require.NoError(t, err)
+			_, err = st.Get([]byte(k))
+			return err
+		}
+
+		// the namespace doesn't exist yet
+		require.Equal(t, database.ErrKeyNotFound, get(t, "a"))
+
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		put(t, tx, "a")
+		require.NoError(t, tx.Rollback())
+		require.Equal(t, database.ErrKeyNotFound, get(t, "a"))
+
+		tx, err = db.Begin(true)
+		require.NoError(t, err)
+		put(t, tx, "a")
+		sp, err := tx.Savepoint()
+		require.NoError(t, err)
+		put(t, tx, "b")
+		require.NoError(t, sp.Rollback())
+		require.NoError(t, tx.Commit())
+
+		require.NoError(t, get(t, "a"))
+		require.Equal(t, database.ErrKeyNotFound, get(t, "b"))
+
+		// read-only transactions can't write
+		tx, err = db.Begin(false)
+		require.NoError(t, err)
+		defer tx.Rollback()
+		st, err := tx.Store("foo")
+		require.NoError(t, err)
+		require.Error(t, st.Put([]byte("c"), nil))
+		require.Equal(t, engine.ErrTransactionReadOnly, st.Delete([]byte("a")))
+	})
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// A MemoryAccount keeps track of the memory used by a statement.
+// Operators that hold documents in memory, like sorting or grouping,
+// must report what they allocate and release.
+// A single account is shared by all the operators of a statement, including those
+// of its subqueries and common table expressions, see WithMemoryAccount.
+// A nil MemoryAccount doesn't limit anything.
+type MemoryAccount struct {
+	limit int64
+	used  int64
+	peak  int64
+}
+
+// NewMemoryAccount creates an account limited to the given number of bytes.
+// If limit is zero, memory is accounted but not limited.
+func NewMemoryAccount(limit int64) *MemoryAccount {
+	return &MemoryAccount{limit: limit}
+}
+
+// Grow reports the allocation of n bytes.
+// It returns an error wrapping ErrMemoryLimitExceeded if the limit is reached,
+// in which case the allocation is not accounted.
+func (m *MemoryAccount) Grow(n int64) error {
+	if m == nil {
+		return nil
+	}
+
+	if m.limit > 0 && m.used+n > m.limit {
+		return fmt.Errorf("%w: statement requires more than %d bytes", ErrMemoryLimitExceeded, m.limit)
+	}
+
+	m.used += n
+	if m.used > m.peak {
+		m.peak = m.used
+	}
+
+	return nil
+}
+
+// Shrink reports the release of n bytes.
+func (m *MemoryAccount) Shrink(n int64) {
+	if m == nil {
+		return
+	}
+
+	m.used -= n
+	if m.used < 0 {
+		m.used = 0
+	}
+}
+
+// Used returns the number of bytes currently accounted.
+func (m *MemoryAccount) Used() int64 {
+	if m == nil {
+		return 0
+	}
+
+	return m.used
+}
+
+// Peak returns the maximum number of bytes accounted at once.
+func (m *MemoryAccount) Peak() int64 {
+	if m == nil {
+		return 0
+	}
+
+	return m.peak
+}
+
+// memoryAccountKey is the key of the account of the contexts
+// returned by WithMemoryAccount.
+type memoryAccountKey struct{}
+
+// WithMemoryAccount returns a copy of ctx carrying m. Once the returned context is passed
+// to Transaction.SetContext, the statements run by the transaction share m.
+func WithMemoryAccount(ctx context.Context, m *MemoryAccount) context.Context {
+	return context.WithValue(ctx, memoryAccountKey{}, m)
+}
+
+// MemoryAccount returns the account of the statement run by the transaction,
+// carried by its context. If there is none, it returns a new account limited by
+// the QueryMemoryLimit of the database.
+func (tx *Transaction) MemoryAccount() *MemoryAccount {
+	if tx.ctx != nil {
+		if m, ok := tx.ctx.Value(memoryAccountKey{}).(*MemoryAccount); ok {
+			return m
+		}
+	}
+
+	return NewMemoryAccount(tx.DB().QueryMemoryLimit())
+}
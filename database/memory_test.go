@@ -0,0 +1,45 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/genjidb/genji/database"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryAccount(t *testing.T) {
+	m := database.NewMemoryAccount(100)
+
+	require.NoError(t, m.Grow(60))
+	err := m.Grow(50)
+	require.True(t, errors.Is(err, database.ErrMemoryLimitExceeded))
+	require.Equal(t, database.CodeOutOfMemory, database.CodeOf(err))
+	require.EqualValues(t, 60, m.Used())
+
+	m.Shrink(20)
+	require.NoError(t, m.Grow(50))
+	require.EqualValues(t, 90, m.Used())
+	require.EqualValues(t, 90, m.Peak())
+
+	// nil accounts are unlimited
+	var nilAccount *database.MemoryAccount
+	require.NoError(t, nilAccount.Grow(1000))
+	require.Zero(t, nilAccount.Used())
+}
+
+func TestTransactionMemoryAccount(t *testing.T) {
+	tx, cleanup := newTestDB(t)
+	defer cleanup()
+
+	// without a statement, every call returns a new account
+	require.NotSame(t, tx.MemoryAccount(), tx.MemoryAccount())
+
+	m := database.NewMemoryAccount(100)
+	tx.SetContext(database.WithMemoryAccount(context.Background(), m))
+	require.Same(t, m, tx.MemoryAccount())
+
+	tx.SetContext(nil)
+	require.NotSame(t, m, tx.MemoryAccount())
+}
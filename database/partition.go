@@ -0,0 +1,404 @@
+package database
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"sort"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/key"
+)
+
+const partitionStorePrefix = 'p'
+
+// A PartitionMethod determines how the documents of a table are distributed
+// among its partitions.
+type PartitionMethod uint8
+
+// List of partition methods.
+const (
+	// PartitionByRange assigns documents to partitions based on
+	// the range their primary key falls in.
+	PartitionByRange PartitionMethod = iota + 1
+	// PartitionByHash assigns documents to partitions based on
+	// the hash of their primary key.
+	PartitionByHash
+)
+
+// String returns the SQL name of the method.
+func (m PartitionMethod) String() string {
+	switch m {
+	case PartitionByRange:
+		return "RANGE"
+	case PartitionByHash:
+		return "HASH"
+	}
+
+	return ""
+}
+
+// Partitioning describes how a table is split into partitions.
+// Each partition is stored in its own engine store.
+// Only the primary key can be used as the partition key.
+type Partitioning struct {
+	Method PartitionMethod
+	Path   document.ValuePath
+	// Bounds of a range partitioning, in increasing order.
+	// Partition i contains the keys lower than Bounds[i],
+	// the last partition contains the remaining keys.
+	Bounds []document.Value
+	// Count is the number of partitions of a hash partitioning.
+	Count int
+}
+
+// Len returns the number of partitions.
+func (p *Partitioning) Len() int {
+	if p.Method == PartitionByRange {
+		return len(p.Bounds) + 1
+	}
+
+	return p.Count
+}
+
+// ToDocument returns a document from p.
+func (p *Partitioning) ToDocument() document.Document {
+	buf := document.NewFieldBuffer()
+
+	buf.Add("method", document.NewIntegerValue(int64(p.Method)))
+	buf.Add("path", document.NewArrayValue(valuePathToArray(p.Path)))
+	buf.Add("bounds", document.NewArrayValue(document.NewValueBuffer(p.Bounds...)))
+	buf.Add("count", document.NewIntegerValue(int64(p.Count)))
+	return buf
+}
+
+// ScanDocument decodes d into p.
+func (p *Partitioning) ScanDocument(d document.Document) error {
+	v, err := d.GetByField("method")
+	if err != nil {
+		return err
+	}
+	p.Method = PartitionMethod(v.V.(int64))
+
+	v, err = d.GetByField("path")
+	if err != nil {
+		return err
+	}
+	p.Path, err = arrayToValuePath(v)
+	if err != nil {
+		return err
+	}
+
+	v, err = d.GetByField("bounds")
+	if err != nil {
+		return err
+	}
+	p.Bounds = p.Bounds[:0]
+	err = v.V.(document.Array).Iterate(func(_ int, value document.Value) error {
+		p.Bounds = append(p.Bounds, value)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	v, err = d.GetByField("count")
+	if err != nil {
+		return err
+	}
+	p.Count = int(v.V.(int64))
+	return nil
+}
+
+// validatePartitioning ensures the partitioning of the table is valid and
+// converts the bounds of a range partitioning to the type of the primary key.
+func (ti *TableInfo) validatePartitioning() error {
+	p := ti.Partitioning
+
+	pk := ti.GetPrimaryKey()
+	if pk == nil || !pk.Path.IsEqual(p.Path) {
+		return NewError(CodeFeatureNotSupported, fmt.Sprintf("partition key %q must be the primary key of the table", p.Path))
+	}
+
+	switch p.Method {
+	case PartitionByHash:
+		if p.Count < 1 {
+			return NewError(CodeInvalidObjectDefinition, "the number of partitions must be greater than zero")
+		}
+	case PartitionByRange:
+		if len(p.Bounds) == 0 {
+			return NewError(CodeInvalidObjectDefinition, "range partitioning requires at least one bound")
+		}
+
+		var prev []byte
+		for i, b := range p.Bounds {
+			if pk.Type != 0 {
+				v, err := b.CastAs(pk.Type)
+				if err != nil {
+					return NewError(CodeDatatypeMismatch, fmt.Sprintf("invalid partition bound %s: %v", b, err))
+				}
+				p.Bounds[i] = v
+			}
+
+			k, err := ti.encodeKey(p.Bounds[i])
+			if err != nil {
+				return err
+			}
+			if prev != nil && bytes.Compare(prev, k) >= 0 {
+				return NewError(CodeInvalidObjectDefinition, "partition bounds must be strictly increasing")
+			}
+			prev = k
+		}
+	default:
+		return NewError(CodeInvalidObjectDefinition, fmt.Sprintf("unknown partition method %d", p.Method))
+	}
+
+	return nil
+}
+
+// partitionStoreName returns the name of the store containing the i-th partition of the table.
+// The first partition uses the store of the table.
+func (ti *TableInfo) partitionStoreName(i int) []byte {
+	if i == 0 {
+		return ti.storeName
+	}
+
+	name := append([]byte{partitionStorePrefix}, ti.storeName...)
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], uint64(i))
+	return append(name, buf[:n]...)
+}
+
+// encodeKey encodes v the same way the primary key of the table is encoded.
+func (ti *TableInfo) encodeKey(v document.Value) ([]byte, error) {
+	pk := ti.GetPrimaryKey()
+	if pk != nil && pk.Type != 0 {
+		v, err := v.CastAs(pk.Type)
+		if err != nil {
+			return nil, err
+		}
+
+		return key.Append(nil, v.Type, v.V)
+	}
+
+	return key.AppendValue(nil, v)
+}
+
+// PartitionOf returns the partition that holds the document whose primary key is v.
+func (ti *TableInfo) PartitionOf(v document.Value) (int, error) {
+	k, err := ti.encodeKey(v)
+	if err != nil {
+		return 0, err
+	}
+
+	bounds, err := ti.boundKeys()
+	if err != nil {
+		return 0, err
+	}
+
+	return partitionOfKey(ti.Partitioning, bounds, k), nil
+}
+
+// boundKeys returns the encoded bounds of a range partitioning.
+func (ti *TableInfo) boundKeys() ([][]byte, error) {
+	if ti.Partitioning.Method != PartitionByRange {
+		return nil, nil
+	}
+
+	bounds := make([][]byte, len(ti.Partitioning.Bounds))
+	for i, b := range ti.Partitioning.Bounds {
+		var err error
+		bounds[i], err = ti.encodeKey(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return bounds, nil
+}
+
+// partitionOfKey returns the partition holding the key k.
+// bounds are the encoded bounds of a range partitioning.
+func partitionOfKey(p *Partitioning, bounds [][]byte, k []byte) int {
+	if p.Method == PartitionByRange {
+		return sort.Search(len(bounds), func(i int) bool {
+			return bytes.Compare(k, bounds[i]) < 0
+		})
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write(k)
+	return int(h.Sum64() % uint64(p.Count))
+}
+
+// checkPartitionKey returns an error if the partition key of d, which is its primary key,
+// doesn't match key. Documents can't be moved to another partition, as they would
+// have to be stored under a different key.
+func (t *Table) checkPartitionKey(info *TableInfo, key []byte, d document.Document) error {
+	k, err := t.generateKey(d)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(k, key) {
+		return newConstraintViolationError(CodeIntegrityConstraintViolation, ConstraintPrimaryKey, info.Partitioning.Path,
+			fmt.Sprintf("cannot modify partition key at path %q", info.Partitioning.Path))
+	}
+
+	return nil
+}
+
+// partitionedStore is an engine.Store that distributes the keys of
+// a table among the stores of its partitions.
+type partitionedStore struct {
+	p      *Partitioning
+	bounds [][]byte
+	stores []engine.Store
+}
+
+func newPartitionedStore(tx engine.Transaction, ti *TableInfo) (*partitionedStore, error) {
+	bounds, err := ti.boundKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	ps := partitionedStore{
+		p:      ti.Partitioning,
+		bounds: bounds,
+		stores: make([]engine.Store, ti.Partitioning.Len()),
+	}
+
+	for i := range ps.stores {
+		ps.stores[i], err = tx.GetStore(ti.partitionStoreName(i))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &ps, nil
+}
+
+func (s *partitionedStore) store(k []byte) engine.Store {
+	return s.stores[partitionOfKey(s.p, s.bounds, k)]
+}
+
+func (s *partitionedStore) Get(k []byte) ([]byte, error) {
+	return s.store(k).Get(k)
+}
+
+func (s *partitionedStore) Put(k, v []byte) error {
+	return s.store(k).Put(k, v)
+}
+
+func (s *partitionedStore) Delete(k []byte) error {
+	return s.store(k).Delete(k)
+}
+
+func (s *partitionedStore) Truncate() error {
+	for _, st := range s.stores {
+		err := st.Truncate()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// NextSequence uses the sequence of the first partition so that generated
+// keys are unique across the table.
+func (s *partitionedStore) NextSequence() (uint64, error) {
+	return s.stores[0].NextSequence()
+}
+
+// NewIterator returns an iterator that merges the keys of all the partitions.
+func (s *partitionedStore) NewIterator(cfg engine.IteratorConfig) engine.Iterator {
+	return s.iterator(nil, cfg)
+}
+
+// iterator merges the keys of the selected partitions, or all of them if parts is nil.
+func (s *partitionedStore) iterator(parts []int, cfg engine.IteratorConfig) engine.Iterator {
+	if parts == nil {
+		parts = make([]int, len(s.stores))
+		for i := range parts {
+			parts[i] = i
+		}
+	}
+
+	it := mergeIterator{
+		reverse: cfg.Reverse,
+		its:     make([]engine.Iterator, len(parts)),
+		cur:     -1,
+	}
+	for i, p := range parts {
+		it.its[i] = s.stores[p].NewIterator(cfg)
+	}
+
+	return &it
+}
+
+// mergeIterator iterates over multiple stores at once,
+// in the order of their keys.
+type mergeIterator struct {
+	reverse bool
+	its     []engine.Iterator
+	// index of the iterator positioned on the current item.
+	cur int
+}
+
+func (it *mergeIterator) Seek(k []byte) {
+	for _, i := range it.its {
+		i.Seek(k)
+	}
+
+	it.pick()
+}
+
+func (it *mergeIterator) Next() {
+	it.its[it.cur].Next()
+	it.pick()
+}
+
+// pick selects the iterator positioned on the smallest key,
+// or the greatest when iterating in reverse.
+func (it *mergeIterator) pick() {
+	it.cur = -1
+	var cur []byte
+	for i, sub := range it.its {
+		if !sub.Valid() {
+			continue
+		}
+
+		k := sub.Item().Key()
+		if it.cur == -1 {
+			it.cur, cur = i, k
+			continue
+		}
+
+		c := bytes.Compare(k, cur)
+		if (!it.reverse && c < 0) || (it.reverse && c > 0) {
+			it.cur, cur = i, k
+		}
+	}
+}
+
+func (it *mergeIterator) Valid() bool {
+	return it.cur >= 0
+}
+
+func (it *mergeIterator) Item() engine.Item {
+	return it.its[it.cur].Item()
+}
+
+func (it *mergeIterator) Close() error {
+	var err error
+	for _, i := range it.its {
+		if e := i.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	return err
+}
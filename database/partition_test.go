@@ -0,0 +1,214 @@
+package database_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/stretchr/testify/require"
+)
+
+func newPartitionedTable(t testing.TB, tx *database.Transaction, p *database.Partitioning) *database.Table {
+	err := tx.CreateTable("test", &database.TableInfo{
+		FieldConstraints: []database.FieldConstraint{
+			{Path: parsePath(t, "a"), Type: document.IntegerValue, IsPrimaryKey: true},
+		},
+		Partitioning: p,
+	})
+	require.NoError(t, err)
+
+	tb, err := tx.GetTable("test")
+	require.NoError(t, err)
+
+	for _, a := range []int64{25, 3, 10, 42, 7, 20, 1} {
+		_, err = tb.Insert(document.NewFieldBuffer().Add("a", document.NewIntegerValue(a)))
+		require.NoError(t, err)
+	}
+
+	return tb
+}
+
+func collectPK(t testing.TB, fn func(func(d document.Document) error) error) []int64 {
+	var pks []int64
+	err := fn(func(d document.Document) error {
+		v, err := d.GetByField("a")
+		if err != nil {
+			return err
+		}
+		pks = append(pks, v.V.(int64))
+		return nil
+	})
+	require.NoError(t, err)
+	return pks
+}
+
+func TestPartitionByRange(t *testing.T) {
+	tx, cleanup := newTestDB(t)
+	defer cleanup()
+
+	tb := newPartitionedTable(t, tx, &database.Partitioning{
+		Method: database.PartitionByRange,
+		Path:   parsePath(t, "a"),
+		// bounds are converted to the type of the primary key
+		Bounds: []document.Value{document.NewIntegerValue(5), document.NewDoubleValue(20)},
+	})
+
+	info, err := tb.Info()
+	require.NoError(t, err)
+	require.Equal(t, document.NewIntegerValue(20), info.Partitioning.Bounds[1])
+
+	for v, p := range map[int64]int{-1: 0, 4: 0, 5: 1, 19: 1, 20: 2, 100: 2} {
+		got, err := info.PartitionOf(document.NewIntegerValue(v))
+		require.NoError(t, err)
+		require.Equal(t, p, got, "partition of %d", v)
+	}
+
+	// documents of all the partitions are returned in key order
+	require.Equal(t, []int64{1, 3, 7, 10, 20, 25, 42}, collectPK(t, tb.Iterate))
+
+	require.Equal(t, []int64{1, 3}, collectPK(t, func(fn func(d document.Document) error) error {
+		return tb.IteratePartitions([]int{0}, fn)
+	}))
+	require.Equal(t, []int64{1, 3, 20, 25, 42}, collectPK(t, func(fn func(d document.Document) error) error {
+		return tb.IteratePartitions([]int{0, 2}, fn)
+	}))
+	require.Empty(t, collectPK(t, func(fn func(d document.Document) error) error {
+		return tb.IteratePartitions([]int{}, fn)
+	}))
+
+	key, err := tb.Insert(document.NewFieldBuffer().Add("a", document.NewIntegerValue(12)))
+	require.NoError(t, err)
+	_, err = tb.GetDocument(key)
+	require.NoError(t, err)
+
+	_, err = tb.Insert(document.NewFieldBuffer().Add("a", document.NewIntegerValue(12)))
+	require.True(t, errors.Is(err, database.ErrDuplicateDocument))
+
+	err = tb.Delete(key)
+	require.NoError(t, err)
+	_, err = tb.GetDocument(key)
+	require.Equal(t, database.ErrDocumentNotFound, err)
+
+	err = tb.Truncate()
+	require.NoError(t, err)
+	require.Empty(t, collectPK(t, tb.Iterate))
+}
+
+func TestPartitionByHash(t *testing.T) {
+	tx, cleanup := newTestDB(t)
+	defer cleanup()
+
+	tb := newPartitionedTable(t, tx, &database.Partitioning{
+		Method: database.PartitionByHash,
+		Path:   parsePath(t, "a"),
+		Count:  3,
+	})
+
+	info, err := tb.Info()
+	require.NoError(t, err)
+
+	require.Equal(t, []int64{1, 3, 7, 10, 20, 25, 42}, collectPK(t, tb.Iterate))
+
+	// each document is stored in the partition of its primary key
+	var total int
+	for i := 0; i < 3; i++ {
+		pks := collectPK(t, func(fn func(d document.Document) error) error {
+			return tb.IteratePartitions([]int{i}, fn)
+		})
+		for _, pk := range pks {
+			p, err := info.PartitionOf(document.NewIntegerValue(pk))
+			require.NoError(t, err)
+			require.Equal(t, i, p)
+		}
+		total += len(pks)
+	}
+	require.Equal(t, 7, total)
+}
+
+func TestPartitionValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		p    database.Partitioning
+	}{
+		{"Not the primary key", database.Partitioning{Method: database.PartitionByHash, Path: parsePath(t, "b"), Count: 2}},
+		{"No partitions", database.Partitioning{Method: database.PartitionByHash, Path: parsePath(t, "a")}},
+		{"No bounds", database.Partitioning{Method: database.PartitionByRange, Path: parsePath(t, "a")}},
+		{"Unordered bounds", database.Partitioning{Method: database.PartitionByRange, Path: parsePath(t, "a"),
+			Bounds: []document.Value{document.NewIntegerValue(10), document.NewIntegerValue(5)}}},
+		{"Invalid bound", database.Partitioning{Method: database.PartitionByRange, Path: parsePath(t, "a"),
+			Bounds: []document.Value{document.NewTextValue("foo")}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tx, cleanup := newTestDB(t)
+			defer cleanup()
+
+			err := tx.CreateTable("test", &database.TableInfo{
+				FieldConstraints: []database.FieldConstraint{
+					{Path: parsePath(t, "a"), Type: document.IntegerValue, IsPrimaryKey: true},
+				},
+				Partitioning: &test.p,
+			})
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestPartitionDrop(t *testing.T) {
+	tx, cleanup := newTestDB(t)
+	defer cleanup()
+
+	p := database.Partitioning{Method: database.PartitionByHash, Path: parsePath(t, "a"), Count: 4}
+	newPartitionedTable(t, tx, &p)
+
+	err := tx.DropTable("test")
+	require.NoError(t, err)
+
+	// the stores of all the partitions must have been removed
+	tb := newPartitionedTable(t, tx, &p)
+	require.Equal(t, []int64{1, 3, 7, 10, 20, 25, 42}, collectPK(t, tb.Iterate))
+}
+
+func TestPartitionReplace(t *testing.T) {
+	tx, cleanup := newTestDB(t)
+	defer cleanup()
+
+	tb := newPartitionedTable(t, tx, &database.Partitioning{
+		Method: database.PartitionByRange,
+		Path:   parsePath(t, "a"),
+		Bounds: []document.Value{document.NewIntegerValue(5), document.NewIntegerValue(20)},
+	})
+
+	var key []byte
+	err := tb.Iterate(func(d document.Document) error {
+		v, err := d.GetByField("a")
+		if err == nil && v.V.(int64) == 10 {
+			key = append([]byte(nil), d.(document.Keyer).Key()...)
+		}
+		return err
+	})
+	require.NoError(t, err)
+	require.NotNil(t, key)
+
+	// the document stays in its partition
+	err = tb.Replace(key, document.NewFieldBuffer().Add("a", document.NewIntegerValue(10)).Add("b", document.NewIntegerValue(1)))
+	require.NoError(t, err)
+	d, err := tb.GetDocument(key)
+	require.NoError(t, err)
+	v, err := d.GetByField("b")
+	require.NoError(t, err)
+	require.Equal(t, document.NewIntegerValue(1), v)
+
+	// moving a document to another partition is not supported
+	err = tb.Replace(key, document.NewFieldBuffer().Add("a", document.NewIntegerValue(30)))
+	var cerr *database.ConstraintViolationError
+	require.True(t, errors.As(err, &cerr))
+	require.Equal(t, []int64{7, 10}, collectPK(t, func(fn func(d document.Document) error) error {
+		return tb.IteratePartitions([]int{1}, fn)
+	}))
+	require.Equal(t, []int64{20, 25, 42}, collectPK(t, func(fn func(d document.Document) error) error {
+		return tb.IteratePartitions([]int{2}, fn)
+	}))
+}
@@ -0,0 +1,147 @@
+package database
+
+import (
+	"bytes"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine"
+)
+
+// ProcedureConfig holds the definition of a stored procedure.
+type ProcedureConfig struct {
+	ProcedureName string
+	// Names of the parameters of the procedure, in order.
+	Params []string
+	// Body is the SQL source of the statements run by the procedure.
+	Body string
+}
+
+// ToDocument creates a document from a ProcedureConfig.
+func (p *ProcedureConfig) ToDocument() document.Document {
+	params := document.NewValueBuffer()
+	for _, name := range p.Params {
+		params = params.Append(document.NewTextValue(name))
+	}
+
+	return document.NewFieldBuffer().
+		Add("procedure_name", document.NewTextValue(p.ProcedureName)).
+		Add("params", document.NewArrayValue(params)).
+		Add("body", document.NewTextValue(p.Body))
+}
+
+// ScanDocument implements the document.Scanner interface.
+func (p *ProcedureConfig) ScanDocument(d document.Document) error {
+	v, err := d.GetByField("procedure_name")
+	if err != nil {
+		return err
+	}
+	p.ProcedureName = v.V.(string)
+
+	v, err = d.GetByField("params")
+	if err != nil {
+		return err
+	}
+	p.Params = nil
+	err = v.V.(document.Array).Iterate(func(i int, v document.Value) error {
+		p.Params = append(p.Params, v.V.(string))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	v, err = d.GetByField("body")
+	if err != nil {
+		return err
+	}
+	p.Body = v.V.(string)
+
+	return nil
+}
+
+// CreateProcedure stores the definition of a procedure.
+// It returns ErrProcedureAlreadyExists if a procedure with the same name exists.
+func (tx *Transaction) CreateProcedure(cfg ProcedureConfig) error {
+	if err := tx.checkAge(); err != nil {
+		return err
+	}
+
+	st, err := tx.tx.GetStore([]byte(procedureStoreName))
+	if err != nil {
+		return err
+	}
+
+	key := []byte(cfg.ProcedureName)
+	_, err = st.Get(key)
+	if err == nil {
+		return ErrProcedureAlreadyExists
+	}
+	if err != engine.ErrKeyNotFound {
+		return err
+	}
+
+	var buf bytes.Buffer
+	err = tx.db.Codec.NewEncoder(&buf).EncodeDocument(cfg.ToDocument())
+	if err != nil {
+		return err
+	}
+
+	err = st.Put(key, buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	tx.recordDDL(DDLCreate, DDLProcedure, cfg.ProcedureName)
+	return nil
+}
+
+// GetProcedure returns the definition of a procedure by name.
+func (tx *Transaction) GetProcedure(name string) (*ProcedureConfig, error) {
+	if err := tx.checkAge(); err != nil {
+		return nil, err
+	}
+
+	st, err := tx.tx.GetStore([]byte(procedureStoreName))
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := st.Get([]byte(name))
+	if err == engine.ErrKeyNotFound {
+		return nil, ErrProcedureNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg ProcedureConfig
+	err = cfg.ScanDocument(tx.db.Codec.NewDocument(v))
+	if err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// DropProcedure deletes the definition of a procedure.
+func (tx *Transaction) DropProcedure(name string) error {
+	if err := tx.checkAge(); err != nil {
+		return err
+	}
+
+	st, err := tx.tx.GetStore([]byte(procedureStoreName))
+	if err != nil {
+		return err
+	}
+
+	err = st.Delete([]byte(name))
+	if err == engine.ErrKeyNotFound {
+		return ErrProcedureNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	tx.recordDDL(DDLDrop, DDLProcedure, name)
+	return nil
+}
@@ -0,0 +1,86 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/document/encoding/msgpack"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxProcedures(t *testing.T) {
+	cfg := database.ProcedureConfig{
+		ProcedureName: "p",
+		Params:        []string{"a", "b"},
+		Body:          "BEGIN DELETE FROM test WHERE a = $a; END",
+	}
+
+	t.Run("Create, get and drop", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		err := tx.CreateProcedure(cfg)
+		require.NoError(t, err)
+
+		err = tx.CreateProcedure(cfg)
+		require.Equal(t, database.ErrProcedureAlreadyExists, err)
+
+		p, err := tx.GetProcedure("p")
+		require.NoError(t, err)
+		require.Equal(t, &cfg, p)
+
+		err = tx.DropProcedure("p")
+		require.NoError(t, err)
+
+		_, err = tx.GetProcedure("p")
+		require.Equal(t, database.ErrProcedureNotFound, err)
+
+		err = tx.DropProcedure("p")
+		require.Equal(t, database.ErrProcedureNotFound, err)
+	})
+
+	t.Run("System table", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		err := tx.CreateProcedure(cfg)
+		require.NoError(t, err)
+
+		tb, err := tx.GetTable("__genji_procedures")
+		require.NoError(t, err)
+
+		var procedures []database.ProcedureConfig
+		err = tb.Iterate(func(d document.Document) error {
+			var p database.ProcedureConfig
+			err := p.ScanDocument(d)
+			procedures = append(procedures, p)
+			return err
+		})
+		require.NoError(t, err)
+		require.Equal(t, []database.ProcedureConfig{cfg}, procedures)
+
+		err = tb.Delete([]byte("p"))
+		require.Equal(t, database.ErrReadOnlyTable, err)
+	})
+
+	t.Run("Rollback", func(t *testing.T) {
+		db, err := database.New(memoryengine.NewEngine(), database.Options{Codec: msgpack.NewCodec()})
+		require.NoError(t, err)
+
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		err = tx.CreateProcedure(cfg)
+		require.NoError(t, err)
+		err = tx.Rollback()
+		require.NoError(t, err)
+
+		tx, err = db.Begin(false)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		_, err = tx.GetProcedure("p")
+		require.Equal(t, database.ErrProcedureNotFound, err)
+	})
+}
@@ -0,0 +1,494 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/genjidb/genji/engine"
+)
+
+// A Savepoint marks a point within a read-write transaction to which
+// it can be rolled back, without aborting the whole transaction.
+// Savepoints can be nested, in which case they must be released
+// or rolled back in the reverse order of their creation.
+type Savepoint struct {
+	tx *Transaction
+	// position of the savepoint in the transaction journal.
+	pos int
+	// copy of the table information when the savepoint was created.
+	tableInfos map[string]TableInfo
+	// number of catalog changes recorded when the savepoint was created.
+	ddl int
+	// savepoints of the transactions started on the attached databases,
+	// by attachment name.
+	attached map[string]*Savepoint
+	done     bool
+}
+
+// Savepoint creates a savepoint at the current state of the transaction.
+// Every change made after its creation is recorded until the savepoint
+// is either released or rolled back.
+func (tx *Transaction) Savepoint() (*Savepoint, error) {
+	if !tx.writable {
+		return nil, NewError(CodeReadOnly, "cannot create a savepoint in a read-only transaction")
+	}
+
+	err := tx.checkAge()
+	if err != nil {
+		return nil, err
+	}
+
+	tx.journal.savepoints++
+	// keys recorded before must be recorded again for the new savepoint
+	tx.journal.keys = nil
+
+	sp := Savepoint{
+		tx:         tx,
+		pos:        len(tx.journal.undo),
+		tableInfos: tx.tableInfoStore.GetTableInfo(),
+		ddl:        len(tx.ddl),
+	}
+
+	for name, atx := range tx.attached {
+		err = sp.attach(name, atx)
+		if err != nil {
+			sp.Release()
+			return nil, err
+		}
+	}
+
+	tx.openSavepoints = append(tx.openSavepoints, &sp)
+	return &sp, nil
+}
+
+// attach creates a savepoint on the transaction started on the attached database name,
+// if it is writable, so that the changes made to it are rolled back with sp.
+func (sp *Savepoint) attach(name string, atx *Transaction) error {
+	if !atx.writable {
+		return nil
+	}
+
+	asp, err := atx.Savepoint()
+	if err != nil {
+		return err
+	}
+
+	if sp.attached == nil {
+		sp.attached = make(map[string]*Savepoint)
+	}
+	sp.attached[name] = asp
+	return nil
+}
+
+// Rollback cancels every change made to the transaction since the creation
+// of the savepoint, and releases it. The transaction remains usable.
+func (sp *Savepoint) Rollback() error {
+	if sp.done {
+		return errors.New("savepoint already released")
+	}
+
+	j := sp.tx.journal
+	for i := len(j.undo) - 1; i >= sp.pos; i-- {
+		err := j.undo[i].fn()
+		if err != nil {
+			return err
+		}
+	}
+	j.truncate(sp.pos)
+
+	sp.tx.tableInfoStore.restore(sp.tableInfos)
+	if len(sp.tx.ddl) > sp.ddl {
+		sp.tx.db.bumpCatalogVersion()
+	}
+	sp.tx.ddl = sp.tx.ddl[:sp.ddl]
+
+	for _, asp := range sp.attached {
+		err := asp.Rollback()
+		if err != nil {
+			return err
+		}
+	}
+
+	sp.Release()
+	return nil
+}
+
+// Release the savepoint, keeping the changes made since its creation.
+// It can be called multiple times.
+func (sp *Savepoint) Release() {
+	if sp.done {
+		return
+	}
+	sp.done = true
+
+	j := sp.tx.journal
+	j.savepoints--
+	// changes only need to be recorded while a savepoint is active
+	if j.savepoints == 0 {
+		j.truncate(0)
+	}
+
+	for _, asp := range sp.attached {
+		asp.Release()
+	}
+
+	for i := len(sp.tx.openSavepoints) - 1; i >= 0; i-- {
+		if sp.tx.openSavepoints[i] == sp {
+			sp.tx.openSavepoints = append(sp.tx.openSavepoints[:i], sp.tx.openSavepoints[i+1:]...)
+			break
+		}
+	}
+}
+
+// namedSavepoint is a savepoint created by CreateSavepoint.
+type namedSavepoint struct {
+	name string
+	sp   *Savepoint
+}
+
+// CreateSavepoint creates a savepoint with the given name, which can then be
+// rolled back to or released by name, as done by the SAVEPOINT statement.
+// Names can be reused, in which case the most recent savepoint is used.
+func (tx *Transaction) CreateSavepoint(name string) error {
+	sp, err := tx.Savepoint()
+	if err != nil {
+		return err
+	}
+
+	tx.savepoints = append(tx.savepoints, namedSavepoint{name: name, sp: sp})
+	return nil
+}
+
+// RollbackToSavepoint cancels every change made since the creation of the named savepoint
+// and destroys the savepoints created after it. The savepoint itself remains
+// and can be rolled back to again.
+func (tx *Transaction) RollbackToSavepoint(name string) error {
+	i, err := tx.savepointIndex(name)
+	if err != nil {
+		return err
+	}
+
+	tx.releaseSavepoints(i + 1)
+
+	err = tx.savepoints[i].sp.Rollback()
+	if err != nil {
+		return err
+	}
+
+	tx.savepoints[i].sp, err = tx.Savepoint()
+	return err
+}
+
+// ReleaseSavepoint destroys the named savepoint and the savepoints created after it,
+// keeping the changes made since their creation.
+func (tx *Transaction) ReleaseSavepoint(name string) error {
+	i, err := tx.savepointIndex(name)
+	if err != nil {
+		return err
+	}
+
+	tx.releaseSavepoints(i)
+	return nil
+}
+
+// savepointIndex returns the position of the most recent savepoint with the given name.
+func (tx *Transaction) savepointIndex(name string) (int, error) {
+	err := tx.checkAge()
+	if err != nil {
+		return 0, err
+	}
+
+	for i := len(tx.savepoints) - 1; i >= 0; i-- {
+		if tx.savepoints[i].name == name {
+			return i, nil
+		}
+	}
+
+	return 0, ErrSavepointNotFound
+}
+
+// releaseSavepoints releases the named savepoints from position i,
+// in the reverse order of their creation.
+func (tx *Transaction) releaseSavepoints(i int) {
+	for j := len(tx.savepoints) - 1; j >= i; j-- {
+		tx.savepoints[j].sp.Release()
+	}
+	tx.savepoints = tx.savepoints[:i]
+}
+
+// journal is an engine transaction that records how to undo the changes
+// made while savepoints are active.
+// The values copied to undo the changes are accounted in memory, and limited
+// by the memory limit of queries: a change fails if it can't be recorded.
+// Sequences are not restored.
+type journal struct {
+	engine.Transaction
+
+	undo       []undoRecord
+	savepoints int
+	memory     *MemoryAccount
+	// keys recorded since the creation of the last savepoint, by store.
+	// Restoring their first recorded value is enough to undo the next changes.
+	keys map[string]map[string]struct{}
+}
+
+// undoRecord undoes a change and holds the size of the copied data it uses.
+type undoRecord struct {
+	fn   func() error
+	size int64
+}
+
+func (j *journal) recording() bool {
+	return j.savepoints > 0
+}
+
+// record adds fn to the journal, if size bytes can be accounted.
+func (j *journal) record(size int64, fn func() error) error {
+	err := j.memory.Grow(size)
+	if err != nil {
+		return err
+	}
+
+	j.undo = append(j.undo, undoRecord{fn: fn, size: size})
+	return nil
+}
+
+// truncate removes the records from position i.
+func (j *journal) truncate(i int) {
+	for _, r := range j.undo[i:] {
+		j.memory.Shrink(r.size)
+	}
+	j.undo = j.undo[:i]
+	j.keys = nil
+}
+
+// isRecorded reports whether the key of the given store was recorded
+// since the creation of the last savepoint.
+func (j *journal) isRecorded(store, k []byte) bool {
+	_, ok := j.keys[string(store)][string(k)]
+	return ok
+}
+
+// markRecorded marks the key of the given store as recorded.
+func (j *journal) markRecorded(store, k []byte) {
+	if j.keys == nil {
+		j.keys = make(map[string]map[string]struct{})
+	}
+
+	keys, ok := j.keys[string(store)]
+	if !ok {
+		keys = make(map[string]struct{})
+		j.keys[string(store)] = keys
+	}
+
+	keys[string(k)] = struct{}{}
+}
+
+func (j *journal) GetStore(name []byte) (engine.Store, error) {
+	st, err := j.Transaction.GetStore(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &journaledStore{Store: st, j: j, name: append([]byte{}, name...)}, nil
+}
+
+func (j *journal) CreateStore(name []byte) error {
+	err := j.Transaction.CreateStore(name)
+	if err != nil || !j.recording() {
+		return err
+	}
+
+	name = append([]byte{}, name...)
+	return j.record(int64(len(name)), func() error {
+		return j.Transaction.DropStore(name)
+	})
+}
+
+func (j *journal) DropStore(name []byte) error {
+	if !j.recording() {
+		return j.Transaction.DropStore(name)
+	}
+
+	st, err := j.Transaction.GetStore(name)
+	if err != nil {
+		return err
+	}
+
+	kvs, size, err := j.copyStore(st)
+	if err != nil {
+		return err
+	}
+
+	err = j.Transaction.DropStore(name)
+	if err != nil {
+		j.memory.Shrink(size)
+		return err
+	}
+
+	name = append([]byte{}, name...)
+	j.undo = append(j.undo, undoRecord{size: size, fn: func() error {
+		err := j.Transaction.CreateStore(name)
+		if err != nil {
+			return err
+		}
+
+		st, err := j.Transaction.GetStore(name)
+		if err != nil {
+			return err
+		}
+
+		return putAll(st, kvs)
+	}})
+	return nil
+}
+
+// journaledStore records how to undo the changes made to a store
+// while savepoints are active.
+type journaledStore struct {
+	engine.Store
+
+	j    *journal
+	name []byte
+}
+
+// store returns the store from the underlying transaction.
+// Undo functions must not use s.Store, which is not valid anymore
+// if the store was dropped and recreated.
+func (s *journaledStore) store() (engine.Store, error) {
+	return s.j.Transaction.GetStore(s.name)
+}
+
+func (s *journaledStore) Put(k, v []byte) error {
+	if s.j.recording() {
+		err := s.recordRestore(k)
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.Store.Put(k, v)
+}
+
+func (s *journaledStore) Delete(k []byte) error {
+	if s.j.recording() {
+		err := s.recordRestore(k)
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.Store.Delete(k)
+}
+
+// recordRestore records how to restore the current value of k, if any,
+// or how to delete it otherwise. Nothing is recorded if k was already
+// recorded since the creation of the last savepoint.
+func (s *journaledStore) recordRestore(k []byte) error {
+	if s.j.isRecorded(s.name, k) {
+		return nil
+	}
+
+	k = append([]byte{}, k...)
+
+	v, err := s.Store.Get(k)
+	if err == engine.ErrKeyNotFound {
+		err = s.j.record(int64(len(k)), func() error {
+			st, err := s.store()
+			if err != nil {
+				return err
+			}
+
+			return st.Delete(k)
+		})
+	} else if err == nil {
+		v = append([]byte{}, v...)
+		err = s.j.record(int64(len(k)+len(v)), func() error {
+			st, err := s.store()
+			if err != nil {
+				return err
+			}
+
+			return st.Put(k, v)
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	s.j.markRecorded(s.name, k)
+	return nil
+}
+
+func (s *journaledStore) Truncate() error {
+	if !s.j.recording() {
+		return s.Store.Truncate()
+	}
+
+	kvs, size, err := s.j.copyStore(s.Store)
+	if err != nil {
+		return err
+	}
+
+	err = s.Store.Truncate()
+	if err != nil {
+		s.j.memory.Shrink(size)
+		return err
+	}
+
+	s.j.undo = append(s.j.undo, undoRecord{size: size, fn: func() error {
+		st, err := s.store()
+		if err != nil {
+			return err
+		}
+
+		err = st.Truncate()
+		if err != nil {
+			return err
+		}
+
+		return putAll(st, kvs)
+	}})
+	return nil
+}
+
+// copyStore returns a copy of all the key value pairs of st and their size,
+// which is accounted in the memory of the journal.
+func (j *journal) copyStore(st engine.Store) ([][2][]byte, int64, error) {
+	var kvs [][2][]byte
+	var size int64
+
+	it := st.NewIterator(engine.IteratorConfig{})
+	defer it.Close()
+
+	for it.Seek(nil); it.Valid(); it.Next() {
+		item := it.Item()
+		v, err := item.ValueCopy(nil)
+		if err != nil {
+			j.memory.Shrink(size)
+			return nil, 0, err
+		}
+
+		k := append([]byte{}, item.Key()...)
+		err = j.memory.Grow(int64(len(k) + len(v)))
+		if err != nil {
+			j.memory.Shrink(size)
+			return nil, 0, err
+		}
+		size += int64(len(k) + len(v))
+
+		kvs = append(kvs, [2][]byte{k, v})
+	}
+
+	return kvs, size, nil
+}
+
+func putAll(st engine.Store, kvs [][2][]byte) error {
+	for _, kv := range kvs {
+		err := st.Put(kv[0], kv[1])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
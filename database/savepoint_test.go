@@ -0,0 +1,196 @@
+package database_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/document/encoding/msgpack"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSavepoint(t *testing.T) {
+	count := func(t *testing.T, tx *database.Transaction, name string) int {
+		tb, err := tx.GetTable(name)
+		require.NoError(t, err)
+
+		var n int
+		err = tb.Iterate(func(d document.Document) error {
+			n++
+			return nil
+		})
+		require.NoError(t, err)
+		return n
+	}
+
+	insert := func(t *testing.T, tx *database.Transaction, name string, n int) {
+		tb, err := tx.GetTable(name)
+		require.NoError(t, err)
+
+		for i := 0; i < n; i++ {
+			_, err = tb.Insert(document.NewFieldBuffer().Add("a", document.NewIntegerValue(int64(i))))
+			require.NoError(t, err)
+		}
+	}
+
+	t.Run("Rollback", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		require.NoError(t, tx.CreateTable("test", nil))
+		insert(t, tx, "test", 2)
+
+		sp, err := tx.Savepoint()
+		require.NoError(t, err)
+
+		insert(t, tx, "test", 3)
+		tb, err := tx.GetTable("test")
+		require.NoError(t, err)
+		require.NoError(t, tb.Truncate())
+		require.NoError(t, tx.CreateTable("foo", nil))
+		insert(t, tx, "foo", 1)
+
+		require.NoError(t, sp.Rollback())
+		require.Error(t, sp.Rollback())
+
+		require.Equal(t, 2, count(t, tx, "test"))
+		_, err = tx.GetTable("foo")
+		require.Equal(t, database.ErrTableNotFound, err)
+
+		// the table can be created again
+		require.NoError(t, tx.CreateTable("foo", nil))
+	})
+
+	t.Run("Release", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		require.NoError(t, tx.CreateTable("test", nil))
+
+		sp, err := tx.Savepoint()
+		require.NoError(t, err)
+		insert(t, tx, "test", 2)
+		sp.Release()
+		sp.Release()
+
+		require.Equal(t, 2, count(t, tx, "test"))
+	})
+
+	t.Run("Nested", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		require.NoError(t, tx.CreateTable("test", nil))
+
+		outer, err := tx.Savepoint()
+		require.NoError(t, err)
+		insert(t, tx, "test", 1)
+
+		inner, err := tx.Savepoint()
+		require.NoError(t, err)
+		require.NoError(t, tx.DropTable("test"))
+		require.NoError(t, inner.Rollback())
+
+		require.Equal(t, 1, count(t, tx, "test"))
+
+		inner, err = tx.Savepoint()
+		require.NoError(t, err)
+		insert(t, tx, "test", 1)
+		inner.Release()
+		require.Equal(t, 2, count(t, tx, "test"))
+
+		require.NoError(t, outer.Rollback())
+		require.Equal(t, 0, count(t, tx, "test"))
+	})
+
+	t.Run("Named", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		require.NoError(t, tx.CreateTable("test", nil))
+
+		require.NoError(t, tx.CreateSavepoint("a"))
+		insert(t, tx, "test", 1)
+		require.NoError(t, tx.CreateSavepoint("b"))
+		insert(t, tx, "test", 1)
+		require.NoError(t, tx.CreateSavepoint("c"))
+		insert(t, tx, "test", 1)
+
+		// rolling back to b destroys c but keeps b
+		require.NoError(t, tx.RollbackToSavepoint("b"))
+		require.Equal(t, 1, count(t, tx, "test"))
+		require.Equal(t, database.ErrSavepointNotFound, tx.RollbackToSavepoint("c"))
+
+		insert(t, tx, "test", 2)
+		require.NoError(t, tx.RollbackToSavepoint("b"))
+		require.Equal(t, 1, count(t, tx, "test"))
+
+		// releasing b keeps its changes
+		insert(t, tx, "test", 1)
+		require.NoError(t, tx.ReleaseSavepoint("b"))
+		require.Equal(t, database.ErrSavepointNotFound, tx.ReleaseSavepoint("b"))
+		require.Equal(t, 2, count(t, tx, "test"))
+
+		// names can be reused
+		require.NoError(t, tx.CreateSavepoint("a"))
+		insert(t, tx, "test", 1)
+		require.NoError(t, tx.RollbackToSavepoint("a"))
+		require.Equal(t, 2, count(t, tx, "test"))
+		require.NoError(t, tx.ReleaseSavepoint("a"))
+		require.NoError(t, tx.RollbackToSavepoint("a"))
+		require.Equal(t, 0, count(t, tx, "test"))
+	})
+
+	t.Run("Read-only", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		cleanup()
+
+		tx, err := tx.DB().Begin(false)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		_, err = tx.Savepoint()
+		require.Error(t, err)
+	})
+
+	t.Run("Memory", func(t *testing.T) {
+		db, err := database.New(memoryengine.NewEngine(), database.Options{Codec: msgpack.NewCodec(), MaxQueryMemory: 1000})
+		require.NoError(t, err)
+
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		require.NoError(t, tx.CreateTable("test", nil))
+		tb, err := tx.GetTable("test")
+		require.NoError(t, err)
+		big := document.NewFieldBuffer().Add("a", document.NewTextValue(strings.Repeat("a", 600)))
+		k, err := tb.Insert(big)
+		require.NoError(t, err)
+
+		sp, err := tx.Savepoint()
+		require.NoError(t, err)
+
+		// the previous value of a key is only copied once per savepoint
+		for i := 0; i < 10; i++ {
+			err = tb.Replace(k, big)
+			require.NoError(t, err)
+		}
+
+		// copying the table exceeds the limit
+		err = tb.Truncate()
+		require.True(t, errors.Is(err, database.ErrMemoryLimitExceeded))
+		require.Equal(t, 1, count(t, tx, "test"))
+
+		// the memory is released with the savepoint
+		sp.Release()
+		sp, err = tx.Savepoint()
+		require.NoError(t, err)
+		err = tb.Replace(k, big)
+		require.NoError(t, err)
+		require.NoError(t, sp.Rollback())
+	})
+}
@@ -0,0 +1,220 @@
+package database
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/genjidb/genji/document"
+)
+
+// maximum number of example values kept for each field by InferSchema.
+const schemaExamples = 3
+
+// A Schema describes the documents of a table, as observed by InferSchema.
+type Schema struct {
+	// Number of documents read.
+	Count int64
+	// Fields found in the documents, sorted by path.
+	Fields []FieldSchema
+}
+
+// A FieldSchema describes a path found in the documents of a table.
+type FieldSchema struct {
+	Path document.ValuePath
+	// Types associates the type of the values of the path
+	// with the number of documents containing a value of that type.
+	// Null values are only counted in Nulls.
+	Types map[document.ValueType]int64
+	// Number of documents read in which the path is null or missing.
+	Nulls int64
+	// NullFrequency is the proportion of the documents read
+	// in which the path is null or missing, between 0 and 1.
+	NullFrequency float64
+	// Up to three distinct non-null values of the path, in the order they were found.
+	Examples []document.Value
+}
+
+// InferSchema reads the documents of the table and returns the paths they contain,
+// with the types of their values, how often they are null or missing and a few example values.
+// It is meant for tables without field constraints, whose documents may differ from one another.
+// Nested documents are described by one field per path, like "a" and "a.b".
+// The values of arrays are not described.
+// If sampleSize is positive, only that many documents are described, selected at random
+// among the documents of the table, otherwise every document is.
+func (t *Table) InferSchema(sampleSize int) (*Schema, error) {
+	s := schemaBuilder{
+		fields: make(map[string]*FieldSchema),
+	}
+
+	if sampleSize <= 0 {
+		err := t.Iterate(s.add)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.schema(), nil
+	}
+
+	keys, err := t.sampleKeys(sampleSize)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, k := range keys {
+		d, err := t.GetDocument(k)
+		if err != nil {
+			return nil, err
+		}
+
+		err = s.add(d)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s.schema(), nil
+}
+
+// sampleKeys selects up to n keys of the table at random, using reservoir sampling,
+// and returns them in order.
+func (t *Table) sampleKeys(n int) ([][]byte, error) {
+	rnd := t.tx.db.Rand()
+
+	var keys [][]byte
+	var i int
+	err := t.Iterate(func(d document.Document) error {
+		// the key is only valid during the iteration
+		k := d.(document.Keyer).Key()
+
+		switch {
+		case i < n:
+			keys = append(keys, append([]byte{}, k...))
+		default:
+			if j := rnd.Intn(i + 1); j < n {
+				keys[j] = append(keys[j][:0], k...)
+			}
+		}
+
+		i++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return bytes.Compare(keys[i], keys[j]) < 0
+	})
+
+	return keys, nil
+}
+
+// schemaBuilder collects the paths of the documents passed to add.
+type schemaBuilder struct {
+	count  int64
+	fields map[string]*FieldSchema
+}
+
+func (s *schemaBuilder) add(d document.Document) error {
+	s.count++
+	return s.addDocument(nil, d)
+}
+
+func (s *schemaBuilder) addDocument(parent document.ValuePath, d document.Document) error {
+	return d.Iterate(func(field string, v document.Value) error {
+		path := append(parent[:len(parent):len(parent)], document.ValuePathFragment{FieldName: field})
+
+		f, ok := s.fields[path.String()]
+		if !ok {
+			f = &FieldSchema{
+				Path:  path,
+				Types: make(map[document.ValueType]int64),
+			}
+			s.fields[path.String()] = f
+		}
+
+		// null values are counted with the missing ones
+		// once every document has been read.
+		if v.Type == document.NullValue {
+			return nil
+		}
+
+		f.Types[v.Type]++
+
+		if len(f.Examples) < schemaExamples {
+			err := f.addExample(v)
+			if err != nil {
+				return err
+			}
+		}
+
+		if v.Type == document.DocumentValue {
+			return s.addDocument(path, v.V.(document.Document))
+		}
+
+		return nil
+	})
+}
+
+// addExample adds v to the examples of the field if it is not one of them already.
+func (f *FieldSchema) addExample(v document.Value) error {
+	for _, e := range f.Examples {
+		if e.Type != v.Type {
+			continue
+		}
+
+		ok, err := e.IsEqual(v)
+		if err != nil || ok {
+			return err
+		}
+	}
+
+	// the value may belong to a buffer reused by the table,
+	// it must be copied.
+	switch v.Type {
+	case document.BlobValue:
+		v = document.NewBlobValue(append([]byte{}, v.V.([]byte)...))
+	case document.ArrayValue:
+		var vb document.ValueBuffer
+		err := vb.Copy(v.V.(document.Array))
+		if err != nil {
+			return err
+		}
+		v = document.NewArrayValue(&vb)
+	case document.DocumentValue:
+		var fb document.FieldBuffer
+		err := fb.Copy(v.V.(document.Document))
+		if err != nil {
+			return err
+		}
+		v = document.NewDocumentValue(&fb)
+	}
+
+	f.Examples = append(f.Examples, v)
+	return nil
+}
+
+// schema returns the fields sorted by path and computes their null frequency.
+func (s *schemaBuilder) schema() *Schema {
+	schema := Schema{
+		Count:  s.count,
+		Fields: make([]FieldSchema, 0, len(s.fields)),
+	}
+
+	for _, f := range s.fields {
+		var n int64
+		for _, c := range f.Types {
+			n += c
+		}
+
+		f.Nulls = s.count - n
+		f.NullFrequency = float64(f.Nulls) / float64(s.count)
+		schema.Fields = append(schema.Fields, *f)
+	}
+
+	sort.Slice(schema.Fields, func(i, j int) bool {
+		return schema.Fields[i].Path.String() < schema.Fields[j].Path.String()
+	})
+
+	return &schema
+}
@@ -0,0 +1,86 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/genjidb/genji/document"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTableInferSchema(t *testing.T) {
+	tb, cleanup := newTestTable(t)
+	defer cleanup()
+
+	for _, d := range []string{
+		`{"a": 1, "b": "x", "c": {"d": true}}`,
+		`{"a": 1.5, "b": null, "c": {"e": [1]}}`,
+		`{"a": 1, "b": "y", "f": [1, 2]}`,
+		`{"a": "z", "b": "x"}`,
+	} {
+		fb, err := document.NewFromJSON([]byte(d))
+		require.NoError(t, err)
+		_, err = tb.Insert(fb)
+		require.NoError(t, err)
+	}
+
+	t.Run("All documents", func(t *testing.T) {
+		s, err := tb.InferSchema(0)
+		require.NoError(t, err)
+		require.EqualValues(t, 4, s.Count)
+
+		var paths []string
+		for _, f := range s.Fields {
+			paths = append(paths, f.Path.String())
+		}
+		require.Equal(t, []string{"a", "b", "c", "c.d", "c.e", "f"}, paths)
+
+		a := s.Fields[0]
+		require.Equal(t, map[document.ValueType]int64{document.IntegerValue: 2, document.DoubleValue: 1, document.TextValue: 1}, a.Types)
+		require.Zero(t, a.Nulls)
+		require.Zero(t, a.NullFrequency)
+		require.Equal(t, []document.Value{document.NewIntegerValue(1), document.NewDoubleValue(1.5), document.NewTextValue("z")}, a.Examples)
+
+		b := s.Fields[1]
+		require.Equal(t, map[document.ValueType]int64{document.TextValue: 3}, b.Types)
+		require.EqualValues(t, 1, b.Nulls)
+		require.Equal(t, 0.25, b.NullFrequency)
+		require.Equal(t, []document.Value{document.NewTextValue("x"), document.NewTextValue("y")}, b.Examples)
+
+		cd := s.Fields[3]
+		require.Equal(t, map[document.ValueType]int64{document.BoolValue: 1}, cd.Types)
+		require.Equal(t, 0.75, cd.NullFrequency)
+
+		f := s.Fields[5]
+		require.Equal(t, map[document.ValueType]int64{document.ArrayValue: 1}, f.Types)
+		require.Len(t, f.Examples, 1)
+		data, err := document.MarshalJSONArray(f.Examples[0].V.(document.Array))
+		require.NoError(t, err)
+		require.JSONEq(t, `[1, 2]`, string(data))
+	})
+
+	t.Run("Sample", func(t *testing.T) {
+		s, err := tb.InferSchema(2)
+		require.NoError(t, err)
+		require.EqualValues(t, 2, s.Count)
+
+		var total int64
+		for _, c := range s.Fields[0].Types {
+			total += c
+		}
+		require.EqualValues(t, 2, total)
+
+		s, err = tb.InferSchema(10)
+		require.NoError(t, err)
+		require.EqualValues(t, 4, s.Count)
+	})
+
+	t.Run("Empty table", func(t *testing.T) {
+		tb, cleanup := newTestTable(t)
+		defer cleanup()
+
+		s, err := tb.InferSchema(10)
+		require.NoError(t, err)
+		require.Zero(t, s.Count)
+		require.Empty(t, s.Fields)
+	})
+}
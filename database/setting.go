@@ -0,0 +1,336 @@
+package database
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine"
+)
+
+// Setting is a runtime setting of the database, which overrides
+// the corresponding field of the Database.
+type Setting struct {
+	Name  string
+	Value document.Value
+	// Persisted is true if the value is stored in the database
+	// and restored the next time it is opened.
+	Persisted bool
+}
+
+// settingDef defines how a setting is read and changed.
+type settingDef struct {
+	get func(db *Database) document.Value
+	// parse validates v and returns a function assigning it to the database.
+	parse func(v document.Value) (func(db *Database), error)
+}
+
+// settings contains the definition of the runtime settings, by name.
+var settings = map[string]settingDef{
+	"busy_mode": {
+		get: func(db *Database) document.Value {
+			if db.BusyMode == BusyError {
+				return document.NewTextValue("error")
+			}
+			return document.NewTextValue("wait")
+		},
+		parse: func(v document.Value) (func(db *Database), error) {
+			var mode BusyMode
+			switch {
+			case v.Type == document.TextValue && strings.EqualFold(v.V.(string), "wait"):
+				mode = BusyWait
+			case v.Type == document.TextValue && strings.EqualFold(v.V.(string), "error"):
+				mode = BusyError
+			default:
+				return nil, fmt.Errorf("%w: busy_mode must be 'wait' or 'error'", ErrInvalidSettingValue)
+			}
+			return func(db *Database) { db.BusyMode = mode }, nil
+		},
+	},
+	"integer_overflow": {
+		get: func(db *Database) document.Value {
+			if db.IntegerOverflowMode == document.OverflowError {
+				return document.NewTextValue("error")
+			}
+			return document.NewTextValue("promote")
+		},
+		parse: func(v document.Value) (func(db *Database), error) {
+			var mode document.OverflowMode
+			switch {
+			case v.Type == document.TextValue && strings.EqualFold(v.V.(string), "promote"):
+				mode = document.OverflowPromote
+			case v.Type == document.TextValue && strings.EqualFold(v.V.(string), "error"):
+				mode = document.OverflowError
+			default:
+				return nil, fmt.Errorf("%w: integer_overflow must be 'promote' or 'error'", ErrInvalidSettingValue)
+			}
+			return func(db *Database) { db.IntegerOverflowMode = mode }, nil
+		},
+	},
+	"busy_timeout": durationSetting("busy_timeout", func(db *Database) *time.Duration {
+		return &db.BusyTimeout
+	}),
+	"max_statement_rate": rateSetting("max_statement_rate", func(db *Database) *int {
+		return &db.MaxStatementRate
+	}),
+	"max_write_rate": rateSetting("max_write_rate", func(db *Database) *int {
+		return &db.MaxWriteRate
+	}),
+	"throttle_timeout": durationSetting("throttle_timeout", func(db *Database) *time.Duration {
+		return &db.ThrottleTimeout
+	}),
+	"statement_timeout": durationSetting("statement_timeout", func(db *Database) *time.Duration {
+		return &db.StatementTimeout
+	}),
+	"max_transaction_age": durationSetting("max_transaction_age", func(db *Database) *time.Duration {
+		return &db.MaxTransactionAge
+	}),
+	"max_transaction_idle": durationSetting("max_transaction_idle", func(db *Database) *time.Duration {
+		return &db.MaxTransactionIdle
+	}),
+	"max_query_memory": {
+		get: func(db *Database) document.Value {
+			return document.NewIntegerValue(db.MaxQueryMemory)
+		},
+		parse: func(v document.Value) (func(db *Database), error) {
+			if v.Type != document.IntegerValue || v.V.(int64) < 0 {
+				return nil, fmt.Errorf("%w: max_query_memory must be a positive integer or zero", ErrInvalidSettingValue)
+			}
+			n := v.V.(int64)
+			return func(db *Database) { db.MaxQueryMemory = n }, nil
+		},
+	},
+	"scan_prefetch": {
+		get: func(db *Database) document.Value {
+			return document.NewIntegerValue(int64(db.ScanPrefetch))
+		},
+		parse: func(v document.Value) (func(db *Database), error) {
+			if v.Type != document.IntegerValue || v.V.(int64) < 0 || int64(int(v.V.(int64))) != v.V.(int64) {
+				return nil, fmt.Errorf("%w: scan_prefetch must be a positive integer or zero", ErrInvalidSettingValue)
+			}
+			n := int(v.V.(int64))
+			return func(db *Database) { db.ScanPrefetch = n }, nil
+		},
+	},
+	"max_recursion": {
+		get: func(db *Database) document.Value {
+			return document.NewIntegerValue(int64(db.MaxRecursion))
+		},
+		parse: func(v document.Value) (func(db *Database), error) {
+			if v.Type != document.IntegerValue || int64(int(v.V.(int64))) != v.V.(int64) {
+				return nil, fmt.Errorf("%w: max_recursion must be an integer", ErrInvalidSettingValue)
+			}
+			n := int(v.V.(int64))
+			return func(db *Database) { db.MaxRecursion = n }, nil
+		},
+	},
+}
+
+// durationSetting defines a setting whose value is a duration,
+// represented by a text like '1m30s'.
+func durationSetting(name string, field func(db *Database) *time.Duration) settingDef {
+	return settingDef{
+		get: func(db *Database) document.Value {
+			return document.NewTextValue(field(db).String())
+		},
+		parse: func(v document.Value) (func(db *Database), error) {
+			var d time.Duration
+			var err error
+			if v.Type == document.TextValue {
+				d, err = time.ParseDuration(v.V.(string))
+			}
+			if v.Type != document.TextValue || err != nil || d < 0 {
+				return nil, fmt.Errorf("%w: %s must be a duration, like '30s'", ErrInvalidSettingValue, name)
+			}
+			return func(db *Database) { *field(db) = d }, nil
+		},
+	}
+}
+
+// rateSetting defines a setting whose value is a number of operations per second.
+func rateSetting(name string, field func(db *Database) *int) settingDef {
+	return settingDef{
+		get: func(db *Database) document.Value {
+			return document.NewIntegerValue(int64(*field(db)))
+		},
+		parse: func(v document.Value) (func(db *Database), error) {
+			if v.Type != document.IntegerValue || v.V.(int64) < 0 || int64(int(v.V.(int64))) != v.V.(int64) {
+				return nil, fmt.Errorf("%w: %s must be a positive integer or zero", ErrInvalidSettingValue, name)
+			}
+			n := int(v.V.(int64))
+			return func(db *Database) { *field(db) = n }, nil
+		},
+	}
+}
+
+func lookupSetting(name string) (string, settingDef, error) {
+	name = strings.ToLower(name)
+	def, ok := settings[name]
+	if !ok {
+		return "", def, fmt.Errorf("%w: %q", ErrSettingNotFound, name)
+	}
+
+	return name, def, nil
+}
+
+// Settings returns the runtime settings of the database, sorted by name.
+func (db *Database) Settings() []Setting {
+	db.settingsMu.RLock()
+	defer db.settingsMu.RUnlock()
+
+	list := make([]Setting, 0, len(settings))
+	for name, def := range settings {
+		_, persisted := db.persistedSettings[name]
+		list = append(list, Setting{Name: name, Value: def.get(db), Persisted: persisted})
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// GetSetting returns the runtime setting with the given name, case insensitively.
+// It returns an error wrapping ErrSettingNotFound if there is no such setting.
+func (db *Database) GetSetting(name string) (Setting, error) {
+	name, def, err := lookupSetting(name)
+	if err != nil {
+		return Setting{}, err
+	}
+
+	db.settingsMu.RLock()
+	defer db.settingsMu.RUnlock()
+
+	_, persisted := db.persistedSettings[name]
+	return Setting{Name: name, Value: def.get(db), Persisted: persisted}, nil
+}
+
+// SetSetting changes the value of a runtime setting until the database is closed.
+// It takes effect for the transactions and statements started afterwards.
+// It returns an error wrapping ErrInvalidSettingValue if the value is not valid for the setting.
+func (db *Database) SetSetting(name string, v document.Value) error {
+	_, def, err := lookupSetting(name)
+	if err != nil {
+		return err
+	}
+
+	apply, err := def.parse(v)
+	if err != nil {
+		return err
+	}
+
+	db.settingsMu.Lock()
+	defer db.settingsMu.Unlock()
+
+	apply(db)
+	return nil
+}
+
+// PersistSetting stores the value of a runtime setting in the database, to be restored
+// the next time it is opened, and changes it once the transaction is committed.
+// Persisted settings can be queried using the read-only __genji_settings table.
+func (tx *Transaction) PersistSetting(name string, v document.Value) error {
+	if err := tx.checkAge(); err != nil {
+		return err
+	}
+
+	name, def, err := lookupSetting(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = def.parse(v)
+	if err != nil {
+		return err
+	}
+
+	st, err := tx.tx.GetStore([]byte(settingStoreName))
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	err = tx.db.Codec.NewEncoder(&buf).EncodeDocument(document.NewFieldBuffer().
+		Add("name", document.NewTextValue(name)).
+		Add("value", v))
+	if err != nil {
+		return err
+	}
+
+	err = st.Put([]byte(name), buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	// the settings are read again before committing
+	tx.markWritten(settingStoreName)
+	return nil
+}
+
+// readPersistedSettings reads the settings stored in the database
+// and returns a function applying them.
+func (db *Database) readPersistedSettings(tx engine.Transaction) (func(), error) {
+	st, err := tx.GetStore([]byte(settingStoreName))
+	if err != nil {
+		return nil, err
+	}
+
+	applies := make(map[string]func(db *Database))
+	it := st.NewIterator(engine.IteratorConfig{})
+	defer it.Close()
+
+	for it.Seek(nil); it.Valid(); it.Next() {
+		buf, err := it.Item().ValueCopy(nil)
+		if err != nil {
+			return nil, err
+		}
+
+		d := db.Codec.NewDocument(buf)
+		v, err := d.GetByField("name")
+		if err != nil {
+			return nil, err
+		}
+		name, def, err := lookupSetting(v.V.(string))
+		if err != nil {
+			return nil, err
+		}
+
+		v, err = d.GetByField("value")
+		if err != nil {
+			return nil, err
+		}
+		applies[name], err = def.parse(v)
+		if err != nil {
+			return nil, fmt.Errorf("persisted setting %q: %w", name, err)
+		}
+	}
+
+	return func() {
+		db.settingsMu.Lock()
+		defer db.settingsMu.Unlock()
+
+		db.persistedSettings = make(map[string]struct{}, len(applies))
+		for name, apply := range applies {
+			apply(db)
+			db.persistedSettings[name] = struct{}{}
+		}
+	}, nil
+}
+
+// scanPrefetch returns the maximum number of documents read ahead by scans.
+func (db *Database) scanPrefetch() int {
+	db.settingsMu.RLock()
+	defer db.settingsMu.RUnlock()
+
+	return db.ScanPrefetch
+}
+
+// QueryMemoryLimit returns the approximate number of bytes a statement can hold in memory,
+// or zero if it is not limited.
+func (db *Database) QueryMemoryLimit() int64 {
+	db.settingsMu.RLock()
+	defer db.settingsMu.RUnlock()
+
+	return db.MaxQueryMemory
+}
@@ -0,0 +1,116 @@
+package database_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/document/encoding/msgpack"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSettings(t *testing.T) {
+	newDB := func(t *testing.T) *database.Database {
+		db, err := database.New(memoryengine.NewEngine(), database.Options{
+			Codec:       msgpack.NewCodec(),
+			BusyTimeout: time.Second,
+		})
+		require.NoError(t, err)
+		return db
+	}
+
+	t.Run("Get and set", func(t *testing.T) {
+		db := newDB(t)
+		defer db.Close()
+
+		s, err := db.GetSetting("busy_timeout")
+		require.NoError(t, err)
+		require.Equal(t, database.Setting{Name: "busy_timeout", Value: document.NewTextValue("1s")}, s)
+
+		err = db.SetSetting("BUSY_TIMEOUT", document.NewTextValue("2m"))
+		require.NoError(t, err)
+		require.Equal(t, 2*time.Minute, db.BusyTimeout)
+
+		err = db.SetSetting("max_recursion", document.NewIntegerValue(-1))
+		require.NoError(t, err)
+		require.Equal(t, -1, db.RecursionLimit())
+
+		err = db.SetSetting("max_query_memory", document.NewIntegerValue(1024))
+		require.NoError(t, err)
+		require.Equal(t, int64(1024), db.QueryMemoryLimit())
+
+		var names []string
+		for _, s := range db.Settings() {
+			names = append(names, s.Name)
+		}
+		require.Equal(t, []string{"busy_mode", "busy_timeout", "integer_overflow", "max_query_memory", "max_recursion", "max_statement_rate", "max_transaction_age", "max_transaction_idle", "max_write_rate", "scan_prefetch", "statement_timeout", "throttle_timeout"}, names)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		db := newDB(t)
+		defer db.Close()
+
+		_, err := db.GetSetting("foo")
+		require.True(t, errors.Is(err, database.ErrSettingNotFound))
+		require.Equal(t, database.CodeUndefinedObject, database.CodeOf(err))
+
+		err = db.SetSetting("busy_mode", document.NewTextValue("sleep"))
+		require.True(t, errors.Is(err, database.ErrInvalidSettingValue))
+		require.Equal(t, database.CodeInvalidParameterValue, database.CodeOf(err))
+
+		err = db.SetSetting("max_query_memory", document.NewDoubleValue(1.5))
+		require.True(t, errors.Is(err, database.ErrInvalidSettingValue))
+
+		// the value is left unchanged
+		require.Equal(t, time.Second, db.BusyTimeout)
+		require.Equal(t, database.BusyWait, db.BusyMode)
+	})
+
+	t.Run("Persist", func(t *testing.T) {
+		ng := memoryengine.NewEngine()
+		db, err := database.New(ng, database.Options{Codec: msgpack.NewCodec()})
+		require.NoError(t, err)
+
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		err = tx.PersistSetting("busy_mode", document.NewTextValue("error"))
+		require.NoError(t, err)
+		err = tx.PersistSetting("max_recursion", document.NewTextValue("many"))
+		require.True(t, errors.Is(err, database.ErrInvalidSettingValue))
+
+		// settings are changed once committed
+		require.Equal(t, database.BusyWait, db.BusyMode)
+		err = tx.Commit()
+		require.NoError(t, err)
+		require.Equal(t, database.BusyError, db.BusyMode)
+
+		s, err := db.GetSetting("busy_mode")
+		require.NoError(t, err)
+		require.True(t, s.Persisted)
+
+		tx, err = db.Begin(true)
+		require.NoError(t, err)
+		err = tx.PersistSetting("max_recursion", document.NewIntegerValue(10))
+		require.NoError(t, err)
+		err = tx.Rollback()
+		require.NoError(t, err)
+		require.Equal(t, database.DefaultMaxRecursion, db.RecursionLimit())
+
+		// persisted settings override the options when the database is opened again
+		db, err = database.New(ng, database.Options{Codec: msgpack.NewCodec(), BusyMode: database.BusyWait})
+		require.NoError(t, err)
+		require.Equal(t, database.BusyError, db.BusyMode)
+
+		tx, err = db.Begin(false)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		tb, err := tx.GetTable("__genji_settings")
+		require.NoError(t, err)
+		err = tb.Delete([]byte("busy_mode"))
+		require.Equal(t, database.ErrReadOnlyTable, err)
+	})
+}
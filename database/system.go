@@ -0,0 +1,152 @@
+package database
+
+import (
+	"bytes"
+	"sort"
+	"time"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/key"
+)
+
+// newTransactionsStore returns a read-only store containing one document
+// per open transaction, as returned by db.Transactions.
+// It is used by the __genji_transactions table.
+func newTransactionsStore(db *Database) (*snapshotStore, error) {
+	var st snapshotStore
+	now := db.Now()
+
+	for _, info := range db.Transactions() {
+		fb := document.NewFieldBuffer().
+			Add("id", document.NewIntegerValue(info.ID)).
+			Add("writable", document.NewBoolValue(info.Writable)).
+			Add("attached", document.NewBoolValue(info.Attached)).
+			Add("started_at", document.NewTextValue(info.StartedAt.Format(time.RFC3339Nano))).
+			Add("age", document.NewTextValue(now.Sub(info.StartedAt).String())).
+			Add("idle", document.NewTextValue(now.Sub(info.LastUsedAt).String())).
+			Add("killed", document.NewBoolValue(info.Killed))
+		if info.Statement != "" {
+			fb.Add("statement", document.NewTextValue(info.Statement))
+		} else {
+			fb.Add("statement", document.NewNullValue())
+		}
+
+		k, err := key.Append(nil, document.IntegerValue, info.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+		err = db.Codec.NewEncoder(&buf).EncodeDocument(fb)
+		if err != nil {
+			return nil, err
+		}
+
+		st.items = append(st.items, snapshotItem{k: k, v: buf.Bytes()})
+	}
+
+	sort.Slice(st.items, func(i, j int) bool {
+		return bytes.Compare(st.items[i].k, st.items[j].k) < 0
+	})
+
+	return &st, nil
+}
+
+// snapshotStore is a read-only engine.Store
+// holding a sorted list of key value pairs in memory.
+type snapshotStore struct {
+	items []snapshotItem
+}
+
+func (s *snapshotStore) Get(k []byte) ([]byte, error) {
+	i := s.search(k)
+	if i < len(s.items) && bytes.Equal(s.items[i].k, k) {
+		return s.items[i].v, nil
+	}
+
+	return nil, engine.ErrKeyNotFound
+}
+
+// search returns the position of the first item whose key is greater than or equal to k.
+func (s *snapshotStore) search(k []byte) int {
+	return sort.Search(len(s.items), func(i int) bool {
+		return bytes.Compare(s.items[i].k, k) >= 0
+	})
+}
+
+func (s *snapshotStore) Put(k, v []byte) error {
+	return engine.ErrTransactionReadOnly
+}
+
+func (s *snapshotStore) Delete(k []byte) error {
+	return engine.ErrTransactionReadOnly
+}
+
+func (s *snapshotStore) Truncate() error {
+	return engine.ErrTransactionReadOnly
+}
+
+func (s *snapshotStore) NextSequence() (uint64, error) {
+	return 0, engine.ErrTransactionReadOnly
+}
+
+func (s *snapshotStore) NewIterator(cfg engine.IteratorConfig) engine.Iterator {
+	return &snapshotIterator{st: s, reverse: cfg.Reverse}
+}
+
+type snapshotIterator struct {
+	st      *snapshotStore
+	reverse bool
+	pos     int
+}
+
+func (it *snapshotIterator) Seek(k []byte) {
+	if !it.reverse {
+		it.pos = it.st.search(k)
+		return
+	}
+
+	if len(k) == 0 {
+		it.pos = len(it.st.items) - 1
+		return
+	}
+
+	// in reverse order, move to the last key lesser than or equal to k.
+	it.pos = it.st.search(k)
+	if it.pos == len(it.st.items) || !bytes.Equal(it.st.items[it.pos].k, k) {
+		it.pos--
+	}
+}
+
+func (it *snapshotIterator) Next() {
+	if it.reverse {
+		it.pos--
+	} else {
+		it.pos++
+	}
+}
+
+func (it *snapshotIterator) Valid() bool {
+	return it.pos >= 0 && it.pos < len(it.st.items)
+}
+
+func (it *snapshotIterator) Item() engine.Item {
+	return &it.st.items[it.pos]
+}
+
+func (it *snapshotIterator) Close() error {
+	return nil
+}
+
+type snapshotItem struct {
+	k, v []byte
+}
+
+func (i *snapshotItem) Key() []byte {
+	return i.k
+}
+
+func (i *snapshotItem) ValueCopy(buf []byte) ([]byte, error) {
+	return append(buf[:0], i.v...), nil
+}
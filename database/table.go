@@ -2,7 +2,7 @@ package database
 
 import (
 	"bytes"
-	"encoding/binary"
+	"context"
 	"errors"
 	"fmt"
 
@@ -19,6 +19,14 @@ type Table struct {
 	Store     engine.Store
 	name      string
 	infoStore *tableInfoStore
+	codec     encoding.Codec
+	// if not nil, the documents read from the table
+	// only give access to these top-level fields.
+	fields []string
+	// columns of the table, nil if the table is not columnar.
+	columns *columnStore
+	// index of the field names, nil if the table doesn't have one.
+	fieldIndex *fieldIndex
 }
 
 // Tx returns the current transaction.
@@ -31,13 +39,52 @@ func (t *Table) Info() (*TableInfo, error) {
 	return t.infoStore.Get(t.tx, t.name)
 }
 
+// Codec returns the codec used to encode and decode the documents of the table.
+// Depending on the table configuration, it may differ from the database codec.
+func (t *Table) Codec() encoding.Codec {
+	return t.codec
+}
+
 // Name returns the name of the table.
 func (t *Table) Name() string {
 	return t.name
 }
 
+// Project returns a copy of the table whose documents, returned by Iterate, IteratePartitions
+// and GetDocument, only give access to the given top-level fields.
+// Only these fields are decoded, the rest of the encoded documents is skipped.
+// It is used by queries that don't need the other fields of the documents.
+func (t *Table) Project(fields ...string) *Table {
+	p := *t
+	p.fields = fields
+	return &p
+}
+
 // Truncate deletes all the documents from the table.
 func (t *Table) Truncate() error {
+	t.tx.markWritten(t.name)
+
+	if oc, ok := t.codec.(*overflowCodec); ok {
+		err := oc.st.Truncate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if t.columns != nil {
+		err := t.columns.truncate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if t.fieldIndex != nil {
+		err := t.fieldIndex.st.Truncate()
+		if err != nil {
+			return err
+		}
+	}
+
 	return t.Store.Truncate()
 }
 
@@ -52,10 +99,12 @@ func (t *Table) Insert(d document.Document) ([]byte, error) {
 	}
 
 	if info.readOnly {
-		return nil, errors.New("cannot write to read-only table")
+		return nil, ErrReadOnlyTable
 	}
 
-	d, err = t.ValidateConstraints(d)
+	t.tx.markWritten(t.name)
+
+	d, err = t.validateConstraints(d, true)
 	if err != nil {
 		return nil, err
 	}
@@ -67,11 +116,11 @@ func (t *Table) Insert(d document.Document) ([]byte, error) {
 
 	_, err = t.Store.Get(key)
 	if err == nil {
-		return nil, ErrDuplicateDocument
+		return nil, duplicateKeyError(info)
 	}
 
 	var buf bytes.Buffer
-	err = t.tx.db.Codec.NewEncoder(&buf).EncodeDocument(d)
+	err = t.codec.NewEncoder(&buf).EncodeDocument(d)
 	if err != nil {
 		return nil, fmt.Errorf("failed to encode document: %w", err)
 	}
@@ -81,6 +130,20 @@ func (t *Table) Insert(d document.Document) ([]byte, error) {
 		return nil, err
 	}
 
+	if t.columns != nil {
+		err = t.columns.put(key, d)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if t.fieldIndex != nil {
+		err = t.fieldIndex.put(key, d)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	indexes, err := t.Indexes()
 	if err != nil {
 		return nil, err
@@ -91,11 +154,14 @@ func (t *Table) Insert(d document.Document) ([]byte, error) {
 		if err != nil {
 			v = document.NewNullValue()
 		}
+		if !idx.indexes(v) {
+			continue
+		}
 
 		err = idx.Set(v, key)
 		if err != nil {
-			if err == index.ErrDuplicate {
-				return nil, ErrDuplicateDocument
+			if errors.Is(err, index.ErrDuplicate) {
+				return nil, duplicateError(&idx.Opts, v)
 			}
 
 			return nil, err
@@ -105,6 +171,87 @@ func (t *Table) Insert(d document.Document) ([]byte, error) {
 	return key, nil
 }
 
+// Conflict returns the key of the document preventing d from being inserted,
+// either because it has the same primary key or the same value for a unique index,
+// or nil if d can be inserted.
+func (t *Table) Conflict(d document.Document) ([]byte, error) {
+	info, err := t.Info()
+	if err != nil {
+		return nil, err
+	}
+
+	d, err = t.validateConstraints(d, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// keys generated by the key generator can't conflict
+	if info.GetPrimaryKey() != nil {
+		k, err := t.generateKey(d)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = t.Store.Get(k)
+		if err == nil {
+			return k, nil
+		}
+		if err != engine.ErrKeyNotFound {
+			return nil, err
+		}
+	}
+
+	indexes, err := t.Indexes()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, idx := range indexes {
+		if !idx.Unique {
+			continue
+		}
+
+		v, err := idx.Opts.Path.GetValue(d)
+		if err != nil {
+			v = document.NewNullValue()
+		}
+		if !idx.indexes(v) {
+			continue
+		}
+
+		k, err := idx.Lookup(v)
+		if err != nil || k != nil {
+			return k, err
+		}
+	}
+
+	return nil, nil
+}
+
+// InsertFrom inserts every document returned by it and returns the number
+// of inserted documents. Documents are inserted as soon as they are read,
+// which allows streaming large sources without buffering them.
+// It stops at the first error or when ctx is canceled.
+func (t *Table) InsertFrom(ctx context.Context, it document.Iterator) (int, error) {
+	var n int
+
+	err := it.Iterate(func(d document.Document) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		_, err := t.Insert(d)
+		if err != nil {
+			return err
+		}
+
+		n++
+		return nil
+	})
+
+	return n, err
+}
+
 // Delete a document by key.
 // Indexes are automatically updated.
 func (t *Table) Delete(key []byte) error {
@@ -114,9 +261,11 @@ func (t *Table) Delete(key []byte) error {
 	}
 
 	if info.readOnly {
-		return errors.New("cannot write to read-only table")
+		return ErrReadOnlyTable
 	}
 
+	t.tx.markWritten(t.name)
+
 	d, err := t.GetDocument(key)
 	if err != nil {
 		return err
@@ -130,7 +279,10 @@ func (t *Table) Delete(key []byte) error {
 	for _, idx := range indexes {
 		v, err := idx.Opts.Path.GetValue(d)
 		if err != nil {
-			return err
+			v = document.NewNullValue()
+		}
+		if !idx.indexes(v) {
+			continue
 		}
 
 		err = idx.Delete(v, key)
@@ -139,12 +291,49 @@ func (t *Table) Delete(key []byte) error {
 		}
 	}
 
+	if t.columns != nil {
+		err = t.columns.delete(key, d)
+		if err != nil {
+			return err
+		}
+	}
+
+	if t.fieldIndex != nil {
+		err = t.fieldIndex.delete(key, d)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = t.freeOverflow(key)
+	if err != nil {
+		return err
+	}
+
 	return t.Store.Delete(key)
 }
 
+// freeOverflow deletes the values of the document stored at key
+// that were moved to the overflow store, if any.
+// It must be called before the document is deleted or overwritten.
+func (t *Table) freeOverflow(key []byte) error {
+	oc, ok := t.codec.(*overflowCodec)
+	if !ok {
+		return nil
+	}
+
+	data, err := t.Store.Get(key)
+	if err != nil {
+		return err
+	}
+
+	return oc.free(data)
+}
+
 // Replace a document by key.
+// The new document is stored under the same key.
 // An error is returned if the key doesn't exist.
-// Indexes are automatically updated.
+// Only the indexes whose value changed are updated.
 func (t *Table) Replace(key []byte, d document.Document) error {
 	info, err := t.Info()
 	if err != nil {
@@ -152,69 +341,320 @@ func (t *Table) Replace(key []byte, d document.Document) error {
 	}
 
 	if info.readOnly {
-		return errors.New("cannot write to read-only table")
+		return ErrReadOnlyTable
 	}
 
+	t.tx.markWritten(t.name)
+
 	d, err = t.ValidateConstraints(d)
 	if err != nil {
 		return err
 	}
 
+	if info.Partitioning != nil {
+		err = t.checkPartitionKey(info, key, d)
+		if err != nil {
+			return err
+		}
+	}
+
 	indexes, err := t.Indexes()
 	if err != nil {
 		return err
 	}
 
-	return t.replace(indexes, key, d)
+	return t.replace(indexes, key, d, nil)
 }
 
-func (t *Table) replace(indexes map[string]Index, key []byte, d document.Document) error {
+// replace stores d under key. If fields is not nil, d only differs from the stored document
+// by these top-level fields, and only their columns and field index entries are updated.
+func (t *Table) replace(indexes map[string]Index, key []byte, d document.Document, fields []string) error {
 	// make sure key exists
 	old, err := t.GetDocument(key)
 	if err != nil {
 		return err
 	}
 
-	// remove key from indexes
+	// update indexes before overwriting the old document,
+	// as some engines don't guarantee it remains valid after a Put
+	err = t.updateIndexes(indexes, key, old, d)
+	if err != nil {
+		return err
+	}
+
+	if fields != nil {
+		err = t.updateFieldStores(key, &projectedDocument{Document: old, fields: fields}, &projectedDocument{Document: d, fields: fields})
+	} else {
+		err = t.updateFieldStores(key, old, d)
+	}
+	if err != nil {
+		return err
+	}
+
+	// encode new document
+	var buf bytes.Buffer
+	err = t.codec.NewEncoder(&buf).EncodeDocument(d)
+	if err != nil {
+		return fmt.Errorf("failed to encode document: %w", err)
+	}
+
+	// d might reference the old document,
+	// which must only be freed after being encoded
+	err = t.freeOverflow(key)
+	if err != nil {
+		return err
+	}
+
+	// replace old document with new document
+	return t.Store.Put(key, buf.Bytes())
+}
+
+// duplicateError returns the error of v being already indexed by the unique index opts:
+// a ConstraintViolationError wrapping ErrDuplicateDocument.
+func duplicateError(opts *IndexConfig, v document.Value) error {
+	msg := ErrDuplicateDocument.Error()
+	if opts.FieldConstraint {
+		msg = fmt.Sprintf("field %q must be unique, value %s already exists", opts.Path, v)
+	}
+
+	return &ConstraintViolationError{
+		Constraint: ConstraintUnique,
+		Path:       opts.Path,
+		Value:      v,
+		code:       CodeUniqueViolation,
+		msg:        msg,
+		err:        ErrDuplicateDocument,
+	}
+}
+
+// duplicateKeyError returns the error of a document having the same key as another
+// document of the table described by info: a ConstraintViolationError wrapping ErrDuplicateDocument.
+// Its path is the one of the primary key, if any.
+func duplicateKeyError(info *TableInfo) error {
+	var path document.ValuePath
+	if pk := info.GetPrimaryKey(); pk != nil {
+		path = pk.Path
+	}
+
+	return &ConstraintViolationError{
+		Constraint: ConstraintPrimaryKey,
+		Path:       path,
+		code:       CodeUniqueViolation,
+		msg:        ErrDuplicateDocument.Error(),
+		err:        ErrDuplicateDocument,
+	}
+}
+
+// updateIndexes compares the indexed values of the old and the new version
+// of a document and only updates the indexes whose value changed.
+func (t *Table) updateIndexes(indexes map[string]Index, key []byte, old, d document.Document) error {
 	for _, idx := range indexes {
-		v, err := idx.Opts.Path.GetValue(old)
+		oldV, err := idx.Opts.Path.GetValue(old)
+		if err != nil {
+			oldV = document.NewNullValue()
+		}
+
+		newV, err := idx.Opts.Path.GetValue(d)
+		if err != nil {
+			newV = document.NewNullValue()
+		}
+
+		if oldV.Type == newV.Type {
+			ok, err := oldV.IsEqual(newV)
+			if err != nil {
+				return err
+			}
+			if ok {
+				continue
+			}
+		}
+
+		if idx.indexes(oldV) {
+			err = idx.Delete(oldV, key)
+			if err != nil {
+				return err
+			}
+		}
+
+		if !idx.indexes(newV) {
+			continue
+		}
+
+		err = idx.Set(newV, key)
 		if err != nil {
+			if errors.Is(err, index.ErrDuplicate) {
+				return duplicateError(&idx.Opts, newV)
+			}
+
 			return err
 		}
+	}
 
-		err = idx.Delete(v, key)
+	return nil
+}
+
+// updateFieldStores replaces the old version of a document by the new one
+// in the columns and the field index of the table, if any.
+func (t *Table) updateFieldStores(key []byte, old, d document.Document) error {
+	if t.columns != nil {
+		err := t.columns.delete(key, old)
+		if err != nil {
+			return err
+		}
+
+		err = t.columns.put(key, d)
 		if err != nil {
 			return err
 		}
 	}
 
-	// encode new document
-	var buf bytes.Buffer
-	err = t.tx.db.Codec.NewEncoder(&buf).EncodeDocument(d)
+	if t.fieldIndex != nil {
+		err := t.fieldIndex.delete(key, old)
+		if err != nil {
+			return err
+		}
+
+		return t.fieldIndex.put(key, d)
+	}
+
+	return nil
+}
+
+// Update replaces the document stored at key by d, which must only differ from it
+// by the values at the given paths, like the documents modified by the UPDATE statement.
+// Contrary to Replace, only the indexes on these paths, or on paths they contain
+// or are contained in, are compared and updated, and only the columns and field index entries
+// of their top-level fields are rewritten. The whole document is still read and
+// re-encoded, since the table stores documents as a single value.
+// An error is returned if the key doesn't exist.
+func (t *Table) Update(key []byte, d document.Document, paths []document.ValuePath) error {
+	info, err := t.Info()
 	if err != nil {
-		return fmt.Errorf("failed to encode document: %w", err)
+		return err
 	}
 
-	// replace old document with new document
-	err = t.Store.Put(key, buf.Bytes())
+	if info.readOnly {
+		return ErrReadOnlyTable
+	}
+
+	t.tx.markWritten(t.name)
+
+	d, err = t.ValidateConstraints(d)
 	if err != nil {
 		return err
 	}
 
-	// update indexes
-	for _, idx := range indexes {
-		v, err := idx.Opts.Path.GetValue(d)
+	// the primary key cannot be modified as it would require
+	// storing the document under a different key
+	if pk := info.GetPrimaryKey(); pk != nil {
+		nk, err := t.generateKey(d)
 		if err != nil {
-			continue
+			return err
 		}
 
-		err = idx.Set(v, key)
+		if !bytes.Equal(nk, key) {
+			return newConstraintViolationError(CodeIntegrityConstraintViolation, ConstraintPrimaryKey, pk.Path,
+				fmt.Sprintf("cannot modify primary key at path %q", pk.Path))
+		}
+	}
+
+	if info.Partitioning != nil {
+		err = t.checkPartitionKey(info, key, d)
 		if err != nil {
 			return err
 		}
 	}
 
-	return err
+	indexes, err := t.Indexes()
+	if err != nil {
+		return err
+	}
+
+	for name, idx := range indexes {
+		if !overlapsAny(idx.Opts.Path, paths) {
+			delete(indexes, name)
+		}
+	}
+
+	fields := make([]string, 0, len(paths))
+	for _, p := range paths {
+		if len(p) == 0 || p[0].Wildcard {
+			// every top-level field might have been modified
+			fields = nil
+			break
+		}
+
+		if !containsField(fields, p[0].FieldName) {
+			fields = append(fields, p[0].FieldName)
+		}
+	}
+
+	return t.replace(indexes, key, d, fields)
+}
+
+// overlapsAny returns whether p is a prefix of one of the paths,
+// or has one of them as prefix. Wildcards match any fragment of the same kind.
+func overlapsAny(p document.ValuePath, paths []document.ValuePath) bool {
+	for _, other := range paths {
+		n := len(p)
+		if len(other) < n {
+			n = len(other)
+		}
+
+		i := 0
+		for ; i < n; i++ {
+			a, b := p[i], other[i]
+			if a.Wildcard || b.Wildcard {
+				if a.IsArrayIndex() != b.IsArrayIndex() {
+					break
+				}
+				continue
+			}
+			if a != b {
+				break
+			}
+		}
+
+		if i == n {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Merge merges the top-level fields of patch into the document stored at key.
+// Fields of patch that already exist in the stored document are replaced,
+// the others are appended.
+// Like Update, only the indexes of the fields of patch are updated.
+// An error is returned if the key doesn't exist.
+func (t *Table) Merge(key []byte, patch document.Document) error {
+	old, err := t.GetDocument(key)
+	if err != nil {
+		return err
+	}
+
+	var fb document.FieldBuffer
+	err = fb.Copy(old)
+	if err != nil {
+		return err
+	}
+
+	var paths []document.ValuePath
+	err = patch.Iterate(func(field string, v document.Value) error {
+		paths = append(paths, document.ValuePath{{FieldName: field}})
+		if _, err := fb.GetByField(field); err == document.ErrFieldNotFound {
+			fb.Add(field, v)
+			return nil
+		}
+
+		return fb.Replace(field, v)
+	})
+	if err != nil {
+		return err
+	}
+
+	return t.Update(key, &fb, paths)
 }
 
 // Indexes returns a map of all the indexes of a table.
@@ -228,6 +668,7 @@ func (t *Table) Indexes() (map[string]Index, error) {
 		tx:    t.tx,
 		Store: s,
 		name:  indexStoreName,
+		codec: t.tx.db.Codec,
 	}
 
 	indexes := make(map[string]Index)
@@ -249,8 +690,9 @@ func (t *Table) Indexes() (map[string]Index, error) {
 			}
 
 			idx := index.NewIndex(t.tx.tx, opts.IndexName, index.Options{
-				Unique: opts.Unique,
-				Type:   opts.Type,
+				Unique:   opts.Unique,
+				Type:     opts.Type,
+				Prefetch: t.tx.db.scanPrefetch(),
 			})
 
 			indexes[opts.Path.String()] = Index{
@@ -277,6 +719,68 @@ func (e encodedDocumentWithKey) Key() []byte {
 	return e.key
 }
 
+// Encoding returns the encoded representation of the document, if any.
+// It implements the document.EncodedDocument interface.
+func (e encodedDocumentWithKey) Encoding() (string, []byte) {
+	if ed, ok := e.Document.(document.EncodedDocument); ok {
+		return ed.Encoding()
+	}
+
+	return "", nil
+}
+
+// projectedDocument only gives access to the selected fields of a document.
+// See Table.Project.
+type projectedDocument struct {
+	document.Document
+
+	fields []string
+}
+
+func (d *projectedDocument) GetByField(field string) (document.Value, error) {
+	if !containsField(d.fields, field) {
+		return document.Value{}, document.ErrFieldNotFound
+	}
+
+	return d.Document.GetByField(field)
+}
+
+func (d *projectedDocument) Iterate(fn func(field string, value document.Value) error) error {
+	return iterateFields(d.Document, d.fields, fn)
+}
+
+// iterateFields calls fn with the given fields of d, in that order, skipping the missing ones.
+// Fields are decoded one by one using GetByField, which skips the encoded values of the other fields
+// instead of decoding the whole document.
+func iterateFields(d document.Document, fields []string, fn func(field string, value document.Value) error) error {
+	for _, f := range fields {
+		v, err := d.GetByField(f)
+		if err == document.ErrFieldNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		err = fn(f, v)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func containsField(fields []string, field string) bool {
+	for _, f := range fields {
+		if f == field {
+			return true
+		}
+	}
+
+	return false
+}
+
 // This document implementation waits until
 // GetByField or Iterate are called to
 // fetch the value from the engine store.
@@ -287,14 +791,23 @@ type lazilyDecodedDocument struct {
 	item  engine.Item
 	buf   []byte
 	codec encoding.Codec
+	// interner, if set, is shared by all the documents of an iteration
+	// to avoid allocating the same field names and small values twice.
+	interner *document.Interner
+	// if not nil, only these fields are decoded. See Table.Project.
+	fields []string
 }
 
 func (d *lazilyDecodedDocument) GetByField(field string) (v document.Value, err error) {
+	if d.fields != nil && !containsField(d.fields, field) {
+		return document.Value{}, document.ErrFieldNotFound
+	}
+
 	if len(d.buf) == 0 {
 		d.copyFromItem()
 	}
 
-	return d.codec.NewDocument(d.buf).GetByField(field)
+	return d.decode().GetByField(field)
 }
 
 func (d *lazilyDecodedDocument) Iterate(fn func(field string, value document.Value) error) error {
@@ -302,7 +815,34 @@ func (d *lazilyDecodedDocument) Iterate(fn func(field string, value document.Val
 		d.copyFromItem()
 	}
 
-	return d.codec.NewDocument(d.buf).Iterate(fn)
+	if d.fields != nil {
+		return iterateFields(d.decode(), d.fields, fn)
+	}
+
+	return d.decode().Iterate(fn)
+}
+
+// Encoding returns the encoded representation of the document, if any.
+// It implements the document.EncodedDocument interface.
+func (d *lazilyDecodedDocument) Encoding() (string, []byte) {
+	// the encoding would give access to all the fields
+	if d.fields != nil {
+		return "", nil
+	}
+
+	if len(d.buf) == 0 {
+		d.copyFromItem()
+	}
+
+	if ed, ok := d.codec.NewDocument(d.buf).(document.EncodedDocument); ok {
+		return ed.Encoding()
+	}
+
+	return "", nil
+}
+
+func (d *lazilyDecodedDocument) decode() document.Document {
+	return newDocument(d.codec, d.buf, d.interner)
 }
 
 func (d *lazilyDecodedDocument) Key() []byte {
@@ -324,17 +864,39 @@ func (d *lazilyDecodedDocument) copyFromItem() error {
 // Iterate goes through all the documents of the table and calls the given function by passing each one of them.
 // If the given function returns an error, the iteration stops.
 func (t *Table) Iterate(fn func(d document.Document) error) error {
+	return t.iterate(t.Store.NewIterator(engine.IteratorConfig{}), fn)
+}
+
+// IteratePartitions goes through the documents stored in the selected partitions of the table,
+// in the order of their keys. If the table is not partitioned, it iterates over the whole table.
+func (t *Table) IteratePartitions(parts []int, fn func(d document.Document) error) error {
+	ps, ok := t.Store.(*partitionedStore)
+	if !ok {
+		return t.Iterate(fn)
+	}
+
+	return t.iterate(ps.iterator(parts, engine.IteratorConfig{}), fn)
+}
+
+func (t *Table) iterate(it engine.Iterator, fn func(d document.Document) error) error {
 	// To avoid unnecessary allocations, we create the struct once and reuse
 	// it during each iteration.
 	d := lazilyDecodedDocument{
-		codec: t.tx.db.Codec,
+		codec:    t.codec,
+		interner: document.NewInterner(),
+		fields:   t.fields,
 	}
 
-	it := t.Store.NewIterator(engine.IteratorConfig{})
+	it = engine.NewPrefetchIterator(it, t.tx.db.scanPrefetch())
 	defer it.Close()
 
 	var err error
 	for it.Seek(nil); it.Valid(); it.Next() {
+		err = t.tx.checkContext()
+		if err != nil {
+			return err
+		}
+
 		d.Reset()
 		d.item = it.Item()
 		// d must be passed as pointer, not value,
@@ -351,6 +913,12 @@ func (t *Table) Iterate(fn func(d document.Document) error) error {
 
 // GetDocument returns one document by key.
 func (t *Table) GetDocument(key []byte) (document.Document, error) {
+	// documents are fetched one by one by the scans of indexes
+	err := t.tx.checkContext()
+	if err != nil {
+		return nil, err
+	}
+
 	v, err := t.Store.Get(key)
 	if err != nil {
 		if err == engine.ErrKeyNotFound {
@@ -360,17 +928,40 @@ func (t *Table) GetDocument(key []byte) (document.Document, error) {
 	}
 
 	var d encodedDocumentWithKey
-	d.Document = t.tx.db.Codec.NewDocument(v)
+	d.Document = t.codec.NewDocument(v)
+	if t.fields != nil {
+		d.Document = &projectedDocument{Document: d.Document, fields: t.fields}
+	}
 	d.key = key
 	return &d, err
 }
 
+// KeyToValue decodes a key of the table into the value of its primary key,
+// as returned by the pk() function. If the table doesn't have a primary key,
+// the key is decoded by the key generator of the database.
+func (t *Table) KeyToValue(k []byte) (document.Value, error) {
+	ti, err := t.infoStore.Get(t.tx, t.name)
+	if err != nil {
+		return document.Value{}, err
+	}
+
+	if pk := ti.GetPrimaryKey(); pk != nil {
+		if pk.Type != 0 {
+			return key.Decode(pk.Type, k)
+		}
+
+		return key.DecodeValue(k)
+	}
+
+	return t.tx.db.KeyGenerator.KeyToValue(k)
+}
+
 // generate a key for d based on the table configuration.
 // if the table has a primary key, it extracts the field from
 // the document, converts it to the targeted type and returns
 // its encoded version.
-// if there are no primary key in the table, a default
-// key is generated, called the docid.
+// if there are no primary key in the table, a key is generated
+// by the database key generator, called the docid.
 func (t *Table) generateKey(d document.Document) ([]byte, error) {
 	ti, err := t.infoStore.Get(t.tx, t.name)
 	if err != nil {
@@ -380,7 +971,8 @@ func (t *Table) generateKey(d document.Document) ([]byte, error) {
 	if pk := ti.GetPrimaryKey(); pk != nil {
 		v, err := pk.Path.GetValue(d)
 		if err == document.ErrFieldNotFound {
-			return nil, fmt.Errorf("missing primary key at path %q", pk.Path)
+			return nil, newConstraintViolationError(CodeNotNullViolation, ConstraintPrimaryKey, pk.Path,
+				fmt.Sprintf("missing primary key at path %q", pk.Path))
 		}
 		if err != nil {
 			return nil, err
@@ -397,14 +989,7 @@ func (t *Table) generateKey(d document.Document) ([]byte, error) {
 		return key.AppendValue(nil, v)
 	}
 
-	docid, err := t.Store.NextSequence()
-	if err != nil {
-		return nil, err
-	}
-
-	buf := make([]byte, binary.MaxVarintLen64)
-	n := binary.PutUvarint(buf, docid)
-	return buf[:n], nil
+	return t.tx.db.KeyGenerator.NextKey(t, d)
 }
 
 // ValidateConstraints check the table configuration for constraints and validates the document
@@ -412,6 +997,13 @@ func (t *Table) generateKey(d document.Document) ([]byte, error) {
 // the document, the fields are converted to these types when possible. if the conversion
 // fails, an error is returned.
 func (t *Table) ValidateConstraints(d document.Document) (document.Document, error) {
+	return t.validateConstraints(d, false)
+}
+
+// validateConstraints validates d like ValidateConstraints. If withDefaults is true,
+// the missing fields with a default value are set first, which is only done for
+// the documents being inserted.
+func (t *Table) validateConstraints(d document.Document, withDefaults bool) (document.Document, error) {
 	info, err := t.Info()
 	if err != nil {
 		return nil, err
@@ -432,6 +1024,15 @@ func (t *Table) ValidateConstraints(d document.Document) (document.Document, err
 		return nil, err
 	}
 
+	if withDefaults {
+		for _, fc := range info.FieldConstraints {
+			err := t.applyDefault(&fb, &fc)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	if pk != nil {
 		err = validateConstraint(&fb, pk)
 		if err != nil {
@@ -446,9 +1047,95 @@ func (t *Table) ValidateConstraints(d document.Document) (document.Document, err
 		}
 	}
 
+	// check constraints are evaluated once every field has been converted
+	for _, fc := range info.FieldConstraints {
+		err := t.validateCheck(&fb, &fc)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &fb, err
 }
 
+// applyDefault sets the field of c to its default value, if any, when it is missing from d.
+// A field explicitly set to null is left untouched, and so are the fields whose
+// parent document is missing or is an array.
+func (t *Table) applyDefault(d document.Document, c *FieldConstraint) error {
+	if c.DefaultValue == nil {
+		return nil
+	}
+
+	parent, err := getParentValue(d, c.Path)
+	if err == document.ErrFieldNotFound || err == document.ErrValueNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if parent.Type != document.DocumentValue {
+		return nil
+	}
+
+	field := c.Path[len(c.Path)-1]
+	if field.FieldName == "" {
+		return nil
+	}
+
+	buf := parent.V.(*document.FieldBuffer)
+	_, err = buf.GetByField(field.FieldName)
+	if err != document.ErrFieldNotFound {
+		return err
+	}
+
+	v, err := c.DefaultValue.Eval(t.tx, nil)
+	if err != nil {
+		return err
+	}
+
+	buf.Add(field.FieldName, v)
+	return nil
+}
+
+// validateCheck evaluates the CHECK constraint of c against d, if any.
+// Like in SQL, the constraint is satisfied if the field is null or missing,
+// or if the expression evaluates to null.
+func (t *Table) validateCheck(d document.Document, c *FieldConstraint) error {
+	if c.Check == nil {
+		return nil
+	}
+
+	v, err := c.Path.GetValue(d)
+	if err == document.ErrFieldNotFound || err == document.ErrValueNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if v.Type == document.NullValue {
+		return nil
+	}
+
+	v, err = c.Check.Eval(t.tx, d)
+	if err != nil {
+		return err
+	}
+	if v.Type == document.NullValue {
+		return nil
+	}
+
+	ok, err := v.IsTruthy()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return newConstraintViolationError(CodeCheckViolation, ConstraintCheck, c.Path,
+			fmt.Sprintf("field %q violates check constraint %s", c.Path, c.Check))
+	}
+
+	return nil
+}
+
 func validateConstraint(d document.Document, c *FieldConstraint) error {
 	// get the parent buffer
 	parent, err := getParentValue(d, c.Path)
@@ -466,7 +1153,8 @@ func validateConstraint(d document.Document, c *FieldConstraint) error {
 		if field.FieldName == "" {
 			// if the field is not found we make sure it is not required
 			if c.IsNotNull {
-				return fmt.Errorf("field %q is required and must be not null", c.Path)
+				return newConstraintViolationError(CodeNotNullViolation, ConstraintNotNull, c.Path,
+					fmt.Sprintf("field %q is required and must be not null", c.Path))
 			}
 			return nil
 		}
@@ -476,7 +1164,8 @@ func validateConstraint(d document.Document, c *FieldConstraint) error {
 		if err != nil {
 			if err == document.ErrFieldNotFound {
 				if c.IsNotNull {
-					return fmt.Errorf("field %q is required and must be not null", c.Path)
+					return newConstraintViolationError(CodeNotNullViolation, ConstraintNotNull, c.Path,
+						fmt.Sprintf("field %q is required and must be not null", c.Path))
 				}
 
 				return nil
@@ -486,7 +1175,8 @@ func validateConstraint(d document.Document, c *FieldConstraint) error {
 		}
 		// if the field is null we make sure it is not required
 		if v.Type == document.NullValue && c.IsNotNull {
-			return fmt.Errorf("field %q is required and must be not null", c.Path)
+			return newConstraintViolationError(CodeNotNullViolation, ConstraintNotNull, c.Path,
+				fmt.Sprintf("field %q is required and must be not null", c.Path))
 		}
 
 		// if not we convert it and replace it in the buffer
@@ -521,7 +1211,8 @@ func validateConstraint(d document.Document, c *FieldConstraint) error {
 		if err != nil {
 			if err == document.ErrValueNotFound {
 				if c.IsNotNull {
-					return fmt.Errorf("value %q is required and must be not null", c.Path)
+					return newConstraintViolationError(CodeNotNullViolation, ConstraintNotNull, c.Path,
+						fmt.Sprintf("value %q is required and must be not null", c.Path))
 				}
 
 				return nil
@@ -569,7 +1260,7 @@ func (t *Table) ReIndex() error {
 	}
 
 	if info.readOnly {
-		return errors.New("cannot write to read-only table")
+		return ErrReadOnlyTable
 	}
 
 	indexes, err := t.Indexes()
@@ -586,3 +1277,20 @@ func (t *Table) ReIndex() error {
 
 	return nil
 }
+
+// Analyze collects statistics about the values of all the indexes of the table.
+func (t *Table) Analyze() error {
+	indexes, err := t.Indexes()
+	if err != nil {
+		return err
+	}
+
+	for _, idx := range indexes {
+		err = t.tx.Analyze(idx.Opts.IndexName)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
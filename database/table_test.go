@@ -1,6 +1,7 @@
 package database_test
 
 import (
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -129,6 +130,160 @@ func TestTableGetDocument(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, vc, fc)
 	})
+
+	t.Run("Should decode documents of tables with field constraints", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		err := tx.CreateTable("test", &database.TableInfo{
+			FieldConstraints: []database.FieldConstraint{
+				{Path: parsePath(t, "fielda"), Type: document.TextValue},
+				{Path: parsePath(t, "fieldb"), Type: document.TextValue},
+			},
+		})
+		require.NoError(t, err)
+		tb, err := tx.GetTable("test")
+		require.NoError(t, err)
+
+		doc := newDocument()
+		doc.Add("fieldc", document.NewIntegerValue(40))
+
+		key, err := tb.Insert(doc)
+		require.NoError(t, err)
+
+		res, err := tb.GetDocument(key)
+		require.NoError(t, err)
+		data, err := document.MarshalJSON(res)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"fielda": "a", "fieldb": "b", "fieldc": 40}`, string(data))
+	})
+}
+
+// TestTablePrefetch verifies that scans return the same documents when reading ahead.
+func TestTablePrefetch(t *testing.T) {
+	db, err := database.New(memoryengine.NewEngine(), database.Options{Codec: msgpack.NewCodec(), ScanPrefetch: 4})
+	require.NoError(t, err)
+	defer db.Close()
+
+	tx, err := db.Begin(true)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	err = tx.CreateTable("test", nil)
+	require.NoError(t, err)
+	err = tx.CreateIndex(database.IndexConfig{IndexName: "idx_test_a", TableName: "test", Path: parsePath(t, "a")})
+	require.NoError(t, err)
+	tb, err := tx.GetTable("test")
+	require.NoError(t, err)
+
+	for i := 0; i < 20; i++ {
+		_, err = tb.Insert(document.NewFieldBuffer().Add("a", document.NewIntegerValue(int64(20-i))))
+		require.NoError(t, err)
+	}
+
+	var values []int64
+	err = tb.Iterate(func(d document.Document) error {
+		v, err := d.GetByField("a")
+		if err != nil {
+			return err
+		}
+		values = append(values, v.V.(int64))
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, values, 20)
+	for i, v := range values {
+		require.Equal(t, int64(20-i), v)
+	}
+
+	idx, err := tx.GetIndex("idx_test_a")
+	require.NoError(t, err)
+	values = values[:0]
+	err = idx.AscendGreaterOrEqual(document.Value{}, func(_, k []byte, _ bool) error {
+		d, err := tb.GetDocument(k)
+		if err != nil {
+			return err
+		}
+		v, err := d.GetByField("a")
+		if err != nil {
+			return err
+		}
+		values = append(values, v.V.(int64))
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, values, 20)
+	for i, v := range values {
+		require.Equal(t, int64(i+1), v)
+	}
+
+	// stopping the iteration early
+	var count int
+	errStop := errors.New("stop")
+	err = tb.Iterate(func(d document.Document) error {
+		count++
+		if count == 3 {
+			return errStop
+		}
+		return nil
+	})
+	require.Equal(t, errStop, err)
+	require.Equal(t, 3, count)
+}
+
+// TestTableProject verifies Project behaviour.
+func TestTableProject(t *testing.T) {
+	tx, cleanup := newTestDB(t)
+	defer cleanup()
+
+	err := tx.CreateTable("test", &database.TableInfo{
+		FieldConstraints: []database.FieldConstraint{
+			{Path: parsePath(t, "fielda"), Type: document.TextValue},
+		},
+	})
+	require.NoError(t, err)
+	tb, err := tx.GetTable("test")
+	require.NoError(t, err)
+
+	doc := newDocument()
+	doc.Add("fieldc", document.NewIntegerValue(40))
+	key, err := tb.Insert(doc)
+	require.NoError(t, err)
+
+	p := tb.Project("fieldc", "fielda", "fieldd")
+
+	check := func(d document.Document) {
+		data, err := document.MarshalJSON(d)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"fieldc": 40, "fielda": "a"}`, string(data))
+
+		_, err = d.GetByField("fieldb")
+		require.Equal(t, document.ErrFieldNotFound, err)
+		v, err := d.GetByField("fieldc")
+		require.NoError(t, err)
+		require.Equal(t, document.NewIntegerValue(40), v)
+
+		require.Equal(t, key, d.(document.Keyer).Key())
+	}
+
+	var count int
+	err = p.Iterate(func(d document.Document) error {
+		count++
+		check(d)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+
+	d, err := p.GetDocument(key)
+	require.NoError(t, err)
+	check(d)
+
+	// the table itself is not projected
+	d, err = tb.GetDocument(key)
+	require.NoError(t, err)
+	_, err = d.GetByField("fieldb")
+	require.NoError(t, err)
 }
 
 // TestTableInsert verifies Insert behaviour.
@@ -193,6 +348,43 @@ func TestTableInsert(t *testing.T) {
 		require.Equal(t, a+1, b)
 	})
 
+	t.Run("Should use the key generator of the database", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			kg       database.KeyGenerator
+			expected []byte
+		}{
+			{"sequence", database.SequenceKeyGenerator, []byte{1}},
+			{"binary sequence", database.BinarySequenceKeyGenerator, []byte{0, 0, 0, 0, 0, 0, 0, 1}},
+			{"func", database.KeyGeneratorFunc(func(t *database.Table, d document.Document) ([]byte, error) {
+				return []byte("foo"), nil
+			}), []byte("foo")},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				db, err := database.New(memoryengine.NewEngine(), database.Options{
+					Codec:        msgpack.NewCodec(),
+					KeyGenerator: test.kg,
+				})
+				require.NoError(t, err)
+
+				tx, err := db.Begin(true)
+				require.NoError(t, err)
+				defer tx.Rollback()
+
+				err = tx.CreateTable("test", nil)
+				require.NoError(t, err)
+				tb, err := tx.GetTable("test")
+				require.NoError(t, err)
+
+				key, err := tb.Insert(newDocument())
+				require.NoError(t, err)
+				require.Equal(t, test.expected, key)
+			})
+		}
+	})
+
 	t.Run("Should use the right field if primary key is specified", func(t *testing.T) {
 		tx, cleanup := newTestDB(t)
 		defer cleanup()
@@ -221,7 +413,7 @@ func TestTableInsert(t *testing.T) {
 
 		// insert again
 		k, err = tb.Insert(doc)
-		require.Equal(t, database.ErrDuplicateDocument, err)
+		require.True(t, errors.Is(err, database.ErrDuplicateDocument))
 	})
 
 	t.Run("Should convert values into the right types if there are constraints", func(t *testing.T) {
@@ -337,8 +529,8 @@ func TestTableInsert(t *testing.T) {
 
 		err := tx.CreateTable("test", &database.TableInfo{
 			FieldConstraints: []database.FieldConstraint{
-				{parsePath(t, "foo"), document.IntegerValue, false, false},
-				{parsePath(t, "bar"), document.IntegerValue, false, false},
+				{parsePath(t, "foo"), document.IntegerValue, false, false, false, nil, nil},
+				{parsePath(t, "bar"), document.IntegerValue, false, false, false, nil, nil},
 			},
 		})
 		require.NoError(t, err)
@@ -375,7 +567,7 @@ func TestTableInsert(t *testing.T) {
 		// no enforced type, not null
 		err := tx.CreateTable("test1", &database.TableInfo{
 			FieldConstraints: []database.FieldConstraint{
-				{parsePath(t, "foo"), 0, false, true},
+				{parsePath(t, "foo"), 0, false, true, false, nil, nil},
 			},
 		})
 		require.NoError(t, err)
@@ -385,7 +577,7 @@ func TestTableInsert(t *testing.T) {
 		// enforced type, not null
 		err = tx.CreateTable("test2", &database.TableInfo{
 			FieldConstraints: []database.FieldConstraint{
-				{parsePath(t, "foo"), document.IntegerValue, false, true},
+				{parsePath(t, "foo"), document.IntegerValue, false, true, false, nil, nil},
 			},
 		})
 		require.NoError(t, err)
@@ -429,7 +621,7 @@ func TestTableInsert(t *testing.T) {
 
 		err := tx.CreateTable("test1", &database.TableInfo{
 			FieldConstraints: []database.FieldConstraint{
-				{parsePath(t, "foo[1]"), 0, false, true},
+				{parsePath(t, "foo[1]"), 0, false, true, false, nil, nil},
 			},
 		})
 		require.NoError(t, err)
@@ -448,6 +640,76 @@ func TestTableInsert(t *testing.T) {
 }
 
 // TestTableDelete verifies Delete behaviour.
+func TestTableConflict(t *testing.T) {
+	tx, cleanup := newTestDB(t)
+	defer cleanup()
+
+	err := tx.CreateTable("test", &database.TableInfo{
+		FieldConstraints: []database.FieldConstraint{
+			{Path: parsePath(t, "a"), Type: document.IntegerValue, IsPrimaryKey: true},
+		},
+	})
+	require.NoError(t, err)
+	err = tx.CreateIndex(database.IndexConfig{
+		IndexName: "idx_b", TableName: "test", Path: parsePath(t, "b"), Unique: true,
+	})
+	require.NoError(t, err)
+
+	tb, err := tx.GetTable("test")
+	require.NoError(t, err)
+
+	doc := func(a int64, b string) document.Document {
+		return document.NewFieldBuffer().
+			Add("a", document.NewIntegerValue(a)).
+			Add("b", document.NewTextValue(b))
+	}
+
+	k, err := tb.Conflict(doc(1, "foo"))
+	require.NoError(t, err)
+	require.Nil(t, k)
+
+	key1, err := tb.Insert(doc(1, "foo"))
+	require.NoError(t, err)
+
+	// same primary key, converted by the constraints
+	k, err = tb.Conflict(document.NewFieldBuffer().Add("a", document.NewDoubleValue(1)))
+	require.NoError(t, err)
+	require.Equal(t, key1, k)
+
+	// same value for a unique index
+	k, err = tb.Conflict(doc(2, "foo"))
+	require.NoError(t, err)
+	require.Equal(t, key1, k)
+
+	k, err = tb.Conflict(doc(2, "bar"))
+	require.NoError(t, err)
+	require.Nil(t, k)
+}
+
+func TestTableInsertFrom(t *testing.T) {
+	tb, cleanup := newTestTable(t)
+	defer cleanup()
+
+	docs := []document.Document{newDocument(), newDocument(), newDocument()}
+
+	n, err := tb.InsertFrom(context.Background(), document.NewIterator(docs...))
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+
+	count, err := document.NewStream(tb).Count()
+	require.NoError(t, err)
+	require.Equal(t, 3, count)
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		n, err := tb.InsertFrom(ctx, document.NewIterator(docs...))
+		require.Equal(t, context.Canceled, err)
+		require.Zero(t, n)
+	})
+}
+
 func TestTableDelete(t *testing.T) {
 	t.Run("Should fail if not found", func(t *testing.T) {
 		tb, cleanup := newTestTable(t)
@@ -535,6 +797,241 @@ func TestTableReplace(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, "c", f.V.(string))
 	})
+
+	t.Run("Should update the indexes", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		err := tx.CreateTable("test", nil)
+		require.NoError(t, err)
+
+		err = tx.CreateIndex(database.IndexConfig{
+			IndexName: "idxFoo", TableName: "test", Path: parsePath(t, "foo"),
+		})
+		require.NoError(t, err)
+		idx, err := tx.GetIndex("idxFoo")
+		require.NoError(t, err)
+
+		tb, err := tx.GetTable("test")
+		require.NoError(t, err)
+
+		key, err := tb.Insert(newDocument().Add("foo", document.NewIntegerValue(1)))
+		require.NoError(t, err)
+
+		countValue := func(v document.Value) int {
+			var count int
+			err := idx.AscendGreaterOrEqual(v, func(val, k []byte, isEqual bool) error {
+				require.Equal(t, key, k)
+				if isEqual {
+					count++
+				}
+				return nil
+			})
+			require.NoError(t, err)
+			return count
+		}
+
+		// same indexed value
+		err = tb.Replace(key, document.NewFieldBuffer().Add("foo", document.NewIntegerValue(1)))
+		require.NoError(t, err)
+		require.Equal(t, 1, countValue(document.NewIntegerValue(1)))
+
+		// different indexed value
+		err = tb.Replace(key, document.NewFieldBuffer().Add("foo", document.NewIntegerValue(2)))
+		require.NoError(t, err)
+		require.Equal(t, 0, countValue(document.NewIntegerValue(1)))
+		require.Equal(t, 1, countValue(document.NewIntegerValue(2)))
+
+		// missing indexed value
+		err = tb.Replace(key, newDocument())
+		require.NoError(t, err)
+		require.Equal(t, 0, countValue(document.NewIntegerValue(2)))
+	})
+}
+
+// TestTableMerge verifies Merge behaviour.
+func TestTableMerge(t *testing.T) {
+	t.Run("Should fail if not found", func(t *testing.T) {
+		tb, cleanup := newTestTable(t)
+		defer cleanup()
+
+		err := tb.Merge([]byte("id"), newDocument())
+		require.Equal(t, database.ErrDocumentNotFound, err)
+	})
+
+	t.Run("Should merge the fields into the document", func(t *testing.T) {
+		tb, cleanup := newTestTable(t)
+		defer cleanup()
+
+		key, err := tb.Insert(newDocument())
+		require.NoError(t, err)
+
+		err = tb.Merge(key, document.NewFieldBuffer().
+			Add("fielda", document.NewTextValue("c")).
+			Add("fieldc", document.NewIntegerValue(10)))
+		require.NoError(t, err)
+
+		res, err := tb.GetDocument(key)
+		require.NoError(t, err)
+		data, err := document.MarshalJSON(res)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"fielda": "c", "fieldb": "b", "fieldc": 10}`, string(data))
+	})
+
+	t.Run("Should update the indexes of the modified fields", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		err := tx.CreateTable("test", nil)
+		require.NoError(t, err)
+
+		err = tx.CreateIndex(database.IndexConfig{
+			IndexName: "idxFoo", TableName: "test", Path: parsePath(t, "foo"),
+		})
+		require.NoError(t, err)
+		idx, err := tx.GetIndex("idxFoo")
+		require.NoError(t, err)
+
+		tb, err := tx.GetTable("test")
+		require.NoError(t, err)
+
+		key, err := tb.Insert(newDocument().Add("foo", document.NewIntegerValue(1)))
+		require.NoError(t, err)
+
+		err = tb.Merge(key, document.NewFieldBuffer().Add("foo", document.NewIntegerValue(2)))
+		require.NoError(t, err)
+
+		// the old value must have been removed from the index
+		var count int
+		err = idx.AscendGreaterOrEqual(document.Value{}, func(val, k []byte, isEqual bool) error {
+			require.Equal(t, key, k)
+			count++
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 1, count)
+
+		count = 0
+		err = idx.AscendGreaterOrEqual(document.NewIntegerValue(2), func(val, k []byte, isEqual bool) error {
+			require.True(t, isEqual)
+			count++
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 1, count)
+	})
+
+	t.Run("Should fail if the primary key is modified", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		err := tx.CreateTable("test", &database.TableInfo{
+			FieldConstraints: []database.FieldConstraint{
+				{Path: parsePath(t, "foo"), Type: document.IntegerValue, IsPrimaryKey: true},
+			},
+		})
+		require.NoError(t, err)
+
+		tb, err := tx.GetTable("test")
+		require.NoError(t, err)
+
+		key, err := tb.Insert(newDocument().Add("foo", document.NewIntegerValue(1)))
+		require.NoError(t, err)
+
+		err = tb.Merge(key, document.NewFieldBuffer().Add("foo", document.NewIntegerValue(2)))
+		require.Error(t, err)
+	})
+}
+
+// TestTableUpdate verifies Update behaviour.
+func TestTableUpdate(t *testing.T) {
+	t.Run("Should fail if not found", func(t *testing.T) {
+		tb, cleanup := newTestTable(t)
+		defer cleanup()
+
+		err := tb.Update([]byte("id"), newDocument(), []document.ValuePath{parsePath(t, "fielda")})
+		require.Equal(t, database.ErrDocumentNotFound, err)
+	})
+
+	t.Run("Should only update the indexes of the modified paths", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		err := tx.CreateTable("test", nil)
+		require.NoError(t, err)
+
+		for _, path := range []string{"a", "b.c", "b.d"} {
+			err = tx.CreateIndex(database.IndexConfig{
+				IndexName: "idx_" + path, TableName: "test", Path: parsePath(t, path),
+			})
+			require.NoError(t, err)
+		}
+
+		tb, err := tx.GetTable("test")
+		require.NoError(t, err)
+
+		doc := func(a, c, d int64) document.Document {
+			return document.NewFieldBuffer().
+				Add("a", document.NewIntegerValue(a)).
+				Add("b", document.NewDocumentValue(document.NewFieldBuffer().
+					Add("c", document.NewIntegerValue(c)).
+					Add("d", document.NewIntegerValue(d))))
+		}
+
+		indexed := func(name string) []int64 {
+			idx, err := tx.GetIndex(name)
+			require.NoError(t, err)
+
+			var values []int64
+			err = idx.AscendGreaterOrEqual(document.Value{}, func(val, k []byte, isEqual bool) error {
+				v, err := key.DecodeValue(val)
+				require.NoError(t, err)
+				values = append(values, v.V.(int64))
+				return nil
+			})
+			require.NoError(t, err)
+			return values
+		}
+
+		k, err := tb.Insert(doc(1, 1, 1))
+		require.NoError(t, err)
+
+		// modifying b updates the indexes of the paths it contains
+		err = tb.Update(k, doc(1, 2, 2), []document.ValuePath{parsePath(t, "b")})
+		require.NoError(t, err)
+		require.Equal(t, []int64{1}, indexed("idx_a"))
+		require.Equal(t, []int64{2}, indexed("idx_b.c"))
+		require.Equal(t, []int64{2}, indexed("idx_b.d"))
+
+		// the indexes of a and b.d are not rewritten, even though the values
+		// of the document differ: only the paths given to Update are considered
+		err = tb.Update(k, doc(3, 3, 3), []document.ValuePath{parsePath(t, "b.c")})
+		require.NoError(t, err)
+		require.Equal(t, []int64{1}, indexed("idx_a"))
+		require.Equal(t, []int64{3}, indexed("idx_b.c"))
+		require.Equal(t, []int64{2}, indexed("idx_b.d"))
+	})
+
+	t.Run("Should fail if the primary key is modified", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		err := tx.CreateTable("test", &database.TableInfo{
+			FieldConstraints: []database.FieldConstraint{
+				{Path: parsePath(t, "foo"), Type: document.IntegerValue, IsPrimaryKey: true},
+			},
+		})
+		require.NoError(t, err)
+
+		tb, err := tx.GetTable("test")
+		require.NoError(t, err)
+
+		key, err := tb.Insert(document.NewFieldBuffer().Add("foo", document.NewIntegerValue(1)))
+		require.NoError(t, err)
+
+		err = tb.Update(key, document.NewFieldBuffer().Add("foo", document.NewIntegerValue(2)), []document.ValuePath{parsePath(t, "foo")})
+		require.Error(t, err)
+	})
 }
 
 // TestTableTruncate verifies Truncate behaviour.
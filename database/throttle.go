@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket, refilled at a given rate per second
+// and holding at most one second worth of tokens.
+type rateLimiter struct {
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// take consumes a token, waiting at most timeout for one to be available,
+// or returns ErrThrottled. If rate is not positive, it is not limited.
+func (l *rateLimiter) take(ctx context.Context, rate int, timeout time.Duration) error {
+	if rate <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	burst := float64(rate)
+	if l.last.IsZero() {
+		l.tokens = burst
+	} else {
+		l.tokens += now.Sub(l.last).Seconds() * burst
+		if l.tokens > burst {
+			l.tokens = burst
+		}
+	}
+	l.last = now
+
+	var wait time.Duration
+	if l.tokens < 1 {
+		wait = time.Duration((1 - l.tokens) / burst * float64(time.Second))
+	}
+	if wait > timeout {
+		l.mu.Unlock()
+		return ErrThrottled
+	}
+
+	// the token is reserved, to be used once the wait is over
+	l.tokens--
+	l.mu.Unlock()
+
+	if wait == 0 {
+		return nil
+	}
+
+	t := time.NewTimer(wait)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// throttleWrite is called before starting a read-write transaction.
+func (db *Database) throttleWrite() error {
+	db.settingsMu.RLock()
+	rate, timeout := db.MaxWriteRate, db.ThrottleTimeout
+	db.settingsMu.RUnlock()
+
+	return db.writeLimiter.take(context.Background(), rate, timeout)
+}
+
+// ThrottleStatement returns ErrThrottled if the maximum number of statements per second
+// of the database is reached and no statement can be run within its ThrottleTimeout,
+// or waits until it can be. It is called before running every statement of a query.
+func (db *Database) ThrottleStatement(ctx context.Context) error {
+	db.settingsMu.RLock()
+	rate, timeout := db.MaxStatementRate, db.ThrottleTimeout
+	db.settingsMu.RUnlock()
+
+	return db.statementLimiter.take(ctx, rate, timeout)
+}
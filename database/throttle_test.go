@@ -0,0 +1,70 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document/encoding/msgpack"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThrottling(t *testing.T) {
+	t.Run("Writes", func(t *testing.T) {
+		db, err := database.New(memoryengine.NewEngine(), database.Options{
+			Codec:        msgpack.NewCodec(),
+			MaxWriteRate: 10,
+		})
+		require.NoError(t, err)
+		defer db.Close()
+
+		// one second worth of transactions can be started at once
+		for i := 0; i < 10; i++ {
+			tx, err := db.Begin(true)
+			require.NoError(t, err)
+			require.NoError(t, tx.Rollback())
+		}
+
+		_, err = db.Begin(true)
+		require.Equal(t, database.ErrThrottled, err)
+		require.Equal(t, database.CodeConfigurationLimitExceeded, database.CodeOf(err))
+
+		// read-only transactions are not limited
+		tx, err := db.Begin(false)
+		require.NoError(t, err)
+		require.NoError(t, tx.Rollback())
+
+		// tokens are refilled over time
+		time.Sleep(150 * time.Millisecond)
+		tx, err = db.Begin(true)
+		require.NoError(t, err)
+		require.NoError(t, tx.Rollback())
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		db, err := database.New(memoryengine.NewEngine(), database.Options{
+			Codec:            msgpack.NewCodec(),
+			MaxStatementRate: 20,
+			ThrottleTimeout:  time.Second,
+		})
+		require.NoError(t, err)
+		defer db.Close()
+
+		ctx := context.Background()
+		for i := 0; i < 20; i++ {
+			require.NoError(t, db.ThrottleStatement(ctx))
+		}
+
+		// the next statement waits for a token
+		start := time.Now()
+		require.NoError(t, db.ThrottleStatement(ctx))
+		require.GreaterOrEqual(t, int64(time.Since(start)), int64(40*time.Millisecond))
+
+		// unless the context is canceled first
+		cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		require.Equal(t, context.DeadlineExceeded, db.ThrottleStatement(cctx))
+	})
+}
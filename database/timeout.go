@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// statementTimeoutKey is the key of the timeout of the contexts
+// returned by WithStatementTimeout.
+type statementTimeoutKey struct{}
+
+type statementTimeout struct {
+	timeout  time.Duration
+	deadline time.Time
+}
+
+// WithStatementTimeout returns a copy of ctx which is canceled once timeout has elapsed.
+// Statements run with the returned context fail with an error wrapping ErrStatementTimeout
+// once it is done, see ContextErr. If timeout is zero or negative, the returned context
+// is only canceled when ctx is or when the returned function is called.
+func WithStatementTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	st := statementTimeout{timeout: timeout, deadline: time.Now().Add(timeout)}
+	ctx, cancel := context.WithDeadline(ctx, st.deadline)
+	return context.WithValue(ctx, statementTimeoutKey{}, st), cancel
+}
+
+// ContextErr returns nil if ctx is not done, an error wrapping ErrStatementTimeout
+// if ctx was canceled by the timeout passed to WithStatementTimeout,
+// or the error of ctx otherwise.
+func ContextErr(ctx context.Context) error {
+	err := ctx.Err()
+	if err != context.DeadlineExceeded {
+		return err
+	}
+
+	// the deadline of ctx may be the one of its parent, if it is earlier
+	if st, ok := ctx.Value(statementTimeoutKey{}).(statementTimeout); ok && !time.Now().Before(st.deadline) {
+		return fmt.Errorf("%w: statement canceled after %s", ErrStatementTimeout, st.timeout)
+	}
+
+	return err
+}
+
+// SetContext sets the context of the statement run by the transaction,
+// which can be nil: the tables stop being read with the error returned by ContextErr
+// once ctx is done, which aborts long running statements without waiting for them to end.
+func (tx *Transaction) SetContext(ctx context.Context) {
+	tx.ctx = ctx
+}
+
+// checkContext returns an error if the context of the transaction is done.
+func (tx *Transaction) checkContext() error {
+	if tx.ctx == nil {
+		return nil
+	}
+
+	return ContextErr(tx.ctx)
+}
+
+// DefaultStatementTimeout returns the maximum duration of the statements,
+// or zero if it is not limited.
+func (db *Database) DefaultStatementTimeout() time.Duration {
+	db.settingsMu.RLock()
+	defer db.settingsMu.RUnlock()
+
+	return db.StatementTimeout
+}
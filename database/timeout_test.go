@@ -0,0 +1,37 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/genjidb/genji/database"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithStatementTimeout(t *testing.T) {
+	ctx, cancel := database.WithStatementTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	require.NoError(t, database.ContextErr(ctx))
+
+	<-ctx.Done()
+	err := database.ContextErr(ctx)
+	require.True(t, errors.Is(err, database.ErrStatementTimeout))
+	require.EqualError(t, err, "statement timeout: statement canceled after 1ms")
+
+	// the deadline of the parent is earlier
+	parent, pcancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer pcancel()
+	ctx, cancel = database.WithStatementTimeout(parent, time.Hour)
+	defer cancel()
+	<-ctx.Done()
+	require.Equal(t, context.DeadlineExceeded, database.ContextErr(ctx))
+
+	// without timeout
+	ctx, cancel = database.WithStatementTimeout(context.Background(), 0)
+	_, ok := ctx.Deadline()
+	require.False(t, ok)
+	cancel()
+	require.Equal(t, context.Canceled, database.ContextErr(ctx))
+}
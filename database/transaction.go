@@ -1,9 +1,13 @@
 package database
 
 import (
-	"errors"
+	"context"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/engine"
@@ -11,11 +15,22 @@ import (
 )
 
 var (
-	internalPrefix     = "__genji_"
-	tableInfoStoreName = internalPrefix + "tables"
-	indexStoreName     = internalPrefix + "indexes"
+	internalPrefix        = "__genji_"
+	tableInfoStoreName    = internalPrefix + "tables"
+	indexStoreName        = internalPrefix + "indexes"
+	procedureStoreName    = internalPrefix + "procedures"
+	eventStoreName        = internalPrefix + "events"
+	settingStoreName      = internalPrefix + "settings"
+	transactionsTableName = internalPrefix + "transactions"
+	kvStorePrefix         = internalPrefix + "kv_"
 )
 
+// IsSystemTable returns true if name is the name of a table
+// managed by the database, like __genji_tables.
+func IsSystemTable(name string) bool {
+	return strings.HasPrefix(name, internalPrefix)
+}
+
 // Transaction represents a database transaction. It provides methods for managing the
 // collection of tables and the transaction itself.
 // Transaction is either read-only or read/write. Read-only can be used to read tables
@@ -26,8 +41,158 @@ type Transaction struct {
 	tx       engine.Transaction
 	writable bool
 
+	// journal wraps tx for read-write transactions
+	// and is used to roll back to savepoints.
+	journal *journal
+
 	tableInfoStore *tableInfoStore
 	indexStore     *indexStore
+
+	// releaseWriter lets other read-write transactions start.
+	// It is nil for read-only transactions or once called.
+	releaseWriter func()
+
+	startedAt time.Time
+	// statement that started the transaction, if known.
+	statement string
+	// if non-nil, the transaction was aborted and
+	// this error is returned by every operation.
+	abortErr error
+	// protects abortErr, since transactions too old can be aborted
+	// by other goroutines, see expire.
+	abortMu sync.Mutex
+	// set atomically by KillTransaction. The transaction
+	// is aborted the next time it is used.
+	killed int32
+	// time the transaction was last used, in nanoseconds since the epoch.
+	// It is accessed atomically.
+	lastUsed int64
+
+	// transactions started on the attached databases, by attachment name.
+	attached map[string]*Transaction
+
+	// names of the tables modified by the transaction.
+	written map[string]struct{}
+	// changes made to the catalog, notified once committed.
+	ddl []DDLChange
+	// savepoints created by name, in the order of their creation.
+	savepoints []namedSavepoint
+	// savepoints not yet released or rolled back, in the order of their creation.
+	openSavepoints []*Savepoint
+
+	// context of the running statement, see SetContext.
+	ctx context.Context
+	// see SetIntegerOverflowMode.
+	overflowMode document.OverflowMode
+}
+
+// DefaultIntegerOverflowMode returns the IntegerOverflowMode
+// copied by the transactions when they begin.
+func (db *Database) DefaultIntegerOverflowMode() document.OverflowMode {
+	db.settingsMu.RLock()
+	defer db.settingsMu.RUnlock()
+
+	return db.IntegerOverflowMode
+}
+
+// IntegerOverflowMode returns the mode used by the arithmetic operations
+// evaluated within the transaction.
+func (tx *Transaction) IntegerOverflowMode() document.OverflowMode {
+	return tx.overflowMode
+}
+
+// SetIntegerOverflowMode overrides, for the rest of the transaction,
+// the IntegerOverflowMode of the database copied when it began.
+func (tx *Transaction) SetIntegerOverflowMode(mode document.OverflowMode) {
+	tx.overflowMode = mode
+}
+
+// checkAge rolls back the transaction and returns an error if it has been killed,
+// open for longer than the database MaxTransactionAge or idle for longer
+// than its MaxTransactionIdle. Otherwise, it records that the transaction is used.
+func (tx *Transaction) checkAge() error {
+	tx.abortMu.Lock()
+	defer tx.abortMu.Unlock()
+
+	if tx.abortErr != nil {
+		return tx.abortErr
+	}
+
+	if atomic.LoadInt32(&tx.killed) != 0 {
+		return tx.abort(fmt.Errorf("%w: transaction %d", ErrTransactionKilled, tx.id))
+	}
+
+	tx.db.settingsMu.RLock()
+	max, maxIdle := tx.db.MaxTransactionAge, tx.db.MaxTransactionIdle
+	tx.db.settingsMu.RUnlock()
+
+	now := tx.db.Now()
+	if age := now.Sub(tx.startedAt); max > 0 && age > max {
+		return tx.abort(tx.tooOldError(age, max))
+	}
+
+	lastUsed := atomic.SwapInt64(&tx.lastUsed, now.UnixNano())
+	if idle := now.Sub(time.Unix(0, lastUsed)); maxIdle > 0 && idle > maxIdle {
+		return tx.abort(fmt.Errorf("%w: transaction %d has been idle for %s, exceeding the maximum of %s", ErrTransactionIdle, tx.id, idle, maxIdle))
+	}
+
+	return nil
+}
+
+// Check returns an error if the transaction can't be used anymore, because it has been killed,
+// or has been open or idle for too long, in which case it is rolled back.
+// It is called by every operation of the transaction, and can be called by those
+// not necessarily performing any, like statements that don't read tables.
+func (tx *Transaction) Check() error {
+	return tx.checkAge()
+}
+
+// expire aborts the transaction if it has been open for longer than max,
+// without waiting for it to be used again. Otherwise, it returns the time
+// at which it expires. It is safe to call from another goroutine.
+func (tx *Transaction) expire(max time.Duration) (deadline time.Time) {
+	tx.abortMu.Lock()
+	defer tx.abortMu.Unlock()
+
+	if tx.abortErr != nil {
+		return time.Time{}
+	}
+
+	age := tx.db.Now().Sub(tx.startedAt)
+	if age > max {
+		tx.abort(tx.tooOldError(age, max))
+		return time.Time{}
+	}
+
+	return tx.startedAt.Add(max)
+}
+
+func (tx *Transaction) tooOldError(age, max time.Duration) error {
+	return fmt.Errorf("%w: transaction %d has been open for %s, exceeding the maximum of %s", ErrTransactionTooOld, tx.id, age, max)
+}
+
+// abort rolls back the transaction, which returns err from then on.
+// It must be called with abortMu held.
+func (tx *Transaction) abort(err error) error {
+	rerr := tx.Rollback()
+	if rerr != nil {
+		return rerr
+	}
+
+	tx.abortErr = err
+	if tx.statement != "" {
+		tx.abortErr = fmt.Errorf("%w (started by %q)", tx.abortErr, tx.statement)
+	}
+	return tx.abortErr
+}
+
+// release lets other read-write transactions start, if tx is writable.
+// It can be called multiple times.
+func (tx *Transaction) release() {
+	if tx.releaseWriter != nil {
+		tx.releaseWriter()
+		tx.releaseWriter = nil
+	}
 }
 
 // DB returns the underlying database that created the transaction.
@@ -40,16 +205,29 @@ func (tx *Transaction) Rollback() error {
 	tx.db.attachedTxMu.Lock()
 	defer tx.db.attachedTxMu.Unlock()
 
+	defer tx.release()
+	defer tx.db.untrack(tx)
+
+	tx.rollbackAttached()
+
 	if tx.writable {
 		tx.tableInfoStore.rollback(tx)
 	}
 
+	// the changes made to the catalog are undone
+	if len(tx.ddl) > 0 {
+		tx.db.bumpCatalogVersion()
+		tx.ddl = nil
+	}
+
 	err := tx.tx.Rollback()
 	if err != nil {
 		return err
 	}
 
-	if tx.db.attachedTransaction != nil {
+	// the transaction may have been aborted already
+	// and another one attached since
+	if tx.db.attachedTransaction == tx {
 		tx.db.attachedTransaction = nil
 	}
 
@@ -58,24 +236,71 @@ func (tx *Transaction) Rollback() error {
 
 // Commit the transaction.
 func (tx *Transaction) Commit() error {
+	err := tx.commit()
+	if err != nil {
+		return err
+	}
+
+	tx.db.notifyDDL(tx.ddl)
+	return nil
+}
+
+func (tx *Transaction) commit() error {
+	err := tx.checkAge()
+	if err != nil {
+		return err
+	}
+
+	err = tx.commitAttached()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// persisted settings are applied once committed
+	var applySettings func()
+	if _, ok := tx.written[settingStoreName]; ok {
+		applySettings, err = tx.db.readPersistedSettings(tx.tx)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
 	tx.db.attachedTxMu.Lock()
+
+	err = tx.tx.Commit()
+	if err != nil {
+		// nothing was written: roll back to release the transaction
+		// and forget the tables it created
+		tx.db.attachedTxMu.Unlock()
+		tx.Rollback()
+		return err
+	}
 	defer tx.db.attachedTxMu.Unlock()
 
 	if tx.writable {
 		tx.tableInfoStore.commit(tx)
 	}
 
-	err := tx.tx.Commit()
-	if err != nil {
-		return err
+	if applySettings != nil {
+		applySettings()
 	}
+	tx.db.bumpTableVersions(tx.written)
+	tx.release()
+	tx.db.untrack(tx)
 
 	if tx.db.attachedTransaction != nil {
 		tx.db.attachedTransaction = nil
 	}
 
 	return nil
+}
 
+// StartedAt returns the time at which the transaction was started,
+// according to the clock of the database.
+func (tx *Transaction) StartedAt() time.Time {
+	return tx.startedAt
 }
 
 // Writable indicates if the transaction is writable or not.
@@ -86,16 +311,53 @@ func (tx *Transaction) Writable() bool {
 // CreateTable creates a table with the given name.
 // If it already exists, returns ErrTableAlreadyExists.
 func (tx *Transaction) CreateTable(name string, info *TableInfo) error {
-	if strings.HasPrefix(name, internalPrefix) {
-		return fmt.Errorf("table name must not start with %s", internalPrefix)
+	if err := tx.checkAge(); err != nil {
+		return err
+	}
+
+	atx, tname, err := tx.attachedTable(name)
+	if err != nil {
+		return err
+	}
+	if atx != nil {
+		return atx.CreateTable(tname, info)
+	}
+
+	if IsSystemTable(name) {
+		return NewError(CodeInvalidName, fmt.Sprintf("table name must not start with %s", internalPrefix))
 	}
 
 	if info == nil {
 		info = new(TableInfo)
 	}
 
+	for _, fc := range info.FieldConstraints {
+		if fc.Path.HasWildcard() {
+			return NewError(CodeFeatureNotSupported, fmt.Sprintf("field constraint path %q must not contain wildcards", fc.Path))
+		}
+	}
+
+	if info.Partitioning != nil {
+		err := info.validatePartitioning()
+		if err != nil {
+			return err
+		}
+	}
+
+	if info.Columnar && info.Partitioning != nil {
+		return NewError(CodeFeatureNotSupported, "columnar tables cannot be partitioned")
+	}
+
 	info.tableName = name
-	err := tx.tableInfoStore.Insert(tx, name, info)
+	info.compactEncoding = info.canUseCompactEncoding()
+	if info.MaxInlineValueSize < 0 {
+		return NewError(CodeInvalidParameterValue, fmt.Sprintf("invalid maximum inline value size %d", info.MaxInlineValueSize))
+	}
+	if info.MaxInlineValueSize == 0 {
+		info.MaxInlineValueSize = tx.db.MaxInlineValueSize
+	}
+	info.overflow = info.MaxInlineValueSize > 0
+	err = tx.tableInfoStore.Insert(tx, name, info)
 	if err != nil {
 		return err
 	}
@@ -105,41 +367,182 @@ func (tx *Transaction) CreateTable(name string, info *TableInfo) error {
 		return fmt.Errorf("failed to create table %q: %w", name, err)
 	}
 
+	if info.Partitioning != nil {
+		for i := 1; i < info.Partitioning.Len(); i++ {
+			err = tx.tx.CreateStore(info.partitionStoreName(i))
+			if err != nil {
+				return fmt.Errorf("failed to create table %q: %w", name, err)
+			}
+		}
+	}
+
+	if info.FieldDictionary {
+		err = tx.tx.CreateStore(info.dictionaryStoreName())
+		if err != nil {
+			return fmt.Errorf("failed to create table %q: %w", name, err)
+		}
+	}
+
+	if info.overflow {
+		err = tx.tx.CreateStore(info.overflowStoreName())
+		if err != nil {
+			return fmt.Errorf("failed to create table %q: %w", name, err)
+		}
+	}
+
+	if info.Columnar {
+		err = tx.tx.CreateStore(info.columnsStoreName())
+		if err != nil {
+			return fmt.Errorf("failed to create table %q: %w", name, err)
+		}
+	}
+
+	if info.FieldIndex {
+		err = tx.tx.CreateStore(info.fieldIndexStoreName())
+		if err != nil {
+			return fmt.Errorf("failed to create table %q: %w", name, err)
+		}
+	}
+
+	tx.recordDDL(DDLCreate, DDLTable, name)
+
+	// unique fields are enforced by unique indexes
+	for _, fc := range info.FieldConstraints {
+		if !fc.IsUnique || fc.IsPrimaryKey {
+			continue
+		}
+
+		err = tx.CreateIndex(IndexConfig{
+			TableName:       name,
+			IndexName:       fmt.Sprintf("%s_%s_key", name, fc.Path),
+			Path:            fc.Path,
+			Unique:          true,
+			FieldConstraint: true,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 // GetTable returns a table by name. The table instance is only valid for the lifetime of the transaction.
 func (tx *Transaction) GetTable(name string) (*Table, error) {
+	if err := tx.checkAge(); err != nil {
+		return nil, err
+	}
+
+	atx, tname, err := tx.attachedTable(name)
+	if err != nil {
+		return nil, err
+	}
+	if atx != nil {
+		return atx.GetTable(tname)
+	}
+
 	ti, err := tx.tableInfoStore.Get(tx, name)
 	if err != nil {
 		return nil, err
 	}
 
-	s, err := tx.tx.GetStore(ti.storeName)
+	var s engine.Store
+	if name == transactionsTableName {
+		s, err = newTransactionsStore(tx.db)
+	} else if ti.Partitioning != nil {
+		s, err = newPartitionedStore(tx.tx, ti)
+	} else {
+		s, err = tx.tx.GetStore(ti.storeName)
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	// the dictionary and the overflow store of the table are identified
+	// by the database and the name of the table store
+	id := fmt.Sprintf("%p/%x", tx.db, ti.storeName)
+	codec := tx.db.Codec
+	if ti.FieldDictionary {
+		ds, err := tx.tx.GetStore(ti.dictionaryStoreName())
+		if err != nil {
+			return nil, err
+		}
+
+		codec = &dictionaryCodec{dict: newFieldDictionary(ds), codec: codec, id: id}
+	}
+	if ti.compactEncoding {
+		codec = newCompactCodec(ti, codec)
+	}
+	if ti.overflow {
+		ovs, err := tx.tx.GetStore(ti.overflowStoreName())
+		if err != nil {
+			return nil, err
+		}
+
+		maxSize := ti.MaxInlineValueSize
+		if maxSize == 0 {
+			maxSize = tx.db.MaxInlineValueSize
+		}
+
+		codec = &overflowCodec{st: ovs, maxSize: maxSize, codec: codec, id: id}
+	}
+
+	var columns *columnStore
+	if ti.Columnar {
+		columns, err = newColumnStore(tx.tx, ti)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var fi *fieldIndex
+	if ti.FieldIndex {
+		fst, err := tx.tx.GetStore(ti.fieldIndexStoreName())
+		if err != nil {
+			return nil, err
+		}
+		fi = &fieldIndex{st: fst}
+	}
+
 	return &Table{
-		tx:        tx,
-		Store:     s,
-		name:      name,
-		infoStore: tx.tableInfoStore,
+		tx:         tx,
+		Store:      s,
+		name:       name,
+		infoStore:  tx.tableInfoStore,
+		codec:      codec,
+		columns:    columns,
+		fieldIndex: fi,
 	}, nil
 }
 
 // RenameTable renames a table.
 // If it doesn't exist, it returns ErrTableNotFound.
 func (tx *Transaction) RenameTable(oldName, newName string) error {
+	if err := tx.checkAge(); err != nil {
+		return err
+	}
+
+	// tables of an attached database are renamed within that database
+	atx, tname, err := tx.attachedTable(oldName)
+	if err != nil {
+		return err
+	}
+	if atx != nil {
+		return atx.RenameTable(tname, newName)
+	}
+
 	ti, err := tx.tableInfoStore.Get(tx, oldName)
 	if err != nil {
 		return err
 	}
 
 	if ti.readOnly {
-		return errors.New("cannot write to read-only table")
+		return ErrReadOnlyTable
 	}
 
+	tx.markWritten(oldName)
+	tx.markWritten(newName)
+
 	ti.tableName = newName
 	// Insert the TableInfo keyed by the newName name.
 	err = tx.tableInfoStore.Insert(tx, newName, ti)
@@ -163,20 +566,46 @@ func (tx *Transaction) RenameTable(oldName, newName string) error {
 	}
 
 	// Delete the old reference from the tableInfoStore.
-	return tx.tableInfoStore.Delete(tx, oldName)
+	err = tx.tableInfoStore.Delete(tx, oldName)
+	if err != nil {
+		return err
+	}
+
+	tx.db.bumpCatalogVersion()
+	tx.ddl = append(tx.ddl, DDLChange{
+		Operation:  DDLAlter,
+		ObjectType: DDLTable,
+		Name:       oldName,
+		NewName:    newName,
+	})
+	return nil
 }
 
 // DropTable deletes a table from the database.
 func (tx *Transaction) DropTable(name string) error {
+	if err := tx.checkAge(); err != nil {
+		return err
+	}
+
+	atx, tname, err := tx.attachedTable(name)
+	if err != nil {
+		return err
+	}
+	if atx != nil {
+		return atx.DropTable(tname)
+	}
+
 	ti, err := tx.tableInfoStore.Get(tx, name)
 	if err != nil {
 		return err
 	}
 
 	if ti.readOnly {
-		return errors.New("cannot write to read-only table")
+		return ErrReadOnlyTable
 	}
 
+	tx.markWritten(name)
+
 	it := tx.indexStore.st.NewIterator(engine.IteratorConfig{})
 
 	var buf []byte
@@ -200,7 +629,7 @@ func (tx *Transaction) DropTable(name string) error {
 			continue
 		}
 
-		err = tx.DropIndex(opts.IndexName)
+		err = tx.dropIndex(&opts)
 		if err != nil {
 			it.Close()
 			return err
@@ -216,12 +645,77 @@ func (tx *Transaction) DropTable(name string) error {
 		return err
 	}
 
-	return tx.tx.DropStore(ti.storeName)
+	if ti.FieldDictionary {
+		err = tx.tx.DropStore(ti.dictionaryStoreName())
+		if err != nil {
+			return err
+		}
+	}
+
+	if ti.overflow {
+		err = tx.tx.DropStore(ti.overflowStoreName())
+		if err != nil {
+			return err
+		}
+	}
+
+	if ti.Columnar {
+		columns, err := newColumnStore(tx.tx, ti)
+		if err != nil {
+			return err
+		}
+
+		err = columns.drop()
+		if err != nil {
+			return err
+		}
+	}
+
+	if ti.FieldIndex {
+		err = tx.tx.DropStore(ti.fieldIndexStoreName())
+		if err != nil {
+			return err
+		}
+	}
+
+	if ti.Partitioning != nil {
+		for i := 1; i < ti.Partitioning.Len(); i++ {
+			err = tx.tx.DropStore(ti.partitionStoreName(i))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = tx.tx.DropStore(ti.storeName)
+	if err != nil {
+		return err
+	}
+
+	tx.recordDDL(DDLDrop, DDLTable, name)
+	return nil
 }
 
 // CreateIndex creates an index with the given name.
 // If it already exists, returns ErrIndexAlreadyExists.
 func (tx *Transaction) CreateIndex(opts IndexConfig) error {
+	if err := tx.checkAge(); err != nil {
+		return err
+	}
+
+	atx, tname, err := tx.attachedTable(opts.TableName)
+	if err != nil {
+		return err
+	}
+	if atx != nil {
+		opts.TableName = tname
+		return atx.CreateIndex(opts)
+	}
+
+	if opts.Path.HasWildcard() {
+		return NewError(CodeFeatureNotSupported, fmt.Sprintf("index path %q must not contain wildcards", opts.Path))
+	}
+
 	t, err := tx.GetTable(opts.TableName)
 	if err != nil {
 		return err
@@ -244,19 +738,38 @@ func (tx *Transaction) CreateIndex(opts IndexConfig) error {
 		}
 	}
 
-	return tx.indexStore.Insert(opts)
+	err = tx.indexStore.Insert(opts)
+	if err != nil {
+		return err
+	}
+
+	tx.recordDDL(DDLCreate, DDLIndex, opts.IndexName)
+	return nil
 }
 
 // GetIndex returns an index by name.
 func (tx *Transaction) GetIndex(name string) (*Index, error) {
+	if err := tx.checkAge(); err != nil {
+		return nil, err
+	}
+
+	atx, tname, err := tx.attachedTable(name)
+	if err != nil {
+		return nil, err
+	}
+	if atx != nil {
+		return atx.GetIndex(tname)
+	}
+
 	opts, err := tx.indexStore.Get(name)
 	if err != nil {
 		return nil, err
 	}
 
 	idx := index.NewIndex(tx.tx, opts.IndexName, index.Options{
-		Unique: opts.Unique,
-		Type:   opts.Type,
+		Unique:   opts.Unique,
+		Type:     opts.Type,
+		Prefetch: tx.db.scanPrefetch(),
 	})
 
 	return &Index{
@@ -267,11 +780,34 @@ func (tx *Transaction) GetIndex(name string) (*Index, error) {
 
 // DropIndex deletes an index from the database.
 func (tx *Transaction) DropIndex(name string) error {
+	if err := tx.checkAge(); err != nil {
+		return err
+	}
+
+	atx, tname, err := tx.attachedTable(name)
+	if err != nil {
+		return err
+	}
+	if atx != nil {
+		return atx.DropIndex(tname)
+	}
+
 	opts, err := tx.indexStore.Get(name)
 	if err != nil {
 		return err
 	}
-	err = tx.indexStore.Delete(name)
+
+	if opts.FieldConstraint {
+		return NewError(CodeDependentObjectsStillExist,
+			fmt.Sprintf("cannot drop index %s, it enforces the UNIQUE constraint of %s(%s)", name, opts.TableName, opts.Path))
+	}
+
+	return tx.dropIndex(opts)
+}
+
+// dropIndex deletes the index described by opts.
+func (tx *Transaction) dropIndex(opts *IndexConfig) error {
+	err := tx.indexStore.Delete(opts.IndexName)
 	if err != nil {
 		return err
 	}
@@ -281,7 +817,29 @@ func (tx *Transaction) DropIndex(name string) error {
 		Type:   opts.Type,
 	})
 
-	return idx.Truncate()
+	err = idx.Truncate()
+	if err != nil {
+		return err
+	}
+
+	tx.recordDDL(DDLDrop, DDLIndex, opts.IndexName)
+	return nil
+}
+
+// ListTables returns the names of all the tables visible by the transaction,
+// including system tables, sorted alphabetically.
+func (tx *Transaction) ListTables() []string {
+	var names []string
+
+	for name, info := range tx.tableInfoStore.GetTableInfo() {
+		if info.transactionID != 0 && info.transactionID != tx.id {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
 }
 
 // ListIndexes lists all indexes.
@@ -308,7 +866,7 @@ func (tx *Transaction) ReIndex(indexName string) error {
 
 	return tb.Iterate(func(d document.Document) error {
 		v, err := idx.Opts.Path.GetValue(d)
-		if err == document.ErrFieldNotFound {
+		if err == document.ErrFieldNotFound || (err == nil && !idx.indexes(v)) {
 			return nil
 		}
 		if err != nil {
@@ -342,6 +900,40 @@ func (tx *Transaction) ReIndexAll() error {
 	return nil
 }
 
+// Analyze collects statistics about the values of the selected index
+// and stores them along with its configuration.
+func (tx *Transaction) Analyze(indexName string) error {
+	idx, err := tx.GetIndex(indexName)
+	if err != nil {
+		return err
+	}
+
+	stats, err := idx.Stats()
+	if err != nil {
+		return err
+	}
+
+	idx.Opts.Stats = &stats
+	return tx.indexStore.Replace(indexName, idx.Opts)
+}
+
+// AnalyzeAll collects statistics about the values of all the indexes of the database.
+func (tx *Transaction) AnalyzeAll() error {
+	indexes, err := tx.ListIndexes()
+	if err != nil {
+		return err
+	}
+
+	for _, idx := range indexes {
+		err = tx.Analyze(idx.IndexName)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (tx *Transaction) getIndexStore() (*indexStore, error) {
 	st, err := tx.tx.GetStore([]byte(indexStoreName))
 	if err != nil {
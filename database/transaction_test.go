@@ -1,12 +1,16 @@
 package database_test
 
 import (
+	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/document/encoding/msgpack"
 	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/genjidb/genji/index"
 	"github.com/genjidb/genji/key"
 	"github.com/stretchr/testify/require"
 )
@@ -183,6 +187,19 @@ func TestTxCreateIndex(t *testing.T) {
 		require.Equal(t, database.ErrIndexAlreadyExists, err)
 	})
 
+	t.Run("Should fail if the path contains wildcards", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		err := tx.CreateTable("test", nil)
+		require.NoError(t, err)
+
+		err = tx.CreateIndex(database.IndexConfig{
+			IndexName: "idxFoo", TableName: "test", Path: parsePath(t, "foo[*].bar"),
+		})
+		require.Error(t, err)
+	})
+
 	t.Run("Should fail if table doesn't exists", func(t *testing.T) {
 		tx, cleanup := newTestDB(t)
 		defer cleanup()
@@ -321,6 +338,48 @@ func TestTxReIndex(t *testing.T) {
 	})
 }
 
+func TestTxAnalyze(t *testing.T) {
+	tx, cleanup := newTestDB(t)
+	defer cleanup()
+
+	err := tx.CreateTable("test", nil)
+	require.NoError(t, err)
+	tb, err := tx.GetTable("test")
+	require.NoError(t, err)
+
+	err = tx.CreateIndex(database.IndexConfig{
+		IndexName: "a",
+		TableName: "test",
+		Path:      parsePath(t, "a"),
+	})
+	require.NoError(t, err)
+
+	for i := int64(0); i < 10; i++ {
+		_, err = tb.Insert(document.NewFieldBuffer().
+			Add("a", document.NewIntegerValue(i%3)),
+		)
+		require.NoError(t, err)
+	}
+
+	idx, err := tx.GetIndex("a")
+	require.NoError(t, err)
+	require.Nil(t, idx.Opts.Stats)
+
+	err = tx.Analyze("foo")
+	require.Equal(t, database.ErrIndexNotFound, err)
+
+	err = tb.Analyze()
+	require.NoError(t, err)
+
+	idx, err = tx.GetIndex("a")
+	require.NoError(t, err)
+	require.Equal(t, &index.Stats{Count: 10, Distinct: 3}, idx.Opts.Stats)
+
+	indexes, err := tb.Indexes()
+	require.NoError(t, err)
+	require.Equal(t, idx.Opts.Stats, indexes["a"].Opts.Stats)
+}
+
 func TestReIndexAll(t *testing.T) {
 	t.Run("Should succeed if not indexes", func(t *testing.T) {
 		tx, cleanup := newTestDB(t)
@@ -402,3 +461,360 @@ func TestReIndexAll(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+func TestTxBusy(t *testing.T) {
+	t.Run("BusyError", func(t *testing.T) {
+		db, err := database.New(memoryengine.NewEngine(), database.Options{
+			Codec:    msgpack.NewCodec(),
+			BusyMode: database.BusyError,
+		})
+		require.NoError(t, err)
+		defer db.Close()
+
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+
+		_, err = db.Begin(true)
+		require.Equal(t, database.ErrBusy, err)
+
+		err = tx.Commit()
+		require.NoError(t, err)
+		// rolling back after commit must not release the writer twice
+		err = tx.Rollback()
+		require.NoError(t, err)
+
+		tx, err = db.Begin(true)
+		require.NoError(t, err)
+		err = tx.Rollback()
+		require.NoError(t, err)
+
+		tx, err = db.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+		_, err = db.Begin(true)
+		require.Equal(t, database.ErrBusy, err)
+	})
+
+	t.Run("BusyWait", func(t *testing.T) {
+		db, err := database.New(memoryengine.NewEngine(), database.Options{Codec: msgpack.NewCodec()})
+		require.NoError(t, err)
+		defer db.Close()
+
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+
+		done := make(chan error)
+		go func() {
+			tx, err := db.Begin(true)
+			if err == nil {
+				err = tx.Rollback()
+			}
+			done <- err
+		}()
+
+		select {
+		case <-done:
+			t.Fatal("the second transaction must wait for the first one")
+		case <-time.After(10 * time.Millisecond):
+		}
+
+		err = tx.Rollback()
+		require.NoError(t, err)
+		require.NoError(t, <-done)
+	})
+
+	t.Run("Reader waiting for a writer", func(t *testing.T) {
+		db, err := database.New(memoryengine.NewEngine(), database.Options{Codec: msgpack.NewCodec()})
+		require.NoError(t, err)
+		defer db.Close()
+
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+
+		// the memory engine makes readers wait for the running writer,
+		// which must still be able to commit
+		done := make(chan error)
+		go func() {
+			tx, err := db.Begin(false)
+			if err == nil {
+				err = tx.Rollback()
+			}
+			done <- err
+		}()
+
+		time.Sleep(10 * time.Millisecond)
+		err = tx.Commit()
+		require.NoError(t, err)
+		require.NoError(t, <-done)
+	})
+
+	t.Run("BusyTimeout", func(t *testing.T) {
+		db, err := database.New(memoryengine.NewEngine(), database.Options{
+			Codec:       msgpack.NewCodec(),
+			BusyTimeout: 10 * time.Millisecond,
+		})
+		require.NoError(t, err)
+		defer db.Close()
+
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+
+		start := time.Now()
+		_, err = db.Begin(true)
+		require.Equal(t, database.ErrBusy, err)
+		require.GreaterOrEqual(t, int64(time.Since(start)), int64(10*time.Millisecond))
+
+		err = tx.Rollback()
+		require.NoError(t, err)
+
+		tx, err = db.Begin(true)
+		require.NoError(t, err)
+		err = tx.Rollback()
+		require.NoError(t, err)
+	})
+}
+
+func TestTxTracking(t *testing.T) {
+	t.Run("Transactions", func(t *testing.T) {
+		db, err := database.New(memoryengine.NewEngine(), database.Options{Codec: msgpack.NewCodec()})
+		require.NoError(t, err)
+		defer db.Close()
+
+		require.Empty(t, db.Transactions())
+
+		wtx, err := db.Begin(true)
+		require.NoError(t, err)
+		txs := db.Transactions()
+		require.Len(t, txs, 1)
+		require.True(t, txs[0].Writable)
+		require.NoError(t, wtx.Commit())
+		require.Empty(t, db.Transactions())
+
+		// the memory engine doesn't allow read-write and read-only transactions
+		// to run concurrently.
+		tx1, err := db.BeginTx(&database.TxOptions{ReadOnly: true, Statement: "SELECT * FROM foo"})
+		require.NoError(t, err)
+		rtx, err := db.Begin(false)
+		require.NoError(t, err)
+
+		txs = db.Transactions()
+		require.Len(t, txs, 2)
+		require.Equal(t, "SELECT * FROM foo", txs[0].Statement)
+		require.False(t, txs[1].Writable)
+		require.Empty(t, txs[1].Statement)
+		require.Less(t, txs[0].ID, txs[1].ID)
+
+		tb, err := rtx.GetTable("__genji_transactions")
+		require.NoError(t, err)
+
+		var ids []int64
+		err = tb.Iterate(func(d document.Document) error {
+			v, err := d.GetByField("id")
+			require.NoError(t, err)
+			ids = append(ids, v.V.(int64))
+
+			v, err = d.GetByField("statement")
+			require.NoError(t, err)
+			if len(ids) == 1 {
+				require.Equal(t, document.NewTextValue("SELECT * FROM foo"), v)
+			} else {
+				require.Equal(t, document.NewNullValue(), v)
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []int64{txs[0].ID, txs[1].ID}, ids)
+
+		_, err = tb.Insert(document.NewFieldBuffer().Add("id", document.NewIntegerValue(10)))
+		require.Error(t, err)
+
+		require.NoError(t, tx1.Rollback())
+		require.NoError(t, rtx.Rollback())
+		require.Empty(t, db.Transactions())
+	})
+
+	t.Run("MaxTransactionIdle", func(t *testing.T) {
+		db, err := database.New(memoryengine.NewEngine(), database.Options{
+			Codec:              msgpack.NewCodec(),
+			MaxTransactionIdle: 20 * time.Millisecond,
+		})
+		require.NoError(t, err)
+		defer db.Close()
+
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+
+		// using the transaction resets its idle duration
+		for i := 0; i < 3; i++ {
+			time.Sleep(10 * time.Millisecond)
+			require.NoError(t, tx.CreateTable(fmt.Sprintf("foo%d", i), nil))
+		}
+
+		time.Sleep(30 * time.Millisecond)
+		_, err = tx.GetTable("foo0")
+		require.True(t, errors.Is(err, database.ErrTransactionIdle))
+		require.Empty(t, db.Transactions())
+	})
+
+	t.Run("Kill", func(t *testing.T) {
+		db, err := database.New(memoryengine.NewEngine(), database.Options{Codec: msgpack.NewCodec()})
+		require.NoError(t, err)
+		defer db.Close()
+
+		tx, err := db.BeginTx(&database.TxOptions{Statement: "CREATE TABLE foo"})
+		require.NoError(t, err)
+		require.NoError(t, tx.CreateTable("foo", nil))
+
+		txs := db.Transactions()
+		require.Len(t, txs, 1)
+		require.False(t, txs[0].Killed)
+
+		err = db.KillTransaction(txs[0].ID)
+		require.NoError(t, err)
+		require.True(t, db.Transactions()[0].Killed)
+
+		err = tx.Commit()
+		require.True(t, errors.Is(err, database.ErrTransactionKilled))
+		require.Equal(t, database.CodeQueryCanceled, database.CodeOf(err))
+		require.Contains(t, err.Error(), `"CREATE TABLE foo"`)
+		require.Empty(t, db.Transactions())
+
+		err = db.KillTransaction(txs[0].ID)
+		require.True(t, errors.Is(err, database.ErrTransactionNotFound))
+
+		// the changes are discarded
+		tx, err = db.Begin(false)
+		require.NoError(t, err)
+		defer tx.Rollback()
+		_, err = tx.GetTable("foo")
+		require.Equal(t, database.ErrTableNotFound, err)
+	})
+
+	t.Run("MaxTransactionAge", func(t *testing.T) {
+		db, err := database.New(memoryengine.NewEngine(), database.Options{
+			Codec:             msgpack.NewCodec(),
+			MaxTransactionAge: 10 * time.Millisecond,
+		})
+		require.NoError(t, err)
+		defer db.Close()
+
+		tx, err := db.BeginTx(&database.TxOptions{Statement: "CREATE TABLE foo"})
+		require.NoError(t, err)
+		require.NoError(t, tx.CreateTable("foo", nil))
+
+		time.Sleep(20 * time.Millisecond)
+
+		_, err = tx.GetTable("foo")
+		require.True(t, errors.Is(err, database.ErrTransactionTooOld))
+		require.Contains(t, err.Error(), `"CREATE TABLE foo"`)
+
+		// the transaction is aborted
+		err = tx.Commit()
+		require.True(t, errors.Is(err, database.ErrTransactionTooOld))
+		require.NoError(t, tx.Rollback())
+		require.Empty(t, db.Transactions())
+
+		// the writer is released and the changes are discarded
+		tx, err = db.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		_, err = tx.GetTable("foo")
+		require.Equal(t, database.ErrTableNotFound, err)
+	})
+
+	t.Run("MaxTransactionAge/unused", func(t *testing.T) {
+		for _, mode := range []database.BusyMode{database.BusyWait, database.BusyError} {
+			db, err := database.New(memoryengine.NewEngine(), database.Options{
+				Codec:             msgpack.NewCodec(),
+				BusyMode:          mode,
+				MaxTransactionAge: 10 * time.Millisecond,
+			})
+			require.NoError(t, err)
+			defer db.Close()
+
+			old, err := db.BeginTx(&database.TxOptions{Statement: "CREATE TABLE foo"})
+			require.NoError(t, err)
+			require.NoError(t, old.CreateTable("foo", nil))
+
+			if mode == database.BusyError {
+				_, err = db.Begin(true)
+				require.Equal(t, database.ErrBusy, err)
+				time.Sleep(20 * time.Millisecond)
+			}
+
+			// the old transaction is aborted without being used again,
+			// since it blocks the new one
+			tx, err := db.Begin(true)
+			require.NoError(t, err)
+			require.Len(t, db.Transactions(), 1)
+
+			_, err = tx.GetTable("foo")
+			require.Equal(t, database.ErrTableNotFound, err)
+
+			err = old.Commit()
+			require.True(t, errors.Is(err, database.ErrTransactionTooOld))
+			require.NoError(t, old.Rollback())
+
+			require.NoError(t, tx.Commit())
+		}
+	})
+}
+
+func TestTableVersion(t *testing.T) {
+	db, err := database.New(memoryengine.NewEngine(), database.Options{Codec: msgpack.NewCodec()})
+	require.NoError(t, err)
+	defer db.Close()
+
+	update := func(fn func(tx *database.Transaction) error, commit bool) {
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		require.NoError(t, fn(tx))
+		if commit {
+			require.NoError(t, tx.Commit())
+		}
+	}
+
+	insert := func(tx *database.Transaction) error {
+		tb, err := tx.GetTable("a")
+		if err != nil {
+			return err
+		}
+		_, err = tb.Insert(document.NewFieldBuffer().Add("a", document.NewIntegerValue(1)))
+		return err
+	}
+
+	update(func(tx *database.Transaction) error {
+		err := tx.CreateTable("a", nil)
+		if err != nil {
+			return err
+		}
+		return tx.CreateTable("b", nil)
+	}, true)
+	require.Zero(t, db.TableVersion("a"))
+
+	// only committed changes are counted
+	update(insert, false)
+	require.Zero(t, db.TableVersion("a"))
+	update(insert, true)
+	require.EqualValues(t, 1, db.TableVersion("a"))
+	require.Zero(t, db.TableVersion("b"))
+
+	// reading a table doesn't change its version
+	update(func(tx *database.Transaction) error {
+		tb, err := tx.GetTable("a")
+		if err != nil {
+			return err
+		}
+		return tb.Iterate(func(d document.Document) error { return nil })
+	}, true)
+	require.EqualValues(t, 1, db.TableVersion("a"))
+
+	update(func(tx *database.Transaction) error {
+		return tx.RenameTable("a", "c")
+	}, true)
+	require.EqualValues(t, 2, db.TableVersion("a"))
+	require.EqualValues(t, 1, db.TableVersion("c"))
+}
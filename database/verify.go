@@ -0,0 +1,110 @@
+package database
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/genjidb/genji/document"
+)
+
+// IndexEntry is a value of an index associated with the key of a document.
+type IndexEntry struct {
+	// Value encoded as stored in the index.
+	Value []byte
+	Key   []byte
+}
+
+// IndexReport lists the inconsistencies between an index and the documents of its table.
+type IndexReport struct {
+	// Missing contains the documents whose value is not indexed,
+	// along with the value they should be indexed with.
+	Missing []IndexEntry
+	// Extra contains the index entries that don't match the value of any document.
+	Extra []IndexEntry
+}
+
+// OK returns true if the index is consistent with its table.
+func (r *IndexReport) OK() bool {
+	return len(r.Missing) == 0 && len(r.Extra) == 0
+}
+
+// Verify cross-checks the entries of the index with the documents of the given table,
+// which must be the table of the index, and reports the missing and extra entries.
+// All the entries of the index are loaded in memory during the verification.
+func (idx *Index) Verify(tb *Table) (*IndexReport, error) {
+	if tb.Name() != idx.Opts.TableName {
+		return nil, fmt.Errorf("index %q doesn't belong to table %q", idx.Opts.IndexName, tb.Name())
+	}
+
+	var report IndexReport
+
+	// index entries, by document key
+	entries := make(map[string][][]byte)
+	err := idx.AscendGreaterOrEqual(document.Value{}, func(val, key []byte, isEqual bool) error {
+		k := string(key)
+		entries[k] = append(entries[k], append([]byte{}, val...))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = tb.Iterate(func(d document.Document) error {
+		// documents without the indexed field are indexed with a null value
+		// on insertion but skipped by ReIndex: both are considered valid.
+		v, err := idx.Opts.Path.GetValue(d)
+		optional := err == document.ErrFieldNotFound
+		if optional {
+			v = document.NewNullValue()
+		} else if err != nil {
+			return err
+		}
+		if !idx.indexes(v) {
+			return nil
+		}
+
+		enc, err := idx.EncodeValue(v)
+		if err != nil {
+			return err
+		}
+
+		key := d.(document.Keyer).Key()
+		vals := entries[string(key)]
+		for i := range vals {
+			if bytes.Equal(vals[i], enc) {
+				entries[string(key)] = append(vals[:i], vals[i+1:]...)
+				return nil
+			}
+		}
+
+		if optional {
+			return nil
+		}
+
+		report.Missing = append(report.Missing, IndexEntry{
+			Value: enc,
+			Key:   append([]byte{}, key...),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for k, vals := range entries {
+		for _, v := range vals {
+			report.Extra = append(report.Extra, IndexEntry{Value: v, Key: []byte(k)})
+		}
+	}
+
+	// sort the extra entries to report them in the order of the index
+	sort.Slice(report.Extra, func(i, j int) bool {
+		if c := bytes.Compare(report.Extra[i].Value, report.Extra[j].Value); c != 0 {
+			return c < 0
+		}
+		return bytes.Compare(report.Extra[i].Key, report.Extra[j].Key) < 0
+	})
+
+	return &report, nil
+}
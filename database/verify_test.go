@@ -0,0 +1,81 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexVerify(t *testing.T) {
+	tx, cleanup := newTestDB(t)
+	defer cleanup()
+
+	err := tx.CreateTable("test", nil)
+	require.NoError(t, err)
+	err = tx.CreateTable("other", nil)
+	require.NoError(t, err)
+	err = tx.CreateIndex(database.IndexConfig{
+		IndexName: "idx_a",
+		TableName: "test",
+		Path:      parsePath(t, "a"),
+	})
+	require.NoError(t, err)
+
+	tb, err := tx.GetTable("test")
+	require.NoError(t, err)
+
+	var keys [][]byte
+	for i := int64(0); i < 5; i++ {
+		k, err := tb.Insert(document.NewFieldBuffer().Add("a", document.NewIntegerValue(i%2)))
+		require.NoError(t, err)
+		keys = append(keys, k)
+	}
+	// documents without the indexed field are indexed with a null value
+	_, err = tb.Insert(document.NewFieldBuffer().Add("b", document.NewIntegerValue(1)))
+	require.NoError(t, err)
+
+	idx, err := tx.GetIndex("idx_a")
+	require.NoError(t, err)
+
+	report, err := idx.Verify(tb)
+	require.NoError(t, err)
+	require.True(t, report.OK(), "%+v", report)
+
+	// remove an entry and add an unknown one
+	err = idx.Delete(document.NewIntegerValue(1), keys[3])
+	require.NoError(t, err)
+	err = idx.Set(document.NewIntegerValue(10), keys[0])
+	require.NoError(t, err)
+
+	report, err = idx.Verify(tb)
+	require.NoError(t, err)
+	require.False(t, report.OK())
+
+	enc := func(i int64) []byte {
+		v, err := idx.EncodeValue(document.NewIntegerValue(i))
+		require.NoError(t, err)
+		return v
+	}
+
+	require.Equal(t, []database.IndexEntry{{Value: enc(1), Key: keys[3]}}, report.Missing)
+	require.Equal(t, []database.IndexEntry{{Value: enc(10), Key: keys[0]}}, report.Extra)
+
+	t.Run("After reindexing", func(t *testing.T) {
+		err := tx.ReIndex("idx_a")
+		require.NoError(t, err)
+
+		report, err := idx.Verify(tb)
+		require.NoError(t, err)
+		require.True(t, report.OK(), "%+v", report)
+	})
+
+	t.Run("Other table", func(t *testing.T) {
+		other, err := tx.GetTable("other")
+		require.NoError(t, err)
+
+		_, err = idx.Verify(other)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,70 @@
+package database
+
+import "sync/atomic"
+
+// TableVersion returns the number of committed transactions that modified
+// the table with the given name since the database was opened.
+// Comparing two versions of a table tells whether it was modified in between.
+// Changes made to attached databases are not counted.
+func (db *Database) TableVersion(name string) uint64 {
+	db.tableVersionsMu.Lock()
+	defer db.tableVersionsMu.Unlock()
+
+	return db.tableVersions[name]
+}
+
+// TableChanges returns a channel which is closed the next time a transaction
+// modifying a table is committed. TableVersion tells which tables were modified.
+func (db *Database) TableChanges() <-chan struct{} {
+	db.tableVersionsMu.Lock()
+	defer db.tableVersionsMu.Unlock()
+
+	if db.tableChanges == nil {
+		db.tableChanges = make(chan struct{})
+	}
+
+	return db.tableChanges
+}
+
+func (db *Database) bumpTableVersions(names map[string]struct{}) {
+	if len(names) == 0 {
+		return
+	}
+
+	db.tableVersionsMu.Lock()
+	defer db.tableVersionsMu.Unlock()
+
+	if db.tableVersions == nil {
+		db.tableVersions = make(map[string]uint64)
+	}
+	for name := range names {
+		db.tableVersions[name]++
+	}
+
+	if db.tableChanges != nil {
+		close(db.tableChanges)
+		db.tableChanges = nil
+	}
+}
+
+// markWritten records that the table was modified by tx,
+// to bump its version when tx is committed.
+func (tx *Transaction) markWritten(name string) {
+	if tx.written == nil {
+		tx.written = make(map[string]struct{})
+	}
+	tx.written[name] = struct{}{}
+}
+
+// CatalogVersion returns a number which changes every time the catalog of the database,
+// made of its tables, indexes, events, procedures and attached databases, is modified,
+// including by a transaction that is not committed yet, and when such a transaction
+// is rolled back. Query plans made while it had another value may be stale.
+// Changes made to the catalogs of attached databases are not counted.
+func (db *Database) CatalogVersion() uint64 {
+	return atomic.LoadUint64(&db.catalogVersion)
+}
+
+func (db *Database) bumpCatalogVersion() {
+	atomic.AddUint64(&db.catalogVersion, 1)
+}
@@ -2,23 +2,232 @@ package genji
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine"
 	"github.com/genjidb/genji/sql/parser"
+	"github.com/genjidb/genji/sql/planner"
 	"github.com/genjidb/genji/sql/query"
 )
 
 // DB represents a collection of tables stored in the underlying engine.
+// DB is safe for concurrent use by multiple goroutines.
+// Read-write transactions are run one at a time: by default, starting one
+// while another is running blocks until it is closed. This can be configured
+// using Options.
 type DB struct {
 	DB *database.Database
+
+	rewritersMu sync.RWMutex
+	rewriters   []planner.RewriteFunc
+
+	parserLimits parser.Limits
+
+	// types of statements allowed and denied, if any
+	allowed, denied map[string]bool
+
+	// if not nil, results of the SELECT statements run by Query are cached.
+	cache *resultCache
+
+	// runs the events of the database, unless disabled.
+	scheduler *scheduler
+}
+
+// ReadOnlyStatements lists the types of the statements that don't modify the database.
+// It can be used as Options.AllowedStatements to expose a database to clients
+// that must not modify it.
+var ReadOnlyStatements = []string{
+	"SELECT", "EXPLAIN", "SHOW TABLES", "SHOW INDEXES", "DESCRIBE", "BEGIN", "COMMIT", "ROLLBACK",
+	"SAVEPOINT", "ROLLBACK TO SAVEPOINT", "RELEASE SAVEPOINT",
+}
+
+func newDB(db *database.Database, opts *Options) *DB {
+	d := DB{
+		DB:           db,
+		parserLimits: opts.ParserLimits,
+		allowed:      statementSet(opts.AllowedStatements),
+		denied:       statementSet(opts.DeniedStatements),
+	}
+
+	if opts.ResultCacheSize > 0 {
+		d.cache = newResultCache(opts.ResultCacheSize, opts.ResultCacheMaxDocuments)
+	}
+
+	if !opts.DisableEvents {
+		d.scheduler = newScheduler(&d, opts.OnEventError)
+	}
+
+	return &d
+}
+
+func statementSet(types []string) map[string]bool {
+	if len(types) == 0 {
+		return nil
+	}
+
+	m := make(map[string]bool, len(types))
+	for _, t := range types {
+		m[strings.ToUpper(t)] = true
+	}
+	return m
+}
+
+// Options configures a database created by OpenWithOptions or NewWithOptions.
+type Options struct {
+	// BusyMode defines what happens when a read-write transaction is started
+	// while another one is running: either wait for it to be closed,
+	// in the order transactions were requested, or fail immediately with database.ErrBusy.
+	// Defaults to database.BusyWait.
+	BusyMode database.BusyMode
+	// BusyTimeout is the maximum duration to wait when using database.BusyWait,
+	// after which database.ErrBusy is returned. If zero, it waits indefinitely.
+	BusyTimeout time.Duration
+	// Clock returns the current time, as seen by the database, which is used by the NOW() function,
+	// to generate keys with database.NewULIDKeyGenerator and to measure the age of the transactions.
+	// If nil, the clock of the engine is used if it implements engine.Deterministic, otherwise the system one.
+	Clock database.Clock
+	// KeyGenerator generates the keys of the documents inserted in tables without primary key.
+	// Defaults to database.SequenceKeyGenerator.
+	KeyGenerator database.KeyGenerator
+	// IntegerOverflowMode determines the result of the arithmetic operations on integers that overflow,
+	// which are either promoted to doubles or fail with document.ErrIntegerOverflow.
+	// It can be changed with PRAGMA integer_overflow = 'error'. Defaults to document.OverflowPromote.
+	IntegerOverflowMode document.OverflowMode
+	// MaxInlineValueSize is the maximum size of the top-level text and blob values stored
+	// along with the other fields of a document, used by the tables created without
+	// a database.TableInfo.MaxInlineValueSize of their own. Larger values are stored separately
+	// and only fetched when requested. If zero, all values are stored inline.
+	MaxInlineValueSize int
+	// MaxTransactionAge is the maximum duration a transaction can stay open,
+	// after which it is rolled back and returns database.ErrTransactionTooOld.
+	// Open transactions can be listed by querying the __genji_transactions table.
+	// If zero, transactions can stay open indefinitely.
+	MaxTransactionAge time.Duration
+	// MaxTransactionIdle is the maximum duration a transaction can stay open without being used,
+	// after which it is rolled back and returns database.ErrTransactionIdle.
+	// If zero, transactions can stay idle indefinitely.
+	MaxTransactionIdle time.Duration
+	// MaxQueryMemory is the approximate number of bytes a statement can use
+	// to sort or group documents, after which it fails with database.ErrMemoryLimitExceeded.
+	// If zero, memory is not limited.
+	MaxQueryMemory int64
+	// MaxRecursion is the maximum number of iterations of a recursive common table expression,
+	// after which the statement fails with database.ErrRecursionLimitExceeded.
+	// If zero, database.DefaultMaxRecursion is used. If negative, it is not limited.
+	MaxRecursion int
+	// StatementTimeout is the maximum duration of a statement, including the time spent
+	// reading its result, after which it fails with an error wrapping database.ErrStatementTimeout.
+	// It can be changed with PRAGMA statement_timeout = '30s' and overridden for the following
+	// statements of a query with SET statement_timeout = '30s'. Deadlines of the contexts
+	// passed to the methods of the DB apply as well. If zero, statements can run indefinitely.
+	StatementTimeout time.Duration
+	// ScanPrefetch is the maximum number of documents read ahead by the scans of tables and indexes,
+	// which read them from the engine in batches, starting with one document and doubling
+	// the size of each batch up to ScanPrefetch. It can hide the latency of remote or compressed
+	// engines, at the cost of copying the documents. If zero, documents are read one at a time.
+	ScanPrefetch int
+	// MaxWriteRate is the maximum number of read-write transactions started per second,
+	// including the ones started by statements, after which they fail with database.ErrThrottled.
+	// It can be used to protect devices from runaway writers. If zero, they are not limited.
+	MaxWriteRate int
+	// MaxStatementRate is the maximum number of statements run per second,
+	// after which they fail with database.ErrThrottled. If zero, they are not limited.
+	MaxStatementRate int
+	// ThrottleTimeout is the maximum duration to wait for MaxWriteRate or MaxStatementRate
+	// to allow an operation before returning database.ErrThrottled. If zero, it doesn't wait.
+	ThrottleTimeout time.Duration
+	// ParserLimits bounds the size, number of tokens and nesting depth of the queries,
+	// which is useful when they come from untrusted sources.
+	// Queries exceeding them fail with a *parser.ParseError. Zero values mean no limit.
+	ParserLimits parser.Limits
+	// AllowedStatements, if not empty, lists the types of the only statements that can be run,
+	// as returned by parser.StatementType, like "SELECT" or "CREATE TABLE".
+	// ReadOnlyStatements can be used to reject any statement modifying the database.
+	// Statements are checked before being executed and the ones that are not allowed
+	// fail with an error wrapping database.ErrStatementNotAllowed.
+	AllowedStatements []string
+	// DeniedStatements lists the types of the statements that can't be run,
+	// as returned by parser.StatementType.
+	DeniedStatements []string
+	// ResultCacheSize is the number of results of SELECT statements kept in memory
+	// by DB.Query, DB.QueryDocument and DB.Exec, which return them again when the same
+	// statement is run with the same parameters, as long as none of the tables it reads
+	// has been modified by a committed transaction.
+	// Statements run within transactions, reading system tables or tables of attached
+	// databases, or using operators added by a rewriter are never cached.
+	// The least recently used results are evicted first. If zero, results are not cached.
+	ResultCacheSize int
+	// ResultCacheMaxDocuments is the maximum number of documents of a cached result.
+	// Larger results are not cached. Defaults to 1000.
+	ResultCacheMaxDocuments int
+	// DisableEvents prevents the events created with the CREATE EVENT statement
+	// from being run by this DB, for example when another process runs them.
+	// Otherwise, they are run periodically by a goroutine, stopped by Close,
+	// each in its own read-write transaction, as long as the DB is open.
+	DisableEvents bool
+	// OnEventError, if not nil, is called from the goroutine running the events
+	// with the errors returned by their statements. The name of the event
+	// is empty if the events couldn't be loaded.
+	OnEventError func(event string, err error)
 }
 
-// Close the database.
+// Close stops the events and closes the database.
 func (db *DB) Close() error {
+	if db.scheduler != nil {
+		db.scheduler.stop()
+	}
+
 	return db.DB.Close()
 }
 
+// Ping verifies that the database can be read, by beginning a read-only transaction
+// and rolling it back, which makes it suitable for health and readiness checks.
+// Transactions can't begin while a read-write one is running on some engines,
+// like the memory engine, in which case Ping returns ctx.Err() if ctx is done first.
+// A transaction started with the BEGIN statement doesn't make Ping fail.
+func (db *DB) Ping(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		tx, err := db.DB.Begin(false)
+		if err == nil {
+			err = tx.Rollback()
+		}
+		if database.CodeOf(err) == database.CodeActiveTransaction {
+			err = nil
+		}
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Attach makes the tables of other available to the queries run on db, under the given name.
+// They are referenced by prefixing their name with the name of the attachment, like "other.users".
+// If readOnly is true, the attached tables can't be modified.
+// Transactions of db start a transaction on other the first time one of its tables is used,
+// which is committed or rolled back with them. Committing both transactions is not atomic.
+// Databases attached using the ATTACH DATABASE statement are opened like Open does
+// and closed when detached, while other must be closed by the caller, after db.
+func (db *DB) Attach(name string, other *DB, readOnly bool) error {
+	return db.DB.Attach(name, other.DB, readOnly)
+}
+
+// Detach removes the database attached under the given name.
+func (db *DB) Detach(name string) error {
+	return db.DB.Detach(name)
+}
+
 // Begin starts a new transaction.
 // The returned transaction must be closed either by calling Rollback or Commit.
 func (db *DB) Begin(writable bool) (*Tx, error) {
@@ -29,9 +238,185 @@ func (db *DB) Begin(writable bool) (*Tx, error) {
 
 	return &Tx{
 		Transaction: tx,
+		db:          db,
 	}, nil
 }
 
+// AddRewriter registers a function that is called with the tree of every SELECT, UPDATE and DELETE
+// statement executed on the database, including the ones that are explained and the queries of
+// common table expressions, before it is optimized.
+// It can be used to apply global rules, like filtering documents by tenant or limiting the number of results.
+// Functions are called in the order they were registered.
+func (db *DB) AddRewriter(fn planner.RewriteFunc) {
+	db.rewritersMu.Lock()
+	db.rewriters = append(db.rewriters, fn)
+	db.rewritersMu.Unlock()
+}
+
+// ParseQuery parses q and applies the registered rewrite functions to its statements.
+// The statements of the procedures called by q are checked and rewritten the same way
+// when they are called.
+func (db *DB) ParseQuery(ctx context.Context, q string) (query.Query, error) {
+	pq, err := parser.ParseQueryWithOptions(ctx, q, &parser.Options{Limits: db.parserLimits})
+	if err != nil {
+		return pq, err
+	}
+
+	err = db.prepareStatements(pq.Statements)
+	if err != nil {
+		return query.Query{}, err
+	}
+
+	return pq, nil
+}
+
+// prepareStatements ensures stmts are allowed by the options of the database
+// and applies the registered rewrite functions to them, in place.
+func (db *DB) prepareStatements(stmts []query.Statement) error {
+	for _, stmt := range stmts {
+		err := db.checkStatement(stmt)
+		if err != nil {
+			return err
+		}
+	}
+
+	db.rewritersMu.RLock()
+	fns := db.rewriters
+	db.rewritersMu.RUnlock()
+
+	return db.rewriteStatements(stmts, fns)
+}
+
+// rewriteStatements applies the rewrite functions to stmts, including the statements
+// of control flow statements. The bodies of the procedures called by stmts are prepared
+// when they are compiled, as they can't be known before the call.
+func (db *DB) rewriteStatements(stmts []query.Statement, fns []planner.RewriteFunc) error {
+	var err error
+
+	for i, stmt := range stmts {
+		switch t := stmt.(type) {
+		case *planner.Tree:
+			if len(fns) > 0 {
+				stmts[i], err = planner.Rewrite(t, fns...)
+			}
+		case *planner.WithStmt:
+			if len(fns) > 0 {
+				err = rewriteWith(t, fns)
+			}
+		case *planner.ExplainStmt:
+			if tree, ok := t.Statement.(*planner.Tree); ok && len(fns) > 0 {
+				t.Statement, err = planner.Rewrite(tree, fns...)
+			}
+		case *planner.IfStmt:
+			err = db.rewriteStatements(t.Then, fns)
+			if err == nil {
+				err = db.rewriteStatements(t.Else, fns)
+			}
+		case *planner.ForStmt:
+			t.Cursor, err = planner.Rewrite(t.Cursor, fns...)
+			if err == nil {
+				err = db.rewriteStatements(t.Body, fns)
+			}
+		case *planner.CallStmt:
+			if compile := t.Compile; compile != nil {
+				t.Compile = func(body string) ([]query.Statement, error) {
+					stmts, err := compile(body)
+					if err != nil {
+						return nil, err
+					}
+
+					return stmts, db.prepareStatements(stmts)
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rewriteWith applies the rewrite functions to the statement and
+// the common table expressions of stmt.
+func rewriteWith(stmt *planner.WithStmt, fns []planner.RewriteFunc) error {
+	var err error
+
+	for i := range stmt.CTEs {
+		cte := &stmt.CTEs[i]
+		cte.Initial, err = planner.Rewrite(cte.Initial, fns...)
+		if err != nil {
+			return err
+		}
+
+		if cte.Recursive != nil {
+			cte.Recursive, err = planner.Rewrite(cte.Recursive, fns...)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	stmt.Statement, err = planner.Rewrite(stmt.Statement, fns...)
+	return err
+}
+
+// checkStatement returns an error if stmt is not allowed by the options of the database.
+func (db *DB) checkStatement(stmt query.Statement) error {
+	if db.allowed == nil && db.denied == nil {
+		return nil
+	}
+
+	// control flow statements are only checked through the statements they run
+	switch t := stmt.(type) {
+	case *planner.IfStmt:
+		err := db.checkStatements(t.Then)
+		if err != nil {
+			return err
+		}
+		return db.checkStatements(t.Else)
+	case *planner.ForStmt:
+		err := db.checkStatement(t.Cursor)
+		if err != nil {
+			return err
+		}
+		return db.checkStatements(t.Body)
+	}
+
+	typ := parser.StatementType(stmt)
+	if (db.allowed != nil && !db.allowed[typ]) || db.denied[typ] {
+		return fmt.Errorf("%w: %s", database.ErrStatementNotAllowed, typ)
+	}
+
+	switch t := stmt.(type) {
+	case *planner.ExplainStmt:
+		// EXPLAIN ANALYZE executes the explained statement
+		if t.Analyze {
+			return db.checkStatement(t.Statement)
+		}
+	case query.CreateProcedureStmt:
+		// procedures run their statements when called
+		return db.checkStatements(t.Statements)
+	case query.CreateEventStmt:
+		if t.Parsed != nil {
+			return db.checkStatement(t.Parsed)
+		}
+	}
+
+	return nil
+}
+
+func (db *DB) checkStatements(stmts []query.Statement) error {
+	for _, stmt := range stmts {
+		err := db.checkStatement(stmt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // View starts a read only transaction, runs fn and automatically rolls it back.
 func (db *DB) View(fn func(tx *Tx) error) error {
 	tx, err := db.Begin(false)
@@ -59,6 +444,105 @@ func (db *DB) Update(fn func(tx *Tx) error) error {
 	return tx.Commit()
 }
 
+// TxnOptions configures how Txn runs transactions.
+type TxnOptions struct {
+	// Writable starts a read-write transaction directly,
+	// instead of detecting whether fn needs to write.
+	Writable bool
+	// MaxRetries is the number of times fn is retried after a conflict
+	// or if the database is busy. Defaults to 10.
+	MaxRetries int
+	// MinBackoff and MaxBackoff bound the duration to wait before retrying.
+	// The duration doubles after every attempt, with a random jitter.
+	// They default to 1ms and 100ms.
+	MinBackoff, MaxBackoff time.Duration
+}
+
+// Txn runs fn within a transaction and commits it if fn returns no error,
+// or rolls it back otherwise.
+// fn is first run in a read-only transaction, which doesn't wait for other
+// read-write transactions. If it attempts to write, it is run again in a
+// read-write transaction. If the transaction conflicts with another one or
+// if the database is busy, fn is retried with an exponential backoff.
+// fn can therefore be called multiple times and must not have side effects
+// outside of the transaction.
+func (db *DB) Txn(ctx context.Context, fn func(tx *Tx) error) error {
+	return db.TxnWithOptions(ctx, nil, fn)
+}
+
+// TxnWithOptions runs fn like Txn, using the given options.
+func (db *DB) TxnWithOptions(ctx context.Context, opts *TxnOptions, fn func(tx *Tx) error) error {
+	var o TxnOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.MaxRetries == 0 {
+		o.MaxRetries = 10
+	}
+	if o.MinBackoff == 0 {
+		o.MinBackoff = time.Millisecond
+	}
+	if o.MaxBackoff == 0 {
+		o.MaxBackoff = 100 * time.Millisecond
+	}
+
+	writable := o.Writable
+	backoff := o.MinBackoff
+	for retries := 0; ; {
+		err := db.runTxn(ctx, writable, fn)
+		if err == nil {
+			return nil
+		}
+
+		if !writable && errors.Is(err, engine.ErrTransactionReadOnly) {
+			writable = true
+			continue
+		}
+
+		if retries >= o.MaxRetries || !(errors.Is(err, engine.ErrTransactionConflict) || errors.Is(err, database.ErrBusy)) {
+			return err
+		}
+		retries++
+
+		// wait between half and the totality of the backoff
+		// to avoid retrying at the same time as other transactions.
+		wait := backoff/2 + time.Duration(db.DB.Rand().Int63n(int64(backoff/2)+1))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+
+		backoff *= 2
+		if backoff > o.MaxBackoff {
+			backoff = o.MaxBackoff
+		}
+	}
+}
+
+func (db *DB) runTxn(ctx context.Context, writable bool, fn func(tx *Tx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin(writable)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = fn(tx)
+	if err != nil {
+		return err
+	}
+
+	if !writable {
+		return nil
+	}
+
+	return tx.Commit()
+}
+
 // Exec a query against the database without returning the result.
 func (db *DB) Exec(ctx context.Context, q string, args ...interface{}) error {
 	res, err := db.Query(ctx, q, args...)
@@ -72,12 +556,22 @@ func (db *DB) Exec(ctx context.Context, q string, args ...interface{}) error {
 // Query the database and return the result.
 // The returned result must always be closed after usage.
 func (db *DB) Query(ctx context.Context, q string, args ...interface{}) (*query.Result, error) {
-	pq, err := parser.ParseQuery(ctx, q)
+	pq, err := db.ParseQuery(ctx, q)
 	if err != nil {
 		return nil, err
 	}
 
-	return pq.Run(ctx, db.DB, argsToParams(args))
+	params := argsToParams(args)
+
+	// statements run within a transaction started with BEGIN
+	// may read its uncommitted changes and are not cached
+	if db.cache != nil && db.DB.GetAttachedTx() == nil {
+		if key, tables, ok := cacheKey(pq, params); ok {
+			return db.cache.query(ctx, db.DB, key, tables, pq, params)
+		}
+	}
+
+	return pq.Run(ctx, db.DB, params)
 }
 
 // QueryDocument runs the query and returns the first document.
@@ -113,12 +607,14 @@ func (db *DB) QueryDocument(ctx context.Context, q string, args ...interface{})
 // and read/write can be used to read, create, delete and modify tables.
 type Tx struct {
 	*database.Transaction
+
+	db *DB
 }
 
 // Query the database withing the transaction and returns the result.
 // Closing the returned result after usage is not mandatory.
 func (tx *Tx) Query(ctx context.Context, q string, args ...interface{}) (*query.Result, error) {
-	pq, err := parser.ParseQuery(ctx, q)
+	pq, err := tx.db.ParseQuery(ctx, q)
 	if err != nil {
 		return nil, err
 	}
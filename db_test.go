@@ -1,14 +1,26 @@
 package genji_test
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/genjidb/genji"
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/genjidb/genji/sql/parser"
+	"github.com/genjidb/genji/sql/planner"
+	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/query/expr"
 	"github.com/stretchr/testify/require"
 )
 
@@ -121,3 +133,1199 @@ func TestQueryDocument(t *testing.T) {
 		require.Nil(t, r)
 	})
 }
+
+func TestOpenWithOptions(t *testing.T) {
+	db, err := genji.OpenWithOptions(":memory:", &genji.Options{
+		BusyMode: database.BusyError,
+	})
+	require.NoError(t, err)
+	defer db.Close()
+
+	tx, err := db.Begin(true)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	_, err = db.Begin(true)
+	require.Equal(t, database.ErrBusy, err)
+}
+
+func TestConcurrentQueries(t *testing.T) {
+	ctx := context.Background()
+
+	for _, mode := range []database.BusyMode{database.BusyWait, database.BusyError} {
+		t.Run(fmt.Sprintf("BusyMode %d", mode), func(t *testing.T) {
+			db, err := genji.OpenWithOptions(":memory:", &genji.Options{BusyMode: mode})
+			require.NoError(t, err)
+			defer db.Close()
+
+			err = db.Exec(ctx, "CREATE TABLE test; INSERT INTO test (a) VALUES (1), (2)")
+			require.NoError(t, err)
+
+			// SELECT statements run in read-only transactions
+			// and don't prevent other statements from running.
+			res1, err := db.Query(ctx, "SELECT * FROM test")
+			require.NoError(t, err)
+			defer res1.Close()
+			res2, err := db.Query(ctx, "SELECT * FROM test WHERE a > 1")
+			require.NoError(t, err)
+			defer res2.Close()
+
+			// the memory engine doesn't let a writer run while reading
+			// transactions are opened: the INSERT runs once the results are closed.
+			done := make(chan error, 1)
+			go func() {
+				done <- db.Exec(ctx, "INSERT INTO test (a) VALUES (3)")
+			}()
+
+			for _, res := range []*query.Result{res1, res2} {
+				var n int
+				err = res.Iterate(func(d document.Document) error {
+					n++
+					return nil
+				})
+				require.NoError(t, err)
+				require.NotZero(t, n)
+				require.NoError(t, res.Close())
+			}
+
+			select {
+			case err = <-done:
+				require.NoError(t, err)
+			case <-time.After(5 * time.Second):
+				t.Fatal("INSERT blocked after the results were closed")
+			}
+
+			d, err := db.QueryDocument(ctx, "SELECT COUNT(*) FROM test")
+			require.NoError(t, err)
+			v, err := d.GetByField("COUNT(*)")
+			require.NoError(t, err)
+			require.Equal(t, int64(3), v.V)
+		})
+	}
+}
+
+func TestTransactionsTable(t *testing.T) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	q := "SELECT statement FROM __genji_transactions"
+	d, err := db.QueryDocument(ctx, q)
+	require.NoError(t, err)
+
+	var statement string
+	err = document.Scan(d, &statement)
+	require.NoError(t, err)
+	require.Equal(t, q, statement)
+
+	require.Empty(t, db.DB.Transactions())
+}
+
+func TestAddRewriter(t *testing.T) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	err = db.Exec(ctx, "CREATE TABLE test; INSERT INTO test (a, tenant) VALUES (1, 1), (2, 2), (3, 1), (4, 1)")
+	require.NoError(t, err)
+
+	// only return documents of tenant 1
+	db.AddRewriter(func(t *planner.Tree) (*planner.Tree, error) {
+		n := t.Root
+		for n.Left().Left() != nil {
+			n = n.Left()
+		}
+		n.SetLeft(planner.NewSelectionNode(n.Left(), expr.Eq(expr.FieldSelector{document.ValuePathFragment{FieldName: "tenant"}}, expr.IntegerValue(1))))
+		return t, nil
+	})
+	// return at most 2 documents
+	db.AddRewriter(func(t *planner.Tree) (*planner.Tree, error) {
+		return planner.NewTree(planner.NewLimitNode(t.Root, 2)), nil
+	})
+
+	res, err := db.Query(ctx, "SELECT a FROM test")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	err = document.IteratorToJSONArray(&buf, res)
+	require.NoError(t, err)
+	require.NoError(t, res.Close())
+	require.JSONEq(t, `[{"a": 1}, {"a": 3}]`, buf.String())
+
+	d, err := db.QueryDocument(ctx, "EXPLAIN SELECT a FROM test")
+	require.NoError(t, err)
+	v, err := d.GetByField("plan")
+	require.NoError(t, err)
+	require.Equal(t, "Limit(2)\n└── ∏(a)\n    └── σ(cond: tenant = 1)\n        └── Table(test)", v.V)
+
+	// so are the statements of procedures, when they are called
+	err = db.Exec(ctx, "CREATE PROCEDURE p() BEGIN IF true THEN SELECT a FROM test; END IF; END")
+	require.NoError(t, err)
+	res, err = db.Query(ctx, "CALL p()")
+	require.NoError(t, err)
+	buf.Reset()
+	err = document.IteratorToJSONArray(&buf, res)
+	require.NoError(t, err)
+	require.NoError(t, res.Close())
+	require.JSONEq(t, `[{"a": 1}, {"a": 3}]`, buf.String())
+
+	// statements run within a transaction are rewritten too
+	err = db.Update(func(tx *genji.Tx) error {
+		return tx.Exec(ctx, "DELETE FROM test")
+	})
+	require.NoError(t, err)
+
+	tx, err := db.Begin(false)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	tb, err := tx.GetTable("test")
+	require.NoError(t, err)
+	var count int
+	err = tb.Iterate(func(d document.Document) error {
+		count++
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+}
+
+func TestStatementPanic(t *testing.T) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	err = db.Exec(ctx, "CREATE TABLE test; INSERT INTO test (a) VALUES (1), (2), (3)")
+	require.NoError(t, err)
+
+	// panic when reading the document with a = 2
+	boom := true
+	db.AddRewriter(func(t *planner.Tree) (*planner.Tree, error) {
+		n := t.Root
+		for n.Left().Left() != nil {
+			n = n.Left()
+		}
+		n.SetLeft(planner.NewOperatorNode(n.Left(), "boom", planner.OperatorFunc(func(d document.Document) (document.Document, error) {
+			v, err := d.GetByField("a")
+			if err != nil {
+				return nil, err
+			}
+			if boom && v.V == int64(2) {
+				panic("boom")
+			}
+			return d, nil
+		})))
+		return t, nil
+	})
+
+	t.Run("Query", func(t *testing.T) {
+		res, err := db.Query(ctx, "SELECT * FROM test")
+		require.NoError(t, err)
+
+		err = res.Iterate(func(d document.Document) error { return nil })
+		require.True(t, errors.Is(err, database.ErrStatementPanic))
+		require.EqualError(t, err, "statement panicked: boom")
+		require.Equal(t, database.CodeInternalError, database.CodeOf(err))
+		require.NoError(t, res.Close())
+
+		_, err = db.QueryDocument(ctx, "SELECT * FROM test WHERE a > 1")
+		require.True(t, errors.Is(err, database.ErrStatementPanic))
+	})
+
+	t.Run("Callback", func(t *testing.T) {
+		res, err := db.Query(ctx, "SELECT * FROM test")
+		require.NoError(t, err)
+		defer res.Close()
+
+		// panics of the caller are not recovered
+		require.PanicsWithValue(t, "callback", func() {
+			res.Iterate(func(d document.Document) error { panic("callback") })
+		})
+	})
+
+	t.Run("Exec", func(t *testing.T) {
+		err := db.Exec(ctx, "UPDATE test SET b = 1")
+		require.True(t, errors.Is(err, database.ErrStatementPanic))
+	})
+
+	t.Run("Transaction", func(t *testing.T) {
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		err = tx.Exec(ctx, "UPDATE test SET b = 1")
+		require.True(t, errors.Is(err, database.ErrStatementPanic))
+
+		// the transaction remains usable
+		err = tx.Exec(ctx, "INSERT INTO test (a) VALUES (4)")
+		require.NoError(t, err)
+		require.NoError(t, tx.Commit())
+	})
+
+	// the changes of the statements that panicked were rolled back
+	boom = false
+	d, err := db.QueryDocument(ctx, "SELECT COUNT(*) AS total, COUNT(b) AS updated FROM test")
+	require.NoError(t, err)
+	data, err := document.MarshalJSON(d)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"total": 4, "updated": 0}`, string(data))
+}
+
+func TestTxn(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Read-only detection", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		var writable []bool
+		err = db.Txn(ctx, func(tx *genji.Tx) error {
+			writable = append(writable, tx.Writable())
+			return tx.Exec(ctx, "CREATE TABLE test; INSERT INTO test (a) VALUES (1)")
+		})
+		require.NoError(t, err)
+		require.Equal(t, []bool{false, true}, writable)
+
+		writable = nil
+		err = db.Txn(ctx, func(tx *genji.Tx) error {
+			writable = append(writable, tx.Writable())
+			_, err := tx.QueryDocument(ctx, "SELECT * FROM test")
+			return err
+		})
+		require.NoError(t, err)
+		require.Equal(t, []bool{false}, writable)
+
+		d, err := db.QueryDocument(ctx, "SELECT COUNT(*) FROM test")
+		require.NoError(t, err)
+		v, err := d.GetByField("COUNT(*)")
+		require.NoError(t, err)
+		require.Equal(t, int64(1), v.V)
+	})
+
+	t.Run("Retries", func(t *testing.T) {
+		db, err := genji.OpenWithOptions(":memory:", &genji.Options{
+			BusyMode: database.BusyError,
+		})
+		require.NoError(t, err)
+		defer db.Close()
+
+		opts := genji.TxnOptions{Writable: true, MaxRetries: 3, MinBackoff: time.Microsecond}
+
+		// conflicts are retried
+		var calls int
+		err = db.TxnWithOptions(ctx, &opts, func(tx *genji.Tx) error {
+			calls++
+			if calls < 3 {
+				return engine.ErrTransactionConflict
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 3, calls)
+
+		// other errors are not
+		calls = 0
+		errFoo := errors.New("foo")
+		err = db.TxnWithOptions(ctx, &opts, func(tx *genji.Tx) error {
+			calls++
+			return errFoo
+		})
+		require.Equal(t, errFoo, err)
+		require.Equal(t, 1, calls)
+
+		// the database stays busy until the maximum number of retries is reached
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		calls = 0
+		err = db.TxnWithOptions(ctx, &opts, func(tx *genji.Tx) error {
+			calls++
+			return nil
+		})
+		require.Equal(t, database.ErrBusy, err)
+		require.Zero(t, calls)
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		ctx, cancel := context.WithCancel(ctx)
+		err = db.Txn(ctx, func(tx *genji.Tx) error {
+			cancel()
+			return engine.ErrTransactionConflict
+		})
+		require.Equal(t, context.Canceled, err)
+	})
+}
+
+func TestMaxQueryMemory(t *testing.T) {
+	db, err := genji.OpenWithOptions(":memory:", &genji.Options{
+		MaxQueryMemory: 10000,
+	})
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	err = db.Update(func(tx *genji.Tx) error {
+		err := tx.Exec(ctx, "CREATE TABLE test")
+		if err != nil {
+			return err
+		}
+
+		for i := 0; i < 1000; i++ {
+			err = tx.Exec(ctx, "INSERT INTO test (a, b) VALUES (?, ?)", i, i%2)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	count := func(q string) error {
+		res, err := db.Query(ctx, q)
+		if err != nil {
+			return err
+		}
+		defer res.Close()
+
+		_, err = res.Count()
+		return err
+	}
+
+	// streaming queries are not limited
+	require.NoError(t, count("SELECT * FROM test"))
+	require.NoError(t, count("SELECT COUNT(*) FROM test GROUP BY b"))
+
+	err = count("SELECT * FROM test ORDER BY a")
+	require.True(t, errors.Is(err, database.ErrMemoryLimitExceeded))
+
+	err = count("SELECT COUNT(*) FROM test GROUP BY a")
+	require.True(t, errors.Is(err, database.ErrMemoryLimitExceeded))
+
+	// the memory of all the subqueries of a statement is accounted together:
+	// the documents of the first one are kept while the second one sorts its documents
+	require.NoError(t, count("SELECT * FROM test WHERE a IN (SELECT a FROM test WHERE a < 200)"))
+	require.NoError(t, count("SELECT * FROM test WHERE b IN (SELECT b FROM test WHERE a < 100 ORDER BY a)"))
+	err = count("SELECT * FROM test WHERE a IN (SELECT a FROM test WHERE a < 200) AND b IN (SELECT b FROM test WHERE a < 100 ORDER BY a)")
+	require.True(t, errors.Is(err, database.ErrMemoryLimitExceeded))
+
+	// joins hold a copy of the documents they combine
+	err = db.Exec(ctx, "CREATE TABLE wide; INSERT INTO wide (a, s) VALUES (1, ?), (2, ?)", strings.Repeat("x", 4000), strings.Repeat("y", 7000))
+	require.NoError(t, err)
+	require.NoError(t, count("SELECT * FROM wide JOIN test ON wide.a = test.a"))
+	err = count("SELECT * FROM wide JOIN wide AS other ON wide.a < other.a")
+	require.True(t, errors.Is(err, database.ErrMemoryLimitExceeded))
+
+	// within a transaction, the previous values of the documents modified
+	// by a statement are kept until it ends
+	tx, err := db.Begin(true)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	err = tx.Exec(ctx, "CREATE TABLE big")
+	require.NoError(t, err)
+	for i := 0; i < 50; i++ {
+		err = tx.Exec(ctx, "INSERT INTO big (a, b) VALUES (?, ?)", i, strings.Repeat("b", 300))
+		require.NoError(t, err)
+	}
+	err = tx.Exec(ctx, "UPDATE big SET b = 2")
+	require.True(t, errors.Is(err, database.ErrMemoryLimitExceeded))
+	err = tx.Exec(ctx, "UPDATE big SET b = 2 WHERE a < 10")
+	require.NoError(t, err)
+	d, err := tx.QueryDocument(ctx, "SELECT COUNT(*) FROM big WHERE b = 2")
+	require.NoError(t, err)
+	v, err := d.GetByField("COUNT(*)")
+	require.NoError(t, err)
+	require.Equal(t, int64(10), v.V)
+}
+
+func TestParserLimits(t *testing.T) {
+	db, err := genji.OpenWithOptions(":memory:", &genji.Options{
+		ParserLimits: parser.Limits{MaxDepth: 10, MaxTokens: 100},
+	})
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	err = db.Exec(ctx, "SELECT ((1))")
+	require.NoError(t, err)
+
+	var perr *parser.ParseError
+	err = db.Exec(ctx, "SELECT "+strings.Repeat("(", 20)+"1"+strings.Repeat(")", 20))
+	require.True(t, errors.As(err, &perr))
+	require.Equal(t, "expression exceeds the maximum nesting depth of 10", perr.Message)
+
+	err = db.Exec(ctx, "SELECT 1 IN ["+strings.Repeat("1, ", 100)+"1]")
+	require.True(t, errors.As(err, &perr))
+	require.Equal(t, "query exceeds the maximum number of 100 tokens", perr.Message)
+}
+
+func TestAllowedStatements(t *testing.T) {
+	open := func(opts *genji.Options) *genji.DB {
+		db, err := genji.OpenWithOptions(":memory:", opts)
+		require.NoError(t, err)
+		return db
+	}
+
+	ctx := context.Background()
+
+	t.Run("Allowed", func(t *testing.T) {
+		db := open(&genji.Options{AllowedStatements: genji.ReadOnlyStatements})
+		defer db.Close()
+
+		require.NoError(t, db.Exec(ctx, "SELECT 1; EXPLAIN SELECT 1; SHOW TABLES"))
+
+		for _, q := range []string{
+			"CREATE TABLE test",
+			"INSERT INTO test VALUES {a: 1}",
+			"SELECT 1; DELETE FROM test",
+			"EXPLAIN ANALYZE DELETE FROM test",
+		} {
+			err := db.Exec(ctx, q)
+			require.True(t, errors.Is(err, database.ErrStatementNotAllowed), q)
+			require.Equal(t, database.CodeInsufficientPrivilege, database.CodeOf(err))
+		}
+
+		// statements are checked before being executed
+		err := db.Exec(ctx, "BEGIN; CREATE TABLE test")
+		require.Error(t, err)
+		require.Nil(t, db.DB.GetAttachedTx())
+	})
+
+	t.Run("Denied", func(t *testing.T) {
+		db := open(&genji.Options{DeniedStatements: []string{"drop table", "DROP INDEX"}})
+		defer db.Close()
+
+		require.NoError(t, db.Exec(ctx, "CREATE TABLE test; CREATE INDEX idx ON test (a)"))
+
+		err := db.Exec(ctx, "DROP TABLE test")
+		require.EqualError(t, err, "statement not allowed: DROP TABLE")
+		err = db.Exec(ctx, "DROP INDEX idx")
+		require.True(t, errors.Is(err, database.ErrStatementNotAllowed))
+
+		// the statements of procedures are checked when they are created
+		err = db.Exec(ctx, "CREATE PROCEDURE p() BEGIN FOR SELECT a FROM test DO IF $a > 1 THEN DROP TABLE test; END IF; END FOR; END")
+		require.True(t, errors.Is(err, database.ErrStatementNotAllowed))
+		err = db.Exec(ctx, "CREATE PROCEDURE p() BEGIN FOR SELECT a FROM test DO DELETE FROM test WHERE a = $a; END FOR; END")
+		require.NoError(t, err)
+
+		// so are the statements of events
+		err = db.Exec(ctx, "CREATE EVENT e EVERY 1 HOUR DO DROP TABLE test")
+		require.True(t, errors.Is(err, database.ErrStatementNotAllowed))
+
+		// and the statements of procedures are checked again when they are called
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		err = tx.CreateProcedure(database.ProcedureConfig{ProcedureName: "dropper", Body: "BEGIN DROP TABLE test; END"})
+		require.NoError(t, err)
+		require.NoError(t, tx.Commit())
+
+		err = db.Exec(ctx, "CALL dropper()")
+		require.True(t, errors.Is(err, database.ErrStatementNotAllowed))
+		require.NoError(t, db.Exec(ctx, "SELECT * FROM test"))
+	})
+}
+
+func TestAttach(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Statement", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `
+			ATTACH DATABASE ':memory:' AS other;
+			CREATE TABLE other.test (a INTEGER PRIMARY KEY);
+			CREATE INDEX idx_b ON other.test (b);
+			INSERT INTO other.test (a, b) VALUES (1, 10), (2, 20);
+			CREATE TABLE test;
+			INSERT INTO test (a) VALUES (3);
+		`)
+		require.NoError(t, err)
+
+		d, err := db.QueryDocument(ctx, "SELECT COUNT(*) AS c FROM other.test WHERE b > 10")
+		require.NoError(t, err)
+		v, err := d.GetByField("c")
+		require.NoError(t, err)
+		require.Equal(t, int64(1), v.V)
+
+		d, err = db.QueryDocument(ctx, "EXPLAIN SELECT * FROM other.test WHERE b = 10")
+		require.NoError(t, err)
+		v, err = d.GetByField("plan")
+		require.NoError(t, err)
+		require.Contains(t, v.V.(string), "Index(idx_b)")
+
+		err = db.Exec(ctx, "UPDATE other.test SET b = 30 WHERE a = 2; DELETE FROM other.test WHERE a = 1")
+		require.NoError(t, err)
+		d, err = db.QueryDocument(ctx, "SELECT b FROM other.test")
+		require.NoError(t, err)
+		v, err = d.GetByField("b")
+		require.NoError(t, err)
+		require.Equal(t, int64(30), v.V)
+
+		// tables of the main database are not affected
+		d, err = db.QueryDocument(ctx, "SELECT a FROM test")
+		require.NoError(t, err)
+		v, err = d.GetByField("a")
+		require.NoError(t, err)
+		require.Equal(t, int64(3), v.V)
+
+		// changes are rolled back along with the main transaction
+		err = db.Exec(ctx, "BEGIN; INSERT INTO other.test (a) VALUES (4); ROLLBACK")
+		require.NoError(t, err)
+		d, err = db.QueryDocument(ctx, "SELECT COUNT(*) AS c FROM other.test")
+		require.NoError(t, err)
+		v, err = d.GetByField("c")
+		require.NoError(t, err)
+		require.Equal(t, int64(1), v.V)
+
+		err = db.Exec(ctx, "ATTACH ':memory:' AS other")
+		require.Error(t, err)
+
+		err = db.Exec(ctx, "DETACH DATABASE other")
+		require.NoError(t, err)
+		err = db.Exec(ctx, "SELECT * FROM other.test")
+		require.Equal(t, database.ErrTableNotFound, err)
+	})
+
+	t.Run("Read only", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+		other, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer other.Close()
+
+		err = other.Exec(ctx, "CREATE TABLE test; INSERT INTO test (a) VALUES (1)")
+		require.NoError(t, err)
+
+		err = db.Attach("other", other, true)
+		require.NoError(t, err)
+
+		d, err := db.QueryDocument(ctx, "SELECT a FROM other.test")
+		require.NoError(t, err)
+		v, err := d.GetByField("a")
+		require.NoError(t, err)
+		require.Equal(t, int64(1), v.V)
+
+		err = db.Exec(ctx, "INSERT INTO other.test (a) VALUES (2)")
+		require.True(t, errors.Is(err, engine.ErrTransactionReadOnly), "%v", err)
+
+		require.NoError(t, db.Detach("other"))
+	})
+}
+
+func TestResultCache(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := genji.OpenWithOptions(":memory:", &genji.Options{ResultCacheSize: 3, ResultCacheMaxDocuments: 3})
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec(ctx, "CREATE TABLE test; CREATE TABLE other")
+	require.NoError(t, err)
+
+	// count returns the c field of the first document of the result, or zero if there is none.
+	count := func(q string, args ...interface{}) int64 {
+		t.Helper()
+		d, err := db.QueryDocument(ctx, q, args...)
+		if err == database.ErrDocumentNotFound {
+			return 0
+		}
+		require.NoError(t, err)
+		v, err := d.GetByField("c")
+		require.NoError(t, err)
+		return v.V.(int64)
+	}
+
+	// keys returns the keys of the documents of the table.
+	keys := func(tb *database.Table) [][]byte {
+		var keys [][]byte
+		err := tb.Iterate(func(d document.Document) error {
+			keys = append(keys, d.(document.Keyer).Key())
+			return nil
+		})
+		require.NoError(t, err)
+		return keys
+	}
+
+	// fill replaces the documents of the table with n documents using the Go API.
+	fill := func(n int) {
+		t.Helper()
+		err := db.Update(func(tx *genji.Tx) error {
+			tb, err := tx.GetTable("test")
+			if err != nil {
+				return err
+			}
+			for _, k := range keys(tb) {
+				err = tb.Delete(k)
+				if err != nil {
+					return err
+				}
+			}
+			for i := 0; i < n; i++ {
+				_, err = tb.Insert(document.NewFieldBuffer().Add("a", document.NewIntegerValue(int64(i))))
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		require.NoError(t, err)
+	}
+
+	// clear removes the documents of the table from its store directly,
+	// which isn't detected by the cache.
+	clear := func() {
+		t.Helper()
+		err := db.Update(func(tx *genji.Tx) error {
+			tb, err := tx.GetTable("test")
+			if err != nil {
+				return err
+			}
+			for _, k := range keys(tb) {
+				err = tb.Store.Delete(k)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		require.NoError(t, err)
+	}
+
+	fill(3)
+	require.EqualValues(t, 2, count("SELECT COUNT(*) AS c FROM test WHERE a > ?", 0))
+	clear()
+
+	// the same statement with the same parameters returns the cached result
+	require.EqualValues(t, 2, count("SELECT COUNT(*) AS c FROM test WHERE a > ?", 0))
+	require.EqualValues(t, 2, count("select count(*) as c from test where a > ?", 0))
+	require.EqualValues(t, 0, count("SELECT COUNT(*) AS c FROM test WHERE a > ?", 1))
+	require.EqualValues(t, 0, count("SELECT COUNT(*) AS c FROM test WHERE a > ?", 0.5))
+
+	// statements run within a transaction are not cached
+	err = db.View(func(tx *genji.Tx) error {
+		d, err := tx.QueryDocument(ctx, "SELECT COUNT(*) AS c FROM test WHERE a > ?", 0)
+		require.NoError(t, err)
+		v, err := d.GetByField("c")
+		require.NoError(t, err)
+		require.EqualValues(t, 0, v.V)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// writes to other tables don't invalidate the result
+	err = db.Exec(ctx, "INSERT INTO other (a) VALUES (1)")
+	require.NoError(t, err)
+	require.EqualValues(t, 2, count("SELECT COUNT(*) AS c FROM test WHERE a > ?", 0))
+
+	// writes to the table do
+	err = db.Exec(ctx, "INSERT INTO test (a) VALUES (3)")
+	require.NoError(t, err)
+	require.EqualValues(t, 1, count("SELECT COUNT(*) AS c FROM test WHERE a > ?", 0))
+
+	// and so do writes made through the Go API
+	fill(2)
+	require.EqualValues(t, 1, count("SELECT COUNT(*) AS c FROM test WHERE a > ?", 0))
+	fill(3)
+
+	// rolled back changes are ignored
+	err = db.Exec(ctx, "BEGIN; INSERT INTO test (a) VALUES (10); ROLLBACK")
+	require.NoError(t, err)
+	require.EqualValues(t, 2, count("SELECT COUNT(*) AS c FROM test WHERE a > ?", 0))
+
+	// uncommitted changes are visible to the transaction
+	err = db.Exec(ctx, "BEGIN; INSERT INTO test (a) VALUES (10)")
+	require.NoError(t, err)
+	require.EqualValues(t, 3, count("SELECT COUNT(*) AS c FROM test WHERE a > ?", 0))
+	err = db.Exec(ctx, "ROLLBACK")
+	require.NoError(t, err)
+
+	countAll := func(q string) int {
+		t.Helper()
+		res, err := db.Query(ctx, q)
+		require.NoError(t, err)
+		defer res.Close()
+		n, err := res.Count()
+		require.NoError(t, err)
+		return n
+	}
+
+	t.Run("Large results", func(t *testing.T) {
+		fill(4)
+		require.Equal(t, 4, countAll("SELECT * FROM test"))
+		clear()
+		require.Equal(t, 0, countAll("SELECT * FROM test"))
+	})
+
+	t.Run("Eviction", func(t *testing.T) {
+		fill(4)
+		require.Equal(t, 1, countAll("SELECT * FROM test LIMIT 1"))
+		require.Equal(t, 2, countAll("SELECT * FROM test LIMIT 2"))
+		require.Equal(t, 3, countAll("SELECT * FROM test LIMIT 3"))
+		require.Equal(t, 3, countAll("SELECT * FROM test WHERE a > 0"))
+		clear()
+		require.Equal(t, 3, countAll("SELECT * FROM test LIMIT 3"))
+		require.Equal(t, 2, countAll("SELECT * FROM test LIMIT 2"))
+		require.Equal(t, 0, countAll("SELECT * FROM test LIMIT 1"))
+	})
+
+	t.Run("Subqueries", func(t *testing.T) {
+		fill(2)
+		q := "SELECT * FROM test WHERE EXISTS (SELECT * FROM other WHERE a = test.a)"
+		require.Equal(t, 1, countAll(q))
+
+		// writes to the tables of subqueries invalidate the result
+		err = db.Exec(ctx, "INSERT INTO other (a) VALUES (0)")
+		require.NoError(t, err)
+		require.Equal(t, 2, countAll(q))
+	})
+
+	t.Run("Nondeterministic statements", func(t *testing.T) {
+		clock := memoryengine.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+		db, err := genji.OpenWithOptions(":memory:", &genji.Options{ResultCacheSize: 10, Clock: clock})
+		require.NoError(t, err)
+		defer db.Close()
+
+		// query returns the documents of the result as JSON
+		query := func(q string) []string {
+			t.Helper()
+			res, err := db.Query(ctx, q)
+			require.NoError(t, err)
+			defer res.Close()
+
+			var docs []string
+			err = res.Iterate(func(d document.Document) error {
+				data, err := document.MarshalJSON(d)
+				docs = append(docs, string(data))
+				return err
+			})
+			require.NoError(t, err)
+			return docs
+		}
+
+		err = db.Exec(ctx, "CREATE TABLE test")
+		require.NoError(t, err)
+		for i := 0; i < 100; i++ {
+			err = db.Exec(ctx, "INSERT INTO test (a) VALUES (?)", i)
+			require.NoError(t, err)
+		}
+
+		tests := []struct {
+			name      string
+			query     string
+			cacheable bool
+		}{
+			{"NOW", "SELECT NOW() AS now", false},
+			{"NOW in a subquery", "SELECT a, (SELECT NOW() AS now FROM test LIMIT 1) AS now FROM test LIMIT 1", false},
+			{"Sample", "SELECT a FROM test TABLESAMPLE 5 ROWS", false},
+			{"Sample in a subquery", "SELECT a FROM test WHERE a IN (SELECT a FROM test TABLESAMPLE 50 PERCENT)", false},
+			{"Repeatable sample", "SELECT a FROM test TABLESAMPLE 5 ROWS REPEATABLE (1)", true},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				var changed bool
+				first := query(test.query)
+				for i := 0; i < 10 && !changed; i++ {
+					clock.Advance(time.Second)
+					changed = !reflect.DeepEqual(first, query(test.query))
+				}
+				require.Equal(t, !test.cacheable, changed)
+			})
+		}
+	})
+}
+
+func TestPing(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+
+	require.NoError(t, db.Ping(ctx))
+
+	// a transaction attached to the database doesn't make it unhealthy
+	err = db.Exec(ctx, "BEGIN READ ONLY")
+	require.NoError(t, err)
+	require.NoError(t, db.Ping(ctx))
+	err = db.Exec(ctx, "ROLLBACK")
+	require.NoError(t, err)
+
+	// the memory engine doesn't let transactions begin while a read-write one is running
+	tx, err := db.Begin(true)
+	require.NoError(t, err)
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	require.Equal(t, context.DeadlineExceeded, db.Ping(timeoutCtx))
+	require.NoError(t, tx.Rollback())
+	require.NoError(t, db.Ping(ctx))
+
+	require.NoError(t, db.Close())
+	require.Error(t, db.Ping(ctx))
+}
+
+func TestThrottling(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec(ctx, "PRAGMA max_statement_rate = 2")
+	require.NoError(t, err)
+
+	// the statements of a query are counted separately
+	err = db.Exec(ctx, "CREATE TABLE test; INSERT INTO test (a) VALUES (1)")
+	require.NoError(t, err)
+
+	err = db.Exec(ctx, "INSERT INTO test (a) VALUES (2)")
+	require.True(t, errors.Is(err, database.ErrThrottled))
+}
+
+func TestDeterministicEngine(t *testing.T) {
+	ctx := context.Background()
+	clock := memoryengine.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	open := func() *genji.DB {
+		db, err := genji.NewWithOptions(memoryengine.NewDeterministicEngine(42, clock.Now), &genji.Options{
+			KeyGenerator: database.RandomKeyGenerator,
+		})
+		require.NoError(t, err)
+		return db
+	}
+
+	// run returns the documents sampled from a table with random keys
+	run := func() []string {
+		db := open()
+		defer db.Close()
+
+		err := db.Exec(ctx, "CREATE TABLE test")
+		require.NoError(t, err)
+		for i := 0; i < 10; i++ {
+			err = db.Exec(ctx, "INSERT INTO test (a) VALUES (?)", i)
+			require.NoError(t, err)
+		}
+
+		res, err := db.Query(ctx, "SELECT pk(), a FROM test TABLESAMPLE 3 ROWS")
+		require.NoError(t, err)
+		defer res.Close()
+
+		var docs []string
+		err = res.Iterate(func(d document.Document) error {
+			data, err := document.MarshalJSON(d)
+			docs = append(docs, string(data))
+			return err
+		})
+		require.NoError(t, err)
+		require.Len(t, docs, 3)
+		return docs
+	}
+
+	require.Equal(t, run(), run())
+
+	db := open()
+	defer db.Close()
+
+	clock.Advance(time.Hour)
+	d, err := db.QueryDocument(ctx, "SELECT started_at FROM __genji_transactions")
+	require.NoError(t, err)
+	var startedAt string
+	err = document.Scan(d, &startedAt)
+	require.NoError(t, err)
+	require.Equal(t, "2020-01-01T01:00:00Z", startedAt)
+}
+
+func TestClock(t *testing.T) {
+	ctx := context.Background()
+	clock := memoryengine.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	db, err := genji.NewWithOptions(memoryengine.NewEngine(), &genji.Options{
+		Clock:        clock,
+		KeyGenerator: database.NewULIDKeyGenerator(),
+	})
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := func() string {
+		d, err := db.QueryDocument(ctx, "SELECT NOW()")
+		require.NoError(t, err)
+		var s string
+		err = document.Scan(d, &s)
+		require.NoError(t, err)
+		return s
+	}
+
+	require.Equal(t, "2020-01-01T00:00:00Z", now())
+	clock.Advance(90 * time.Minute)
+	require.Equal(t, "2020-01-01T01:30:00Z", now())
+
+	// keys generated within the same millisecond follow the insertion order
+	clock.Set(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	err = db.Exec(ctx, "CREATE TABLE test")
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		err = db.Exec(ctx, "INSERT INTO test (a) VALUES (?)", i)
+		require.NoError(t, err)
+	}
+
+	res, err := db.Query(ctx, "SELECT pk(), a FROM test")
+	require.NoError(t, err)
+	defer res.Close()
+
+	var i int
+	err = res.Iterate(func(d document.Document) error {
+		var pk string
+		var a int
+		err := document.Scan(d, &pk, &a)
+		if err != nil {
+			return err
+		}
+		require.Len(t, pk, 26)
+		require.Equal(t, "01DXF6DT00", pk[:10])
+		require.Equal(t, i, a)
+		i++
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, i)
+}
+
+func TestOnDDL(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	var changes []database.DDLChange
+	cancel := db.DB.OnDDL(func(c database.DDLChange) {
+		changes = append(changes, c)
+	})
+	defer cancel()
+
+	err = db.Exec(ctx, "CREATE TABLE foo; CREATE INDEX idx_foo_a ON foo(a); ALTER TABLE foo RENAME TO bar")
+	require.NoError(t, err)
+	// failed statements are not reported
+	err = db.Exec(ctx, "CREATE TABLE bar")
+	require.Error(t, err)
+	err = db.Exec(ctx, "DROP TABLE bar")
+	require.NoError(t, err)
+
+	require.Equal(t, []database.DDLChange{
+		{Operation: database.DDLCreate, ObjectType: database.DDLTable, Name: "foo"},
+		{Operation: database.DDLCreate, ObjectType: database.DDLIndex, Name: "idx_foo_a"},
+		{Operation: database.DDLAlter, ObjectType: database.DDLTable, Name: "foo", NewName: "bar"},
+		{Operation: database.DDLDrop, ObjectType: database.DDLIndex, Name: "idx_foo_a"},
+		{Operation: database.DDLDrop, ObjectType: database.DDLTable, Name: "bar"},
+	}, changes)
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	st := db.Store("settings")
+	_, err = st.Get([]byte("version"))
+	require.Equal(t, database.ErrKeyNotFound, err)
+
+	require.NoError(t, st.Put([]byte("version"), []byte("1")))
+	v, err := st.Get([]byte("version"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("1"), v)
+
+	// raw values and documents are written within the same transaction
+	err = db.Update(func(tx *genji.Tx) error {
+		err := tx.Exec(ctx, "CREATE TABLE foo; INSERT INTO foo (a) VALUES (1)")
+		if err != nil {
+			return err
+		}
+
+		kv, err := tx.Store("settings")
+		if err != nil {
+			return err
+		}
+		err = kv.Put([]byte("version"), []byte("2"))
+		if err != nil {
+			return err
+		}
+
+		return errors.New("abort")
+	})
+	require.EqualError(t, err, "abort")
+
+	v, err = st.Get([]byte("version"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("1"), v)
+	err = db.Exec(ctx, "SELECT * FROM foo")
+	require.Equal(t, database.ErrTableNotFound, err)
+
+	var keys []string
+	err = st.Iterate(nil, func(k, v []byte) error {
+		keys = append(keys, string(k))
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"version"}, keys)
+
+	require.NoError(t, st.Delete([]byte("version")))
+	require.Equal(t, database.ErrKeyNotFound, st.Delete([]byte("version")))
+}
+
+func TestPrepare(t *testing.T) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	err = db.Exec(ctx, "CREATE TABLE test; CREATE INDEX idx_test_a ON test (a)")
+	require.NoError(t, err)
+
+	// count the number of times queries are parsed
+	var parsed int
+	db.AddRewriter(func(t *planner.Tree) (*planner.Tree, error) {
+		parsed++
+		return t, nil
+	})
+
+	insert, err := db.Prepare("INSERT INTO test (a, b) VALUES (?, ?)")
+	require.NoError(t, err)
+	for i := 1; i <= 3; i++ {
+		err = insert.Exec(ctx, i, fmt.Sprintf("b%d", i))
+		require.NoError(t, err)
+	}
+	require.Equal(t, "INSERT INTO test (a, b) VALUES (?, ?)", insert.String())
+
+	query := func(t *testing.T, s *genji.Statement, args ...interface{}) string {
+		res, err := s.Query(ctx, args...)
+		require.NoError(t, err)
+		defer res.Close()
+
+		var buf bytes.Buffer
+		err = document.IteratorToJSONArray(&buf, res)
+		require.NoError(t, err)
+		return buf.String()
+	}
+
+	sel, err := db.Prepare("SELECT b FROM test WHERE a = $a")
+	require.NoError(t, err)
+	require.JSONEq(t, `[{"b": "b1"}]`, query(t, sel, sql.Named("a", 1)))
+	require.JSONEq(t, `[{"b": "b3"}]`, query(t, sel, sql.Named("a", 3)))
+	require.JSONEq(t, `[]`, query(t, sel, sql.Named("a", 4)))
+	d, err := sel.QueryDocument(ctx, sql.Named("a", 2))
+	require.NoError(t, err)
+	v, err := d.GetByField("b")
+	require.NoError(t, err)
+	require.Equal(t, "b2", v.V)
+	_, err = sel.QueryDocument(ctx, sql.Named("a", 4))
+	require.Equal(t, database.ErrDocumentNotFound, err)
+
+	// the select statement was parsed once
+	require.Equal(t, 1, parsed)
+
+	// the plan of the statement uses an index that doesn't exist anymore:
+	// the statement is parsed and planned again
+	err = db.Exec(ctx, "DROP INDEX idx_test_a")
+	require.NoError(t, err)
+	parsed = 0
+	require.JSONEq(t, `[{"b": "b1"}]`, query(t, sel, sql.Named("a", 1)))
+	require.JSONEq(t, `[{"b": "b2"}]`, query(t, sel, sql.Named("a", 2)))
+	require.Equal(t, 1, parsed)
+
+	// rolled back changes to the catalog also invalidate the plans
+	tx, err := db.Begin(true)
+	require.NoError(t, err)
+	err = tx.Exec(ctx, "CREATE INDEX idx_test_a ON test (a)")
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback())
+	require.JSONEq(t, `[{"b": "b3"}]`, query(t, sel, sql.Named("a", 3)))
+	require.Equal(t, 2, parsed)
+
+	t.Run("Params", func(t *testing.T) {
+		err := db.Exec(ctx, "CREATE TABLE typed (a INTEGER, b TEXT, c DOCUMENT)")
+		require.NoError(t, err)
+
+		s, err := db.Prepare(`
+			SELECT * FROM typed WHERE a > ? AND b = ? AND c IN ? AND a < (SELECT MAX(a) FROM typed WHERE b != ?);
+			INSERT INTO typed (a, b, c) VALUES (?, 'x', ?);
+		`)
+		require.NoError(t, err)
+		require.Equal(t, []genji.Param{
+			{Position: 1, Type: document.IntegerValue},
+			{Position: 2, Type: document.TextValue},
+			{Position: 3, Type: document.ArrayValue},
+			{Position: 4, Type: document.TextValue},
+			{Position: 5, Type: document.IntegerValue},
+			{Position: 6, Type: document.DocumentValue},
+		}, s.Params())
+
+		s, err = db.Prepare("SELECT * FROM typed WHERE a = $a OR b = $a OR c = $c")
+		require.NoError(t, err)
+		require.Equal(t, []genji.Param{{Name: "a"}, {Name: "c", Type: document.DocumentValue}}, s.Params())
+		err = s.Exec(ctx, sql.Named("a", 1))
+		require.Equal(t, database.CodeUndefinedParameter, database.CodeOf(err))
+		require.EqualError(t, err, "param $c not found")
+		err = s.Exec(ctx, sql.Named("a", 1), sql.Named("c", 1))
+		require.EqualError(t, err, "param $c must be of type document, got integer")
+
+		s, err = db.Prepare("CREATE PROCEDURE p(a) BEGIN DELETE FROM typed WHERE a = $a; END; SELECT * FROM typed WHERE a = ?")
+		require.NoError(t, err)
+		require.Equal(t, []genji.Param{{Position: 1, Type: document.IntegerValue}}, s.Params())
+
+		s, err = db.Prepare("SELECT * FROM typed WHERE a = ? AND b = ?")
+		require.NoError(t, err)
+
+		tests := []struct {
+			name string
+			args []interface{}
+			code database.Code
+		}{
+			{"Valid", []interface{}{1, "x"}, ""},
+			{"Number", []interface{}{1.0, "x"}, ""},
+			{"Null", []interface{}{nil, nil}, ""},
+			{"Missing", []interface{}{1}, database.CodeUndefinedParameter},
+			{"Too many", []interface{}{1, "x", 2}, database.CodeUndefinedParameter},
+			{"Wrong type", []interface{}{"x", "x"}, database.CodeDatatypeMismatch},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				err := s.Exec(ctx, test.args...)
+				if test.code == "" {
+					require.NoError(t, err)
+					return
+				}
+
+				require.Error(t, err)
+				require.Equal(t, test.code, database.CodeOf(err), "%v", err)
+			})
+		}
+	})
+
+	t.Run("Invalid query", func(t *testing.T) {
+		_, err := db.Prepare("SELEC * FROM test")
+		require.Error(t, err)
+
+		s, err := db.Prepare("SELECT * FROM unknown")
+		require.NoError(t, err)
+		_, err = s.Query(ctx)
+		require.Error(t, err)
+	})
+}
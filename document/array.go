@@ -199,50 +199,23 @@ func (a sortableArray) Len() int {
 
 func (a *sortableArray) Swap(i, j int) { a.vb[i], a.vb[j] = a.vb[j], a.vb[i] }
 
-var typeSortOrder = map[ValueType]int{
-	NullValue:     0,
-	BoolValue:     1,
-	DoubleValue:   2,
-	TextValue:     3,
-	ArrayValue:    4,
-	DocumentValue: 5,
-}
-
-func (a *sortableArray) Less(i, j int) (ok bool) {
-	it, jt := a.vb[i].Type, a.vb[j].Type
-	if it == jt {
-		ok, a.err = a.vb[i].IsLesserThan(a.vb[j])
-		return
-	}
-
-	switch {
-	case it.IsNumber():
-		it = DoubleValue
-	case it == BlobValue:
-		it = TextValue
-	}
-
-	switch {
-	case jt.IsNumber():
-		jt = DoubleValue
-	case jt == BlobValue:
-		jt = TextValue
-	}
-
-	if typeSortOrder[it] == typeSortOrder[jt] {
-		ok, a.err = a.vb[i].IsLesserThan(a.vb[j])
-		return
+func (a *sortableArray) Less(i, j int) bool {
+	cmp, err := Compare(a.vb[i], a.vb[j])
+	if err != nil {
+		a.err = err
 	}
 
-	return typeSortOrder[it]-typeSortOrder[jt] < 0
+	return cmp < 0
 }
 
 // SortArray creates a new sorted array.
-// Types are sorted in the following ascending order:
+// Values are sorted using Compare, which means types are sorted
+// in the following ascending order:
 //   - NULL
 //   - Booleans
 //   - Numbers
-//   - Text / Blob
+//   - Text
+//   - Blob
 //   - Arrays
 //   - Documents
 // It doesn't sort nested arrays.
@@ -256,7 +229,7 @@ func SortArray(a Array) (Array, error) {
 	sort.Sort(&s)
 
 	if s.err != nil {
-		return nil, err
+		return nil, s.err
 	}
 
 	return &s.vb, nil
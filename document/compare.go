@@ -2,6 +2,7 @@ package document
 
 import (
 	"bytes"
+	"math"
 	"strings"
 )
 
@@ -145,37 +146,21 @@ func compareBooleans(op operator, a, b bool) bool {
 }
 
 func compareTexts(op operator, l, r string) bool {
-	switch op {
-	case operatorEq:
+	// equality doesn't require ordering the strings,
+	// == is faster than strings.Compare.
+	if op == operatorEq {
 		return l == r
-	case operatorGt:
-		return strings.Compare(l, r) > 0
-	case operatorGte:
-		return strings.Compare(l, r) >= 0
-	case operatorLt:
-		return strings.Compare(l, r) < 0
-	case operatorLte:
-		return strings.Compare(l, r) <= 0
 	}
 
-	return false
+	return op.isSatisfiedBy(strings.Compare(l, r))
 }
 
 func compareBlobs(op operator, l, r []byte) bool {
-	switch op {
-	case operatorEq:
+	if op == operatorEq {
 		return bytes.Equal(l, r)
-	case operatorGt:
-		return bytes.Compare(l, r) > 0
-	case operatorGte:
-		return bytes.Compare(l, r) >= 0
-	case operatorLt:
-		return bytes.Compare(l, r) < 0
-	case operatorLte:
-		return bytes.Compare(l, r) <= 0
 	}
 
-	return false
+	return op.isSatisfiedBy(bytes.Compare(l, r))
 }
 
 func compareIntegers(op operator, l, r int64) bool {
@@ -196,192 +181,235 @@ func compareIntegers(op operator, l, r int64) bool {
 }
 
 func compareNumbers(op operator, l, r Value) (bool, error) {
-	var err error
+	// NaN is neither equal to, lower or greater than any number
+	if isNaN(l) || isNaN(r) {
+		return false, nil
+	}
 
-	l, err = l.CastAsDouble()
+	cmp, err := compareNumberValues(l, r)
 	if err != nil {
 		return false, err
 	}
-	r, err = r.CastAsDouble()
+
+	return op.isSatisfiedBy(cmp), nil
+}
+
+func isNaN(v Value) bool {
+	return v.Type == DoubleValue && math.IsNaN(v.V.(float64))
+}
+
+func compareArrays(op operator, l Array, r Array) (bool, error) {
+	cmp, err := CompareArrays(l, r)
 	if err != nil {
 		return false, err
 	}
 
-	af := l.V.(float64)
-	bf := r.V.(float64)
+	return op.isSatisfiedBy(cmp), nil
+}
+
+func compareDocuments(op operator, l, r Document) (bool, error) {
+	cmp, err := CompareDocuments(l, r)
+	if err != nil {
+		return false, err
+	}
 
-	var ok bool
+	return op.isSatisfiedBy(cmp), nil
+}
 
+// isSatisfiedBy returns whether the result of a three-way comparison
+// satisfies the operator.
+func (op operator) isSatisfiedBy(cmp int) bool {
 	switch op {
 	case operatorEq:
-		ok = af == bf
+		return cmp == 0
 	case operatorGt:
-		ok = af > bf
+		return cmp > 0
 	case operatorGte:
-		ok = af >= bf
+		return cmp >= 0
 	case operatorLt:
-		ok = af < bf
+		return cmp < 0
 	case operatorLte:
-		ok = af <= bf
+		return cmp <= 0
 	}
 
-	return ok, nil
+	return false
 }
 
-func compareArrays(op operator, l Array, r Array) (bool, error) {
-	var i, j int
+// Compare returns an integer comparing two values.
+// The result will be 0 if a == b, -1 if a < b, and +1 if a > b.
+// Integers and doubles are compared by value. Values of other different types
+// are ordered by type: null < bool < numbers < text < blob < array < document.
+func Compare(a, b Value) (int, error) {
+	switch {
+	case a.Type.IsNumber() && b.Type.IsNumber():
+		return compareNumberValues(a, b)
+	case a.Type != b.Type:
+		return compareInts(int64(a.Type), int64(b.Type)), nil
+	}
 
-	for {
-		lv, lerr := l.GetByIndex(i)
-		rv, rerr := r.GetByIndex(j)
-		if lerr == nil {
-			i++
-		}
-		if rerr == nil {
-			j++
-		}
-		if lerr != nil || rerr != nil {
-			break
-		}
-		isEq, err := compare(operatorEq, lv, rv, true)
-		if err != nil {
-			return false, err
-		}
-		if !isEq && op != operatorEq {
-			return compare(op, lv, rv, true)
-		}
-		if !isEq {
-			return false, nil
+	switch a.Type {
+	case NullValue:
+		return 0, nil
+	case BoolValue:
+		x, y := a.V.(bool), b.V.(bool)
+		switch {
+		case x == y:
+			return 0, nil
+		case y:
+			return -1, nil
 		}
+		return 1, nil
+	case TextValue:
+		return strings.Compare(a.V.(string), b.V.(string)), nil
+	case BlobValue:
+		return bytes.Compare(a.V.([]byte), b.V.([]byte)), nil
+	case ArrayValue:
+		return CompareArrays(a.V.(Array), b.V.(Array))
+	case DocumentValue:
+		return CompareDocuments(a.V.(Document), b.V.(Document))
 	}
 
+	return 0, &ErrUnsupportedType{a.V, "unsupported type"}
+}
+
+func compareInts(a, b int64) int {
 	switch {
-	case i > j:
-		switch op {
-		case operatorEq, operatorLt, operatorLte:
-			return false, nil
-		default:
-			return true, nil
-		}
-	case i < j:
-		switch op {
-		case operatorEq, operatorGt, operatorGte:
-			return false, nil
-		default:
-			return true, nil
-		}
-	default:
-		switch op {
-		case operatorEq, operatorGte, operatorLte:
-			return true, nil
-		default:
-			return false, nil
-		}
+	case a < b:
+		return -1
+	case a > b:
+		return 1
 	}
+
+	return 0
 }
 
-func compareDocuments(op operator, l, r Document) (bool, error) {
-	lf, err := Fields(l)
+func compareNumberValues(a, b Value) (int, error) {
+	switch {
+	case a.Type == IntegerValue && b.Type == IntegerValue:
+		return compareInts(a.V.(int64), b.V.(int64)), nil
+	case a.Type == IntegerValue && b.Type == DoubleValue:
+		return compareIntToDouble(a.V.(int64), b.V.(float64)), nil
+	case a.Type == DoubleValue && b.Type == IntegerValue:
+		return -compareIntToDouble(b.V.(int64), a.V.(float64)), nil
+	}
+
+	a, err := a.CastAsDouble()
 	if err != nil {
-		return false, err
+		return 0, err
 	}
-	rf, err := Fields(r)
+	b, err = b.CastAsDouble()
 	if err != nil {
-		return false, err
+		return 0, err
 	}
 
-	if len(lf) == 0 && len(rf) > 0 {
-		switch op {
-		case operatorEq:
-			return false, nil
-		case operatorGt:
-			return false, nil
-		case operatorGte:
-			return false, nil
-		case operatorLt:
-			return true, nil
-		case operatorLte:
-			return true, nil
-		}
+	x, y := a.V.(float64), b.V.(float64)
+	switch {
+	case x < y:
+		return -1, nil
+	case x > y:
+		return 1, nil
 	}
 
-	if len(rf) == 0 && len(lf) > 0 {
-		switch op {
-		case operatorEq:
-			return false, nil
-		case operatorGt:
-			return true, nil
-		case operatorGte:
-			return true, nil
-		case operatorLt:
-			return false, nil
-		case operatorLte:
-			return false, nil
-		}
+	return 0, nil
+}
+
+// compareIntToDouble compares an integer to a double exactly,
+// without converting the integer to a double, which would round it if it is
+// larger than 2^53. NaN is considered equal to every integer, like when comparing doubles.
+func compareIntToDouble(i int64, f float64) int {
+	switch {
+	case math.IsNaN(f):
+		return 0
+	// float64(math.MaxInt64) is 2^63, which is out of range
+	case f >= math.MaxInt64:
+		return -1
+	case f < math.MinInt64:
+		return 1
 	}
 
-	var i, j int
-
-	for i < len(lf) && j < len(rf) {
-		if cmp := strings.Compare(lf[i], rf[j]); cmp != 0 {
-			switch op {
-			case operatorEq:
-				return false, nil
-			case operatorGt:
-				return cmp > 0, nil
-			case operatorGte:
-				return cmp >= 0, nil
-			case operatorLt:
-				return cmp < 0, nil
-			case operatorLte:
-				return cmp <= 0, nil
-			}
-		}
+	t := math.Trunc(f)
+	if c := compareInts(i, int64(t)); c != 0 {
+		return c
+	}
 
-		lv, lerr := l.GetByField(lf[i])
-		rv, rerr := r.GetByField(rf[j])
-		if lerr == nil {
-			i++
-		}
-		if rerr == nil {
-			j++
-		}
-		if lerr != nil || rerr != nil {
-			break
+	switch {
+	case f > t:
+		return -1
+	case f < t:
+		return 1
+	}
+
+	return 0
+}
+
+// CompareArrays compares two arrays element by element, using Compare.
+// If all the elements of the shortest array are equal to the ones
+// of the other array, the shortest array is considered the smallest.
+func CompareArrays(a, b Array) (int, error) {
+	for i := 0; ; i++ {
+		av, aerr := a.GetByIndex(i)
+		if aerr != nil && aerr != ErrValueNotFound && aerr != ErrFieldNotFound {
+			return 0, aerr
 		}
-		isEq, err := compare(operatorEq, lv, rv, true)
-		if err != nil {
-			return false, err
+		bv, berr := b.GetByIndex(i)
+		if berr != nil && berr != ErrValueNotFound && berr != ErrFieldNotFound {
+			return 0, berr
 		}
-		if !isEq && op != operatorEq {
-			return compare(op, lv, rv, true)
+
+		switch {
+		case aerr != nil && berr != nil:
+			return 0, nil
+		case aerr != nil:
+			return -1, nil
+		case berr != nil:
+			return 1, nil
 		}
-		if !isEq {
-			return false, nil
+
+		cmp, err := Compare(av, bv)
+		if err != nil || cmp != 0 {
+			return cmp, err
 		}
 	}
+}
+
+// CompareDocuments compares two documents.
+// Fields are compared in lexicographic order, first by name then by value, using Compare.
+// If all the fields of the smallest document are equal to the ones
+// of the other document, the smallest document is considered the smallest.
+// Encoded documents with the same encoded representation are equal without being decoded.
+func CompareDocuments(a, b Document) (int, error) {
+	if equalEncoding(a, b) {
+		return 0, nil
+	}
 
-	switch {
-	case i > j:
-		switch op {
-		case operatorEq, operatorLt, operatorLte:
-			return false, nil
-		default:
-			return true, nil
+	af, err := Fields(a)
+	if err != nil {
+		return 0, err
+	}
+	bf, err := Fields(b)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := 0; i < len(af) && i < len(bf); i++ {
+		if cmp := strings.Compare(af[i], bf[i]); cmp != 0 {
+			return cmp, nil
 		}
-	case i < j:
-		switch op {
-		case operatorEq, operatorGt, operatorGte:
-			return false, nil
-		default:
-			return true, nil
+
+		av, err := a.GetByField(af[i])
+		if err != nil {
+			return 0, err
 		}
-	default:
-		switch op {
-		case operatorEq, operatorGte, operatorLte:
-			return true, nil
-		default:
-			return false, nil
+		bv, err := b.GetByField(bf[i])
+		if err != nil {
+			return 0, err
+		}
+
+		cmp, err := Compare(av, bv)
+		if err != nil || cmp != 0 {
+			return cmp, err
 		}
 	}
+
+	return compareInts(int64(len(af)), int64(len(bf))), nil
 }
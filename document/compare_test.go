@@ -1,8 +1,12 @@
 package document_test
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"strings"
 	"testing"
 
 	"github.com/genjidb/genji/document"
@@ -249,3 +253,137 @@ func TestCompare(t *testing.T) {
 		})
 	}
 }
+
+func TestCompareFunc(t *testing.T) {
+	tests := []struct {
+		a, b     document.Value
+		expected int
+	}{
+		{document.NewNullValue(), document.NewNullValue(), 0},
+		{document.NewNullValue(), document.NewBoolValue(false), -1},
+		{document.NewBoolValue(true), document.NewBoolValue(false), 1},
+		{document.NewBoolValue(true), document.NewIntegerValue(0), -1},
+		{document.NewIntegerValue(1), document.NewDoubleValue(1), 0},
+		{document.NewIntegerValue(2), document.NewDoubleValue(1.5), 1},
+		{document.NewDoubleValue(-1), document.NewIntegerValue(1), -1},
+		{document.NewIntegerValue(1<<53 + 1), document.NewDoubleValue(1 << 53), 1},
+		{document.NewIntegerValue(math.MaxInt64), document.NewDoubleValue(math.MaxInt64), -1},
+		{document.NewIntegerValue(math.MinInt64), document.NewDoubleValue(math.MinInt64), 0},
+		{document.NewIntegerValue(-2), document.NewDoubleValue(-1.5), -1},
+		{document.NewDoubleValue(10), document.NewTextValue("a"), -1},
+		{document.NewTextValue("b"), document.NewTextValue("a"), 1},
+		{document.NewTextValue("b"), document.NewBlobValue([]byte("a")), -1},
+		{document.NewBlobValue([]byte("a")), document.NewBlobValue([]byte("a")), 0},
+		{jsonToArray(t, `[1, 2]`), jsonToArray(t, `[1, 2]`), 0},
+		{jsonToArray(t, `[1, 2]`), jsonToArray(t, `[1, 2, 3]`), -1},
+		{jsonToArray(t, `[1, 3]`), jsonToArray(t, `[1, 2, 3]`), 1},
+		{jsonToArray(t, `[null]`), jsonToArray(t, `[1]`), -1},
+		{jsonToArray(t, `[]`), jsonToDocument(t, `{}`), -1},
+		{jsonToDocument(t, `{"a": 1}`), jsonToDocument(t, `{"a": 1}`), 0},
+		{jsonToDocument(t, `{}`), jsonToDocument(t, `{"a": 1}`), -1},
+		{jsonToDocument(t, `{"b": 1}`), jsonToDocument(t, `{"a": 1}`), 1},
+		{jsonToDocument(t, `{"a": 1}`), jsonToDocument(t, `{"a": 2}`), -1},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s/%s", test.a, test.b), func(t *testing.T) {
+			cmp, err := document.Compare(test.a, test.b)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, cmp)
+
+			// comparison must be antisymmetric
+			cmp, err = document.Compare(test.b, test.a)
+			require.NoError(t, err)
+			require.Equal(t, -test.expected, cmp)
+		})
+	}
+}
+
+func BenchmarkCompareTexts(b *testing.B) {
+	a := document.NewTextValue(strings.Repeat("a", 100) + "a")
+	c := document.NewTextValue(strings.Repeat("a", 100) + "b")
+
+	b.Run("IsEqual", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			a.IsEqual(c)
+		}
+	})
+
+	b.Run("IsLesserThan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			a.IsLesserThan(c)
+		}
+	})
+
+	b.Run("IsGreaterThanOrEqual", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			a.IsGreaterThanOrEqual(c)
+		}
+	})
+
+	b.Run("Compare", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			document.Compare(a, c)
+		}
+	})
+}
+
+func BenchmarkCompareBlobs(b *testing.B) {
+	a := document.NewBlobValue(bytes.Repeat([]byte{1}, 100))
+	c := document.NewBlobValue(append(bytes.Repeat([]byte{1}, 99), 2))
+
+	b.Run("IsEqual", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			a.IsEqual(c)
+		}
+	})
+
+	b.Run("IsLesserThan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			a.IsLesserThan(c)
+		}
+	})
+}
+
+// encodedDoc is an encoded document that fails when decoded.
+type encodedDoc struct {
+	name string
+	data []byte
+}
+
+func (e encodedDoc) Iterate(fn func(field string, value document.Value) error) error {
+	return errors.New("decoded")
+}
+
+func (e encodedDoc) GetByField(field string) (document.Value, error) {
+	return document.Value{}, errors.New("decoded")
+}
+
+func (e encodedDoc) Encoding() (string, []byte) {
+	return e.name, e.data
+}
+
+func TestCompareEncodedDocuments(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    encodedDoc
+		decoded bool
+	}{
+		{"Same bytes", encodedDoc{"a", []byte("foo")}, encodedDoc{"a", []byte("foo")}, false},
+		{"Different bytes", encodedDoc{"a", []byte("foo")}, encodedDoc{"a", []byte("bar")}, true},
+		{"Different encodings", encodedDoc{"a", []byte("foo")}, encodedDoc{"b", []byte("foo")}, true},
+		{"No encoding", encodedDoc{"", []byte("foo")}, encodedDoc{"", []byte("foo")}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ok, err := document.NewDocumentValue(test.a).IsEqual(document.NewDocumentValue(test.b))
+			if test.decoded {
+				require.EqualError(t, err, "decoded")
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, ok)
+		})
+	}
+}
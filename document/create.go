@@ -12,6 +12,7 @@ import (
 )
 
 // NewFromJSON creates a document from a JSON object.
+// Fields are iterated in the order they appear in the JSON object.
 func NewFromJSON(data []byte) (Document, error) {
 	var fb FieldBuffer
 	err := fb.UnmarshalJSON(data)
@@ -22,12 +23,24 @@ func NewFromJSON(data []byte) (Document, error) {
 }
 
 // NewFromMap creates a document from a map.
+// The keys of the map must be strings or interfaces holding strings,
+// like the map[interface{}]interface{} produced by some decoders.
+// Values are converted using NewValue, which means nested maps, slices and structs
+// are converted recursively when accessed.
 // Due to the way maps are designed, iteration order is not guaranteed.
+// Use Sorted to iterate over the fields in a deterministic order.
 func NewFromMap(m interface{}) (Document, error) {
 	M := reflect.ValueOf(m)
-	if M.Kind() != reflect.Map || M.Type().Key().Kind() != reflect.String {
+	if M.Kind() != reflect.Map {
 		return nil, &ErrUnsupportedType{m, "parameter must be a map with a string key"}
 	}
+
+	switch M.Type().Key().Kind() {
+	case reflect.String, reflect.Interface:
+	default:
+		return nil, &ErrUnsupportedType{m, "parameter must be a map with a string key"}
+	}
+
 	return mapDocument(M), nil
 }
 
@@ -35,17 +48,39 @@ type mapDocument reflect.Value
 
 var _ Document = (*mapDocument)(nil)
 
+// mapKeyToString returns the string representation of a map key.
+// Interface keys must hold a string.
+func mapKeyToString(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.Interface {
+		if k.IsNil() {
+			return "", &ErrUnsupportedType{nil, "map key must be a string"}
+		}
+		k = k.Elem()
+	}
+
+	if k.Kind() != reflect.String {
+		return "", &ErrUnsupportedType{k.Interface(), "map key must be a string"}
+	}
+
+	return k.String(), nil
+}
+
 func (m mapDocument) Iterate(fn func(field string, value Value) error) error {
 	M := reflect.Value(m)
 	it := M.MapRange()
 
 	for it.Next() {
+		k, err := mapKeyToString(it.Key())
+		if err != nil {
+			return err
+		}
+
 		v, err := NewValue(it.Value().Interface())
 		if err != nil {
 			return err
 		}
 
-		err = fn(it.Key().String(), v)
+		err = fn(k, v)
 		if err != nil {
 			return err
 		}
@@ -55,7 +90,29 @@ func (m mapDocument) Iterate(fn func(field string, value Value) error) error {
 
 func (m mapDocument) GetByField(field string) (Value, error) {
 	M := reflect.Value(m)
-	v := M.MapIndex(reflect.ValueOf(field))
+	kt := M.Type().Key()
+
+	var v reflect.Value
+	if kt.Kind() == reflect.String {
+		// convert the field to the key type, in case it is a named string type
+		v = M.MapIndex(reflect.ValueOf(field).Convert(kt))
+	} else {
+		// interface keys may hold a named string type
+		// that wouldn't be matched by MapIndex
+		it := M.MapRange()
+		for it.Next() {
+			k, err := mapKeyToString(it.Key())
+			if err != nil {
+				return Value{}, err
+			}
+
+			if k == field {
+				v = it.Value()
+				break
+			}
+		}
+	}
+
 	if v == (reflect.Value{}) {
 		return Value{}, ErrFieldNotFound
 	}
@@ -68,6 +125,7 @@ func (m mapDocument) MarshalJSON() ([]byte, error) {
 }
 
 // NewFromStruct creates a document from a struct using reflection.
+// Fields are iterated in the order they are declared in the struct.
 func NewFromStruct(s interface{}) (Document, error) {
 	ref := reflect.Indirect(reflect.ValueOf(s))
 
@@ -110,7 +168,7 @@ func (s structDocument) Iterate(fn func(field string, value Value) error) error
 
 		v, err := NewValue(f.Interface())
 		if err != nil {
-			if err.(*ErrUnsupportedType) != nil {
+			if _, ok := err.(*ErrUnsupportedType); ok {
 				continue
 			}
 			return err
@@ -245,7 +303,7 @@ func (s sliceArray) Iterate(fn func(i int, v Value) error) error {
 
 		v, err := NewValue(f.Interface())
 		if err != nil {
-			if err.(*ErrUnsupportedType) != nil {
+			if _, ok := err.(*ErrUnsupportedType); ok {
 				continue
 			}
 			return err
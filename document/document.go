@@ -41,6 +41,37 @@ type Keyer interface {
 	Key() []byte
 }
 
+// An EncodedDocument is a document backed by an encoded representation.
+// Documents with the same encoding and the same encoded bytes are equal,
+// which allows comparing them without decoding them.
+type EncodedDocument interface {
+	Document
+
+	// Encoding returns the name of the encoding and the encoded document.
+	// The name must identify the encoding unambiguously: encodings
+	// that depend on an external state, like a schema, must not be exposed.
+	// An empty name means the document has no encoded representation,
+	// which allows wrappers to implement this interface.
+	Encoding() (name string, data []byte)
+}
+
+// equalEncoding reports whether both documents are encoded and have the same
+// encoded representation. If false, the documents may still be equal.
+func equalEncoding(a, b Document) bool {
+	ea, ok := a.(EncodedDocument)
+	if !ok {
+		return false
+	}
+	eb, ok := b.(EncodedDocument)
+	if !ok {
+		return false
+	}
+
+	na, da := ea.Encoding()
+	nb, db := eb.Encoding()
+	return na != "" && na == nb && bytes.Equal(da, db)
+}
+
 // Length returns the length of a document.
 func Length(d Document) (int, error) {
 	if fb, ok := d.(*FieldBuffer); ok {
@@ -75,7 +106,99 @@ func Fields(d Document) ([]string, error) {
 	return fields, nil
 }
 
+// Sorted returns a view of d whose fields are iterated in lexicographic order.
+// Documents and arrays nested in d are sorted as well.
+// It is useful to produce a deterministic output from documents whose iteration order
+// isn't guaranteed, like the ones created by NewFromMap.
+func Sorted(d Document) Document {
+	if s, ok := d.(sortedDocument); ok {
+		return s
+	}
+
+	return sortedDocument{d}
+}
+
+type sortedDocument struct {
+	Document
+}
+
+func (s sortedDocument) Iterate(fn func(field string, value Value) error) error {
+	var fields []fieldValue
+	err := s.Document.Iterate(func(f string, v Value) error {
+		fields = append(fields, fieldValue{f, v})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.SliceStable(fields, func(i, j int) bool {
+		return fields[i].Field < fields[j].Field
+	})
+
+	for _, fv := range fields {
+		err = fn(fv.Field, sortedValue(fv.Value))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s sortedDocument) GetByField(field string) (Value, error) {
+	v, err := s.Document.GetByField(field)
+	if err != nil {
+		return v, err
+	}
+
+	return sortedValue(v), nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (s sortedDocument) MarshalJSON() ([]byte, error) {
+	return jsonDocument{Document: s}.MarshalJSON()
+}
+
+type sortedArray struct {
+	Array
+}
+
+func (s sortedArray) Iterate(fn func(i int, value Value) error) error {
+	return s.Array.Iterate(func(i int, v Value) error {
+		return fn(i, sortedValue(v))
+	})
+}
+
+func (s sortedArray) GetByIndex(i int) (Value, error) {
+	v, err := s.Array.GetByIndex(i)
+	if err != nil {
+		return v, err
+	}
+
+	return sortedValue(v), nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (s sortedArray) MarshalJSON() ([]byte, error) {
+	return jsonArray{Array: s}.MarshalJSON()
+}
+
+func sortedValue(v Value) Value {
+	switch v.Type {
+	case DocumentValue:
+		return NewDocumentValue(Sorted(v.V.(Document)))
+	case ArrayValue:
+		return NewArrayValue(sortedArray{v.V.(Array)})
+	}
+
+	return v
+}
+
 // FieldBuffer stores a group of fields in memory. It implements the Document interface.
+// Fields are iterated in the order they were added, which is guaranteed to remain stable
+// when fields are replaced or deleted. This makes FieldBuffer suitable for building documents
+// whose JSON output must be deterministic.
 type FieldBuffer struct {
 	fields []fieldValue
 	key    []byte
@@ -109,7 +232,7 @@ type fieldValue struct {
 	Value Value
 }
 
-// Add a field to the buffer.
+// Add a field at the end of the buffer.
 func (fb *FieldBuffer) Add(field string, v Value) *FieldBuffer {
 	fb.fields = append(fb.fields, fieldValue{field, v})
 	return fb
@@ -228,7 +351,7 @@ func (fb *FieldBuffer) Set(path ValuePath, v Value) error {
 	return nil
 }
 
-// Iterate goes through all the fields of the document and calls the given function by passing each one of them.
+// Iterate goes through all the fields of the document in insertion order and calls the given function by passing each one of them.
 // If the given function returns an error, the iteration stops.
 func (fb FieldBuffer) Iterate(fn func(field string, value Value) error) error {
 	for _, fv := range fb.fields {
@@ -254,6 +377,7 @@ func (fb *FieldBuffer) Delete(field string) error {
 }
 
 // Replace the value of the field by v.
+// The field keeps its position in the buffer.
 func (fb *FieldBuffer) Replace(field string, v Value) error {
 	for i := range fb.fields {
 		if fb.fields[i].Field == field {
@@ -325,33 +449,94 @@ func (fb *FieldBuffer) Fields() []string {
 }
 
 // A ValuePath represents the path to a particular value within a document.
+// Its string representation uses dots to separate field names
+// and brackets for array indexes, for example: a.b[3].c.
 type ValuePath []ValuePathFragment
 
 // ValuePathFragment is a fragment of a path representing either a field name or
 // the index of an array.
+// If Wildcard is true, the fragment matches every field of a document
+// if FieldName is "*", or every element of an array otherwise.
 type ValuePathFragment struct {
 	FieldName  string
 	ArrayIndex int
+	Wildcard   bool
+}
+
+// IsArrayIndex returns whether the fragment selects one or more elements of an array.
+func (f ValuePathFragment) IsArrayIndex() bool {
+	return f.FieldName == ""
 }
 
 // String representation of all the fragments of the path.
+// Field names are not quoted, see QuotedString for a representation
+// that can be parsed back.
 // It implements the Stringer interface.
 func (p ValuePath) String() string {
+	return p.format(false)
+}
+
+// QuotedString returns a representation of the path where
+// every field name is quoted with backquotes, which makes it unambiguous
+// even if field names contain dots, spaces or reserved keywords.
+// Example: `a`.`b.c`[3]
+func (p ValuePath) QuotedString() string {
+	return p.format(true)
+}
+
+func (p ValuePath) format(quoted bool) string {
 	var b strings.Builder
 
 	for i := range p {
-		if p[i].FieldName != "" {
+		switch {
+		case p[i].Wildcard && p[i].IsArrayIndex():
+			b.WriteString("[*]")
+		case p[i].IsArrayIndex():
+			b.WriteString("[" + strconv.Itoa(p[i].ArrayIndex) + "]")
+		default:
 			if i != 0 {
 				b.WriteRune('.')
 			}
-			b.WriteString(p[i].FieldName)
-		} else {
-			b.WriteString("[" + strconv.Itoa(p[i].ArrayIndex) + "]")
+			if quoted && !p[i].Wildcard {
+				b.WriteString(quoteIdent(p[i].FieldName))
+			} else {
+				b.WriteString(p[i].FieldName)
+			}
 		}
 	}
 	return b.String()
 }
 
+func quoteIdent(s string) string {
+	var b strings.Builder
+
+	b.WriteByte('`')
+	for _, r := range s {
+		switch r {
+		case '`', '\\':
+			b.WriteRune('\\')
+		case '\n':
+			b.WriteString("\\n")
+			continue
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('`')
+
+	return b.String()
+}
+
+// HasWildcard returns whether one of the fragments of the path is a wildcard.
+func (p ValuePath) HasWildcard() bool {
+	for i := range p {
+		if p[i].Wildcard {
+			return true
+		}
+	}
+
+	return false
+}
+
 // IsEqual returns whether other is equal to p.
 func (p ValuePath) IsEqual(other ValuePath) bool {
 	if len(other) != len(p) {
@@ -368,10 +553,74 @@ func (p ValuePath) IsEqual(other ValuePath) bool {
 }
 
 // GetValue from a document.
+// If the path contains wildcards, all the matching values
+// are returned in an array.
 func (p ValuePath) GetValue(d Document) (Value, error) {
+	if p.HasWildcard() {
+		var vb ValueBuffer
+		err := p.iterateValues(NewDocumentValue(d), func(v Value) error {
+			vb = vb.Append(v)
+			return nil
+		})
+		if err != nil {
+			return Value{}, err
+		}
+
+		return NewArrayValue(&vb), nil
+	}
+
 	return p.getValueFromDocument(d)
 }
 
+// iterateValues calls fn for every value of v matching the path.
+// Paths that don't match are ignored.
+func (p ValuePath) iterateValues(v Value, fn func(v Value) error) error {
+	if len(p) == 0 {
+		return fn(v)
+	}
+
+	frag := p[0]
+
+	switch {
+	case v.Type == DocumentValue && !frag.IsArrayIndex():
+		d := v.V.(Document)
+		if frag.Wildcard {
+			return d.Iterate(func(_ string, v Value) error {
+				return p[1:].iterateValues(v, fn)
+			})
+		}
+
+		v, err := d.GetByField(frag.FieldName)
+		if err == ErrFieldNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		return p[1:].iterateValues(v, fn)
+	case v.Type == ArrayValue && frag.IsArrayIndex():
+		a := v.V.(Array)
+		if frag.Wildcard {
+			return a.Iterate(func(_ int, v Value) error {
+				return p[1:].iterateValues(v, fn)
+			})
+		}
+
+		v, err := a.GetByIndex(frag.ArrayIndex)
+		if err == ErrValueNotFound || err == ErrFieldNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		return p[1:].iterateValues(v, fn)
+	}
+
+	return nil
+}
+
 func (p ValuePath) getValueFromDocument(d Document) (Value, error) {
 	if len(p) == 0 {
 		return Value{}, ErrFieldNotFound
@@ -281,7 +281,95 @@ func TestNewFromMap(t *testing.T) {
 		require.Error(t, err, "Expected document.NewFromMap to return an error if the passed parameter is not a map")
 		_, err = document.NewFromMap(map[int]float64{2: 4.3})
 		require.Error(t, err, "Expected document.NewFromMap to return an error if the passed parameter is not a map with a string key type")
+
+		d, err := document.NewFromMap(map[interface{}]int{1: 1})
+		require.NoError(t, err)
+		_, err = document.MarshalJSON(d)
+		require.Error(t, err)
+	})
+
+	t.Run("Other map types", func(t *testing.T) {
+		type key string
+
+		tests := []struct {
+			name     string
+			m        interface{}
+			expected string
+		}{
+			{"map[string]string", map[string]string{"a": "b"}, `{"a": "b"}`},
+			{"map[string]int", map[string]int{"a": 1}, `{"a": 1}`},
+			{"named key", map[key]float64{"a": 1.5}, `{"a": 1.5}`},
+			{"map[interface{}]interface{}", map[interface{}]interface{}{"a": 1, key("b"): true}, `{"a": 1, "b": true}`},
+			{"nested", map[string]interface{}{
+				"a": map[interface{}]interface{}{"b": []interface{}{1, map[string]string{"c": "d"}}},
+				"e": []map[string]int{{"f": 1}},
+			}, `{"a": {"b": [1, {"c": "d"}]}, "e": [{"f": 1}]}`},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				d, err := document.NewFromMap(test.m)
+				require.NoError(t, err)
+				data, err := document.MarshalJSON(d)
+				require.NoError(t, err)
+				require.JSONEq(t, test.expected, string(data))
+			})
+		}
+
+		d, err := document.NewFromMap(map[key]int{"a": 1})
+		require.NoError(t, err)
+		v, err := d.GetByField("a")
+		require.NoError(t, err)
+		require.Equal(t, document.NewIntegerValue(1), v)
+
+		d, err = document.NewFromMap(map[interface{}]int{key("a"): 1})
+		require.NoError(t, err)
+		v, err = d.GetByField("a")
+		require.NoError(t, err)
+		require.Equal(t, document.NewIntegerValue(1), v)
+		_, err = d.GetByField("b")
+		require.Equal(t, document.ErrFieldNotFound, err)
+	})
+}
+
+func TestFieldBufferOrder(t *testing.T) {
+	fb := document.NewFieldBuffer().
+		Add("c", document.NewIntegerValue(1)).
+		Add("a", document.NewIntegerValue(2)).
+		Add("b", document.NewIntegerValue(3)).
+		Add("d", document.NewIntegerValue(4))
+
+	require.NoError(t, fb.Replace("a", document.NewTextValue("foo")))
+	require.NoError(t, fb.Delete("b"))
+	fb.Add("b", document.NewIntegerValue(5))
+
+	data, err := document.MarshalJSON(fb)
+	require.NoError(t, err)
+	require.Equal(t, `{"c": 1, "a": "foo", "d": 4, "b": 5}`, string(data))
+}
+
+func TestSorted(t *testing.T) {
+	d, err := document.NewFromMap(map[string]interface{}{
+		"c": 1,
+		"a": map[string]int{"z": 1, "y": 2},
+		"b": []interface{}{map[string]int{"x": 1, "w": 2}},
 	})
+	require.NoError(t, err)
+
+	expected := `{"a": {"y": 2, "z": 1}, "b": [{"w": 2, "x": 1}], "c": 1}`
+
+	// the output must be the same regardless of the map iteration order
+	for i := 0; i < 10; i++ {
+		data, err := document.MarshalJSON(document.Sorted(d))
+		require.NoError(t, err)
+		require.Equal(t, expected, string(data))
+	}
+
+	v, err := document.Sorted(d).GetByField("a")
+	require.NoError(t, err)
+	data, err := document.MarshalJSON(v.V.(document.Document))
+	require.NoError(t, err)
+	require.Equal(t, `{"y": 2, "z": 1}`, string(data))
 }
 
 func TestNewFromStruct(t *testing.T) {
@@ -575,6 +663,9 @@ func TestValuePath(t *testing.T) {
 		{"number field", `{"a": {"0": [1, 2, 3]}}`, "a.`0`", `[1, 2, 3]`, false},
 		{"letter index", `{"a": {"b": [1, 2, 3]}}`, `a.b.c`, ``, true},
 		{"unknown path", `{"a": {"b": [1, 2, 3]}}`, `a.e.f`, ``, true},
+		{"array wildcard", `{"a": [{"b": 1}, {"c": 2}, {"b": 3}]}`, `a[*].b`, `[1, 3]`, false},
+		{"field wildcard", `{"a": {"b": [1, 2], "c": [3]}}`, `a.*[0]`, `[1, 3]`, false},
+		{"no match", `{"a": [1, 2]}`, `a[*].b`, `[]`, false},
 	}
 
 	for _, test := range tests {
@@ -598,6 +689,34 @@ func TestValuePath(t *testing.T) {
 	}
 }
 
+func TestValuePathString(t *testing.T) {
+	tests := []struct {
+		path           string
+		expected       string
+		expectedQuoted string
+	}{
+		{`a`, `a`, "`a`"},
+		{`a.b[1].c`, `a.b[1].c`, "`a`.`b`[1].`c`"},
+		{"`a.b`.c", `a.b.c`, "`a.b`.`c`"},
+		{"a.`b\\`c`", "a.b`c", "`a`.`b\\`c`"},
+		{`a[*].*`, `a[*].*`, "`a`[*].*"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			p, err := parser.ParsePath(test.path)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, p.String())
+			require.Equal(t, test.expectedQuoted, p.QuotedString())
+
+			// the quoted representation can always be parsed back
+			pp, err := parser.ParsePath(p.QuotedString())
+			require.NoError(t, err)
+			require.Equal(t, p, pp)
+		})
+	}
+}
+
 func TestJSONDocument(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -19,6 +19,16 @@ type Codec interface {
 	NewDocument([]byte) document.Document
 }
 
+// An InterningCodec is a codec able to share the small values decoded from
+// many documents, using a document.Interner.
+type InterningCodec interface {
+	Codec
+
+	// NewInterningDocument returns a document like NewDocument, whose decoded
+	// field names and values are shared through the given interner when possible.
+	NewInterningDocument(data []byte, in *document.Interner) document.Document
+}
+
 // An Encoder encodes one document to the underlying writer.
 type Encoder interface {
 	EncodeDocument(d document.Document) error
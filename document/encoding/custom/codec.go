@@ -126,7 +126,7 @@ func EncodeValue(v document.Value) ([]byte, error) {
 	case document.IntegerValue:
 		return encodeInt64(v.V.(int64)), nil
 	case document.DoubleValue:
-		key.AppendFloat64(nil, v.V.(float64))
+		return key.AppendFloat64(nil, v.V.(float64)), nil
 	case document.NullValue:
 		return nil, nil
 	}
@@ -153,25 +153,29 @@ func (e EncodedDocument) GetByField(field string) (document.Value, error) {
 // Iterate decodes each fields one by one and passes them to fn until the end of the document
 // or until fn returns an error.
 func (e EncodedDocument) Iterate(fn func(field string, value document.Value) error) error {
-	var format Format
-	err := format.Decode(e)
-	if err != nil {
-		return err
-	}
-
-	for _, fh := range format.Header.FieldHeaders {
-		v, err := DecodeValue(document.ValueType(fh.Type), format.Body[fh.Offset:fh.Offset+fh.Size])
+	return iterateRaw(e, func(field []byte, rv RawValue) error {
+		v, err := rv.Decode()
 		if err != nil {
 			return err
 		}
 
-		err = fn(string(fh.Name), v)
-		if err != nil {
-			return err
-		}
-	}
+		return fn(string(field), v)
+	})
+}
 
-	return nil
+// IterateRaw goes through all the fields of the document and passes their name
+// and their encoded value to fn, until the end of the document or until fn returns an error.
+// Neither the field names nor the values are copied or decoded: both slices point to e
+// and values are only decoded when calling RawValue.Decode.
+// This allows inspecting the fields of a document without allocating.
+func (e EncodedDocument) IterateRaw(fn func(field []byte, value RawValue) error) error {
+	return iterateRaw(e, fn)
+}
+
+// Encoding returns the encoded document.
+// It implements the document.EncodedDocument interface.
+func (e EncodedDocument) Encoding() (string, []byte) {
+	return "custom", e
 }
 
 // MarshalJSON implements the json.Marshaler interface.
@@ -187,26 +191,15 @@ type EncodedArray []byte
 // Iterate goes through all the values of the array and calls the given function by passing each one of them.
 // If the given function returns an error, the iteration stops.
 func (e EncodedArray) Iterate(fn func(i int, value document.Value) error) error {
-	var format Format
-	err := format.Decode(e)
-	if err != nil {
-		return err
-	}
-
-	for _, fh := range format.Header.FieldHeaders {
-		v, err := DecodeValue(document.ValueType(fh.Type), format.Body[fh.Offset:fh.Offset+fh.Size])
+	return iterateRaw(e, func(field []byte, rv RawValue) error {
+		v, err := rv.Decode()
 		if err != nil {
 			return err
 		}
 
-		i, _ := binary.Varint(fh.Name)
-		err = fn(int(i), v)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+		i, _ := binary.Varint(field)
+		return fn(int(i), v)
+	})
 }
 
 // GetByIndex returns a value by index of the array.
@@ -220,9 +213,36 @@ func (e EncodedArray) MarshalJSON() ([]byte, error) {
 }
 
 func decodeValueFromDocument(data []byte, field string) (document.Value, error) {
+	var rv RawValue
+	var found bool
+
+	err := iterateRaw(data, func(f []byte, v RawValue) error {
+		if field == string(f) {
+			rv, found = v, true
+			return errStopIteration
+		}
+
+		return nil
+	})
+	if err != nil && err != errStopIteration {
+		return document.Value{}, err
+	}
+	if !found {
+		return document.Value{}, document.ErrValueNotFound
+	}
+
+	return rv.Decode()
+}
+
+// errStopIteration is used internally to stop iterating over the fields of a document.
+var errStopIteration = errors.New("stop iteration")
+
+// iterateRaw reads the header of an encoded document or array field by field
+// and passes the name and the encoded value of each field to fn.
+func iterateRaw(data []byte, fn func(field []byte, value RawValue) error) error {
 	hsize, n := binary.Uvarint(data)
-	if n <= 0 {
-		return document.Value{}, errors.New("cannot decode data")
+	if n <= 0 || len(data) < n+int(hsize) {
+		return errors.New("cannot decode data")
 	}
 
 	hdata := data[n : n+int(hsize)]
@@ -231,7 +251,7 @@ func decodeValueFromDocument(data []byte, field string) (document.Value, error)
 	// skip number of fields
 	_, n = binary.Uvarint(hdata)
 	if n <= 0 {
-		return document.Value{}, errors.New("cannot decode data")
+		return errors.New("cannot decode data")
 	}
 	hdata = hdata[n:]
 
@@ -239,16 +259,36 @@ func decodeValueFromDocument(data []byte, field string) (document.Value, error)
 	for len(hdata) > 0 {
 		n, err := fh.Decode(hdata)
 		if err != nil {
-			return document.Value{}, err
+			return err
 		}
 		hdata = hdata[n:]
 
-		if field == string(fh.Name) {
-			return DecodeValue(document.ValueType(fh.Type), body[fh.Offset:fh.Offset+fh.Size])
+		if uint64(len(body)) < fh.Offset+fh.Size {
+			return errors.New("cannot decode data")
+		}
+
+		err = fn(fh.Name, RawValue{
+			Type: document.ValueType(fh.Type),
+			Data: body[fh.Offset : fh.Offset+fh.Size],
+		})
+		if err != nil {
+			return err
 		}
 	}
 
-	return document.Value{}, document.ErrValueNotFound
+	return nil
+}
+
+// A RawValue is the encoded representation of a value.
+// It is only decoded when calling Decode.
+type RawValue struct {
+	Type document.ValueType
+	Data []byte
+}
+
+// Decode the raw value.
+func (r RawValue) Decode() (document.Value, error) {
+	return DecodeValue(r.Type, r.Data)
 }
 
 // EncodeArray encodes a into its binary representation.
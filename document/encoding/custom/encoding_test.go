@@ -1,10 +1,14 @@
 package custom
 
 import (
+	"bytes"
+	"fmt"
 	"testing"
 
+	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/document/encoding"
 	"github.com/genjidb/genji/document/encoding/encodingtest"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCodec(t *testing.T) {
@@ -13,8 +17,77 @@ func TestCodec(t *testing.T) {
 	})
 }
 
+func TestEncodedDocumentIterateRaw(t *testing.T) {
+	d := document.NewFieldBuffer().
+		Add("a", document.NewIntegerValue(10)).
+		Add("b", document.NewTextValue("foo")).
+		Add("c", document.NewNullValue())
+
+	data, err := EncodeDocument(d)
+	require.NoError(t, err)
+	ed := EncodedDocument(data)
+
+	var fields []string
+	var values []document.Value
+	err = ed.IterateRaw(func(field []byte, rv RawValue) error {
+		fields = append(fields, string(field))
+		v, err := rv.Decode()
+		if err != nil {
+			return err
+		}
+		values = append(values, v)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b", "c"}, fields)
+	require.Equal(t, []document.Value{
+		document.NewIntegerValue(10),
+		document.NewTextValue("foo"),
+		document.NewNullValue(),
+	}, values)
+
+	t.Run("No allocation", func(t *testing.T) {
+		name := []byte("d")
+		allocs := testing.AllocsPerRun(100, func() {
+			ed.IterateRaw(func(field []byte, rv RawValue) error {
+				if bytes.Equal(field, name) {
+					t.Fatal("unexpected field")
+				}
+				return nil
+			})
+		})
+		require.Zero(t, allocs)
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		err := EncodedDocument(data[:len(data)-2]).IterateRaw(func([]byte, RawValue) error {
+			return nil
+		})
+		require.Error(t, err)
+	})
+}
+
 func BenchmarkCodec(b *testing.B) {
 	encodingtest.BenchmarkCodec(b, func() encoding.Codec {
 		return NewCodec()
 	})
 }
+
+func BenchmarkEncodedDocumentIterateRaw(b *testing.B) {
+	var fb document.FieldBuffer
+
+	for i := int64(0); i < 100; i++ {
+		fb.Add(fmt.Sprintf("name-%d", i), document.NewIntegerValue(i))
+	}
+
+	data, err := EncodeDocument(&fb)
+	require.NoError(b, err)
+	ed := EncodedDocument(data)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ed.IterateRaw(func([]byte, RawValue) error {
+			return nil
+		})
+	}
+}
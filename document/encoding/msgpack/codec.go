@@ -11,16 +11,29 @@ import (
 )
 
 // A Codec is a MessagePack implementation of an encoding.Codec.
-type Codec struct{}
+type Codec struct {
+	canonical bool
+}
 
 // NewCodec creates a MessagePack codec.
 func NewCodec() Codec {
 	return Codec{}
 }
 
+// NewCanonicalCodec creates a MessagePack codec whose encoders produce a canonical encoding:
+// fields are written sorted by name, in nested documents as well, and negative zeros
+// are written as zeros, so that identical documents are encoded with identical bytes.
+// The encoded documents are regular MessagePack documents and can be decoded by any codec
+// created by this package.
+func NewCanonicalCodec() Codec {
+	return Codec{canonical: true}
+}
+
 // NewEncoder implements the encoding.Codec interface.
 func (c Codec) NewEncoder(w io.Writer) encoding.Encoder {
-	return NewEncoder(w)
+	enc := NewEncoder(w)
+	enc.canonical = c.canonical
+	return enc
 }
 
 // NewDocument implements the encoding.Codec interface.
@@ -28,10 +41,17 @@ func (c Codec) NewDocument(data []byte) document.Document {
 	return EncodedDocument(data)
 }
 
+// NewInterningDocument implements the encoding.InterningCodec interface.
+func (c Codec) NewInterningDocument(data []byte, in *document.Interner) document.Document {
+	return interningDocument{EncodedDocument: data, interner: in}
+}
+
 // Encoder encodes Genji documents and values
 // in MessagePack.
 type Encoder struct {
 	enc *msgpack.Encoder
+
+	canonical bool
 }
 
 // NewEncoder creates an Encoder that writes in the given writer.
@@ -64,6 +84,10 @@ func (e *Encoder) EncodeDocument(d document.Document) error {
 		return err
 	}
 
+	if e.canonical {
+		d = document.Sorted(d)
+	}
+
 	return d.Iterate(func(f string, v document.Value) error {
 		if err := e.enc.EncodeString(f); err != nil {
 			return err
@@ -126,7 +150,12 @@ func (e *Encoder) EncodeValue(v document.Value) error {
 	case document.IntegerValue:
 		return e.enc.EncodeInt64(v.V.(int64))
 	case document.DoubleValue:
-		return e.enc.EncodeFloat64(v.V.(float64))
+		f := v.V.(float64)
+		if e.canonical && f == 0 {
+			// -0 and 0 are equal but have a different representation
+			f = 0
+		}
+		return e.enc.EncodeFloat64(f)
 	}
 
 	return e.enc.Encode(v.V)
@@ -141,6 +170,10 @@ func (e *Encoder) Close() {
 // from MessagePack.
 type Decoder struct {
 	dec *msgpack.Decoder
+
+	// if set, used to share the decoded texts.
+	interner *document.Interner
+	buf      []byte
 }
 
 // NewDecoder creates a Decoder that reads from the given reader.
@@ -187,7 +220,7 @@ func (d *Decoder) DecodeValue() (v document.Value, err error) {
 	// decode string
 	if codes.IsString(c) {
 		var s string
-		s, err = d.dec.DecodeString()
+		s, err = d.decodeString()
 		if err != nil {
 			return
 		}
@@ -219,11 +252,12 @@ func (d *Decoder) DecodeValue() (v document.Value, err error) {
 		v.Type = document.BoolValue
 		return
 	case codes.Int8, codes.Int16, codes.Int32, codes.Int64, codes.Uint8, codes.Uint16, codes.Uint32, codes.Uint64:
-		v.V, err = d.dec.DecodeInt64()
+		var x int64
+		x, err = d.dec.DecodeInt64()
 		if err != nil {
 			return
 		}
-		v.Type = document.IntegerValue
+		v = document.NewIntegerValue(x)
 		return
 	case codes.Double:
 		v.V, err = d.dec.DecodeFloat64()
@@ -246,9 +280,52 @@ func (d *Decoder) DecodeDocument() (document.Document, error) {
 		return nil, err
 	}
 
+	if d.interner != nil {
+		return interningDocument{EncodedDocument: EncodedDocument(r), interner: d.interner}, nil
+	}
+
 	return EncodedDocument(r), nil
 }
 
+// decodeString decodes a string, using the interner if any.
+func (d *Decoder) decodeString() (string, error) {
+	if d.interner == nil {
+		return d.dec.DecodeString()
+	}
+
+	c, err := d.dec.PeekCode()
+	if err != nil {
+		return "", err
+	}
+
+	// skip the type code
+	if cap(d.buf) == 0 {
+		d.buf = make([]byte, 32)
+	}
+	err = d.dec.ReadFull(d.buf[:1])
+	if err != nil {
+		return "", err
+	}
+
+	n, err := bytesLen(c, d.dec)
+	if err != nil {
+		return "", err
+	}
+	if n < 0 {
+		return "", nil
+	}
+
+	if cap(d.buf) < n {
+		d.buf = make([]byte, n)
+	}
+	err = d.dec.ReadFull(d.buf[:n])
+	if err != nil {
+		return "", err
+	}
+
+	return d.interner.String(d.buf[:n]), nil
+}
+
 // DecodeArray decodes one array from the reader.
 // If the array is malformed, this function will not return an error.
 // However, calls to Iterate or GetByIndex will fail.
@@ -87,7 +87,12 @@ func bytesLen(c codes.Code, dec *msgpack.Decoder) (int, error) {
 
 // GetByField decodes the selected field from the buffer.
 func (e EncodedDocument) GetByField(field string) (v document.Value, err error) {
+	return getByField(e, field, nil)
+}
+
+func getByField(e []byte, field string, in *document.Interner) (v document.Value, err error) {
 	dec := NewDecoder(bytes.NewReader(e))
+	dec.interner = in
 	defer dec.Close()
 
 	l, err := dec.dec.DecodeMapLen()
@@ -163,7 +168,12 @@ func (e EncodedDocument) GetByField(field string) (v document.Value, err error)
 // Iterate decodes each fields one by one and passes them to fn
 // until the end of the document or until fn returns an error.
 func (e EncodedDocument) Iterate(fn func(field string, value document.Value) error) error {
+	return iterate(e, fn, nil)
+}
+
+func iterate(e []byte, fn func(field string, value document.Value) error, in *document.Interner) error {
 	dec := NewDecoder(bytes.NewReader(e))
+	dec.interner = in
 	defer dec.Close()
 
 	l, err := dec.dec.DecodeMapLen()
@@ -172,7 +182,7 @@ func (e EncodedDocument) Iterate(fn func(field string, value document.Value) err
 	}
 
 	for i := 0; i < l; i++ {
-		f, err := dec.dec.DecodeString()
+		f, err := dec.decodeString()
 		if err != nil {
 			return err
 		}
@@ -191,11 +201,38 @@ func (e EncodedDocument) Iterate(fn func(field string, value document.Value) err
 	return nil
 }
 
+// Encoding returns the encoded document.
+// It implements the document.EncodedDocument interface.
+func (e EncodedDocument) Encoding() (string, []byte) {
+	return "msgpack", e
+}
+
 // MarshalJSON implements the json.Marshaler interface.
 func (e EncodedDocument) MarshalJSON() ([]byte, error) {
 	return document.MarshalJSON(e)
 }
 
+// interningDocument is an EncodedDocument whose decoded field names
+// and texts are shared through an interner.
+type interningDocument struct {
+	EncodedDocument
+
+	interner *document.Interner
+}
+
+func (d interningDocument) GetByField(field string) (document.Value, error) {
+	return getByField(d.EncodedDocument, field, d.interner)
+}
+
+func (d interningDocument) Iterate(fn func(field string, value document.Value) error) error {
+	return iterate(d.EncodedDocument, fn, d.interner)
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (d interningDocument) MarshalJSON() ([]byte, error) {
+	return document.MarshalJSON(d)
+}
+
 // An EncodedArray implements the document.Array interface on top of an
 // encoded representation of an array.
 // It is useful for avoiding decoding the entire array when
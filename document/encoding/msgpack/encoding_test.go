@@ -1,10 +1,14 @@
 package msgpack
 
 import (
+	"bytes"
+	"math"
 	"testing"
 
+	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/document/encoding"
 	"github.com/genjidb/genji/document/encoding/encodingtest"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCodec(t *testing.T) {
@@ -13,8 +17,72 @@ func TestCodec(t *testing.T) {
 	})
 }
 
+func TestCanonicalCodec(t *testing.T) {
+	encode := func(c Codec, d document.Document) []byte {
+		var buf bytes.Buffer
+		err := c.NewEncoder(&buf).EncodeDocument(d)
+		require.NoError(t, err)
+		return buf.Bytes()
+	}
+
+	a := document.NewFieldBuffer().
+		Add("b", document.NewIntegerValue(1)).
+		Add("a", document.NewDocumentValue(document.NewFieldBuffer().
+			Add("d", document.NewDoubleValue(math.Copysign(0, -1))).
+			Add("c", document.NewArrayValue(document.NewValueBuffer(
+				document.NewDocumentValue(document.NewFieldBuffer().
+					Add("f", document.NewNullValue()).
+					Add("e", document.NewTextValue("foo"))),
+			)))))
+
+	b, err := document.NewFromJSON([]byte(`{"a": {"c": [{"e": "foo", "f": null}], "d": 0.0}, "b": 1}`))
+	require.NoError(t, err)
+
+	require.NotEqual(t, encode(NewCodec(), a), encode(NewCodec(), b))
+
+	enc := encode(NewCanonicalCodec(), a)
+	require.Equal(t, enc, encode(NewCanonicalCodec(), b))
+
+	// canonical documents are regular documents
+	data, err := document.MarshalJSON(NewCodec().NewDocument(enc))
+	require.NoError(t, err)
+	require.Equal(t, `{"a": {"c": [{"e": "foo", "f": null}], "d": 0}, "b": 1}`, string(data))
+
+	t.Run("Codec", func(t *testing.T) {
+		encodingtest.TestCodec(t, func() encoding.Codec {
+			return NewCanonicalCodec()
+		})
+	})
+}
+
 func BenchmarkCodec(b *testing.B) {
 	encodingtest.BenchmarkCodec(b, func() encoding.Codec {
 		return NewCodec()
 	})
 }
+
+func TestInterningDocument(t *testing.T) {
+	long := string(bytes.Repeat([]byte("a"), 100))
+	doc := `{"a": "foo", "b": {"c": ["foo", 10, "` + long + `"]}, "d": null}`
+	d, err := document.NewFromJSON([]byte(doc))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	err = NewCodec().NewEncoder(&buf).EncodeDocument(d)
+	require.NoError(t, err)
+
+	in := document.NewInterner()
+	idoc := NewCodec().NewInterningDocument(buf.Bytes(), in)
+
+	ok, err := document.NewDocumentValue(d).IsEqual(document.NewDocumentValue(idoc))
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	v, err := idoc.GetByField("a")
+	require.NoError(t, err)
+	require.Equal(t, document.NewTextValue("foo"), v)
+
+	data, err := document.MarshalJSON(idoc)
+	require.NoError(t, err)
+	require.JSONEq(t, doc, string(data))
+}
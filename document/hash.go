@@ -0,0 +1,134 @@
+package document
+
+import (
+	"encoding/binary"
+	"hash"
+	"math"
+	"sort"
+)
+
+// Tags written before each value by Hash.
+const (
+	hashTagNull byte = iota + 1
+	hashTagFalse
+	hashTagTrue
+	hashTagInteger
+	hashTagDouble
+	hashTagText
+	hashTagBlob
+	hashTagArray
+	hashTagDocument
+)
+
+// Hash writes a representation of v to h and returns the resulting hash.
+// Values that are equal according to Value.IsEqual have the same hash,
+// regardless of the way they are stored: integers and integral doubles are hashed the same way,
+// and the fields of documents are hashed in lexicographic order.
+//
+// The algorithm is stable and can be used to compute persistent fingerprints.
+// Each value is written as a tag byte followed by:
+//   - null, false, true: nothing
+//   - integers, and doubles representing an integer: the integer as 8 bytes, in big endian
+//   - other doubles: the IEEE 754 binary representation as 8 bytes, in big endian
+//   - texts and blobs: the length as an unsigned varint followed by the bytes
+//   - arrays: the number of values as an unsigned varint followed by every value
+//   - documents: the number of fields as an unsigned varint followed by every field name,
+//     written like a text without tag, and its value, sorted by field name
+//
+// The tags are, in order and starting at 1: null, false, true, integer, double,
+// text, blob, array, document.
+func Hash(v Value, h hash.Hash64) (uint64, error) {
+	var buf [binary.MaxVarintLen64]byte
+
+	err := writeHash(h, v, buf[:])
+	if err != nil {
+		return 0, err
+	}
+
+	return h.Sum64(), nil
+}
+
+func writeHash(h hash.Hash64, v Value, buf []byte) error {
+	writeUvarint := func(x uint64) {
+		n := binary.PutUvarint(buf, x)
+		h.Write(buf[:n])
+	}
+
+	write64 := func(tag byte, x uint64) {
+		h.Write([]byte{tag})
+		binary.BigEndian.PutUint64(buf, x)
+		h.Write(buf[:8])
+	}
+
+	switch v.Type {
+	case NullValue:
+		h.Write([]byte{hashTagNull})
+	case BoolValue:
+		if v.V.(bool) {
+			h.Write([]byte{hashTagTrue})
+		} else {
+			h.Write([]byte{hashTagFalse})
+		}
+	case IntegerValue:
+		write64(hashTagInteger, uint64(v.V.(int64)))
+	case DoubleValue:
+		f := v.V.(float64)
+		if f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+			write64(hashTagInteger, uint64(int64(f)))
+		} else {
+			write64(hashTagDouble, math.Float64bits(f))
+		}
+	case TextValue:
+		s := v.V.(string)
+		h.Write([]byte{hashTagText})
+		writeUvarint(uint64(len(s)))
+		h.Write([]byte(s))
+	case BlobValue:
+		b := v.V.([]byte)
+		h.Write([]byte{hashTagBlob})
+		writeUvarint(uint64(len(b)))
+		h.Write(b)
+	case ArrayValue:
+		a := v.V.(Array)
+		n, err := ArrayLength(a)
+		if err != nil {
+			return err
+		}
+
+		h.Write([]byte{hashTagArray})
+		writeUvarint(uint64(n))
+		return a.Iterate(func(i int, v Value) error {
+			return writeHash(h, v, buf)
+		})
+	case DocumentValue:
+		d := v.V.(Document)
+		var fields []fieldValue
+		err := d.Iterate(func(f string, v Value) error {
+			fields = append(fields, fieldValue{f, v})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		sort.SliceStable(fields, func(i, j int) bool {
+			return fields[i].Field < fields[j].Field
+		})
+
+		h.Write([]byte{hashTagDocument})
+		writeUvarint(uint64(len(fields)))
+		for _, fv := range fields {
+			writeUvarint(uint64(len(fv.Field)))
+			h.Write([]byte(fv.Field))
+
+			err = writeHash(h, fv.Value, buf)
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		return &ErrUnsupportedType{v.V, "unsupported type"}
+	}
+
+	return nil
+}
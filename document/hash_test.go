@@ -0,0 +1,79 @@
+package document_test
+
+import (
+	"hash/fnv"
+	"testing"
+
+	"github.com/genjidb/genji/document"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHash(t *testing.T) {
+	hash := func(t *testing.T, v document.Value) uint64 {
+		h, err := document.Hash(v, fnv.New64a())
+		require.NoError(t, err)
+		return h
+	}
+
+	doc := func(t *testing.T, s string) document.Value {
+		d, err := document.NewFromJSON([]byte(s))
+		require.NoError(t, err)
+		return document.NewDocumentValue(d)
+	}
+
+	t.Run("Equal values", func(t *testing.T) {
+		tests := []struct {
+			name string
+			a, b document.Value
+		}{
+			{"integer and double", document.NewIntegerValue(10), document.NewDoubleValue(10)},
+			{"zeros", document.NewDoubleValue(0), document.NewDoubleValue(-1 * 0.0)},
+			{"arrays", document.NewArrayValue(document.NewValueBuffer(document.NewIntegerValue(1))), document.NewArrayValue(document.NewValueBuffer(document.NewDoubleValue(1)))},
+			{"documents", doc(t, `{"a": 1, "b": {"c": [1, "foo"], "d": null}}`), doc(t, `{"b": {"d": null, "c": [1.0, "foo"]}, "a": 1.0}`)},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				ok, err := test.a.IsEqual(test.b)
+				require.NoError(t, err)
+				require.True(t, ok)
+				require.Equal(t, hash(t, test.a), hash(t, test.b))
+			})
+		}
+	})
+
+	t.Run("Different values", func(t *testing.T) {
+		values := []document.Value{
+			document.NewNullValue(),
+			document.NewBoolValue(false),
+			document.NewBoolValue(true),
+			document.NewIntegerValue(0),
+			document.NewIntegerValue(1),
+			document.NewDoubleValue(1.5),
+			document.NewTextValue(""),
+			document.NewTextValue("a"),
+			document.NewBlobValue([]byte("a")),
+			document.NewArrayValue(document.NewValueBuffer()),
+			document.NewArrayValue(document.NewValueBuffer(document.NewTextValue("a"))),
+			document.NewArrayValue(document.NewValueBuffer(document.NewTextValue("a"), document.NewTextValue(""))),
+			document.NewArrayValue(document.NewValueBuffer(document.NewTextValue(""), document.NewTextValue("a"))),
+			doc(t, `{}`),
+			doc(t, `{"a": "b"}`),
+			doc(t, `{"ab": ""}`),
+		}
+
+		seen := make(map[uint64]int)
+		for i, v := range values {
+			h := hash(t, v)
+			j, ok := seen[h]
+			require.False(t, ok, "%v and %v have the same hash", v, values[j])
+			seen[h] = i
+		}
+	})
+
+	t.Run("Stable", func(t *testing.T) {
+		// the algorithm must not change, hashes may be persisted
+		require.Equal(t, uint64(0xaf63bc4c8601b62c), hash(t, document.NewNullValue()))
+		require.Equal(t, uint64(0x34e871b2e18461fd), hash(t, doc(t, `{"a": 1, "b": [true, "foo", 1.5]}`)))
+	})
+}
@@ -0,0 +1,73 @@
+package document
+
+const (
+	// maxInternedTextLen is the length above which texts are not interned.
+	maxInternedTextLen = 64
+	// maxInternedTexts bounds the memory used by an interner.
+	maxInternedTexts = 4096
+
+	// integers in [minSmallInteger, maxSmallInteger) are preallocated.
+	minSmallInteger = -128
+	maxSmallInteger = 1024
+)
+
+// smallIntegers contains the boxed representation of the small integers,
+// to avoid allocating when converting them to a Value.
+var smallIntegers = func() []interface{} {
+	ints := make([]interface{}, maxSmallInteger-minSmallInteger)
+	for i := range ints {
+		ints[i] = int64(i + minSmallInteger)
+	}
+	return ints
+}()
+
+// An Interner deduplicates the small values decoded during a scan, so that
+// identical values share the same memory instead of being allocated for every document.
+// This reduces the pressure on the garbage collector when millions of documents
+// with the same field names and a few distinct values are read, like during aggregations.
+// Only short texts are interned and their number is bounded, after which texts are allocated as usual.
+// An Interner is not safe for concurrent use. A nil Interner interns nothing.
+type Interner struct {
+	texts map[string]string
+}
+
+// NewInterner creates an Interner.
+func NewInterner() *Interner {
+	return &Interner{
+		texts: make(map[string]string),
+	}
+}
+
+// String returns a string equal to b, shared with the previous calls if possible.
+// b can be reused by the caller once the function returns.
+func (in *Interner) String(b []byte) string {
+	if in == nil || len(b) > maxInternedTextLen {
+		return string(b)
+	}
+
+	// the conversion doesn't allocate when used as a map key
+	if s, ok := in.texts[string(b)]; ok {
+		return s
+	}
+
+	s := string(b)
+	if len(in.texts) < maxInternedTexts {
+		in.texts[s] = s
+	}
+
+	return s
+}
+
+// NewTextValue returns a text value equal to b, shared with the previous calls if possible.
+func (in *Interner) NewTextValue(b []byte) Value {
+	return NewTextValue(in.String(b))
+}
+
+// newIntegerInterface returns x as an interface, without allocating for small integers.
+func newIntegerInterface(x int64) interface{} {
+	if x >= minSmallInteger && x < maxSmallInteger {
+		return smallIntegers[x-minSmallInteger]
+	}
+
+	return x
+}
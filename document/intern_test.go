@@ -0,0 +1,53 @@
+package document_test
+
+import (
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/genjidb/genji/document"
+	"github.com/stretchr/testify/require"
+)
+
+func stringData(s string) uintptr {
+	return *(*uintptr)(unsafe.Pointer(&s))
+}
+
+func TestInterner(t *testing.T) {
+	t.Run("Shared", func(t *testing.T) {
+		in := document.NewInterner()
+
+		a := in.String([]byte("foo"))
+		b := in.String([]byte("foo"))
+		require.Equal(t, "foo", a)
+		require.Equal(t, stringData(a), stringData(b))
+
+		require.Equal(t, "bar", in.String([]byte("bar")))
+	})
+
+	t.Run("Long texts", func(t *testing.T) {
+		in := document.NewInterner()
+
+		long := []byte(strings.Repeat("a", 100))
+		a := in.String(long)
+		b := in.String(long)
+		require.Equal(t, string(long), a)
+		require.NotEqual(t, stringData(a), stringData(b))
+	})
+
+	t.Run("Nil", func(t *testing.T) {
+		var in *document.Interner
+
+		require.Equal(t, "foo", in.String([]byte("foo")))
+		require.Equal(t, document.NewTextValue("foo"), in.NewTextValue([]byte("foo")))
+	})
+
+	t.Run("Small integers", func(t *testing.T) {
+		var v document.Value
+		allocs := testing.AllocsPerRun(100, func() {
+			v = document.NewIntegerValue(42)
+		})
+		require.Zero(t, allocs)
+		require.Equal(t, int64(42), v.V)
+	})
+}
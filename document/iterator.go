@@ -2,6 +2,7 @@ package document
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"io"
 )
@@ -36,6 +37,30 @@ func (rr documentsIterator) Iterate(fn func(d Document) error) error {
 	return nil
 }
 
+// NewChannelIterator creates an iterator that returns the documents received from ch,
+// until ch is closed or ctx is canceled. Since documents are read one at a time,
+// the sender is blocked until the previous document has been processed.
+// If ctx is canceled, Iterate returns the error of the context.
+func NewChannelIterator(ctx context.Context, ch <-chan Document) Iterator {
+	return IteratorFunc(func(fn func(d Document) error) error {
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case d, ok := <-ch:
+				if !ok {
+					return nil
+				}
+
+				err := fn(d)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	})
+}
+
 // The IteratorFunc type is an adapter to allow the use of ordinary functions as Iterators.
 // If f is a function with the appropriate signature, HandlerFunc(f) is a Handler that calls f.
 type IteratorFunc func(func(d Document) error) error
@@ -216,6 +241,35 @@ func (s Stream) Offset(n int) Stream {
 	})
 }
 
+// Tee passes every document of the stream to each consumer, in order, before passing it
+// to the next stream. It lets multiple consumers share a single iteration of the stream,
+// for example to write documents to a table while returning them.
+// Consumers must copy the documents they keep after returning.
+// If a consumer returns ErrStreamClosed, it stops receiving documents while the other
+// consumers and the stream continue. Any other error interrupts the stream.
+func (s Stream) Tee(consumers ...func(d Document) error) Stream {
+	return s.Pipe(func() func(d Document) (Document, error) {
+		active := make([]func(d Document) error, len(consumers))
+		copy(active, consumers)
+
+		return func(d Document) (Document, error) {
+			for i := 0; i < len(active); {
+				err := active[i](d)
+				if err == ErrStreamClosed {
+					active = append(active[:i], active[i+1:]...)
+					continue
+				}
+				if err != nil {
+					return nil, err
+				}
+				i++
+			}
+
+			return d, nil
+		}
+	})
+}
+
 // Append adds the given iterator to the stream.
 func (s Stream) Append(it Iterator) Stream {
 	if mr, ok := s.it.(multiIterator); ok {
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"testing"
@@ -170,3 +171,90 @@ func TestIteratorToJSONArray(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, `[{"a": 0}, {"a": 1}, {"a": 2}]`, buf.String())
 }
+
+func TestChannelIterator(t *testing.T) {
+	ch := make(chan document.Document)
+	go func() {
+		for i := 0; i < 3; i++ {
+			ch <- document.NewFieldBuffer().Add("a", document.NewIntegerValue(int64(i)))
+		}
+		close(ch)
+	}()
+
+	var buf bytes.Buffer
+	err := document.IteratorToJSONArray(&buf, document.NewChannelIterator(context.Background(), ch))
+	require.NoError(t, err)
+	require.Equal(t, `[{"a": 0}, {"a": 1}, {"a": 2}]`, buf.String())
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := document.NewChannelIterator(ctx, make(chan document.Document)).Iterate(func(d document.Document) error {
+			return nil
+		})
+		require.Equal(t, context.Canceled, err)
+	})
+}
+
+func TestStreamTee(t *testing.T) {
+	var docs []document.Document
+	for i := 0; i < 4; i++ {
+		docs = append(docs, document.NewFieldBuffer().Add("a", document.NewIntegerValue(int64(i))))
+	}
+
+	collect := func(values *[]int64) func(d document.Document) error {
+		return func(d document.Document) error {
+			v, err := d.GetByField("a")
+			if err != nil {
+				return err
+			}
+			*values = append(*values, v.V.(int64))
+			return nil
+		}
+	}
+
+	t.Run("All consumers receive every document", func(t *testing.T) {
+		var a, b, out []int64
+		st := document.NewStream(document.NewIterator(docs...)).Tee(collect(&a), collect(&b))
+
+		err := st.Iterate(collect(&out))
+		require.NoError(t, err)
+		require.Equal(t, []int64{0, 1, 2, 3}, a)
+		require.Equal(t, a, b)
+		require.Equal(t, a, out)
+	})
+
+	t.Run("Closed consumer", func(t *testing.T) {
+		var a, out []int64
+		first := func(d document.Document) error {
+			if len(a) == 2 {
+				return document.ErrStreamClosed
+			}
+			return collect(&a)(d)
+		}
+		st := document.NewStream(document.NewIterator(docs...)).Tee(first)
+
+		err := st.Iterate(collect(&out))
+		require.NoError(t, err)
+		require.Equal(t, []int64{0, 1}, a)
+		require.Equal(t, []int64{0, 1, 2, 3}, out)
+
+		// consumers are enabled again when the stream is iterated again
+		a, out = nil, nil
+		err = st.Iterate(collect(&out))
+		require.NoError(t, err)
+		require.Equal(t, []int64{0, 1}, a)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		var out []int64
+		st := document.NewStream(document.NewIterator(docs...)).Tee(func(d document.Document) error {
+			return errors.New("boom")
+		})
+
+		err := st.Iterate(collect(&out))
+		require.EqualError(t, err, "boom")
+		require.Empty(t, out)
+	})
+}
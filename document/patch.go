@@ -0,0 +1,125 @@
+package document
+
+// MergePatch applies patch to dst following the semantics of RFC 7386
+// and returns the resulting document. dst is not modified.
+// Fields of patch whose value is null are removed from the result,
+// fields whose value is a document are merged recursively
+// and any other value replaces the value found in dst, if any.
+func MergePatch(dst, patch Document) (*FieldBuffer, error) {
+	var fb FieldBuffer
+
+	if dst != nil {
+		err := fb.Copy(dst)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := patch.Iterate(func(field string, v Value) error {
+		cur, err := fb.GetByField(field)
+		if err != nil && err != ErrFieldNotFound {
+			return err
+		}
+		exists := err == nil
+
+		switch v.Type {
+		case NullValue:
+			if exists {
+				return fb.Delete(field)
+			}
+			return nil
+		case DocumentValue:
+			var target Document
+			if exists && cur.Type == DocumentValue {
+				target = cur.V.(Document)
+			}
+
+			d, err := MergePatch(target, v.V.(Document))
+			if err != nil {
+				return err
+			}
+			v = NewDocumentValue(d)
+		}
+
+		if exists {
+			return fb.Replace(field, v)
+		}
+
+		fb.Add(field, v)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &fb, nil
+}
+
+// Diff returns a merge patch that transforms a into b when
+// applied to a using MergePatch.
+// As with RFC 7386, fields of b whose value is null cannot be represented
+// in the patch and are treated as removed.
+func Diff(a, b Document) (*FieldBuffer, error) {
+	var patch FieldBuffer
+
+	// fields removed from a
+	err := a.Iterate(func(field string, _ Value) error {
+		_, err := b.GetByField(field)
+		if err == ErrFieldNotFound {
+			patch.Add(field, NewNullValue())
+			return nil
+		}
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// fields added or modified in b
+	err = b.Iterate(func(field string, v Value) error {
+		old, err := a.GetByField(field)
+		if err == ErrFieldNotFound {
+			if v.Type != NullValue {
+				patch.Add(field, v)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if v.Type == NullValue {
+			if old.Type != NullValue {
+				patch.Add(field, v)
+			}
+			return nil
+		}
+
+		if old.Type == DocumentValue && v.Type == DocumentValue {
+			d, err := Diff(old.V.(Document), v.V.(Document))
+			if err != nil {
+				return err
+			}
+			if d.Len() > 0 {
+				patch.Add(field, NewDocumentValue(d))
+			}
+			return nil
+		}
+
+		if old.Type == v.Type {
+			ok, err := old.IsEqual(v)
+			if err != nil || ok {
+				return err
+			}
+		}
+
+		patch.Add(field, v)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &patch, nil
+}
@@ -0,0 +1,72 @@
+package document_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/genjidb/genji/document"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name, dst, patch, expected string
+	}{
+		{"empty patch", `{"a": 1}`, `{}`, `{"a": 1}`},
+		{"replace", `{"a": 1}`, `{"a": "b"}`, `{"a": "b"}`},
+		{"add", `{"a": 1}`, `{"b": 2}`, `{"a": 1, "b": 2}`},
+		{"remove", `{"a": 1, "b": 2}`, `{"a": null}`, `{"b": 2}`},
+		{"remove missing", `{"a": 1}`, `{"b": null}`, `{"a": 1}`},
+		{"replace array", `{"a": [1, 2]}`, `{"a": [3]}`, `{"a": [3]}`},
+		{"nested", `{"a": {"b": 1, "c": 2}}`, `{"a": {"b": null, "d": 3}}`, `{"a": {"c": 2, "d": 3}}`},
+		{"nested on non document", `{"a": 1}`, `{"a": {"b": 1, "c": null}}`, `{"a": {"b": 1}}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var dst, patch document.FieldBuffer
+			require.NoError(t, json.Unmarshal([]byte(test.dst), &dst))
+			require.NoError(t, json.Unmarshal([]byte(test.patch), &patch))
+
+			res, err := document.MergePatch(&dst, &patch)
+			require.NoError(t, err)
+			data, err := document.MarshalJSON(res)
+			require.NoError(t, err)
+			require.JSONEq(t, test.expected, string(data))
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name, a, b, expected string
+	}{
+		{"same", `{"a": 1, "b": {"c": [1]}}`, `{"a": 1, "b": {"c": [1]}}`, `{}`},
+		{"modified", `{"a": 1}`, `{"a": 2}`, `{"a": 2}`},
+		{"type changed", `{"a": 1}`, `{"a": 1.0}`, `{"a": 1.0}`},
+		{"added", `{"a": 1}`, `{"a": 1, "b": 2}`, `{"b": 2}`},
+		{"removed", `{"a": 1, "b": 2}`, `{"b": 2}`, `{"a": null}`},
+		{"nested", `{"a": {"b": 1, "c": 2}}`, `{"a": {"c": 3}}`, `{"a": {"b": null, "c": 3}}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var a, b document.FieldBuffer
+			require.NoError(t, json.Unmarshal([]byte(test.a), &a))
+			require.NoError(t, json.Unmarshal([]byte(test.b), &b))
+
+			patch, err := document.Diff(&a, &b)
+			require.NoError(t, err)
+			data, err := document.MarshalJSON(patch)
+			require.NoError(t, err)
+			require.JSONEq(t, test.expected, string(data))
+
+			// applying the patch to a must return b
+			res, err := document.MergePatch(&a, patch)
+			require.NoError(t, err)
+			data, err = document.MarshalJSON(res)
+			require.NoError(t, err)
+			require.JSONEq(t, test.b, string(data))
+		})
+	}
+}
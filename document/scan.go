@@ -291,24 +291,12 @@ func scanValue(v Value, ref reflect.Value) error {
 		ref.SetFloat(v.V.(float64))
 		return nil
 	case reflect.Interface:
-		switch v.Type {
-		case DocumentValue:
-			m := make(map[string]interface{})
-			vm := reflect.ValueOf(m)
-			ref.Set(vm)
-			return mapScan(v.V.(Document), vm)
-		case ArrayValue:
-			var s []interface{}
-			vs := reflect.ValueOf(&s)
-			err := sliceScan(v.V.(Array), vs)
-			if err != nil {
-				return err
-			}
-			ref.Set(vs.Elem())
-			return nil
+		x, err := Decoder{}.DecodeValue(v)
+		if err != nil {
+			return err
 		}
 
-		ref.Set(reflect.ValueOf(v.V))
+		ref.Set(reflect.ValueOf(x))
 		return nil
 	}
 
@@ -368,3 +356,82 @@ func scanValue(v Value, ref reflect.Value) error {
 func (v Value) Scan(t interface{}) error {
 	return scanValue(v, reflect.ValueOf(t))
 }
+
+// A Decoder converts documents, arrays and values into plain Go values,
+// which is useful for passing them to code that doesn't know about Genji types,
+// like templates or JSON encoders.
+// Documents are converted to map[string]interface{} and arrays to []interface{}, recursively.
+// Other values are converted to nil, bool, int64, float64, string or []byte.
+// Blobs are copied, so the returned values remain valid after the transaction is closed.
+type Decoder struct {
+	// If true, texts representing a timestamp in the RFC3339 format,
+	// as created by NewValue from a time.Time, are converted to time.Time.
+	ParseTime bool
+}
+
+// DecodeDocument converts d into a map.
+func (dec Decoder) DecodeDocument(d Document) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+
+	err := d.Iterate(func(f string, v Value) error {
+		x, err := dec.DecodeValue(v)
+		if err != nil {
+			return err
+		}
+
+		m[f] = x
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// DecodeArray converts a into a slice.
+func (dec Decoder) DecodeArray(a Array) ([]interface{}, error) {
+	s := []interface{}{}
+
+	err := a.Iterate(func(i int, v Value) error {
+		x, err := dec.DecodeValue(v)
+		if err != nil {
+			return err
+		}
+
+		s = append(s, x)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// DecodeValue converts v into a plain Go value.
+func (dec Decoder) DecodeValue(v Value) (interface{}, error) {
+	switch v.Type {
+	case NullValue:
+		return nil, nil
+	case BoolValue, IntegerValue, DoubleValue:
+		return v.V, nil
+	case TextValue:
+		if dec.ParseTime {
+			if t, err := time.Parse(time.RFC3339Nano, v.V.(string)); err == nil {
+				return t, nil
+			}
+		}
+		return v.V, nil
+	case BlobValue:
+		b := make([]byte, len(v.V.([]byte)))
+		copy(b, v.V.([]byte))
+		return b, nil
+	case DocumentValue:
+		return dec.DecodeDocument(v.V.(Document))
+	case ArrayValue:
+		return dec.DecodeArray(v.V.(Array))
+	}
+
+	return nil, &ErrUnsupportedType{v.V, "unsupported type"}
+}
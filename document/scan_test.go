@@ -174,3 +174,66 @@ type documentScanner struct {
 func (ds documentScanner) ScanDocument(d document.Document) error {
 	return ds.fn(d)
 }
+
+func TestDecoder(t *testing.T) {
+	now := time.Now().UTC()
+	blob := []byte("bar")
+
+	d := document.NewFieldBuffer().
+		Add("a", document.NewNullValue()).
+		Add("b", document.NewBoolValue(true)).
+		Add("c", document.NewIntegerValue(10)).
+		Add("d", document.NewDoubleValue(1.5)).
+		Add("e", document.NewTextValue("foo")).
+		Add("f", document.NewBlobValue(blob)).
+		Add("g", document.NewTextValue(now.Format(time.RFC3339Nano))).
+		Add("h", document.NewArrayValue(document.NewValueBuffer().
+			Append(document.NewIntegerValue(1)).
+			Append(document.NewDocumentValue(document.NewFieldBuffer().
+				Add("i", document.NewTextValue("baz")))))).
+		Add("j", document.NewArrayValue(document.NewValueBuffer()))
+
+	expected := map[string]interface{}{
+		"a": nil,
+		"b": true,
+		"c": int64(10),
+		"d": 1.5,
+		"e": "foo",
+		"f": []byte("bar"),
+		"g": now.Format(time.RFC3339Nano),
+		"h": []interface{}{int64(1), map[string]interface{}{"i": "baz"}},
+		"j": []interface{}{},
+	}
+
+	m, err := document.Decoder{}.DecodeDocument(d)
+	require.NoError(t, err)
+	require.Equal(t, expected, m)
+
+	// blobs must be copied
+	blob[0] = 'c'
+	require.Equal(t, []byte("bar"), m["f"])
+
+	t.Run("ParseTime", func(t *testing.T) {
+		m, err := document.Decoder{ParseTime: true}.DecodeDocument(d)
+		require.NoError(t, err)
+		require.True(t, now.Equal(m["g"].(time.Time)))
+		require.Equal(t, "foo", m["e"])
+	})
+
+	t.Run("Scan into interface", func(t *testing.T) {
+		var x interface{}
+		err := document.NewDocumentValue(d).Scan(&x)
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{
+			"a": nil,
+			"b": true,
+			"c": int64(10),
+			"d": 1.5,
+			"e": "foo",
+			"f": []byte("car"),
+			"g": now.Format(time.RFC3339Nano),
+			"h": []interface{}{int64(1), map[string]interface{}{"i": "baz"}},
+			"j": []interface{}{},
+		}, x)
+	})
+}
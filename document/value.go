@@ -111,11 +111,11 @@ func NewBoolValue(x bool) Value {
 }
 
 // NewIntegerValue encodes x and returns a value whose type depends on the
-// magnitude of x.
+// magnitude of x. Small integers are preallocated and don't cause any allocation.
 func NewIntegerValue(x int64) Value {
 	return Value{
 		Type: IntegerValue,
-		V:    int64(x),
+		V:    newIntegerInterface(x),
 	}
 }
 
@@ -295,57 +295,108 @@ func (v Value) String() string {
 // Add u to v and return the result.
 // Only numeric values and booleans can be added together.
 func (v Value) Add(u Value) (res Value, err error) {
-	return calculateValues(v, u, '+')
+	return calculateValues(v, u, '+', OverflowPromote)
 }
 
 // Sub calculates v - u and returns the result.
 // Only numeric values and booleans can be calculated together.
 func (v Value) Sub(u Value) (res Value, err error) {
-	return calculateValues(v, u, '-')
+	return calculateValues(v, u, '-', OverflowPromote)
 }
 
 // Mul calculates v * u and returns the result.
 // Only numeric values and booleans can be calculated together.
 func (v Value) Mul(u Value) (res Value, err error) {
-	return calculateValues(v, u, '*')
+	return calculateValues(v, u, '*', OverflowPromote)
 }
 
 // Div calculates v / u and returns the result.
 // Only numeric values and booleans can be calculated together.
 // If both v and u are integers, the result will be an integer.
 func (v Value) Div(u Value) (res Value, err error) {
-	return calculateValues(v, u, '/')
+	return calculateValues(v, u, '/', OverflowPromote)
 }
 
 // Mod calculates v / u and returns the result.
 // Only numeric values and booleans can be calculated together.
 // If both v and u are integers, the result will be an integer.
 func (v Value) Mod(u Value) (res Value, err error) {
-	return calculateValues(v, u, '%')
+	return calculateValues(v, u, '%', OverflowPromote)
 }
 
 // BitwiseAnd calculates v & u and returns the result.
 // Only numeric values and booleans can be calculated together.
 // If both v and u are integers, the result will be an integer.
 func (v Value) BitwiseAnd(u Value) (res Value, err error) {
-	return calculateValues(v, u, '&')
+	return calculateValues(v, u, '&', OverflowPromote)
 }
 
 // BitwiseOr calculates v | u and returns the result.
 // Only numeric values and booleans can be calculated together.
 // If both v and u are integers, the result will be an integer.
 func (v Value) BitwiseOr(u Value) (res Value, err error) {
-	return calculateValues(v, u, '|')
+	return calculateValues(v, u, '|', OverflowPromote)
 }
 
 // BitwiseXor calculates v ^ u and returns the result.
 // Only numeric values and booleans can be calculated together.
 // If both v and u are integers, the result will be an integer.
 func (v Value) BitwiseXor(u Value) (res Value, err error) {
-	return calculateValues(v, u, '^')
+	return calculateValues(v, u, '^', OverflowPromote)
 }
 
-func calculateValues(a, b Value, operator byte) (res Value, err error) {
+// ErrIntegerOverflow is returned by arithmetic operations on integers
+// whose result overflows, when using OverflowError.
+var ErrIntegerOverflow = errors.New("integer overflow")
+
+// OverflowMode determines the behaviour of arithmetic operations
+// on integers when the result doesn't fit in an int64.
+type OverflowMode uint8
+
+const (
+	// OverflowPromote converts the result to a double.
+	// It is used by the arithmetic methods of Value.
+	OverflowPromote OverflowMode = iota
+	// OverflowError makes the operation fail with ErrIntegerOverflow.
+	OverflowError
+)
+
+// Add calculates v + u like Value.Add, handling integer overflows according to m.
+func (m OverflowMode) Add(v, u Value) (Value, error) {
+	return calculateValues(v, u, '+', m)
+}
+
+// Sub calculates v - u like Value.Sub, handling integer overflows according to m.
+func (m OverflowMode) Sub(v, u Value) (Value, error) {
+	return calculateValues(v, u, '-', m)
+}
+
+// Mul calculates v * u like Value.Mul, handling integer overflows according to m.
+func (m OverflowMode) Mul(v, u Value) (Value, error) {
+	return calculateValues(v, u, '*', m)
+}
+
+// Div calculates v / u like Value.Div, handling integer overflows according to m.
+func (m OverflowMode) Div(v, u Value) (Value, error) {
+	return calculateValues(v, u, '/', m)
+}
+
+// Mod calculates v % u like Value.Mod, handling integer overflows according to m.
+func (m OverflowMode) Mod(v, u Value) (Value, error) {
+	return calculateValues(v, u, '%', m)
+}
+
+// overflow returns the result of an operation that overflowed,
+// according to mode.
+func overflow(f float64, mode OverflowMode) (Value, error) {
+	if mode == OverflowError {
+		return Value{}, ErrIntegerOverflow
+	}
+
+	return NewDoubleValue(f), nil
+}
+
+func calculateValues(a, b Value, operator byte, mode OverflowMode) (res Value, err error) {
 	if a.Type == NullValue || b.Type == NullValue {
 		return NewNullValue(), nil
 	}
@@ -360,7 +411,7 @@ func calculateValues(a, b Value, operator byte) (res Value, err error) {
 		}
 
 		if a.Type == IntegerValue || b.Type == IntegerValue {
-			return calculateIntegers(a, b, operator)
+			return calculateIntegers(a, b, operator, mode)
 		}
 	}
 
@@ -387,7 +438,7 @@ func convertNumberToInt64(v Value) (int64, error) {
 	return i, nil
 }
 
-func calculateIntegers(a, b Value, operator byte) (res Value, err error) {
+func calculateIntegers(a, b Value, operator byte, mode OverflowMode) (res Value, err error) {
 	var xa, xb int64
 
 	ia, err := a.CastAsInteger()
@@ -405,15 +456,18 @@ func calculateIntegers(a, b Value, operator byte) (res Value, err error) {
 	var xr int64
 
 	switch operator {
-	case '-':
-		xb = -xb
-		fallthrough
 	case '+':
 		xr = xa + xb
 		// if there is an integer overflow
-		// convert to float
+		// the sign of the result is wrong
 		if (xr > xa) != (xb > 0) {
-			return NewDoubleValue(float64(xa) + float64(xb)), nil
+			return overflow(float64(xa)+float64(xb), mode)
+		}
+		return NewIntegerValue(xr), nil
+	case '-':
+		xr = xa - xb
+		if (xr < xa) != (xb > 0) {
+			return overflow(float64(xa)-float64(xb), mode)
 		}
 		return NewIntegerValue(xr), nil
 	case '*':
@@ -423,25 +477,34 @@ func calculateIntegers(a, b Value, operator byte) (res Value, err error) {
 
 		xr = xa * xb
 		// if there is no integer overflow
-		// return an int, otherwise
-		// convert to float
+		// return an int
 		if (xr < 0) == ((xa < 0) != (xb < 0)) {
 			if xr/xb == xa {
 				return NewIntegerValue(xr), nil
 			}
 		}
-		return NewDoubleValue(float64(xa) * float64(xb)), nil
+		return overflow(float64(xa)*float64(xb), mode)
 	case '/':
 		if xb == 0 {
 			return NewNullValue(), nil
 		}
 
+		// the only division that overflows
+		if xa == math.MinInt64 && xb == -1 {
+			return overflow(-float64(xa), mode)
+		}
+
 		return NewIntegerValue(xa / xb), nil
 	case '%':
 		if xb == 0 {
 			return NewNullValue(), nil
 		}
 
+		// the remainder is 0 but the division it comes from overflows
+		if xa == math.MinInt64 && xb == -1 && mode == OverflowError {
+			return Value{}, ErrIntegerOverflow
+		}
+
 		return NewIntegerValue(xa % xb), nil
 	case '&':
 		return NewIntegerValue(xa & xb), nil
@@ -132,6 +132,42 @@ func TestValueAdd(t *testing.T) {
 	}
 }
 
+func TestValueOverflowMode(t *testing.T) {
+	mode := document.OverflowError
+	max := document.NewIntegerValue(math.MaxInt64)
+	min := document.NewIntegerValue(math.MinInt64)
+
+	_, err := mode.Add(max, document.NewIntegerValue(1))
+	require.Equal(t, document.ErrIntegerOverflow, err)
+	_, err = mode.Sub(min, document.NewIntegerValue(1))
+	require.Equal(t, document.ErrIntegerOverflow, err)
+	_, err = mode.Mul(max, document.NewIntegerValue(2))
+	require.Equal(t, document.ErrIntegerOverflow, err)
+	_, err = mode.Div(min, document.NewIntegerValue(-1))
+	require.Equal(t, document.ErrIntegerOverflow, err)
+	_, err = mode.Mod(min, document.NewIntegerValue(-1))
+	require.Equal(t, document.ErrIntegerOverflow, err)
+
+	// operations that don't overflow are not affected
+	res, err := mode.Sub(max, document.NewIntegerValue(1))
+	require.NoError(t, err)
+	require.Equal(t, document.NewIntegerValue(math.MaxInt64-1), res)
+	res, err = mode.Mod(min, document.NewIntegerValue(3))
+	require.NoError(t, err)
+	require.Equal(t, document.NewIntegerValue(math.MinInt64%3), res)
+
+	// the methods of Value promote the results to doubles
+	res, err = max.Add(document.NewIntegerValue(1))
+	require.NoError(t, err)
+	require.Equal(t, document.NewDoubleValue(float64(math.MaxInt64)+1), res)
+	res, err = document.OverflowPromote.Add(max, document.NewIntegerValue(1))
+	require.NoError(t, err)
+	require.Equal(t, document.NewDoubleValue(float64(math.MaxInt64)+1), res)
+	res, err = document.OverflowPromote.Mod(min, document.NewIntegerValue(-1))
+	require.NoError(t, err)
+	require.Equal(t, document.NewIntegerValue(0), res)
+}
+
 func TestValueSub(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -149,6 +185,8 @@ func TestValueSub(t *testing.T) {
 		{"integer(120)-float64(120.1)", document.NewIntegerValue(120), document.NewDoubleValue(120.1), document.NewDoubleValue(-0.09999999999999432), false},
 		{"int64(min)-integer(10)", document.NewIntegerValue(math.MinInt64), document.NewIntegerValue(10), document.NewDoubleValue(math.MinInt64 - 10), false},
 		{"int64(max)-integer(-10)", document.NewIntegerValue(math.MaxInt64), document.NewIntegerValue(-10), document.NewDoubleValue(math.MaxInt64 + 10), false},
+		{"integer(-1)-int64(min)", document.NewIntegerValue(-1), document.NewIntegerValue(math.MinInt64), document.NewIntegerValue(math.MaxInt64), false},
+		{"integer(0)-int64(min)", document.NewIntegerValue(0), document.NewIntegerValue(math.MinInt64), document.NewDoubleValue(-math.MinInt64), false},
 		{"integer(120)-text('120')", document.NewIntegerValue(120), document.NewTextValue("120"), document.NewNullValue(), false},
 		{"text('120')-text('120')", document.NewTextValue("120"), document.NewTextValue("120"), document.NewNullValue(), false},
 		{"document-document", document.NewDocumentValue(document.NewFieldBuffer().Add("a", document.NewIntegerValue(10))), document.NewDocumentValue(document.NewFieldBuffer().Add("a", document.NewIntegerValue(10))), document.NewNullValue(), false},
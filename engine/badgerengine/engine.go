@@ -74,7 +74,17 @@ func (t *Transaction) Commit() error {
 	}
 
 	t.discarded = true
-	return t.tx.Commit()
+	return convertError(t.tx.Commit())
+}
+
+// convertError returns engine.ErrTransactionConflict if err is badger.ErrConflict,
+// which lets the callers retry the transaction, or err otherwise.
+func convertError(err error) error {
+	if err == badger.ErrConflict {
+		return engine.ErrTransactionConflict
+	}
+
+	return err
 }
 
 func buildStoreKey(name []byte) []byte {
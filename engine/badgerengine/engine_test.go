@@ -1,15 +1,23 @@
 package badgerengine_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
 	"testing"
+	"time"
 
 	"github.com/dgraph-io/badger/v2"
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/engine"
 	"github.com/genjidb/genji/engine/badgerengine"
 	"github.com/genjidb/genji/engine/enginetest"
+	"github.com/genjidb/genji/sql/query"
 	"github.com/stretchr/testify/require"
 )
 
@@ -29,6 +37,101 @@ func TestBadgerEngine(t *testing.T) {
 	enginetest.TestSuite(t, builder(t))
 }
 
+func TestTransactionConflict(t *testing.T) {
+	ng, cleanup := builder(t)()
+	defer cleanup()
+
+	tx, err := ng.Begin(true)
+	require.NoError(t, err)
+	require.NoError(t, tx.CreateStore([]byte("store")))
+	require.NoError(t, tx.Commit())
+
+	// tx reads a key written by another transaction before committing
+	tx, err = ng.Begin(true)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	st, err := tx.GetStore([]byte("store"))
+	require.NoError(t, err)
+	_, err = st.Get([]byte("foo"))
+	require.Equal(t, engine.ErrKeyNotFound, err)
+
+	other, err := ng.Begin(true)
+	require.NoError(t, err)
+	ost, err := other.GetStore([]byte("store"))
+	require.NoError(t, err)
+	require.NoError(t, ost.Put([]byte("foo"), []byte("bar")))
+	require.NoError(t, other.Commit())
+
+	require.NoError(t, st.Put([]byte("foo"), []byte("baz")))
+	err = tx.Commit()
+	require.True(t, errors.Is(err, engine.ErrTransactionConflict))
+}
+
+func TestTxnRetries(t *testing.T) {
+	ng, cleanup := builder(t)()
+	defer cleanup()
+
+	db, err := genji.New(ng)
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	opts := genji.TxnOptions{Writable: true, MaxRetries: 3, MinBackoff: time.Microsecond}
+
+	// the first attempt conflicts with a transaction of the engine
+	// writing the value it read, and is retried
+	var calls int
+	err = db.TxnWithOptions(ctx, &opts, func(tx *genji.Tx) error {
+		calls++
+
+		s, err := tx.Store("counter")
+		if err != nil {
+			return err
+		}
+		v, err := s.Get([]byte("n"))
+		if err != nil && err != database.ErrKeyNotFound {
+			return err
+		}
+
+		if calls == 1 {
+			other, err := ng.Begin(true)
+			if err != nil {
+				return err
+			}
+			err = other.CreateStore([]byte("__genji_kv_counter"))
+			if err != nil {
+				return err
+			}
+			st, err := other.GetStore([]byte("__genji_kv_counter"))
+			if err != nil {
+				return err
+			}
+			err = st.Put([]byte("n"), []byte("a"))
+			if err != nil {
+				return err
+			}
+			err = other.Commit()
+			if err != nil {
+				return err
+			}
+		}
+
+		return s.Put([]byte("n"), append(v, 'b'))
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, calls)
+
+	err = db.View(func(tx *genji.Tx) error {
+		s, err := tx.Store("counter")
+		require.NoError(t, err)
+		v, err := s.Get([]byte("n"))
+		require.NoError(t, err)
+		require.Equal(t, []byte("ab"), v)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
 func BenchmarkBadgerEngineStorePut(b *testing.B) {
 	enginetest.BenchmarkStorePut(b, builder(b))
 }
@@ -45,3 +148,50 @@ func tempDir(t require.TestingT) (string, func()) {
 		os.RemoveAll(dir)
 	}
 }
+
+func TestConcurrentQueries(t *testing.T) {
+	ctx := context.Background()
+
+	for _, mode := range []database.BusyMode{database.BusyWait, database.BusyError} {
+		t.Run(fmt.Sprintf("BusyMode %d", mode), func(t *testing.T) {
+			ng, cleanup := builder(t)()
+			defer cleanup()
+
+			db, err := genji.NewWithOptions(ng, &genji.Options{BusyMode: mode})
+			require.NoError(t, err)
+			defer db.Close()
+
+			err = db.Exec(ctx, "CREATE TABLE test; INSERT INTO test (a) VALUES (1), (2)")
+			require.NoError(t, err)
+
+			res1, err := db.Query(ctx, "SELECT * FROM test")
+			require.NoError(t, err)
+			defer res1.Close()
+			res2, err := db.Query(ctx, "SELECT * FROM test WHERE a > 1")
+			require.NoError(t, err)
+			defer res2.Close()
+
+			// the INSERT runs while the results are opened,
+			// which read the documents as they were before
+			err = db.Exec(ctx, "INSERT INTO test (a) VALUES (3)")
+			require.NoError(t, err)
+
+			count := func(res *query.Result) int {
+				var n int
+				err := res.Iterate(func(d document.Document) error {
+					n++
+					return nil
+				})
+				require.NoError(t, err)
+				return n
+			}
+			require.Equal(t, 2, count(res1))
+			require.Equal(t, 1, count(res2))
+
+			res3, err := db.Query(ctx, "SELECT * FROM test")
+			require.NoError(t, err)
+			defer res3.Close()
+			require.Equal(t, 3, count(res3))
+		})
+	}
+}
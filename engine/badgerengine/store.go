@@ -42,7 +42,7 @@ func (s *Store) Put(k, v []byte) error {
 		return errors.New("cannot store empty key")
 	}
 
-	return s.tx.Set(buildKey(s.prefix, k), v)
+	return convertError(s.tx.Set(buildKey(s.prefix, k), v))
 }
 
 // Get returns a value associated with the given key. If not found, returns engine.ErrKeyNotFound.
@@ -75,7 +75,7 @@ func (s *Store) Delete(k []byte) error {
 		return err
 	}
 
-	return s.tx.Delete(key)
+	return convertError(s.tx.Delete(key))
 }
 
 // Truncate deletes all the records of the store.
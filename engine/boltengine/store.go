@@ -97,10 +97,14 @@ func (it *iterator) Seek(pivot []byte) {
 	}
 
 	it.item.k, it.item.v = it.c.Seek(pivot)
-	if it.item.k != nil {
-		for bytes.Compare(it.item.k, pivot) > 0 {
-			it.item.k, it.item.v = it.c.Prev()
-		}
+	if it.item.k == nil {
+		// all the keys are lower than the pivot
+		it.item.k, it.item.v = it.c.Last()
+		return
+	}
+
+	for bytes.Compare(it.item.k, pivot) > 0 {
+		it.item.k, it.item.v = it.c.Prev()
 	}
 }
 
@@ -3,6 +3,7 @@ package engine
 
 import (
 	"errors"
+	"time"
 )
 
 // Common errors returned by the engine implementations.
@@ -19,6 +20,10 @@ var (
 
 	// ErrKeyNotFound is returned when the targeted key doesn't exist.
 	ErrKeyNotFound = errors.New("key not found")
+
+	// ErrTransactionConflict must be returned by engines supporting concurrent read/write transactions
+	// when a transaction can't be committed because of a conflicting one. The transaction can be retried.
+	ErrTransactionConflict = errors.New("transaction conflict")
 )
 
 // An Engine is responsible for storing data.
@@ -34,6 +39,18 @@ type Engine interface {
 	Close() error
 }
 
+// A Deterministic engine provides the database with the seed of its random numbers
+// and with the current time, instead of using the system ones, so that generated
+// keys and timestamps are reproducible in tests.
+type Deterministic interface {
+	Engine
+
+	// Seed returns the seed of the random numbers generated by the database.
+	Seed() int64
+	// Now returns the current time.
+	Now() time.Time
+}
+
 // A Transaction provides methods for managing the collection of stores and the transaction itself.
 // The transaction is either read-only or read/write. Read-only transactions can be used to read stores
 // and read/write ones can be used to read, create, delete and modify stores.
@@ -0,0 +1,144 @@
+package enginetest
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/genjidb/genji/engine"
+)
+
+// ErrInjected is returned by a FaultyEngine when it injects a fault.
+var ErrInjected = errors.New("injected fault")
+
+// Faults configures the errors injected by a FaultyEngine.
+// Zero values disable the corresponding fault.
+type Faults struct {
+	// FailPutEvery makes every Nth call to Store.Put fail.
+	FailPutEvery int
+	// FailCommitEvery makes every Nth commit fail. The transaction
+	// is rolled back, as if the engine couldn't persist it.
+	FailCommitEvery int
+	// TornWrites makes failing calls to Store.Put write the first half
+	// of the value before returning, as if the write was interrupted.
+	TornWrites bool
+}
+
+// FaultyEngine wraps an engine and injects errors in its stores and
+// transactions, to test how failures of the engine are recovered from.
+// Faults can be changed while the engine is used.
+type FaultyEngine struct {
+	engine.Engine
+
+	mu      sync.Mutex
+	faults  Faults
+	puts    int
+	commits int
+}
+
+// NewFaultyEngine returns a FaultyEngine wrapping ng.
+func NewFaultyEngine(ng engine.Engine, faults Faults) *FaultyEngine {
+	return &FaultyEngine{Engine: ng, faults: faults}
+}
+
+// InjectFaults returns a middleware wrapping an engine with a FaultyEngine.
+func InjectFaults(faults Faults) engine.Middleware {
+	return func(ng engine.Engine) engine.Engine {
+		return NewFaultyEngine(ng, faults)
+	}
+}
+
+// FaultyBuilder returns a builder wrapping the engines created by builder
+// with a FaultyEngine.
+func FaultyBuilder(builder Builder, faults Faults) Builder {
+	return func() (engine.Engine, func()) {
+		ng, cleanup := builder()
+		return NewFaultyEngine(ng, faults), cleanup
+	}
+}
+
+// SetFaults changes the injected faults and resets the counters.
+func (ng *FaultyEngine) SetFaults(faults Faults) {
+	ng.mu.Lock()
+	defer ng.mu.Unlock()
+
+	ng.faults = faults
+	ng.puts = 0
+	ng.commits = 0
+}
+
+// Begin returns a transaction injecting the configured faults.
+func (ng *FaultyEngine) Begin(writable bool) (engine.Transaction, error) {
+	tx, err := ng.Engine.Begin(writable)
+	if err != nil {
+		return nil, err
+	}
+
+	return &faultyTransaction{Transaction: tx, ng: ng}, nil
+}
+
+// failPut returns whether the current Put must fail and, if so, whether it is torn.
+func (ng *FaultyEngine) failPut() (fail, torn bool) {
+	ng.mu.Lock()
+	defer ng.mu.Unlock()
+
+	ng.puts++
+	if ng.faults.FailPutEvery <= 0 || ng.puts%ng.faults.FailPutEvery != 0 {
+		return false, false
+	}
+
+	return true, ng.faults.TornWrites
+}
+
+func (ng *FaultyEngine) failCommit() bool {
+	ng.mu.Lock()
+	defer ng.mu.Unlock()
+
+	ng.commits++
+	return ng.faults.FailCommitEvery > 0 && ng.commits%ng.faults.FailCommitEvery == 0
+}
+
+type faultyTransaction struct {
+	engine.Transaction
+
+	ng *FaultyEngine
+}
+
+func (tx *faultyTransaction) Commit() error {
+	if tx.ng.failCommit() {
+		tx.Transaction.Rollback()
+		return ErrInjected
+	}
+
+	return tx.Transaction.Commit()
+}
+
+func (tx *faultyTransaction) GetStore(name []byte) (engine.Store, error) {
+	st, err := tx.Transaction.GetStore(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &faultyStore{Store: st, ng: tx.ng}, nil
+}
+
+type faultyStore struct {
+	engine.Store
+
+	ng *FaultyEngine
+}
+
+func (s *faultyStore) Put(k, v []byte) error {
+	fail, torn := s.ng.failPut()
+	if !fail {
+		return s.Store.Put(k, v)
+	}
+
+	if torn {
+		err := s.Store.Put(k, v[:len(v)/2])
+		if err != nil {
+			return err
+		}
+	}
+
+	return ErrInjected
+}
@@ -0,0 +1,115 @@
+package enginetest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/engine/enginetest"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFaultyEngine(t *testing.T) {
+	t.Run("Suite", func(t *testing.T) {
+		enginetest.TestSuite(t, enginetest.FaultyBuilder(func() (engine.Engine, func()) {
+			ng := memoryengine.NewEngine()
+			return ng, func() { ng.Close() }
+		}, enginetest.Faults{}))
+	})
+
+	t.Run("Put", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			torn     bool
+			expected []byte
+		}{
+			{"Fail", false, nil},
+			{"Torn", true, []byte("b")},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				ng := enginetest.NewFaultyEngine(memoryengine.NewEngine(), enginetest.Faults{FailPutEvery: 2, TornWrites: test.torn})
+				defer ng.Close()
+
+				tx, err := ng.Begin(true)
+				require.NoError(t, err)
+				defer tx.Rollback()
+
+				require.NoError(t, tx.CreateStore([]byte("test")))
+				st, err := tx.GetStore([]byte("test"))
+				require.NoError(t, err)
+
+				require.NoError(t, st.Put([]byte("a"), []byte("foo")))
+				require.Equal(t, enginetest.ErrInjected, st.Put([]byte("b"), []byte("bar")))
+				require.NoError(t, st.Put([]byte("c"), []byte("baz")))
+
+				v, err := st.Get([]byte("b"))
+				if test.expected == nil {
+					require.Equal(t, engine.ErrKeyNotFound, err)
+				} else {
+					require.NoError(t, err)
+					require.Equal(t, test.expected, v)
+				}
+			})
+		}
+	})
+
+	t.Run("Commit", func(t *testing.T) {
+		ng := enginetest.NewFaultyEngine(memoryengine.NewEngine(), enginetest.Faults{FailCommitEvery: 1})
+		defer ng.Close()
+
+		tx, err := ng.Begin(true)
+		require.NoError(t, err)
+		require.NoError(t, tx.CreateStore([]byte("test")))
+		require.Equal(t, enginetest.ErrInjected, tx.Commit())
+
+		ng.SetFaults(enginetest.Faults{})
+
+		tx, err = ng.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		_, err = tx.GetStore([]byte("test"))
+		require.Equal(t, engine.ErrStoreNotFound, err)
+	})
+
+	t.Run("Recovery", func(t *testing.T) {
+		ng := enginetest.NewFaultyEngine(memoryengine.NewEngine(), enginetest.Faults{})
+		db, err := genji.New(ng)
+		require.NoError(t, err)
+		defer db.Close()
+
+		ng.SetFaults(enginetest.Faults{FailCommitEvery: 1})
+		err = db.Exec(context.Background(), "CREATE TABLE test")
+		require.True(t, errors.Is(err, enginetest.ErrInjected))
+
+		ng.SetFaults(enginetest.Faults{})
+		require.NoError(t, db.Exec(context.Background(), "CREATE TABLE test"))
+
+		ng.SetFaults(enginetest.Faults{FailPutEvery: 1})
+		err = db.Exec(context.Background(), "INSERT INTO test (a) VALUES (1)")
+		require.True(t, errors.Is(err, enginetest.ErrInjected))
+
+		ng.SetFaults(enginetest.Faults{})
+		require.NoError(t, db.Exec(context.Background(), "INSERT INTO test (a) VALUES (2)"))
+
+		res, err := db.Query(context.Background(), "SELECT a FROM test")
+		require.NoError(t, err)
+		defer res.Close()
+
+		var values []int
+		err = res.Iterate(func(d document.Document) error {
+			var a int
+			err := document.Scan(d, &a)
+			values = append(values, a)
+			return err
+		})
+		require.NoError(t, err)
+		require.Equal(t, []int{2}, values)
+	})
+}
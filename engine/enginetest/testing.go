@@ -1,10 +1,15 @@
 // Package enginetest defines a list of tests that can be used to test
 // a complete or partial engine implementation.
+// Engine authors can validate their implementation by calling TestSuite
+// from their own tests.
 package enginetest
 
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/genjidb/genji"
@@ -33,7 +38,11 @@ func TestSuite(t *testing.T, builder Builder) {
 		{"Transaction/Store", TestTransactionStore},
 		{"Transaction/CreateStore", TestTransactionCreateStore},
 		{"Transaction/DropStore", TestTransactionDropStore},
+		{"Transaction/StoreLifecycle", TestTransactionStoreLifecycle},
+		{"Transaction/Concurrency", TestTransactionConcurrency},
 		{"Store/Iterator", TestStoreIterator},
+		{"Store/IteratorBoundaries", TestStoreIteratorBoundaries},
+		{"Store/Ordering", TestStoreOrdering},
 		{"Store/Put", TestStorePut},
 		{"Store/Get", TestStoreGet},
 		{"Store/Delete", TestStoreDelete},
@@ -425,6 +434,242 @@ func TestTransactionDropStore(t *testing.T, builder Builder) {
 	})
 }
 
+// TestTransactionStoreLifecycle verifies that creating and dropping stores
+// follows the transaction they are part of.
+func TestTransactionStoreLifecycle(t *testing.T, builder Builder) {
+	// createStore creates a store with one key and commits it.
+	createStore := func(t *testing.T, ng engine.Engine) {
+		tx, err := ng.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		err = tx.CreateStore([]byte("store"))
+		require.NoError(t, err)
+		st, err := tx.GetStore([]byte("store"))
+		require.NoError(t, err)
+		err = st.Put([]byte("foo"), []byte("bar"))
+		require.NoError(t, err)
+
+		err = tx.Commit()
+		require.NoError(t, err)
+	}
+
+	t.Run("Should not keep a store created by a rolled back transaction", func(t *testing.T) {
+		ng, cleanup := builder()
+		defer cleanup()
+
+		tx, err := ng.Begin(true)
+		require.NoError(t, err)
+		err = tx.CreateStore([]byte("store"))
+		require.NoError(t, err)
+		err = tx.Rollback()
+		require.NoError(t, err)
+
+		tx, err = ng.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		_, err = tx.GetStore([]byte("store"))
+		require.Equal(t, engine.ErrStoreNotFound, err)
+		err = tx.CreateStore([]byte("store"))
+		require.NoError(t, err)
+	})
+
+	t.Run("Should keep a store dropped by a rolled back transaction", func(t *testing.T) {
+		ng, cleanup := builder()
+		defer cleanup()
+
+		createStore(t, ng)
+
+		tx, err := ng.Begin(true)
+		require.NoError(t, err)
+		err = tx.DropStore([]byte("store"))
+		require.NoError(t, err)
+		err = tx.Rollback()
+		require.NoError(t, err)
+
+		tx, err = ng.Begin(false)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		st, err := tx.GetStore([]byte("store"))
+		require.NoError(t, err)
+		v, err := st.Get([]byte("foo"))
+		require.NoError(t, err)
+		require.Equal(t, []byte("bar"), v)
+	})
+
+	t.Run("Should persist a drop on commit", func(t *testing.T) {
+		ng, cleanup := builder()
+		defer cleanup()
+
+		createStore(t, ng)
+
+		tx, err := ng.Begin(true)
+		require.NoError(t, err)
+		err = tx.DropStore([]byte("store"))
+		require.NoError(t, err)
+		err = tx.Commit()
+		require.NoError(t, err)
+
+		tx, err = ng.Begin(false)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		_, err = tx.GetStore([]byte("store"))
+		require.Equal(t, engine.ErrStoreNotFound, err)
+	})
+
+	t.Run("Should recreate a dropped store empty", func(t *testing.T) {
+		ng, cleanup := builder()
+		defer cleanup()
+
+		createStore(t, ng)
+
+		tx, err := ng.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		err = tx.DropStore([]byte("store"))
+		require.NoError(t, err)
+		err = tx.CreateStore([]byte("store"))
+		require.NoError(t, err)
+		st, err := tx.GetStore([]byte("store"))
+		require.NoError(t, err)
+		_, err = st.Get([]byte("foo"))
+		require.Equal(t, engine.ErrKeyNotFound, err)
+
+		err = tx.Commit()
+		require.NoError(t, err)
+
+		tx, err = ng.Begin(false)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		st, err = tx.GetStore([]byte("store"))
+		require.NoError(t, err)
+		_, err = st.Get([]byte("foo"))
+		require.Equal(t, engine.ErrKeyNotFound, err)
+	})
+}
+
+// TestTransactionConcurrency verifies that transactions can be used from multiple goroutines.
+// Read-only transactions must be able to run at the same time, while read/write ones
+// can either wait for each other or fail with ErrTransactionConflict.
+func TestTransactionConcurrency(t *testing.T, builder Builder) {
+	const n = 10
+
+	t.Run("Should run read-only transactions concurrently", func(t *testing.T) {
+		ng, cleanup := builder()
+		defer cleanup()
+
+		tx, err := ng.Begin(true)
+		require.NoError(t, err)
+		err = tx.CreateStore([]byte("store"))
+		require.NoError(t, err)
+		st, err := tx.GetStore([]byte("store"))
+		require.NoError(t, err)
+		err = st.Put([]byte("foo"), []byte("bar"))
+		require.NoError(t, err)
+		err = tx.Commit()
+		require.NoError(t, err)
+
+		// all the transactions are opened before any of them is closed
+		var opened sync.WaitGroup
+		opened.Add(n)
+		errc := make(chan error, n)
+		for i := 0; i < n; i++ {
+			go func() {
+				errc <- func() error {
+					tx, err := ng.Begin(false)
+					opened.Done()
+					if err != nil {
+						return err
+					}
+					defer tx.Rollback()
+
+					opened.Wait()
+					st, err := tx.GetStore([]byte("store"))
+					if err != nil {
+						return err
+					}
+					v, err := st.Get([]byte("foo"))
+					if err != nil {
+						return err
+					}
+					if !bytes.Equal(v, []byte("bar")) {
+						return fmt.Errorf("expected %q, got %q", "bar", v)
+					}
+					return nil
+				}()
+			}()
+		}
+
+		for i := 0; i < n; i++ {
+			require.NoError(t, <-errc)
+		}
+	})
+
+	t.Run("Should commit concurrent read/write transactions", func(t *testing.T) {
+		ng, cleanup := builder()
+		defer cleanup()
+
+		tx, err := ng.Begin(true)
+		require.NoError(t, err)
+		err = tx.CreateStore([]byte("store"))
+		require.NoError(t, err)
+		err = tx.Commit()
+		require.NoError(t, err)
+
+		put := func(k []byte) error {
+			tx, err := ng.Begin(true)
+			if err != nil {
+				return err
+			}
+			defer tx.Rollback()
+
+			st, err := tx.GetStore([]byte("store"))
+			if err != nil {
+				return err
+			}
+			err = st.Put(k, k)
+			if err != nil {
+				return err
+			}
+			return tx.Commit()
+		}
+
+		errc := make(chan error, n)
+		for i := 0; i < n; i++ {
+			go func(k []byte) {
+				for {
+					err := put(k)
+					if !errors.Is(err, engine.ErrTransactionConflict) {
+						errc <- err
+						return
+					}
+				}
+			}([]byte{uint8(i)})
+		}
+
+		for i := 0; i < n; i++ {
+			require.NoError(t, <-errc)
+		}
+
+		tx, err = ng.Begin(false)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		st, err := tx.GetStore([]byte("store"))
+		require.NoError(t, err)
+		for i := 0; i < n; i++ {
+			v, err := st.Get([]byte{uint8(i)})
+			require.NoError(t, err)
+			require.Equal(t, []byte{uint8(i)}, v)
+		}
+	})
+}
+
 func storeBuilder(t testing.TB, builder Builder) (engine.Store, func()) {
 	ng, cleanup := builder()
 	tx, err := ng.Begin(true)
@@ -637,6 +882,139 @@ func TestStoreIterator(t *testing.T, builder Builder) {
 	})
 }
 
+// TestStoreOrdering verifies that keys are sorted by comparing their bytes,
+// regardless of the order in which they were inserted.
+func TestStoreOrdering(t *testing.T, builder Builder) {
+	keys := [][]byte{
+		{0}, {0, 0}, {0, 1}, {1}, []byte("a"), []byte("aa"), []byte("ab"), []byte("b"),
+		{0x7f}, {0x80}, {0xff}, {0xff, 0xff},
+	}
+
+	for _, reverse := range []bool{false, true} {
+		t.Run(fmt.Sprintf("Reverse: %v", reverse), func(t *testing.T) {
+			st, cleanup := storeBuilder(t, builder)
+			defer cleanup()
+
+			// 5 and the number of keys are coprime, so every key is inserted once
+			for i := range keys {
+				k := keys[i*5%len(keys)]
+				err := st.Put(k, k)
+				require.NoError(t, err)
+			}
+
+			var got [][]byte
+			it := st.NewIterator(engine.IteratorConfig{Reverse: reverse})
+			defer it.Close()
+
+			for it.Seek(nil); it.Valid(); it.Next() {
+				got = append(got, append([]byte{}, it.Item().Key()...))
+			}
+
+			expected := make([][]byte, len(keys))
+			for i := range keys {
+				if reverse {
+					expected[i] = keys[len(keys)-1-i]
+				} else {
+					expected[i] = keys[i]
+				}
+			}
+			require.Equal(t, expected, got)
+		})
+	}
+}
+
+// TestStoreIteratorBoundaries verifies the behaviour of iterators around the first and last keys.
+func TestStoreIteratorBoundaries(t *testing.T, builder Builder) {
+	// seek fills a store with the keys 1 to 10 and returns the first key
+	// returned after seeking pivot, or nil if the iterator isn't valid.
+	seek := func(t *testing.T, reverse bool, pivot []byte) []byte {
+		st, cleanup := storeBuilder(t, builder)
+		defer cleanup()
+
+		for i := 1; i <= 10; i++ {
+			err := st.Put([]byte{uint8(i)}, []byte{uint8(i)})
+			require.NoError(t, err)
+		}
+
+		it := st.NewIterator(engine.IteratorConfig{Reverse: reverse})
+		defer it.Close()
+
+		it.Seek(pivot)
+		if !it.Valid() {
+			return nil
+		}
+		return append([]byte{}, it.Item().Key()...)
+	}
+
+	tests := []struct {
+		name     string
+		reverse  bool
+		pivot    []byte
+		expected []byte
+	}{
+		{"Seek before the first key", false, []byte{0}, []byte{1}},
+		{"Seek the last key", false, []byte{10}, []byte{10}},
+		{"Seek after the last key", false, []byte{11}, nil},
+		{"Seek between keys", false, []byte{5, 0}, []byte{6}},
+		{"Reverse seek before the first key", true, []byte{0}, nil},
+		{"Reverse seek the first key", true, []byte{1}, []byte{1}},
+		{"Reverse seek after the last key", true, []byte{11}, []byte{10}},
+		{"Reverse seek between keys", true, []byte{5, 0}, []byte{5}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, seek(t, test.reverse, test.pivot))
+		})
+	}
+
+	t.Run("Should seek again after the end", func(t *testing.T) {
+		st, cleanup := storeBuilder(t, builder)
+		defer cleanup()
+
+		for i := 1; i <= 3; i++ {
+			err := st.Put([]byte{uint8(i)}, []byte{uint8(i)})
+			require.NoError(t, err)
+		}
+
+		it := st.NewIterator(engine.IteratorConfig{})
+		defer it.Close()
+
+		for it.Seek(nil); it.Valid(); it.Next() {
+		}
+
+		it.Seek([]byte{2})
+		require.True(t, it.Valid())
+		require.Equal(t, []byte{2}, it.Item().Key())
+	})
+
+	t.Run("ValueCopy should not share memory with the store", func(t *testing.T) {
+		st, cleanup := storeBuilder(t, builder)
+		defer cleanup()
+
+		err := st.Put([]byte("foo"), []byte("bar"))
+		require.NoError(t, err)
+
+		it := st.NewIterator(engine.IteratorConfig{})
+		defer it.Close()
+
+		it.Seek(nil)
+		require.True(t, it.Valid())
+
+		// a buffer too small must be replaced, a large one can be reused
+		for _, buf := range [][]byte{nil, make([]byte, 1), make([]byte, 10)} {
+			v, err := it.Item().ValueCopy(buf)
+			require.NoError(t, err)
+			require.Equal(t, []byte("bar"), v)
+			v[0] = 'c'
+		}
+
+		v, err := st.Get([]byte("foo"))
+		require.NoError(t, err)
+		require.Equal(t, []byte("bar"), v)
+	})
+}
+
 // TestStorePut verifies Put behaviour.
 func TestStorePut(t *testing.T, builder Builder) {
 	t.Run("Should insert data", func(t *testing.T) {
@@ -755,6 +1133,42 @@ func TestStoreDelete(t *testing.T, builder Builder) {
 		require.NoError(t, err)
 		require.Equal(t, []byte("BAR"), v)
 	})
+
+	t.Run("Should keep a key put again after being deleted", func(t *testing.T) {
+		ng, cleanup := builder()
+		defer cleanup()
+
+		tx, err := ng.Begin(true)
+		require.NoError(t, err)
+		err = tx.CreateStore([]byte("test"))
+		require.NoError(t, err)
+		st, err := tx.GetStore([]byte("test"))
+		require.NoError(t, err)
+		err = st.Put([]byte("foo"), []byte("FOO"))
+		require.NoError(t, err)
+		err = tx.Commit()
+		require.NoError(t, err)
+
+		tx, err = ng.Begin(true)
+		require.NoError(t, err)
+		st, err = tx.GetStore([]byte("test"))
+		require.NoError(t, err)
+		err = st.Delete([]byte("foo"))
+		require.NoError(t, err)
+		err = st.Put([]byte("foo"), []byte("BAR"))
+		require.NoError(t, err)
+		err = tx.Commit()
+		require.NoError(t, err)
+
+		tx, err = ng.Begin(false)
+		require.NoError(t, err)
+		defer tx.Rollback()
+		st, err = tx.GetStore([]byte("test"))
+		require.NoError(t, err)
+		v, err := st.Get([]byte("foo"))
+		require.NoError(t, err)
+		require.Equal(t, []byte("BAR"), v)
+	})
 }
 
 // TestStoreTruncate verifies Truncate behaviour.
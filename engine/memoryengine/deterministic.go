@@ -0,0 +1,77 @@
+package memoryengine
+
+import (
+	"sync"
+	"time"
+)
+
+// DeterministicEngine is a memory engine implementing engine.Deterministic:
+// databases using it generate the same random numbers for a given seed,
+// and see the time returned by the given clock.
+// It is meant to make tests asserting generated keys and timestamps reproducible.
+type DeterministicEngine struct {
+	*Engine
+
+	seed int64
+	now  func() time.Time
+}
+
+// NewDeterministicEngine creates an in-memory engine providing the database with seed
+// and with the time returned by now. If now is nil, the time is always the Unix epoch.
+func NewDeterministicEngine(seed int64, now func() time.Time) *DeterministicEngine {
+	if now == nil {
+		now = NewFakeClock(time.Unix(0, 0).UTC()).Now
+	}
+
+	return &DeterministicEngine{
+		Engine: NewEngine(),
+		seed:   seed,
+		now:    now,
+	}
+}
+
+// Seed returns the seed given to NewDeterministicEngine.
+func (ng *DeterministicEngine) Seed() int64 {
+	return ng.seed
+}
+
+// Now returns the time of the clock given to NewDeterministicEngine.
+func (ng *DeterministicEngine) Now() time.Time {
+	return ng.now()
+}
+
+// A FakeClock returns a time that only changes when it is advanced.
+// It is safe for concurrent use.
+type FakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+// NewFakeClock returns a clock set to t.
+func NewFakeClock(t time.Time) *FakeClock {
+	return &FakeClock{t: t}
+}
+
+// Now returns the time of the clock.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.t
+}
+
+// Advance moves the clock forward by d.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.t = c.t.Add(d)
+}
+
+// Set changes the time of the clock.
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.t = t
+}
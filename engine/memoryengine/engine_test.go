@@ -2,10 +2,12 @@ package memoryengine_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/genjidb/genji/engine"
 	"github.com/genjidb/genji/engine/enginetest"
 	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/stretchr/testify/require"
 )
 
 func builder() (engine.Engine, func()) {
@@ -17,6 +19,25 @@ func TestMemoryEngine(t *testing.T) {
 	enginetest.TestSuite(t, builder)
 }
 
+func TestDeterministicEngine(t *testing.T) {
+	enginetest.TestSuite(t, func() (engine.Engine, func()) {
+		ng := memoryengine.NewDeterministicEngine(42, nil)
+		return ng, func() { ng.Close() }
+	})
+
+	clock := memoryengine.NewFakeClock(time.Unix(10, 0))
+	ng := memoryengine.NewDeterministicEngine(42, clock.Now)
+	defer ng.Close()
+
+	var _ engine.Deterministic = ng
+	require.Equal(t, int64(42), ng.Seed())
+	require.Equal(t, time.Unix(10, 0), ng.Now())
+	clock.Advance(time.Second)
+	require.Equal(t, time.Unix(11, 0), ng.Now())
+
+	require.Equal(t, time.Unix(0, 0).UTC(), memoryengine.NewDeterministicEngine(42, nil).Now())
+}
+
 func BenchmarkMemoryEngineStorePut(b *testing.B) {
 	enginetest.BenchmarkStorePut(b, builder)
 }
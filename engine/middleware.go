@@ -0,0 +1,65 @@
+package engine
+
+// A Middleware returns an engine wrapping another one, to add a behaviour
+// to it without changing its implementation, like logging, metrics, encryption or caching.
+// Wrappers only need to override the methods they care about and can
+// delegate the others to the wrapped engine, for example by embedding it.
+type Middleware func(Engine) Engine
+
+// Wrap returns ng wrapped by the given middlewares.
+// As with http middlewares, the first one is the outermost: it is called
+// first and its calls go through the following ones before reaching ng.
+func Wrap(ng Engine, mws ...Middleware) Engine {
+	for i := len(mws) - 1; i >= 0; i-- {
+		ng = mws[i](ng)
+	}
+
+	return ng
+}
+
+// WrapTransactions returns a middleware wrapping every transaction begun by the engine with fn.
+func WrapTransactions(fn func(tx Transaction, writable bool) Transaction) Middleware {
+	return func(ng Engine) Engine {
+		return &txEngine{Engine: ng, fn: fn}
+	}
+}
+
+// WrapStores returns a middleware wrapping every store returned by the transactions
+// of the engine with fn. The name of the store and whether the transaction is
+// read/write are passed to fn.
+func WrapStores(fn func(st Store, name []byte, writable bool) Store) Middleware {
+	return WrapTransactions(func(tx Transaction, writable bool) Transaction {
+		return &storeTransaction{Transaction: tx, writable: writable, fn: fn}
+	})
+}
+
+type txEngine struct {
+	Engine
+
+	fn func(tx Transaction, writable bool) Transaction
+}
+
+func (ng *txEngine) Begin(writable bool) (Transaction, error) {
+	tx, err := ng.Engine.Begin(writable)
+	if err != nil {
+		return nil, err
+	}
+
+	return ng.fn(tx, writable), nil
+}
+
+type storeTransaction struct {
+	Transaction
+
+	writable bool
+	fn       func(st Store, name []byte, writable bool) Store
+}
+
+func (tx *storeTransaction) GetStore(name []byte) (Store, error) {
+	st, err := tx.Transaction.GetStore(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return tx.fn(st, name, tx.writable), nil
+}
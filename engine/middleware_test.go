@@ -0,0 +1,78 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/engine/enginetest"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingStore struct {
+	engine.Store
+
+	name  string
+	calls *[]string
+}
+
+func (s *recordingStore) Put(k, v []byte) error {
+	*s.calls = append(*s.calls, s.name+":"+string(k))
+	return s.Store.Put(k, v)
+}
+
+func recordPuts(name string, calls *[]string) engine.Middleware {
+	return engine.WrapStores(func(st engine.Store, _ []byte, _ bool) engine.Store {
+		return &recordingStore{Store: st, name: name, calls: calls}
+	})
+}
+
+func TestWrap(t *testing.T) {
+	t.Run("Suite", func(t *testing.T) {
+		var calls []string
+
+		enginetest.TestSuite(t, func() (engine.Engine, func()) {
+			ng := engine.Wrap(memoryengine.NewEngine(), recordPuts("a", &calls), recordPuts("b", &calls))
+			return ng, func() { ng.Close() }
+		})
+	})
+
+	t.Run("Order", func(t *testing.T) {
+		var calls []string
+		ng := engine.Wrap(memoryengine.NewEngine(), recordPuts("a", &calls), recordPuts("b", &calls))
+		defer ng.Close()
+
+		tx, err := ng.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		require.NoError(t, tx.CreateStore([]byte("test")))
+		st, err := tx.GetStore([]byte("test"))
+		require.NoError(t, err)
+		require.NoError(t, st.Put([]byte("foo"), []byte("bar")))
+		require.Equal(t, []string{"a:foo", "b:foo"}, calls)
+
+		v, err := st.Get([]byte("foo"))
+		require.NoError(t, err)
+		require.Equal(t, []byte("bar"), v)
+	})
+
+	t.Run("Transactions", func(t *testing.T) {
+		var modes []bool
+		ng := engine.Wrap(memoryengine.NewEngine(), engine.WrapTransactions(func(tx engine.Transaction, writable bool) engine.Transaction {
+			modes = append(modes, writable)
+			return tx
+		}))
+		defer ng.Close()
+
+		tx, err := ng.Begin(true)
+		require.NoError(t, err)
+		require.NoError(t, tx.Rollback())
+
+		tx, err = ng.Begin(false)
+		require.NoError(t, err)
+		require.NoError(t, tx.Rollback())
+
+		require.Equal(t, []bool{true, false}, modes)
+	})
+}
@@ -0,0 +1,107 @@
+package engine
+
+// NewPrefetchIterator returns an iterator reading the items of it ahead of the caller,
+// in batches of up to max items whose keys and values are copied into a buffer.
+// It allows iterators of engines with a high latency per read, like remote or compressed ones,
+// to fetch the items in bulk rather than one at a time.
+// The batches are adaptive: the first one after a call to Seek contains a single item
+// and each following one is twice as large, up to max, so that short scans,
+// like the ones of statements with a LIMIT, don't read many items they won't use.
+// If max is lower than 2, it is returned unchanged.
+func NewPrefetchIterator(it Iterator, max int) Iterator {
+	if max < 2 {
+		return it
+	}
+
+	return &prefetchIterator{it: it, max: max}
+}
+
+type prefetchIterator struct {
+	it  Iterator
+	max int
+	// size of the next batch
+	size  int
+	items []prefetchedItem
+	pos   int
+}
+
+// Seek moves the underlying iterator to k and reads the first batch.
+func (it *prefetchIterator) Seek(k []byte) {
+	it.it.Seek(k)
+	it.size = 1
+	it.fill()
+}
+
+// Next moves to the next buffered item, reading a new batch
+// once the buffer has been consumed.
+func (it *prefetchIterator) Next() {
+	it.pos++
+	if it.pos >= len(it.items) {
+		it.fill()
+	}
+}
+
+// fill replaces the buffer with the next batch of items of the underlying iterator.
+// The buffers of the previous items are reused.
+func (it *prefetchIterator) fill() {
+	n := 0
+	for ; n < it.size && it.it.Valid(); n++ {
+		if n == len(it.items) {
+			it.items = append(it.items, prefetchedItem{})
+		}
+
+		item := it.it.Item()
+		pi := &it.items[n]
+		pi.key = append(pi.key[:0], item.Key()...)
+		pi.value, pi.err = item.ValueCopy(pi.value[:cap(pi.value)])
+
+		it.it.Next()
+	}
+
+	it.items = it.items[:n]
+	it.pos = 0
+
+	if it.size < it.max {
+		it.size *= 2
+		if it.size > it.max {
+			it.size = it.max
+		}
+	}
+}
+
+func (it *prefetchIterator) Valid() bool {
+	return it.pos < len(it.items)
+}
+
+// Item returns the current buffered item, which remains valid until the next call to Next or Seek.
+func (it *prefetchIterator) Item() Item {
+	return &it.items[it.pos]
+}
+
+func (it *prefetchIterator) Close() error {
+	return it.it.Close()
+}
+
+// prefetchedItem is a copy of an item read ahead.
+type prefetchedItem struct {
+	key   []byte
+	value []byte
+	// error returned when copying the value, if any
+	err error
+}
+
+func (i *prefetchedItem) Key() []byte {
+	return i.key
+}
+
+func (i *prefetchedItem) ValueCopy(buf []byte) ([]byte, error) {
+	if i.err != nil {
+		return nil, i.err
+	}
+
+	if len(buf) < len(i.value) {
+		buf = make([]byte, len(i.value))
+	}
+	n := copy(buf, i.value)
+	return buf[:n], nil
+}
@@ -0,0 +1,104 @@
+package engine_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/engine/enginetest"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/stretchr/testify/require"
+)
+
+type prefetchStore struct {
+	engine.Store
+
+	max int
+	// number of items read from the underlying iterators
+	reads *int
+}
+
+func (s *prefetchStore) NewIterator(cfg engine.IteratorConfig) engine.Iterator {
+	return engine.NewPrefetchIterator(&countingIterator{Iterator: s.Store.NewIterator(cfg), reads: s.reads}, s.max)
+}
+
+type countingIterator struct {
+	engine.Iterator
+
+	reads *int
+}
+
+func (it *countingIterator) Item() engine.Item {
+	*it.reads++
+	return it.Iterator.Item()
+}
+
+func withPrefetch(max int, reads *int) engine.Middleware {
+	return engine.WrapStores(func(st engine.Store, _ []byte, _ bool) engine.Store {
+		return &prefetchStore{Store: st, max: max, reads: reads}
+	})
+}
+
+func TestPrefetchIterator(t *testing.T) {
+	var reads int
+	builder := func() (engine.Engine, func()) {
+		ng := engine.Wrap(memoryengine.NewEngine(), withPrefetch(4, &reads))
+		return ng, func() { ng.Close() }
+	}
+
+	t.Run("Iterator", func(t *testing.T) {
+		enginetest.TestStoreIterator(t, builder)
+	})
+	t.Run("Boundaries", func(t *testing.T) {
+		enginetest.TestStoreIteratorBoundaries(t, builder)
+	})
+	t.Run("Ordering", func(t *testing.T) {
+		enginetest.TestStoreOrdering(t, builder)
+	})
+
+	t.Run("Batches", func(t *testing.T) {
+		ng, cleanup := builder()
+		defer cleanup()
+
+		tx, err := ng.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		require.NoError(t, tx.CreateStore([]byte("test")))
+		st, err := tx.GetStore([]byte("test"))
+		require.NoError(t, err)
+		for i := 0; i < 20; i++ {
+			require.NoError(t, st.Put([]byte(fmt.Sprintf("k%02d", i)), []byte(fmt.Sprintf("v%02d", i))))
+		}
+
+		it := st.NewIterator(engine.IteratorConfig{})
+		defer it.Close()
+
+		// batches of 1, 2, 4, 4... items
+		reads = 0
+		it.Seek(nil)
+		require.Equal(t, 1, reads)
+		it.Next()
+		require.Equal(t, 3, reads)
+		it.Next()
+		it.Next()
+		require.Equal(t, 7, reads)
+
+		var keys []string
+		for ; it.Valid(); it.Next() {
+			v, err := it.Item().ValueCopy(nil)
+			require.NoError(t, err)
+			require.Equal(t, "v"+string(it.Item().Key()[1:]), string(v))
+			keys = append(keys, string(it.Item().Key()))
+		}
+		require.Len(t, keys, 17)
+		require.Equal(t, "k03", keys[0])
+		require.Equal(t, 20, reads)
+
+		// seeking resets the size of the batches
+		reads = 0
+		it.Seek([]byte("k10"))
+		require.Equal(t, 1, reads)
+		require.Equal(t, "k10", string(it.Item().Key()))
+	})
+}
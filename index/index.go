@@ -5,6 +5,8 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"math"
+	"sort"
 
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/engine"
@@ -39,6 +41,7 @@ type Index struct {
 
 	tx        engine.Transaction
 	storeName []byte
+	prefetch  int
 }
 
 // Options of the index.
@@ -47,6 +50,10 @@ type Options struct {
 
 	// If specified, the indexed expects only one type.
 	Type document.ValueType
+
+	// Prefetch is the maximum number of entries read ahead
+	// when iterating over the index. See engine.NewPrefetchIterator.
+	Prefetch int
 }
 
 // NewIndex creates an index that associates a value with a list of keys.
@@ -56,6 +63,7 @@ func NewIndex(tx engine.Transaction, idxName string, opts Options) *Index {
 		storeName: append([]byte(storePrefix), idxName...),
 		Unique:    opts.Unique,
 		Type:      opts.Type,
+		prefetch:  opts.Prefetch,
 	}
 }
 
@@ -81,7 +89,7 @@ func (idx *Index) Set(v document.Value, k []byte) error {
 	}
 
 	// encode the value we are going to use as a key
-	buf, err := idx.encodeValue(v)
+	buf, err := idx.EncodeValue(v)
 	if err != nil {
 		return err
 	}
@@ -125,6 +133,35 @@ func (idx *Index) Set(v document.Value, k []byte) error {
 	return st.Put(buf, k)
 }
 
+// Lookup returns the key associated with v in a unique index,
+// or nil if v isn't indexed. It returns an error if the index is not unique.
+func (idx *Index) Lookup(v document.Value) ([]byte, error) {
+	if !idx.Unique {
+		return nil, errors.New("cannot lookup a value in a non-unique index")
+	}
+
+	if idx.Type != 0 && idx.Type != v.Type {
+		return nil, nil
+	}
+
+	st, err := getOrCreateStore(idx.tx, idx.storeName)
+	if err != nil {
+		return nil, err
+	}
+
+	buf, err := idx.EncodeValue(v)
+	if err != nil {
+		return nil, err
+	}
+
+	k, err := st.Get(buf)
+	if err == engine.ErrKeyNotFound {
+		return nil, nil
+	}
+
+	return k, err
+}
+
 // Delete all the references to the key from the index.
 func (idx *Index) Delete(v document.Value, k []byte) error {
 	st, err := getOrCreateStore(idx.tx, idx.storeName)
@@ -160,6 +197,7 @@ func (idx *Index) Delete(v document.Value, k []byte) error {
 // AscendGreaterOrEqual seeks for the pivot and then goes through all the subsequent key value pairs in increasing order and calls the given function for each pair.
 // If the given function returns an error, the iteration stops and returns that error.
 // If the pivot is empty, starts from the beginning.
+// On a typed index, a numeric pivot of the other numeric type is converted like the lower bound of Range.
 func (idx *Index) AscendGreaterOrEqual(pivot document.Value, fn func(val, key []byte, isEqual bool) error) error {
 	return idx.iterateOnStore(pivot, false, fn)
 }
@@ -167,11 +205,17 @@ func (idx *Index) AscendGreaterOrEqual(pivot document.Value, fn func(val, key []
 // DescendLessOrEqual seeks for the pivot and then goes through all the subsequent key value pairs in descreasing order and calls the given function for each pair.
 // If the given function returns an error, the iteration stops and returns that error.
 // If the pivot is empty, starts from the end.
+// On a typed index, a numeric pivot of the other numeric type is converted like the upper bound of Range.
 func (idx *Index) DescendLessOrEqual(pivot document.Value, fn func(val, key []byte, isEqual bool) error) error {
 	return idx.iterateOnStore(pivot, true, fn)
 }
 
 func (idx *Index) iterateOnStore(pivot document.Value, reverse bool, fn func(val, key []byte, isEqual bool) error) error {
+	pivot, exact, ok := idx.convertBound(pivot, reverse)
+	if !ok {
+		return nil
+	}
+
 	if idx.Type != 0 && pivot.Type != 0 && idx.Type != pivot.Type {
 		return nil
 	}
@@ -186,7 +230,7 @@ func (idx *Index) iterateOnStore(pivot document.Value, reverse bool, fn func(val
 
 	var enc []byte
 	if pivot.V != nil {
-		enc, err = idx.encodeValue(pivot)
+		enc, err = idx.EncodeValue(pivot)
 		if err != nil {
 			return err
 		}
@@ -196,13 +240,95 @@ func (idx *Index) iterateOnStore(pivot document.Value, reverse bool, fn func(val
 	return idx.iterate(st, pivot, reverse, func(item engine.Item) error {
 		var err error
 
-		k := item.Key()
+		k := idx.trimKey(item.Key())
+
+		buf, err = item.ValueCopy(buf[:0])
+		if err != nil {
+			return err
+		}
+
+		return fn(k, buf, exact && bytes.Equal(k, enc))
+	})
+}
+
+// Range goes through all the key value pairs whose values are between min and max, both included,
+// in increasing order, or in decreasing order if reverse is true, and calls the given function for each pair.
+// isMin and isMax report whether the value is equal to min or max, which allows
+// the caller to exclude the bounds of the range.
+// A bound with an empty type is ignored. If a bound has a type but no value, only the values of that type
+// are iterated over on that side of the range. Bounds of different types select no value,
+// except for integers and doubles which are compared as numbers.
+// On a typed index, numeric bounds of the other numeric type are converted to the type of the index,
+// rounding towards the inside of the range, and isMin or isMax are then only reported
+// if the conversion is exact. Bounds satisfied by every value of the index, like doubles
+// beyond the range of integers, are ignored, and bounds satisfied by none, like NaN, select no value.
+// If the given function returns an error, the iteration stops and returns that error.
+func (idx *Index) Range(min, max document.Value, reverse bool, fn func(val, key []byte, isMin, isMax bool) error) error {
+	min, minExact, ok := idx.convertBound(min, false)
+	if !ok {
+		return nil
+	}
+	max, maxExact, ok := idx.convertBound(max, true)
+	if !ok {
+		return nil
+	}
+
+	for _, b := range []document.Value{min, max} {
+		if idx.Type != 0 && b.Type != 0 && idx.Type != b.Type {
+			return nil
+		}
+	}
+
+	if min.Type != 0 && max.Type != 0 && rangeType(min.Type) != rangeType(max.Type) {
+		return nil
+	}
+
+	st, err := idx.tx.GetStore(idx.storeName)
+	if err != nil && err != engine.ErrStoreNotFound {
+		return err
+	}
+	if st == nil {
+		return nil
+	}
+
+	var encMin, encMax []byte
+	if min.V != nil {
+		encMin, err = idx.EncodeValue(min)
+		if err != nil {
+			return err
+		}
+	}
+	if max.V != nil {
+		encMax, err = idx.EncodeValue(max)
+		if err != nil {
+			return err
+		}
+	}
+
+	// start from one bound, or from the first value of the type of the other bound
+	pivot, other, end := min, max, encMax
+	if reverse {
+		pivot, other, end = max, min, encMin
+	}
+	if pivot.Type == 0 {
+		pivot = document.Value{Type: other.Type}
+	}
+	// values of typed indexes are not prefixed by their type
+	if idx.Type != 0 && pivot.V == nil {
+		pivot = document.Value{}
+	}
+
+	var buf []byte
+	err = idx.iterate(st, pivot, reverse, func(item engine.Item) error {
+		var err error
+
+		k := idx.trimKey(item.Key())
 
-		// the last byte of the key of a non-unique index is the size of the varint.
-		// if that byte is 0, it means that key is not duplicated.
-		if !idx.Unique {
-			n := k[len(k)-1]
-			k = k[:len(k)-int(n)-1]
+		if end != nil {
+			cmp := bytes.Compare(k, end)
+			if (!reverse && cmp > 0) || (reverse && cmp < 0) {
+				return errStop
+			}
 		}
 
 		buf, err = item.ValueCopy(buf[:0])
@@ -210,8 +336,198 @@ func (idx *Index) iterateOnStore(pivot document.Value, reverse bool, fn func(val
 			return err
 		}
 
-		return fn(k, buf, bytes.Equal(k, enc))
+		return fn(k, buf, minExact && bytes.Equal(k, encMin), maxExact && bytes.Equal(k, encMax))
 	})
+	if err == errStop {
+		return nil
+	}
+
+	return err
+}
+
+// convertBound converts a numeric bound of a range to the type of a typed index.
+// Doubles are rounded up if they are a lower bound and rounded down otherwise,
+// and integers that can't be represented exactly are converted to the nearest double
+// inside the range. It reports whether the converted bound is equal to v, and returns false
+// if no value of the index can satisfy the bound, like NaN, or an integer index and a lower bound
+// above the largest integer. Bounds satisfied by every value of the index are converted
+// to an empty value.
+func (idx *Index) convertBound(v document.Value, upper bool) (document.Value, bool, bool) {
+	switch {
+	case idx.Type == document.IntegerValue && v.Type == document.DoubleValue && v.V != nil:
+		f := v.V.(float64)
+		if math.IsNaN(f) {
+			return document.Value{}, false, false
+		}
+
+		r := math.Ceil(f)
+		if upper {
+			r = math.Floor(f)
+		}
+		// float64(math.MaxInt64) is 2^63, which is out of range
+		if r >= math.MaxInt64 {
+			return document.Value{}, false, upper
+		}
+		if r < math.MinInt64 {
+			return document.Value{}, false, !upper
+		}
+		return document.NewIntegerValue(int64(r)), r == f, true
+	case idx.Type == document.DoubleValue && v.Type == document.IntegerValue && v.V != nil:
+		i := v.V.(int64)
+		f := float64(i)
+		switch {
+		// float64(math.MaxInt64) is 2^63, which is out of range
+		case f >= math.MaxInt64 || int64(f) > i:
+			if upper {
+				f = math.Nextafter(f, math.Inf(-1))
+			}
+		case int64(f) < i:
+			if !upper {
+				f = math.Nextafter(f, math.Inf(1))
+			}
+		default:
+			return document.NewDoubleValue(f), true, true
+		}
+		return document.NewDoubleValue(f), false, true
+	}
+
+	return v, true, true
+}
+
+// SeekAll looks up all the given values and calls the given function for every key value pair
+// whose value is equal to one of them. Values are sorted and deduplicated first,
+// which allows all the lookups to be done with a single iterator, by moving it forward.
+// The pairs are returned in increasing order of values. Null values and values
+// whose type doesn't match the type of the index are ignored.
+// On a typed index, numeric values of the other numeric type are converted to the type
+// of the index if the conversion is exact, and ignored otherwise, like 2.5 on an integer index.
+// If the given function returns an error, the iteration stops and returns that error.
+func (idx *Index) SeekAll(values []document.Value, fn func(val, key []byte) error) error {
+	encs := make([][]byte, 0, len(values))
+	for _, v := range values {
+		v, exact, _ := idx.convertBound(v, false)
+		if !exact || v.Type == document.NullValue || (idx.Type != 0 && idx.Type != v.Type) {
+			continue
+		}
+
+		enc, err := idx.EncodeValue(v)
+		if err != nil {
+			return err
+		}
+		encs = append(encs, enc)
+	}
+
+	if len(encs) == 0 {
+		return nil
+	}
+
+	sort.Slice(encs, func(i, j int) bool {
+		return bytes.Compare(encs[i], encs[j]) < 0
+	})
+
+	st, err := idx.tx.GetStore(idx.storeName)
+	if err != nil && err != engine.ErrStoreNotFound {
+		return err
+	}
+	if st == nil {
+		return nil
+	}
+
+	it := st.NewIterator(engine.IteratorConfig{})
+	defer it.Close()
+
+	var buf, prev []byte
+	for i, enc := range encs {
+		if i > 0 && bytes.Equal(enc, prev) {
+			continue
+		}
+		prev = enc
+
+		// the iterator only needs to be moved if it is positioned before the value
+		if i == 0 || (it.Valid() && bytes.Compare(idx.trimKey(it.Item().Key()), enc) < 0) {
+			it.Seek(enc)
+		}
+
+		for ; it.Valid(); it.Next() {
+			item := it.Item()
+			k := idx.trimKey(item.Key())
+			if !bytes.Equal(k, enc) {
+				break
+			}
+
+			buf, err = item.ValueCopy(buf[:0])
+			if err != nil {
+				return err
+			}
+
+			err = fn(k, buf)
+			if err != nil {
+				return err
+			}
+		}
+
+		if !it.Valid() {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// rangeType returns the type used to order values of type t in an index.
+func rangeType(t document.ValueType) document.ValueType {
+	if t == document.IntegerValue {
+		return document.DoubleValue
+	}
+
+	return t
+}
+
+// trimKey removes the suffix of the keys of non-unique indexes.
+// The last byte of the key of a non-unique index is the size of the varint.
+// If that byte is 0, it means that key is not duplicated.
+func (idx *Index) trimKey(k []byte) []byte {
+	if idx.Unique {
+		return k
+	}
+
+	n := k[len(k)-1]
+	return k[:len(k)-int(n)-1]
+}
+
+// Stats holds statistics about the values of an index.
+type Stats struct {
+	// Count is the number of keys associated with a value.
+	Count int64
+	// Distinct is the number of distinct values.
+	Distinct int64
+}
+
+// Selectivity returns the average number of keys associated with a value.
+// It returns 0 if the index is empty.
+func (s Stats) Selectivity() float64 {
+	if s.Distinct == 0 {
+		return 0
+	}
+
+	return float64(s.Count) / float64(s.Distinct)
+}
+
+// Stats goes through the whole index and returns statistics about its values.
+func (idx *Index) Stats() (Stats, error) {
+	var stats Stats
+	var prev []byte
+
+	err := idx.iterateOnStore(document.Value{}, false, func(val, key []byte, isEqual bool) error {
+		stats.Count++
+		if stats.Count == 1 || !bytes.Equal(val, prev) {
+			stats.Distinct++
+			prev = append(prev[:0], val...)
+		}
+		return nil
+	})
+
+	return stats, err
 }
 
 // Truncate deletes all the index data.
@@ -224,11 +540,11 @@ func (idx *Index) Truncate() error {
 	return nil
 }
 
-// encode the value we are going to use as a key
-// if the index is typed, encode the value without expecting
+// EncodeValue encodes the value the way it is stored in the index.
+// If the index is typed, encode the value without expecting
 // the presence of other types.
-// if not, encode so that order is preserved regardless of the type.
-func (idx *Index) encodeValue(v document.Value) (buf []byte, err error) {
+// If not, encode so that order is preserved regardless of the type.
+func (idx *Index) EncodeValue(v document.Value) (buf []byte, err error) {
 	if idx.Type != 0 {
 		buf, err = key.Append(buf, v.Type, v.V)
 	} else {
@@ -260,7 +576,7 @@ func (idx *Index) iterate(st engine.Store, pivot document.Value, reverse bool, f
 	var err error
 
 	if pivot.V != nil {
-		seek, err = idx.encodeValue(pivot)
+		seek, err = idx.EncodeValue(pivot)
 		if err != nil {
 			return err
 		}
@@ -282,7 +598,7 @@ func (idx *Index) iterate(st engine.Store, pivot document.Value, reverse bool, f
 		}
 	}
 
-	it := st.NewIterator(engine.IteratorConfig{Reverse: reverse})
+	it := engine.NewPrefetchIterator(st.NewIterator(engine.IteratorConfig{Reverse: reverse}), idx.prefetch)
 	defer it.Close()
 
 	for it.Seek(seek); it.Valid(); it.Next() {
@@ -4,6 +4,7 @@ package index_test
 import (
 	"errors"
 	"fmt"
+	"math"
 	"strconv"
 	"testing"
 
@@ -419,6 +420,330 @@ func TestIndexDescendLessOrEqual(t *testing.T) {
 	}
 }
 
+func TestIndexRange(t *testing.T) {
+	for _, unique := range []bool{true, false} {
+		text := fmt.Sprintf("Unique: %v, ", unique)
+
+		setup := func(t *testing.T) (*index.Index, func()) {
+			idx, cleanup := getIndex(t, unique)
+
+			for i := 0; i < 10; i++ {
+				require.NoError(t, idx.Set(document.NewIntegerValue(int64(i)), []byte{'i', 'a' + byte(i)}))
+				require.NoError(t, idx.Set(document.NewTextValue(strconv.Itoa(i)), []byte{'s', 'a' + byte(i)}))
+			}
+
+			return idx, cleanup
+		}
+
+		tests := []struct {
+			name     string
+			min, max document.Value
+			reverse  bool
+			expected []int
+		}{
+			{"Both bounds", document.NewIntegerValue(3), document.NewIntegerValue(6), false, []int{3, 4, 5, 6}},
+			{"Both bounds, reverse", document.NewIntegerValue(3), document.NewDoubleValue(6), true, []int{6, 5, 4, 3}},
+			{"Min only", document.NewIntegerValue(7), document.Value{}, false, []int{7, 8, 9}},
+			{"Max only", document.Value{}, document.NewIntegerValue(2), false, []int{0, 1, 2}},
+			{"Max only, reverse", document.Value{}, document.NewIntegerValue(2), true, []int{2, 1, 0}},
+			{"Typed empty max", document.NewIntegerValue(8), document.Value{Type: document.IntegerValue}, false, []int{8, 9}},
+			{"Min greater than max", document.NewIntegerValue(6), document.NewIntegerValue(3), false, nil},
+			{"Different types", document.NewIntegerValue(3), document.NewTextValue("6"), false, nil},
+		}
+
+		for _, test := range tests {
+			t.Run(text+test.name, func(t *testing.T) {
+				idx, cleanup := setup(t)
+				defer cleanup()
+
+				var values []int
+				err := idx.Range(test.min, test.max, test.reverse, func(val, key []byte, isMin, isMax bool) error {
+					i := int(key[1] - 'a')
+					requireEqualEncoded(t, document.NewIntegerValue(int64(i)), val)
+
+					values = append(values, i)
+					return nil
+				})
+				require.NoError(t, err)
+				require.Equal(t, test.expected, values)
+			})
+		}
+
+		t.Run(text+"Bounds on text", func(t *testing.T) {
+			idx, cleanup := setup(t)
+			defer cleanup()
+
+			var values []string
+			err := idx.Range(document.NewTextValue("2"), document.NewTextValue("4"), false, func(val, key []byte, isMin, isMax bool) error {
+				require.Equal(t, byte('s'), key[0])
+				require.Equal(t, key[1] == 'c', isMin)
+				require.Equal(t, key[1] == 'e', isMax)
+
+				values = append(values, strconv.Itoa(int(key[1]-'a')))
+				return nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, []string{"2", "3", "4"}, values)
+		})
+
+		t.Run(text+"Typed index with bounds of the other numeric type", func(t *testing.T) {
+			ng := memoryengine.NewEngine()
+			tx, err := ng.Begin(true)
+			require.NoError(t, err)
+			defer tx.Rollback()
+
+			idx := index.NewIndex(tx, "foo", index.Options{Unique: unique, Type: document.IntegerValue})
+			for i := 0; i < 5; i++ {
+				require.NoError(t, idx.Set(document.NewIntegerValue(int64(i)), []byte{'a' + byte(i)}))
+			}
+
+			type bound struct {
+				i            int
+				isMin, isMax bool
+			}
+			var bounds []bound
+			err = idx.Range(document.NewDoubleValue(1.5), document.NewDoubleValue(3), false, func(val, key []byte, isMin, isMax bool) error {
+				bounds = append(bounds, bound{int(key[0] - 'a'), isMin, isMax})
+				return nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, []bound{{2, false, false}, {3, false, true}}, bounds)
+
+			// no value is greater than NaN
+			bounds = nil
+			err = idx.Range(document.NewDoubleValue(math.NaN()), document.NewDoubleValue(1), false, func(val, key []byte, isMin, isMax bool) error {
+				bounds = append(bounds, bound{int(key[0] - 'a'), isMin, isMax})
+				return nil
+			})
+			require.NoError(t, err)
+			require.Nil(t, bounds)
+
+			// bounds beyond the range of integers are ignored
+			err = idx.Range(document.NewDoubleValue(-1e20), document.NewDoubleValue(1), false, func(val, key []byte, isMin, isMax bool) error {
+				bounds = append(bounds, bound{int(key[0] - 'a'), isMin, isMax})
+				return nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, []bound{{0, false, false}, {1, false, true}}, bounds)
+		})
+	}
+}
+
+func TestIndexTypedPivot(t *testing.T) {
+	// the index contains the integers from -3 to 4, the key of i is 'd'+i
+	setup := func(t *testing.T, typ document.ValueType) (*index.Index, func()) {
+		ng := memoryengine.NewEngine()
+		tx, err := ng.Begin(true)
+		require.NoError(t, err)
+
+		idx := index.NewIndex(tx, "foo", index.Options{Type: typ})
+		for i := -3; i <= 4; i++ {
+			v := document.NewIntegerValue(int64(i))
+			if typ == document.DoubleValue {
+				v = document.NewDoubleValue(float64(i))
+			}
+			require.NoError(t, idx.Set(v, []byte{byte('d' + i)}))
+		}
+
+		return idx, func() {
+			tx.Rollback()
+		}
+	}
+
+	tests := []struct {
+		name     string
+		typ      document.ValueType
+		pivot    document.Value
+		reverse  bool
+		expected []int
+		equal    []int
+	}{
+		{"Integer index, ascend from 3.5", document.IntegerValue, document.NewDoubleValue(3.5), false, []int{4}, nil},
+		{"Integer index, ascend from -2.5", document.IntegerValue, document.NewDoubleValue(-2.5), false, []int{-2, -1, 0, 1, 2, 3, 4}, nil},
+		{"Integer index, ascend from 1.0", document.IntegerValue, document.NewDoubleValue(1), false, []int{1, 2, 3, 4}, []int{1}},
+		{"Integer index, ascend from -0.0", document.IntegerValue, document.NewDoubleValue(math.Copysign(0, -1)), false, []int{0, 1, 2, 3, 4}, []int{0}},
+		{"Integer index, ascend from NaN", document.IntegerValue, document.NewDoubleValue(math.NaN()), false, nil, nil},
+		{"Integer index, ascend from 1e20", document.IntegerValue, document.NewDoubleValue(1e20), false, nil, nil},
+		{"Integer index, ascend from -1e20", document.IntegerValue, document.NewDoubleValue(-1e20), false, []int{-3, -2, -1, 0, 1, 2, 3, 4}, nil},
+		{"Integer index, descend from -0.5", document.IntegerValue, document.NewDoubleValue(-0.5), true, []int{-1, -2, -3}, nil},
+		{"Integer index, descend from 2.5", document.IntegerValue, document.NewDoubleValue(2.5), true, []int{2, 1, 0, -1, -2, -3}, nil},
+		{"Integer index, descend from -2.0", document.IntegerValue, document.NewDoubleValue(-2), true, []int{-2, -3}, []int{-2}},
+		{"Integer index, descend from 1e20", document.IntegerValue, document.NewDoubleValue(1e20), true, []int{4, 3, 2, 1, 0, -1, -2, -3}, nil},
+		{"Integer index, descend from -1e20", document.IntegerValue, document.NewDoubleValue(-1e20), true, nil, nil},
+		{"Double index, ascend from 2", document.DoubleValue, document.NewIntegerValue(2), false, []int{2, 3, 4}, []int{2}},
+		{"Double index, descend from -2", document.DoubleValue, document.NewIntegerValue(-2), true, []int{-2, -3}, []int{-2}},
+		{"Double index, ascend from the largest integer", document.DoubleValue, document.NewIntegerValue(math.MaxInt64), false, nil, nil},
+		{"Double index, descend from the largest integer", document.DoubleValue, document.NewIntegerValue(math.MaxInt64), true, []int{4, 3, 2, 1, 0, -1, -2, -3}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			idx, cleanup := setup(t, test.typ)
+			defer cleanup()
+
+			var values []int
+			var equal []int
+			fn := func(val, key []byte, isEqual bool) error {
+				i := int(key[0]) - 'd'
+				if isEqual {
+					equal = append(equal, i)
+				}
+				values = append(values, i)
+				return nil
+			}
+
+			var err error
+			if test.reverse {
+				err = idx.DescendLessOrEqual(test.pivot, fn)
+			} else {
+				err = idx.AscendGreaterOrEqual(test.pivot, fn)
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expected, values)
+			require.Equal(t, test.equal, equal)
+		})
+	}
+}
+
+func TestIndexSeekAll(t *testing.T) {
+	for _, unique := range []bool{true, false} {
+		text := fmt.Sprintf("Unique: %v, ", unique)
+
+		t.Run(text+"Should not iterate if index is empty", func(t *testing.T) {
+			idx, cleanup := getIndex(t, unique)
+			defer cleanup()
+
+			err := idx.SeekAll([]document.Value{document.NewIntegerValue(1)}, func(val, key []byte) error {
+				return errors.New("should not iterate")
+			})
+			require.NoError(t, err)
+		})
+
+		t.Run(text+"Should return the keys of all the values in order", func(t *testing.T) {
+			idx, cleanup := getIndex(t, unique)
+			defer cleanup()
+
+			for i := 0; i < 10; i++ {
+				require.NoError(t, idx.Set(document.NewIntegerValue(int64(i)), []byte{'i', 'a' + byte(i)}))
+				require.NoError(t, idx.Set(document.NewTextValue(strconv.Itoa(i)), []byte{'s', 'a' + byte(i)}))
+			}
+
+			values := []document.Value{
+				document.NewTextValue("3"),
+				document.NewIntegerValue(8),
+				document.NewNullValue(),
+				document.NewIntegerValue(2),
+				document.NewDoubleValue(8),
+				document.NewIntegerValue(42),
+				document.NewTextValue("1"),
+			}
+
+			var keys []string
+			err := idx.SeekAll(values, func(val, key []byte) error {
+				keys = append(keys, string(key))
+				return nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, []string{"ic", "ii", "sb", "sd"}, keys)
+		})
+	}
+
+	t.Run("Typed index with values of the other numeric type", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			typ      document.ValueType
+			values   []document.Value
+			expected string
+		}{
+			{"Integer index", document.IntegerValue, []document.Value{
+				document.NewDoubleValue(1), document.NewDoubleValue(2.5), document.NewIntegerValue(4),
+				document.NewDoubleValue(-0.5), document.NewDoubleValue(math.NaN()), document.NewDoubleValue(math.Inf(1)),
+			}, "be"},
+			{"Double index", document.DoubleValue, []document.Value{
+				document.NewIntegerValue(3), document.NewDoubleValue(1), document.NewIntegerValue(math.MaxInt64),
+			}, "bd"},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				ng := memoryengine.NewEngine()
+				tx, err := ng.Begin(true)
+				require.NoError(t, err)
+				defer tx.Rollback()
+
+				idx := index.NewIndex(tx, "foo", index.Options{Type: test.typ})
+				for i := 0; i < 5; i++ {
+					v := document.NewIntegerValue(int64(i))
+					if test.typ == document.DoubleValue {
+						v = document.NewDoubleValue(float64(i))
+					}
+					require.NoError(t, idx.Set(v, []byte{'a' + byte(i)}))
+				}
+
+				var keys string
+				err = idx.SeekAll(test.values, func(val, key []byte) error {
+					keys += string(key)
+					return nil
+				})
+				require.NoError(t, err)
+				require.Equal(t, test.expected, keys)
+			})
+		}
+	})
+
+	t.Run("Unique: false, Should return all the keys of duplicated values", func(t *testing.T) {
+		idx, cleanup := getIndex(t, false)
+		defer cleanup()
+
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				require.NoError(t, idx.Set(document.NewIntegerValue(int64(i)), []byte{'a' + byte(i), '0' + byte(j)}))
+			}
+		}
+
+		var keys []string
+		err := idx.SeekAll([]document.Value{document.NewIntegerValue(2), document.NewIntegerValue(0)}, func(val, key []byte) error {
+			requireEqualEncoded(t, document.NewIntegerValue(int64(key[0]-'a')), val)
+			keys = append(keys, string(key))
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"a0", "a1", "a2", "c0", "c1", "c2"}, keys)
+	})
+}
+
+func TestIndexStats(t *testing.T) {
+	for _, unique := range []bool{true, false} {
+		text := fmt.Sprintf("Unique: %v, ", unique)
+
+		t.Run(text+"Empty index", func(t *testing.T) {
+			idx, cleanup := getIndex(t, unique)
+			defer cleanup()
+
+			stats, err := idx.Stats()
+			require.NoError(t, err)
+			require.Equal(t, index.Stats{}, stats)
+			require.Zero(t, stats.Selectivity())
+		})
+	}
+
+	t.Run("Unique: false, Should count distinct values", func(t *testing.T) {
+		idx, cleanup := getIndex(t, false)
+		defer cleanup()
+
+		for i := 0; i < 12; i++ {
+			require.NoError(t, idx.Set(document.NewIntegerValue(int64(i%4)), []byte{'a' + byte(i)}))
+		}
+		require.NoError(t, idx.Set(document.NewTextValue("0"), []byte("text")))
+		require.NoError(t, idx.Delete(document.NewIntegerValue(0), []byte{'a'}))
+
+		stats, err := idx.Stats()
+		require.NoError(t, err)
+		require.Equal(t, index.Stats{Count: 12, Distinct: 5}, stats)
+		require.Equal(t, 2.4, stats.Selectivity())
+	})
+}
+
 // BenchmarkIndexSet benchmarks the Set method with 1, 10, 1000 and 10000 successive insertions.
 func BenchmarkIndexSet(b *testing.B) {
 	for size := 10; size <= 10000; size *= 10 {
@@ -462,3 +787,32 @@ func BenchmarkIndexIteration(b *testing.B) {
 		})
 	}
 }
+
+func TestIndexLookup(t *testing.T) {
+	idx, cleanup := getIndex(t, true)
+	defer cleanup()
+
+	k, err := idx.Lookup(document.NewIntegerValue(1))
+	require.NoError(t, err)
+	require.Nil(t, k)
+
+	require.NoError(t, idx.Set(document.NewIntegerValue(1), []byte("a")))
+	require.NoError(t, idx.Set(document.NewNullValue(), []byte("b")))
+
+	k, err = idx.Lookup(document.NewIntegerValue(1))
+	require.NoError(t, err)
+	require.Equal(t, []byte("a"), k)
+
+	k, err = idx.Lookup(document.NewNullValue())
+	require.NoError(t, err)
+	require.Equal(t, []byte("b"), k)
+
+	k, err = idx.Lookup(document.NewTextValue("1"))
+	require.NoError(t, err)
+	require.Nil(t, k)
+
+	nidx, cleanup := getIndex(t, false)
+	defer cleanup()
+	_, err = nidx.Lookup(document.NewIntegerValue(1))
+	require.Error(t, err)
+}
@@ -73,7 +73,9 @@ func DecodeInt64(buf []byte) (int64, error) {
 // AppendFloat64 takes an float64 and returns its binary representation.
 func AppendFloat64(buf []byte, x float64) []byte {
 	fb := math.Float64bits(x)
-	if x >= 0 {
+	// the sign bit is checked instead of the sign of x,
+	// for -0 to be ordered right before 0
+	if fb&(1<<63) == 0 {
 		fb ^= 1 << 63
 	} else {
 		fb ^= 1<<64 - 1
@@ -123,27 +125,76 @@ func DecodeBase64(data []byte) ([]byte, error) {
 }
 
 // AppendNumber takes a number value, integer or double, and encodes it in 16 bytes
-// so that encoded integers and doubles are naturally ordered.
-// Integers will fist be encoded using AppendInt64 on 8 bytes, then 8 zero-bytes will be
-// appended to them.
-// Doubles will first be converted to integer, encoded using AppendInt64,
-// then AppendFloat64 will be called with the float value.
+// so that encoded integers and doubles are ordered by value, like document.Compare orders them.
+// Integers are encoded using AppendInt64 on 8 bytes, followed by 8 zero-bytes.
+// Doubles are first rounded down to an integer, clamped to the range of int64
+// and encoded using AppendInt64, followed by the double encoded using AppendFloat64,
+// which is never zero: an integer is lower than the doubles it is rounded down from.
+// As doubles lower than math.MinInt64 are all rounded to it, math.MinInt64
+// is followed by the encoding of float64(math.MinInt64) instead, and that double
+// by the same encoding plus one, to keep them ordered.
+// NaN is greater than all the other numbers.
 func AppendNumber(buf []byte, v document.Value) ([]byte, error) {
 	if !v.Type.IsNumber() {
 		return nil, errors.New("expected number type")
 	}
 
 	if v.Type == document.IntegerValue {
-		// appending 8 zero bytes so that the integer has the same size as the double
-		// but always lower for the same value.
-		return append(AppendInt64(buf, v.V.(int64)), 0, 0, 0, 0, 0, 0, 0, 0), nil
+		x := v.V.(int64)
+		if x == math.MinInt64 {
+			return AppendFloat64(AppendInt64(buf, x), math.MinInt64), nil
+		}
+
+		return append(AppendInt64(buf, x), 0, 0, 0, 0, 0, 0, 0, 0), nil
 	}
 
 	x := v.V.(float64)
-	if x > math.MaxInt64 {
+	f := math.Floor(x)
+	switch {
+	case math.IsNaN(x):
+		// NaNs are all encoded the same way, and never as zero
+		return AppendFloat64(AppendInt64(buf, math.MaxInt64), math.NaN()), nil
+	case f >= math.MaxInt64:
+		// float64(math.MaxInt64) is 2^63, which is out of range
 		return AppendFloat64(AppendInt64(buf, math.MaxInt64), x), nil
+	case f < math.MinInt64:
+		return AppendFloat64(AppendInt64(buf, math.MinInt64), x), nil
+	case f == math.MinInt64:
+		return AppendUint64(AppendInt64(buf, math.MinInt64), minInt64Suffix+1), nil
+	}
+
+	return AppendFloat64(AppendInt64(buf, int64(f)), x), nil
+}
+
+// minInt64Suffix is the suffix of math.MinInt64 encoded by AppendNumber.
+var minInt64Suffix = binary.BigEndian.Uint64(AppendFloat64(nil, math.MinInt64))
+
+// decodeNumber decodes a number encoded by AppendNumber.
+func decodeNumber(data []byte) (document.Value, error) {
+	if len(data) < 16 {
+		return document.Value{}, errors.New("cannot decode buffer to number")
+	}
+
+	x, err := DecodeInt64(data[:8])
+	if err != nil {
+		return document.Value{}, err
+	}
+
+	suffix := binary.BigEndian.Uint64(data[8:])
+	switch {
+	case suffix == 0:
+		return document.NewIntegerValue(x), nil
+	case x == math.MinInt64 && suffix == minInt64Suffix:
+		return document.NewIntegerValue(x), nil
+	case x == math.MinInt64 && suffix == minInt64Suffix+1:
+		return document.NewDoubleValue(math.MinInt64), nil
+	}
+
+	f, err := DecodeFloat64(data[8:])
+	if err != nil {
+		return document.Value{}, err
 	}
-	return AppendFloat64(AppendInt64(buf, int64(x)), x), nil
+	return document.NewDoubleValue(f), nil
 }
 
 // AppendArray encodes an array into a sort-ordered binary representation.
@@ -381,18 +432,7 @@ func DecodeValue(data []byte) (document.Value, error) {
 	case document.BoolValue:
 		return document.NewBoolValue(DecodeBool(data)), nil
 	case document.DoubleValue:
-		if bytes.Equal(data[8:], []byte{0, 0, 0, 0, 0, 0, 0, 0}) {
-			x, err := DecodeInt64(data[:8])
-			if err != nil {
-				return document.Value{}, err
-			}
-			return document.NewIntegerValue(x), nil
-		}
-		x, err := DecodeFloat64(data[8:])
-		if err != nil {
-			return document.Value{}, err
-		}
-		return document.NewDoubleValue(x), nil
+		return decodeNumber(data)
 	case document.NullValue:
 		return document.NewNullValue(), nil
 	case document.ArrayValue:
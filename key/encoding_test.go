@@ -3,6 +3,7 @@ package key
 import (
 	"bytes"
 	"math"
+	"math/rand"
 	"sort"
 	"testing"
 
@@ -224,3 +225,86 @@ func TestOrdering(t *testing.T) {
 		}
 	})
 }
+
+// TestNumberOrdering verifies that integers and doubles encoded with AppendValue
+// are ordered like document.Compare orders them, over random and boundary values.
+func TestNumberOrdering(t *testing.T) {
+	values := []document.Value{
+		document.NewIntegerValue(0),
+		document.NewIntegerValue(1),
+		document.NewIntegerValue(-1),
+		document.NewIntegerValue(1 << 53),
+		document.NewIntegerValue(1<<53 + 1),
+		document.NewIntegerValue(math.MaxInt64),
+		document.NewIntegerValue(math.MaxInt64 - 1),
+		document.NewIntegerValue(math.MinInt64),
+		document.NewIntegerValue(math.MinInt64 + 1),
+		document.NewDoubleValue(0),
+		document.NewDoubleValue(math.Copysign(0, -1)),
+		document.NewDoubleValue(0.5),
+		document.NewDoubleValue(-0.5),
+		document.NewDoubleValue(1),
+		document.NewDoubleValue(-1),
+		document.NewDoubleValue(-1.5),
+		document.NewDoubleValue(1 << 53),
+		document.NewDoubleValue(1<<53 + 2),
+		document.NewDoubleValue(math.MaxInt64),
+		document.NewDoubleValue(math.MinInt64),
+		document.NewDoubleValue(math.Nextafter(math.MinInt64, math.Inf(-1))),
+		document.NewDoubleValue(math.Nextafter(math.MinInt64, 0)),
+		document.NewDoubleValue(math.Nextafter(math.MaxInt64, 0)),
+		document.NewDoubleValue(math.MaxFloat64),
+		document.NewDoubleValue(-math.MaxFloat64),
+		document.NewDoubleValue(math.SmallestNonzeroFloat64),
+		document.NewDoubleValue(math.Inf(1)),
+		document.NewDoubleValue(math.Inf(-1)),
+	}
+
+	rnd := rand.New(rand.NewSource(42))
+	for i := 0; i < 500; i++ {
+		switch rnd.Intn(4) {
+		case 0:
+			values = append(values, document.NewIntegerValue(rnd.Int63n(2000)-1000))
+		case 1:
+			values = append(values, document.NewIntegerValue(int64(rnd.Uint64())))
+		case 2:
+			values = append(values, document.NewDoubleValue(rnd.NormFloat64()*1000))
+		default:
+			values = append(values, document.NewDoubleValue(math.Float64frombits(rnd.Uint64())))
+		}
+	}
+
+	encoded := make([][]byte, len(values))
+	for i, v := range values {
+		if v.Type == document.DoubleValue && math.IsNaN(v.V.(float64)) {
+			v = document.NewDoubleValue(0)
+			values[i] = v
+		}
+
+		enc, err := AppendValue(nil, v)
+		require.NoError(t, err)
+		encoded[i] = enc
+
+		got, err := DecodeValue(enc)
+		require.NoError(t, err)
+		require.Equal(t, v.Type, got.Type, "%v", v)
+		c, err := document.Compare(v, got)
+		require.NoError(t, err)
+		require.Zero(t, c, "%v decoded as %v", v, got)
+	}
+
+	for i := range values {
+		for j := range values {
+			c, err := document.Compare(values[i], values[j])
+			require.NoError(t, err)
+			e := bytes.Compare(encoded[i], encoded[j])
+
+			if c != 0 {
+				require.Equal(t, c, e, "%v and %v", values[i], values[j])
+			}
+			if e == 0 {
+				require.Zero(t, c, "%v and %v", values[i], values[j])
+			}
+		}
+	}
+}
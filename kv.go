@@ -0,0 +1,73 @@
+package genji
+
+import "github.com/genjidb/genji/database"
+
+// KVStore gives access to the raw key-value pairs of a namespace of the database,
+// each method running in its own transaction.
+// Use the Store method of Tx to read and write them within the same transaction as SQL statements.
+type KVStore struct {
+	db   *DB
+	name string
+}
+
+// Store returns the key-value namespace with the given name,
+// which is created by the first write to it.
+// Namespaces are separated from the tables and are not visible by SQL statements.
+func (db *DB) Store(name string) *KVStore {
+	return &KVStore{db: db, name: name}
+}
+
+// Get returns the value associated with k.
+// If k doesn't exist, it returns database.ErrKeyNotFound.
+func (s *KVStore) Get(k []byte) ([]byte, error) {
+	var v []byte
+	err := s.db.View(func(tx *Tx) error {
+		st, err := tx.Store(s.name)
+		if err != nil {
+			return err
+		}
+
+		v, err = st.Get(k)
+		return err
+	})
+	return v, err
+}
+
+// Put associates v with k, replacing any existing value.
+func (s *KVStore) Put(k, v []byte) error {
+	return s.update(func(st *database.KVStore) error {
+		return st.Put(k, v)
+	})
+}
+
+// Delete the value associated with k.
+// If k doesn't exist, it returns database.ErrKeyNotFound.
+func (s *KVStore) Delete(k []byte) error {
+	return s.update(func(st *database.KVStore) error {
+		return st.Delete(k)
+	})
+}
+
+// Iterate calls fn for each key starting with prefix, in lexicographic order.
+// k and v are only valid until fn returns and must be copied to be kept.
+func (s *KVStore) Iterate(prefix []byte, fn func(k, v []byte) error) error {
+	return s.db.View(func(tx *Tx) error {
+		st, err := tx.Store(s.name)
+		if err != nil {
+			return err
+		}
+
+		return st.Iterate(prefix, fn)
+	})
+}
+
+func (s *KVStore) update(fn func(st *database.KVStore) error) error {
+	return s.db.Update(func(tx *Tx) error {
+		st, err := tx.Store(s.name)
+		if err != nil {
+			return err
+		}
+
+		return fn(st)
+	})
+}
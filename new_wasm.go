@@ -6,16 +6,43 @@ import (
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document/encoding/custom"
 	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/sql/parser"
 )
 
 // New initializes the DB using the given engine.
 func New(ng engine.Engine) (*DB, error) {
-	db, err := database.New(ng, database.Options{Codec: custom.NewCodec()})
+	return NewWithOptions(ng, nil)
+}
+
+// NewWithOptions initializes the DB using the given engine and options.
+// If opts is nil, default options are used.
+func NewWithOptions(ng engine.Engine, opts *Options) (*DB, error) {
+	if opts == nil {
+		opts = new(Options)
+	}
+
+	db, err := database.New(ng, database.Options{
+		Codec:               custom.NewCodec(),
+		KeyGenerator:        opts.KeyGenerator,
+		Clock:               opts.Clock,
+		BusyMode:            opts.BusyMode,
+		BusyTimeout:         opts.BusyTimeout,
+		MaxTransactionAge:   opts.MaxTransactionAge,
+		MaxTransactionIdle:  opts.MaxTransactionIdle,
+		MaxQueryMemory:      opts.MaxQueryMemory,
+		MaxRecursion:        opts.MaxRecursion,
+		StatementTimeout:    opts.StatementTimeout,
+		ScanPrefetch:        opts.ScanPrefetch,
+		MaxWriteRate:        opts.MaxWriteRate,
+		MaxStatementRate:    opts.MaxStatementRate,
+		ThrottleTimeout:     opts.ThrottleTimeout,
+		MaxInlineValueSize:  opts.MaxInlineValueSize,
+		IntegerOverflowMode: opts.IntegerOverflowMode,
+		ParseExpr:           parser.ParseConstraintExpr,
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return &DB{
-		DB: db,
-	}, nil
+	return newDB(db, opts), nil
 }
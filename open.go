@@ -12,6 +12,12 @@ import (
 // If path is equal to ":memory:" it will open an in-memory database,
 // otherwise it will create an on-disk database using the BoltDB engine.
 func Open(path string) (*DB, error) {
+	return OpenWithOptions(path, nil)
+}
+
+// OpenWithOptions works like Open but configures the database using opts.
+// If opts is nil, default options are used.
+func OpenWithOptions(path string, opts *Options) (*DB, error) {
 	var ng engine.Engine
 	var err error
 
@@ -25,5 +31,5 @@ func Open(path string) (*DB, error) {
 		return nil, err
 	}
 
-	return New(ng)
+	return NewWithOptions(ng, opts)
 }
@@ -0,0 +1,158 @@
+package genji
+
+import (
+	"context"
+	"time"
+
+	"github.com/genjidb/genji/database"
+)
+
+// scheduler runs the events of a database, created with the CREATE EVENT statement.
+// Events are run one after the other, in their own read-write transaction.
+type scheduler struct {
+	db      *DB
+	onError func(event string, err error)
+
+	cancel func()
+	done   chan struct{}
+}
+
+// scheduledEvent is an event and the next time it must be run.
+type scheduledEvent struct {
+	database.EventConfig
+	next time.Time
+}
+
+func newScheduler(db *DB, onError func(event string, err error)) *scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := scheduler{
+		db:      db,
+		onError: onError,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+
+	go s.run(ctx)
+	return &s
+}
+
+// run loads the events every time they are modified and runs them
+// when they are due, until ctx is canceled.
+// Events are first run one interval after being loaded. Runs that are missed,
+// because the previous ones took too long, are skipped.
+func (s *scheduler) run(ctx context.Context) {
+	defer close(s.done)
+
+	var events []*scheduledEvent
+	var version uint64
+	loaded := false
+
+	for {
+		// the channel must be obtained before reading the version,
+		// to be notified of the changes committed in between
+		changes := s.db.DB.TableChanges()
+
+		if v := s.db.DB.EventsVersion(); !loaded || v != version {
+			cfgs, err := s.loadEvents()
+			if err != nil {
+				s.reportError("", err)
+			} else {
+				events = mergeEvents(events, cfgs, time.Now())
+				version = v
+				loaded = true
+			}
+		}
+
+		var next time.Time
+		for _, ev := range events {
+			if !ev.next.After(time.Now()) {
+				s.reportError(ev.EventName, s.runEvent(ctx, ev))
+
+				ev.next = ev.next.Add(ev.Interval)
+				if now := time.Now(); ev.next.Before(now) {
+					ev.next = now.Add(ev.Interval)
+				}
+			}
+
+			if next.IsZero() || ev.next.Before(next) {
+				next = ev.next
+			}
+		}
+
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if !next.IsZero() {
+			timer = time.NewTimer(time.Until(next))
+			timeout = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-changes:
+		case <-timeout:
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// loadEvents reads the definitions of the events in a read-only transaction.
+func (s *scheduler) loadEvents() ([]database.EventConfig, error) {
+	tx, err := s.db.DB.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	return tx.ListEvents()
+}
+
+// runEvent runs the statement of the event in a read-write transaction.
+func (s *scheduler) runEvent(ctx context.Context, ev *scheduledEvent) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *Tx) error {
+		return tx.Exec(ctx, ev.Statement)
+	})
+}
+
+func (s *scheduler) reportError(event string, err error) {
+	if err != nil && s.onError != nil {
+		s.onError(event, err)
+	}
+}
+
+// stop cancels the running event, if any, and waits for the scheduler to return.
+func (s *scheduler) stop() {
+	s.cancel()
+	<-s.done
+}
+
+// mergeEvents returns the events defined by cfgs. Events which were already scheduled
+// with the same definition keep their next run, new ones are due one interval after now.
+func mergeEvents(events []*scheduledEvent, cfgs []database.EventConfig, now time.Time) []*scheduledEvent {
+	old := make(map[string]*scheduledEvent, len(events))
+	for _, ev := range events {
+		old[ev.EventName] = ev
+	}
+
+	merged := make([]*scheduledEvent, len(cfgs))
+	for i, cfg := range cfgs {
+		if ev, ok := old[cfg.EventName]; ok && ev.EventConfig == cfg {
+			merged[i] = ev
+			continue
+		}
+
+		merged[i] = &scheduledEvent{EventConfig: cfg, next: now.Add(cfg.Interval)}
+	}
+
+	return merged
+}
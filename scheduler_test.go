@@ -0,0 +1,146 @@
+package genji_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/planner"
+	"github.com/genjidb/genji/sql/query/expr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvents(t *testing.T) {
+	ctx := context.Background()
+
+	count := func(t *testing.T, db *genji.DB) int {
+		res, err := db.Query(ctx, "SELECT * FROM test")
+		require.NoError(t, err)
+		defer res.Close()
+
+		var n int
+		err = res.Iterate(func(d document.Document) error {
+			n++
+			return nil
+		})
+		require.NoError(t, err)
+		return n
+	}
+
+	t.Run("Run and drop", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, "CREATE TABLE test; CREATE EVENT e EVERY 10 MILLISECONDS DO INSERT INTO test (a) VALUES (1)")
+		require.NoError(t, err)
+
+		require.Eventually(t, func() bool { return count(t, db) >= 2 }, 5*time.Second, 5*time.Millisecond)
+
+		err = db.Exec(ctx, "DROP EVENT e")
+		require.NoError(t, err)
+
+		// a run may have started before the event was dropped
+		time.Sleep(50 * time.Millisecond)
+		n := count(t, db)
+		time.Sleep(50 * time.Millisecond)
+		require.Equal(t, n, count(t, db))
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		var mu sync.Mutex
+		var events []string
+
+		db, err := genji.OpenWithOptions(":memory:", &genji.Options{
+			OnEventError: func(event string, err error) {
+				mu.Lock()
+				defer mu.Unlock()
+				events = append(events, event)
+			},
+		})
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, "CREATE EVENT e EVERY 10 MILLISECONDS DO INSERT INTO unknown (a) VALUES (1)")
+		require.NoError(t, err)
+
+		require.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(events) > 0
+		}, 5*time.Second, 5*time.Millisecond)
+
+		mu.Lock()
+		require.Equal(t, "e", events[0])
+		mu.Unlock()
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		db, err := genji.OpenWithOptions(":memory:", &genji.Options{DisableEvents: true})
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, "CREATE TABLE test; CREATE EVENT e EVERY 10 MILLISECONDS DO INSERT INTO test (a) VALUES (1)")
+		require.NoError(t, err)
+
+		time.Sleep(50 * time.Millisecond)
+		require.Equal(t, 0, count(t, db))
+
+		d, err := db.QueryDocument(ctx, "SELECT interval, statement FROM __genji_events WHERE event_name = 'e'")
+		require.NoError(t, err)
+		var interval, statement string
+		require.NoError(t, document.Scan(d, &interval, &statement))
+		require.Equal(t, "10ms", interval)
+		require.Equal(t, "INSERT INTO test (a) VALUES (1)", statement)
+	})
+
+	t.Run("Rewriters", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		// only delete documents of tenant 1
+		db.AddRewriter(func(t *planner.Tree) (*planner.Tree, error) {
+			n := t.Root
+			for n.Left().Left() != nil {
+				n = n.Left()
+			}
+			n.SetLeft(planner.NewSelectionNode(n.Left(), expr.Eq(expr.FieldSelector{document.ValuePathFragment{FieldName: "tenant"}}, expr.IntegerValue(1))))
+			return t, nil
+		})
+
+		// the statements of the procedures called by events are rewritten
+		err = db.Exec(ctx, `
+			CREATE TABLE test;
+			INSERT INTO test (a, tenant) VALUES (1, 1), (2, 2);
+			CREATE PROCEDURE p() BEGIN DELETE FROM test; END;
+			CREATE EVENT e EVERY 10 MILLISECONDS DO CALL p();
+		`)
+		require.NoError(t, err)
+
+		// count ignores the rewriters
+		count := func() int {
+			var n int
+			err := db.View(func(tx *genji.Tx) error {
+				tb, err := tx.GetTable("test")
+				if err != nil {
+					return err
+				}
+
+				return tb.Iterate(func(d document.Document) error {
+					n++
+					return nil
+				})
+			})
+			require.NoError(t, err)
+			return n
+		}
+
+		require.Eventually(t, func() bool { return count() == 1 }, 5*time.Second, 5*time.Millisecond)
+		time.Sleep(50 * time.Millisecond)
+		require.Equal(t, 1, count())
+	})
+}
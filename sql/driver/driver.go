@@ -11,7 +11,6 @@ import (
 
 	"github.com/genjidb/genji"
 	"github.com/genjidb/genji/document"
-	"github.com/genjidb/genji/sql/parser"
 	"github.com/genjidb/genji/sql/planner"
 	"github.com/genjidb/genji/sql/query"
 	"github.com/genjidb/genji/sql/query/expr"
@@ -49,6 +48,16 @@ func (d sqlDriver) OpenConnector(name string) (driver.Connector, error) {
 	return c, nil
 }
 
+// NewConnector returns a connector using db, which can be passed to sql.OpenDB
+// to use a database configured with genji.OpenWithOptions, for example to restrict
+// the statements that can be run. Closing the returned *sql.DB closes db.
+func NewConnector(db *genji.DB) driver.Connector {
+	return &connector{
+		db:     db,
+		driver: sqlDriver{},
+	}
+}
+
 var (
 	_ driver.Connector = (*connector)(nil)
 	_ io.Closer        = (*connector)(nil)
@@ -92,7 +101,7 @@ func (c *conn) Prepare(q string) (driver.Stmt, error) {
 
 // PrepareContext returns a prepared statement, bound to this connection.
 func (c *conn) PrepareContext(ctx context.Context, q string) (driver.Stmt, error) {
-	pq, err := parser.ParseQuery(ctx, q)
+	pq, err := c.db.ParseQuery(ctx, q)
 	if err != nil {
 		return nil, err
 	}
@@ -224,10 +233,15 @@ type result struct {
 	*query.Result
 }
 
-// LastInsertId is not supported and returns an error.
-// Use LastInsertKey instead.
+// LastInsertId returns the primary key of the last inserted document,
+// if it is an integer, like the keys generated for tables without primary key.
+// Otherwise, it returns an error and LastInsertKey must be used instead.
 func (r result) LastInsertId() (int64, error) {
-	return driver.RowsAffected(r.Result.RowsAffected).LastInsertId()
+	if r.Result.LastInsertPK.Type != document.IntegerValue {
+		return 0, errors.New("LastInsertId is only supported for integer primary keys, use LastInsertKey instead")
+	}
+
+	return r.Result.LastInsertPK.V.(int64), nil
 }
 
 // RowsAffected returns the number of rows affected by the
@@ -269,17 +283,21 @@ func (s stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (drive
 		return rs, nil
 	}
 
-	lastStmt := s.q.Statements[len(s.q.Statements)-1]
-
-	tree, ok := lastStmt.(*planner.Tree)
-	if !ok {
-		return rs, nil
+	var fields []planner.ProjectedField
+	switch t := s.q.Statements[len(s.q.Statements)-1].(type) {
+	case *planner.Tree:
+		if pn, ok := t.Root.(*planner.ProjectionNode); ok {
+			fields = pn.Expressions
+		}
+	case query.InsertStmt:
+		// fields of the RETURNING clause
+		fields, _ = t.Returning.(planner.Returning)
 	}
 
-	if pn, ok := tree.Root.(*planner.ProjectionNode); ok && len(pn.Expressions) > 0 {
-		rs.fields = make([]string, len(pn.Expressions))
-		for i := range pn.Expressions {
-			rs.fields[i] = pn.Expressions[i].Name()
+	if len(fields) > 0 {
+		rs.fields = make([]string, len(fields))
+		for i := range fields {
+			rs.fields[i] = fields[i].Name()
 		}
 	}
 
@@ -3,8 +3,11 @@ package driver
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"testing"
 
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/engine"
 	"github.com/stretchr/testify/require"
 )
@@ -211,4 +214,88 @@ func TestDriver(t *testing.T) {
 		`)
 		require.Equal(t, err, engine.ErrTransactionReadOnly)
 	})
+
+	t.Run("Transaction statements", func(t *testing.T) {
+		conn, err := db.Conn(context.Background())
+		require.NoError(t, err)
+		defer conn.Close()
+
+		_, err = conn.ExecContext(context.Background(), "BEGIN")
+		require.NoError(t, err)
+		_, err = conn.ExecContext(context.Background(), "INSERT INTO test (a) VALUES (100)")
+		require.NoError(t, err)
+		_, err = conn.ExecContext(context.Background(), "ROLLBACK")
+		require.NoError(t, err)
+
+		var a int
+		err = conn.QueryRowContext(context.Background(), "SELECT a FROM test WHERE a = 100").Scan(&a)
+		require.Equal(t, sql.ErrNoRows, err)
+	})
+
+	t.Run("Returning", func(t *testing.T) {
+		rows, err := db.Query("CREATE TABLE returning; INSERT INTO returning (a, b) VALUES (1, 'foo'), (2, 'bar') RETURNING b, a")
+		require.NoError(t, err)
+		defer rows.Close()
+
+		columns, err := rows.Columns()
+		require.NoError(t, err)
+		require.Equal(t, []string{"b", "a"}, columns)
+
+		var count int
+		var a int
+		var b string
+		for rows.Next() {
+			err = rows.Scan(&b, &a)
+			require.NoError(t, err)
+			count++
+			require.Equal(t, count, a)
+		}
+		require.NoError(t, rows.Err())
+		require.Equal(t, 2, count)
+		require.Equal(t, "bar", b)
+	})
+}
+
+func TestNewConnector(t *testing.T) {
+	gdb, err := genji.OpenWithOptions(":memory:", &genji.Options{
+		AllowedStatements: genji.ReadOnlyStatements,
+	})
+	require.NoError(t, err)
+
+	db := sql.OpenDB(NewConnector(gdb))
+	defer db.Close()
+
+	var n int
+	err = db.QueryRow("SELECT 1").Scan(&n)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	_, err = db.Exec("CREATE TABLE test")
+	require.True(t, errors.Is(err, database.ErrStatementNotAllowed))
+}
+
+func TestResult(t *testing.T) {
+	db, err := sql.Open("genji", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE test; CREATE TABLE withpk (a TEXT PRIMARY KEY)")
+	require.NoError(t, err)
+
+	res, err := db.Exec("INSERT INTO test (a) VALUES (1), (2)")
+	require.NoError(t, err)
+	id, err := res.LastInsertId()
+	require.NoError(t, err)
+	require.EqualValues(t, 2, id)
+
+	res, err = db.Exec("UPDATE test SET b = 1")
+	require.NoError(t, err)
+	n, err := res.RowsAffected()
+	require.NoError(t, err)
+	require.EqualValues(t, 2, n)
+
+	res, err = db.Exec("INSERT INTO withpk (a) VALUES ('foo')")
+	require.NoError(t, err)
+	_, err = res.LastInsertId()
+	require.Error(t, err)
 }
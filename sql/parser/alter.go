@@ -16,8 +16,14 @@ func (p *Parser) parseAlterStatement() (query.AlterStmt, error) {
 		return stmt, newParseError(scanner.Tokstr(tok, lit), []string{"TABLE"}, pos)
 	}
 
+	// Parse IF EXISTS
+	stmt.IfExists, err = p.parseIfExists()
+	if err != nil {
+		return stmt, err
+	}
+
 	// Parse table name.
-	stmt.TableName, err = p.parseIdent()
+	stmt.TableName, err = p.parseTableName()
 	if err != nil {
 		pErr := err.(*ParseError)
 		pErr.Expected = []string{"table_name"}
@@ -16,6 +16,8 @@ func TestParserAlterTable(t *testing.T) {
 		errored  bool
 	}{
 		{"Basic", "ALTER TABLE foo RENAME TO bar", query.AlterStmt{TableName: "foo", NewTableName: "bar"}, false},
+		{"If exists", "ALTER TABLE IF EXISTS foo RENAME TO bar", query.AlterStmt{TableName: "foo", NewTableName: "bar", IfExists: true}, false},
+		{"With error / IF without EXISTS", "ALTER TABLE IF foo RENAME TO bar", query.AlterStmt{}, true},
 		{"With error / missing TABLE keyword", "ALTER foo RENAME TO bar", query.AlterStmt{}, true},
 		{"With error / two identifiers for table name", "ALTER TABLE foo baz RENAME TO bar", query.AlterStmt{}, true},
 		{"With error / two identifiers for new table name", "ALTER TABLE foo RENAME TO bar baz", query.AlterStmt{}, true},
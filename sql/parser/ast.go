@@ -0,0 +1,161 @@
+package parser
+
+import (
+	"github.com/genjidb/genji/sql/planner"
+	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// A Visitor's Visit method is invoked for each node encountered by Walk.
+// If the result visitor w is not nil, Walk visits each of the children
+// of node with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node interface{}) (w Visitor)
+}
+
+// Walk traverses the AST returned by the parser in depth-first order.
+// It starts by calling v.Visit(node); node must not be nil.
+//
+// Nodes can be a query.Query, any query.Statement, a planner.Node or an expr.Expr.
+// The nodes of a planner.Tree are visited from its input to its root,
+// followed by their expressions. The trees of subqueries are visited after
+// the subquery expressions.
+func Walk(v Visitor, node interface{}) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case query.Query:
+		for _, stmt := range n.Statements {
+			Walk(v, stmt)
+		}
+	case *planner.Tree:
+		if n.Root != nil {
+			Walk(v, n.Root)
+		}
+	case *planner.ExplainStmt:
+		Walk(v, n.Statement)
+	case query.InsertStmt:
+		for _, e := range n.Values {
+			Walk(v, e)
+		}
+	case query.CreateProcedureStmt:
+		for _, stmt := range n.Statements {
+			Walk(v, stmt)
+		}
+	case query.CreateEventStmt:
+		if n.Parsed != nil {
+			Walk(v, n.Parsed)
+		}
+	case *planner.CallStmt:
+		for _, e := range n.Args {
+			Walk(v, e)
+		}
+	case *planner.IfStmt:
+		Walk(v, n.Cond)
+		for _, stmt := range n.Then {
+			Walk(v, stmt)
+		}
+		for _, stmt := range n.Else {
+			Walk(v, stmt)
+		}
+	case *planner.ForStmt:
+		Walk(v, n.Cursor)
+		for _, stmt := range n.Body {
+			Walk(v, stmt)
+		}
+	case query.KillStmt:
+		Walk(v, n.TransactionID)
+	case query.PragmaStmt:
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+	case query.SetGlobalStmt:
+		Walk(v, n.Value)
+	case query.SetStmt:
+		Walk(v, n.Value)
+	case planner.Node:
+		if l := n.Left(); l != nil {
+			Walk(v, l)
+		}
+		if r := n.Right(); r != nil {
+			Walk(v, r)
+		}
+		for _, e := range planner.Exprs(n) {
+			Walk(v, e)
+		}
+	case expr.Operator:
+		Walk(v, n.LeftHand())
+		Walk(v, n.RightHand())
+	case expr.Parentheses:
+		Walk(v, n.E)
+	case expr.LiteralExprList:
+		for _, e := range n {
+			Walk(v, e)
+		}
+	case expr.KVPairs:
+		for _, p := range n {
+			Walk(v, p.V)
+		}
+	case expr.KeysFunc:
+		Walk(v, n.Expr)
+	case expr.ValuesFunc:
+		Walk(v, n.Expr)
+	case expr.FlattenFunc:
+		Walk(v, n.Expr)
+	case expr.CastFunc:
+		Walk(v, n.Expr)
+	case *expr.CountFunc:
+		if n.Expr != nil {
+			Walk(v, n.Expr)
+		}
+	case *expr.MinFunc:
+		Walk(v, n.Expr)
+	case *expr.MaxFunc:
+		Walk(v, n.Expr)
+	case *expr.SumFunc:
+		Walk(v, n.Expr)
+	case *expr.ApproxCountDistinctFunc:
+		Walk(v, n.Expr)
+	case *expr.ApproxPercentileFunc:
+		Walk(v, n.Expr)
+		Walk(v, n.Percentile)
+	case *expr.PivotFunc:
+		Walk(v, n.Key)
+		Walk(v, n.Value)
+	case *expr.FilterFunc:
+		Walk(v, n.Aggregate)
+		Walk(v, n.Cond)
+	case expr.Exists:
+		walkSubquery(v, n.Subquery)
+	case expr.ScalarSubquery:
+		walkSubquery(v, n.Subquery)
+	case expr.ArraySubquery:
+		walkSubquery(v, n.Subquery)
+	}
+
+	v.Visit(nil)
+}
+
+func walkSubquery(v Visitor, s expr.Subquery) {
+	if t := planner.SubqueryTree(s); t != nil {
+		Walk(v, t)
+	}
+}
+
+type inspector func(interface{}) bool
+
+func (f inspector) Visit(node interface{}) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the AST in depth-first order, like Walk.
+// It starts by calling f(node); if f returns true, Inspect invokes f
+// recursively for each of the children of node, followed by a call of f(nil).
+func Inspect(node interface{}, f func(interface{}) bool) {
+	Walk(inspector(f), node)
+}
@@ -0,0 +1,79 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/scanner"
+)
+
+// parseAttachStatement parses an attach string and returns a Statement AST object.
+// This function assumes the ATTACH token has already been consumed.
+// DATABASE is optional and not a reserved keyword.
+func (p *Parser) parseAttachStatement() (query.AttachStmt, error) {
+	var stmt query.AttachStmt
+
+	p.parseOptionalDatabase()
+
+	// Parse path
+	tok, pos, lit := p.ScanIgnoreWhitespace()
+	if tok != scanner.STRING {
+		return stmt, newParseError(scanner.Tokstr(tok, lit), []string{"string"}, pos)
+	}
+	stmt.Path = lit
+
+	// Parse "AS"
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.AS {
+		return stmt, newParseError(scanner.Tokstr(tok, lit), []string{"AS"}, pos)
+	}
+
+	var err error
+	stmt.Name, err = p.parseIdent()
+	if err != nil {
+		pErr := err.(*ParseError)
+		pErr.Expected = []string{"database_name"}
+		return stmt, pErr
+	}
+
+	// Parse optional READ ONLY or READ WRITE
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.READ {
+		p.Unscan()
+		return stmt, nil
+	}
+
+	tok, pos, lit = p.ScanIgnoreWhitespace()
+	switch tok {
+	case scanner.ONLY:
+		stmt.ReadOnly = true
+	case scanner.WRITE:
+	default:
+		return stmt, newParseError(scanner.Tokstr(tok, lit), []string{"ONLY", "WRITE"}, pos)
+	}
+
+	return stmt, nil
+}
+
+// parseDetachStatement parses a detach string and returns a Statement AST object.
+// This function assumes the DETACH token has already been consumed.
+func (p *Parser) parseDetachStatement() (query.DetachStmt, error) {
+	var stmt query.DetachStmt
+
+	p.parseOptionalDatabase()
+
+	var err error
+	stmt.Name, err = p.parseIdent()
+	if err != nil {
+		pErr := err.(*ParseError)
+		pErr.Expected = []string{"database_name"}
+		return stmt, pErr
+	}
+
+	return stmt, nil
+}
+
+// parseOptionalDatabase consumes the DATABASE keyword, if present.
+func (p *Parser) parseOptionalDatabase() {
+	if tok, _, lit := p.ScanIgnoreWhitespace(); tok != scanner.IDENT || !strings.EqualFold(lit, "DATABASE") {
+		p.Unscan()
+	}
+}
@@ -0,0 +1,41 @@
+package parser
+
+import (
+	"context"
+	"testing"
+
+	"github.com/genjidb/genji/sql/query"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParserAttach(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		expected query.Statement
+		errored  bool
+	}{
+		{"Attach", "ATTACH DATABASE 'foo.db' AS foo", query.AttachStmt{Path: "foo.db", Name: "foo"}, false},
+		{"Attach without DATABASE", "ATTACH 'foo.db' AS foo", query.AttachStmt{Path: "foo.db", Name: "foo"}, false},
+		{"Attach read only", "ATTACH DATABASE 'foo.db' AS foo READ ONLY", query.AttachStmt{Path: "foo.db", Name: "foo", ReadOnly: true}, false},
+		{"Attach read write", "ATTACH DATABASE 'foo.db' AS foo READ WRITE", query.AttachStmt{Path: "foo.db", Name: "foo"}, false},
+		{"Attach without name", "ATTACH DATABASE 'foo.db'", nil, true},
+		{"Attach without path", "ATTACH DATABASE AS foo", nil, true},
+		{"Detach", "DETACH DATABASE foo", query.DetachStmt{Name: "foo"}, false},
+		{"Detach without DATABASE", "DETACH foo", query.DetachStmt{Name: "foo"}, false},
+		{"Detach without name", "DETACH DATABASE", nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q, err := ParseQuery(context.Background(), test.s)
+			if test.errored {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, q.Statements, 1)
+			require.EqualValues(t, test.expected, q.Statements[0])
+		})
+	}
+}
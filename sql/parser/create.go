@@ -2,9 +2,12 @@ package parser
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/query/expr"
 	"github.com/genjidb/genji/sql/scanner"
 )
 
@@ -23,9 +26,17 @@ func (p *Parser) parseCreateStatement() (query.Statement, error) {
 		return p.parseCreateIndexStatement(true)
 	case scanner.INDEX:
 		return p.parseCreateIndexStatement(false)
+	case scanner.IDENT:
+		// PROCEDURE and EVENT are not reserved keywords
+		switch strings.ToUpper(lit) {
+		case "PROCEDURE":
+			return p.parseCreateProcedureStatement()
+		case "EVENT":
+			return p.parseCreateEventStatement()
+		}
 	}
 
-	return nil, newParseError(scanner.Tokstr(tok, lit), []string{"TABLE", "INDEX"}, pos)
+	return nil, newParseError(scanner.Tokstr(tok, lit), []string{"TABLE", "INDEX", "PROCEDURE", "EVENT"}, pos)
 }
 
 // parseCreateTableStatement parses a create table string and returns a Statement AST object.
@@ -41,7 +52,7 @@ func (p *Parser) parseCreateTableStatement() (query.CreateTableStmt, error) {
 	}
 
 	// Parse table name
-	stmt.TableName, err = p.parseIdent()
+	stmt.TableName, err = p.parseTableName()
 	if err != nil {
 		return stmt, err
 	}
@@ -52,9 +63,136 @@ func (p *Parser) parseCreateTableStatement() (query.CreateTableStmt, error) {
 		return stmt, err
 	}
 
+	// parse partitioning
+	stmt.Info.Partitioning, err = p.parsePartitionBy()
+	if err != nil {
+		return stmt, err
+	}
+
+	// parse storage options
+	err = p.parseWithStorageOptions(&stmt.Info)
+	if err != nil {
+		return stmt, err
+	}
+
 	return stmt, nil
 }
 
+// parseWithStorageOptions parses the optional "WITH option [, option]" clause of a CREATE TABLE statement,
+// where each option is either "COLUMNAR STORAGE", "FIELD INDEX" or "FIELD DICTIONARY".
+// COLUMNAR, STORAGE, FIELD and DICTIONARY are not reserved keywords.
+func (p *Parser) parseWithStorageOptions(info *database.TableInfo) error {
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.WITH {
+		p.Unscan()
+		return nil
+	}
+
+	for {
+		tok, pos, lit := p.ScanIgnoreWhitespace()
+		switch {
+		case tok == scanner.IDENT && strings.EqualFold(lit, "COLUMNAR"):
+			if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.IDENT || !strings.EqualFold(lit, "STORAGE") {
+				return newParseError(scanner.Tokstr(tok, lit), []string{"STORAGE"}, pos)
+			}
+			info.Columnar = true
+		case tok == scanner.IDENT && strings.EqualFold(lit, "FIELD"):
+			tok, pos, lit := p.ScanIgnoreWhitespace()
+			switch {
+			case tok == scanner.INDEX:
+				info.FieldIndex = true
+			case tok == scanner.IDENT && strings.EqualFold(lit, "DICTIONARY"):
+				info.FieldDictionary = true
+			default:
+				return newParseError(scanner.Tokstr(tok, lit), []string{"INDEX", "DICTIONARY"}, pos)
+			}
+		default:
+			return newParseError(scanner.Tokstr(tok, lit), []string{"COLUMNAR", "FIELD"}, pos)
+		}
+
+		if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.COMMA {
+			p.Unscan()
+			return nil
+		}
+	}
+}
+
+// parsePartitionBy parses the optional "PARTITION BY" clause of a CREATE TABLE statement,
+// either "PARTITION BY HASH (path) PARTITIONS n" or "PARTITION BY RANGE (path) VALUES (bound, ...)".
+// PARTITION, PARTITIONS, HASH and RANGE are not reserved keywords,
+// to allow them to be used as field names.
+func (p *Parser) parsePartitionBy() (*database.Partitioning, error) {
+	if tok, _, lit := p.ScanIgnoreWhitespace(); tok != scanner.IDENT || !strings.EqualFold(lit, "PARTITION") {
+		p.Unscan()
+		return nil, nil
+	}
+
+	// Parse "BY"
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.BY {
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"BY"}, pos)
+	}
+
+	var pt database.Partitioning
+	tok, pos, lit := p.ScanIgnoreWhitespace()
+	switch {
+	case tok == scanner.IDENT && strings.EqualFold(lit, "HASH"):
+		pt.Method = database.PartitionByHash
+	case tok == scanner.IDENT && strings.EqualFold(lit, "RANGE"):
+		pt.Method = database.PartitionByRange
+	default:
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"HASH", "RANGE"}, pos)
+	}
+
+	paths, err := p.parsePathList()
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		tok, pos, lit := p.ScanIgnoreWhitespace()
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"("}, pos)
+	}
+	if len(paths) > 1 {
+		return nil, &ParseError{Message: "partitioning on more than one path is not supported", Pos: p.s.Curr().Pos}
+	}
+	pt.Path = paths[0]
+
+	if pt.Method == database.PartitionByHash {
+		// Parse "PARTITIONS"
+		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.IDENT || !strings.EqualFold(lit, "PARTITIONS") {
+			return nil, newParseError(scanner.Tokstr(tok, lit), []string{"PARTITIONS"}, pos)
+		}
+
+		tok, pos, lit := p.ScanIgnoreWhitespace()
+		if tok != scanner.INTEGER {
+			return nil, newParseError(scanner.Tokstr(tok, lit), []string{"integer"}, pos)
+		}
+		pt.Count, err = strconv.Atoi(lit)
+		if err != nil {
+			return nil, &ParseError{Message: "unable to parse integer", Pos: pos}
+		}
+
+		return &pt, nil
+	}
+
+	// Parse "VALUES"
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.VALUES {
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"VALUES"}, pos)
+	}
+
+	list, err := p.parseExprList(scanner.LPAREN, scanner.RPAREN)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range list {
+		v, err := e.Eval(expr.EvalStack{})
+		if err != nil {
+			return nil, &ParseError{Message: fmt.Sprintf("partition bound %s must be a constant", e), Pos: p.s.Curr().Pos}
+		}
+		pt.Bounds = append(pt.Bounds, v)
+	}
+
+	return &pt, nil
+}
+
 func (p *Parser) parseIfNotExists() (bool, error) {
 	// Parse "IF"
 	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.IF {
@@ -122,7 +260,7 @@ func (p *Parser) parseFieldConstraints(info *database.TableInfo) error {
 		}
 	}
 	if pkCount > 1 {
-		return &ParseError{Message: fmt.Sprintf("only one primary key is allowed, got %d", pkCount)}
+		return &ParseError{Message: fmt.Sprintf("only one primary key is allowed, got %d", pkCount), Pos: p.s.Curr().Pos}
 	}
 
 	return nil
@@ -156,6 +294,42 @@ func (p *Parser) parseFieldConstraint(fc *database.FieldConstraint) error {
 			}
 
 			fc.IsNotNull = true
+		case scanner.UNIQUE:
+			// if it's already unique we return an error
+			if fc.IsUnique {
+				return newParseError(scanner.Tokstr(tok, lit), []string{"CONSTRAINT", ")"}, pos)
+			}
+
+			fc.IsUnique = true
+		case scanner.IDENT:
+			// CHECK and DEFAULT are not reserved keywords
+			switch {
+			case strings.EqualFold(lit, "CHECK"):
+				// if it already has a check we return an error
+				if fc.Check != nil {
+					return newParseError(scanner.Tokstr(tok, lit), []string{"CONSTRAINT", ")"}, pos)
+				}
+
+				e, err := p.parseCheck()
+				if err != nil {
+					return err
+				}
+				fc.Check = query.ConstraintExpr{Expr: e}
+			case strings.EqualFold(lit, "DEFAULT"):
+				// if it already has a default value we return an error
+				if fc.DefaultValue != nil {
+					return newParseError(scanner.Tokstr(tok, lit), []string{"CONSTRAINT", ")"}, pos)
+				}
+
+				e, err := p.parseDefault()
+				if err != nil {
+					return err
+				}
+				fc.DefaultValue = query.ConstraintExpr{Expr: e}
+			default:
+				p.Unscan()
+				return nil
+			}
 		default:
 			p.Unscan()
 			return nil
@@ -163,6 +337,73 @@ func (p *Parser) parseFieldConstraint(fc *database.FieldConstraint) error {
 	}
 }
 
+// parseCheck parses the parenthesized expression of a CHECK constraint.
+// This function assumes the CHECK token has already been consumed.
+func (p *Parser) parseCheck() (expr.Expr, error) {
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.LPAREN {
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"("}, pos)
+	}
+
+	e, _, err := p.ParseExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.RPAREN {
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{")"}, pos)
+	}
+
+	if hasParam(e) {
+		return nil, &ParseError{Message: "check constraints cannot use parameters", Pos: p.s.Curr().Pos}
+	}
+
+	return e, nil
+}
+
+// parseDefault parses the expression of a DEFAULT value, which is either
+// a literal, a function call or a parenthesized expression.
+// This function assumes the DEFAULT token has already been consumed.
+func (p *Parser) parseDefault() (expr.Expr, error) {
+	e, err := p.parseUnaryExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	// the expression is evaluated without any document
+	var invalid string
+	Inspect(e, func(n interface{}) bool {
+		switch n.(type) {
+		case expr.NamedParam, expr.PositionalParam:
+			invalid = "parameters"
+		case expr.FieldSelector:
+			invalid = "fields"
+		case expr.ScalarSubquery, expr.ArraySubquery, expr.Exists:
+			invalid = "subqueries"
+		}
+		return invalid == ""
+	})
+	if invalid != "" {
+		return nil, &ParseError{Message: "default values cannot use " + invalid, Pos: p.s.Curr().Pos}
+	}
+
+	return e, nil
+}
+
+// hasParam reports whether e uses parameters. The expressions of the field constraints
+// cannot, since they are evaluated long after the statement is run.
+func hasParam(e expr.Expr) bool {
+	var found bool
+	Inspect(e, func(n interface{}) bool {
+		switch n.(type) {
+		case expr.NamedParam, expr.PositionalParam:
+			found = true
+		}
+		return !found
+	})
+
+	return found
+}
+
 // parseCreateIndexStatement parses a create index string and returns a Statement AST object.
 // This function assumes the CREATE INDEX or CREATE UNIQUE INDEX tokens have already been consumed.
 func (p *Parser) parseCreateIndexStatement(unique bool) (query.CreateIndexStmt, error) {
@@ -171,21 +412,10 @@ func (p *Parser) parseCreateIndexStatement(unique bool) (query.CreateIndexStmt,
 		Unique: unique,
 	}
 
-	// Parse "IF"
-	if tok, _, _ := p.ScanIgnoreWhitespace(); tok == scanner.IF {
-		// Parse "NOT"
-		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.NOT {
-			return stmt, newParseError(scanner.Tokstr(tok, lit), []string{"NOT", "EXISTS"}, pos)
-		}
-
-		// Parse "EXISTS"
-		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.EXISTS {
-			return stmt, newParseError(scanner.Tokstr(tok, lit), []string{"EXISTS"}, pos)
-		}
-
-		stmt.IfNotExists = true
-	} else {
-		p.Unscan()
+	// Parse IF NOT EXISTS
+	stmt.IfNotExists, err = p.parseIfNotExists()
+	if err != nil {
+		return stmt, err
 	}
 
 	// Parse index name
@@ -200,7 +430,7 @@ func (p *Parser) parseCreateIndexStatement(unique bool) (query.CreateIndexStmt,
 	}
 
 	// Parse table name
-	stmt.TableName, err = p.parseIdent()
+	stmt.TableName, err = p.parseTableName()
 	if err != nil {
 		return stmt, err
 	}
@@ -215,7 +445,7 @@ func (p *Parser) parseCreateIndexStatement(unique bool) (query.CreateIndexStmt,
 	}
 
 	if len(paths) != 1 {
-		return stmt, &ParseError{Message: "indexes on more than one path are not supported"}
+		return stmt, &ParseError{Message: "indexes on more than one path are not supported", Pos: p.s.Curr().Pos}
 	}
 
 	stmt.Path = paths[0]
@@ -7,6 +7,7 @@ import (
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/query/expr"
 	"github.com/stretchr/testify/require"
 )
 
@@ -88,6 +89,65 @@ func TestParserCreateTable(t *testing.T) {
 					},
 				},
 			}, false},
+		{"With check", "CREATE TABLE test(age INTEGER NOT NULL CHECK (age >= 0 AND age < 150), name check(name != ''))",
+			query.CreateTableStmt{
+				TableName: "test",
+				Info: database.TableInfo{
+					FieldConstraints: []database.FieldConstraint{
+						{Path: parsePath(t, "age"), Type: document.IntegerValue, IsNotNull: true, Check: query.ConstraintExpr{
+							Expr: expr.And(
+								expr.Gte(expr.FieldSelector(parsePath(t, "age")), expr.IntegerValue(0)),
+								expr.Lt(expr.FieldSelector(parsePath(t, "age")), expr.IntegerValue(150)),
+							),
+						}},
+						{Path: parsePath(t, "name"), Check: query.ConstraintExpr{
+							Expr: expr.Neq(expr.FieldSelector(parsePath(t, "name")), expr.TextValue("")),
+						}},
+					},
+				},
+			}, false},
+		{"With check twice", "CREATE TABLE test(foo CHECK (foo > 0) CHECK (foo < 10))",
+			query.CreateTableStmt{}, true},
+		{"With check without parentheses", "CREATE TABLE test(foo CHECK foo > 0)",
+			query.CreateTableStmt{}, true},
+		{"With check using params", "CREATE TABLE test(foo CHECK (foo > ?))",
+			query.CreateTableStmt{}, true},
+		{"With default", "CREATE TABLE test(a INTEGER DEFAULT -1 NOT NULL, b default 'x', c DEFAULT NOW(), d DEFAULT (1 + 2))",
+			query.CreateTableStmt{
+				TableName: "test",
+				Info: database.TableInfo{
+					FieldConstraints: []database.FieldConstraint{
+						{Path: parsePath(t, "a"), Type: document.IntegerValue, IsNotNull: true, DefaultValue: query.ConstraintExpr{Expr: expr.IntegerValue(-1)}},
+						{Path: parsePath(t, "b"), DefaultValue: query.ConstraintExpr{Expr: expr.TextValue("x")}},
+						{Path: parsePath(t, "c"), DefaultValue: query.ConstraintExpr{Expr: new(expr.NowFunc)}},
+						{Path: parsePath(t, "d"), DefaultValue: query.ConstraintExpr{
+							Expr: expr.Parentheses{E: expr.Add(expr.IntegerValue(1), expr.IntegerValue(2))},
+						}},
+					},
+				},
+			}, false},
+		{"With unique", "CREATE TABLE test(foo INTEGER UNIQUE NOT NULL, bar.baz unique)",
+			query.CreateTableStmt{
+				TableName: "test",
+				Info: database.TableInfo{
+					FieldConstraints: []database.FieldConstraint{
+						{Path: parsePath(t, "foo"), Type: document.IntegerValue, IsNotNull: true, IsUnique: true},
+						{Path: parsePath(t, "bar.baz"), IsUnique: true},
+					},
+				},
+			}, false},
+		{"With unique twice", "CREATE TABLE test(foo UNIQUE UNIQUE)",
+			query.CreateTableStmt{}, true},
+		{"With default twice", "CREATE TABLE test(foo DEFAULT 1 DEFAULT 2)",
+			query.CreateTableStmt{}, true},
+		{"With default without value", "CREATE TABLE test(foo DEFAULT)",
+			query.CreateTableStmt{}, true},
+		{"With default using params", "CREATE TABLE test(foo DEFAULT ?)",
+			query.CreateTableStmt{}, true},
+		{"With default using fields", "CREATE TABLE test(foo DEFAULT (bar + 1))",
+			query.CreateTableStmt{}, true},
+		{"With default using subqueries", "CREATE TABLE test(foo DEFAULT (SELECT 1))",
+			query.CreateTableStmt{}, true},
 		{"With multiple primary keys", "CREATE TABLE test(foo PRIMARY KEY, bar PRIMARY KEY)",
 			query.CreateTableStmt{}, true},
 		{"With all supported fixed size data types",
@@ -116,6 +176,64 @@ func TestParserCreateTable(t *testing.T) {
 					},
 				},
 			}, false},
+		{"With hash partitioning", "CREATE TABLE test(foo INTEGER PRIMARY KEY) PARTITION BY HASH (foo) PARTITIONS 4",
+			query.CreateTableStmt{
+				TableName: "test",
+				Info: database.TableInfo{
+					FieldConstraints: []database.FieldConstraint{
+						{Path: parsePath(t, "foo"), Type: document.IntegerValue, IsPrimaryKey: true},
+					},
+					Partitioning: &database.Partitioning{
+						Method: database.PartitionByHash,
+						Path:   parsePath(t, "foo"),
+						Count:  4,
+					},
+				},
+			}, false},
+		{"With range partitioning", "CREATE TABLE test(foo INTEGER PRIMARY KEY) PARTITION BY RANGE (foo) VALUES (-10, 10 * 2)",
+			query.CreateTableStmt{
+				TableName: "test",
+				Info: database.TableInfo{
+					FieldConstraints: []database.FieldConstraint{
+						{Path: parsePath(t, "foo"), Type: document.IntegerValue, IsPrimaryKey: true},
+					},
+					Partitioning: &database.Partitioning{
+						Method: database.PartitionByRange,
+						Path:   parsePath(t, "foo"),
+						Bounds: []document.Value{document.NewIntegerValue(-10), document.NewIntegerValue(20)},
+					},
+				},
+			}, false},
+		{"With partitioning and no path", "CREATE TABLE test(foo PRIMARY KEY) PARTITION BY HASH PARTITIONS 4",
+			query.CreateTableStmt{}, true},
+		{"With partitioning on multiple paths", "CREATE TABLE test(foo PRIMARY KEY) PARTITION BY HASH (foo, bar) PARTITIONS 4",
+			query.CreateTableStmt{}, true},
+		{"With unknown partitioning method", "CREATE TABLE test(foo PRIMARY KEY) PARTITION BY LIST (foo)",
+			query.CreateTableStmt{}, true},
+		{"With non constant bound", "CREATE TABLE test(foo PRIMARY KEY) PARTITION BY RANGE (foo) VALUES (bar)",
+			query.CreateTableStmt{}, true},
+		{"With columnar storage", "CREATE TABLE test(foo INTEGER) WITH COLUMNAR STORAGE",
+			query.CreateTableStmt{
+				TableName: "test",
+				Info: database.TableInfo{
+					FieldConstraints: []database.FieldConstraint{
+						{Path: parsePath(t, "foo"), Type: document.IntegerValue},
+					},
+					Columnar: true,
+				},
+			}, false},
+		{"With columnar storage and no field constraints", "CREATE TABLE test with columnar storage",
+			query.CreateTableStmt{TableName: "test", Info: database.TableInfo{Columnar: true}}, false},
+		{"With unknown storage", "CREATE TABLE test WITH ROW STORAGE", query.CreateTableStmt{}, true},
+		{"With incomplete storage", "CREATE TABLE test WITH COLUMNAR", query.CreateTableStmt{}, true},
+		{"With field index", "CREATE TABLE test WITH FIELD INDEX",
+			query.CreateTableStmt{TableName: "test", Info: database.TableInfo{FieldIndex: true}}, false},
+		{"With several options", "CREATE TABLE test WITH field index, COLUMNAR STORAGE",
+			query.CreateTableStmt{TableName: "test", Info: database.TableInfo{Columnar: true, FieldIndex: true}}, false},
+		{"With trailing comma", "CREATE TABLE test WITH FIELD INDEX,", query.CreateTableStmt{}, true},
+		{"With incomplete field index", "CREATE TABLE test WITH FIELD", query.CreateTableStmt{}, true},
+		{"With field dictionary", "CREATE TABLE test WITH FIELD DICTIONARY, field index",
+			query.CreateTableStmt{TableName: "test", Info: database.TableInfo{FieldDictionary: true, FieldIndex: true}}, false},
 	}
 
 	for _, test := range tests {
@@ -142,6 +260,7 @@ func TestParserCreateIndex(t *testing.T) {
 		{"Basic", "CREATE INDEX idx ON test (foo)", query.CreateIndexStmt{IndexName: "idx", TableName: "test", Path: parsePath(t, "foo")}, false},
 		{"If not exists", "CREATE INDEX IF NOT EXISTS idx ON test (foo.bar[1])", query.CreateIndexStmt{IndexName: "idx", TableName: "test", Path: parsePath(t, "foo.bar[1]"), IfNotExists: true}, false},
 		{"Unique", "CREATE UNIQUE INDEX IF NOT EXISTS idx ON test (foo[3].baz)", query.CreateIndexStmt{IndexName: "idx", TableName: "test", Path: parsePath(t, "foo[3].baz"), IfNotExists: true, Unique: true}, false},
+		{"If without not exists", "CREATE INDEX IF idx ON test (foo)", nil, true},
 		{"No fields", "CREATE INDEX idx ON test", nil, true},
 		{"More than 1 path", "CREATE INDEX idx ON test (foo, bar)", nil, true},
 	}
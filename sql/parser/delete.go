@@ -18,12 +18,13 @@ func (p *Parser) parseDeleteStatement() (*planner.Tree, error) {
 	}
 
 	// Parse table name
-	cfg.TableName, err = p.parseIdent()
+	cfg.TableName, err = p.parseTableName()
 	if err != nil {
 		pErr := err.(*ParseError)
 		pErr.Expected = []string{"table_name"}
 		return nil, pErr
 	}
+	defer p.enterTable(cfg.TableName)()
 
 	// Parse condition: "WHERE EXPR".
 	cfg.WhereExpr, err = p.parseCondition()
@@ -31,6 +32,12 @@ func (p *Parser) parseDeleteStatement() (*planner.Tree, error) {
 		return nil, err
 	}
 
+	// Parse "RETURNING fields"
+	cfg.Returning, err = p.parseReturningClause()
+	if err != nil {
+		return nil, err
+	}
+
 	return cfg.ToTree(), nil
 }
 
@@ -38,6 +45,7 @@ func (p *Parser) parseDeleteStatement() (*planner.Tree, error) {
 type deleteConfig struct {
 	TableName string
 	WhereExpr expr.Expr
+	Returning []planner.ProjectedField
 }
 
 // ToTree turns the statement into an expression tree.
@@ -50,5 +58,9 @@ func (cfg deleteConfig) ToTree() *planner.Tree {
 
 	t = planner.NewDeletionNode(t, cfg.TableName)
 
+	if cfg.Returning != nil {
+		t = planner.NewReturningNode(t, cfg.Returning, cfg.TableName)
+	}
+
 	return &planner.Tree{Root: t}
 }
@@ -25,6 +25,18 @@ func TestParserDelete(t *testing.T) {
 					planner.NewTableInputNode("test"),
 					expr.Eq(expr.FieldSelector(parsePath(t, "age")), expr.IntegerValue(10))),
 				"test"))},
+		{"Returning", "DELETE FROM test WHERE age = 10 RETURNING pk(), *",
+			planner.NewTree(planner.NewReturningNode(
+				planner.NewDeletionNode(
+					planner.NewSelectionNode(
+						planner.NewTableInputNode("test"),
+						expr.Eq(expr.FieldSelector(parsePath(t, "age")), expr.IntegerValue(10))),
+					"test"),
+				[]planner.ProjectedField{
+					planner.ProjectedExpr{Expr: new(expr.PKFunc), ExprName: "pk()"},
+					planner.Wildcard{},
+				},
+				"test"))},
 	}
 
 	for _, test := range tests {
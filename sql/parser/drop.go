@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"strings"
+
 	"github.com/genjidb/genji/sql/query"
 	"github.com/genjidb/genji/sql/scanner"
 )
@@ -14,9 +16,17 @@ func (p *Parser) parseDropStatement() (query.Statement, error) {
 		return p.parseDropTableStatement()
 	case scanner.INDEX:
 		return p.parseDropIndexStatement()
+	case scanner.IDENT:
+		// PROCEDURE and EVENT are not reserved keywords
+		switch strings.ToUpper(lit) {
+		case "PROCEDURE":
+			return p.parseDropProcedureStatement()
+		case "EVENT":
+			return p.parseDropEventStatement()
+		}
 	}
 
-	return nil, newParseError(scanner.Tokstr(tok, lit), []string{"TABLE", "INDEX"}, pos)
+	return nil, newParseError(scanner.Tokstr(tok, lit), []string{"TABLE", "INDEX", "PROCEDURE", "EVENT"}, pos)
 }
 
 // parseDropTableStatement parses a drop table string and returns a Statement AST object.
@@ -25,19 +35,14 @@ func (p *Parser) parseDropTableStatement() (query.DropTableStmt, error) {
 	var stmt query.DropTableStmt
 	var err error
 
-	// Parse "IF"
-	if tok, _, _ := p.ScanIgnoreWhitespace(); tok == scanner.IF {
-		// Parse "EXISTS"
-		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.EXISTS {
-			return stmt, newParseError(scanner.Tokstr(tok, lit), []string{"EXISTS"}, pos)
-		}
-		stmt.IfExists = true
-	} else {
-		p.Unscan()
+	// Parse IF EXISTS
+	stmt.IfExists, err = p.parseIfExists()
+	if err != nil {
+		return stmt, err
 	}
 
 	// Parse table name
-	stmt.TableName, err = p.parseIdent()
+	stmt.TableName, err = p.parseTableName()
 	if err != nil {
 		pErr := err.(*ParseError)
 		pErr.Expected = []string{"table_name"}
@@ -53,19 +58,14 @@ func (p *Parser) parseDropIndexStatement() (query.DropIndexStmt, error) {
 	var stmt query.DropIndexStmt
 	var err error
 
-	// Parse "IF"
-	if tok, _, _ := p.ScanIgnoreWhitespace(); tok == scanner.IF {
-		// Parse "EXISTS"
-		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.EXISTS {
-			return stmt, newParseError(scanner.Tokstr(tok, lit), []string{"EXISTS"}, pos)
-		}
-		stmt.IfExists = true
-	} else {
-		p.Unscan()
+	// Parse IF EXISTS
+	stmt.IfExists, err = p.parseIfExists()
+	if err != nil {
+		return stmt, err
 	}
 
 	// Parse index name
-	stmt.IndexName, err = p.parseIdent()
+	stmt.IndexName, err = p.parseTableName()
 	if err != nil {
 		pErr := err.(*ParseError)
 		pErr.Expected = []string{"index_name"}
@@ -74,3 +74,18 @@ func (p *Parser) parseDropIndexStatement() (query.DropIndexStmt, error) {
 
 	return stmt, nil
 }
+
+func (p *Parser) parseIfExists() (bool, error) {
+	// Parse "IF"
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.IF {
+		p.Unscan()
+		return false, nil
+	}
+
+	// Parse "EXISTS"
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.EXISTS {
+		return false, newParseError(scanner.Tokstr(tok, lit), []string{"EXISTS"}, pos)
+	}
+
+	return true, nil
+}
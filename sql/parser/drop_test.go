@@ -19,6 +19,8 @@ func TestParserDrop(t *testing.T) {
 		{"Drop table If not exists", "DROP TABLE IF EXISTS test", query.DropTableStmt{TableName: "test", IfExists: true}, false},
 		{"Drop index", "DROP INDEX test", query.DropIndexStmt{IndexName: "test"}, false},
 		{"Drop index if exists", "DROP INDEX IF EXISTS test", query.DropIndexStmt{IndexName: "test", IfExists: true}, false},
+		{"Drop table IF without EXISTS", "DROP TABLE IF test", nil, true},
+		{"Drop index IF NOT EXISTS", "DROP INDEX IF NOT EXISTS test", nil, true},
 	}
 
 	for _, test := range tests {
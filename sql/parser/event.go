@@ -0,0 +1,166 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/query/expr"
+	"github.com/genjidb/genji/sql/scanner"
+)
+
+// intervalUnits are the units of the intervals of events, from the largest to the smallest.
+var intervalUnits = []struct {
+	name string
+	d    time.Duration
+}{
+	{"DAY", 24 * time.Hour},
+	{"HOUR", time.Hour},
+	{"MINUTE", time.Minute},
+	{"SECOND", time.Second},
+	{"MILLISECOND", time.Millisecond},
+}
+
+// parseCreateEventStatement parses "name EVERY n unit DO statement"
+// and returns a Statement AST object.
+// This function assumes the CREATE EVENT tokens have already been consumed.
+// EVERY and the units are not reserved keywords.
+func (p *Parser) parseCreateEventStatement() (query.CreateEventStmt, error) {
+	var stmt query.CreateEventStmt
+	var err error
+
+	// Parse IF NOT EXISTS
+	stmt.IfNotExists, err = p.parseIfNotExists()
+	if err != nil {
+		return stmt, err
+	}
+
+	stmt.EventName, err = p.parseIdent()
+	if err != nil {
+		pErr := err.(*ParseError)
+		pErr.Expected = []string{"event_name"}
+		return stmt, pErr
+	}
+
+	if !p.parseOptionalKeyword("EVERY") {
+		tok, pos, lit := p.ScanIgnoreWhitespace()
+		return stmt, newParseError(scanner.Tokstr(tok, lit), []string{"EVERY"}, pos)
+	}
+
+	stmt.Interval, err = p.parseInterval()
+	if err != nil {
+		return stmt, err
+	}
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.DO {
+		return stmt, newParseError(scanner.Tokstr(tok, lit), []string{"DO"}, pos)
+	}
+
+	tok, pos, lit := p.ScanIgnoreWhitespace()
+	switch tok {
+	case scanner.BEGIN, scanner.COMMIT, scanner.ROLLBACK, scanner.SAVEPOINT, scanner.RELEASE:
+		return stmt, &ParseError{Message: "transactions can't be controlled by an event", Pos: pos}
+	case scanner.EOF:
+		return stmt, newParseError(scanner.Tokstr(tok, lit), []string{"statement"}, pos)
+	}
+	p.Unscan()
+
+	stmt.Parsed, err = p.parseStatement()
+	if err != nil {
+		return stmt, err
+	}
+
+	// events are run without parameters
+	var hasParams bool
+	Inspect(stmt.Parsed, func(n interface{}) bool {
+		switch n.(type) {
+		case expr.NamedParam, expr.PositionalParam:
+			hasParams = true
+		case query.CreateProcedureStmt:
+			// the body of a procedure references its own parameters
+			return false
+		}
+		return !hasParams
+	})
+	if hasParams {
+		return stmt, &ParseError{Message: "parameters can't be used by an event", Pos: pos}
+	}
+
+	stmt.Statement = Format(stmt.Parsed)
+	return stmt, nil
+}
+
+// parseInterval parses "n unit", where n is a positive integer and unit
+// one of the intervalUnits, optionally in the plural.
+func (p *Parser) parseInterval() (time.Duration, error) {
+	tok, pos, lit := p.ScanIgnoreWhitespace()
+	if tok != scanner.INTEGER {
+		return 0, newParseError(scanner.Tokstr(tok, lit), []string{"integer"}, pos)
+	}
+
+	n, err := strconv.ParseInt(lit, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, &ParseError{Message: "interval must be a positive integer", Pos: pos}
+	}
+
+	tok, pos, lit = p.ScanIgnoreWhitespace()
+	if tok == scanner.IDENT {
+		name := strings.TrimSuffix(strings.ToUpper(lit), "S")
+		for _, u := range intervalUnits {
+			if name == u.name {
+				if time.Duration(n) > time.Duration(1<<63-1)/u.d {
+					return 0, &ParseError{Message: "interval is too large", Pos: pos}
+				}
+
+				return time.Duration(n) * u.d, nil
+			}
+		}
+	}
+
+	expected := make([]string, len(intervalUnits))
+	for i, u := range intervalUnits {
+		expected[i] = u.name
+	}
+	return 0, newParseError(scanner.Tokstr(tok, lit), expected, pos)
+}
+
+// formatInterval returns the SQL representation of d, using the largest unit dividing it.
+func formatInterval(d time.Duration) string {
+	for _, u := range intervalUnits {
+		if d%u.d != 0 {
+			continue
+		}
+
+		n := d / u.d
+		if n == 1 {
+			return "1 " + u.name
+		}
+		return strconv.FormatInt(int64(n), 10) + " " + u.name + "S"
+	}
+
+	// intervals are always a multiple of a millisecond when parsed
+	return strconv.FormatInt(d.Milliseconds(), 10) + " MILLISECONDS"
+}
+
+// parseDropEventStatement parses a drop event string and returns a Statement AST object.
+// This function assumes the DROP EVENT tokens have already been consumed.
+func (p *Parser) parseDropEventStatement() (query.DropEventStmt, error) {
+	var stmt query.DropEventStmt
+	var err error
+
+	// Parse IF EXISTS
+	stmt.IfExists, err = p.parseIfExists()
+	if err != nil {
+		return stmt, err
+	}
+
+	stmt.EventName, err = p.parseIdent()
+	if err != nil {
+		pErr := err.(*ParseError)
+		pErr.Expected = []string{"event_name"}
+		return stmt, pErr
+	}
+
+	return stmt, nil
+}
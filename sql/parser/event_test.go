@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/genjidb/genji/sql/query"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParserCreateEvent(t *testing.T) {
+	tests := []struct {
+		name        string
+		s           string
+		ifNotExists bool
+		interval    time.Duration
+		statement   string
+		errored     bool
+	}{
+		{"Seconds", "CREATE EVENT e EVERY 10 SECONDS DO DELETE FROM test WHERE a < 10", false, 10 * time.Second, "DELETE FROM test WHERE a < 10", false},
+		{"Singular", "CREATE EVENT e EVERY 1 DAY DO DELETE FROM test", false, 24 * time.Hour, "DELETE FROM test", false},
+		{"Case insensitive", "create event if not exists e every 5 minutes do delete from test", true, 5 * time.Minute, "DELETE FROM test", false},
+		{"Milliseconds", "CREATE EVENT e EVERY 250 MILLISECONDS DO CALL p()", false, 250 * time.Millisecond, "CALL p()", false},
+		{"Procedure", "CREATE EVENT e EVERY 1 HOUR DO CREATE PROCEDURE p(a) BEGIN SELECT $a; END", false, time.Hour, "CREATE PROCEDURE p(a) BEGIN SELECT $a; END", false},
+		{"Missing EVERY", "CREATE EVENT e 1 HOUR DO DELETE FROM test", false, 0, "", true},
+		{"Missing DO", "CREATE EVENT e EVERY 1 HOUR DELETE FROM test", false, 0, "", true},
+		{"Missing statement", "CREATE EVENT e EVERY 1 HOUR DO", false, 0, "", true},
+		{"Unknown unit", "CREATE EVENT e EVERY 1 WEEK DO DELETE FROM test", false, 0, "", true},
+		{"Zero interval", "CREATE EVENT e EVERY 0 SECONDS DO DELETE FROM test", false, 0, "", true},
+		{"Negative interval", "CREATE EVENT e EVERY -1 SECONDS DO DELETE FROM test", false, 0, "", true},
+		{"Too large interval", "CREATE EVENT e EVERY 9223372036854775807 DAYS DO DELETE FROM test", false, 0, "", true},
+		{"Float interval", "CREATE EVENT e EVERY 1.5 HOURS DO DELETE FROM test", false, 0, "", true},
+		{"Named params", "CREATE EVENT e EVERY 1 HOUR DO DELETE FROM test WHERE a = $a", false, 0, "", true},
+		{"Positional params", "CREATE EVENT e EVERY 1 HOUR DO DELETE FROM test WHERE a = ?", false, 0, "", true},
+		{"Transaction control", "CREATE EVENT e EVERY 1 HOUR DO BEGIN", false, 0, "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q, err := ParseQuery(context.Background(), test.s)
+			if test.errored {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, q.Statements, 1)
+
+			stmt, ok := q.Statements[0].(query.CreateEventStmt)
+			require.True(t, ok)
+			require.Equal(t, "e", stmt.EventName)
+			require.Equal(t, test.ifNotExists, stmt.IfNotExists)
+			require.Equal(t, test.interval, stmt.Interval)
+			require.Equal(t, test.statement, stmt.Statement)
+			require.NotNil(t, stmt.Parsed)
+		})
+	}
+}
+
+func TestParserDropEvent(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		expected query.Statement
+		errored  bool
+	}{
+		{"Drop event", "DROP EVENT e", query.DropEventStmt{EventName: "e"}, false},
+		{"Drop event if exists", "DROP EVENT IF EXISTS e", query.DropEventStmt{EventName: "e", IfExists: true}, false},
+		{"Missing name", "DROP EVENT", nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q, err := ParseQuery(context.Background(), test.s)
+			if test.errored {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, q.Statements, 1)
+			require.EqualValues(t, test.expected, q.Statements[0])
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package parser
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/genjidb/genji/sql/planner"
+	"github.com/genjidb/genji/sql/query/expr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParserExists(t *testing.T) {
+	sub := func(name string) expr.Subquery {
+		return planner.NewSubquery(planner.NewTree(
+			planner.NewProjectionNode(
+				planner.NewSelectionNode(planner.NewTableInputNode("b"),
+					expr.Eq(expr.FieldSelector(parsePath(t, "x")), expr.FieldSelector(parsePath(t, "a.x"))),
+				),
+				[]planner.ProjectedField{planner.Wildcard{}},
+				"b",
+			)), name)
+	}
+
+	tests := []struct {
+		name     string
+		s        string
+		expected expr.Expr
+		errored  bool
+	}{
+		{"Exists", "EXISTS (SELECT * FROM b WHERE x = a.x)", expr.Exists{Subquery: sub("")}, false},
+		{"Not exists", "NOT EXISTS (SELECT * FROM b WHERE x = a.x)", expr.Exists{Subquery: sub(""), Not: true}, false},
+		{"Operand", "y AND EXISTS (SELECT * FROM b WHERE x = a.x)", expr.And(expr.FieldSelector(parsePath(t, "y")), expr.Exists{Subquery: sub("")}), false},
+		{"Without parentheses", "EXISTS SELECT * FROM b", nil, true},
+		{"Not a select", "EXISTS (DELETE FROM b)", nil, true},
+		{"Unclosed", "EXISTS (SELECT * FROM b", nil, true},
+		{"Not without exists", "NOT x", nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e, _, err := NewParser(strings.NewReader(test.s)).ParseExpr()
+			if test.errored {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.EqualValues(t, test.expected, e)
+		})
+	}
+
+	t.Run("Enclosing table", func(t *testing.T) {
+		q, err := ParseQuery(context.Background(), "SELECT EXISTS (SELECT * FROM b WHERE x = a.x) AS e FROM a WHERE EXISTS (SELECT * FROM b WHERE x = a.x)")
+		require.NoError(t, err)
+
+		expected := planner.NewTree(
+			planner.NewProjectionNode(
+				planner.NewSelectionNode(planner.NewTableInputNode("a"), expr.Exists{Subquery: sub("a")}),
+				[]planner.ProjectedField{planner.ProjectedExpr{Expr: expr.Exists{Subquery: sub("a")}, ExprName: "e"}},
+				"a",
+			))
+		require.EqualValues(t, expected, q.Statements[0])
+	})
+}
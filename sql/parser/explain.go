@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"strings"
+
 	"github.com/genjidb/genji/sql/planner"
 	"github.com/genjidb/genji/sql/query"
 	"github.com/genjidb/genji/sql/scanner"
@@ -9,6 +11,22 @@ import (
 // parseExplainStatement parses any statement and returns an ExplainStmt object.
 // This function assumes the EXPLAIN token has already been consumed.
 func (p *Parser) parseExplainStatement() (query.Statement, error) {
+	var stmt planner.ExplainStmt
+
+	// parse optional ANALYZE keyword
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok == scanner.ANALYZE {
+		stmt.Analyze = true
+	} else {
+		p.Unscan()
+	}
+
+	// parse optional NODES keyword, which is not reserved
+	if tok, _, lit := p.ScanIgnoreWhitespace(); tok == scanner.IDENT && strings.EqualFold(lit, "NODES") {
+		stmt.Nodes = true
+	} else {
+		p.Unscan()
+	}
+
 	// ensure we don't have multiple EXPLAIN keywords
 	tok, pos, lit := p.ScanIgnoreWhitespace()
 	if tok == scanner.EXPLAIN {
@@ -21,5 +39,6 @@ func (p *Parser) parseExplainStatement() (query.Statement, error) {
 		return nil, err
 	}
 
-	return &planner.ExplainStmt{Statement: innerStmt}, nil
+	stmt.Statement = innerStmt
+	return &stmt, nil
 }
@@ -17,7 +17,11 @@ func TestParserExplain(t *testing.T) {
 		errored  bool
 	}{
 		{"Explain create table", "EXPLAIN CREATE TABLE test", &planner.ExplainStmt{Statement: query.CreateTableStmt{TableName: "test"}}, false},
+		{"Explain analyze", "EXPLAIN ANALYZE CREATE TABLE test", &planner.ExplainStmt{Statement: query.CreateTableStmt{TableName: "test"}, Analyze: true}, false},
+		{"Explain nodes", "EXPLAIN nodes CREATE TABLE test", &planner.ExplainStmt{Statement: query.CreateTableStmt{TableName: "test"}, Nodes: true}, false},
+		{"Explain analyze nodes", "EXPLAIN ANALYZE NODES CREATE TABLE test", &planner.ExplainStmt{Statement: query.CreateTableStmt{TableName: "test"}, Analyze: true, Nodes: true}, false},
 		{"Multiple Explains", "EXPLAIN EXPLAIN CREATE TABLE test", nil, true},
+		{"Explain analyze explain", "EXPLAIN ANALYZE EXPLAIN CREATE TABLE test", nil, true},
 	}
 
 	for _, test := range tests {
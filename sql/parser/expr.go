@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/planner"
 	"github.com/genjidb/genji/sql/query/expr"
 	"github.com/genjidb/genji/sql/scanner"
 )
@@ -33,6 +34,15 @@ func (p *Parser) ParseExpr() (e expr.Expr, lit string, err error) {
 		defer func() { p.buf = nil }()
 	}
 
+	p.depth++
+	defer func() { p.depth-- }()
+	if max := p.opts.Limits.MaxDepth; max > 0 && p.depth > max {
+		_, pos, _ := p.ScanIgnoreWhitespace()
+		p.Unscan()
+		p.limitErr = &ParseError{Message: fmt.Sprintf("expression exceeds the maximum nesting depth of %d", max), Pos: pos}
+		return nil, "", p.limitErr
+	}
+
 	// Dummy root node.
 	var root expr.Operator = new(dummyOperator)
 
@@ -61,6 +71,11 @@ func (p *Parser) ParseExpr() (e expr.Expr, lit string, err error) {
 			return nil, "", err
 		}
 
+		// IN (SELECT ...) reads all the values returned by the subquery
+		if sq, ok := rhs.(expr.ScalarSubquery); ok && (tok == scanner.IN || tok == scanner.NOT) {
+			rhs = expr.ArraySubquery{Subquery: sq.Subquery}
+		}
+
 		// Find the right spot in the tree to add the new expression by
 		// descending the RHS of the expression tree until we reach the last
 		// BinaryExpr or a BinaryExpr whose RHS has an operator with
@@ -142,6 +157,14 @@ func (p *Parser) parseUnaryExpr() (expr.Expr, error) {
 	case scanner.CAST:
 		p.Unscan()
 		return p.parseCastExpression()
+	case scanner.EXISTS:
+		return p.parseExists(false)
+	case scanner.NOT:
+		// NOT is only allowed as a unary operator before EXISTS
+		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.EXISTS {
+			return nil, newParseError(scanner.Tokstr(tok, lit), []string{"EXISTS"}, pos)
+		}
+		return p.parseExists(true)
 	case scanner.IDENT:
 		// if the next token is a left parenthesis, this is a function
 		if tok1, _, _ := p.Scan(); tok1 == scanner.LPAREN {
@@ -157,18 +180,21 @@ func (p *Parser) parseUnaryExpr() (expr.Expr, error) {
 		}
 		fs := expr.FieldSelector(field)
 		return fs, nil
+	case scanner.VALUES:
+		// VALUES is a keyword but also the name of a function
+		return p.parseFunctionCall("VALUES")
 	case scanner.NAMEDPARAM:
 		if len(lit) == 1 {
-			return nil, &ParseError{Message: "missing param name"}
+			return nil, &ParseError{Message: "missing param name", Pos: pos}
 		}
 		if p.orderedParams > 0 {
-			return nil, &ParseError{Message: "cannot mix positional arguments with named arguments"}
+			return nil, &ParseError{Message: "cannot mix positional arguments with named arguments", Pos: pos}
 		}
 		p.namedParams++
 		return expr.NamedParam(lit[1:]), nil
 	case scanner.POSITIONALPARAM:
 		if p.namedParams > 0 {
-			return nil, &ParseError{Message: "cannot mix positional arguments with named arguments"}
+			return nil, &ParseError{Message: "cannot mix positional arguments with named arguments", Pos: pos}
 		}
 		p.orderedParams++
 		return expr.PositionalParam(p.orderedParams), nil
@@ -202,6 +228,16 @@ func (p *Parser) parseUnaryExpr() (expr.Expr, error) {
 		p.Unscan()
 		return p.parseExprList(scanner.LSBRACKET, scanner.RSBRACKET)
 	case scanner.LPAREN:
+		if tok, _, _ := p.ScanIgnoreWhitespace(); tok == scanner.SELECT {
+			p.Unscan()
+			sub, err := p.parseSubquery()
+			if err != nil {
+				return nil, err
+			}
+			return expr.ScalarSubquery{Subquery: sub}, nil
+		}
+		p.Unscan()
+
 		e, _, err := p.ParseExpr()
 		if err != nil {
 			return nil, err
@@ -215,6 +251,54 @@ func (p *Parser) parseUnaryExpr() (expr.Expr, error) {
 	}
 }
 
+// parseExists parses "(select)" and returns an EXISTS expression.
+// This function assumes the EXISTS token, and the NOT token if not is true,
+// have already been consumed.
+func (p *Parser) parseExists(not bool) (expr.Expr, error) {
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.LPAREN {
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"("}, pos)
+	}
+
+	sub, err := p.parseSubquery()
+	if err != nil {
+		return nil, err
+	}
+
+	return expr.Exists{Subquery: sub, Not: not}, nil
+}
+
+// parseSubquery parses "select)" and returns a subquery reading the table
+// of the enclosing statement, if any.
+// This function assumes the left parenthesis has already been consumed.
+func (p *Parser) parseSubquery() (expr.Subquery, error) {
+	// the subquery is parsed without the expression buffer,
+	// which would otherwise be used to name its result fields.
+	buf := p.buf
+	p.buf = nil
+
+	name := p.currentTable()
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.SELECT {
+		p.buf = buf
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"SELECT"}, pos)
+	}
+
+	t, err := p.parseSelectStatement()
+	p.buf = buf
+	if err != nil {
+		return nil, err
+	}
+	if p.buf != nil {
+		p.buf.WriteString(t.SQL())
+	}
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.RPAREN {
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{")"}, pos)
+	}
+
+	return planner.NewSubquery(t, name), nil
+}
+
 // parseIdent parses an identifier.
 func (p *Parser) parseIdent() (string, error) {
 	tok, pos, lit := p.ScanIgnoreWhitespace()
@@ -225,6 +309,27 @@ func (p *Parser) parseIdent() (string, error) {
 	return lit, nil
 }
 
+// parseTableName parses the name of a table, which can be qualified
+// by the name of an attached database, like "other.users".
+func (p *Parser) parseTableName() (string, error) {
+	name, err := p.parseIdent()
+	if err != nil {
+		return "", err
+	}
+
+	if tok, _, _ := p.Scan(); tok != scanner.DOT {
+		p.Unscan()
+		return name, nil
+	}
+
+	table, err := p.parseIdent()
+	if err != nil {
+		return "", err
+	}
+
+	return name + "." + table, nil
+}
+
 // parseIdentList parses a comma delimited list of identifiers.
 func (p *Parser) parseIdentList() ([]string, error) {
 	// Parse first (required) identifier.
@@ -251,20 +356,20 @@ func (p *Parser) parseIdentList() ([]string, error) {
 
 // parseParam parses a positional or named param.
 func (p *Parser) parseParam() (expr.Expr, error) {
-	tok, _, lit := p.ScanIgnoreWhitespace()
+	tok, pos, lit := p.ScanIgnoreWhitespace()
 	switch tok {
 	case scanner.NAMEDPARAM:
 		if len(lit) == 1 {
-			return nil, &ParseError{Message: "missing param name"}
+			return nil, &ParseError{Message: "missing param name", Pos: pos}
 		}
 		if p.orderedParams > 0 {
-			return nil, &ParseError{Message: "cannot mix positional arguments with named arguments"}
+			return nil, &ParseError{Message: "cannot mix positional arguments with named arguments", Pos: pos}
 		}
 		p.namedParams++
 		return expr.NamedParam(lit[1:]), nil
 	case scanner.POSITIONALPARAM:
 		if p.namedParams > 0 {
-			return nil, &ParseError{Message: "cannot mix positional arguments with named arguments"}
+			return nil, &ParseError{Message: "cannot mix positional arguments with named arguments", Pos: pos}
 		}
 		p.orderedParams++
 		return expr.PositionalParam(p.orderedParams), nil
@@ -380,8 +485,15 @@ LOOP:
 		tok, _, _ := p.Scan()
 		switch tok {
 		case scanner.DOT:
-			// scan the next token for an ident
+			// scan the next token for an ident or a wildcard
 			tok, pos, lit := p.Scan()
+			if tok == scanner.MUL {
+				vPath = append(vPath, document.ValuePathFragment{
+					FieldName: "*",
+					Wildcard:  true,
+				})
+				continue
+			}
 			if tok != scanner.IDENT {
 				return nil, newParseError(lit, []string{"identifier"}, pos)
 			}
@@ -389,18 +501,24 @@ LOOP:
 				FieldName: lit,
 			})
 		case scanner.LSBRACKET:
-			// scan the next token for an integer
+			// scan the next token for an integer or a wildcard
 			tok, pos, lit := p.Scan()
-			if tok != scanner.INTEGER || lit[0] == '-' {
-				return nil, newParseError(lit, []string{"array index"}, pos)
-			}
-			idx, err := strconv.Atoi(lit)
-			if err != nil {
-				return nil, err
+			if tok == scanner.MUL {
+				vPath = append(vPath, document.ValuePathFragment{
+					Wildcard: true,
+				})
+			} else {
+				if tok != scanner.INTEGER || lit[0] == '-' {
+					return nil, newParseError(lit, []string{"array index"}, pos)
+				}
+				idx, err := strconv.Atoi(lit)
+				if err != nil {
+					return nil, err
+				}
+				vPath = append(vPath, document.ValuePathFragment{
+					ArrayIndex: idx,
+				})
 			}
-			vPath = append(vPath, document.ValuePathFragment{
-				ArrayIndex: idx,
-			})
 			// scan the next token for a closing left bracket
 			tok, pos, lit = p.Scan()
 			if tok != scanner.RSBRACKET {
@@ -458,17 +576,40 @@ func (p *Parser) parseFunction() (expr.Expr, error) {
 		return nil, err
 	}
 
+	return p.parseFunctionCall(fname)
+}
+
+// parseFunctionCall parses the arguments of the function fname and the FILTER clause
+// of aggregate functions.
+// This function assumes the name of the function has already been consumed.
+func (p *Parser) parseFunctionCall(fname string) (expr.Expr, error) {
+	e, err := p.parseFunctionArgs(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.parseFilterClause(e)
+}
+
+// parseFunctionArgs parses the arguments of the function fname and returns the function.
+func (p *Parser) parseFunctionArgs(fname string) (expr.Expr, error) {
 	// Parse required ( token.
 	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.LPAREN {
 		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"("}, pos)
 	}
 
-	// Special case: If the function is COUNT, support the special case COUNT(*)
+	// Special case: support COUNT(*), and KEYS(*), VALUES(*) and FLATTEN(*)
+	// which take the current document
 	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok == scanner.MUL {
 		if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.RPAREN {
 			return nil, newParseError(scanner.Tokstr(tok, lit), []string{")"}, pos)
 		}
 
+		switch strings.ToLower(fname) {
+		case "keys", "values", "flatten":
+			return expr.GetFunc(fname, expr.Wildcard{})
+		}
+
 		return &expr.CountFunc{Wildcard: true}, nil
 	}
 	p.Unscan()
@@ -504,6 +645,38 @@ func (p *Parser) parseFunction() (expr.Expr, error) {
 	return expr.GetFunc(fname, exprs...)
 }
 
+// parseFilterClause parses the "FILTER (WHERE cond)" clause following the aggregate function e, if it exists.
+func (p *Parser) parseFilterClause(e expr.Expr) (expr.Expr, error) {
+	// FILTER is not a reserved keyword
+	tok, pos, lit := p.ScanIgnoreWhitespace()
+	if tok != scanner.IDENT || !strings.EqualFold(lit, "FILTER") {
+		p.Unscan()
+		return e, nil
+	}
+
+	agg, ok := e.(expr.AggregateFunc)
+	if !ok {
+		return nil, &ParseError{Message: fmt.Sprintf("FILTER is only allowed on aggregate functions, got %s", expr.Format(e)), Pos: pos}
+	}
+
+	for _, want := range []scanner.Token{scanner.LPAREN, scanner.WHERE} {
+		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != want {
+			return nil, newParseError(scanner.Tokstr(tok, lit), []string{want.String()}, pos)
+		}
+	}
+
+	cond, _, err := p.ParseExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.RPAREN {
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{")"}, pos)
+	}
+
+	return &expr.FilterFunc{Aggregate: agg, Cond: cond}, nil
+}
+
 // parseCastExpression parses a string of the form CAST(expr AS type).
 func (p *Parser) parseCastExpression() (expr.Expr, error) {
 	// Parse required CAST token.
@@ -203,6 +203,16 @@ func TestParserPath(t *testing.T) {
 			document.ValuePathFragment{ArrayIndex: 5},
 			document.ValuePathFragment{FieldName: "  \"quotes"},
 		}, false},
+		{"wildcards", `a[*].*.b`, document.ValuePath{
+			document.ValuePathFragment{FieldName: "a"},
+			document.ValuePathFragment{Wildcard: true},
+			document.ValuePathFragment{FieldName: "*", Wildcard: true},
+			document.ValuePathFragment{FieldName: "b"},
+		}, false},
+		{"quoted star", "a.`*`", document.ValuePath{
+			document.ValuePathFragment{FieldName: "a"},
+			document.ValuePathFragment{FieldName: "*"},
+		}, false},
 		{"negative index", `a.b[-100].c`, nil, true},
 		{"with spaces", `a.  b[100].  c`, nil, true},
 		{"starting with array", `[10].a`, nil, true},
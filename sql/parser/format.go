@@ -0,0 +1,381 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/sql/planner"
+	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// Format returns the canonical SQL representation of a statement returned by the parser.
+// The result can be parsed back to an equivalent statement, which makes it suitable
+// for logging or for storing rewritten queries.
+func Format(stmt query.Statement) string {
+	var b strings.Builder
+
+	switch t := stmt.(type) {
+	case *planner.Tree:
+		b.WriteString(t.SQL())
+	case *planner.WithStmt:
+		b.WriteString("WITH ")
+		for _, cte := range t.CTEs {
+			if cte.Recursive != nil {
+				b.WriteString("RECURSIVE ")
+				break
+			}
+		}
+		for i, cte := range t.CTEs {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, "%s AS (%s", expr.FormatIdent(cte.Name), cte.Initial.SQL())
+			if cte.Recursive != nil {
+				b.WriteString(" UNION ")
+				if cte.UnionAll {
+					b.WriteString("ALL ")
+				}
+				b.WriteString(cte.Recursive.SQL())
+			}
+			b.WriteString(")")
+		}
+		b.WriteString(" " + t.Statement.SQL())
+	case *planner.ExplainStmt:
+		b.WriteString("EXPLAIN ")
+		if t.Analyze {
+			b.WriteString("ANALYZE ")
+		}
+		if t.Nodes {
+			b.WriteString("NODES ")
+		}
+		b.WriteString(Format(t.Statement))
+	case query.InsertStmt:
+		b.WriteString("INSERT INTO " + expr.FormatTableName(t.TableName))
+		if len(t.FieldNames) > 0 {
+			b.WriteString(" (" + formatIdentList(t.FieldNames) + ")")
+		}
+		b.WriteString(" VALUES ")
+		for i, v := range t.Values {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			// with a list of fields, values are lists of expressions
+			// surrounded by parentheses.
+			if l, ok := v.(expr.LiteralExprList); ok && len(t.FieldNames) > 0 {
+				b.WriteString("(")
+				for j, e := range l {
+					if j > 0 {
+						b.WriteString(", ")
+					}
+					b.WriteString(expr.Format(e))
+				}
+				b.WriteString(")")
+				continue
+			}
+			b.WriteString(expr.Format(v))
+		}
+		switch t.OnConflict {
+		case query.OnConflictDoNothing:
+			b.WriteString(" ON CONFLICT DO NOTHING")
+		case query.OnConflictDoReplace:
+			b.WriteString(" ON CONFLICT DO REPLACE")
+		}
+		if r, ok := t.Returning.(planner.Returning); ok {
+			b.WriteString(" RETURNING " + r.String())
+		}
+	case query.CreateTableStmt:
+		b.WriteString("CREATE TABLE ")
+		if t.IfNotExists {
+			b.WriteString("IF NOT EXISTS ")
+		}
+		b.WriteString(expr.FormatTableName(t.TableName))
+		if len(t.Info.FieldConstraints) > 0 {
+			b.WriteString(" (")
+			for i, fc := range t.Info.FieldConstraints {
+				if i > 0 {
+					b.WriteString(", ")
+				}
+				b.WriteString(expr.FormatPath(fc.Path))
+				if fc.Type != 0 {
+					b.WriteString(" " + strings.ToUpper(fc.Type.String()))
+				}
+				if fc.IsPrimaryKey {
+					b.WriteString(" PRIMARY KEY")
+				}
+				if fc.IsNotNull {
+					b.WriteString(" NOT NULL")
+				}
+				if fc.IsUnique {
+					b.WriteString(" UNIQUE")
+				}
+				if fc.DefaultValue != nil {
+					b.WriteString(" DEFAULT " + fc.DefaultValue.String())
+				}
+				if fc.Check != nil {
+					b.WriteString(" CHECK (" + fc.Check.String() + ")")
+				}
+			}
+			b.WriteString(")")
+		}
+		if pt := t.Info.Partitioning; pt != nil {
+			fmt.Fprintf(&b, " PARTITION BY %s (%s)", pt.Method, expr.FormatPath(pt.Path))
+			if pt.Method == database.PartitionByHash {
+				fmt.Fprintf(&b, " PARTITIONS %d", pt.Count)
+			} else {
+				b.WriteString(" VALUES (")
+				for i, v := range pt.Bounds {
+					if i > 0 {
+						b.WriteString(", ")
+					}
+					b.WriteString(v.String())
+				}
+				b.WriteString(")")
+			}
+		}
+		var options []string
+		if t.Info.Columnar {
+			options = append(options, "COLUMNAR STORAGE")
+		}
+		if t.Info.FieldIndex {
+			options = append(options, "FIELD INDEX")
+		}
+		if t.Info.FieldDictionary {
+			options = append(options, "FIELD DICTIONARY")
+		}
+		if len(options) > 0 {
+			b.WriteString(" WITH " + strings.Join(options, ", "))
+		}
+	case query.CreateIndexStmt:
+		b.WriteString("CREATE ")
+		if t.Unique {
+			b.WriteString("UNIQUE ")
+		}
+		b.WriteString("INDEX ")
+		if t.IfNotExists {
+			b.WriteString("IF NOT EXISTS ")
+		}
+		fmt.Fprintf(&b, "%s ON %s (%s)", expr.FormatIdent(t.IndexName), expr.FormatTableName(t.TableName), expr.FormatPath(t.Path))
+	case query.CreateProcedureStmt:
+		b.WriteString("CREATE PROCEDURE ")
+		if t.IfNotExists {
+			b.WriteString("IF NOT EXISTS ")
+		}
+		fmt.Fprintf(&b, "%s(%s) %s", expr.FormatIdent(t.ProcedureName), formatIdentList(t.Params), t.Body)
+	case query.CreateEventStmt:
+		b.WriteString("CREATE EVENT ")
+		if t.IfNotExists {
+			b.WriteString("IF NOT EXISTS ")
+		}
+		fmt.Fprintf(&b, "%s EVERY %s DO %s", expr.FormatIdent(t.EventName), formatInterval(t.Interval), t.Statement)
+	case *planner.CallStmt:
+		args := make([]string, len(t.Args))
+		for i, e := range t.Args {
+			args[i] = expr.Format(e)
+		}
+		fmt.Fprintf(&b, "CALL %s(%s)", expr.FormatIdent(t.ProcedureName), strings.Join(args, ", "))
+	case *planner.IfStmt:
+		fmt.Fprintf(&b, "IF %s THEN %s", expr.Format(t.Cond), formatProcedureStatements(t.Then))
+		if len(t.Else) > 0 {
+			b.WriteString("ELSE " + formatProcedureStatements(t.Else))
+		}
+		b.WriteString("END IF")
+	case *planner.ForStmt:
+		fmt.Fprintf(&b, "FOR %s DO %sEND FOR", t.Cursor.SQL(), formatProcedureStatements(t.Body))
+	case query.DropTableStmt:
+		b.WriteString("DROP TABLE ")
+		if t.IfExists {
+			b.WriteString("IF EXISTS ")
+		}
+		b.WriteString(expr.FormatTableName(t.TableName))
+	case query.DropIndexStmt:
+		b.WriteString("DROP INDEX ")
+		if t.IfExists {
+			b.WriteString("IF EXISTS ")
+		}
+		b.WriteString(expr.FormatTableName(t.IndexName))
+	case query.DropProcedureStmt:
+		b.WriteString("DROP PROCEDURE ")
+		if t.IfExists {
+			b.WriteString("IF EXISTS ")
+		}
+		b.WriteString(expr.FormatIdent(t.ProcedureName))
+	case query.DropEventStmt:
+		b.WriteString("DROP EVENT ")
+		if t.IfExists {
+			b.WriteString("IF EXISTS ")
+		}
+		b.WriteString(expr.FormatIdent(t.EventName))
+	case query.AlterStmt:
+		b.WriteString("ALTER TABLE ")
+		if t.IfExists {
+			b.WriteString("IF EXISTS ")
+		}
+		fmt.Fprintf(&b, "%s RENAME TO %s", expr.FormatTableName(t.TableName), expr.FormatIdent(t.NewTableName))
+	case query.AttachStmt:
+		fmt.Fprintf(&b, "ATTACH DATABASE %s AS %s", strconv.Quote(t.Path), expr.FormatIdent(t.Name))
+		if t.ReadOnly {
+			b.WriteString(" READ ONLY")
+		}
+	case query.DetachStmt:
+		b.WriteString("DETACH DATABASE " + expr.FormatIdent(t.Name))
+	case query.ReIndexStmt:
+		b.WriteString("REINDEX")
+		if t.TableOrIndexName != "" {
+			b.WriteString(" " + expr.FormatIdent(t.TableOrIndexName))
+		}
+	case query.AnalyzeStmt:
+		b.WriteString("ANALYZE")
+		if t.TableOrIndexName != "" {
+			b.WriteString(" " + expr.FormatIdent(t.TableOrIndexName))
+		}
+	case query.ShowTablesStmt:
+		b.WriteString("SHOW TABLES")
+	case query.ShowIndexesStmt:
+		b.WriteString("SHOW INDEXES")
+		if t.TableName != "" {
+			b.WriteString(" FROM " + expr.FormatTableName(t.TableName))
+		}
+	case query.DescribeStmt:
+		b.WriteString("DESCRIBE " + expr.FormatTableName(t.TableName))
+	case query.KillStmt:
+		b.WriteString("KILL " + expr.Format(t.TransactionID))
+	case query.PragmaStmt:
+		b.WriteString("PRAGMA")
+		if t.Name != "" {
+			b.WriteString(" " + expr.FormatIdent(t.Name))
+		}
+		if t.Value != nil {
+			b.WriteString(" = " + expr.Format(t.Value))
+		}
+	case query.SetGlobalStmt:
+		fmt.Fprintf(&b, "SET GLOBAL %s = %s", expr.FormatIdent(t.Name), expr.Format(t.Value))
+	case query.SetStmt:
+		fmt.Fprintf(&b, "SET %s = %s", expr.FormatIdent(t.Name), expr.Format(t.Value))
+	case query.BeginStmt:
+		b.WriteString("BEGIN")
+		if !t.Writable {
+			b.WriteString(" READ ONLY")
+		}
+	case query.CommitStmt:
+		b.WriteString("COMMIT")
+	case query.RollbackStmt:
+		b.WriteString("ROLLBACK")
+	case query.SavepointStmt:
+		b.WriteString("SAVEPOINT " + expr.FormatIdent(t.Name))
+	case query.RollbackToSavepointStmt:
+		b.WriteString("ROLLBACK TO SAVEPOINT " + expr.FormatIdent(t.Name))
+	case query.ReleaseSavepointStmt:
+		b.WriteString("RELEASE SAVEPOINT " + expr.FormatIdent(t.Name))
+	default:
+		fmt.Fprintf(&b, "%v", stmt)
+	}
+
+	return b.String()
+}
+
+// FormatQuery returns the canonical SQL representation of every statement of q,
+// separated by semicolons.
+func FormatQuery(q query.Query) string {
+	stmts := make([]string, len(q.Statements))
+	for i, stmt := range q.Statements {
+		stmts[i] = Format(stmt)
+	}
+
+	return strings.Join(stmts, "; ")
+}
+
+func formatIdentList(idents []string) string {
+	s := make([]string, len(idents))
+	for i := range idents {
+		s[i] = expr.FormatIdent(idents[i])
+	}
+
+	return strings.Join(s, ", ")
+}
+
+// StatementType returns the keywords identifying the type of a statement returned
+// by the parser, like "SELECT", "CREATE TABLE" or "SHOW INDEXES".
+// It returns an empty string for statements created outside of the parser.
+func StatementType(stmt query.Statement) string {
+	switch t := stmt.(type) {
+	case *planner.Tree:
+		for n := t.Root; n != nil; n = n.Left() {
+			switch n.Operation() {
+			case planner.Deletion:
+				return "DELETE"
+			case planner.Replacement:
+				return "UPDATE"
+			}
+		}
+		return "SELECT"
+	case *planner.WithStmt:
+		return "SELECT"
+	case *planner.ExplainStmt:
+		return "EXPLAIN"
+	case query.InsertStmt:
+		return "INSERT"
+	case query.CreateTableStmt:
+		return "CREATE TABLE"
+	case query.CreateIndexStmt:
+		return "CREATE INDEX"
+	case query.CreateProcedureStmt:
+		return "CREATE PROCEDURE"
+	case query.CreateEventStmt:
+		return "CREATE EVENT"
+	case *planner.CallStmt:
+		return "CALL"
+	case *planner.IfStmt:
+		return "IF"
+	case *planner.ForStmt:
+		return "FOR"
+	case query.DropTableStmt:
+		return "DROP TABLE"
+	case query.DropIndexStmt:
+		return "DROP INDEX"
+	case query.DropProcedureStmt:
+		return "DROP PROCEDURE"
+	case query.DropEventStmt:
+		return "DROP EVENT"
+	case query.AlterStmt:
+		return "ALTER TABLE"
+	case query.ReIndexStmt:
+		return "REINDEX"
+	case query.AttachStmt:
+		return "ATTACH"
+	case query.DetachStmt:
+		return "DETACH"
+	case query.AnalyzeStmt:
+		return "ANALYZE"
+	case query.ShowTablesStmt:
+		return "SHOW TABLES"
+	case query.ShowIndexesStmt:
+		return "SHOW INDEXES"
+	case query.DescribeStmt:
+		return "DESCRIBE"
+	case query.KillStmt:
+		return "KILL"
+	case query.PragmaStmt:
+		return "PRAGMA"
+	case query.SetGlobalStmt:
+		return "SET GLOBAL"
+	case query.SetStmt:
+		return "SET"
+	case query.BeginStmt:
+		return "BEGIN"
+	case query.CommitStmt:
+		return "COMMIT"
+	case query.RollbackStmt:
+		return "ROLLBACK"
+	case query.SavepointStmt:
+		return "SAVEPOINT"
+	case query.RollbackToSavepointStmt:
+		return "ROLLBACK TO SAVEPOINT"
+	case query.ReleaseSavepointStmt:
+		return "RELEASE SAVEPOINT"
+	}
+
+	return ""
+}
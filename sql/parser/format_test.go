@@ -0,0 +1,194 @@
+package parser
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/genjidb/genji/sql/query/expr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected string
+	}{
+		{"SELECT 1", "SELECT 1"},
+		{"select * from test", "SELECT * FROM test"},
+		{"SELECT a, `b c`.d[1] FROM test WHERE age>=10 AND (name = 'foo' OR name IS NOT NULL)",
+			"SELECT a, `b c`.d[1] FROM test WHERE age >= 10 AND (name = 'foo' OR name IS NOT NULL)"},
+		{"SELECT a+1, b AS c, COUNT(*) FROM test GROUP BY a", "SELECT a + 1 AS `a+1`, b AS c, COUNT(*) FROM test GROUP BY a"},
+		{"SELECT * FROM test WHERE a NOT IN [1, 2.5, \"it's\"] AND b = {x: $foo, `y z`: CAST(c AS TEXT)}",
+			"SELECT * FROM test WHERE a NOT IN [1, 2.5, 'it\\'s'] AND b = {x: $foo, `y z`: CAST(c AS TEXT)}"},
+		{"SELECT * FROM `select` ORDER BY a DESC NULLS LAST LIMIT 10 OFFSET 2", "SELECT * FROM `select` ORDER BY a DESC NULLS LAST LIMIT 10 OFFSET 2"},
+		{"SELECT * FROM test ORDER BY city ASC, age DESC, name NULLS LAST", "SELECT * FROM test ORDER BY city, age DESC, name NULLS LAST"},
+		{"SELECT keys(*), values(a.b), FLATTEN(*) AS f FROM test", "SELECT KEYS(*) AS `keys(*)`, VALUES(a.b) AS `values(a.b)`, FLATTEN(*) AS f FROM test"},
+		{"SELECT approx_count_distinct(a), APPROX_PERCENTILE(b, 0.9) FROM test", "SELECT APPROX_COUNT_DISTINCT(a) AS `approx_count_distinct(a)`, APPROX_PERCENTILE(b, 0.9) FROM test"},
+		{"SELECT pivot(a, b) AS p FROM test GROUP BY c", "SELECT PIVOT(a, b) AS p FROM test GROUP BY c"},
+		{"SELECT count(*) filter (where a > 1) AS n, sum(b) FILTER (WHERE c) FROM test", "SELECT COUNT(*) FILTER (WHERE a > 1) AS n, SUM(b) FILTER (WHERE c) AS `sum(b) FILTER (WHERE c)` FROM test"},
+		{"SELECT * FROM test SAMPLE 10 ROWS REPEATABLE (3) WHERE a = 1", "SELECT * FROM test TABLESAMPLE 10 ROWS REPEATABLE (3) WHERE a = 1"},
+		{"UPDATE test SET a = 1.0, b.c = ? WHERE pk() = 2", "UPDATE test SET a = 1.0, b.c = ? WHERE pk() = 2"},
+		{"UPDATE test UNSET a, b", "UPDATE test UNSET a, b"},
+		{"DELETE FROM test WHERE a < 0", "DELETE FROM test WHERE a < 0"},
+		{"INSERT INTO test (a, b) VALUES (1, 'a'), (2, 'b')", "INSERT INTO test (a, b) VALUES (1, 'a'), (2, 'b')"},
+		{"INSERT INTO test VALUES {a: 1}, ?", "INSERT INTO test VALUES {a: 1}, ?"},
+		{"INSERT INTO test VALUES {a: 1} ON CONFLICT DO NOTHING", "INSERT INTO test VALUES {a: 1} ON CONFLICT DO NOTHING"},
+		{"INSERT INTO test (a) VALUES (1) on conflict do replace", "INSERT INTO test (a) VALUES (1) ON CONFLICT DO REPLACE"},
+		{"INSERT INTO test VALUES {a: 1} returning pk(), a + 1 AS b, *", "INSERT INTO test VALUES {a: 1} RETURNING pk(), a + 1 AS b, *"},
+		{"UPDATE test SET a = 1 WHERE b = 2 RETURNING a", "UPDATE test SET a = 1 WHERE b = 2 RETURNING a"},
+		{"DELETE FROM test RETURNING *", "DELETE FROM test RETURNING *"},
+		{"CREATE TABLE IF NOT EXISTS test(a INTEGER PRIMARY KEY, b.c TEXT NOT NULL)", "CREATE TABLE IF NOT EXISTS test (a INTEGER PRIMARY KEY, b.c TEXT NOT NULL)"},
+		{"CREATE TABLE test(a INTEGER check(a>=0 AND b.c != 'x'))", "CREATE TABLE test (a INTEGER CHECK (a >= 0 AND b.c != 'x'))"},
+		{"CREATE TABLE test(a INTEGER unique NOT NULL, b.c UNIQUE)", "CREATE TABLE test (a INTEGER NOT NULL UNIQUE, b.c UNIQUE)"},
+		{"CREATE TABLE test(a INTEGER DEFAULT (1+2) NOT NULL, b default now())", "CREATE TABLE test (a INTEGER NOT NULL DEFAULT (1 + 2), b DEFAULT NOW())"},
+		{"create table test(a integer primary key) partition by hash(a) partitions 4", "CREATE TABLE test (a INTEGER PRIMARY KEY) PARTITION BY HASH (a) PARTITIONS 4"},
+		{"CREATE TABLE test(a TEXT PRIMARY KEY) PARTITION BY RANGE (a) VALUES ('h', 'p')", `CREATE TABLE test (a TEXT PRIMARY KEY) PARTITION BY RANGE (a) VALUES ("h", "p")`},
+		{"create table test with columnar storage", "CREATE TABLE test WITH COLUMNAR STORAGE"},
+		{"create table test with field index, columnar storage", "CREATE TABLE test WITH COLUMNAR STORAGE, FIELD INDEX"},
+		{"create table test with field dictionary", "CREATE TABLE test WITH FIELD DICTIONARY"},
+		{"CREATE UNIQUE INDEX idx ON test (a.b)", "CREATE UNIQUE INDEX idx ON test (a.b)"},
+		{"DROP TABLE IF EXISTS test", "DROP TABLE IF EXISTS test"},
+		{"DROP INDEX idx", "DROP INDEX idx"},
+		{"ALTER TABLE a RENAME TO b", "ALTER TABLE a RENAME TO b"},
+		{"alter table if exists a rename to b", "ALTER TABLE IF EXISTS a RENAME TO b"},
+		{"drop index if exists idx", "DROP INDEX IF EXISTS idx"},
+		{"attach 'foo.db' as foo read only", `ATTACH DATABASE "foo.db" AS foo READ ONLY`},
+		{"DETACH foo", "DETACH DATABASE foo"},
+		{"SELECT * FROM foo.test", "SELECT * FROM foo.test"},
+		{"select * from a, lateral (select * from b where b.x = a.x order by y desc limit 3) top where top.y > 1", "SELECT * FROM a, LATERAL (SELECT * FROM b WHERE b.x = a.x ORDER BY y DESC LIMIT 3) AS top WHERE top.y > 1"},
+		{"select * from a left outer join b on a.id = b.a_id join c on c.x = b.x", "SELECT * FROM a LEFT JOIN b ON a.id = b.a_id JOIN c ON c.x = b.x"},
+		{"select x.a, (select COUNT(*) from b where b.a = x.a) as n from a as x join a as y on x.id = y.parent", "SELECT x.a, (SELECT COUNT(*) FROM b WHERE b.a = x.a) AS n FROM a AS x JOIN a AS y ON x.id = y.parent"},
+		{"select * from a where exists (select * from b where b.x = a.x) and not exists(select 1 from c)", "SELECT * FROM a WHERE EXISTS (SELECT * FROM b WHERE b.x = a.x) AND NOT EXISTS (SELECT 1 FROM c)"},
+		{"delete from a where not exists (select * from b where x = ?)", "DELETE FROM a WHERE NOT EXISTS (SELECT * FROM b WHERE x = ?)"},
+		{"select a, (select MAX(x) from b where b.y = a.y) as m from a where id not in (select a_id from c)", "SELECT a, (SELECT MAX(x) FROM b WHERE b.y = a.y) AS m FROM a WHERE id NOT IN (SELECT a_id FROM c)"},
+		{"select x from (select * from test where x > 1) t", "SELECT x FROM (SELECT * FROM test WHERE x > 1) AS t"},
+		{"with a as (select * from test where x > 1) select a from a", "WITH a AS (SELECT * FROM test WHERE x > 1) SELECT a FROM a"},
+		{"with recursive c as (select 1 as n union all select n + 1 as n from c where n < 5), d as (select * from c) select * from d", "WITH RECURSIVE c AS (SELECT 1 AS n UNION ALL SELECT n + 1 AS n FROM c WHERE n < 5), d AS (SELECT * FROM c) SELECT * FROM d"},
+		{"WITH RECURSIVE c AS (SELECT 1 AS n UNION SELECT n FROM c) SELECT n FROM c", "WITH RECURSIVE c AS (SELECT 1 AS n UNION SELECT n FROM c) SELECT n FROM c"},
+		{"create procedure if not exists p(a, `b c`) begin update test set x = $a where y = $b; if exists (select * from test where x > $a) then delete from test; else call q($a, 1); end if; for select x from test do insert into t (x) values ($x); end for; end",
+			"CREATE PROCEDURE IF NOT EXISTS p(a, `b c`) BEGIN UPDATE test SET x = $a WHERE y = $b; IF EXISTS (SELECT * FROM test WHERE x > $a) THEN DELETE FROM test; ELSE CALL q($a, 1); END IF; FOR SELECT x FROM test DO INSERT INTO t (x) VALUES ($x); END FOR; END"},
+		{"call p()", "CALL p()"},
+		{"drop procedure if exists p", "DROP PROCEDURE IF EXISTS p"},
+		{"create event if not exists e every 90 seconds do delete from t where x < 1", "CREATE EVENT IF NOT EXISTS e EVERY 90 SECONDS DO DELETE FROM t WHERE x < 1"},
+		{"create event e every 60 minutes do delete from t", "CREATE EVENT e EVERY 1 HOUR DO DELETE FROM t"},
+		{"drop event if exists e", "DROP EVENT IF EXISTS e"},
+		{"kill $id", "KILL $id"},
+		{"pragma", "PRAGMA"},
+		{"pragma Busy_Mode = 'error'", "PRAGMA busy_mode = 'error'"},
+		{"set global max_recursion = $n", "SET GLOBAL max_recursion = $n"},
+		{"set STATEMENT_TIMEOUT = '1s'", "SET statement_timeout = '1s'"},
+		{"REINDEX", "REINDEX"},
+		{"analyze `my table`", "ANALYZE `my table`"},
+		{"BEGIN READ ONLY", "BEGIN READ ONLY"},
+		{"savepoint `my sp`", "SAVEPOINT `my sp`"},
+		{"ROLLBACK TRANSACTION TO sp", "ROLLBACK TO SAVEPOINT sp"},
+		{"RELEASE sp", "RELEASE SAVEPOINT sp"},
+		{"EXPLAIN SELECT * FROM test", "EXPLAIN SELECT * FROM test"},
+		{"EXPLAIN ANALYZE SELECT * FROM test", "EXPLAIN ANALYZE SELECT * FROM test"},
+		{"explain analyze nodes SELECT * FROM test", "EXPLAIN ANALYZE NODES SELECT * FROM test"},
+		{"show tables", "SHOW TABLES"},
+		{"SHOW INDEXES FROM `my table`", "SHOW INDEXES FROM `my table`"},
+		{"DESCRIBE TABLE test", "DESCRIBE test"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.s, func(t *testing.T) {
+			q, err := ParseQuery(context.Background(), test.s)
+			require.NoError(t, err)
+			require.Len(t, q.Statements, 1)
+
+			s := Format(q.Statements[0])
+			require.Equal(t, test.expected, s)
+
+			// the formatted statement must be parsed back to the same statement
+			qq, err := ParseQuery(context.Background(), s)
+			require.NoError(t, err)
+			require.Equal(t, s, Format(qq.Statements[0]))
+		})
+	}
+
+	t.Run("Query", func(t *testing.T) {
+		q, err := ParseQuery(context.Background(), "begin; insert into test (a) values (1);commit")
+		require.NoError(t, err)
+		require.Equal(t, "BEGIN; INSERT INTO test (a) VALUES (1); COMMIT", FormatQuery(q))
+	})
+}
+
+func TestWalk(t *testing.T) {
+	q, err := ParseQuery(context.Background(), "SELECT a, b + 1 FROM test WHERE c = 1 OR d IN [e, 2]; INSERT INTO test VALUES {f: g}")
+	require.NoError(t, err)
+
+	var fields []string
+	Inspect(q, func(n interface{}) bool {
+		if fs, ok := n.(expr.FieldSelector); ok {
+			fields = append(fields, fs.String())
+		}
+		return true
+	})
+
+	// nodes of a tree are visited from the input to the root
+	require.Equal(t, []string{"c", "d", "e", "a", "b", "g"}, fields)
+
+	t.Run("Skip children", func(t *testing.T) {
+		var fields []string
+		Inspect(q, func(n interface{}) bool {
+			if fs, ok := n.(expr.FieldSelector); ok {
+				fields = append(fields, fs.String())
+			}
+			_, isOp := n.(expr.Operator)
+			return !isOp
+		})
+
+		require.Equal(t, []string{"a", "g"}, fields)
+	})
+}
+
+func TestStatementType(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected string
+	}{
+		{"SELECT * FROM test WHERE a > 1 ORDER BY a LIMIT 10", "SELECT"},
+		{"UPDATE test SET a = 1 WHERE b = 2", "UPDATE"},
+		{"DELETE FROM test WHERE a = 1", "DELETE"},
+		{"INSERT INTO test VALUES {a: 1}", "INSERT"},
+		{"EXPLAIN DELETE FROM test", "EXPLAIN"},
+		{"CREATE TABLE test", "CREATE TABLE"},
+		{"CREATE UNIQUE INDEX idx ON test (a)", "CREATE INDEX"},
+		{"DROP TABLE test", "DROP TABLE"},
+		{"DROP INDEX idx", "DROP INDEX"},
+		{"CREATE PROCEDURE p() BEGIN SELECT 1; END", "CREATE PROCEDURE"},
+		{"DROP PROCEDURE p", "DROP PROCEDURE"},
+		{"CREATE EVENT e EVERY 1 DAY DO DELETE FROM test", "CREATE EVENT"},
+		{"DROP EVENT e", "DROP EVENT"},
+		{"KILL 1", "KILL"},
+		{"PRAGMA max_recursion", "PRAGMA"},
+		{"SET GLOBAL max_recursion = 10", "SET GLOBAL"},
+		{"SET statement_timeout = '1s'", "SET"},
+		{"CALL p(1)", "CALL"},
+		{"ALTER TABLE test RENAME TO foo", "ALTER TABLE"},
+		{"ATTACH 'foo.db' AS foo", "ATTACH"},
+		{"DETACH foo", "DETACH"},
+		{"WITH a AS (SELECT * FROM test) SELECT * FROM a", "SELECT"},
+		{"REINDEX", "REINDEX"},
+		{"ANALYZE test", "ANALYZE"},
+		{"SHOW TABLES", "SHOW TABLES"},
+		{"SHOW INDEXES", "SHOW INDEXES"},
+		{"DESCRIBE test", "DESCRIBE"},
+		{"BEGIN READ ONLY", "BEGIN"},
+		{"COMMIT", "COMMIT"},
+		{"ROLLBACK", "ROLLBACK"},
+		{"SAVEPOINT sp", "SAVEPOINT"},
+		{"ROLLBACK TO sp", "ROLLBACK TO SAVEPOINT"},
+		{"RELEASE SAVEPOINT sp", "RELEASE SAVEPOINT"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.s, func(t *testing.T) {
+			stmt, err := NewParser(strings.NewReader(test.s)).ParseStatement()
+			require.NoError(t, err)
+			require.Equal(t, test.expected, StatementType(stmt))
+		})
+	}
+}
@@ -2,7 +2,9 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/genjidb/genji/sql/planner"
 	"github.com/genjidb/genji/sql/query"
 	"github.com/genjidb/genji/sql/query/expr"
 	"github.com/genjidb/genji/sql/scanner"
@@ -20,7 +22,7 @@ func (p *Parser) parseInsertStatement() (query.InsertStmt, error) {
 	}
 
 	// Parse table name
-	stmt.TableName, err = p.parseIdent()
+	stmt.TableName, err = p.parseTableName()
 	if err != nil {
 		pErr := err.(*ParseError)
 		pErr.Expected = []string{"table_name"}
@@ -53,15 +55,59 @@ func (p *Parser) parseInsertStatement() (query.InsertStmt, error) {
 		for _, l := range values {
 			el := l.(expr.LiteralExprList)
 			if len(el) != len(stmt.FieldNames) {
-				return stmt, fmt.Errorf("%d values for %d fields", len(el), len(stmt.FieldNames))
+				return stmt, &ParseError{
+					Message: fmt.Sprintf("%d values for %d fields", len(el), len(stmt.FieldNames)),
+					Pos:     p.s.Curr().Pos,
+				}
 			}
 		}
 	}
 
 	stmt.Values = values
+
+	stmt.OnConflict, err = p.parseOnConflictClause()
+	if err != nil {
+		return stmt, err
+	}
+
+	// Parse "RETURNING fields"
+	defer p.enterTable(stmt.TableName)()
+	returning, err := p.parseReturningClause()
+	if err != nil {
+		return stmt, err
+	}
+	if returning != nil {
+		stmt.Returning = planner.Returning(returning)
+	}
+
 	return stmt, nil
 }
 
+// parseOnConflictClause parses the "ON CONFLICT DO NOTHING" or "ON CONFLICT DO REPLACE" clause, if it exists.
+func (p *Parser) parseOnConflictClause() (query.OnConflictAction, error) {
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.ON {
+		p.Unscan()
+		return query.OnConflictAbort, nil
+	}
+
+	for _, want := range []scanner.Token{scanner.CONFLICT, scanner.DO} {
+		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != want {
+			return 0, newParseError(scanner.Tokstr(tok, lit), []string{want.String()}, pos)
+		}
+	}
+
+	// REPLACE is not a reserved keyword
+	tok, pos, lit := p.ScanIgnoreWhitespace()
+	switch {
+	case tok == scanner.NOTHING:
+		return query.OnConflictDoNothing, nil
+	case tok == scanner.IDENT && strings.EqualFold(lit, "REPLACE"):
+		return query.OnConflictDoReplace, nil
+	}
+
+	return 0, newParseError(scanner.Tokstr(tok, lit), []string{"NOTHING", "REPLACE"}, pos)
+}
+
 // parseFieldList parses a list of fields in the form: (path, path, ...), if exists
 func (p *Parser) parseFieldList() ([]string, bool, error) {
 	// Parse ( token.
@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/genjidb/genji/sql/planner"
 	"github.com/genjidb/genji/sql/query"
 	"github.com/genjidb/genji/sql/query/expr"
 	"github.com/stretchr/testify/require"
@@ -76,6 +77,47 @@ func TestParserInsert(t *testing.T) {
 			nil, true},
 		{"Values / Without fields / Wrong values", "INSERT INTO test VALUES {a: 1}, ('e', 'f')",
 			nil, true},
+		{"On conflict do nothing", "INSERT INTO test (a) VALUES (1) ON CONFLICT DO NOTHING",
+			query.InsertStmt{
+				TableName:  "test",
+				FieldNames: []string{"a"},
+				Values: expr.LiteralExprList{
+					expr.LiteralExprList{expr.IntegerValue(1)},
+				},
+				OnConflict: query.OnConflictDoNothing,
+			}, false},
+		{"On conflict do replace", "INSERT INTO test VALUES {a: 1} ON CONFLICT DO replace",
+			query.InsertStmt{
+				TableName: "test",
+				Values: expr.LiteralExprList{
+					expr.KVPairs{expr.KVPair{K: "a", V: expr.IntegerValue(1)}},
+				},
+				OnConflict: query.OnConflictDoReplace,
+			}, false},
+		{"Returning", "INSERT INTO test VALUES {a: 1} ON CONFLICT DO NOTHING RETURNING pk(), a AS b",
+			query.InsertStmt{
+				TableName: "test",
+				Values: expr.LiteralExprList{
+					expr.KVPairs{expr.KVPair{K: "a", V: expr.IntegerValue(1)}},
+				},
+				OnConflict: query.OnConflictDoNothing,
+				Returning: planner.Returning{
+					planner.ProjectedExpr{Expr: new(expr.PKFunc), ExprName: "pk()"},
+					planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "a")), ExprName: "b"},
+				},
+			}, false},
+		{"Returning / Wildcard", "INSERT INTO test (a) VALUES (1) returning *",
+			query.InsertStmt{
+				TableName:  "test",
+				FieldNames: []string{"a"},
+				Values: expr.LiteralExprList{
+					expr.LiteralExprList{expr.IntegerValue(1)},
+				},
+				Returning: planner.Returning{planner.Wildcard{}},
+			}, false},
+		{"Returning / Missing fields", "INSERT INTO test VALUES {a: 1} RETURNING", nil, true},
+		{"On conflict / Missing action", "INSERT INTO test VALUES {a: 1} ON CONFLICT", nil, true},
+		{"On conflict / Unknown action", "INSERT INTO test VALUES {a: 1} ON CONFLICT DO SOMETHING", nil, true},
 	}
 
 	for _, test := range tests {
@@ -0,0 +1,76 @@
+package parser
+
+import (
+	"context"
+	"testing"
+
+	"github.com/genjidb/genji/sql/planner"
+	"github.com/genjidb/genji/sql/query/expr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParserJoin(t *testing.T) {
+	on := func(l, r string) expr.Expr {
+		return expr.Eq(expr.FieldSelector(parsePath(t, l)), expr.FieldSelector(parsePath(t, r)))
+	}
+
+	tests := []struct {
+		name     string
+		s        string
+		expected *planner.Tree
+		errored  bool
+	}{
+		{"Join", "SELECT * FROM a JOIN b ON a.id = b.a_id",
+			planner.NewTree(
+				planner.NewProjectionNode(
+					planner.NewJoinNode(planner.NewTableInputNode("a"), "a", planner.InnerJoin, "b", "", on("a.id", "b.a_id")),
+					[]planner.ProjectedField{planner.Wildcard{}},
+					"a",
+				)), false},
+		{"Inner", "SELECT * FROM a inner join b ON a.id = b.a_id WHERE b.x > 1",
+			planner.NewTree(
+				planner.NewProjectionNode(
+					planner.NewSelectionNode(
+						planner.NewJoinNode(planner.NewTableInputNode("a"), "a", planner.InnerJoin, "b", "", on("a.id", "b.a_id")),
+						expr.Gt(expr.FieldSelector(parsePath(t, "b.x")), expr.IntegerValue(1)),
+					),
+					[]planner.ProjectedField{planner.Wildcard{}},
+					"a",
+				)), false},
+		{"Left", "SELECT * FROM a LEFT JOIN b ON a.id = b.a_id LEFT OUTER JOIN c ON c.b_id = b.id",
+			planner.NewTree(
+				planner.NewProjectionNode(
+					planner.NewJoinNode(
+						planner.NewJoinNode(planner.NewTableInputNode("a"), "a", planner.LeftJoin, "b", "", on("a.id", "b.a_id")),
+						"a", planner.LeftJoin, "c", "", on("c.b_id", "b.id")),
+					[]planner.ProjectedField{planner.Wildcard{}},
+					"a",
+				)), false},
+		{"Aliases", "SELECT * FROM a AS x JOIN a AS y ON x.id = y.parent_id",
+			planner.NewTree(
+				planner.NewProjectionNodeWithAlias(
+					planner.NewJoinNode(planner.NewTableInputNode("a"), "x", planner.InnerJoin, "a", "y", on("x.id", "y.parent_id")),
+					[]planner.ProjectedField{planner.Wildcard{}},
+					"a", "x",
+				)), false},
+		{"Without ON", "SELECT * FROM a JOIN b", nil, true},
+		{"Without alias", "SELECT * FROM a JOIN b AS ON a.id = b.a_id", nil, true},
+		{"Same table", "SELECT * FROM a JOIN a ON a.id = a.parent_id", nil, true},
+		{"Same alias", "SELECT * FROM a AS x JOIN b AS x ON x.id = x.a_id", nil, true},
+		{"Without table", "SELECT * FROM a JOIN ON a.id = b.a_id", nil, true},
+		{"Without JOIN", "SELECT * FROM a LEFT b ON a.id = b.a_id", nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q, err := ParseQuery(context.Background(), test.s)
+			if test.errored {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, q.Statements, 1)
+			require.EqualValues(t, test.expected, q.Statements[0])
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package parser
+
+import (
+	"context"
+	"testing"
+
+	"github.com/genjidb/genji/sql/planner"
+	"github.com/genjidb/genji/sql/query/expr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParserLateral(t *testing.T) {
+	sub := func(table string) *planner.Tree {
+		return planner.NewTree(
+			planner.NewProjectionNode(
+				planner.NewSelectionNode(planner.NewTableInputNode(table),
+					expr.Eq(expr.FieldSelector(parsePath(t, "x")), expr.FieldSelector(parsePath(t, "a.x"))),
+				),
+				[]planner.ProjectedField{planner.Wildcard{}},
+				table,
+			))
+	}
+
+	tests := []struct {
+		name     string
+		s        string
+		expected *planner.Tree
+		errored  bool
+	}{
+		{"Lateral", "SELECT * FROM a, LATERAL (SELECT * FROM b WHERE x = a.x) AS l",
+			planner.NewTree(
+				planner.NewProjectionNode(
+					planner.NewLateralNode(planner.NewTableInputNode("a"), "a", sub("b"), "l"),
+					[]planner.ProjectedField{planner.Wildcard{}},
+					"a",
+				)), false},
+		{"Without AS", "SELECT * FROM a, lateral (SELECT * FROM b WHERE x = a.x) l WHERE l.y > 1",
+			planner.NewTree(
+				planner.NewProjectionNode(
+					planner.NewSelectionNode(
+						planner.NewLateralNode(planner.NewTableInputNode("a"), "a", sub("b"), "l"),
+						expr.Gt(expr.FieldSelector(parsePath(t, "l.y")), expr.IntegerValue(1)),
+					),
+					[]planner.ProjectedField{planner.Wildcard{}},
+					"a",
+				)), false},
+		{"Multiple", "SELECT * FROM a, LATERAL (SELECT * FROM b WHERE x = a.x) AS l, LATERAL (SELECT * FROM c WHERE x = a.x) AS m",
+			planner.NewTree(
+				planner.NewProjectionNode(
+					planner.NewLateralNode(
+						planner.NewLateralNode(planner.NewTableInputNode("a"), "a", sub("b"), "l"),
+						"a", sub("c"), "m"),
+					[]planner.ProjectedField{planner.Wildcard{}},
+					"a",
+				)), false},
+		{"Without LATERAL", "SELECT * FROM a, b", nil, true},
+		{"Without alias", "SELECT * FROM a, LATERAL (SELECT * FROM b)", nil, true},
+		{"Without parentheses", "SELECT * FROM a, LATERAL SELECT * FROM b AS l", nil, true},
+		{"Not a select", "SELECT * FROM a, LATERAL (DELETE FROM b) AS l", nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q, err := ParseQuery(context.Background(), test.s)
+			if test.errored {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, q.Statements, 1)
+			require.EqualValues(t, test.expected, q.Statements[0])
+		})
+	}
+}
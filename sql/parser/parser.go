@@ -3,10 +3,12 @@ package parser
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
 
+	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/sql/query"
 	"github.com/genjidb/genji/sql/query/expr"
@@ -19,26 +21,113 @@ type Parser struct {
 	orderedParams int
 	namedParams   int
 	buf           *bytes.Buffer
+	opts          Options
+
+	// size and number of the tokens scanned so far
+	size, tokens int
+	// nesting depth of the expression being parsed
+	depth int
+	// limitErr is set once one of the limits is exceeded
+	limitErr error
+	// tables read by the statements being parsed, the innermost last
+	tables []string
+}
+
+// Options configure the parser.
+type Options struct {
+	// If Recover is true, ParseQuery doesn't stop at the first syntax error.
+	// It skips the invalid statement, continues with the next one
+	// and returns all the errors found as Errors,
+	// along with the statements successfully parsed.
+	Recover bool
+
+	// Limits protects the parser against pathological queries.
+	Limits Limits
+}
+
+// Limits bounds the resources used to parse a query, to prevent queries coming
+// from untrusted sources, like deeply nested expressions or huge IN lists,
+// from exhausting memory. Once a limit is exceeded, parsing stops and a *ParseError
+// describing the limit is returned, even if the Recover option is set.
+// Zero values mean no limit.
+type Limits struct {
+	// MaxLength is the maximum size of a query, in bytes.
+	MaxLength int
+	// MaxTokens is the maximum number of tokens of a query,
+	// excluding whitespaces and comments.
+	MaxTokens int
+	// MaxDepth is the maximum nesting depth of expressions,
+	// for example with parentheses, arrays or documents.
+	MaxDepth int
 }
 
 // NewParser returns a new instance of Parser.
 func NewParser(r io.Reader) *Parser {
-	return &Parser{s: scanner.NewBufScanner(r)}
+	return NewParserWithOptions(r, nil)
+}
+
+// NewParserWithOptions returns a new instance of Parser configured with the given options.
+// If opts is nil, default options are used.
+func NewParserWithOptions(r io.Reader, opts *Options) *Parser {
+	p := Parser{s: scanner.NewBufScanner(r)}
+	if opts != nil {
+		p.opts = *opts
+	}
+
+	return &p
 }
 
 // ParseQuery parses a query string and returns its AST representation.
 func ParseQuery(ctx context.Context, s string) (query.Query, error) {
-	return NewParser(strings.NewReader(s)).ParseQuery(ctx)
+	return ParseQueryWithOptions(ctx, s, nil)
+}
+
+// ParseQueryWithOptions parses a query string using the given options and returns its AST representation.
+// If opts is nil, default options are used.
+func ParseQueryWithOptions(ctx context.Context, s string, opts *Options) (query.Query, error) {
+	q, err := NewParserWithOptions(strings.NewReader(s), opts).ParseQuery(ctx)
+	if errs, ok := err.(Errors); ok {
+		for _, err := range errs {
+			withContext(err, s)
+		}
+		q.Source = s
+		return q, errs
+	}
+	if err != nil {
+		return q, withContext(err, s)
+	}
+
+	q.Source = s
+	return q, nil
 }
 
 // ParsePath parses the path of a value in a document.
 func ParsePath(s string) (document.ValuePath, error) {
-	return NewParser(strings.NewReader(s)).parsePath()
+	p, err := NewParser(strings.NewReader(s)).parsePath()
+	if err != nil {
+		return nil, withContext(err, s)
+	}
+
+	return p, nil
+}
+
+// ParseConstraintExpr parses the expression of a field constraint, as stored in the catalog.
+// It can be used as the ParseExpr function of a database.Database.
+func ParseConstraintExpr(s string) (database.Expr, error) {
+	e, _, err := NewParser(strings.NewReader(s)).ParseExpr()
+	if err != nil {
+		return nil, withContext(err, s)
+	}
+
+	return query.ConstraintExpr{Expr: e}, nil
 }
 
 // ParseQuery parses a Genji SQL string and returns a Query.
+// If the Recover option is set, it returns all the syntax errors found as Errors,
+// along with the statements successfully parsed.
 func (p *Parser) ParseQuery(ctx context.Context) (query.Query, error) {
 	var statements []query.Statement
+	var errs Errors
 	semi := true
 
 	for {
@@ -49,32 +138,95 @@ func (p *Parser) ParseQuery(ctx context.Context) (query.Query, error) {
 		}
 
 		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok == scanner.EOF {
+			if p.limitErr != nil {
+				return query.Query{}, p.limitErr
+			}
+			if len(errs) > 0 {
+				return query.New(statements...), errs
+			}
 			return query.New(statements...), nil
 		} else if tok == scanner.SEMICOLON {
 			semi = true
 		} else {
+			var s query.Statement
+			var err error
+
 			if !semi {
-				return query.Query{}, newParseError(scanner.Tokstr(tok, lit), []string{";"}, pos)
+				err = newParseError(scanner.Tokstr(tok, lit), []string{";"}, pos)
+			} else {
+				p.Unscan()
+				s, err = p.ParseStatement()
 			}
-			p.Unscan()
-			s, err := p.ParseStatement()
 			if err != nil {
-				return query.Query{}, err
+				if !p.opts.Recover || p.limitErr != nil {
+					return query.Query{}, err
+				}
+
+				errs = append(errs, err)
+				p.skipStatement()
+				semi = true
+				continue
 			}
+
 			statements = append(statements, s)
 			semi = false
 		}
 	}
 }
 
+// skipStatement skips the tokens of the current statement,
+// up to the next semicolon.
+func (p *Parser) skipStatement() {
+	// the error may have been caused by the end of the statement
+	if tok := p.s.Curr().Tok; tok == scanner.SEMICOLON || tok == scanner.EOF {
+		return
+	}
+
+	for {
+		if tok, _, _ := p.Scan(); tok == scanner.SEMICOLON || tok == scanner.EOF {
+			return
+		}
+	}
+}
+
+// Errors is returned by ParseQuery when the Recover option is set
+// and one or more statements are invalid.
+type Errors []error
+
+// Error returns the errors, one per line.
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
 // ParseStatement parses a Genji SQL string and returns a Statement AST object.
 func (p *Parser) ParseStatement() (query.Statement, error) {
+	stmt, err := p.parseStatement()
+	// exceeding a limit may cause misleading syntax errors
+	if p.limitErr != nil {
+		return nil, p.limitErr
+	}
+
+	return stmt, err
+}
+
+func (p *Parser) parseStatement() (query.Statement, error) {
 	tok, pos, lit := p.ScanIgnoreWhitespace()
 	switch tok {
 	case scanner.ALTER:
 		return p.parseAlterStatement()
+	case scanner.ANALYZE:
+		return p.parseAnalyzeStatement()
+	case scanner.ATTACH:
+		return p.parseAttachStatement()
 	case scanner.BEGIN:
 		return p.parseBeginStatement()
+	case scanner.CALL:
+		return p.parseCallStatement()
 	case scanner.COMMIT:
 		return p.parseCommitStatement()
 	case scanner.SELECT:
@@ -91,14 +243,32 @@ func (p *Parser) ParseStatement() (query.Statement, error) {
 		return p.parseDropStatement()
 	case scanner.EXPLAIN:
 		return p.parseExplainStatement()
+	case scanner.KILL:
+		return p.parseKillStatement()
+	case scanner.PRAGMA:
+		return p.parsePragmaStatement()
 	case scanner.REINDEX:
 		return p.parseReIndexStatement()
+	case scanner.RELEASE:
+		return p.parseReleaseStatement()
 	case scanner.ROLLBACK:
 		return p.parseRollbackStatement()
+	case scanner.SAVEPOINT:
+		return p.parseSavepointStatement()
+	case scanner.SET:
+		return p.parseSetStatement()
+	case scanner.SHOW:
+		return p.parseShowStatement()
+	case scanner.DESCRIBE:
+		return p.parseDescribeStatement()
+	case scanner.DETACH:
+		return p.parseDetachStatement()
+	case scanner.WITH:
+		return p.parseWithStatement()
 	}
 
 	return nil, newParseError(scanner.Tokstr(tok, lit), []string{
-		"ALTER", "BEGIN", "COMMIT", "SELECT", "DELETE", "UPDATE", "INSERT", "CREATE", "DROP", "EXPLAIN", "REINDEX", "ROLLBACK",
+		"ALTER", "ANALYZE", "ATTACH", "BEGIN", "CALL", "COMMIT", "SELECT", "DELETE", "UPDATE", "INSERT", "CREATE", "DROP", "EXPLAIN", "KILL", "PRAGMA", "REINDEX", "RELEASE", "ROLLBACK", "SAVEPOINT", "SET", "SHOW", "DESCRIBE", "DETACH", "WITH",
 	}, pos)
 }
 
@@ -160,14 +330,53 @@ func (p *Parser) parsePathList() ([]document.ValuePath, error) {
 	return paths, nil
 }
 
+// enterTable records the table read by the statement being parsed,
+// which is the one referenced by the subqueries of its expressions.
+// The returned function must be called once the statement is parsed.
+func (p *Parser) enterTable(name string) func() {
+	p.tables = append(p.tables, name)
+	return func() { p.tables = p.tables[:len(p.tables)-1] }
+}
+
+// currentTable returns the table read by the innermost statement being parsed, if any.
+func (p *Parser) currentTable() string {
+	if len(p.tables) == 0 {
+		return ""
+	}
+
+	return p.tables[len(p.tables)-1]
+}
+
 // Scan returns the next token from the underlying scanner.
+// Once a limit is exceeded, it returns EOF.
 func (p *Parser) Scan() (tok scanner.Token, pos scanner.Pos, lit string) {
+	if p.limitErr != nil {
+		return scanner.EOF, p.s.Curr().Pos, ""
+	}
+
 	ti := p.s.Scan()
 	if p.buf != nil {
 		p.buf.WriteString(ti.Raw)
 	}
 
 	tok, pos, lit = ti.Tok, ti.Pos, ti.Lit
+
+	p.size += len(ti.Raw)
+	if tok != scanner.WS && tok != scanner.COMMENT && tok != scanner.EOF {
+		p.tokens++
+	}
+
+	limits := p.opts.Limits
+	switch {
+	case limits.MaxLength > 0 && p.size > limits.MaxLength:
+		p.limitErr = &ParseError{Message: fmt.Sprintf("query exceeds the maximum length of %d bytes", limits.MaxLength), Pos: pos}
+	case limits.MaxTokens > 0 && p.tokens > limits.MaxTokens:
+		p.limitErr = &ParseError{Message: fmt.Sprintf("query exceeds the maximum number of %d tokens", limits.MaxTokens), Pos: pos}
+	}
+	if p.limitErr != nil {
+		return scanner.EOF, pos, ""
+	}
+
 	return
 }
 
@@ -184,19 +393,31 @@ func (p *Parser) ScanIgnoreWhitespace() (tok scanner.Token, pos scanner.Pos, lit
 
 // Unscan pushes the previously read token back onto the buffer.
 func (p *Parser) Unscan() {
+	if p.limitErr != nil {
+		return
+	}
+
+	ti := p.s.Curr()
 	if p.buf != nil {
-		ti := p.s.Curr()
 		p.buf.Truncate(p.buf.Len() - len(ti.Raw))
 	}
+	p.size -= len(ti.Raw)
+	if ti.Tok != scanner.WS && ti.Tok != scanner.COMMENT && ti.Tok != scanner.EOF {
+		p.tokens--
+	}
 	p.s.Unscan()
 }
 
-// ParseError represents an error that occurred during parsing.
+// ParseError represents a syntax error that occurred during parsing.
+// It implements the database.Coder interface.
 type ParseError struct {
 	Message  string
 	Found    string
 	Expected []string
-	Pos      scanner.Pos
+	// Pos is the zero-based position of the offending token.
+	Pos scanner.Pos
+	// Context is the line of the source where the error occurred, if known.
+	Context string
 }
 
 // newParseError returns a new instance of ParseError.
@@ -211,3 +432,59 @@ func (e *ParseError) Error() string {
 	}
 	return fmt.Sprintf("found %s, expected %s at line %d, char %d", e.Found, strings.Join(e.Expected, ", "), e.Pos.Line+1, e.Pos.Char+1)
 }
+
+// Line returns the line where the error occurred, starting at 1.
+func (e *ParseError) Line() int {
+	return e.Pos.Line + 1
+}
+
+// Column returns the column where the error occurred, starting at 1.
+func (e *ParseError) Column() int {
+	return e.Pos.Char + 1
+}
+
+// Snippet returns the line of the source where the error occurred followed by a line
+// pointing to the offending token, or an empty string if the context is unknown.
+func (e *ParseError) Snippet() string {
+	if e.Context == "" {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(e.Context)
+	sb.WriteByte('\n')
+	for i, r := range []rune(e.Context) {
+		if i >= e.Pos.Char {
+			break
+		}
+		// keep tabs to align the caret with the token
+		if r == '\t' {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteByte(' ')
+		}
+	}
+	sb.WriteByte('^')
+	return sb.String()
+}
+
+// withContext adds the line of s where the error occurred
+// to err, if it is a *ParseError.
+func withContext(err error, s string) error {
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		return err
+	}
+
+	lines := strings.Split(s, "\n")
+	if perr.Pos.Line < len(lines) {
+		perr.Context = strings.TrimRight(lines[perr.Pos.Line], "\r")
+	}
+
+	return err
+}
+
+// Code returns database.CodeSyntaxError.
+func (e *ParseError) Code() database.Code {
+	return database.CodeSyntaxError
+}
@@ -2,8 +2,10 @@ package parser
 
 import (
 	"context"
+	"errors"
 	"testing"
 
+	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/sql/planner"
 	"github.com/genjidb/genji/sql/query"
 	"github.com/stretchr/testify/require"
@@ -43,3 +45,120 @@ func TestParserMultiStatement(t *testing.T) {
 		})
 	}
 }
+
+func TestParserErrorCode(t *testing.T) {
+	_, err := ParseQuery(context.Background(), "SELECT FROM")
+	require.Error(t, err)
+	require.Equal(t, database.CodeSyntaxError, database.CodeOf(err))
+
+	var perr *ParseError
+	require.True(t, errors.As(err, &perr))
+	require.Equal(t, 0, perr.Pos.Line)
+}
+
+func TestParserErrorPosition(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		line     int
+		column   int
+		expected string
+		snippet  string
+	}{
+		{"first line", "SELECT FROM foo", 1, 8, "found FROM, expected identifier, string, number, bool at line 1, char 8", "SELECT FROM foo\n       ^"},
+		{"second line", "SELECT a FROM foo;\n\tUPDATE foo SETT a = 1", 2, 13,
+			"found SETT, expected SET, UNSET at line 2, char 13", "\tUPDATE foo SETT a = 1\n\t           ^"},
+		{"message", "SELECT * FROM foo WHERE a = $a AND b = ?", 1, 40,
+			"cannot mix positional arguments with named arguments at line 1, char 40", "SELECT * FROM foo WHERE a = $a AND b = ?\n                                       ^"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseQuery(context.Background(), test.s)
+			var perr *ParseError
+			require.True(t, errors.As(err, &perr))
+			require.Equal(t, test.line, perr.Line())
+			require.Equal(t, test.column, perr.Column())
+			require.Equal(t, test.expected, perr.Error())
+			require.Equal(t, test.snippet, perr.Snippet())
+		})
+	}
+}
+
+func TestParserRecover(t *testing.T) {
+	tests := []struct {
+		name       string
+		s          string
+		statements int
+		errors     []string
+	}{
+		{"no errors", "SELECT 1; SELECT 2", 2, nil},
+		{"one error", "SELECT 1; SELECT FROM foo; SELECT 2", 2, []string{
+			"found FROM, expected identifier, string, number, bool at line 1, char 18",
+		}},
+		{"error at end of statement", "SELECT 1; DELETE; SELECT 2", 2, []string{
+			"found ;, expected FROM at line 1, char 17",
+		}},
+		{"multiple errors", "SELECT FROM foo;\nUPDATE foo SETT a = 1;\nSELECT 1 2;\nSELECT 3", 2, []string{
+			"found FROM, expected identifier, string, number, bool at line 1, char 8",
+			"found SETT, expected SET, UNSET at line 2, char 12",
+			"found 2, expected ; at line 3, char 10",
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q, err := ParseQueryWithOptions(context.Background(), test.s, &Options{Recover: true})
+			require.Len(t, q.Statements, test.statements)
+			if test.errors == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			errs, ok := err.(Errors)
+			require.True(t, ok)
+			require.Len(t, errs, len(test.errors))
+			for i := range errs {
+				require.EqualError(t, errs[i], test.errors[i])
+				require.NotEmpty(t, errs[i].(*ParseError).Snippet())
+			}
+		})
+	}
+
+	// without the option, parsing stops at the first error
+	_, err := ParseQuery(context.Background(), "SELECT FROM foo; SELECT FROM foo")
+	require.IsType(t, &ParseError{}, err)
+}
+
+func TestParserLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		limits   Limits
+		expected string
+	}{
+		{"no limits", "SELECT ((((1)))) FROM foo WHERE a IN [1, 2, 3]", Limits{}, ""},
+		{"length ok", "SELECT 1", Limits{MaxLength: 8}, ""},
+		{"length", "SELECT 1; SELECT 2", Limits{MaxLength: 12}, "query exceeds the maximum length of 12 bytes at line 1, char 11"},
+		{"tokens ok", "SELECT a FROM foo", Limits{MaxTokens: 4}, ""},
+		{"tokens", "SELECT a FROM foo WHERE a IN [1, 2, 3, 4]", Limits{MaxTokens: 10}, "query exceeds the maximum number of 10 tokens at line 1, char 34"},
+		{"depth ok", "SELECT ((1))", Limits{MaxDepth: 3}, ""},
+		{"depth", "SELECT (([1, {a: (1)}]))", Limits{MaxDepth: 4}, "expression exceeds the maximum nesting depth of 4 at line 1, char 18"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseQueryWithOptions(context.Background(), test.s, &Options{Limits: test.limits})
+			if test.expected == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			require.EqualError(t, err, test.expected)
+
+			// limits are enforced even when recovering from errors
+			_, err = ParseQueryWithOptions(context.Background(), test.s, &Options{Recover: true, Limits: test.limits})
+			require.EqualError(t, err, test.expected)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/scanner"
+)
+
+// parsePragmaStatement parses a PRAGMA statement, with an optional setting name,
+// optionally followed by "= value", and returns a Statement AST object.
+// This function assumes the PRAGMA token has already been consumed.
+func (p *Parser) parsePragmaStatement() (query.PragmaStmt, error) {
+	var stmt query.PragmaStmt
+
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.IDENT {
+		p.Unscan()
+		return stmt, nil
+	}
+	p.Unscan()
+
+	var err error
+	stmt.Name, err = p.parseIdent()
+	if err != nil {
+		return stmt, err
+	}
+	stmt.Name = strings.ToLower(stmt.Name)
+
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.EQ {
+		p.Unscan()
+		return stmt, nil
+	}
+
+	stmt.Value, _, err = p.ParseExpr()
+	return stmt, err
+}
+
+// parseSetStatement parses "GLOBAL name = value" or "statement_timeout = value"
+// and returns a Statement AST object. GLOBAL is not a reserved keyword.
+// This function assumes the SET token has already been consumed.
+func (p *Parser) parseSetStatement() (query.Statement, error) {
+	if p.parseOptionalKeyword("GLOBAL") {
+		return p.parseSetGlobalStatement()
+	}
+
+	// statement_timeout is the only setting that can be set for a query
+	tok, pos, lit := p.ScanIgnoreWhitespace()
+	if tok != scanner.IDENT || !strings.EqualFold(lit, "statement_timeout") {
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"GLOBAL", "statement_timeout"}, pos)
+	}
+
+	stmt := query.SetStmt{Name: strings.ToLower(lit)}
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.EQ {
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"="}, pos)
+	}
+
+	var err error
+	stmt.Value, _, err = p.ParseExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	return stmt, nil
+}
+
+// parseSetGlobalStatement parses "name = value" and returns a Statement AST object.
+// This function assumes the SET GLOBAL tokens have already been consumed.
+func (p *Parser) parseSetGlobalStatement() (query.SetGlobalStmt, error) {
+	var stmt query.SetGlobalStmt
+
+	var err error
+	stmt.Name, err = p.parseIdent()
+	if err != nil {
+		pErr := err.(*ParseError)
+		pErr.Expected = []string{"setting_name"}
+		return stmt, pErr
+	}
+	stmt.Name = strings.ToLower(stmt.Name)
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.EQ {
+		return stmt, newParseError(scanner.Tokstr(tok, lit), []string{"="}, pos)
+	}
+
+	stmt.Value, _, err = p.ParseExpr()
+	return stmt, err
+}
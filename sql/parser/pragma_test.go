@@ -0,0 +1,46 @@
+package parser
+
+import (
+	"context"
+	"testing"
+
+	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/query/expr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParserPragma(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		expected query.Statement
+		errored  bool
+	}{
+		{"All", "PRAGMA", query.PragmaStmt{}, false},
+		{"One", "PRAGMA busy_timeout", query.PragmaStmt{Name: "busy_timeout"}, false},
+		{"Lowercased", "pragma BUSY_TIMEOUT", query.PragmaStmt{Name: "busy_timeout"}, false},
+		{"Set", "PRAGMA busy_timeout = '10s'", query.PragmaStmt{Name: "busy_timeout", Value: expr.TextValue("10s")}, false},
+		{"Set with param", "PRAGMA max_recursion = ?", query.PragmaStmt{Name: "max_recursion", Value: expr.PositionalParam(1)}, false},
+		{"Missing value", "PRAGMA max_recursion =", nil, true},
+		{"Set global", "SET GLOBAL max_recursion = 10", query.SetGlobalStmt{Name: "max_recursion", Value: expr.IntegerValue(10)}, false},
+		{"Set global lowercased", "set global Busy_Mode = 'error'", query.SetGlobalStmt{Name: "busy_mode", Value: expr.TextValue("error")}, false},
+		{"Set without GLOBAL", "SET max_recursion = 10", nil, true},
+		{"Set global without value", "SET GLOBAL max_recursion", nil, true},
+		{"Set global without name", "SET GLOBAL = 10", nil, true},
+		{"Set statement timeout", "SET Statement_Timeout = '1s'", query.SetStmt{Name: "statement_timeout", Value: expr.TextValue("1s")}, false},
+		{"Set statement timeout without value", "SET statement_timeout =", nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q, err := ParseQuery(context.Background(), test.s)
+			if test.errored {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, q.Statements, 1)
+			require.EqualValues(t, test.expected, q.Statements[0])
+		})
+	}
+}
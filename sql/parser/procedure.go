@@ -0,0 +1,268 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/genjidb/genji/sql/planner"
+	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/scanner"
+)
+
+// parseCreateProcedureStatement parses "name (param, ...) BEGIN statement; ... END"
+// and returns a Statement AST object.
+// This function assumes the CREATE PROCEDURE tokens have already been consumed.
+func (p *Parser) parseCreateProcedureStatement() (query.CreateProcedureStmt, error) {
+	var stmt query.CreateProcedureStmt
+	var err error
+
+	// Parse IF NOT EXISTS
+	stmt.IfNotExists, err = p.parseIfNotExists()
+	if err != nil {
+		return stmt, err
+	}
+
+	stmt.ProcedureName, err = p.parseIdent()
+	if err != nil {
+		pErr := err.(*ParseError)
+		pErr.Expected = []string{"procedure_name"}
+		return stmt, pErr
+	}
+
+	// Parse the list of parameters, which can be empty
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.LPAREN {
+		return stmt, newParseError(scanner.Tokstr(tok, lit), []string{"("}, pos)
+	}
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.RPAREN {
+		p.Unscan()
+
+		stmt.Params, err = p.parseIdentList()
+		if err != nil {
+			return stmt, err
+		}
+
+		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.RPAREN {
+			return stmt, newParseError(scanner.Tokstr(tok, lit), []string{")"}, pos)
+		}
+	}
+
+	stmt.Statements, err = p.parseProcedureBody()
+	if err != nil {
+		return stmt, err
+	}
+	stmt.Body = formatProcedureBody(stmt.Statements)
+
+	return stmt, nil
+}
+
+// parseProcedureBody parses "BEGIN statement; ... END".
+// Parameters of the procedure and fields of the documents of FOR loops
+// are referenced as named parameters, positional parameters are not allowed.
+func (p *Parser) parseProcedureBody() ([]query.Statement, error) {
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.BEGIN {
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"BEGIN"}, pos)
+	}
+
+	ordered, named := p.orderedParams, p.namedParams
+	p.orderedParams, p.namedParams = 0, 1
+	defer func() { p.orderedParams, p.namedParams = ordered, named }()
+
+	stmts, _, err := p.parseProcedureStatements("END")
+	return stmts, err
+}
+
+// parseProcedureStatements parses a list of statements, each followed by a semicolon,
+// until one of the given non-reserved keywords, which is returned.
+func (p *Parser) parseProcedureStatements(end ...string) ([]query.Statement, string, error) {
+	var stmts []query.Statement
+
+	for {
+		for _, kw := range end {
+			if p.parseOptionalKeyword(kw) {
+				return stmts, kw, nil
+			}
+		}
+
+		stmt, err := p.parseProcedureStatement()
+		if err != nil {
+			return nil, "", err
+		}
+		stmts = append(stmts, stmt)
+
+		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.SEMICOLON {
+			return nil, "", newParseError(scanner.Tokstr(tok, lit), []string{";"}, pos)
+		}
+	}
+}
+
+// parseProcedureStatement parses a statement of the body of a procedure:
+// either an IF or FOR statement, or any statement which doesn't control transactions.
+// IF is a reserved keyword, FOR is not.
+func (p *Parser) parseProcedureStatement() (query.Statement, error) {
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok == scanner.IF {
+		return p.parseIfStatement()
+	}
+	p.Unscan()
+
+	if p.parseOptionalKeyword("FOR") {
+		return p.parseForStatement()
+	}
+
+	tok, pos, lit := p.ScanIgnoreWhitespace()
+	switch tok {
+	case scanner.BEGIN, scanner.COMMIT, scanner.ROLLBACK, scanner.SAVEPOINT, scanner.RELEASE:
+		return nil, &ParseError{Message: "transactions can't be controlled within a procedure", Pos: pos}
+	case scanner.EOF:
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"END"}, pos)
+	}
+	p.Unscan()
+
+	return p.parseStatement()
+}
+
+// parseIfStatement parses "cond THEN statement; ... [ELSE statement; ...] END IF".
+// This function assumes the IF token has already been consumed.
+// THEN, ELSE and END are not reserved keywords.
+func (p *Parser) parseIfStatement() (*planner.IfStmt, error) {
+	var stmt planner.IfStmt
+	var err error
+
+	stmt.Cond, _, err = p.ParseExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.parseOptionalKeyword("THEN") {
+		tok, pos, lit := p.ScanIgnoreWhitespace()
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"THEN"}, pos)
+	}
+
+	var kw string
+	stmt.Then, kw, err = p.parseProcedureStatements("ELSE", "END")
+	if err != nil {
+		return nil, err
+	}
+
+	if kw == "ELSE" {
+		stmt.Else, _, err = p.parseProcedureStatements("END")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.IF {
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"IF"}, pos)
+	}
+
+	return &stmt, nil
+}
+
+// parseForStatement parses "SELECT ... DO statement; ... END FOR".
+// This function assumes the FOR token has already been consumed.
+// END and FOR are not reserved keywords.
+func (p *Parser) parseForStatement() (*planner.ForStmt, error) {
+	var stmt planner.ForStmt
+	var err error
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.SELECT {
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"SELECT"}, pos)
+	}
+
+	stmt.Cursor, err = p.parseSelectStatement()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.DO {
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"DO"}, pos)
+	}
+
+	stmt.Body, _, err = p.parseProcedureStatements("END")
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.parseOptionalKeyword("FOR") {
+		tok, pos, lit := p.ScanIgnoreWhitespace()
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"FOR"}, pos)
+	}
+
+	return &stmt, nil
+}
+
+// parseDropProcedureStatement parses a drop procedure string and returns a Statement AST object.
+// This function assumes the DROP PROCEDURE tokens have already been consumed.
+func (p *Parser) parseDropProcedureStatement() (query.DropProcedureStmt, error) {
+	var stmt query.DropProcedureStmt
+	var err error
+
+	// Parse IF EXISTS
+	stmt.IfExists, err = p.parseIfExists()
+	if err != nil {
+		return stmt, err
+	}
+
+	stmt.ProcedureName, err = p.parseIdent()
+	if err != nil {
+		pErr := err.(*ParseError)
+		pErr.Expected = []string{"procedure_name"}
+		return stmt, pErr
+	}
+
+	return stmt, nil
+}
+
+// parseCallStatement parses "name (arg, ...)" and returns a Statement AST object.
+// This function assumes the CALL token has already been consumed.
+func (p *Parser) parseCallStatement() (*planner.CallStmt, error) {
+	var stmt planner.CallStmt
+	var err error
+
+	stmt.ProcedureName, err = p.parseIdent()
+	if err != nil {
+		pErr := err.(*ParseError)
+		pErr.Expected = []string{"procedure_name"}
+		return nil, pErr
+	}
+
+	stmt.Args, err = p.parseExprList(scanner.LPAREN, scanner.RPAREN)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt.Compile = compileProcedure
+	return &stmt, nil
+}
+
+// compileProcedure parses the body of a stored procedure.
+func compileProcedure(body string) ([]query.Statement, error) {
+	p := NewParser(strings.NewReader(body))
+
+	stmts, err := p.parseProcedureBody()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.EOF {
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"EOF"}, pos)
+	}
+
+	return stmts, nil
+}
+
+// formatProcedureBody returns the SQL representation of the body of a procedure.
+func formatProcedureBody(stmts []query.Statement) string {
+	return "BEGIN " + formatProcedureStatements(stmts) + "END"
+}
+
+// formatProcedureStatements returns the SQL representation of stmts,
+// each followed by a semicolon and a space.
+func formatProcedureStatements(stmts []query.Statement) string {
+	var b strings.Builder
+
+	for _, stmt := range stmts {
+		b.WriteString(Format(stmt))
+		b.WriteString("; ")
+	}
+
+	return b.String()
+}
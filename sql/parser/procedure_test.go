@@ -0,0 +1,125 @@
+package parser
+
+import (
+	"context"
+	"testing"
+
+	"github.com/genjidb/genji/sql/planner"
+	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/query/expr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParserCreateProcedure(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		params  []string
+		body    string
+		errored bool
+	}{
+		{"No params", "CREATE PROCEDURE p() BEGIN DELETE FROM test; END", nil, "BEGIN DELETE FROM test; END", false},
+		{"Params", "CREATE PROCEDURE p(a, b) BEGIN DELETE FROM test WHERE a = $a; UPDATE test SET b = $b; END", []string{"a", "b"}, "BEGIN DELETE FROM test WHERE a = $a; UPDATE test SET b = $b; END", false},
+		{"Empty body", "CREATE PROCEDURE p() BEGIN END", nil, "BEGIN END", false},
+		{"Nested", "CREATE PROCEDURE p() BEGIN FOR SELECT a FROM test DO IF $a > 1 THEN CALL q($a); END IF; END FOR; END", nil, "BEGIN FOR SELECT a FROM test DO IF $a > 1 THEN CALL q($a); END IF; END FOR; END", false},
+		{"Keywords are case insensitive", "create procedure p() begin if true then select 1; else select 2; end if; end", nil, "BEGIN IF true THEN SELECT 1; ELSE SELECT 2; END IF; END", false},
+		{"Missing params", "CREATE PROCEDURE p BEGIN END", nil, "", true},
+		{"Missing semicolon", "CREATE PROCEDURE p() BEGIN SELECT 1 END", nil, "", true},
+		{"Missing END", "CREATE PROCEDURE p() BEGIN SELECT 1;", nil, "", true},
+		{"Positional params", "CREATE PROCEDURE p() BEGIN DELETE FROM test WHERE a = ?; END", nil, "", true},
+		{"Transaction control", "CREATE PROCEDURE p() BEGIN COMMIT; END", nil, "", true},
+		{"Savepoint", "CREATE PROCEDURE p() BEGIN SAVEPOINT sp; END", nil, "", true},
+		{"IF without THEN", "CREATE PROCEDURE p() BEGIN IF true SELECT 1; END IF; END", nil, "", true},
+		{"IF without END IF", "CREATE PROCEDURE p() BEGIN IF true THEN SELECT 1; END; END", nil, "", true},
+		{"FOR without DO", "CREATE PROCEDURE p() BEGIN FOR SELECT 1 SELECT 1; END FOR; END", nil, "", true},
+		{"FOR without SELECT", "CREATE PROCEDURE p() BEGIN FOR DELETE FROM test DO SELECT 1; END FOR; END", nil, "", true},
+		{"IF outside of a procedure", "IF true THEN SELECT 1; END IF", nil, "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q, err := ParseQuery(context.Background(), test.s)
+			if test.errored {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, q.Statements, 1)
+
+			stmt, ok := q.Statements[0].(query.CreateProcedureStmt)
+			require.True(t, ok)
+			require.Equal(t, "p", stmt.ProcedureName)
+			require.Equal(t, test.params, stmt.Params)
+			require.Equal(t, test.body, stmt.Body)
+
+			// the body is parsed again when the procedure is called
+			stmts, err := compileProcedure(stmt.Body)
+			require.NoError(t, err)
+			require.Equal(t, stmt.Body, formatProcedureBody(stmts))
+		})
+	}
+
+	t.Run("Positional params outside of the body", func(t *testing.T) {
+		q, err := ParseQuery(context.Background(), "CREATE PROCEDURE p(a) BEGIN SELECT $a; END; CALL p(?)")
+		require.NoError(t, err)
+		require.Len(t, q.Statements, 2)
+	})
+}
+
+func TestParserCall(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		args    []expr.Expr
+		errored bool
+	}{
+		{"No args", "CALL p()", nil, false},
+		{"Args", "CALL p(1, $a + 1)", []expr.Expr{expr.IntegerValue(1), expr.Add(expr.NamedParam("a"), expr.IntegerValue(1))}, false},
+		{"Missing parentheses", "CALL p", nil, true},
+		{"Missing name", "CALL (1)", nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q, err := ParseQuery(context.Background(), test.s)
+			if test.errored {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, q.Statements, 1)
+
+			stmt, ok := q.Statements[0].(*planner.CallStmt)
+			require.True(t, ok)
+			require.Equal(t, "p", stmt.ProcedureName)
+			require.EqualValues(t, test.args, stmt.Args)
+			require.NotNil(t, stmt.Compile)
+		})
+	}
+}
+
+func TestParserDropProcedure(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		expected query.Statement
+		errored  bool
+	}{
+		{"Drop procedure", "DROP PROCEDURE p", query.DropProcedureStmt{ProcedureName: "p"}, false},
+		{"Drop procedure if exists", "DROP PROCEDURE IF EXISTS p", query.DropProcedureStmt{ProcedureName: "p", IfExists: true}, false},
+		{"Missing name", "DROP PROCEDURE", nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q, err := ParseQuery(context.Background(), test.s)
+			if test.errored {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, q.Statements, 1)
+			require.EqualValues(t, test.expected, q.Statements[0])
+		})
+	}
+}
@@ -2,7 +2,9 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/sql/planner"
 	"github.com/genjidb/genji/sql/query/expr"
 	"github.com/genjidb/genji/sql/scanner"
@@ -14,6 +16,10 @@ func (p *Parser) parseSelectStatement() (*planner.Tree, error) {
 	var cfg selectConfig
 	var err error
 
+	// the table is not known until FROM is parsed,
+	// see planner.NewProjectionNode
+	defer p.enterTable("")()
+
 	// Parse path list or query.Wildcard
 	cfg.ProjectionExprs, err = p.parseResultFields()
 	if err != nil {
@@ -22,13 +28,38 @@ func (p *Parser) parseSelectStatement() (*planner.Tree, error) {
 
 	// Parse "FROM".
 	var found bool
-	cfg.TableName, found, err = p.parseFrom()
+	cfg.TableName, cfg.Derived, found, err = p.parseFrom()
 	if err != nil {
 		return nil, err
 	}
 	if !found {
 		return cfg.ToTree()
 	}
+	if cfg.Derived == nil {
+		cfg.Alias, err = p.parseTableAlias()
+		if err != nil {
+			return nil, err
+		}
+	}
+	p.tables[len(p.tables)-1] = cfg.name()
+
+	// Parse sample: "TABLESAMPLE expr PERCENT|ROWS [REPEATABLE (expr)]"
+	cfg.SampleExpr, cfg.SampleMethod, cfg.SampleSeedExpr, err = p.parseSample()
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse joins: "[INNER | LEFT [OUTER]] JOIN table_name [AS alias] ON expr"
+	cfg.Joins, err = p.parseJoins()
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse lateral subqueries: ", LATERAL (select) [AS] alias"
+	cfg.Laterals, err = p.parseLaterals()
+	if err != nil {
+		return nil, err
+	}
 
 	// Parse condition: "WHERE expr".
 	cfg.WhereExpr, err = p.parseCondition()
@@ -43,7 +74,7 @@ func (p *Parser) parseSelectStatement() (*planner.Tree, error) {
 	}
 
 	// Parse order by: "ORDER BY path [ASC|DESC]?"
-	cfg.OrderBy, cfg.OrderByDirection, err = p.parseOrderBy()
+	cfg.OrderBy, err = p.parseOrderBy()
 	if err != nil {
 		return nil, err
 	}
@@ -122,21 +153,252 @@ func (p *Parser) parseResultField() (planner.ProjectedField, error) {
 	return rf, nil
 }
 
-func (p *Parser) parseFrom() (string, bool, error) {
+// parseReturningClause parses the "RETURNING" clause of INSERT, UPDATE and DELETE statements, if it exists.
+func (p *Parser) parseReturningClause() ([]planner.ProjectedField, error) {
+	// RETURNING is not a reserved keyword
+	if tok, _, lit := p.ScanIgnoreWhitespace(); tok != scanner.IDENT || !strings.EqualFold(lit, "RETURNING") {
+		p.Unscan()
+		return nil, nil
+	}
+
+	return p.parseResultFields()
+}
+
+// parseFrom parses the optional "FROM" clause: either a table name or
+// a derived table, "(select) [AS] alias", whose alias is returned as the table name.
+func (p *Parser) parseFrom() (string, *planner.Tree, bool, error) {
 	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.FROM {
 		p.Unscan()
-		return "", false, nil
+		return "", nil, false, nil
 	}
 
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok == scanner.LPAREN {
+		t, alias, err := p.parseDerivedTable()
+		return alias, t, true, err
+	}
+	p.Unscan()
+
 	// Parse table name
-	ident, err := p.parseIdent()
+	ident, err := p.parseTableName()
 	if err != nil {
 		pErr := err.(*ParseError)
 		pErr.Expected = []string{"table_name"}
-		return ident, true, pErr
+		return ident, nil, true, pErr
+	}
+
+	return ident, nil, true, nil
+}
+
+// parseTableAlias parses the optional "AS alias" following the name of a table.
+// Unlike the alias of a derived table, AS is required, since JOIN, LEFT or SAMPLE,
+// which may follow the name of the table, are not reserved keywords.
+func (p *Parser) parseTableAlias() (string, error) {
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.AS {
+		p.Unscan()
+		return "", nil
+	}
+
+	alias, err := p.parseIdent()
+	if err != nil {
+		pErr := err.(*ParseError)
+		pErr.Expected = []string{"alias"}
+		return "", pErr
+	}
+
+	return alias, nil
+}
+
+// parseDerivedTable parses "select) [AS] alias".
+// This function assumes the left parenthesis has already been consumed.
+func (p *Parser) parseDerivedTable() (*planner.Tree, string, error) {
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.SELECT {
+		return nil, "", newParseError(scanner.Tokstr(tok, lit), []string{"SELECT"}, pos)
+	}
+
+	t, err := p.parseSelectStatement()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.RPAREN {
+		return nil, "", newParseError(scanner.Tokstr(tok, lit), []string{")"}, pos)
+	}
+
+	// the alias is required, AS is optional
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.AS {
+		p.Unscan()
 	}
 
-	return ident, true, nil
+	alias, err := p.parseIdent()
+	if err != nil {
+		pErr := err.(*ParseError)
+		pErr.Expected = []string{"alias"}
+		return nil, "", pErr
+	}
+
+	return t, alias, nil
+}
+
+// parseSample parses the optional "TABLESAMPLE" or "SAMPLE" clause.
+// TABLESAMPLE, SAMPLE, PERCENT, ROWS and REPEATABLE are not reserved keywords,
+// to allow them to be used as field names.
+func (p *Parser) parseSample() (size expr.Expr, method planner.SampleMethod, seed expr.Expr, err error) {
+	if tok, _, lit := p.ScanIgnoreWhitespace(); tok != scanner.IDENT || (!strings.EqualFold(lit, "TABLESAMPLE") && !strings.EqualFold(lit, "SAMPLE")) {
+		p.Unscan()
+		return nil, 0, nil, nil
+	}
+
+	size, _, err = p.ParseExpr()
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	tok, pos, lit := p.ScanIgnoreWhitespace()
+	switch {
+	case tok == scanner.IDENT && strings.EqualFold(lit, "PERCENT"):
+		method = planner.SamplePercent
+	case tok == scanner.IDENT && strings.EqualFold(lit, "ROWS"):
+		method = planner.SampleRows
+	default:
+		return nil, 0, nil, newParseError(scanner.Tokstr(tok, lit), []string{"PERCENT", "ROWS"}, pos)
+	}
+
+	if tok, _, lit := p.ScanIgnoreWhitespace(); tok != scanner.IDENT || !strings.EqualFold(lit, "REPEATABLE") {
+		p.Unscan()
+		return size, method, nil, nil
+	}
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.LPAREN {
+		return nil, 0, nil, newParseError(scanner.Tokstr(tok, lit), []string{"("}, pos)
+	}
+
+	seed, _, err = p.ParseExpr()
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.RPAREN {
+		return nil, 0, nil, newParseError(scanner.Tokstr(tok, lit), []string{")"}, pos)
+	}
+
+	return size, method, seed, nil
+}
+
+// parseJoins parses the list of joins following the table name.
+// JOIN, INNER, LEFT and OUTER are not reserved keywords.
+func (p *Parser) parseJoins() ([]joinConfig, error) {
+	var joins []joinConfig
+
+	for {
+		var j joinConfig
+
+		tok, _, lit := p.ScanIgnoreWhitespace()
+		if tok != scanner.IDENT {
+			p.Unscan()
+			return joins, nil
+		}
+
+		switch {
+		case strings.EqualFold(lit, "JOIN"):
+		case strings.EqualFold(lit, "INNER"):
+			if err := p.parseJoinKeyword(); err != nil {
+				return nil, err
+			}
+		case strings.EqualFold(lit, "LEFT"):
+			j.Type = planner.LeftJoin
+			if tok, _, lit := p.ScanIgnoreWhitespace(); tok != scanner.IDENT || !strings.EqualFold(lit, "OUTER") {
+				p.Unscan()
+			}
+			if err := p.parseJoinKeyword(); err != nil {
+				return nil, err
+			}
+		default:
+			p.Unscan()
+			return joins, nil
+		}
+
+		var err error
+		j.TableName, err = p.parseTableName()
+		if err != nil {
+			pErr := err.(*ParseError)
+			pErr.Expected = []string{"table_name"}
+			return nil, pErr
+		}
+
+		j.Alias, err = p.parseTableAlias()
+		if err != nil {
+			return nil, err
+		}
+
+		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.ON {
+			return nil, newParseError(scanner.Tokstr(tok, lit), []string{"ON"}, pos)
+		}
+
+		j.On, _, err = p.ParseExpr()
+		if err != nil {
+			return nil, err
+		}
+
+		joins = append(joins, j)
+	}
+}
+
+func (p *Parser) parseJoinKeyword() error {
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.IDENT || !strings.EqualFold(lit, "JOIN") {
+		return newParseError(scanner.Tokstr(tok, lit), []string{"JOIN"}, pos)
+	}
+
+	return nil
+}
+
+// parseLaterals parses the list of lateral subqueries following the table name.
+// LATERAL is not a reserved keyword.
+func (p *Parser) parseLaterals() ([]lateralConfig, error) {
+	var laterals []lateralConfig
+
+	for {
+		if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.COMMA {
+			p.Unscan()
+			return laterals, nil
+		}
+
+		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.IDENT || !strings.EqualFold(lit, "LATERAL") {
+			return nil, newParseError(scanner.Tokstr(tok, lit), []string{"LATERAL"}, pos)
+		}
+
+		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.LPAREN {
+			return nil, newParseError(scanner.Tokstr(tok, lit), []string{"("}, pos)
+		}
+
+		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.SELECT {
+			return nil, newParseError(scanner.Tokstr(tok, lit), []string{"SELECT"}, pos)
+		}
+
+		var l lateralConfig
+		var err error
+		l.Subquery, err = p.parseSelectStatement()
+		if err != nil {
+			return nil, err
+		}
+
+		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.RPAREN {
+			return nil, newParseError(scanner.Tokstr(tok, lit), []string{")"}, pos)
+		}
+
+		// the alias is required, AS is optional
+		if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.AS {
+			p.Unscan()
+		}
+
+		l.Alias, err = p.parseIdent()
+		if err != nil {
+			pErr := err.(*ParseError)
+			pErr.Expected = []string{"alias"}
+			return nil, pErr
+		}
+
+		laterals = append(laterals, l)
+	}
 }
 
 func (p *Parser) parseGroupBy() (expr.Expr, error) {
@@ -156,31 +418,70 @@ func (p *Parser) parseGroupBy() (expr.Expr, error) {
 	return e, err
 }
 
-func (p *Parser) parseOrderBy() (expr.FieldSelector, scanner.Token, error) {
+// parseOrderBy parses the optional ORDER BY clause, made of one or more
+// paths, each followed by an optional direction and position of null values.
+func (p *Parser) parseOrderBy() ([]planner.SortKey, error) {
 	// parse ORDER token
 	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.ORDER {
 		p.Unscan()
-		return nil, 0, nil
+		return nil, nil
 	}
 
 	// parse BY token
 	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.BY {
-		return nil, 0, newParseError(scanner.Tokstr(tok, lit), []string{"BY"}, pos)
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"BY"}, pos)
 	}
 
-	// parse path
-	ref, err := p.parsePath()
-	if err != nil {
-		return nil, 0, err
+	var keys []planner.SortKey
+	for {
+		// parse path
+		ref, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+
+		// parse optional ASC or DESC
+		var dir scanner.Token
+		if tok, _, _ := p.ScanIgnoreWhitespace(); tok == scanner.ASC || tok == scanner.DESC {
+			dir = tok
+		} else {
+			p.Unscan()
+		}
+
+		nulls, err := p.parseNullsOrder()
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, planner.SortKey{Path: expr.FieldSelector(ref), Direction: dir, Nulls: nulls})
+
+		if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.COMMA {
+			p.Unscan()
+			return keys, nil
+		}
+	}
+}
+
+// parseNullsOrder parses the optional "NULLS FIRST" or "NULLS LAST" clause.
+// NULLS, FIRST and LAST are not reserved keywords, to allow them
+// to be used as field names.
+func (p *Parser) parseNullsOrder() (planner.NullsOrder, error) {
+	if tok, _, lit := p.ScanIgnoreWhitespace(); tok != scanner.IDENT || !strings.EqualFold(lit, "NULLS") {
+		p.Unscan()
+		return planner.NullsDefault, nil
 	}
 
-	// parse optional ASC or DESC
-	if tok, _, _ := p.ScanIgnoreWhitespace(); tok == scanner.ASC || tok == scanner.DESC {
-		return expr.FieldSelector(ref), tok, nil
+	tok, pos, lit := p.ScanIgnoreWhitespace()
+	if tok == scanner.IDENT {
+		switch {
+		case strings.EqualFold(lit, "FIRST"):
+			return planner.NullsFirst, nil
+		case strings.EqualFold(lit, "LAST"):
+			return planner.NullsLast, nil
+		}
 	}
-	p.Unscan()
 
-	return expr.FieldSelector(ref), 0, nil
+	return 0, newParseError(scanner.Tokstr(tok, lit), []string{"FIRST", "LAST"}, pos)
 }
 
 func (p *Parser) parseLimit() (expr.Expr, error) {
@@ -207,24 +508,117 @@ func (p *Parser) parseOffset() (expr.Expr, error) {
 
 // SelectConfig holds SELECT configuration.
 type selectConfig struct {
-	TableName        string
-	WhereExpr        expr.Expr
-	GroupByExpr      expr.Expr
-	OrderBy          expr.FieldSelector
-	OrderByDirection scanner.Token
-	OffsetExpr       expr.Expr
-	LimitExpr        expr.Expr
-	ProjectionExprs  []planner.ProjectedField
+	TableName string
+	// if set, name under which the documents of the table are read.
+	Alias          string
+	SampleExpr     expr.Expr
+	SampleMethod   planner.SampleMethod
+	SampleSeedExpr expr.Expr
+	Joins          []joinConfig
+	Laterals       []lateralConfig
+	// if not nil, documents are read from this subquery, named TableName.
+	Derived         *planner.Tree
+	WhereExpr       expr.Expr
+	GroupByExpr     expr.Expr
+	OrderBy         []planner.SortKey
+	OffsetExpr      expr.Expr
+	LimitExpr       expr.Expr
+	ProjectionExprs []planner.ProjectedField
+}
+
+// joinConfig holds a table joined to the documents of the table of the FROM clause.
+type joinConfig struct {
+	Type      planner.JoinType
+	TableName string
+	Alias     string
+	On        expr.Expr
+}
+
+// name returns the name under which the documents of the joined table are read.
+func (j joinConfig) name() string {
+	if j.Alias != "" {
+		return j.Alias
+	}
+
+	return j.TableName
+}
+
+// lateralConfig holds a subquery of the FROM clause run for every document of the table.
+type lateralConfig struct {
+	Subquery *planner.Tree
+	Alias    string
+}
+
+// name returns the name under which the documents of the FROM clause are read.
+func (cfg selectConfig) name() string {
+	if cfg.Alias != "" {
+		return cfg.Alias
+	}
+
+	return cfg.TableName
 }
 
 // ToTree turns the statement into an expression tree.
 func (cfg selectConfig) ToTree() (*planner.Tree, error) {
 	var n planner.Node
 
-	if cfg.TableName != "" {
+	if cfg.Derived != nil {
+		n = planner.NewDerivedTableNode(cfg.Derived, cfg.TableName)
+	} else if cfg.TableName != "" {
 		n = planner.NewTableInputNode(cfg.TableName)
 	}
 
+	if cfg.SampleExpr != nil {
+		v, err := cfg.SampleExpr.Eval(expr.EvalStack{})
+		if err != nil {
+			return nil, err
+		}
+
+		if !v.Type.IsNumber() {
+			return nil, database.NewError(database.CodeDatatypeMismatch, fmt.Sprintf("sample expression must evaluate to a number, got %q", v.Type))
+		}
+
+		v, err = v.CastAsDouble()
+		if err != nil {
+			return nil, err
+		}
+
+		if cfg.SampleSeedExpr != nil {
+			seed, err := cfg.SampleSeedExpr.Eval(expr.EvalStack{})
+			if err != nil {
+				return nil, err
+			}
+
+			if !seed.Type.IsNumber() {
+				return nil, database.NewError(database.CodeDatatypeMismatch, fmt.Sprintf("repeatable expression must evaluate to a number, got %q", seed.Type))
+			}
+
+			seed, err = seed.CastAsInteger()
+			if err != nil {
+				return nil, err
+			}
+
+			n = planner.NewSampleNodeWithSeed(n, cfg.SampleMethod, v.V.(float64), seed.V.(int64))
+		} else {
+			n = planner.NewSampleNode(n, cfg.SampleMethod, v.V.(float64))
+		}
+	}
+
+	// the documents of each table are read by name
+	names := map[string]bool{cfg.name(): true}
+	for _, j := range cfg.Joins {
+		if names[j.name()] {
+			return nil, database.NewError(database.CodeDuplicateAlias, fmt.Sprintf("table name %q specified more than once", j.name()))
+		}
+		names[j.name()] = true
+
+		n = planner.NewJoinNode(n, cfg.name(), j.Type, j.TableName, j.Alias, j.On)
+	}
+
+	for _, l := range cfg.Laterals {
+		n = planner.NewLateralNode(n, cfg.name(), l.Subquery, l.Alias)
+	}
+
 	if cfg.WhereExpr != nil {
 		n = planner.NewSelectionNode(n, cfg.WhereExpr)
 	}
@@ -233,10 +627,10 @@ func (cfg selectConfig) ToTree() (*planner.Tree, error) {
 		n = planner.NewGroupingNode(n, cfg.GroupByExpr)
 	}
 
-	n = planner.NewProjectionNode(n, cfg.ProjectionExprs, cfg.TableName)
+	n = planner.NewProjectionNodeWithAlias(n, cfg.ProjectionExprs, cfg.TableName, cfg.Alias)
 
 	if cfg.OrderBy != nil {
-		n = planner.NewSortNode(n, cfg.OrderBy, cfg.OrderByDirection)
+		n = planner.NewSortNode(n, cfg.OrderBy...)
 	}
 
 	if cfg.OffsetExpr != nil {
@@ -246,7 +640,7 @@ func (cfg selectConfig) ToTree() (*planner.Tree, error) {
 		}
 
 		if !v.Type.IsNumber() {
-			return nil, fmt.Errorf("offset expression must evaluate to a number, got %q", v.Type)
+			return nil, database.NewError(database.CodeDatatypeMismatch, fmt.Sprintf("offset expression must evaluate to a number, got %q", v.Type))
 		}
 
 		v, err = v.CastAsInteger()
@@ -264,7 +658,7 @@ func (cfg selectConfig) ToTree() (*planner.Tree, error) {
 		}
 
 		if !v.Type.IsNumber() {
-			return nil, fmt.Errorf("limit expression must evaluate to a number, got %q", v.Type)
+			return nil, database.NewError(database.CodeDatatypeMismatch, fmt.Sprintf("limit expression must evaluate to a number, got %q", v.Type))
 		}
 
 		v, err = v.CastAsInteger()
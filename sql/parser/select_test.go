@@ -108,8 +108,7 @@ func TestParserSelect(t *testing.T) {
 						[]planner.ProjectedField{planner.Wildcard{}},
 						"test",
 					),
-					expr.FieldSelector(parsePath(t, "a.b.c")),
-					scanner.ASC,
+					planner.SortKey{Path: expr.FieldSelector(parsePath(t, "a.b.c")), Direction: scanner.ASC},
 				)),
 			false},
 		{"WithOrderBy ASC", "SELECT * FROM test WHERE age = 10 ORDER BY a.b.c ASC",
@@ -123,8 +122,7 @@ func TestParserSelect(t *testing.T) {
 						[]planner.ProjectedField{planner.Wildcard{}},
 						"test",
 					),
-					expr.FieldSelector(parsePath(t, "a.b.c")),
-					scanner.ASC,
+					planner.SortKey{Path: expr.FieldSelector(parsePath(t, "a.b.c")), Direction: scanner.ASC},
 				)),
 			false},
 		{"WithOrderBy DESC", "SELECT * FROM test WHERE age = 10 ORDER BY a.b.c DESC",
@@ -138,10 +136,38 @@ func TestParserSelect(t *testing.T) {
 						[]planner.ProjectedField{planner.Wildcard{}},
 						"test",
 					),
-					expr.FieldSelector(parsePath(t, "a.b.c")),
-					scanner.DESC,
+					planner.SortKey{Path: expr.FieldSelector(parsePath(t, "a.b.c")), Direction: scanner.DESC},
 				)),
 			false},
+		{"WithOrderBy DESC NULLS FIRST", "SELECT * FROM test WHERE age = 10 ORDER BY a.b.c DESC NULLS FIRST",
+			planner.NewTree(
+				planner.NewSortNode(
+					planner.NewProjectionNode(
+						planner.NewSelectionNode(
+							planner.NewTableInputNode("test"),
+							expr.Eq(expr.FieldSelector(parsePath(t, "age")), expr.IntegerValue(10)),
+						),
+						[]planner.ProjectedField{planner.Wildcard{}},
+						"test",
+					),
+					planner.SortKey{Path: expr.FieldSelector(parsePath(t, "a.b.c")), Direction: scanner.DESC, Nulls: planner.NullsFirst},
+				)),
+			false},
+		{"WithOrderBy multiple fields", "SELECT * FROM test ORDER BY city ASC, age DESC NULLS LAST, name",
+			planner.NewTree(
+				planner.NewSortNode(
+					planner.NewProjectionNode(
+						planner.NewTableInputNode("test"),
+						[]planner.ProjectedField{planner.Wildcard{}},
+						"test",
+					),
+					planner.SortKey{Path: expr.FieldSelector(parsePath(t, "city")), Direction: scanner.ASC},
+					planner.SortKey{Path: expr.FieldSelector(parsePath(t, "age")), Direction: scanner.DESC, Nulls: planner.NullsLast},
+					planner.SortKey{Path: expr.FieldSelector(parsePath(t, "name")), Direction: scanner.ASC},
+				)),
+			false},
+		{"WithOrderBy trailing comma", "SELECT * FROM test ORDER BY a,", nil, true},
+		{"WithOrderBy NULLS without position", "SELECT * FROM test ORDER BY a NULLS", nil, true},
 		{"WithLimit", "SELECT * FROM test WHERE age = 10 LIMIT 20",
 			planner.NewTree(
 				planner.NewLimitNode(
@@ -188,6 +214,28 @@ func TestParserSelect(t *testing.T) {
 				)),
 			false},
 		{"WithOffsetThenLimit", "SELECT * FROM test WHERE age = 10 OFFSET 20 LIMIT 10", nil, true},
+		{"WithSamplePercent", "SELECT * FROM test TABLESAMPLE 10 PERCENT WHERE age = 10",
+			planner.NewTree(
+				planner.NewProjectionNode(
+					planner.NewSelectionNode(
+						planner.NewSampleNode(planner.NewTableInputNode("test"), planner.SamplePercent, 10),
+						expr.Eq(expr.FieldSelector(parsePath(t, "age")), expr.IntegerValue(10)),
+					),
+					[]planner.ProjectedField{planner.Wildcard{}},
+					"test",
+				)),
+			false},
+		{"WithSampleRows", "SELECT * FROM test sample 2.5 * 2 rows repeatable (42)",
+			planner.NewTree(
+				planner.NewProjectionNode(
+					planner.NewSampleNodeWithSeed(planner.NewTableInputNode("test"), planner.SampleRows, 5, 42),
+					[]planner.ProjectedField{planner.Wildcard{}},
+					"test",
+				)),
+			false},
+		{"WithSample without method", "SELECT * FROM test TABLESAMPLE 10", nil, true},
+		{"WithSample not a number", "SELECT * FROM test TABLESAMPLE 'a' PERCENT", nil, true},
+		{"WithSample REPEATABLE without parentheses", "SELECT * FROM test TABLESAMPLE 10 PERCENT REPEATABLE 1", nil, true},
 	}
 
 	for _, test := range tests {
@@ -0,0 +1,70 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/scanner"
+)
+
+// parseShowStatement parses a SHOW TABLES or SHOW INDEXES statement.
+// TABLES and INDEXES are not reserved keywords, to allow them
+// to be used as field names.
+// This function assumes the SHOW token has already been consumed.
+func (p *Parser) parseShowStatement() (query.Statement, error) {
+	tok, pos, lit := p.ScanIgnoreWhitespace()
+	if tok == scanner.IDENT {
+		switch {
+		case strings.EqualFold(lit, "TABLES"):
+			return query.ShowTablesStmt{}, nil
+		case strings.EqualFold(lit, "INDEXES"):
+			return p.parseShowIndexesStatement()
+		}
+	}
+
+	return nil, newParseError(scanner.Tokstr(tok, lit), []string{"TABLES", "INDEXES"}, pos)
+}
+
+// parseShowIndexesStatement parses the optional FROM clause of a SHOW INDEXES statement.
+// This function assumes the SHOW INDEXES tokens have already been consumed.
+func (p *Parser) parseShowIndexesStatement() (query.ShowIndexesStmt, error) {
+	var stmt query.ShowIndexesStmt
+
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.FROM {
+		p.Unscan()
+		return stmt, nil
+	}
+
+	// Parse table name
+	var err error
+	stmt.TableName, err = p.parseIdent()
+	if err != nil {
+		pErr := err.(*ParseError)
+		pErr.Expected = []string{"table_name"}
+		return stmt, pErr
+	}
+
+	return stmt, nil
+}
+
+// parseDescribeStatement parses a DESCRIBE statement.
+// This function assumes the DESCRIBE token has already been consumed.
+func (p *Parser) parseDescribeStatement() (query.DescribeStmt, error) {
+	var stmt query.DescribeStmt
+
+	// Parse optional TABLE token
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.TABLE {
+		p.Unscan()
+	}
+
+	// Parse table name
+	var err error
+	stmt.TableName, err = p.parseTableName()
+	if err != nil {
+		pErr := err.(*ParseError)
+		pErr.Expected = []string{"table_name"}
+		return stmt, pErr
+	}
+
+	return stmt, nil
+}
@@ -0,0 +1,40 @@
+package parser
+
+import (
+	"context"
+	"testing"
+
+	"github.com/genjidb/genji/sql/query"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParserShow(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		expected query.Statement
+		errored  bool
+	}{
+		{"Tables", "SHOW TABLES", query.ShowTablesStmt{}, false},
+		{"Indexes", "show indexes", query.ShowIndexesStmt{}, false},
+		{"Indexes from", "SHOW INDEXES FROM test", query.ShowIndexesStmt{TableName: "test"}, false},
+		{"Indexes from without table", "SHOW INDEXES FROM", nil, true},
+		{"Unknown", "SHOW foo", nil, true},
+		{"Describe", "DESCRIBE test", query.DescribeStmt{TableName: "test"}, false},
+		{"Describe table", "DESCRIBE TABLE test", query.DescribeStmt{TableName: "test"}, false},
+		{"Describe without table", "DESCRIBE", nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q, err := ParseQuery(context.Background(), test.s)
+			if test.errored {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, q.Statements, 1)
+			require.EqualValues(t, test.expected, q.Statements[0])
+		})
+	}
+}
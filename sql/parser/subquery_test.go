@@ -0,0 +1,72 @@
+package parser
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/genjidb/genji/sql/planner"
+	"github.com/genjidb/genji/sql/query/expr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParserSubquery(t *testing.T) {
+	sub := planner.NewSubquery(planner.NewTree(
+		planner.NewProjectionNode(
+			planner.NewTableInputNode("b"),
+			[]planner.ProjectedField{planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "x")), ExprName: "x"}},
+			"b",
+		)), "")
+
+	tests := []struct {
+		name     string
+		s        string
+		expected expr.Expr
+		errored  bool
+	}{
+		{"Scalar", "(SELECT x FROM b)", expr.ScalarSubquery{Subquery: sub}, false},
+		{"Operand", "y = (SELECT x FROM b)", expr.Eq(expr.FieldSelector(parsePath(t, "y")), expr.ScalarSubquery{Subquery: sub}), false},
+		{"In", "y IN (SELECT x FROM b)", expr.In(expr.FieldSelector(parsePath(t, "y")), expr.ArraySubquery{Subquery: sub}), false},
+		{"Not in", "y NOT IN (SELECT x FROM b)", expr.NotIn(expr.FieldSelector(parsePath(t, "y")), expr.ArraySubquery{Subquery: sub}), false},
+		{"Unclosed", "(SELECT x FROM b", nil, true},
+		{"Not a select", "(DELETE FROM b)", nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e, _, err := NewParser(strings.NewReader(test.s)).ParseExpr()
+			if test.errored {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.EqualValues(t, test.expected, e)
+		})
+	}
+
+	t.Run("Derived table", func(t *testing.T) {
+		q, err := ParseQuery(context.Background(), "SELECT * FROM (SELECT x FROM b) AS t WHERE x > 1")
+		require.NoError(t, err)
+
+		expected := planner.NewTree(
+			planner.NewProjectionNode(
+				planner.NewSelectionNode(
+					planner.NewDerivedTableNode(planner.NewTree(
+						planner.NewProjectionNode(
+							planner.NewTableInputNode("b"),
+							[]planner.ProjectedField{planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "x")), ExprName: "x"}},
+							"b",
+						)), "t"),
+					expr.Gt(expr.FieldSelector(parsePath(t, "x")), expr.IntegerValue(1)),
+				),
+				[]planner.ProjectedField{planner.Wildcard{}},
+				"t",
+			))
+		require.EqualValues(t, expected, q.Statements[0])
+	})
+
+	t.Run("Derived table without alias", func(t *testing.T) {
+		_, err := ParseQuery(context.Background(), "SELECT * FROM (SELECT x FROM b)")
+		require.Error(t, err)
+	})
+}
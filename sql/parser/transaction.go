@@ -34,7 +34,7 @@ func (p *Parser) parseBeginStatement() (query.Statement, error) {
 	return query.BeginStmt{Writable: true}, nil
 }
 
-// parseRollbackStatement parses a ROLLBACK statement.
+// parseRollbackStatement parses a ROLLBACK statement, or a ROLLBACK TO SAVEPOINT statement.
 // This function assumes the ROLLBACK token has already been consumed.
 func (p *Parser) parseRollbackStatement() (query.Statement, error) {
 	// parse optional TRANSCACTION token
@@ -42,7 +42,59 @@ func (p *Parser) parseRollbackStatement() (query.Statement, error) {
 		p.Unscan()
 	}
 
-	return query.RollbackStmt{}, nil
+	// parse optional TO token
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.TO {
+		p.Unscan()
+		return query.RollbackStmt{}, nil
+	}
+
+	name, err := p.parseSavepointName()
+	if err != nil {
+		return nil, err
+	}
+
+	return query.RollbackToSavepointStmt{Name: name}, nil
+}
+
+// parseSavepointStatement parses a SAVEPOINT statement.
+// This function assumes the SAVEPOINT token has already been consumed.
+func (p *Parser) parseSavepointStatement() (query.Statement, error) {
+	name, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+
+	return query.SavepointStmt{Name: name}, nil
+}
+
+// parseReleaseStatement parses a RELEASE SAVEPOINT statement.
+// This function assumes the RELEASE token has already been consumed.
+func (p *Parser) parseReleaseStatement() (query.Statement, error) {
+	name, err := p.parseSavepointName()
+	if err != nil {
+		return nil, err
+	}
+
+	return query.ReleaseSavepointStmt{Name: name}, nil
+}
+
+// parseSavepointName parses the name of a savepoint, preceded by an optional SAVEPOINT token.
+func (p *Parser) parseSavepointName() (string, error) {
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.SAVEPOINT {
+		p.Unscan()
+	}
+
+	return p.parseIdent()
+}
+
+// parseKillStatement parses a KILL statement, followed by the id of a transaction.
+// This function assumes the KILL token has already been consumed.
+func (p *Parser) parseKillStatement() (query.KillStmt, error) {
+	var stmt query.KillStmt
+	var err error
+
+	stmt.TransactionID, _, err = p.ParseExpr()
+	return stmt, err
 }
 
 // parseCommitStatement parses a COMMIT statement.
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/query/expr"
 	"github.com/stretchr/testify/require"
 )
 
@@ -22,8 +23,19 @@ func TestParserTransactions(t *testing.T) {
 		{"BEGIN WRITE", query.BeginStmt{}, true},
 		{"ROLLBACK", query.RollbackStmt{}, false},
 		{"ROLLBACK TRANSACTION", query.RollbackStmt{}, false},
+		{"ROLLBACK TO sp", query.RollbackToSavepointStmt{Name: "sp"}, false},
+		{"ROLLBACK TRANSACTION TO SAVEPOINT sp", query.RollbackToSavepointStmt{Name: "sp"}, false},
+		{"ROLLBACK TO", nil, true},
 		{"COMMIT", query.CommitStmt{}, false},
 		{"COMMIT TRANSACTION", query.CommitStmt{}, false},
+		{"SAVEPOINT sp", query.SavepointStmt{Name: "sp"}, false},
+		{"SAVEPOINT", nil, true},
+		{"RELEASE sp", query.ReleaseSavepointStmt{Name: "sp"}, false},
+		{"RELEASE SAVEPOINT sp", query.ReleaseSavepointStmt{Name: "sp"}, false},
+		{"RELEASE SAVEPOINT", nil, true},
+		{"KILL 10", query.KillStmt{TransactionID: expr.IntegerValue(10)}, false},
+		{"KILL ?", query.KillStmt{TransactionID: expr.PositionalParam(1)}, false},
+		{"KILL", nil, true},
 	}
 
 	for _, test := range tests {
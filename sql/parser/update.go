@@ -14,12 +14,13 @@ func (p *Parser) parseUpdateStatement() (*planner.Tree, error) {
 	var err error
 
 	// Parse table name
-	cfg.TableName, err = p.parseIdent()
+	cfg.TableName, err = p.parseTableName()
 	if err != nil {
 		pErr := err.(*ParseError)
 		pErr.Expected = []string{"table_name"}
 		return nil, pErr
 	}
+	defer p.enterTable(cfg.TableName)()
 
 	// Parse clause: SET or UNSET.
 	tok, pos, lit := p.ScanIgnoreWhitespace()
@@ -41,6 +42,12 @@ func (p *Parser) parseUpdateStatement() (*planner.Tree, error) {
 		return nil, err
 	}
 
+	// Parse "RETURNING fields"
+	cfg.Returning, err = p.parseReturningClause()
+	if err != nil {
+		return nil, err
+	}
+
 	return cfg.ToTree(), nil
 }
 
@@ -60,12 +67,17 @@ func (p *Parser) parseSetClause() ([]updateSetPair, error) {
 		}
 
 		// Scan the identifier for the path name.
+		_, pos, _ := p.ScanIgnoreWhitespace()
+		p.Unscan()
 		path, err := p.parsePath()
 		if err != nil {
 			pErr := err.(*ParseError)
 			pErr.Expected = []string{"path"}
 			return nil, pErr
 		}
+		if path.HasWildcard() {
+			return nil, newParseError(path.String(), []string{"path without wildcards"}, pos)
+		}
 
 		// Scan the eq sign
 		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.EQ {
@@ -125,6 +137,9 @@ type updateConfig struct {
 	UnsetFields []string
 
 	WhereExpr expr.Expr
+
+	// Returning holds the fields of the RETURNING clause, if any.
+	Returning []planner.ProjectedField
 }
 
 type updateSetPair struct {
@@ -152,5 +167,9 @@ func (cfg updateConfig) ToTree() *planner.Tree {
 
 	t = planner.NewReplacementNode(t, cfg.TableName)
 
+	if cfg.Returning != nil {
+		t = planner.NewReturningNode(t, cfg.Returning, cfg.TableName)
+	}
+
 	return &planner.Tree{Root: t}
 }
@@ -26,6 +26,20 @@ func TestParserUpdate(t *testing.T) {
 					"test",
 				)),
 			false},
+		{"SET/Returning", "UPDATE test SET a = 1 RETURNING a",
+			planner.NewTree(
+				planner.NewReturningNode(
+					planner.NewReplacementNode(
+						planner.NewSetNode(
+							planner.NewTableInputNode("test"),
+							parsePath(t, "a"), expr.IntegerValue(1),
+						),
+						"test",
+					),
+					[]planner.ProjectedField{planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "a")), ExprName: "a"}},
+					"test",
+				)),
+			false},
 		{"SET/With cond", "UPDATE test SET a = 1, b = 2 WHERE age = 10",
 			planner.NewTree(
 				planner.NewReplacementNode(
@@ -0,0 +1,108 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/genjidb/genji/sql/planner"
+	"github.com/genjidb/genji/sql/scanner"
+)
+
+// parseWithStatement parses a SELECT statement preceded by a list of common table expressions
+// and returns a Statement AST object.
+// This function assumes the WITH token has already been consumed.
+// RECURSIVE, UNION and ALL are not reserved keywords.
+func (p *Parser) parseWithStatement() (*planner.WithStmt, error) {
+	var stmt planner.WithStmt
+
+	recursive := p.parseOptionalKeyword("RECURSIVE")
+
+	for {
+		cte, err := p.parseCommonTableExpr(recursive)
+		if err != nil {
+			return nil, err
+		}
+		stmt.CTEs = append(stmt.CTEs, cte)
+
+		if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.COMMA {
+			p.Unscan()
+			break
+		}
+	}
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.SELECT {
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"SELECT"}, pos)
+	}
+
+	var err error
+	stmt.Statement, err = p.parseSelectStatement()
+	if err != nil {
+		return nil, err
+	}
+
+	return &stmt, nil
+}
+
+// parseCommonTableExpr parses "name AS (select [UNION [ALL] select])".
+// UNION is only allowed in recursive expressions.
+func (p *Parser) parseCommonTableExpr(recursive bool) (planner.CommonTableExpr, error) {
+	var cte planner.CommonTableExpr
+	var err error
+
+	cte.Name, err = p.parseIdent()
+	if err != nil {
+		pErr := err.(*ParseError)
+		pErr.Expected = []string{"cte_name"}
+		return cte, pErr
+	}
+
+	// Parse "AS ("
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.AS {
+		return cte, newParseError(scanner.Tokstr(tok, lit), []string{"AS"}, pos)
+	}
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.LPAREN {
+		return cte, newParseError(scanner.Tokstr(tok, lit), []string{"("}, pos)
+	}
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.SELECT {
+		return cte, newParseError(scanner.Tokstr(tok, lit), []string{"SELECT"}, pos)
+	}
+	cte.Initial, err = p.parseSelectStatement()
+	if err != nil {
+		return cte, err
+	}
+
+	expected := []string{")"}
+	if recursive {
+		expected = append(expected, "UNION")
+
+		if p.parseOptionalKeyword("UNION") {
+			cte.UnionAll = p.parseOptionalKeyword("ALL")
+
+			if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.SELECT {
+				return cte, newParseError(scanner.Tokstr(tok, lit), []string{"SELECT"}, pos)
+			}
+			cte.Recursive, err = p.parseSelectStatement()
+			if err != nil {
+				return cte, err
+			}
+
+			expected = expected[:1]
+		}
+	}
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.RPAREN {
+		return cte, newParseError(scanner.Tokstr(tok, lit), expected, pos)
+	}
+
+	return cte, nil
+}
+
+// parseOptionalKeyword consumes the given non-reserved keyword and returns true if present.
+func (p *Parser) parseOptionalKeyword(kw string) bool {
+	if tok, _, lit := p.ScanIgnoreWhitespace(); tok != scanner.IDENT || !strings.EqualFold(lit, kw) {
+		p.Unscan()
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,54 @@
+package parser
+
+import (
+	"context"
+	"testing"
+
+	"github.com/genjidb/genji/sql/planner"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParserWith(t *testing.T) {
+	tests := []struct {
+		name      string
+		s         string
+		ctes      []string
+		recursive bool
+		unionAll  bool
+		errored   bool
+	}{
+		{"One", "WITH a AS (SELECT * FROM test) SELECT * FROM a", []string{"a"}, false, false, false},
+		{"Many", "WITH a AS (SELECT * FROM test), b AS (SELECT * FROM a) SELECT * FROM b", []string{"a", "b"}, false, false, false},
+		{"Recursive", "WITH RECURSIVE a AS (SELECT 1 AS n UNION SELECT n + 1 AS n FROM a) SELECT * FROM a", []string{"a"}, true, false, false},
+		{"Recursive union all", "WITH recursive a AS (SELECT 1 AS n union all SELECT n + 1 AS n FROM a) SELECT * FROM a", []string{"a"}, true, true, false},
+		{"Recursive without union", "WITH RECURSIVE a AS (SELECT 1 AS n) SELECT * FROM a", []string{"a"}, false, false, false},
+		{"Union without recursive", "WITH a AS (SELECT 1 AS n UNION SELECT n FROM a) SELECT * FROM a", nil, false, false, true},
+		{"No name", "WITH AS (SELECT 1) SELECT * FROM a", nil, false, false, true},
+		{"No parentheses", "WITH a AS SELECT 1 SELECT * FROM a", nil, false, false, true},
+		{"No statement", "WITH a AS (SELECT 1)", nil, false, false, true},
+		{"Not a select", "WITH a AS (SELECT 1) DELETE FROM a", nil, false, false, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q, err := ParseQuery(context.Background(), test.s)
+			if test.errored {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, q.Statements, 1)
+
+			stmt, ok := q.Statements[0].(*planner.WithStmt)
+			require.True(t, ok)
+			require.NotNil(t, stmt.Statement)
+			require.Len(t, stmt.CTEs, len(test.ctes))
+			for i, cte := range stmt.CTEs {
+				require.Equal(t, test.ctes[i], cte.Name)
+				require.NotNil(t, cte.Initial)
+				require.Equal(t, test.recursive, cte.Recursive != nil)
+				require.Equal(t, test.unionAll, cte.UnionAll)
+			}
+		})
+	}
+}
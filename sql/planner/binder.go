@@ -22,6 +22,11 @@ func bindNode(n Node, tx *database.Transaction, params []expr.Param) error {
 		return err
 	}
 
+	// subqueries are only cached for a single execution of the statement
+	for _, s := range subqueries(n) {
+		s.cache = nil
+	}
+
 	if n.Left() != nil {
 		err = bindNode(n.Left(), tx, params)
 		if err != nil {
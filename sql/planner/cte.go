@@ -0,0 +1,239 @@
+package planner
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// A CommonTableExpr is a named query whose documents can be read by the statement of
+// a WITH clause, and by the expressions defined after it, as if it was a table.
+type CommonTableExpr struct {
+	Name string
+	// Initial computes the first documents of the expression.
+	Initial *Tree
+	// Recursive, if not nil, is run with the documents returned by its previous iteration,
+	// starting with the ones of Initial, until it returns no document.
+	// At each iteration, reading Name returns the documents of the previous one.
+	Recursive *Tree
+	// UnionAll keeps the documents returned multiple times.
+	// Otherwise, they are discarded, which also stops the recursion on cycles.
+	UnionAll bool
+}
+
+// WithStmt is a query.Statement that computes a list of common table expressions,
+// in order, and runs a tree reading from them.
+type WithStmt struct {
+	CTEs      []CommonTableExpr
+	Statement *Tree
+}
+
+// Run computes the documents of every expression then runs the statement.
+// Recursive expressions are computed as a fixed point: the recursive tree is run
+// until it returns no new document, or until the recursion limit of the database
+// is reached, in which case it returns an error wrapping database.ErrRecursionLimitExceeded.
+// The documents of the expressions are kept in memory while the statement runs.
+func (s *WithStmt) Run(ctx context.Context, tx *database.Transaction, params []expr.Param) (query.Result, error) {
+	memory := tx.MemoryAccount()
+	tables := make(map[string][]document.Document, len(s.CTEs))
+
+	for _, cte := range s.CTEs {
+		docs, err := cte.compute(ctx, tx, params, tables, memory)
+		if err != nil {
+			return query.Result{}, err
+		}
+
+		tables[cte.Name] = docs
+	}
+
+	bindCTEs(s.Statement, tables)
+	return s.Statement.Run(ctx, tx, params)
+}
+
+// IsReadOnly implements the query.Statement interface.
+func (s *WithStmt) IsReadOnly() bool {
+	return false
+}
+
+// compute returns the documents of the expression, given the ones of the previous expressions.
+func (cte *CommonTableExpr) compute(ctx context.Context, tx *database.Transaction, params []expr.Param, tables map[string][]document.Document, memory *database.MemoryAccount) ([]document.Document, error) {
+	u := union{all: cte.UnionAll, memory: memory}
+
+	bindCTEs(cte.Initial, tables)
+	working, err := u.add(ctx, tx, cte.Initial, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if cte.Recursive == nil {
+		return u.docs, nil
+	}
+
+	limit := tx.DB().RecursionLimit()
+
+	// the recursive tree reads the documents of the previous iteration
+	rtables := make(map[string][]document.Document, len(tables)+1)
+	for name, docs := range tables {
+		rtables[name] = docs
+	}
+
+	for i := 0; len(working) > 0; i++ {
+		if limit >= 0 && i >= limit {
+			return nil, fmt.Errorf("%w: %q iterated more than %d times", database.ErrRecursionLimitExceeded, cte.Name, limit)
+		}
+
+		rtables[cte.Name] = working
+		bindCTEs(cte.Recursive, rtables)
+
+		working, err = u.add(ctx, tx, cte.Recursive, params)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return u.docs, nil
+}
+
+// union accumulates the documents returned by the trees of an expression.
+type union struct {
+	all    bool
+	docs   []document.Document
+	memory *database.MemoryAccount
+	// documents already added, by hash, if duplicates are discarded.
+	seen map[uint64][]document.Document
+}
+
+// add runs t and adds the documents it returns to u.
+// It returns the documents that were added.
+func (u *union) add(ctx context.Context, tx *database.Transaction, t *Tree, params []expr.Param) ([]document.Document, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	res, err := t.Run(ctx, tx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	start := len(u.docs)
+	err = res.Iterate(func(d document.Document) error {
+		fb := document.NewFieldBuffer()
+		err := fb.Copy(d)
+		if err != nil {
+			return err
+		}
+
+		if !u.all {
+			ok, err := u.insert(fb)
+			if err != nil || !ok {
+				return err
+			}
+		}
+
+		err = u.memory.Grow(documentSize(fb))
+		if err != nil {
+			return err
+		}
+
+		u.docs = append(u.docs, fb)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return u.docs[start:len(u.docs):len(u.docs)], nil
+}
+
+// insert records d and returns false if an equal document was already recorded.
+func (u *union) insert(d document.Document) (bool, error) {
+	v := document.NewDocumentValue(d)
+
+	h, err := document.Hash(v, fnv.New64a())
+	if err != nil {
+		return false, err
+	}
+
+	for _, other := range u.seen[h] {
+		ok, err := v.IsEqual(document.NewDocumentValue(other))
+		if err != nil || ok {
+			return false, err
+		}
+	}
+
+	if u.seen == nil {
+		u.seen = make(map[uint64][]document.Document)
+	}
+	u.seen[h] = append(u.seen[h], d)
+	return true, nil
+}
+
+// bindCTEs replaces the table input nodes of t reading the given expressions
+// by nodes reading their documents.
+func bindCTEs(t *Tree, tables map[string][]document.Document) {
+	if t.Root != nil {
+		t.Root = bindCTENode(t.Root, tables)
+	}
+}
+
+func bindCTENode(n Node, tables map[string][]document.Document) Node {
+	switch in := n.(type) {
+	case *tableInputNode:
+		if docs, ok := tables[in.tableName]; ok {
+			return &cteInputNode{node: node{op: Input}, name: in.tableName, docs: docs}
+		}
+		return n
+	case *cteInputNode:
+		if docs, ok := tables[in.name]; ok {
+			in.docs = docs
+		}
+		return n
+	case *ProjectionNode:
+		// documents of an expression don't belong to a table
+		if _, ok := tables[in.tableName]; ok {
+			in.tableName = ""
+		}
+	}
+
+	for _, s := range subqueries(n) {
+		bindCTEs(s.tree, tables)
+	}
+
+	if l := n.Left(); l != nil {
+		n.SetLeft(bindCTENode(l, tables))
+	}
+	if r := n.Right(); r != nil {
+		n.SetRight(bindCTENode(r, tables))
+	}
+
+	return n
+}
+
+// cteInputNode reads the documents of a common table expression.
+type cteInputNode struct {
+	node
+
+	name string
+	docs []document.Document
+}
+
+var _ InputNode = (*cteInputNode)(nil)
+
+func (n *cteInputNode) Bind(tx *database.Transaction, params []expr.Param) error {
+	return nil
+}
+
+func (n *cteInputNode) BuildStream() (document.Stream, error) {
+	return document.NewStream(document.NewIterator(n.docs...)), nil
+}
+
+func (n *cteInputNode) String() string {
+	return fmt.Sprintf("CTE(%s)", n.name)
+}
@@ -0,0 +1,67 @@
+package planner
+
+import (
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// An Operator is a user defined operation that can be injected in a tree,
+// for example by a RewriteFunc, to transform the stream of documents.
+type Operator interface {
+	// Bind is called before the tree is optimized and executed,
+	// with the transaction and the parameters of the query.
+	Bind(tx *database.Transaction, params []expr.Param) error
+	// Exec returns a stream derived from the stream of the child node.
+	// It is called once per execution of the tree.
+	Exec(st document.Stream) (document.Stream, error)
+}
+
+// OperatorFunc is an Operator that calls fn on every document of the stream
+// and streams the document it returns instead.
+type OperatorFunc func(d document.Document) (document.Document, error)
+
+// Bind does nothing. It implements the Operator interface.
+func (fn OperatorFunc) Bind(tx *database.Transaction, params []expr.Param) error {
+	return nil
+}
+
+// Exec implements the Operator interface.
+func (fn OperatorFunc) Exec(st document.Stream) (document.Stream, error) {
+	return st.Map(fn), nil
+}
+
+type operatorNode struct {
+	node
+
+	name string
+	op   Operator
+}
+
+var _ OperationNode = (*operatorNode)(nil)
+
+// NewOperatorNode creates a node that transforms the stream of n using op.
+// The name is used to represent the node when the tree is displayed.
+// The optimizer never uses an index for selection nodes above a custom node.
+func NewOperatorNode(n Node, name string, op Operator) Node {
+	return &operatorNode{
+		node: node{
+			op:   Custom,
+			left: n,
+		},
+		name: name,
+		op:   op,
+	}
+}
+
+func (n *operatorNode) Bind(tx *database.Transaction, params []expr.Param) error {
+	return n.op.Bind(tx, params)
+}
+
+func (n *operatorNode) ToStream(st document.Stream) (document.Stream, error) {
+	return n.op.Exec(st)
+}
+
+func (n *operatorNode) String() string {
+	return n.name
+}
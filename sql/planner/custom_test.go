@@ -0,0 +1,64 @@
+package planner_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/planner"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOperatorNode(t *testing.T) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	err = db.Exec(ctx, "CREATE TABLE test; CREATE INDEX idx_a ON test (a); INSERT INTO test (a) VALUES (1), (2), (3)")
+	require.NoError(t, err)
+
+	// multiply a by 10 right after reading the table
+	double := planner.OperatorFunc(func(d document.Document) (document.Document, error) {
+		var fb document.FieldBuffer
+		err := fb.Copy(d)
+		if err != nil {
+			return nil, err
+		}
+
+		v, err := d.GetByField("a")
+		if err != nil {
+			return nil, err
+		}
+
+		return &fb, fb.Replace("a", document.NewIntegerValue(v.V.(int64)*10))
+	})
+
+	db.AddRewriter(func(t *planner.Tree) (*planner.Tree, error) {
+		n := t.Root
+		for n.Left().Left() != nil {
+			n = n.Left()
+		}
+		n.SetLeft(planner.NewOperatorNode(n.Left(), "x10", double))
+		return t, nil
+	})
+
+	res, err := db.Query(ctx, "SELECT a FROM test WHERE a = 20")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	err = document.IteratorToJSONArray(&buf, res)
+	require.NoError(t, err)
+	require.NoError(t, res.Close())
+	require.JSONEq(t, `[{"a": 20}]`, buf.String())
+
+	// the selection can't use the index as it is above the custom node
+	d, err := db.QueryDocument(ctx, "EXPLAIN SELECT a FROM test WHERE a = 20")
+	require.NoError(t, err)
+	v, err := d.GetByField("plan")
+	require.NoError(t, err)
+	require.Equal(t, "∏(a)\n└── σ(cond: a = 20)\n    └── x10\n        └── Table(test)", v.V)
+}
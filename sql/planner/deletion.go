@@ -17,9 +17,13 @@ type deletionNode struct {
 
 	tableName string
 	table     *database.Table
+	// number of documents deleted by the last call to ToStream
+	deleted int64
+	// if true, ToStream returns the deleted documents, see NewReturningNode.
+	returning bool
 }
 
-var _ operationNode = (*deletionNode)(nil)
+var _ OperationNode = (*deletionNode)(nil)
 
 // NewDeletionNode creates a node that delete every document of a stream
 // from their respective table.
@@ -45,10 +49,13 @@ func (n *deletionNode) Bind(tx *database.Transaction, params []expr.Param) (err
 // to a buffer and delete them after the iteration is complete, and it will do that until there is no document
 // left to delete.
 // Increasing deleteBufferSize will occasionate less key searches (O(log n) for most engines) but will take more memory.
-func (n *deletionNode) toStream(st document.Stream) (document.Stream, error) {
+func (n *deletionNode) ToStream(st document.Stream) (document.Stream, error) {
 	st = st.Limit(deleteBufferSize)
 
 	keys := make([][]byte, deleteBufferSize)
+	n.deleted = 0
+
+	var docs []document.Document
 
 	for {
 		var i int
@@ -61,6 +68,14 @@ func (n *deletionNode) toStream(st document.Stream) (document.Stream, error) {
 			// copy the key and reuse the buffer
 			keys[i] = append(keys[i][0:0], k.Key()...)
 			i++
+
+			if n.returning {
+				c, err := copyDocument(d, k.Key())
+				if err != nil {
+					return err
+				}
+				docs = append(docs, c)
+			}
 			return nil
 		})
 		if err != nil {
@@ -74,6 +89,7 @@ func (n *deletionNode) toStream(st document.Stream) (document.Stream, error) {
 			if err != nil {
 				return document.Stream{}, err
 			}
+			n.deleted++
 		}
 
 		if i < deleteBufferSize {
@@ -81,9 +97,17 @@ func (n *deletionNode) toStream(st document.Stream) (document.Stream, error) {
 		}
 	}
 
+	if n.returning {
+		return document.NewStream(document.NewIterator(docs...)), nil
+	}
+
 	return document.Stream{}, nil
 }
 
+func (n *deletionNode) rowsAffected() int64 {
+	return n.deleted
+}
+
 func (n *deletionNode) String() string {
 	return fmt.Sprintf("Delete(%s)", n.tableName)
 }
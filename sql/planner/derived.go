@@ -0,0 +1,61 @@
+package planner
+
+import (
+	"fmt"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// derivedTableNode reads the documents returned by a subquery of the FROM clause.
+type derivedTableNode struct {
+	node
+
+	alias     string
+	optimized bool
+}
+
+var _ InputNode = (*derivedTableNode)(nil)
+
+// NewDerivedTableNode creates an input node streaming the documents returned by the subquery,
+// which can be referred to as alias by the statement.
+// The subquery is run once per execution of the statement and can't read the documents
+// of the statement. It is stored as the right child of the node.
+func NewDerivedTableNode(subquery *Tree, alias string) Node {
+	return &derivedTableNode{
+		node: node{
+			op:    Input,
+			right: subquery.Root,
+		},
+		alias: alias,
+	}
+}
+
+// Bind doesn't do anything, the subquery is bound as the right child of the node.
+func (n *derivedTableNode) Bind(tx *database.Transaction, params []expr.Param) error {
+	return nil
+}
+
+// BuildStream optimizes the subquery the first time it is run
+// and returns its stream.
+func (n *derivedTableNode) BuildStream() (document.Stream, error) {
+	if n.right == nil {
+		return document.NewStream(document.NewIterator()), nil
+	}
+
+	if !n.optimized {
+		t, err := Optimize(&Tree{Root: n.right})
+		if err != nil {
+			return document.Stream{}, err
+		}
+		n.right = t.Root
+		n.optimized = true
+	}
+
+	return nodeToStream(n.right, nil)
+}
+
+func (n *derivedTableNode) String() string {
+	return fmt.Sprintf("Derived(%s)", n.alias)
+}
@@ -2,7 +2,7 @@ package planner
 
 import (
 	"context"
-	"errors"
+	"fmt"
 
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
@@ -15,6 +15,14 @@ import (
 // is going to be executed, without executing it.
 type ExplainStmt struct {
 	Statement query.Statement
+
+	// Analyze executes the statement and reports, for each node,
+	// the number of documents it returned and the time spent producing them.
+	Analyze bool
+
+	// Nodes returns one document per node of the plan, see nodesResult,
+	// instead of a single document describing the whole plan as text.
+	Nodes bool
 }
 
 // Run analyses the inner statement and displays its execution plan.
@@ -34,23 +42,230 @@ func (s *ExplainStmt) Run(ctx context.Context, tx *database.Transaction, params
 			return query.Result{}, err
 		}
 
-		return s.createResult(t.String())
+		if s.Analyze {
+			return s.analyze(t, tx)
+		}
+
+		if s.Nodes {
+			return s.nodesResult(t, nil)
+		}
+
+		return s.createResult(t, t.String())
+	}
+
+	return query.Result{}, database.NewError(database.CodeFeatureNotSupported, "EXPLAIN only works on SELECT, UPDATE AND DELETE statements")
+}
+
+// analyze executes t, discarding its documents, and displays
+// its execution plan along with the statistics of each node.
+func (s *ExplainStmt) analyze(t *Tree, tx *database.Transaction) (query.Result, error) {
+	setMemoryAccount(t.Root, tx.MemoryAccount())
+
+	p := newProfiler()
+	st, err := nodeToStream(t.Root, p)
+	if err != nil {
+		return query.Result{}, err
 	}
 
-	return query.Result{}, errors.New("EXPLAIN only works on SELECT, UPDATE AND DELETE statements")
+	err = st.Iterate(func(d document.Document) error { return nil })
+	if err != nil {
+		return query.Result{}, err
+	}
+
+	if s.Nodes {
+		return s.nodesResult(t, p)
+	}
+
+	return s.createResult(t, p.String(t))
 }
 
-func (s *ExplainStmt) createResult(text string) (query.Result, error) {
+// createResult returns a document with the plan of t, as text, and its fingerprint.
+func (s *ExplainStmt) createResult(t *Tree, text string) (query.Result, error) {
 	return query.Result{
 		Stream: document.NewStream(
 			document.NewIterator(
 				document.NewFieldBuffer().
-					Add("plan", document.NewTextValue(text)))),
+					Add("plan", document.NewTextValue(text)).
+					Add("fingerprint", document.NewTextValue(t.Fingerprint())))),
+	}, nil
+}
+
+// nodesResult returns one document per node of t, starting with the root,
+// each node being followed by its children. The documents have the following fields:
+//   - id: position of the node, starting at 1
+//   - parent: id of the parent node, null for the root
+//   - node: type of operation of the node, like "Input" or "Selection"
+//   - description: the node, as displayed by the text plan
+//   - table: name of the table read or written by the node, if any
+//   - index: name of the index used by the node, if any
+//   - condition: condition evaluated by the node, if any
+//   - cost: estimated number of documents read by an input node from the table or the index,
+//     null if unknown. Estimations rely on unique indexes and on the statistics collected by ANALYZE.
+//
+// If p is not nil, the fields rows and time report the statistics of the execution of the node.
+func (s *ExplainStmt) nodesResult(t *Tree, p *profiler) (query.Result, error) {
+	var docs []document.Document
+
+	var walk func(n Node, parent int) error
+	walk = func(n Node, parent int) error {
+		id := len(docs) + 1
+
+		fb := document.NewFieldBuffer().
+			Add("id", document.NewIntegerValue(int64(id)))
+		if parent == 0 {
+			fb.Add("parent", document.NewNullValue())
+		} else {
+			fb.Add("parent", document.NewIntegerValue(int64(parent)))
+		}
+		fb.Add("node", document.NewTextValue(n.Operation().String()))
+		fb.Add("description", document.NewTextValue(fmt.Sprintf("%v", n)))
+
+		table, index := nodeTableAndIndex(n)
+		fb.Add("table", textOrNull(table))
+		fb.Add("index", textOrNull(index))
+
+		if cond := nodeCondition(n); cond != nil {
+			fb.Add("condition", document.NewTextValue(expr.Format(cond)))
+		} else {
+			fb.Add("condition", document.NewNullValue())
+		}
+
+		cost, ok, err := estimateNodeCost(n)
+		if err != nil {
+			return err
+		}
+		if ok {
+			fb.Add("cost", document.NewDoubleValue(cost))
+		} else {
+			fb.Add("cost", document.NewNullValue())
+		}
+
+		if p != nil {
+			if st, ok := p.self(n); ok {
+				fb.Add("rows", document.NewIntegerValue(st.rows))
+				fb.Add("time", document.NewTextValue(st.elapsed.String()))
+			}
+		}
+
+		docs = append(docs, fb)
+
+		for _, c := range nodeChildren(n) {
+			err := walk(c, id)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if t.Root != nil {
+		err := walk(t.Root, 0)
+		if err != nil {
+			return query.Result{}, err
+		}
+	}
+
+	return query.Result{
+		Stream: document.NewStream(document.NewIterator(docs...)),
 	}, nil
 }
 
+func textOrNull(s string) document.Value {
+	if s == "" {
+		return document.NewNullValue()
+	}
+
+	return document.NewTextValue(s)
+}
+
+// nodeTableAndIndex returns the names of the table and of the index used by n, if any.
+func nodeTableAndIndex(n Node) (table, index string) {
+	switch t := n.(type) {
+	case *tableInputNode:
+		return t.tableName, ""
+	case *indexInputNode:
+		return t.tableName, t.indexName
+	case *joinNode:
+		if t.index != nil {
+			return t.tableName, t.index.Opts.IndexName
+		}
+		return t.tableName, ""
+	case *replacementNode:
+		return t.tableName, ""
+	case *deletionNode:
+		return t.tableName, ""
+	}
+
+	return "", ""
+}
+
+// nodeCondition returns the condition evaluated by n, if any.
+func nodeCondition(n Node) expr.Expr {
+	switch t := n.(type) {
+	case *selectionNode:
+		return t.cond
+	case *indexInputNode:
+		return t.cond()
+	case *joinNode:
+		return t.on
+	}
+
+	return nil
+}
+
+// estimateNodeCost returns the estimated number of documents read by an input node.
+// Tables and complete scans of indexes are estimated using the number of documents
+// of an index that was analyzed, since every document of a table is indexed.
+func estimateNodeCost(n Node) (float64, bool, error) {
+	var tb *database.Table
+
+	switch t := n.(type) {
+	case *tableInputNode:
+		if t.partitions != nil || t.field != "" {
+			return 0, false, nil
+		}
+		tb = t.table
+	case *indexInputNode:
+		if cost, ok := estimateIndexCost(t); ok {
+			return cost, true, nil
+		}
+		if t.e != nil || t.rng != nil {
+			return 0, false, nil
+		}
+		tb = t.table
+	default:
+		return 0, false, nil
+	}
+
+	if tb == nil {
+		return 0, false, nil
+	}
+
+	indexes, err := tb.Indexes()
+	if err != nil {
+		return 0, false, err
+	}
+
+	// indexes may have been analyzed at different times,
+	// the same one is always used.
+	var stats *database.IndexConfig
+	for _, idx := range indexes {
+		if idx.Opts.Stats != nil && (stats == nil || idx.Opts.IndexName < stats.IndexName) {
+			opts := idx.Opts
+			stats = &opts
+		}
+	}
+
+	if stats == nil {
+		return 0, false, nil
+	}
+
+	return float64(stats.Stats.Count), true, nil
+}
+
 // IsReadOnly indicates that this statement doesn't write anything into
-// the database.
+// the database, unless it analyzes a statement that does.
 func (s *ExplainStmt) IsReadOnly() bool {
-	return true
+	return !s.Analyze || s.Statement.IsReadOnly()
 }
@@ -1,10 +1,13 @@
 package planner_test
 
 import (
+	"bytes"
 	"context"
+	"regexp"
 	"testing"
 
 	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/document"
 	"github.com/stretchr/testify/require"
 )
 
@@ -16,22 +19,25 @@ func TestExplainStmt(t *testing.T) {
 	}{
 		{"EXPLAIN SELECT 1 + 1", false, `"∏(1 + 1)"`},
 		{"EXPLAIN SELECT * FROM noexist", true, ``},
-		{"EXPLAIN SELECT * FROM test", false, `"Table(test) -> ∏(*)"`},
-		{"EXPLAIN SELECT a + 1 FROM test", false, `"Table(test) -> ∏(a + 1)"`},
-		{"EXPLAIN SELECT a + 1 FROM test WHERE c > 10", false, `"Table(test) -> σ(cond: c > 10) -> ∏(a + 1)"`},
-		{"EXPLAIN SELECT a + 1 FROM test WHERE c > 10 AND d > 20", false, `"Table(test) -> σ(cond: d > 20) -> σ(cond: c > 10) -> ∏(a + 1)"`},
-		{"EXPLAIN SELECT a + 1 FROM test WHERE c > 10 OR d > 20", false, `"Table(test) -> σ(cond: c > 10 OR d > 20) -> ∏(a + 1)"`},
-		{"EXPLAIN SELECT a + 1 FROM test WHERE c IN [1 + 1, 2 + 2]", false, `"Table(test) -> σ(cond: c IN [2, 4]) -> ∏(a + 1)"`},
-		{"EXPLAIN SELECT a + 1 FROM test WHERE a > 10", false, `"Index(idx_a) -> ∏(a + 1)"`},
-		{"EXPLAIN SELECT a + 1 FROM test WHERE a > 10 AND b > 20 AND c > 30", false, `"Index(idx_b) -> σ(cond: c > 30) -> σ(cond: a > 10) -> ∏(a + 1)"`},
-		{"EXPLAIN SELECT a + 1 FROM test WHERE c > 30 ORDER BY a DESC LIMIT 10 OFFSET 20", false, `"Table(test) -> σ(cond: c > 30) -> ∏(a + 1) -> Sort(a DESC) -> Offset(20) -> Limit(10)"`},
-		{"EXPLAIN SELECT a + 1 FROM test WHERE c > 30 GROUP BY b ORDER BY a DESC LIMIT 10 OFFSET 20", false, `"Table(test) -> σ(cond: c > 30) -> G(b) -> ∏(a + 1) -> Sort(a DESC) -> Offset(20) -> Limit(10)"`},
-		{"EXPLAIN UPDATE test SET a = 10", false, `"Table(test) -> Set(a = 10) -> Replace(test)"`},
-		{"EXPLAIN UPDATE test SET a = 10 WHERE c > 10", false, `"Table(test) -> σ(cond: c > 10) -> Set(a = 10) -> Replace(test)"`},
-		{"EXPLAIN UPDATE test SET a = 10 WHERE a > 10", false, `"Index(idx_a) -> Set(a = 10) -> Replace(test)"`},
-		{"EXPLAIN DELETE FROM test", false, `"Table(test) -> Delete(test)"`},
-		{"EXPLAIN DELETE FROM test WHERE c > 10", false, `"Table(test) -> σ(cond: c > 10) -> Delete(test)"`},
-		{"EXPLAIN DELETE FROM test WHERE a > 10", false, `"Index(idx_a) -> Delete(test)"`},
+		{"EXPLAIN SELECT * FROM test", false, `"∏(*)\n└── Table(test)"`},
+		{"EXPLAIN SELECT a + 1 FROM test", false, `"∏(a + 1)\n└── Table(test)"`},
+		{"EXPLAIN SELECT a + 1 FROM test WHERE c > 10", false, `"∏(a + 1)\n└── σ(cond: c > 10)\n    └── Table(test)"`},
+		{"EXPLAIN SELECT a + 1 FROM test WHERE c > 10 AND d > 20", false, `"∏(a + 1)\n└── σ(cond: c > 10)\n    └── σ(cond: d > 20)\n        └── Table(test)"`},
+		{"EXPLAIN SELECT a + 1 FROM test WHERE c > 10 OR d > 20", false, `"∏(a + 1)\n└── σ(cond: c > 10 OR d > 20)\n    └── Table(test)"`},
+		{"EXPLAIN SELECT a + 1 FROM test WHERE c IN [1 + 1, 2 + 2]", false, `"∏(a + 1)\n└── σ(cond: c IN [2, 4])\n    └── Table(test)"`},
+		{"EXPLAIN SELECT a + 1 FROM test WHERE a > 10", false, `"∏(a + 1)\n└── Index(idx_a)"`},
+		{"EXPLAIN SELECT a + 1 FROM test WHERE a > 10 AND a <= 20", false, `"∏(a + 1)\n└── Index(idx_a)"`},
+		{"EXPLAIN SELECT a + 1 FROM test WHERE a > 10 AND c < 30 AND a > 20", false, `"∏(a + 1)\n└── σ(cond: c < 30)\n    └── σ(cond: a > 20)\n        └── Index(idx_a)"`},
+		{"EXPLAIN SELECT a + 1 FROM test TABLESAMPLE 10 ROWS WHERE a > 10", false, `"∏(a + 1)\n└── σ(cond: a > 10)\n    └── Sample(10 ROWS)\n        └── Table(test)"`},
+		{"EXPLAIN SELECT a + 1 FROM test WHERE a > 10 AND b > 20 AND c > 30", false, `"∏(a + 1)\n└── σ(cond: a > 10)\n    └── σ(cond: c > 30)\n        └── Index(idx_b)"`},
+		{"EXPLAIN SELECT a + 1 FROM test WHERE c > 30 ORDER BY a DESC LIMIT 10 OFFSET 20", false, `"Limit(10)\n└── Offset(20)\n    └── Sort(a DESC)\n        └── ∏(a + 1)\n            └── σ(cond: c > 30)\n                └── Table(test)"`},
+		{"EXPLAIN SELECT a + 1 FROM test WHERE c > 30 GROUP BY b ORDER BY a DESC LIMIT 10 OFFSET 20", false, `"Limit(10)\n└── Offset(20)\n    └── Sort(a DESC)\n        └── ∏(a + 1)\n            └── G(b)\n                └── σ(cond: c > 30)\n                    └── Table(test)"`},
+		{"EXPLAIN UPDATE test SET a = 10", false, `"Replace(test)\n└── Set(a = 10)\n    └── Table(test)"`},
+		{"EXPLAIN UPDATE test SET a = 10 WHERE c > 10", false, `"Replace(test)\n└── Set(a = 10)\n    └── σ(cond: c > 10)\n        └── Table(test)"`},
+		{"EXPLAIN UPDATE test SET a = 10 WHERE a > 10", false, `"Replace(test)\n└── Set(a = 10)\n    └── Index(idx_a)"`},
+		{"EXPLAIN DELETE FROM test", false, `"Delete(test)\n└── Table(test)"`},
+		{"EXPLAIN DELETE FROM test WHERE c > 10", false, `"Delete(test)\n└── σ(cond: c > 10)\n    └── Table(test)"`},
+		{"EXPLAIN DELETE FROM test WHERE a > 10", false, `"Delete(test)\n└── Index(idx_a)"`},
 	}
 
 	for _, test := range tests {
@@ -64,3 +70,192 @@ func TestExplainStmt(t *testing.T) {
 		})
 	}
 }
+
+func TestExplainAnalyze(t *testing.T) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	err = db.Exec(ctx, "CREATE TABLE test; CREATE INDEX idx_a ON test (a)")
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		err = db.Exec(ctx, "INSERT INTO test (a, b) VALUES (?, ?)", i, i%2)
+		require.NoError(t, err)
+	}
+
+	// times and allocations vary between executions
+	re := regexp.MustCompile(`time: [^,)]+(, allocs: \d+, bytes: \d+)?`)
+
+	explain := func(q string) string {
+		d, err := db.QueryDocument(ctx, q)
+		require.NoError(t, err)
+
+		v, err := d.GetByField("plan")
+		require.NoError(t, err)
+
+		return re.ReplaceAllString(v.V.(string), "time: x")
+	}
+
+	require.Equal(t,
+		"Limit(2) (rows: 2, time: x)\n└── ∏(a) (rows: 3, time: x)\n    └── σ(cond: b = 1) (rows: 3, time: x)\n        └── Index(idx_a) (rows: 5, time: x)",
+		explain("EXPLAIN ANALYZE SELECT a FROM test WHERE a >= 5 AND b = 1 LIMIT 2"))
+
+	// the statement is executed
+	require.Equal(t,
+		"Delete(test) (rows: 0, time: x)\n└── σ(cond: b = 1) (rows: 5, time: x)\n    └── Table(test) (rows: 10, time: x)",
+		explain("EXPLAIN ANALYZE DELETE FROM test WHERE b = 1"))
+
+	d, err := db.QueryDocument(ctx, "SELECT COUNT(*) AS c FROM test")
+	require.NoError(t, err)
+	var count int
+	err = document.Scan(d, &count)
+	require.NoError(t, err)
+	require.Equal(t, 5, count)
+}
+
+func TestExplainNodes(t *testing.T) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	err = db.Exec(ctx, "CREATE TABLE test; CREATE INDEX idx_a ON test (a); CREATE UNIQUE INDEX idx_b ON test (b)")
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		err = db.Exec(ctx, "INSERT INTO test (a, b, c) VALUES (?, ?, ?)", i%2, i, i)
+		require.NoError(t, err)
+	}
+
+	explain := func(q string) string {
+		res, err := db.Query(ctx, q)
+		require.NoError(t, err)
+		defer res.Close()
+
+		var buf bytes.Buffer
+		err = document.IteratorToJSONArray(&buf, res)
+		require.NoError(t, err)
+		return buf.String()
+	}
+
+	require.JSONEq(t, `[
+		{"id": 1, "parent": null, "node": "Projection", "description": "∏(a)", "table": null, "index": null, "condition": null, "cost": null},
+		{"id": 2, "parent": 1, "node": "Selection", "description": "σ(cond: c > 2)", "table": null, "index": null, "condition": "c > 2", "cost": null},
+		{"id": 3, "parent": 2, "node": "Input", "description": "Index(idx_b)", "table": "test", "index": "idx_b", "condition": "b = 1", "cost": 1.0}
+	]`, explain("EXPLAIN NODES SELECT a FROM test WHERE b = 1 AND c > 2"))
+
+	// the tables are estimated once analyzed
+	require.JSONEq(t, `[
+		{"id": 1, "parent": null, "node": "Deletion", "description": "Delete(test)", "table": "test", "index": null, "condition": null, "cost": null},
+		{"id": 2, "parent": 1, "node": "Selection", "description": "σ(cond: c > 2)", "table": null, "index": null, "condition": "c > 2", "cost": null},
+		{"id": 3, "parent": 2, "node": "Input", "description": "Table(test)", "table": "test", "index": null, "condition": null, "cost": null}
+	]`, explain("EXPLAIN NODES DELETE FROM test WHERE c > 2"))
+
+	err = db.Exec(ctx, "ANALYZE test")
+	require.NoError(t, err)
+
+	require.JSONEq(t, `[
+		{"id": 1, "parent": null, "node": "Projection", "description": "∏(*)", "table": null, "index": null, "condition": null, "cost": null},
+		{"id": 2, "parent": 1, "node": "Input", "description": "Index(idx_a)", "table": "test", "index": "idx_a", "condition": "a = 1", "cost": 5.0}
+	]`, explain("EXPLAIN NODES SELECT * FROM test WHERE a = 1"))
+
+	require.JSONEq(t, `[
+		{"id": 1, "parent": null, "node": "Projection", "description": "∏(*)", "table": null, "index": null, "condition": null, "cost": null},
+		{"id": 2, "parent": 1, "node": "Input", "description": "Table(test)", "table": "test", "index": null, "condition": null, "cost": 10.0}
+	]`, explain("EXPLAIN NODES SELECT * FROM test"))
+
+	// times vary between executions
+	res, err := db.Query(ctx, "EXPLAIN ANALYZE NODES SELECT * FROM test WHERE c > 2 LIMIT 3")
+	require.NoError(t, err)
+	defer res.Close()
+
+	var rows []int64
+	err = res.Iterate(func(d document.Document) error {
+		v, err := d.GetByField("rows")
+		if err != nil {
+			return err
+		}
+		rows = append(rows, v.V.(int64))
+
+		_, err = d.GetByField("time")
+		return err
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int64{3, 4, 4, 7}, rows)
+}
+
+func TestPartitionPruning(t *testing.T) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	err = db.Exec(ctx, `
+		CREATE TABLE r (k INTEGER PRIMARY KEY) PARTITION BY RANGE (k) VALUES (10, 20);
+		CREATE TABLE h (k INTEGER PRIMARY KEY) PARTITION BY HASH (k) PARTITIONS 4;
+	`)
+	require.NoError(t, err)
+	for i := 0; i < 30; i++ {
+		err = db.Exec(ctx, "INSERT INTO r (k) VALUES (?); INSERT INTO h (k) VALUES (?)", i, i)
+		require.NoError(t, err)
+	}
+
+	tests := []struct {
+		query    string
+		plan     string
+		expected []int64
+	}{
+		{"SELECT k FROM r WHERE k = 15", `"∏(k)\n└── σ(cond: k = 15)\n    └── Table(r, partitions: [1])"`, []int64{15}},
+		{"SELECT k FROM r WHERE 25 < k", `"∏(k)\n└── σ(cond: 25 < k)\n    └── Table(r, partitions: [2])"`, []int64{26, 27, 28, 29}},
+		{"SELECT k FROM r WHERE k >= 8 AND k < 12", `"∏(k)\n└── σ(cond: k >= 8)\n    └── σ(cond: k < 12)\n        └── Table(r, partitions: [0 1])"`, []int64{8, 9, 10, 11}},
+		{"SELECT k FROM r WHERE k IN [2, 25]", `"∏(k)\n└── σ(cond: k IN [2, 25])\n    └── Table(r, partitions: [0 2])"`, []int64{2, 25}},
+		{"SELECT k FROM r WHERE k < 5 AND k > 25", `"∏(k)\n└── σ(cond: k < 5)\n    └── σ(cond: k > 25)\n        └── Table(r, partitions: [])"`, []int64{}},
+		{"SELECT k FROM r WHERE k < 5 OR k > 25", `"∏(k)\n└── σ(cond: k < 5 OR k > 25)\n    └── Table(r)"`, nil},
+		{"SELECT k FROM h WHERE k IN [3, 17]", ``, []int64{3, 17}},
+		{"SELECT k FROM h WHERE k > 27", `"∏(k)\n└── σ(cond: k > 27)\n    └── Table(h)"`, []int64{28, 29}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			d, err := db.QueryDocument(ctx, "EXPLAIN "+test.query)
+			require.NoError(t, err)
+			v, err := d.GetByField("plan")
+			require.NoError(t, err)
+			if test.plan != "" {
+				require.JSONEq(t, test.plan, v.String())
+			} else {
+				require.Regexp(t, regexp.MustCompile(`Table\(h, partitions: \[\d( \d)?\]\)`), v.String())
+			}
+
+			if test.expected == nil {
+				return
+			}
+			res, err := db.Query(ctx, test.query)
+			require.NoError(t, err)
+			defer res.Close()
+
+			ks := []int64{}
+			err = res.Iterate(func(d document.Document) error {
+				v, err := d.GetByField("k")
+				if err != nil {
+					return err
+				}
+				ks = append(ks, v.V.(int64))
+				return nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, test.expected, ks)
+		})
+	}
+
+	err = db.Exec(ctx, "DELETE FROM r WHERE k >= 20")
+	require.NoError(t, err)
+	d, err := db.QueryDocument(ctx, "SELECT COUNT(*) AS c FROM r")
+	require.NoError(t, err)
+	v, err := d.GetByField("c")
+	require.NoError(t, err)
+	require.Equal(t, int64(20), v.V)
+}
@@ -0,0 +1,91 @@
+package planner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Shape returns a description of the operations of the tree and of the tables,
+// indexes and common table expressions they read, ignoring their expressions.
+// Queries that differ only by their values, like "a > 10" and "a > 20",
+// have the same shape if they are executed the same way.
+// Example:
+//
+//	Projection(Selection(Index(idx_a)))
+func (t *Tree) Shape() string {
+	if t.Root == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	writeShape(&b, t.Root)
+	return b.String()
+}
+
+// Fingerprint returns a hash of the shape of the tree, which can be used to make sure
+// critical queries keep being executed the same way, for example that they use an index.
+// It is only meaningful once the tree has been optimized, like the one displayed by EXPLAIN.
+func (t *Tree) Fingerprint() string {
+	h := sha256.Sum256([]byte(t.Shape()))
+	return hex.EncodeToString(h[:8])
+}
+
+func writeShape(b *strings.Builder, n Node) {
+	switch t := n.(type) {
+	case *tableInputNode:
+		if t.columns {
+			b.WriteString("Columns(" + t.tableName + ")")
+		} else if t.field != "" {
+			b.WriteString("FieldIndex(" + t.tableName + ")")
+		} else {
+			b.WriteString("Table(" + t.tableName + ")")
+		}
+	case *indexInputNode:
+		b.WriteString("Index(" + t.indexName + ")")
+	case *cteInputNode:
+		b.WriteString("CTE(" + t.name + ")")
+	case *derivedTableNode:
+		b.WriteString("Derived")
+	case *operatorNode:
+		b.WriteString("Custom(" + t.name + ")")
+	case *GroupingNode:
+		b.WriteString("Group")
+	case *joinNode:
+		// the joined table is read using an index instead of its input node
+		if t.index != nil {
+			b.WriteString("Join(")
+			writeShape(b, t.left)
+			b.WriteString(", Index(" + t.index.Opts.IndexName + "))")
+			return
+		}
+		b.WriteString("Join")
+	default:
+		b.WriteString(n.Operation().String())
+	}
+
+	children := nodeChildren(n)
+	subs := subqueries(n)
+	if len(children) == 0 && len(subs) == 0 {
+		return
+	}
+
+	b.WriteByte('(')
+	for i, c := range children {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		writeShape(b, c)
+	}
+	for i, s := range subs {
+		if i > 0 || len(children) > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString("Subquery(")
+		if s.tree.Root != nil {
+			writeShape(b, s.tree.Root)
+		}
+		b.WriteByte(')')
+	}
+	b.WriteByte(')')
+}
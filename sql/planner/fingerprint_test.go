@@ -0,0 +1,84 @@
+package planner_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/parser"
+	"github.com/genjidb/genji/sql/planner"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFingerprint(t *testing.T) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	err = db.Exec(ctx, `
+		CREATE TABLE test (k INTEGER PRIMARY KEY);
+		CREATE INDEX idx_a ON test (a);
+		CREATE TABLE other;
+	`)
+	require.NoError(t, err)
+
+	t.Run("Shape", func(t *testing.T) {
+		tests := []struct {
+			query    string
+			expected string
+		}{
+			{"SELECT 1", "Projection"},
+			{"SELECT * FROM test", "Projection(Table(test))"},
+			{"SELECT a FROM test WHERE a > 10", "Projection(Index(idx_a))"},
+			{"SELECT a FROM test WHERE c > 10 ORDER BY a DESC LIMIT 10", "Limit(Sort(Projection(Selection(Table(test)))))"},
+			{"SELECT COUNT(*) FROM test GROUP BY b", "Projection(Group(Table(test)))"},
+			{"SELECT * FROM test WHERE EXISTS (SELECT * FROM other WHERE b = 1)", "Projection(Selection(Table(test), Subquery(Projection(Selection(Table(other))))))"},
+			{"DELETE FROM test WHERE a = 1", "Deletion(Index(idx_a))"},
+			{"SELECT * FROM other JOIN test ON test.a = other.b", "Projection(Join(Table(other), Index(idx_a)))"},
+			{"SELECT * FROM other JOIN test ON test.c = other.b", "Projection(Join(Table(other), Table(test)))"},
+		}
+
+		for _, test := range tests {
+			t.Run(test.query, func(t *testing.T) {
+				q, err := parser.ParseQuery(ctx, test.query)
+				require.NoError(t, err)
+
+				tx, err := db.Begin(false)
+				require.NoError(t, err)
+				defer tx.Rollback()
+
+				tree := q.Statements[0].(*planner.Tree)
+				err = planner.Bind(tree, tx.Transaction, nil)
+				require.NoError(t, err)
+				tree, err = planner.Optimize(tree)
+				require.NoError(t, err)
+
+				require.Equal(t, test.expected, tree.Shape())
+				require.Len(t, tree.Fingerprint(), 16)
+			})
+		}
+	})
+
+	t.Run("EXPLAIN", func(t *testing.T) {
+		fingerprint := func(q string) string {
+			d, err := db.QueryDocument(ctx, "EXPLAIN "+q)
+			require.NoError(t, err)
+			v, err := d.GetByField("fingerprint")
+			require.NoError(t, err)
+			require.Equal(t, document.TextValue, v.Type)
+			return v.V.(string)
+		}
+
+		// values don't change the fingerprint, unlike the way a query is executed
+		require.Equal(t, fingerprint("SELECT * FROM test WHERE a > 10"), fingerprint("SELECT * FROM test WHERE a > ?"))
+		require.NotEqual(t, fingerprint("SELECT * FROM test WHERE a > 10"), fingerprint("SELECT * FROM test WHERE b > 10"))
+
+		before := fingerprint("SELECT * FROM test WHERE b > 10")
+		err = db.Exec(ctx, "CREATE INDEX idx_b ON test (b)")
+		require.NoError(t, err)
+		require.NotEqual(t, before, fingerprint("SELECT * FROM test WHERE b > 10"))
+	})
+}
@@ -0,0 +1,272 @@
+package planner
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// SQL returns the canonical SQL statement represented by the tree.
+// The tree must have been created from a SELECT, UPDATE or DELETE statement,
+// optimized or not. Nodes that have no SQL equivalent are ignored.
+func (t *Tree) SQL() string {
+	if t.Root == nil {
+		return ""
+	}
+
+	var nodes []Node
+	for n := t.Root; n != nil; n = n.Left() {
+		nodes = append(nodes, n)
+	}
+
+	var s sqlStatement
+	for i := len(nodes) - 1; i >= 0; i-- {
+		s.add(nodes[i])
+	}
+
+	return s.String()
+}
+
+// Exprs returns the expressions used by the node, if any.
+func Exprs(n Node) []expr.Expr {
+	switch t := n.(type) {
+	case *selectionNode:
+		return []expr.Expr{t.cond}
+	case *indexInputNode:
+		if cond := t.cond(); cond != nil {
+			return []expr.Expr{cond}
+		}
+	case *GroupingNode:
+		return []expr.Expr{t.Expr}
+	case *ProjectionNode:
+		var exprs []expr.Expr
+		for _, pf := range t.Expressions {
+			if pe, ok := pf.(ProjectedExpr); ok {
+				exprs = append(exprs, pe.Expr)
+			}
+		}
+		return exprs
+	case *sortNode:
+		exprs := make([]expr.Expr, len(t.keys))
+		for i, k := range t.keys {
+			exprs[i] = k.Path
+		}
+		return exprs
+	case *setNode:
+		return []expr.Expr{t.e}
+	case *joinNode:
+		return []expr.Expr{t.on}
+	}
+
+	return nil
+}
+
+// sqlStatement collects the clauses of a statement
+// while walking a tree from its input to its root.
+type sqlStatement struct {
+	tableName  string
+	alias      string
+	derived    *derivedTableNode
+	sample     *sampleNode
+	joins      []*joinNode
+	laterals   []*lateralNode
+	conds      []expr.Expr
+	groupBy    expr.Expr
+	projection []ProjectedField
+	sort       *sortNode
+	limit      *int
+	offset     *int
+	sets       []*setNode
+	unsets     []string
+	update     bool
+	delete     bool
+}
+
+func (s *sqlStatement) add(n Node) {
+	switch t := n.(type) {
+	case *tableInputNode:
+		s.tableName = t.tableName
+	case *cteInputNode:
+		s.tableName = t.name
+	case *derivedTableNode:
+		s.tableName = t.alias
+		s.derived = t
+	case *indexInputNode:
+		s.tableName = t.tableName
+		if cond := t.cond(); cond != nil {
+			s.conds = append(s.conds, cond)
+		}
+	case *sampleNode:
+		s.sample = t
+	case *joinNode:
+		s.joins = append(s.joins, t)
+	case *lateralNode:
+		s.laterals = append(s.laterals, t)
+	case *selectionNode:
+		if t.cond != nil {
+			s.conds = append(s.conds, t.cond)
+		}
+	case *GroupingNode:
+		s.groupBy = t.Expr
+	case *ProjectionNode:
+		s.projection = t.Expressions
+		s.alias = t.alias
+	case *sortNode:
+		s.sort = t
+	case *offsetNode:
+		s.offset = &t.offset
+	case *limitNode:
+		s.limit = &t.limit
+	case *setNode:
+		s.sets = append(s.sets, t)
+	case *unsetNode:
+		s.unsets = append(s.unsets, t.field)
+	case *replacementNode:
+		s.tableName = t.tableName
+		s.update = true
+	case *deletionNode:
+		s.tableName = t.tableName
+		s.delete = true
+	}
+}
+
+func (s *sqlStatement) String() string {
+	var b strings.Builder
+
+	switch {
+	case s.delete:
+		b.WriteString("DELETE FROM " + expr.FormatTableName(s.tableName))
+	case s.update:
+		b.WriteString("UPDATE " + expr.FormatTableName(s.tableName))
+		if len(s.sets) > 0 {
+			b.WriteString(" SET ")
+			for i, sn := range s.sets {
+				if i > 0 {
+					b.WriteString(", ")
+				}
+				b.WriteString(expr.FormatPath(sn.path) + " = " + expr.Format(sn.e))
+			}
+		} else {
+			b.WriteString(" UNSET ")
+			for i, f := range s.unsets {
+				if i > 0 {
+					b.WriteString(", ")
+				}
+				b.WriteString(expr.FormatIdent(f))
+			}
+		}
+	default:
+		b.WriteString("SELECT ")
+		for i, pf := range s.projection {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(formatProjectedField(pf))
+		}
+		if s.derived != nil {
+			sub := Tree{Root: s.derived.right}
+			b.WriteString(" FROM (" + sub.SQL() + ") AS " + expr.FormatIdent(s.derived.alias))
+		} else if s.tableName != "" {
+			b.WriteString(" FROM " + expr.FormatTableName(s.tableName))
+			if s.alias != "" {
+				b.WriteString(" AS " + expr.FormatIdent(s.alias))
+			}
+		}
+		if s.sample != nil {
+			b.WriteString(" TABLESAMPLE " + strconv.FormatFloat(s.sample.size, 'f', -1, 64))
+			if s.sample.method == SampleRows {
+				b.WriteString(" ROWS")
+			} else {
+				b.WriteString(" PERCENT")
+			}
+			if s.sample.hasSeed {
+				b.WriteString(" REPEATABLE (" + strconv.FormatInt(s.sample.seed, 10) + ")")
+			}
+		}
+		for _, j := range s.joins {
+			b.WriteString(" " + j.joinType.String() + " " + expr.FormatTableName(j.tableName))
+			if j.alias != j.tableName {
+				b.WriteString(" AS " + expr.FormatIdent(j.alias))
+			}
+			b.WriteString(" ON " + expr.Format(j.on))
+		}
+		for _, l := range s.laterals {
+			sub := Tree{Root: l.right}
+			b.WriteString(", LATERAL (" + sub.SQL() + ") AS " + expr.FormatIdent(l.alias))
+		}
+	}
+
+	if len(s.conds) > 0 {
+		b.WriteString(" WHERE ")
+		for i, c := range s.conds {
+			if i > 0 {
+				b.WriteString(" AND ")
+			}
+			// conditions split by the optimizer must be grouped
+			// to preserve their meaning
+			if len(s.conds) > 1 {
+				c = expr.Parentheses{E: c}
+			}
+			b.WriteString(expr.Format(c))
+		}
+	}
+
+	if s.groupBy != nil {
+		b.WriteString(" GROUP BY " + expr.Format(s.groupBy))
+	}
+
+	if s.sort != nil {
+		b.WriteString(" ORDER BY ")
+		for i, k := range s.sort.keys {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(expr.Format(k.Path))
+			if k.desc() {
+				b.WriteString(" DESC")
+			}
+			switch k.Nulls {
+			case NullsFirst:
+				b.WriteString(" NULLS FIRST")
+			case NullsLast:
+				b.WriteString(" NULLS LAST")
+			}
+		}
+	}
+
+	if s.limit != nil {
+		b.WriteString(" LIMIT " + strconv.Itoa(*s.limit))
+	}
+
+	if s.offset != nil {
+		b.WriteString(" OFFSET " + strconv.Itoa(*s.offset))
+	}
+
+	if (s.update || s.delete) && s.projection != nil {
+		b.WriteString(" RETURNING " + Returning(s.projection).String())
+	}
+
+	return b.String()
+}
+
+// formatProjectedField returns the SQL representation of a projected field,
+// with an alias if its name differs from the formatted expression.
+func formatProjectedField(pf ProjectedField) string {
+	pe, ok := pf.(ProjectedExpr)
+	if !ok {
+		return pf.Name()
+	}
+
+	s := expr.Format(pe.Expr)
+	name := s
+	if fs, ok := pe.Expr.(expr.FieldSelector); ok {
+		name = fs.String()
+	}
+
+	if pe.ExprName != name {
+		s += " AS " + expr.FormatIdent(pe.ExprName)
+	}
+
+	return s
+}
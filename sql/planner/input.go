@@ -17,9 +17,18 @@ type tableInputNode struct {
 	table     *database.Table
 	tx        *database.Transaction
 	params    []expr.Param
+	// if not nil, only these partitions of the table are read.
+	partitions []int
+	// if not nil, only these fields of the documents are decoded.
+	fields []string
+	// if true, the fields are read from the columns of the table.
+	columns bool
+	// if not empty, only the documents containing this top-level field are read,
+	// using the field index of the table.
+	field string
 }
 
-var _ inputNode = (*tableInputNode)(nil)
+var _ InputNode = (*tableInputNode)(nil)
 
 // NewTableInputNode creates an input node that can be used to read documents
 // from a table.
@@ -40,11 +49,46 @@ func (n *tableInputNode) Bind(tx *database.Transaction, params []expr.Param) (er
 }
 
 func (n *tableInputNode) String() string {
+	if n.columns {
+		return fmt.Sprintf("Table(%s, columns: %v)", n.tableName, n.fields)
+	}
+
+	if n.partitions != nil {
+		return fmt.Sprintf("Table(%s, partitions: %v)", n.tableName, n.partitions)
+	}
+
+	if n.field != "" {
+		return fmt.Sprintf("Table(%s, field index: %s)", n.tableName, n.field)
+	}
+
 	return fmt.Sprintf("Table(%s)", n.tableName)
 }
 
-func (n *tableInputNode) buildStream() (document.Stream, error) {
-	return document.NewStream(n.table), nil
+func (n *tableInputNode) BuildStream() (document.Stream, error) {
+	if n.columns {
+		return document.NewStream(document.IteratorFunc(func(fn func(d document.Document) error) error {
+			return n.table.IterateColumns(n.fields, fn)
+		})), nil
+	}
+
+	table := n.table
+	if n.fields != nil {
+		table = table.Project(n.fields...)
+	}
+
+	if n.partitions != nil {
+		return document.NewStream(document.IteratorFunc(func(fn func(d document.Document) error) error {
+			return table.IteratePartitions(n.partitions, fn)
+		})), nil
+	}
+
+	if n.field != "" {
+		return document.NewStream(document.IteratorFunc(func(fn func(d document.Document) error) error {
+			return table.IterateField(n.field, fn)
+		})), nil
+	}
+
+	return document.NewStream(table), nil
 }
 
 type indexInputNode struct {
@@ -60,9 +104,14 @@ type indexInputNode struct {
 	iop              IndexIteratorOperator
 	e                expr.Expr
 	orderByDirection scanner.Token
+	// if set, the index is scanned between the bounds of the range
+	// instead of using iop.
+	rng *indexRange
+	// if not nil, only these fields of the documents are decoded.
+	fields []string
 }
 
-var _ inputNode = (*indexInputNode)(nil)
+var _ InputNode = (*indexInputNode)(nil)
 
 // NewIndexInputNode creates a node that can be used to read documents using an index.
 func NewIndexInputNode(tableName, indexName string, iop IndexIteratorOperator, filter expr.Expr, orderByDirection scanner.Token) Node {
@@ -78,41 +127,82 @@ func NewIndexInputNode(tableName, indexName string, iop IndexIteratorOperator, f
 	}
 }
 
+// Bind fetches the table and the index from tx, which may differ from the transaction
+// used when the node was created by the optimizer, if the tree is run again.
 func (n *indexInputNode) Bind(tx *database.Transaction, params []expr.Param) (err error) {
-	if n.table == nil {
-		n.table, err = tx.GetTable(n.tableName)
-		if err != nil {
-			return
-		}
+	n.table, err = tx.GetTable(n.tableName)
+	if err != nil {
+		return
 	}
 
-	if n.index == nil {
-		n.index, err = tx.GetIndex(n.indexName)
-		if err != nil {
-			return
+	// the index is looked up through the table, which may belong to an attached database
+	indexes, err := n.table.Indexes()
+	if err != nil {
+		return
+	}
+
+	n.index = nil
+	for _, idx := range indexes {
+		if idx.Opts.IndexName == n.indexName {
+			idx := idx
+			n.index = &idx
+			break
 		}
 	}
+	if n.index == nil {
+		return database.ErrIndexNotFound
+	}
 
 	n.tx = tx
 	n.params = params
 	return
 }
 
-func (n *indexInputNode) buildStream() (document.Stream, error) {
+func (n *indexInputNode) BuildStream() (document.Stream, error) {
+	table := n.table
+	if n.fields != nil {
+		table = table.Project(n.fields...)
+	}
+
 	return document.NewStream(&indexIterator{
 		tx:     n.tx,
-		tb:     n.table,
+		tb:     table,
 		params: n.params,
 		index:  n.index,
 		e:      n.e,
 		iop:    n.iop,
+		rng:    n.rng,
+
+		orderByDirection: n.orderByDirection,
 	}), nil
 }
 
 func (n *indexInputNode) String() string {
+	if n.orderByDirection == scanner.DESC {
+		return fmt.Sprintf("Index(%s DESC)", n.indexName)
+	}
+
 	return fmt.Sprintf("Index(%s)", n.indexName)
 }
 
+// cond returns the condition used to iterate over the index, if any.
+func (n *indexInputNode) cond() expr.Expr {
+	if n.rng != nil {
+		return expr.And(n.rng.minCond, n.rng.maxCond)
+	}
+
+	if n.e == nil {
+		return nil
+	}
+
+	// operators that can use an index implement IndexIteratorOperator
+	if e, ok := n.iop.(expr.Expr); ok {
+		return e
+	}
+
+	return n.e
+}
+
 // IndexIteratorOperator is an operator that can be used
 // as an input node.
 type IndexIteratorOperator interface {
@@ -127,11 +217,16 @@ type indexIterator struct {
 	iop              IndexIteratorOperator
 	e                expr.Expr
 	orderByDirection scanner.Token
+	rng              *indexRange
 }
 
 var errStop = errors.New("stop")
 
 func (it indexIterator) Iterate(fn func(d document.Document) error) error {
+	if it.rng != nil {
+		return it.iterateRange(fn)
+	}
+
 	if it.e == nil {
 		var err error
 
@@ -168,3 +263,69 @@ func (it indexIterator) Iterate(fn func(d document.Document) error) error {
 
 	return it.iop.IterateIndex(it.index, it.tb, v, fn)
 }
+
+// indexRange describes a scan of an index between a lower and an upper bound.
+// It is created by the optimizer from two conditions on the same indexed field,
+// such as a > 1 AND a < 10.
+type indexRange struct {
+	min, max                   expr.Expr
+	minExclusive, maxExclusive bool
+
+	// the conditions the range was created from.
+	minCond, maxCond expr.Expr
+}
+
+func (it indexIterator) iterateRange(fn func(d document.Document) error) error {
+	stack := expr.EvalStack{
+		Tx:     it.tx,
+		Params: it.params,
+	}
+
+	min, err := it.rng.min.Eval(stack)
+	if err != nil {
+		return err
+	}
+
+	max, err := it.rng.max.Eval(stack)
+	if err != nil {
+		return err
+	}
+
+	// comparing with null never matches
+	if min.Type == document.NullValue || max.Type == document.NullValue {
+		return nil
+	}
+
+	// the index converts bounds of another type than its own, which may select
+	// more values than the conditions: they are then evaluated on every document.
+	var filter expr.Expr
+	if t := it.index.Type; t != 0 && (min.Type != t || max.Type != t) {
+		filter = expr.And(it.rng.minCond, it.rng.maxCond)
+	}
+
+	return it.index.Range(min, max, it.orderByDirection == scanner.DESC, func(val, key []byte, isMin, isMax bool) error {
+		if (isMin && it.rng.minExclusive) || (isMax && it.rng.maxExclusive) {
+			return nil
+		}
+
+		d, err := it.tb.GetDocument(key)
+		if err != nil {
+			return err
+		}
+
+		if filter != nil {
+			stack.Document = d
+			v, err := filter.Eval(stack)
+			if err != nil {
+				return err
+			}
+
+			ok, err := v.IsTruthy()
+			if err != nil || !ok {
+				return err
+			}
+		}
+
+		return fn(d)
+	})
+}
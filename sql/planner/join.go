@@ -0,0 +1,350 @@
+package planner
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/query/expr"
+	"github.com/genjidb/genji/sql/scanner"
+)
+
+// JoinType defines which documents are returned by a join.
+type JoinType int
+
+const (
+	// InnerJoin only returns the documents of the stream that match at least
+	// one document of the joined table.
+	InnerJoin JoinType = iota
+	// LeftJoin also returns the documents of the stream that don't match
+	// any document of the joined table, with a null value in place of it.
+	LeftJoin
+)
+
+func (t JoinType) String() string {
+	if t == LeftJoin {
+		return "LEFT JOIN"
+	}
+
+	return "JOIN"
+}
+
+type joinNode struct {
+	node
+
+	joinType JoinType
+	// name under which the documents of the stream are read by the condition
+	// and the following nodes.
+	name      string
+	tableName string
+	// name under which the documents of the table are read,
+	// which is the name of the table unless it is aliased.
+	alias string
+	on    expr.Expr
+
+	tx     *database.Transaction
+	params []expr.Param
+	table  *database.Table
+	memory *database.MemoryAccount
+
+	// if set by Bind, the joined table is read using this index,
+	// looked up with the value of e for every document of the stream.
+	index *database.Index
+	iop   IndexIteratorOperator
+	e     expr.Expr
+}
+
+var _ OperationNode = (*joinNode)(nil)
+
+// NewJoinNode creates a node that combines every document of the stream with
+// the documents of the table satisfying the condition on. The combined documents
+// are made of the fields of the document of the stream and of a field named alias,
+// or after the table if alias is empty, holding the document of the table.
+// Within the condition and the following nodes, name refers to the document of the stream,
+// so that fields of both documents can be qualified, like "a.id = b.a_id".
+// Unqualified fields are looked up in both documents, and must not exist in both.
+// Documents of the stream are read once, and the table once per document of the stream,
+// unless the condition compares an indexed field of the table with an expression
+// that doesn't depend on it, in which case the index is used to find the matching documents.
+// The input node of the table is stored as the right child of the node.
+func NewJoinNode(n Node, name string, joinType JoinType, tableName, alias string, on expr.Expr) Node {
+	if alias == "" {
+		alias = tableName
+	}
+
+	return &joinNode{
+		node: node{
+			op:    Join,
+			left:  n,
+			right: NewTableInputNode(tableName),
+		},
+		joinType:  joinType,
+		name:      name,
+		tableName: tableName,
+		alias:     alias,
+		on:        on,
+	}
+}
+
+func (n *joinNode) Bind(tx *database.Transaction, params []expr.Param) (err error) {
+	n.tx = tx
+	n.params = params
+
+	n.table, err = tx.GetTable(n.tableName)
+	if err != nil {
+		return err
+	}
+
+	n.index, n.iop, n.e = nil, nil, nil
+
+	indexes, err := n.table.Indexes()
+	if err != nil {
+		return err
+	}
+
+	op, ok := n.on.(expr.Operator)
+	if !ok || op.Token() != scanner.EQ {
+		return nil
+	}
+	iop, ok := op.(IndexIteratorOperator)
+	if !ok {
+		return nil
+	}
+
+	// alias.path = e or e = alias.path,
+	// where e doesn't read the documents of the table
+	for _, operands := range [][2]expr.Expr{{op.LeftHand(), op.RightHand()}, {op.RightHand(), op.LeftHand()}} {
+		fs, ok := operands[0].(expr.FieldSelector)
+		if !ok || len(fs) < 2 || fs[0].FieldName != n.alias {
+			continue
+		}
+
+		if readsField(operands[1], n.alias) {
+			continue
+		}
+
+		idx, ok := indexes[fs[1:].Name()]
+		if !ok {
+			continue
+		}
+
+		n.index, n.iop, n.e = &idx, iop, operands[1]
+		return nil
+	}
+
+	return nil
+}
+
+// readsField returns true if e contains a path starting with the given field.
+func readsField(e expr.Expr, field string) bool {
+	switch t := e.(type) {
+	case expr.FieldSelector:
+		return len(t) > 0 && t[0].FieldName == field
+	case expr.LiteralValue, expr.NamedParam, expr.PositionalParam:
+		return false
+	case expr.Parentheses:
+		return readsField(t.E, field)
+	case expr.Operator:
+		return readsField(t.LeftHand(), field) || readsField(t.RightHand(), field)
+	}
+
+	// unknown expressions may read any field
+	return true
+}
+
+func (n *joinNode) setMemoryAccount(m *database.MemoryAccount) {
+	n.memory = m
+}
+
+func (n *joinNode) ToStream(st document.Stream) (document.Stream, error) {
+	path := document.ValuePath{document.ValuePathFragment{FieldName: n.alias}}
+
+	return document.NewStream(document.IteratorFunc(func(fn func(d document.Document) error) error {
+		stack := expr.EvalStack{
+			Tx:     n.tx,
+			Params: n.params,
+		}
+		var fb, tfb document.FieldBuffer
+		jd := joinedDocument{FieldBuffer: &fb, name: n.name, alias: n.alias}
+
+		return st.Iterate(func(outer document.Document) error {
+			fb.Reset()
+			err := fb.ScanDocument(outer)
+			if err != nil {
+				return err
+			}
+			jd.outer, jd.inner = outer, nil
+			stack.Document = &jd
+
+			// the document of the table is stored under its name,
+			// which must not be used by a field of the document of the stream
+			if _, err := fb.GetByField(n.alias); err == nil {
+				return database.NewError(database.CodeAmbiguousColumn,
+					fmt.Sprintf("field %q conflicts with the name of the joined table %s, which must be given another name with AS", n.alias, n.tableName))
+			}
+
+			// the copies of both documents are held while the table is read
+			size := documentSize(&fb)
+			err = n.memory.Grow(size)
+			if err != nil {
+				return err
+			}
+			defer n.memory.Shrink(size)
+
+			var matched bool
+			err = n.iterateTable(&jd, stack, func(d document.Document) error {
+				// documents of the table must be materialized to be read by field
+				tfb.Reset()
+				err := tfb.Copy(d)
+				if err != nil {
+					return err
+				}
+
+				tsize := documentSize(&tfb)
+				err = n.memory.Grow(tsize)
+				if err != nil {
+					return err
+				}
+				defer n.memory.Shrink(tsize)
+
+				err = fb.Set(path, document.NewDocumentValue(&tfb))
+				if err != nil {
+					return err
+				}
+				jd.inner = &tfb
+
+				v, err := n.on.Eval(stack)
+				if err != nil {
+					return err
+				}
+				ok, err := v.IsTruthy()
+				if err != nil || !ok {
+					return err
+				}
+
+				matched = true
+				return fn(&jd)
+			})
+			if err != nil || matched || n.joinType != LeftJoin {
+				return err
+			}
+
+			err = fb.Set(path, document.NewNullValue())
+			if err != nil {
+				return err
+			}
+			jd.inner = nil
+			return fn(&jd)
+		})
+	})), nil
+}
+
+// iterateTable calls fn with the documents of the table that may match jd:
+// all of them, or the ones returned by the index.
+func (n *joinNode) iterateTable(jd *joinedDocument, stack expr.EvalStack, fn func(d document.Document) error) error {
+	if n.index == nil {
+		return n.table.Iterate(fn)
+	}
+
+	// the value looked up in the index is computed from the document of the stream only,
+	// unless one of its unqualified fields is missing and may belong to the table
+	jd.inner, jd.strict = nil, true
+	v, err := n.e.Eval(stack)
+	jd.strict = false
+	if err == errFieldUnresolved {
+		return n.table.Iterate(fn)
+	}
+	if err != nil {
+		return err
+	}
+
+	// comparing with null never matches
+	if v.Type == document.NullValue {
+		return nil
+	}
+
+	return n.iop.IterateIndex(n.index, n.table, v, fn)
+}
+
+func (n *joinNode) String() string {
+	table := n.tableName
+	if n.alias != n.tableName {
+		table += " AS " + n.alias
+	}
+
+	if n.index != nil {
+		return fmt.Sprintf("%s(%s ON %s, index: %s)", n.joinType, table, n.on, n.index.Opts.IndexName)
+	}
+
+	return fmt.Sprintf("%s(%s ON %s)", n.joinType, table, n.on)
+}
+
+// errFieldUnresolved is returned by a strict joinedDocument when a field
+// can't be found without the document of the table.
+var errFieldUnresolved = errors.New("field not resolved")
+
+// joinedDocument is a document returned by a join, which gives access
+// to the document of the stream and to the one of the table by their names.
+type joinedDocument struct {
+	*document.FieldBuffer
+
+	name  string
+	alias string
+	outer document.Document
+	// document of the table, or nil if the document of the stream
+	// didn't match any document of a left join.
+	inner document.Document
+	// if true, fields not found in the document of the stream
+	// return errFieldUnresolved, since inner is not known yet.
+	strict bool
+}
+
+// GetByField returns the document of the table if field is its name, or else
+// the field of the document of the stream or of the document of the table,
+// which must not exist in both. If the document of the stream doesn't have the field,
+// it is itself returned if field is its name.
+func (d *joinedDocument) GetByField(field string) (document.Value, error) {
+	if field == d.alias {
+		return d.FieldBuffer.GetByField(field)
+	}
+
+	v, err := d.outer.GetByField(field)
+	if err != nil && err != document.ErrFieldNotFound {
+		return v, err
+	}
+	found := err == nil
+	if !found && field == d.name {
+		return document.NewDocumentValue(d.outer), nil
+	}
+
+	if d.inner == nil {
+		if !found && d.strict {
+			return document.Value{}, errFieldUnresolved
+		}
+		return v, err
+	}
+
+	iv, err := d.inner.GetByField(field)
+	switch {
+	case err == document.ErrFieldNotFound:
+		if found {
+			return v, nil
+		}
+	case err != nil:
+	case found:
+		return document.Value{}, database.NewError(database.CodeAmbiguousColumn,
+			fmt.Sprintf("field %q is ambiguous, it must be qualified with the name of its table", field))
+	}
+
+	return iv, err
+}
+
+// Key returns the key of the document of the stream, if any.
+func (d *joinedDocument) Key() []byte {
+	if k, ok := d.outer.(document.Keyer); ok {
+		return k.Key()
+	}
+
+	return nil
+}
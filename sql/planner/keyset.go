@@ -0,0 +1,188 @@
+package planner
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/key"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// A Cursor is the position of a document in the results of a query sorted with ORDER BY.
+// It allows fetching the following documents using a condition on the sorted field,
+// which can use an index, instead of skipping the previous ones with OFFSET.
+type Cursor struct {
+	// Value of the sorted field.
+	Value document.Value
+	// Key of the document, used to order documents with the same value.
+	Key []byte
+}
+
+// Cursor returns the cursor of d, a document returned by the tree.
+// The tree must have a sort node and d must contain the sorted field.
+func (t *Tree) Cursor(d document.Document) (*Cursor, error) {
+	key, _, err := t.keysetNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := document.ValuePath(key.Path).GetValue(d)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get the value of %s: %w", key.Path, err)
+	}
+	if v.Type == document.NullValue {
+		return nil, errors.New("cannot paginate after a null value")
+	}
+
+	k, ok := d.(document.Keyer)
+	if !ok || k.Key() == nil {
+		return nil, errors.New("document has no key")
+	}
+
+	v, err = copyValue(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cursor{Value: v, Key: append([]byte{}, k.Key()...)}, nil
+}
+
+// After modifies the tree to only return the documents following the cursor,
+// and returns it. The tree must not be bound yet.
+// A selection node using the sorted field is added above the input node,
+// allowing the optimizer to seek the position of the cursor in an index.
+// Documents whose sorted field is null or missing are only returned by the first page.
+func (t *Tree) After(c *Cursor) (*Tree, error) {
+	key, pn, err := t.keysetNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	var prev Node
+	n := t.Root
+	for n != nil && n.Operation() != Input {
+		prev = n
+		n = n.Left()
+	}
+	if n == nil || pn == nil {
+		return nil, errors.New("cannot paginate a query without table")
+	}
+
+	desc := key.desc()
+	var cond expr.Expr
+	if desc {
+		cond = expr.Lte(key.Path, expr.LiteralValue(c.Value))
+	} else {
+		cond = expr.Gte(key.Path, expr.LiteralValue(c.Value))
+	}
+
+	// documents with the same value as the cursor are ordered by key,
+	// skip the ones that were already returned.
+	skip := OperatorFunc(func(d document.Document) (document.Document, error) {
+		v, err := document.ValuePath(key.Path).GetValue(d)
+		if err != nil && err != document.ErrFieldNotFound {
+			return nil, err
+		}
+		if err == nil {
+			ok, err := v.IsEqual(c.Value)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				k, _ := d.(document.Keyer)
+				if k != nil {
+					cmp := bytes.Compare(k.Key(), c.Key)
+					if (!desc && cmp <= 0) || (desc && cmp >= 0) {
+						return nil, nil
+					}
+				}
+			}
+		}
+
+		return d, nil
+	})
+
+	// the skip node is placed right below the projection, to keep
+	// the other selection nodes below it usable by the optimizer.
+	prev.SetLeft(NewSelectionNode(n, cond))
+	pn.SetLeft(NewOperatorNode(pn.Left(), "After("+c.Value.String()+")", skip))
+	return t, nil
+}
+
+// keysetNodes returns the sort key and the projection node of the tree,
+// if the sorted field can be used to paginate.
+func (t *Tree) keysetNodes() (SortKey, *ProjectionNode, error) {
+	var sn *sortNode
+	var pn *ProjectionNode
+
+	for n := t.Root; n != nil; n = n.Left() {
+		switch n.Operation() {
+		case Sort:
+			sn = n.(*sortNode)
+		case Projection:
+			if p, ok := n.(*ProjectionNode); ok {
+				pn = p
+			}
+		}
+	}
+
+	if sn == nil {
+		return SortKey{}, nil, errors.New("cannot paginate a query without ORDER BY")
+	}
+
+	// the cursor only holds the value of one field
+	if len(sn.keys) > 1 {
+		return SortKey{}, nil, errors.New("cannot paginate a query sorted by multiple fields")
+	}
+	key := sn.keys[0]
+
+	// the sorted field must not be computed by the projection,
+	// for the condition to be evaluated on the stored documents.
+	if pn != nil && pn.computes(key.Path[0].FieldName) {
+		return SortKey{}, nil, fmt.Errorf("cannot paginate on computed field %s", key.Path[0].FieldName)
+	}
+
+	return key, pn, nil
+}
+
+// Token encodes the cursor as an opaque string that can be sent to clients.
+func (c *Cursor) Token() (string, error) {
+	v, err := key.AppendValue(nil, c.Value)
+	if err != nil {
+		return "", err
+	}
+
+	buf := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+len(v)+len(c.Key))
+	buf = buf[:binary.PutUvarint(buf, uint64(len(v)))]
+	buf = append(buf, v...)
+	buf = append(buf, c.Key...)
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// ParseCursor decodes a token returned by Cursor.Token.
+func ParseCursor(token string) (*Cursor, error) {
+	buf, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	l, n := binary.Uvarint(buf)
+	if n <= 0 || l == 0 || uint64(len(buf)-n) < l {
+		return nil, errInvalidCursor
+	}
+
+	v, err := key.DecodeValue(buf[n : n+int(l)])
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	return &Cursor{Value: v, Key: buf[n+int(l):]}, nil
+}
+
+var errInvalidCursor = database.NewError(database.CodeDatatypeMismatch, "invalid cursor")
@@ -0,0 +1,88 @@
+package planner_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/parser"
+	"github.com/genjidb/genji/sql/planner"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTreeAfter(t *testing.T) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	err = db.Exec(ctx, `CREATE TABLE test; CREATE INDEX idx_a ON test (a);
+		INSERT INTO test (b, a) VALUES (1, 3), (2, 1), (3, 2), (4, 2), (5, 1), (6, 2), (7, 5), (8, NULL)`)
+	require.NoError(t, err)
+
+	tx, err := db.Begin(false)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	// fetches all the pages and returns the values of b
+	paginate := func(q string) [][]int64 {
+		var pages [][]int64
+		var token string
+
+		for {
+			stmt, err := parser.ParseQuery(ctx, q)
+			require.NoError(t, err)
+			tree := stmt.Statements[0].(*planner.Tree)
+
+			if token != "" {
+				c, err := planner.ParseCursor(token)
+				require.NoError(t, err)
+				tree, err = tree.After(c)
+				require.NoError(t, err)
+			}
+
+			res, err := tree.Run(ctx, tx.Transaction, nil)
+			require.NoError(t, err)
+
+			var page []int64
+			var last *planner.Cursor
+			err = res.Iterate(func(d document.Document) error {
+				v, err := d.GetByField("b")
+				if err != nil {
+					return err
+				}
+				page = append(page, v.V.(int64))
+
+				last, err = tree.Cursor(d)
+				return err
+			})
+			require.NoError(t, err)
+
+			if len(page) == 0 {
+				return pages
+			}
+			pages = append(pages, page)
+
+			token, err = last.Token()
+			require.NoError(t, err)
+		}
+	}
+
+	require.Equal(t, [][]int64{{2, 5, 3}, {4, 6, 1}, {7}}, paginate("SELECT a, b FROM test WHERE a >= 0 ORDER BY a LIMIT 3"))
+	require.Equal(t, [][]int64{{7, 1}, {6, 4}, {3, 5}, {2}}, paginate("SELECT * FROM test ORDER BY a DESC LIMIT 2"))
+
+	t.Run("Errors", func(t *testing.T) {
+		for _, q := range []string{"SELECT * FROM test", "SELECT b AS a FROM test ORDER BY a"} {
+			stmt, err := parser.ParseQuery(ctx, q)
+			require.NoError(t, err)
+
+			_, err = stmt.Statements[0].(*planner.Tree).After(&planner.Cursor{Value: document.NewIntegerValue(1)})
+			require.Error(t, err)
+		}
+
+		_, err := planner.ParseCursor("foo")
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,178 @@
+package planner
+
+import (
+	"fmt"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+type lateralNode struct {
+	node
+
+	// name under which the documents of the stream are read by the subquery
+	name  string
+	alias string
+
+	optimized bool
+	tx        *database.Transaction
+	params    []expr.Param
+}
+
+var _ OperationNode = (*lateralNode)(nil)
+
+// NewLateralNode creates a node that runs the subquery for every document of the stream
+// and returns one document per document returned by the subquery, made of the fields
+// of the document of the stream and of a field named alias holding the one of the subquery.
+// Documents for which the subquery returns nothing are discarded.
+// Within the subquery, name refers to the document of the stream, and fields which are
+// not found in the documents of the subquery are looked up in that document.
+// If the subquery doesn't read the document of the stream, it is only run once.
+// The subquery is stored as the right child of the node.
+func NewLateralNode(n Node, name string, subquery *Tree, alias string) Node {
+	return &lateralNode{
+		node: node{
+			op:    Lateral,
+			left:  n,
+			right: subquery.Root,
+		},
+		name:  name,
+		alias: alias,
+	}
+}
+
+func (n *lateralNode) Bind(tx *database.Transaction, params []expr.Param) error {
+	n.tx = tx
+	n.params = params
+	return nil
+}
+
+func (n *lateralNode) ToStream(st document.Stream) (document.Stream, error) {
+	path := document.ValuePath{document.ValuePathFragment{FieldName: n.alias}}
+
+	return document.NewStream(document.IteratorFunc(func(fn func(d document.Document) error) error {
+		// the subquery and its cache only live for this execution
+		sub := subquery{tree: &Tree{Root: n.right}, name: n.name, optimized: n.optimized}
+		defer func() {
+			n.right = sub.tree.Root
+			n.optimized = sub.optimized
+		}()
+
+		stack := expr.EvalStack{
+			Tx:     n.tx,
+			Params: n.params,
+		}
+		var fb, sfb document.FieldBuffer
+
+		return st.Iterate(func(outer document.Document) error {
+			stack.Document = outer
+
+			// errors returned by fn, including ErrStreamClosed,
+			// must interrupt the stream of the node, not only the subquery.
+			var fnErr error
+			err := sub.Iterate(stack, func(d document.Document) error {
+				fb.Reset()
+				err := fb.ScanDocument(outer)
+				if err != nil {
+					return err
+				}
+
+				// projected documents must be materialized to be read by field
+				sfb.Reset()
+				err = sfb.Copy(d)
+				if err != nil {
+					return err
+				}
+
+				err = fb.Set(path, document.NewDocumentValue(&sfb))
+				if err != nil {
+					return err
+				}
+
+				fnErr = fn(&fb)
+				return fnErr
+			})
+			if fnErr != nil {
+				return fnErr
+			}
+
+			return err
+		})
+	})), nil
+}
+
+func (n *lateralNode) String() string {
+	return fmt.Sprintf("Lateral(%s)", n.alias)
+}
+
+// correlatedStream builds the stream of n, like nodeToStream, and passes every document
+// read by its input node to the subquery as the document of cd.
+func correlatedStream(n Node, cd *correlatedDocument) (st document.Stream, err error) {
+	if l := n.Left(); l != nil {
+		st, err = correlatedStream(l, cd)
+		if err != nil {
+			return
+		}
+	}
+
+	switch t := n.(type) {
+	case InputNode:
+		st, err = t.BuildStream()
+		if err != nil {
+			return
+		}
+
+		st = st.Map(func(d document.Document) (document.Document, error) {
+			cd.Document = d
+			return cd, nil
+		})
+	case OperationNode:
+		st, err = t.ToStream(st)
+	default:
+		err = fmt.Errorf("incorrect node type %#v", n)
+	}
+
+	return
+}
+
+// correlatedDocument is a document read by a lateral subquery or a subquery of an expression,
+// which gives access to the document of the outer stream, if any.
+type correlatedDocument struct {
+	document.Document
+
+	name  string
+	outer document.Document
+	// outerRead is true once the outer document has been read,
+	// in which case the result of the subquery depends on it.
+	outerRead bool
+}
+
+// GetByField returns the field of the document or, if it doesn't exist, the outer document
+// if field is its name, or the field of the outer document.
+func (d *correlatedDocument) GetByField(field string) (document.Value, error) {
+	v, err := d.Document.GetByField(field)
+	if err != document.ErrFieldNotFound {
+		return v, err
+	}
+
+	if d.outer == nil {
+		return v, err
+	}
+	d.outerRead = true
+
+	if field == d.name {
+		return document.NewDocumentValue(d.outer), nil
+	}
+
+	return d.outer.GetByField(field)
+}
+
+// Key returns the key of the document, if any.
+func (d *correlatedDocument) Key() []byte {
+	if k, ok := d.Document.(document.Keyer); ok {
+		return k.Key()
+	}
+
+	return nil
+}
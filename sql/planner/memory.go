@@ -0,0 +1,98 @@
+package planner
+
+import (
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+)
+
+// memoryConsumer is implemented by nodes holding documents in memory
+// during the execution of the tree.
+type memoryConsumer interface {
+	setMemoryAccount(m *database.MemoryAccount)
+}
+
+// setMemoryAccount shares m with every node of the tree consuming memory.
+func setMemoryAccount(n Node, m *database.MemoryAccount) {
+	if n == nil {
+		return
+	}
+
+	if mc, ok := n.(memoryConsumer); ok {
+		mc.setMemoryAccount(m)
+	}
+
+	setMemoryAccount(n.Left(), m)
+	setMemoryAccount(n.Right(), m)
+}
+
+// Approximate sizes in bytes, used for memory accounting.
+const (
+	valueOverhead      = 24
+	fieldOverhead      = 16
+	aggregatorOverhead = 64
+)
+
+// documentSize returns the approximate number of bytes used by d once copied.
+func documentSize(d document.Document) int64 {
+	var size int64
+
+	_ = d.Iterate(func(field string, v document.Value) error {
+		size += fieldOverhead + int64(len(field)) + valueSize(v)
+		return nil
+	})
+
+	return size
+}
+
+// valueSize returns the approximate number of bytes used by v once copied.
+func valueSize(v document.Value) int64 {
+	switch v.Type {
+	case document.TextValue:
+		return valueOverhead + int64(len(v.V.(string)))
+	case document.BlobValue:
+		return valueOverhead + int64(len(v.V.([]byte)))
+	case document.DocumentValue:
+		return valueOverhead + documentSize(v.V.(document.Document))
+	case document.ArrayValue:
+		size := int64(valueOverhead)
+		_ = v.V.(document.Array).Iterate(func(i int, v document.Value) error {
+			size += valueSize(v)
+			return nil
+		})
+		return size
+	}
+
+	return valueOverhead
+}
+
+// accountedAggregatorBuilder accounts for the memory used by the aggregators of each group.
+type accountedAggregatorBuilder struct {
+	document.AggregatorBuilder
+
+	memory *database.MemoryAccount
+	size   *int64
+}
+
+func (a *accountedAggregatorBuilder) NewAggregator(group document.Value) document.Aggregator {
+	size := aggregatorOverhead + valueSize(group)
+	err := a.memory.Grow(size)
+	if err != nil {
+		return failingAggregator{err}
+	}
+	*a.size += size
+
+	return a.AggregatorBuilder.NewAggregator(group)
+}
+
+// failingAggregator is returned instead of an aggregator that couldn't be allocated.
+type failingAggregator struct {
+	err error
+}
+
+func (f failingAggregator) Add(d document.Document) error {
+	return f.err
+}
+
+func (f failingAggregator) Aggregate(fb *document.FieldBuffer) error {
+	return f.err
+}
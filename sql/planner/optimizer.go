@@ -1,6 +1,9 @@
 package planner
 
 import (
+	"math"
+	"sort"
+
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/sql/query/expr"
@@ -12,6 +15,11 @@ var optimizerRules = []func(t *Tree) (*Tree, error){
 	PrecalculateExprRule,
 	RemoveUnnecessarySelectionNodesRule,
 	UseIndexBasedOnSelectionNodeRule,
+	UseIndexForSortRule,
+	PrunePartitionsRule,
+	UseFieldIndexRule,
+	PushProjectionRule,
+	UseColumnarStorageRule,
 }
 
 // Optimize takes a tree, applies a list of optimization rules
@@ -201,6 +209,17 @@ func precalculateExpr(e expr.Expr) expr.Expr {
 			if err != nil {
 				panic(err)
 			}
+			// integer overflows are left to be evaluated within the transaction,
+			// whose overflow mode might be to fail
+			if expr.IsArithmeticOperator(t) && lh.(expr.LiteralValue).Type == document.IntegerValue && rh.(expr.LiteralValue).Type == document.IntegerValue {
+				if v.Type == document.DoubleValue {
+					return e
+				}
+				// MinInt64 % -1 is 0, but the division it comes from overflows
+				if t.Token() == scanner.MOD && lh.(expr.LiteralValue).V == int64(math.MinInt64) && rh.(expr.LiteralValue).V == int64(-1) {
+					return e
+				}
+			}
 			// we replace this expression with the result of its evaluation
 			return expr.LiteralValue(v)
 		}
@@ -254,9 +273,10 @@ func RemoveUnnecessarySelectionNodesRule(t *Tree) (*Tree, error) {
 // - one of its operands is path selector that is indexed
 // - the other operand is a literal value or a parameter
 // If found, it will replace the input node by an indexInputNode using this index.
+// A lower and an upper bound on the same indexed path, like a > 1 AND a < 10, are combined
+// into a single scan of the index between both bounds.
 func UseIndexBasedOnSelectionNodeRule(t *Tree) (*Tree, error) {
 	n := t.Root
-	var prev Node
 	var inputNode Node
 
 	// first we lookup for the input node
@@ -273,17 +293,21 @@ func UseIndexBasedOnSelectionNodeRule(t *Tree) (*Tree, error) {
 		return t, nil
 	}
 
-	// then we get the table indexes. here we will assume that at this point
-	// inputNodes can only be instances of tableInputNode.
-	inpn := inputNode.(*tableInputNode)
+	// then we get the table indexes. custom input nodes
+	// are not backed by a table and can't use an index.
+	inpn, ok := inputNode.(*tableInputNode)
+	if !ok {
+		return t, nil
+	}
 	indexes, err := inpn.table.Indexes()
 	if err != nil {
 		return nil, err
 	}
 
 	type candidate struct {
-		prevNode, nextNode Node
-		in                 *indexInputNode
+		// selection nodes replaced by the index input node
+		nodes []Node
+		in    *indexInputNode
 	}
 
 	var candidates []candidate
@@ -291,24 +315,71 @@ func UseIndexBasedOnSelectionNodeRule(t *Tree) (*Tree, error) {
 	n = t.Root
 	// look for all selection nodes that satisfy our requirements
 	for n != nil {
-		if n.Operation() == Selection {
+		switch n.Operation() {
+		case Custom, Sample, Lateral, Join:
+			// custom operators, lateral subqueries and joins may modify
+			// documents and samples must be taken before filtering,
+			// selection nodes above them can't be evaluated using an index.
+			candidates = candidates[:0]
+		case Selection:
 			sn := n.(*selectionNode)
 			indexedNode := selectionNodeValidForIndex(sn, inpn.tableName, indexes)
 			if indexedNode != nil {
 				candidates = append(candidates, candidate{
-					prevNode: prev,
-					nextNode: n.Left(),
-					in:       indexedNode,
+					nodes: []Node{n},
+					in:    indexedNode,
 				})
 			}
 		}
 
-		prev = n
 		n = n.Left()
 	}
 
+	// a lower and an upper bound on the same index are combined
+	// into a single bounded scan.
+	for i := 0; i < len(candidates); i++ {
+		lower, lowerExclusive, ok := indexBound(candidates[i].in)
+		if !ok {
+			continue
+		}
+
+		for j := i + 1; j < len(candidates); j++ {
+			other, otherExclusive, ok := indexBound(candidates[j].in)
+			if !ok || other == lower || candidates[j].in.indexName != candidates[i].in.indexName {
+				continue
+			}
+
+			min, max := candidates[i], candidates[j]
+			minExclusive, maxExclusive := lowerExclusive, otherExclusive
+			if !lower {
+				min, max = max, min
+				minExclusive, maxExclusive = maxExclusive, minExclusive
+			}
+
+			in := NewIndexInputNode(inpn.tableName, min.in.indexName, nil, nil, scanner.ASC).(*indexInputNode)
+			in.index = min.in.index
+			in.rng = &indexRange{
+				min:          min.in.e,
+				max:          max.in.e,
+				minExclusive: minExclusive,
+				maxExclusive: maxExclusive,
+				minCond:      min.in.cond(),
+				maxCond:      max.in.cond(),
+			}
+
+			candidates[i] = candidate{
+				nodes: append(min.nodes, max.nodes...),
+				in:    in,
+			}
+			candidates = append(candidates[:j], candidates[j+1:]...)
+			break
+		}
+	}
+
 	// determine which index is the most interesting and replace it in the tree.
-	// we will assume that unique indexes are more interesting than list indexes
+	// if the number of documents read by both candidates can be estimated,
+	// we select the one that reads the fewest.
+	// otherwise, we will assume that unique indexes are more interesting than list indexes
 	// because they usually have less elements.
 	var selectedCandidate *candidate
 
@@ -318,6 +389,15 @@ func UseIndexBasedOnSelectionNodeRule(t *Tree) (*Tree, error) {
 			continue
 		}
 
+		cost, ok := estimateIndexCost(candidate.in)
+		selectedCost, selectedOk := estimateIndexCost(selectedCandidate.in)
+		if ok && selectedOk {
+			if cost < selectedCost {
+				selectedCandidate = &candidates[i]
+			}
+			continue
+		}
+
 		// if the candidate's related index is a unique index,
 		// select it.
 		idx := candidate.in.index
@@ -335,15 +415,13 @@ func UseIndexBasedOnSelectionNodeRule(t *Tree) (*Tree, error) {
 		return nil, err
 	}
 
-	// we remove the selection node from the tree
-	if selectedCandidate.prevNode == nil {
-		t.Root = selectedCandidate.nextNode
-	} else {
-		selectedCandidate.prevNode.SetLeft(selectedCandidate.nextNode)
+	// we remove the selection nodes from the tree
+	for _, sn := range selectedCandidate.nodes {
+		removeNode(t, sn)
 	}
 
 	n = t.Root
-	prev = nil
+	var prev Node
 	// we lookup again for the input node and the node that is right before.
 	for n != nil {
 		if n.Operation() == Input {
@@ -364,6 +442,454 @@ func UseIndexBasedOnSelectionNodeRule(t *Tree) (*Tree, error) {
 	return t, nil
 }
 
+// UseIndexForSortRule removes the sort node of the tree when the documents can be read
+// in the requested order from a unique index on the path of the first sort key.
+// Since a unique index holds at most one document per value, including null and missing values,
+// the first key is enough to order the documents and the other keys are never compared.
+// Indexes only cover a single path, so they can't provide the order of the other keys.
+// The table must be read entirely or using a bounded scan of that same index, and the nodes
+// between the sort and the input nodes must only filter or project the documents, without
+// computing a field with the same name as the sorted one.
+// The null values must be placed where the index stores them, i.e. before the other values
+// in ascending order and after them in descending order.
+// Example:
+//   this:
+//     Sort(a DESC, b ASC)
+//     └── ∏(*)
+//         └── Table(test)
+//   becomes this, if idx_a is a unique index on a:
+//     ∏(*)
+//     └── Index(idx_a DESC)
+func UseIndexForSortRule(t *Tree) (*Tree, error) {
+	var sn *sortNode
+	var pn *ProjectionNode
+	var prev Node
+
+	n := t.Root
+	for ; n != nil && n.Operation() != Input; n = n.Left() {
+		prev = n
+
+		if sn == nil {
+			sn, _ = n.(*sortNode)
+			continue
+		}
+
+		switch tn := n.(type) {
+		case *selectionNode:
+		case *ProjectionNode:
+			pn = tn
+		default:
+			// other nodes may reorder or modify the documents
+			return t, nil
+		}
+	}
+
+	if sn == nil || n == nil {
+		return t, nil
+	}
+
+	key := sn.keys[0]
+	if key.Nulls != NullsDefault && (key.Nulls == NullsFirst) == key.desc() {
+		return t, nil
+	}
+	if key.Path[0].FieldName == "" || (pn != nil && pn.computes(key.Path[0].FieldName)) {
+		return t, nil
+	}
+
+	dir := scanner.ASC
+	if key.desc() {
+		dir = scanner.DESC
+	}
+
+	path := document.ValuePath(key.Path)
+
+	switch in := n.(type) {
+	case *tableInputNode:
+		indexes, err := in.table.Indexes()
+		if err != nil {
+			return nil, err
+		}
+
+		// the index must contain all the documents of the table
+		idx, ok := indexes[path.String()]
+		if !ok || !idx.Unique || idx.IsSparse() {
+			return t, nil
+		}
+
+		idxn := NewIndexInputNode(in.tableName, idx.Opts.IndexName, nil, nil, dir)
+		if err := idxn.Bind(in.tx, in.params); err != nil {
+			return nil, err
+		}
+		prev.SetLeft(idxn)
+	case *indexInputNode:
+		// scans using an operator follow the order of the operator
+		if !in.index.Unique || !in.index.Opts.Path.IsEqual(path) || (in.e != nil && in.rng == nil) {
+			return t, nil
+		}
+
+		in.orderByDirection = dir
+	default:
+		return t, nil
+	}
+
+	removeNode(t, sn)
+	return t, nil
+}
+
+// PrunePartitionsRule restricts the partitions read by a table input node
+// to the ones that may contain documents matching the selection nodes.
+// Only selection nodes comparing the partition key to a literal are used.
+// Hash partitions can only be pruned by equality and IN conditions.
+// The selection nodes are kept in the tree.
+func PrunePartitionsRule(t *Tree) (*Tree, error) {
+	n := t.Root
+	for n != nil && n.Operation() != Input {
+		n = n.Left()
+	}
+
+	inpn, ok := n.(*tableInputNode)
+	if !ok {
+		return t, nil
+	}
+
+	info, err := inpn.table.Info()
+	if err != nil {
+		return nil, err
+	}
+	if info.Partitioning == nil {
+		return t, nil
+	}
+
+	all := info.Partitioning.Len()
+	selected := make([]int, 0, all)
+	reset := func() {
+		selected = selected[:0]
+		for i := 0; i < all; i++ {
+			selected = append(selected, i)
+		}
+	}
+	reset()
+
+	for n = t.Root; n != nil; n = n.Left() {
+		switch n.Operation() {
+		case Custom, Sample, Lateral, Join:
+			// see UseIndexBasedOnSelectionNodeRule
+			reset()
+		case Selection:
+			parts, ok := partitionsForSelection(n.(*selectionNode), info)
+			if !ok {
+				continue
+			}
+
+			kept := selected[:0]
+			for _, p := range selected {
+				if parts[p] {
+					kept = append(kept, p)
+				}
+			}
+			selected = kept
+		}
+	}
+
+	if len(selected) < all {
+		inpn.partitions = selected
+	}
+
+	return t, nil
+}
+
+// UseFieldIndexRule only reads the documents containing a top-level field from the field index
+// of the table, when the statement selects them with a "path IS NOT NULL" condition, where the path
+// starts with that field. The selection node is kept to filter out the null values.
+// It doesn't apply if the table is read using an index or only some of its partitions.
+func UseFieldIndexRule(t *Tree) (*Tree, error) {
+	n := t.Root
+	for n != nil && n.Operation() != Input {
+		n = n.Left()
+	}
+
+	inpn, ok := n.(*tableInputNode)
+	if !ok || inpn.partitions != nil || !inpn.table.HasFieldIndex() {
+		return t, nil
+	}
+
+	var field string
+	for n = t.Root; n != nil; n = n.Left() {
+		switch n.Operation() {
+		case Custom, Sample, Lateral, Join:
+			// see UseIndexBasedOnSelectionNodeRule
+			field = ""
+		case Selection:
+			if f, ok := isNotNullField(n.(*selectionNode).cond); ok && field == "" {
+				field = f
+			}
+		}
+	}
+
+	inpn.field = field
+	return t, nil
+}
+
+// isNotNullField returns the top-level field of e if it is a "path IS NOT NULL" condition.
+func isNotNullField(e expr.Expr) (string, bool) {
+	if !expr.IsIsNotOperator(e) {
+		return "", false
+	}
+
+	op := e.(expr.Operator)
+	fs, ok := op.LeftHand().(expr.FieldSelector)
+	if !ok || len(fs) == 0 || fs[0].FieldName == "" {
+		return "", false
+	}
+
+	lv, ok := op.RightHand().(expr.LiteralValue)
+	if !ok || lv.Type != document.NullValue {
+		return "", false
+	}
+
+	return fs[0].FieldName, true
+}
+
+// PushProjectionRule passes the top-level fields read by a SELECT statement down to
+// its input node, so that only these fields are decoded from the documents of the table.
+// It only applies when the projection doesn't contain a wildcard and when the other nodes
+// read the documents through known expressions, without subqueries.
+// Example:
+//   this:
+//     ∏(a, b.c)
+//     └── σ(cond: d > 10)
+//         └── Table(test)
+//   only reads the fields a, b and d of the documents of test.
+func PushProjectionRule(t *Tree) (*Tree, error) {
+	var projected bool
+	var in Node
+	fields := make(map[string]bool)
+
+	for n := t.Root; n != nil; n = n.Left() {
+		switch tn := n.(type) {
+		case *ProjectionNode:
+			for _, pf := range tn.Expressions {
+				if _, ok := pf.(ProjectedExpr); !ok {
+					return t, nil
+				}
+			}
+			projected = true
+		case *tableInputNode, *indexInputNode:
+			in = n
+		case *selectionNode, *GroupingNode, *sortNode, *limitNode, *offsetNode:
+		default:
+			// other nodes may read or write the whole documents
+			return t, nil
+		}
+
+		for _, e := range Exprs(n) {
+			if !collectFields(e, fields) {
+				return t, nil
+			}
+		}
+	}
+
+	if !projected || in == nil {
+		return t, nil
+	}
+
+	names := make([]string, 0, len(fields))
+	for f := range fields {
+		names = append(names, f)
+	}
+	sort.Strings(names)
+
+	switch tn := in.(type) {
+	case *tableInputNode:
+		tn.fields = names
+	case *indexInputNode:
+		tn.fields = names
+	}
+
+	return t, nil
+}
+
+// UseColumnarStorageRule reads the fields selected by PushProjectionRule from the columns
+// of a columnar table instead of its documents, when the statement aggregates the documents
+// and only needs some of the columns of the table.
+// Aggregations read every document of the table, which is faster when each column
+// only contains the values of one field instead of whole documents.
+func UseColumnarStorageRule(t *Tree) (*Tree, error) {
+	var aggregates bool
+	var n Node
+	for n = t.Root; n != nil && n.Operation() != Input; n = n.Left() {
+		switch tn := n.(type) {
+		case *GroupingNode:
+			aggregates = true
+		case *ProjectionNode:
+			for _, pf := range tn.Expressions {
+				if pe, ok := pf.(ProjectedExpr); ok {
+					if _, ok := pe.Expr.(AggregatorBuilder); ok {
+						aggregates = true
+					}
+				}
+			}
+		}
+	}
+
+	inpn, ok := n.(*tableInputNode)
+	if !ok || !aggregates || inpn.fields == nil || inpn.partitions != nil || inpn.field != "" || !inpn.table.IsColumnar() {
+		return t, nil
+	}
+
+	columns, err := inpn.table.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	// reading all the columns is slower than reading the documents
+	if len(inpn.fields) >= len(columns) {
+		return t, nil
+	}
+
+	inpn.columns = true
+	return t, nil
+}
+
+// collectFields adds the top-level fields read by e to fields.
+// It returns false if e may read other fields, like subqueries.
+func collectFields(e expr.Expr, fields map[string]bool) bool {
+	switch t := e.(type) {
+	case expr.FieldSelector:
+		if len(t) == 0 || t[0].FieldName == "" {
+			return false
+		}
+		fields[t[0].FieldName] = true
+		return true
+	case expr.LiteralValue, expr.NamedParam, expr.PositionalParam, expr.PKFunc, expr.NowFunc:
+		return true
+	case expr.Parentheses:
+		return collectFields(t.E, fields)
+	case expr.CastFunc:
+		return collectFields(t.Expr, fields)
+	case *expr.CountFunc:
+		return t.Wildcard || collectFields(t.Expr, fields)
+	case *expr.MinFunc:
+		return collectFields(t.Expr, fields)
+	case *expr.MaxFunc:
+		return collectFields(t.Expr, fields)
+	case *expr.SumFunc:
+		return collectFields(t.Expr, fields)
+	case *expr.ApproxCountDistinctFunc:
+		return collectFields(t.Expr, fields)
+	case *expr.ApproxPercentileFunc:
+		return collectFields(t.Expr, fields) && collectFields(t.Percentile, fields)
+	case *expr.PivotFunc:
+		return collectFields(t.Key, fields) && collectFields(t.Value, fields)
+	case *expr.FilterFunc:
+		return collectFields(t.Aggregate, fields) && collectFields(t.Cond, fields)
+	case expr.LiteralExprList:
+		for _, e := range t {
+			if !collectFields(e, fields) {
+				return false
+			}
+		}
+		return true
+	case expr.KVPairs:
+		for _, kv := range t {
+			if !collectFields(kv.V, fields) {
+				return false
+			}
+		}
+		return true
+	case expr.Operator:
+		return collectFields(t.LeftHand(), fields) && collectFields(t.RightHand(), fields)
+	}
+
+	return false
+}
+
+// partitionsForSelection returns the set of partitions that may contain documents matching
+// the condition of the selection node. It returns false if the condition can't be used to
+// prune partitions.
+func partitionsForSelection(sn *selectionNode, info *database.TableInfo) (map[int]bool, bool) {
+	op, ok := sn.cond.(expr.Operator)
+	if !ok {
+		return nil, false
+	}
+
+	ok, field, e := opCanUseIndex(op)
+	if !ok || !document.ValuePath(field).IsEqual(info.Partitioning.Path) {
+		return nil, false
+	}
+
+	lv, ok := e.(expr.LiteralValue)
+	if !ok {
+		return nil, false
+	}
+
+	pt := info.Partitioning
+	parts := make(map[int]bool)
+
+	if expr.IsInOperator(op) {
+		// only path IN [values] can be used
+		if _, ok := op.LeftHand().(expr.FieldSelector); !ok || lv.Type != document.ArrayValue {
+			return nil, false
+		}
+
+		err := lv.V.(document.Array).Iterate(func(_ int, v document.Value) error {
+			p, err := info.PartitionOf(v)
+			if err != nil {
+				return err
+			}
+			parts[p] = true
+			return nil
+		})
+		if err != nil {
+			// values that can't be converted to the type of the key
+			// are left to the selection node.
+			return nil, false
+		}
+
+		return parts, true
+	}
+
+	tok := op.Token()
+	if tok != scanner.EQ && pt.Method != database.PartitionByRange {
+		return nil, false
+	}
+
+	p, err := info.PartitionOf(document.Value(lv))
+	if err != nil {
+		return nil, false
+	}
+
+	if _, ok := op.LeftHand().(expr.FieldSelector); !ok {
+		switch tok {
+		case scanner.GT:
+			tok = scanner.LT
+		case scanner.GTE:
+			tok = scanner.LTE
+		case scanner.LT:
+			tok = scanner.GT
+		case scanner.LTE:
+			tok = scanner.GTE
+		}
+	}
+
+	switch tok {
+	case scanner.EQ:
+		parts[p] = true
+	case scanner.GT, scanner.GTE:
+		for i := p; i < pt.Len(); i++ {
+			parts[i] = true
+		}
+	case scanner.LT, scanner.LTE:
+		for i := 0; i <= p; i++ {
+			parts[i] = true
+		}
+	default:
+		return nil, false
+	}
+
+	return parts, true
+}
+
 func selectionNodeValidForIndex(sn *selectionNode, tableName string, indexes map[string]database.Index) *indexInputNode {
 	if sn.cond == nil {
 		return nil
@@ -403,6 +929,102 @@ func selectionNodeValidForIndex(sn *selectionNode, tableName string, indexes map
 	return in
 }
 
+// estimateIndexCost returns the estimated number of documents read by an index input node
+// for equality and IN conditions. The estimation relies on the uniqueness of the index
+// or on the statistics collected by ANALYZE. It returns false if the cost can't be estimated.
+func estimateIndexCost(in *indexInputNode) (float64, bool) {
+	if in.rng != nil {
+		return 0, false
+	}
+
+	op, ok := in.iop.(expr.Operator)
+	if !ok {
+		return 0, false
+	}
+
+	var values float64
+	switch {
+	case op.Token() == scanner.EQ:
+		values = 1
+	case expr.IsInOperator(op):
+		lv, ok := in.e.(expr.LiteralValue)
+		if !ok || lv.Type != document.ArrayValue {
+			return 0, false
+		}
+		n, err := document.ArrayLength(lv.V.(document.Array))
+		if err != nil {
+			return 0, false
+		}
+		values = float64(n)
+	default:
+		return 0, false
+	}
+
+	switch {
+	case in.index.Unique:
+		return values, true
+	case in.index.Opts.Stats != nil:
+		return values * in.index.Opts.Stats.Selectivity(), true
+	}
+
+	return 0, false
+}
+
+// removeNode removes the given node from the left branch of the tree.
+func removeNode(t *Tree, target Node) {
+	var prev Node
+	for n := t.Root; n != nil; prev, n = n, n.Left() {
+		if n != target {
+			continue
+		}
+
+		if prev == nil {
+			t.Root = n.Left()
+		} else {
+			prev.SetLeft(n.Left())
+		}
+		return
+	}
+}
+
+// indexBound reports whether the condition of an index input node is a bound of a range,
+// i.e. one of the >, >=, < or <= operators. lower is true if values must be greater
+// than the bound, exclusive is true if values equal to the bound don't match.
+func indexBound(in *indexInputNode) (lower, exclusive, ok bool) {
+	op, ok := in.iop.(expr.Operator)
+	if !ok {
+		return false, false, false
+	}
+
+	tok := op.Token()
+	// expr OP path is the same as path reversed-OP expr
+	if _, ok := op.LeftHand().(expr.FieldSelector); !ok {
+		switch tok {
+		case scanner.GT:
+			tok = scanner.LT
+		case scanner.GTE:
+			tok = scanner.LTE
+		case scanner.LT:
+			tok = scanner.GT
+		case scanner.LTE:
+			tok = scanner.GTE
+		}
+	}
+
+	switch tok {
+	case scanner.GT:
+		return true, true, true
+	case scanner.GTE:
+		return true, false, true
+	case scanner.LT:
+		return false, true, true
+	case scanner.LTE:
+		return false, false, true
+	}
+
+	return false, false, false
+}
+
 func opCanUseIndex(op expr.Operator) (bool, expr.FieldSelector, expr.Expr) {
 	lf, leftIsField := op.LeftHand().(expr.FieldSelector)
 	rf, rightIsField := op.RightHand().(expr.FieldSelector)
@@ -1,6 +1,7 @@
 package planner_test
 
 import (
+	"bytes"
 	"context"
 	"testing"
 
@@ -375,3 +376,292 @@ func TestUseIndexBasedOnSelectionNodeRule(t *testing.T) {
 		})
 	}
 }
+
+func TestUseIndexBasedOnStatistics(t *testing.T) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	err = db.Exec(ctx, `
+		CREATE TABLE test;
+		CREATE INDEX idx_status ON test (status);
+		CREATE INDEX idx_b ON test (b);
+	`)
+	require.NoError(t, err)
+
+	for i := 0; i < 30; i++ {
+		err = db.Exec(ctx, "INSERT INTO test (status, b) VALUES (?, ?)", i%3, i)
+		require.NoError(t, err)
+	}
+
+	plan := func() string {
+		d, err := db.QueryDocument(ctx, "EXPLAIN SELECT * FROM test WHERE status = 1 AND b = 4")
+		require.NoError(t, err)
+		v, err := d.GetByField("plan")
+		require.NoError(t, err)
+		return v.V.(string)
+	}
+
+	// without statistics, indexes are treated equally
+	require.Equal(t, "∏(*)\n└── σ(cond: b = 4)\n    └── Index(idx_status)", plan())
+
+	err = db.Exec(ctx, "ANALYZE test")
+	require.NoError(t, err)
+
+	require.Equal(t, "∏(*)\n└── σ(cond: status = 1)\n    └── Index(idx_b)", plan())
+}
+
+func TestPushProjectionRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{"Fields", "SELECT a, b.c AS c FROM test", `[{"a":1,"c":2},{"a":3,"c":null}]`},
+		{"Wildcard", "SELECT * FROM test WHERE a = 3", `[{"a":3,"b":1,"d":"x"}]`},
+		{"Selection", "SELECT a FROM test WHERE d = 'x'", `[{"a":3}]`},
+		{"Index", "SELECT d FROM test WHERE a = 3", `[{"d":"x"}]`},
+		{"Order by", "SELECT a FROM test ORDER BY d DESC", `[{"a":3},{"a":1}]`},
+		{"Group by", "SELECT COUNT(*) AS n FROM test GROUP BY d", `[{"n":1},{"n":1}]`},
+		{"Count", "SELECT COUNT(*) AS n FROM test", `[{"n":2}]`},
+		{"Pk", "SELECT pk() AS k, a FROM test WHERE a = 1", `[{"k":1,"a":1}]`},
+		{"Subquery", "SELECT a FROM test WHERE EXISTS (SELECT * FROM other WHERE x = test.d)", `[{"a":3}]`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, err := genji.Open(":memory:")
+			require.NoError(t, err)
+			defer db.Close()
+
+			ctx := context.Background()
+
+			err = db.Exec(ctx, `
+				CREATE TABLE test;
+				CREATE INDEX idx_a ON test (a);
+				CREATE TABLE other;
+				INSERT INTO test (a, b, d) VALUES (1, {c: 2}, 'w'), (3, 1, 'x');
+				INSERT INTO other (x) VALUES ('x');
+			`)
+			require.NoError(t, err)
+
+			res, err := db.Query(ctx, test.query)
+			require.NoError(t, err)
+			defer res.Close()
+
+			var buf bytes.Buffer
+			err = document.IteratorToJSONArray(&buf, res)
+			require.NoError(t, err)
+			require.JSONEq(t, test.expected, buf.String())
+		})
+	}
+}
+
+func TestUseColumnarStorageRule(t *testing.T) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	err = db.Exec(ctx, `
+		CREATE TABLE test WITH COLUMNAR STORAGE;
+		CREATE TABLE rows;
+		INSERT INTO test (a, b, c) VALUES (1, 'x', 10), (2, 'y', 20), (3, 'x', 30);
+		INSERT INTO test (c) VALUES (40);
+		INSERT INTO rows (a, b, c) VALUES (1, 'x', 10);
+	`)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		query    string
+		plan     string
+		expected string
+	}{
+		{"Aggregate", "SELECT SUM(a) AS s, COUNT(b) AS n FROM test", "∏(SUM(a), COUNT(b))\n└── Table(test, columns: [a b])", `[{"s":6,"n":3}]`},
+		{"Group by", "SELECT COUNT(a) AS n FROM test GROUP BY b", "∏(COUNT(a))\n└── G(b)\n    └── Table(test, columns: [a b])", `[{"n":2},{"n":1},{"n":0}]`},
+		{"Selection", "SELECT MAX(a) AS m FROM test WHERE b = 'y'", "∏(MAX(a))\n└── σ(cond: b = \"y\")\n    └── Table(test, columns: [a b])", `[{"m":2}]`},
+		{"All the columns", "SELECT MIN(a + c) AS m FROM test WHERE b = 'x'", "∏(MIN(a + c))\n└── σ(cond: b = \"x\")\n    └── Table(test)", `[{"m":11}]`},
+		{"No aggregation", "SELECT a FROM test WHERE c > 25", "∏(a)\n└── σ(cond: c > 25)\n    └── Table(test)", `[{"a":3},{"a":null}]`},
+		{"Not columnar", "SELECT SUM(a) AS s FROM rows", "∏(SUM(a))\n└── Table(rows)", `[{"s":1}]`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d, err := db.QueryDocument(ctx, "EXPLAIN "+test.query)
+			require.NoError(t, err)
+			v, err := d.GetByField("plan")
+			require.NoError(t, err)
+			require.Equal(t, test.plan, v.V.(string))
+
+			res, err := db.Query(ctx, test.query)
+			require.NoError(t, err)
+			defer res.Close()
+
+			var buf bytes.Buffer
+			err = document.IteratorToJSONArray(&buf, res)
+			require.NoError(t, err)
+			require.JSONEq(t, test.expected, buf.String())
+		})
+	}
+}
+
+func TestUseFieldIndexRule(t *testing.T) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	err = db.Exec(ctx, `
+		CREATE TABLE test WITH FIELD INDEX;
+		CREATE TABLE rows;
+		CREATE INDEX idx_test_c ON test (c);
+		INSERT INTO test (a, b) VALUES (1, {x: 1}), (2, {y: 2});
+		INSERT INTO test (b, c) VALUES (null, 3);
+		INSERT INTO test (c) VALUES (4);
+		INSERT INTO rows (a) VALUES (1), (null);
+	`)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		query    string
+		plan     string
+		expected string
+	}{
+		{"Is not null", "SELECT a FROM test WHERE a IS NOT NULL", "∏(a)\n└── σ(cond: a IS NOT NULL)\n    └── Table(test, field index: a)", `[{"a":1},{"a":2}]`},
+		{"Null values", "SELECT c FROM test WHERE b IS NOT NULL", "∏(c)\n└── σ(cond: b IS NOT NULL)\n    └── Table(test, field index: b)", `[{"c":null},{"c":null}]`},
+		{"Nested path", "SELECT a FROM test WHERE b.y IS NOT NULL", "∏(a)\n└── σ(cond: b.y IS NOT NULL)\n    └── Table(test, field index: b)", `[{"a":2}]`},
+		{"Other conditions", "SELECT a FROM test WHERE a > 1 AND a IS NOT NULL", "∏(a)\n└── σ(cond: a > 1)\n    └── σ(cond: a IS NOT NULL)\n        └── Table(test, field index: a)", `[{"a":2}]`},
+		{"Unknown field", "SELECT * FROM test WHERE d IS NOT NULL", "∏(*)\n└── σ(cond: d IS NOT NULL)\n    └── Table(test, field index: d)", `[]`},
+		{"Is null", "SELECT c FROM test WHERE a IS NULL", "∏(c)\n└── σ(cond: a IS NULL)\n    └── Table(test)", `[{"c":3},{"c":4}]`},
+		{"Index", "SELECT c FROM test WHERE c = 4 AND a IS NOT NULL", "∏(c)\n└── σ(cond: a IS NOT NULL)\n    └── Index(idx_test_c)", `[]`},
+		{"No field index", "SELECT a FROM rows WHERE a IS NOT NULL", "∏(a)\n└── σ(cond: a IS NOT NULL)\n    └── Table(rows)", `[{"a":1}]`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d, err := db.QueryDocument(ctx, "EXPLAIN "+test.query)
+			require.NoError(t, err)
+			v, err := d.GetByField("plan")
+			require.NoError(t, err)
+			require.Equal(t, test.plan, v.V.(string))
+
+			res, err := db.Query(ctx, test.query)
+			require.NoError(t, err)
+			defer res.Close()
+
+			var buf bytes.Buffer
+			err = document.IteratorToJSONArray(&buf, res)
+			require.NoError(t, err)
+			require.JSONEq(t, test.expected, buf.String())
+		})
+	}
+}
+
+func TestUseIndexForSortRule(t *testing.T) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	err = db.Exec(ctx, `
+		CREATE TABLE test;
+		CREATE UNIQUE INDEX idx_test_a ON test (a);
+		CREATE INDEX idx_test_b ON test (b);
+		INSERT INTO test (a, b, c) VALUES (2, 1, 'x'), (1, 1, 'y'), (3, 2, 'z');
+		INSERT INTO test (b) VALUES (3);
+	`)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		query    string
+		plan     string
+		expected string
+	}{
+		{"Ascending", "SELECT a FROM test ORDER BY a", "∏(a)\n└── Index(idx_test_a)", `[{"a":null},{"a":1},{"a":2},{"a":3}]`},
+		{"Multiple keys", "SELECT a FROM test ORDER BY a DESC, b", "∏(a)\n└── Index(idx_test_a DESC)", `[{"a":3},{"a":2},{"a":1},{"a":null}]`},
+		{"Selection", "SELECT a FROM test WHERE c != 'y' ORDER BY a DESC", "∏(a)\n└── σ(cond: c != \"y\")\n    └── Index(idx_test_a DESC)", `[{"a":3},{"a":2}]`},
+		{"Range", "SELECT a FROM test WHERE a > 1 AND a < 5 ORDER BY a DESC", "∏(a)\n└── Index(idx_test_a DESC)", `[{"a":3},{"a":2}]`},
+		{"Limit", "SELECT a FROM test ORDER BY a DESC LIMIT 1", "Limit(1)\n└── ∏(a)\n    └── Index(idx_test_a DESC)", `[{"a":3}]`},
+		{"Non unique index", "SELECT a FROM test ORDER BY b, a", "Sort(b ASC, a ASC)\n└── ∏(a)\n    └── Table(test)", `[{"a":1},{"a":2},{"a":3},{"a":null}]`},
+		{"Nulls last", "SELECT a FROM test ORDER BY a NULLS LAST", "Sort(a ASC NULLS LAST)\n└── ∏(a)\n    └── Table(test)", `[{"a":1},{"a":2},{"a":3},{"a":null}]`},
+		{"Computed field", "SELECT b AS a FROM test ORDER BY a", "Sort(a ASC)\n└── ∏(b)\n    └── Table(test)", `[{"a":1},{"a":1},{"a":2},{"a":3}]`},
+		{"Other index", "SELECT a FROM test WHERE b = 1 ORDER BY a DESC", "Sort(a DESC)\n└── ∏(a)\n    └── Index(idx_test_b)", `[{"a":2},{"a":1}]`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d, err := db.QueryDocument(ctx, "EXPLAIN "+test.query)
+			require.NoError(t, err)
+			v, err := d.GetByField("plan")
+			require.NoError(t, err)
+			require.Equal(t, test.plan, v.V.(string))
+
+			res, err := db.Query(ctx, test.query)
+			require.NoError(t, err)
+			defer res.Close()
+
+			var buf bytes.Buffer
+			err = document.IteratorToJSONArray(&buf, res)
+			require.NoError(t, err)
+			require.JSONEq(t, test.expected, buf.String())
+		})
+	}
+}
+
+func TestIndexRangeMixedNumericTypes(t *testing.T) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	err = db.Exec(ctx, `
+		CREATE TABLE test (a INTEGER, b DOUBLE);
+		CREATE INDEX idx_test_a ON test (a);
+		CREATE INDEX idx_test_b ON test (b);
+		INSERT INTO test (a, b) VALUES (1, 1.0), (2, 2.0), (3, 3.0), (4, 4.0);
+	`)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{"Integer index/ Double max", "SELECT a FROM test WHERE a > 1 AND a < 3.5", `[{"a":2},{"a":3}]`},
+		{"Integer index/ Double min", "SELECT a FROM test WHERE a > 1.5 AND a <= 3", `[{"a":2},{"a":3}]`},
+		{"Integer index/ Exclusive integral doubles", "SELECT a FROM test WHERE a > 1.0 AND a < 4.0", `[{"a":2},{"a":3}]`},
+		{"Integer index/ Inclusive integral doubles", "SELECT a FROM test WHERE a >= 2.0 AND a <= 3.0", `[{"a":2},{"a":3}]`},
+		{"Integer index/ Out of range bound", "SELECT a FROM test WHERE a > 2 AND a < 100000000000000000000.0", `[{"a":3},{"a":4}]`},
+		{"Integer index/ Descending", "SELECT a FROM test WHERE a >= 1.5 AND a < 4 ORDER BY a DESC", `[{"a":3},{"a":2}]`},
+		{"Double index/ Integer bounds", "SELECT b FROM test WHERE b > 1 AND b <= 3", `[{"b":2.0},{"b":3.0}]`},
+		{"Double index/ Mixed bounds", "SELECT b FROM test WHERE b >= 2 AND b < 3.5", `[{"b":2.0},{"b":3.0}]`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d, err := db.QueryDocument(ctx, "EXPLAIN "+test.query)
+			require.NoError(t, err)
+			v, err := d.GetByField("plan")
+			require.NoError(t, err)
+			require.Contains(t, v.V.(string), "Index(idx_test_")
+
+			res, err := db.Query(ctx, test.query)
+			require.NoError(t, err)
+			defer res.Close()
+
+			var buf bytes.Buffer
+			err = document.IteratorToJSONArray(&buf, res)
+			require.NoError(t, err)
+			require.JSONEq(t, test.expected, buf.String())
+		})
+	}
+}
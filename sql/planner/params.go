@@ -0,0 +1,149 @@
+package planner
+
+import (
+	"errors"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/query/expr"
+	"github.com/genjidb/genji/sql/scanner"
+)
+
+// InferParamTypes gives the parameters of the tree the type of the field they are
+// compared with, using =, !=, <, <=, > or >=, or assigned to by UPDATE. The types are
+// the ones of the field constraints of the table read by the tree, or by its subqueries.
+// Parameters compared with the fields of joined tables or of derived tables are not typed.
+// A parameter on the right of IN or NOT IN expects an array.
+// It implements the query.ParamTyper interface.
+func (t *Tree) InferParamTypes(tx *database.Transaction, types *query.ParamTypes) error {
+	if t.Root == nil {
+		return nil
+	}
+
+	return inferParamTypes(tx, t.Root, types)
+}
+
+// inferParamTypes infers the types of the parameters of the tree whose root is n.
+func inferParamTypes(tx *database.Transaction, root Node, types *query.ParamTypes) error {
+	info, err := treeTableInfo(tx, root)
+	if err != nil {
+		return err
+	}
+
+	for n := root; n != nil; n = n.Left() {
+		if sn, ok := n.(*setNode); ok && info != nil && isParam(sn.e) {
+			types.Add(sn.e, info.FieldType(sn.path))
+		}
+
+		for _, e := range Exprs(n) {
+			inferExprParamTypes(e, info, types)
+		}
+
+		for _, s := range subqueries(n) {
+			if s.tree.Root == nil {
+				continue
+			}
+
+			err := inferParamTypes(tx, s.tree.Root, types)
+			if err != nil {
+				return err
+			}
+		}
+
+		// lateral subqueries, joined tables and derived tables
+		// read their own table.
+		if r := n.Right(); r != nil {
+			err := inferParamTypes(tx, r, types)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// treeTableInfo returns the information of the table read or written by the tree
+// whose root is n, or nil if the tree doesn't read a table or if the table doesn't exist,
+// since it may be created by a previous statement.
+func treeTableInfo(tx *database.Transaction, root Node) (*database.TableInfo, error) {
+	var name string
+	for n := root; n != nil && name == ""; n = n.Left() {
+		switch t := n.(type) {
+		case *tableInputNode:
+			name = t.tableName
+		case *indexInputNode:
+			name = t.tableName
+		case *replacementNode:
+			name = t.tableName
+		case *deletionNode:
+			name = t.tableName
+		}
+	}
+
+	if name == "" {
+		return nil, nil
+	}
+
+	tb, err := tx.GetTable(name)
+	if errors.Is(err, database.ErrTableNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return tb.Info()
+}
+
+// inferExprParamTypes infers the types of the parameters of e.
+// If info is nil, only the parameters of IN operators are typed.
+func inferExprParamTypes(e expr.Expr, info *database.TableInfo, types *query.ParamTypes) {
+	switch t := e.(type) {
+	case expr.Operator:
+		l, r := t.LeftHand(), t.RightHand()
+
+		switch t.Token() {
+		case scanner.IN:
+			if isParam(r) {
+				types.Add(r, document.ArrayValue)
+			}
+		case scanner.EQ, scanner.NEQ, scanner.GT, scanner.GTE, scanner.LT, scanner.LTE:
+			if info == nil {
+				break
+			}
+			if fs, ok := l.(expr.FieldSelector); ok && isParam(r) {
+				types.Add(r, info.FieldType(document.ValuePath(fs)))
+			}
+			if fs, ok := r.(expr.FieldSelector); ok && isParam(l) {
+				types.Add(l, info.FieldType(document.ValuePath(fs)))
+			}
+		}
+
+		inferExprParamTypes(l, info, types)
+		inferExprParamTypes(r, info, types)
+	case expr.Parentheses:
+		inferExprParamTypes(t.E, info, types)
+	case expr.LiteralExprList:
+		for _, e := range t {
+			inferExprParamTypes(e, info, types)
+		}
+	case expr.KVPairs:
+		for _, kv := range t {
+			inferExprParamTypes(kv.V, info, types)
+		}
+	case expr.CastFunc:
+		inferExprParamTypes(t.Expr, info, types)
+	}
+}
+
+// isParam reports whether e is a named or positional parameter.
+func isParam(e expr.Expr) bool {
+	switch e.(type) {
+	case expr.NamedParam, expr.PositionalParam:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,233 @@
+package planner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// CallStmt is a query.Statement that runs the body of a stored procedure
+// within the transaction of the statement.
+type CallStmt struct {
+	ProcedureName string
+	Args          []expr.Expr
+	// Compile parses the body of the procedure into statements.
+	// It is set by the parser.
+	Compile func(body string) ([]query.Statement, error)
+}
+
+// callDepthKey is the context key holding the number of nested procedure calls.
+type callDepthKey struct{}
+
+// Run evaluates the arguments, then runs the statements of the procedure in order,
+// with every parameter of the procedure passed as a named parameter.
+// The parameters of the statement itself are not visible to the procedure.
+// It returns the result of the last statement run, with the number of documents
+// modified by the whole procedure.
+// If procedure calls are nested deeper than the recursion limit of the database,
+// it returns an error wrapping database.ErrRecursionLimitExceeded.
+func (s *CallStmt) Run(ctx context.Context, tx *database.Transaction, params []expr.Param) (query.Result, error) {
+	if s.Compile == nil {
+		return query.Result{}, errors.New("missing procedure compiler")
+	}
+
+	depth, _ := ctx.Value(callDepthKey{}).(int)
+	if limit := tx.DB().RecursionLimit(); limit >= 0 && depth >= limit {
+		return query.Result{}, fmt.Errorf("%w: calls to %q nested more than %d times", database.ErrRecursionLimitExceeded, s.ProcedureName, limit)
+	}
+	ctx = context.WithValue(ctx, callDepthKey{}, depth+1)
+
+	cfg, err := tx.GetProcedure(s.ProcedureName)
+	if err != nil {
+		return query.Result{}, err
+	}
+
+	if len(s.Args) != len(cfg.Params) {
+		return query.Result{}, fmt.Errorf("procedure %s expects %d arguments, got %d", cfg.ProcedureName, len(cfg.Params), len(s.Args))
+	}
+
+	resetSubqueries(s.Args...)
+
+	stack := expr.EvalStack{Tx: tx, Params: params}
+	args := make([]expr.Param, len(s.Args))
+	for i, e := range s.Args {
+		v, err := e.Eval(stack)
+		if err != nil {
+			return query.Result{}, err
+		}
+
+		args[i] = expr.Param{Name: cfg.Params[i], Value: v.V}
+	}
+
+	stmts, err := s.Compile(cfg.Body)
+	if err != nil {
+		return query.Result{}, fmt.Errorf("procedure %s: %w", cfg.ProcedureName, err)
+	}
+
+	return runStatements(ctx, tx, stmts, args)
+}
+
+// IsReadOnly implements the query.Statement interface.
+func (s *CallStmt) IsReadOnly() bool {
+	return false
+}
+
+// IfStmt is a query.Statement that runs the statements of Then if Cond
+// evaluates to a truthy value, or the ones of Else otherwise.
+// It is only used within the body of a procedure.
+type IfStmt struct {
+	Cond expr.Expr
+	Then []query.Statement
+	Else []query.Statement
+}
+
+// Run evaluates the condition and runs the statements of the selected branch.
+func (s *IfStmt) Run(ctx context.Context, tx *database.Transaction, params []expr.Param) (query.Result, error) {
+	// the statements of the procedure may have modified what the subqueries read
+	resetSubqueries(s.Cond)
+
+	v, err := s.Cond.Eval(expr.EvalStack{Tx: tx, Params: params})
+	if err != nil {
+		return query.Result{}, err
+	}
+
+	ok, err := v.IsTruthy()
+	if err != nil {
+		return query.Result{}, err
+	}
+
+	if ok {
+		return runStatements(ctx, tx, s.Then, params)
+	}
+
+	return runStatements(ctx, tx, s.Else, params)
+}
+
+// IsReadOnly returns true if all the statements of both branches are read-only.
+func (s *IfStmt) IsReadOnly() bool {
+	return isReadOnly(s.Then) && isReadOnly(s.Else)
+}
+
+// ForStmt is a query.Statement that runs its body once per document returned by Cursor.
+// It is only used within the body of a procedure.
+type ForStmt struct {
+	Cursor *Tree
+	Body   []query.Statement
+}
+
+// Run reads all the documents of the cursor, then runs the body for each of them,
+// with every top-level field of the document passed as a named parameter,
+// in addition to the parameters of the statement.
+// The documents are read before running the body, which can therefore
+// modify the tables read by the cursor, and are limited by the memory
+// allowed to a query.
+func (s *ForStmt) Run(ctx context.Context, tx *database.Transaction, params []expr.Param) (query.Result, error) {
+	res, err := s.Cursor.Run(ctx, tx, params)
+	if err != nil {
+		return query.Result{}, err
+	}
+
+	memory := tx.MemoryAccount()
+	var size int64
+	defer func() {
+		memory.Shrink(size)
+	}()
+
+	var docs []document.Document
+	err = res.Iterate(func(d document.Document) error {
+		fb := document.NewFieldBuffer()
+		err := fb.Copy(d)
+		if err != nil {
+			return err
+		}
+
+		dsize := documentSize(fb)
+		err = memory.Grow(dsize)
+		if err != nil {
+			return err
+		}
+		size += dsize
+
+		docs = append(docs, fb)
+		return nil
+	})
+	if err != nil {
+		return query.Result{}, err
+	}
+
+	var affected int64
+	for _, d := range docs {
+		// the fields of the document shadow the other parameters
+		var dparams []expr.Param
+		err = d.Iterate(func(field string, v document.Value) error {
+			dparams = append(dparams, expr.Param{Name: field, Value: v.V})
+			return nil
+		})
+		if err != nil {
+			return query.Result{}, err
+		}
+		dparams = append(dparams, params...)
+
+		res, err = runStatements(ctx, tx, s.Body, dparams)
+		if err != nil {
+			return query.Result{}, err
+		}
+		affected += res.RowsAffected
+	}
+
+	res.RowsAffected = affected
+	return res, nil
+}
+
+// IsReadOnly returns true if the cursor and all the statements of the body are read-only.
+func (s *ForStmt) IsReadOnly() bool {
+	return s.Cursor.IsReadOnly() && isReadOnly(s.Body)
+}
+
+// runStatements runs stmts in order and returns the result of the last one,
+// with the number of documents modified by all of them.
+func runStatements(ctx context.Context, tx *database.Transaction, stmts []query.Statement, params []expr.Param) (query.Result, error) {
+	var res query.Result
+	var affected int64
+
+	for _, stmt := range stmts {
+		select {
+		case <-ctx.Done():
+			return query.Result{}, ctx.Err()
+		default:
+		}
+
+		var err error
+		res, err = stmt.Run(ctx, tx, params)
+		if err != nil {
+			return query.Result{}, err
+		}
+		affected += res.RowsAffected
+	}
+
+	res.RowsAffected = affected
+	return res, nil
+}
+
+func isReadOnly(stmts []query.Statement) bool {
+	for _, stmt := range stmts {
+		if !stmt.IsReadOnly() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// resetSubqueries discards the documents cached by the subqueries of the given expressions,
+// which are evaluated outside of a tree and whose cache would otherwise never be reset.
+func resetSubqueries(exprs ...expr.Expr) {
+	for _, s := range exprSubqueries(exprs...) {
+		s.cache = nil
+	}
+}
@@ -0,0 +1,17 @@
+// +build genji_profile
+
+package planner
+
+import "runtime"
+
+// allocProfiling reports whether EXPLAIN ANALYZE records the allocations of each node.
+// Reading the memory statistics stops the world, which slows down the execution
+// considerably, so it is only enabled when building with the genji_profile tag.
+const allocProfiling = true
+
+// readAllocs returns the cumulative number of allocations and allocated bytes.
+func readAllocs() (allocs, bytes uint64) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.Mallocs, m.TotalAlloc
+}
@@ -0,0 +1,11 @@
+// +build !genji_profile
+
+package planner
+
+// allocProfiling reports whether EXPLAIN ANALYZE records the allocations of each node.
+// It is only enabled when building with the genji_profile tag.
+const allocProfiling = false
+
+func readAllocs() (allocs, bytes uint64) {
+	return 0, 0
+}
@@ -18,23 +18,49 @@ type ProjectionNode struct {
 
 	Expressions []ProjectedField
 	tableName   string
+	// name under which the documents of the table are read, if it differs from tableName.
+	alias string
 
-	info *database.TableInfo
-	tx   *database.Transaction
+	info   *database.TableInfo
+	tx     *database.Transaction
+	memory *database.MemoryAccount
 }
 
-var _ operationNode = (*ProjectionNode)(nil)
+var _ OperationNode = (*ProjectionNode)(nil)
 
 // NewProjectionNode creates a ProjectionNode.
+// Subqueries of the expressions that don't know the table of their enclosing statement,
+// because they were parsed before it, refer to tableName.
 func NewProjectionNode(n Node, expressions []ProjectedField, tableName string) Node {
-	return &ProjectionNode{
+	return NewProjectionNodeWithAlias(n, expressions, tableName, "")
+}
+
+// NewProjectionNodeWithAlias creates a ProjectionNode for a statement
+// reading the documents of tableName under the given alias, like "FROM a AS x".
+// Subqueries of the expressions that don't know the table of their enclosing statement
+// refer to alias instead of tableName.
+func NewProjectionNodeWithAlias(n Node, expressions []ProjectedField, tableName, alias string) Node {
+	pn := &ProjectionNode{
 		node: node{
 			op:   Projection,
 			left: n,
 		},
 		Expressions: expressions,
 		tableName:   tableName,
+		alias:       alias,
+	}
+
+	name := tableName
+	if alias != "" {
+		name = alias
+	}
+	for _, s := range subqueries(pn) {
+		if s.name == "" {
+			s.name = name
+		}
 	}
+
+	return pn
 }
 
 // Bind database resources to this node.
@@ -44,6 +70,13 @@ func (n *ProjectionNode) Bind(tx *database.Transaction, params []expr.Param) (er
 		return
 	}
 
+	// the documents of a derived table don't belong to a table
+	for in := n.left; in != nil; in = in.Left() {
+		if _, ok := in.(*derivedTableNode); ok {
+			return nil
+		}
+	}
+
 	table, err := tx.GetTable(n.tableName)
 	if err != nil {
 		return err
@@ -53,13 +86,38 @@ func (n *ProjectionNode) Bind(tx *database.Transaction, params []expr.Param) (er
 	return
 }
 
+// aggregateEmpty returns the aggregation of an empty group
+// if st, the aggregation of a stream without groups, is empty.
+func aggregateEmpty(st document.Stream, builders []document.AggregatorBuilder) document.Stream {
+	return document.NewStream(document.IteratorFunc(func(fn func(d document.Document) error) error {
+		empty := true
+		err := st.Iterate(func(d document.Document) error {
+			empty = false
+			return fn(d)
+		})
+		if err != nil || !empty {
+			return err
+		}
+
+		fb := document.NewFieldBuffer()
+		for _, builder := range builders {
+			err = builder.NewAggregator(document.NewNullValue()).Aggregate(fb)
+			if err != nil {
+				return err
+			}
+		}
+
+		return fn(fb)
+	}))
+}
+
 // An AggregatorBuilder can build aggregators on demand.
 type AggregatorBuilder interface {
 	document.AggregatorBuilder
 	SetAlias(string)
 }
 
-func (n *ProjectionNode) toStream(st document.Stream) (document.Stream, error) {
+func (n *ProjectionNode) ToStream(st document.Stream) (document.Stream, error) {
 	var aggBuilders []document.AggregatorBuilder
 
 	for _, e := range n.Expressions {
@@ -77,11 +135,36 @@ func (n *ProjectionNode) toStream(st document.Stream) (document.Stream, error) {
 	}
 
 	if len(aggBuilders) > 0 {
-		st = st.Aggregate(aggBuilders...)
+		if n.memory != nil {
+			var size int64
+			for i := range aggBuilders {
+				aggBuilders[i] = &accountedAggregatorBuilder{AggregatorBuilder: aggBuilders[i], memory: n.memory, size: &size}
+			}
+
+			// release the memory of the aggregators once the stream has been read
+			agg := st.Aggregate(aggBuilders...)
+			st = document.NewStream(document.IteratorFunc(func(fn func(d document.Document) error) error {
+				defer func() {
+					n.memory.Shrink(size)
+					size = 0
+				}()
+
+				return agg.Iterate(fn)
+			}))
+		} else {
+			st = st.Aggregate(aggBuilders...)
+		}
+
+		// without GROUP BY, aggregating an empty stream still returns
+		// a single document, like COUNT(*) returning 0
+		if _, ok := n.left.(*GroupingNode); !ok {
+			st = aggregateEmpty(st, aggBuilders)
+		}
 	}
 
 	if st.IsEmpty() {
 		d := documentMask{
+			tx:           n.tx,
 			resultFields: n.Expressions,
 		}
 		var fb document.FieldBuffer
@@ -95,6 +178,7 @@ func (n *ProjectionNode) toStream(st document.Stream) (document.Stream, error) {
 		var dm documentMask
 		st = st.Map(func(d document.Document) (document.Document, error) {
 			dm.info = n.info
+			dm.tx = n.tx
 			dm.d = d
 			dm.resultFields = n.Expressions
 
@@ -105,6 +189,10 @@ func (n *ProjectionNode) toStream(st document.Stream) (document.Stream, error) {
 	return st, nil
 }
 
+func (n *ProjectionNode) setMemoryAccount(m *database.MemoryAccount) {
+	n.memory = m
+}
+
 func (n *ProjectionNode) String() string {
 	var b strings.Builder
 
@@ -118,19 +206,50 @@ func (n *ProjectionNode) String() string {
 	return fmt.Sprintf("∏(%s)", b.String())
 }
 
+// computes reports whether the projection returns a top-level field with the given name
+// whose value is not the field of the same name of the input documents.
+func (n *ProjectionNode) computes(name string) bool {
+	for _, pf := range n.Expressions {
+		pe, ok := pf.(ProjectedExpr)
+		if !ok || pe.ExprName != name {
+			continue
+		}
+
+		if fs, ok := pe.Expr.(expr.FieldSelector); !ok || document.ValuePath(fs).String() != name {
+			return true
+		}
+	}
+
+	return false
+}
+
 type documentMask struct {
 	info         *database.TableInfo
+	tx           *database.Transaction
 	d            document.Document
 	resultFields []ProjectedField
 }
 
 var _ document.Document = documentMask{}
 
+// errFieldFound stops the iteration of a documentMask once a field was found.
+var errFieldFound = errors.New("field found")
+
+// GetByField returns the value of the projected field, which may be aliased.
 func (r documentMask) GetByField(field string) (document.Value, error) {
-	for _, rf := range r.resultFields {
-		if rf.Name() == field || rf.Name() == "*" {
-			return r.d.GetByField(field)
+	var v document.Value
+	err := r.Iterate(func(f string, value document.Value) error {
+		if f == field {
+			v = value
+			return errFieldFound
 		}
+		return nil
+	})
+	if err == errFieldFound {
+		return v, nil
+	}
+	if err != nil {
+		return document.Value{}, err
 	}
 
 	return document.Value{}, document.ErrFieldNotFound
@@ -138,6 +257,7 @@ func (r documentMask) GetByField(field string) (document.Value, error) {
 
 func (r documentMask) Iterate(fn func(field string, value document.Value) error) error {
 	stack := expr.EvalStack{
+		Tx:       r.tx,
 		Document: r.d,
 		Info:     r.info,
 	}
@@ -152,6 +272,15 @@ func (r documentMask) Iterate(fn func(field string, value document.Value) error)
 	return nil
 }
 
+// Key returns the key of the projected document, if any.
+func (r documentMask) Key() []byte {
+	if k, ok := r.d.(document.Keyer); ok {
+		return k.Key()
+	}
+
+	return nil
+}
+
 // MarshalJSON implements the json.Marshaler interface.
 func (r documentMask) MarshalJSON() ([]byte, error) {
 	return document.MarshalJSON(r)
@@ -20,9 +20,15 @@ type replacementNode struct {
 	tableName string
 	table     *database.Table
 	codec     encoding.Codec
+	// paths modified by the set and unset nodes of the stream, see modifiedPaths.
+	paths []document.ValuePath
+	// number of documents replaced by the last call to ToStream
+	replaced int64
+	// if true, ToStream returns the replaced documents, see NewReturningNode.
+	returning bool
 }
 
-var _ operationNode = (*replacementNode)(nil)
+var _ OperationNode = (*replacementNode)(nil)
 
 // NewReplacementNode creates a node that stores every document of a stream
 // in their respective table and primary keys.
@@ -38,16 +44,38 @@ func NewReplacementNode(n Node, tableName string) Node {
 
 func (n *replacementNode) Bind(tx *database.Transaction, params []expr.Param) (err error) {
 	n.table, err = tx.GetTable(n.tableName)
+	n.paths = modifiedPaths(n.left)
 	return
 }
 
+// modifiedPaths returns the paths modified by the chain of set or unset nodes
+// at the top of n, as built for the UPDATE statement.
+// It returns nil if n doesn't start with such a chain.
+func modifiedPaths(n Node) []document.ValuePath {
+	var paths []document.ValuePath
+
+	for n != nil {
+		switch t := n.(type) {
+		case *setNode:
+			paths = append(paths, t.path)
+		case *unsetNode:
+			paths = append(paths, document.ValuePath{{FieldName: t.field}})
+		default:
+			return paths
+		}
+		n = n.Left()
+	}
+
+	return paths
+}
+
 // toResult replaces matching documents by batches of replaceBufferSize documents.
 // Some engines can't create more than one iterator per read-write transaction (https://github.com/dgraph-io/badger/issues/1093).
 // To deal with these limitations, Run will iterate on a limited number of documents, copy the keys
 // to a buffer and replace them after the iteration is complete, and it will do that until there is no document
 // left to replace.
 // Increasing replaceBufferSize will occasionate less key searches (O(log n) for most engines) but will take more memory.
-func (n *replacementNode) toStream(st document.Stream) (document.Stream, error) {
+func (n *replacementNode) ToStream(st document.Stream) (document.Stream, error) {
 	// replace store implementation by a resumable store, temporarily.
 	rit := resumableIterator{
 		store: n.table.Store,
@@ -58,7 +86,9 @@ func (n *replacementNode) toStream(st document.Stream) (document.Stream, error)
 
 	keys := make([][]byte, replaceBufferSize)
 	docs := make([]document.FieldBuffer, replaceBufferSize)
+	n.replaced = 0
 
+	var returned []document.Document
 	var err error
 	for {
 		var i int
@@ -83,10 +113,29 @@ func (n *replacementNode) toStream(st document.Stream) (document.Stream, error)
 		})
 
 		for j := 0; j < i; j++ {
-			err = n.table.Replace(keys[j], docs[j])
+			if n.paths != nil {
+				// only update the indexes and the field stores of the modified paths
+				err = n.table.Update(keys[j], docs[j], n.paths)
+			} else {
+				err = n.table.Replace(keys[j], docs[j])
+			}
 			if err != nil {
 				return document.Stream{}, err
 			}
+			n.replaced++
+
+			if n.returning {
+				// return the document as stored, once converted and validated by the table
+				d, err := n.table.GetDocument(keys[j])
+				if err != nil {
+					return document.Stream{}, err
+				}
+				c, err := copyDocument(d, keys[j])
+				if err != nil {
+					return document.Stream{}, err
+				}
+				returned = append(returned, c)
+			}
 		}
 
 		if i < replaceBufferSize {
@@ -96,9 +145,17 @@ func (n *replacementNode) toStream(st document.Stream) (document.Stream, error)
 		rit.curKey = keys[i-1]
 	}
 
+	if n.returning && err == nil {
+		return document.NewStream(document.NewIterator(returned...)), nil
+	}
+
 	return document.Stream{}, err
 }
 
+func (n *replacementNode) rowsAffected() int64 {
+	return n.replaced
+}
+
 func (n *replacementNode) String() string {
 	return fmt.Sprintf("Replace(%s)", n.tableName)
 }
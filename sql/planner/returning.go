@@ -0,0 +1,74 @@
+package planner
+
+import (
+	"strings"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// Returning is the list of fields of the RETURNING clause of an INSERT statement.
+// UPDATE and DELETE statements project the documents they write with NewReturningNode instead.
+type Returning []ProjectedField
+
+var _ query.Projector = Returning(nil)
+
+// Project returns a stream projecting every document of st, inserted in the given table,
+// like the projection of a SELECT statement on that table.
+func (r Returning) Project(tx *database.Transaction, tableName string, args []expr.Param, st document.Stream) (document.Stream, error) {
+	pn := NewProjectionNode(nil, r, tableName)
+
+	err := Bind(&Tree{Root: pn}, tx, args)
+	if err != nil {
+		return document.Stream{}, err
+	}
+
+	return pn.(*ProjectionNode).ToStream(st)
+}
+
+// String returns the fields of the clause, separated by commas.
+func (r Returning) String() string {
+	var b strings.Builder
+
+	for i, pf := range r {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(formatProjectedField(pf))
+	}
+
+	return b.String()
+}
+
+// NewReturningNode creates a projection node returning the documents written by n,
+// which must have been created by NewDeletionNode or NewReplacementNode.
+// The stream of n then contains the deleted documents, or the replaced documents
+// as they were stored, instead of being empty.
+func NewReturningNode(n Node, fields []ProjectedField, tableName string) Node {
+	switch t := n.(type) {
+	case *deletionNode:
+		t.returning = true
+	case *replacementNode:
+		t.returning = true
+	}
+
+	return NewProjectionNode(n, fields, tableName)
+}
+
+// copyDocument returns a copy of d and of its key, which remains valid
+// once d is modified or deleted.
+func copyDocument(d document.Document, key []byte) (document.Document, error) {
+	var fb document.FieldBuffer
+
+	err := fb.Copy(d)
+	if err != nil {
+		return nil, err
+	}
+
+	return &encodedDocumentWithKey{
+		Document: &fb,
+		key:      append([]byte(nil), key...),
+	}, nil
+}
@@ -0,0 +1,178 @@
+package planner
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// A SampleMethod determines how documents are sampled.
+type SampleMethod int
+
+const (
+	// SamplePercent selects each document of the stream with a given probability,
+	// expressed as a percentage. The number of documents returned varies from one
+	// execution to another.
+	SamplePercent SampleMethod = iota
+	// SampleRows selects a fixed number of documents at random,
+	// using reservoir sampling. The entire stream is read before the
+	// first document is returned.
+	SampleRows
+)
+
+type sampleNode struct {
+	node
+
+	method  SampleMethod
+	size    float64
+	seed    int64
+	hasSeed bool
+	// seed used for the current execution if hasSeed is false,
+	// generated by the database when the node is bound.
+	randSeed int64
+}
+
+var _ OperationNode = (*sampleNode)(nil)
+
+// NewSampleNode creates a node that returns a random subset of the documents of a stream.
+// Depending on the method, size is either a percentage or a number of documents.
+func NewSampleNode(n Node, method SampleMethod, size float64) Node {
+	return &sampleNode{
+		node: node{
+			op:   Sample,
+			left: n,
+		},
+		method: method,
+		size:   size,
+	}
+}
+
+// NewSampleNodeWithSeed creates a sample node that always selects the same documents
+// of a given stream, by using seed to initialize the random number generator.
+func NewSampleNodeWithSeed(n Node, method SampleMethod, size float64, seed int64) Node {
+	sn := NewSampleNode(n, method, size).(*sampleNode)
+	sn.seed = seed
+	sn.hasSeed = true
+	return sn
+}
+
+// IsRepeatable reports whether n returns the same documents every time
+// it reads the same stream. Only sample nodes without a seed don't.
+func IsRepeatable(n Node) bool {
+	sn, ok := n.(*sampleNode)
+	return !ok || sn.hasSeed
+}
+
+func (n *sampleNode) Bind(tx *database.Transaction, params []expr.Param) (err error) {
+	if !n.hasSeed {
+		n.randSeed = tx.DB().Rand().Int63()
+	}
+	return
+}
+
+func (n *sampleNode) ToStream(st document.Stream) (document.Stream, error) {
+	if n.size < 0 {
+		return st, fmt.Errorf("sample size must be positive, got %v", n.size)
+	}
+
+	seed := n.seed
+	if !n.hasSeed {
+		seed = n.randSeed
+	}
+
+	switch n.method {
+	case SamplePercent:
+		if n.size > 100 {
+			return st, fmt.Errorf("sample percentage must be between 0 and 100, got %v", n.size)
+		}
+
+		return document.NewStream(document.IteratorFunc(func(fn func(d document.Document) error) error {
+			// a new generator is created on every iteration
+			// for the results to be reproducible.
+			rnd := rand.New(rand.NewSource(seed))
+			return st.Iterate(func(d document.Document) error {
+				if rnd.Float64()*100 >= n.size {
+					return nil
+				}
+
+				return fn(d)
+			})
+		})), nil
+	case SampleRows:
+		return document.NewStream(&reservoirIterator{
+			st:   st,
+			size: int(n.size),
+			seed: seed,
+		}), nil
+	}
+
+	return st, fmt.Errorf("unknown sample method %d", n.method)
+}
+
+func (n *sampleNode) String() string {
+	s := "Sample(" + strconv.FormatFloat(n.size, 'f', -1, 64)
+	if n.method == SampleRows {
+		s += " ROWS"
+	} else {
+		s += " PERCENT"
+	}
+
+	if n.hasSeed {
+		s += " REPEATABLE " + strconv.FormatInt(n.seed, 10)
+	}
+
+	return s + ")"
+}
+
+// reservoirIterator selects size documents from a stream of unknown length.
+type reservoirIterator struct {
+	st   document.Stream
+	size int
+	seed int64
+}
+
+func (it *reservoirIterator) Iterate(fn func(d document.Document) error) error {
+	if it.size == 0 {
+		return nil
+	}
+
+	rnd := rand.New(rand.NewSource(it.seed))
+	reservoir := make([]document.FieldBuffer, 0, it.size)
+
+	var i int
+	err := it.st.Iterate(func(d document.Document) error {
+		i++
+
+		// fill the reservoir first, then replace the document at a random
+		// position with a probability of size / i.
+		j := len(reservoir)
+		if j == it.size {
+			j = rnd.Intn(i)
+			if j >= it.size {
+				return nil
+			}
+			reservoir[j].Reset()
+		} else {
+			reservoir = append(reservoir, document.FieldBuffer{})
+		}
+
+		// the document may be reused by the stream, it must be copied.
+		return reservoir[j].Copy(d)
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := range reservoir {
+		err = fn(&reservoir[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
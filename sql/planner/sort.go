@@ -4,28 +4,78 @@ import (
 	"bytes"
 	"container/heap"
 	"fmt"
+	"strings"
 
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
-	"github.com/genjidb/genji/key"
 	"github.com/genjidb/genji/sql/query/expr"
 	"github.com/genjidb/genji/sql/scanner"
 )
 
+// NullsOrder determines where null and missing values are placed
+// by a sort operation.
+type NullsOrder uint8
+
+const (
+	// NullsDefault sorts null values as the smallest values: they appear
+	// first in ascending order and last in descending order.
+	NullsDefault NullsOrder = iota
+	// NullsFirst places null values before any other value.
+	NullsFirst
+	// NullsLast places null values after any other value.
+	NullsLast
+)
+
+// A SortKey is one of the expressions of an ORDER BY clause.
+type SortKey struct {
+	Path expr.FieldSelector
+	// ASC or DESC, ASC if zero.
+	Direction scanner.Token
+	Nulls     NullsOrder
+}
+
+func (k SortKey) desc() bool {
+	return k.Direction == scanner.DESC
+}
+
+// String returns the key with its direction, like "a DESC NULLS LAST".
+func (k SortKey) String() string {
+	dir := "ASC"
+	if k.desc() {
+		dir = "DESC"
+	}
+
+	switch k.Nulls {
+	case NullsFirst:
+		dir += " NULLS FIRST"
+	case NullsLast:
+		dir += " NULLS LAST"
+	}
+
+	return fmt.Sprintf("%s %s", k.Path, dir)
+}
+
 type sortNode struct {
 	node
 
-	sortField expr.FieldSelector
-	direction scanner.Token
-}
+	keys []SortKey
 
-var _ operationNode = (*sortNode)(nil)
+	memory *database.MemoryAccount
+}
 
-// NewSortNode creates a node that sorts a stream according to a given
-// document path and a sort direction.
-func NewSortNode(n Node, sortField expr.FieldSelector, direction scanner.Token) Node {
-	if direction == 0 {
-		direction = scanner.ASC
+var _ OperationNode = (*sortNode)(nil)
+
+// NewSortNode creates a node that sorts a stream according to the given keys.
+// Documents are sorted by the first key, then documents with equal values
+// are sorted by the second key, and so on.
+// Values are sorted following the order defined by document.Compare.
+// Documents with equal values for every key are sorted by document key,
+// in the direction of the first key, then in the order of the stream.
+func NewSortNode(n Node, keys ...SortKey) Node {
+	for i := range keys {
+		if keys[i].Direction == 0 {
+			keys[i].Direction = scanner.ASC
+		}
 	}
 
 	return &sortNode{
@@ -33,8 +83,7 @@ func NewSortNode(n Node, sortField expr.FieldSelector, direction scanner.Token)
 			op:   Sort,
 			left: n,
 		},
-		sortField: sortField,
-		direction: direction,
+		keys: keys,
 	}
 }
 
@@ -42,37 +91,50 @@ func (n *sortNode) Bind(tx *database.Transaction, params []expr.Param) (err erro
 	return
 }
 
-func (n *sortNode) toStream(st document.Stream) (document.Stream, error) {
+func (n *sortNode) ToStream(st document.Stream) (document.Stream, error) {
 	return document.NewStream(&sortIterator{
-		st:        st,
-		sortField: n.sortField,
-		direction: n.direction,
+		st:     st,
+		keys:   n.keys,
+		memory: n.memory,
 	}), nil
 }
 
+func (n *sortNode) setMemoryAccount(m *database.MemoryAccount) {
+	n.memory = m
+}
+
 func (n *sortNode) String() string {
-	dir := "ASC"
-	if n.direction == scanner.DESC {
-		dir = "DESC"
+	keys := make([]string, len(n.keys))
+	for i, k := range n.keys {
+		keys[i] = k.String()
 	}
 
-	return fmt.Sprintf("Sort(%s %s)", n.sortField, dir)
+	return fmt.Sprintf("Sort(%s)", strings.Join(keys, ", "))
 }
 
 type sortIterator struct {
-	st        document.Stream
-	sortField expr.FieldSelector
-	direction scanner.Token
+	st     document.Stream
+	keys   []SortKey
+	memory *database.MemoryAccount
 }
 
 func (it *sortIterator) Iterate(fn func(d document.Document) error) error {
-	h, err := it.sortStream(it.st)
+	var size int64
+	defer func() {
+		it.memory.Shrink(size)
+	}()
+
+	h, err := it.sortStream(it.st, &size)
 	if err != nil {
 		return err
 	}
 
 	for h.Len() > 0 {
 		node := heap.Pop(h).(heapNode)
+		if h.err != nil {
+			return h.err
+		}
+
 		err := fn(&(node.data))
 		if err != nil {
 			return err
@@ -85,117 +147,182 @@ func (it *sortIterator) Iterate(fn func(d document.Document) error) error {
 // sortStream operates a partial sort on the iterator using a heap.
 // This ensures a O(k+n log n) time complexity, where k is the sum of
 // OFFSET + LIMIT clauses, if provided, otherwise k = n.
-// If the sorting is in ascending order, a min-heap will be used
-// otherwise a max-heap will be used instead.
 // Once the heap is filled entirely with the content of the table a stream is returned.
 // During iteration, the stream will pop the k-smallest or k-largest elements, depending on
 // the chosen sorting order (ASC or DESC).
 // This function is not memory efficient as it's loading the entire stream in memory before
 // returning the k-smallest or k-largest elements.
-func (it *sortIterator) sortStream(st document.Stream) (heap.Interface, error) {
-	path := document.ValuePath(it.sortField)
+// The memory used by the documents is added to size.
+func (it *sortIterator) sortStream(st document.Stream, size *int64) (*sortHeap, error) {
+	paths := make([]document.ValuePath, len(it.keys))
+	for i, k := range it.keys {
+		paths[i] = document.ValuePath(k.Path)
+	}
 
-	var h heap.Interface
-	if it.direction == scanner.ASC {
-		h = new(minHeap)
-	} else {
-		h = new(maxHeap)
+	h := sortHeap{
+		keys: it.keys,
 	}
 
-	heap.Init(h)
+	heap.Init(&h)
 
-	return h, st.Iterate(func(d document.Document) error {
-		// It is possible to sort by any projected field
-		// or field of the original document.
-		v, err := path.GetValue(d)
-		if err != nil && err != document.ErrFieldNotFound {
-			return err
-		}
-
-		// If a field is not found in the projected fields
-		// Look for fields in the original document.
-		if err == document.ErrFieldNotFound {
-			if dm, ok := d.(*documentMask); ok {
-				v, err = path.GetValue(dm.d)
-				if err != nil && err != document.ErrFieldNotFound {
-					return err
-				}
-				if err == document.ErrFieldNotFound {
-					v = document.NewNullValue()
-				}
-			} else {
-				v = document.NewNullValue()
-			}
+	var seq int
+	return &h, st.Iterate(func(d document.Document) error {
+		node := heapNode{
+			values: make([]document.Value, len(paths)),
+			seq:    seq,
 		}
+		seq++
 
-		// We need to make sure sort behaviour
-		// if the same with or without indexes.
-		// To achieve that, the value must be encoded using the same method
-		// as what the index package would do.
-		if v.Type == document.IntegerValue {
-			v, err = v.CastAsDouble()
+		dsize := documentSize(d)
+		for i, path := range paths {
+			v, err := sortValue(d, path)
 			if err != nil {
 				return err
 			}
-		}
 
-		var value []byte
-		if v.Type != document.ArrayValue && v.Type != document.DocumentValue {
-			value, err = key.AppendValue(nil, v)
+			// the value may belong to a buffer reused by the stream,
+			// it must be copied.
+			node.values[i], err = copyValue(v)
 			if err != nil {
 				return err
 			}
-		}
 
-		// to ensure ordering of values based on their types
-		// (i.e. booleans < numbers < text, ...,
-		// see index package for more info)
-		// we will prepend the encoded value with one byte
-		// representing the type of the value.
-		// integer will be considered as double
-		value = append([]byte{byte(v.Type)}, value...)
+			dsize += valueSize(v)
+		}
 
-		node := heapNode{
-			value: value,
+		err := it.memory.Grow(dsize)
+		if err != nil {
+			return err
 		}
+		*size += dsize
+
 		err = node.data.Copy(d)
 		if err != nil {
 			return err
 		}
 
-		heap.Push(h, node)
+		heap.Push(&h, node)
 
-		return nil
+		return h.err
 	})
 }
 
+// sortValue returns the value of the path in d.
+// It is possible to sort by any projected field
+// or field of the original document: if a field is not found
+// in the projected fields, it is looked for in the original document.
+// Missing fields are sorted as null values.
+func sortValue(d document.Document, path document.ValuePath) (document.Value, error) {
+	v, err := path.GetValue(d)
+	if err != document.ErrFieldNotFound {
+		return v, err
+	}
+
+	if dm, ok := d.(*documentMask); ok {
+		v, err = path.GetValue(dm.d)
+		if err != document.ErrFieldNotFound {
+			return v, err
+		}
+	}
+
+	return document.NewNullValue(), nil
+}
+
+func copyValue(v document.Value) (document.Value, error) {
+	switch v.Type {
+	case document.BlobValue:
+		return document.NewBlobValue(append([]byte{}, v.V.([]byte)...)), nil
+	case document.ArrayValue:
+		var vb document.ValueBuffer
+		err := vb.Copy(v.V.(document.Array))
+		return document.NewArrayValue(&vb), err
+	case document.DocumentValue:
+		var fb document.FieldBuffer
+		err := fb.Copy(v.V.(document.Document))
+		return document.NewDocumentValue(&fb), err
+	}
+
+	return v, nil
+}
+
 type heapNode struct {
-	value []byte
-	data  document.FieldBuffer
+	// values of the sort keys
+	values []document.Value
+	data   document.FieldBuffer
+	// position of the document in the stream being sorted
+	seq int
 }
 
-type minHeap []heapNode
+// sortHeap is a heap whose smallest element is the first document
+// in the order defined by the sort keys.
+// Values are compared using document.Compare.
+type sortHeap struct {
+	nodes []heapNode
+	keys  []SortKey
+	err   error
+}
 
-func (h minHeap) Len() int           { return len(h) }
-func (h minHeap) Less(i, j int) bool { return bytes.Compare(h[i].value, h[j].value) < 0 }
-func (h minHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h sortHeap) Len() int      { return len(h.nodes) }
+func (h sortHeap) Swap(i, j int) { h.nodes[i], h.nodes[j] = h.nodes[j], h.nodes[i] }
 
-func (h *minHeap) Push(x interface{}) {
-	*h = append(*h, x.(heapNode))
+func (h *sortHeap) Less(i, j int) bool {
+	for k, key := range h.keys {
+		cmp := h.compare(key, h.nodes[i].values[k], h.nodes[j].values[k])
+		if cmp != 0 {
+			return cmp < 0
+		}
+	}
+
+	// equal values are ordered by key, for the order
+	// to be the same on every execution.
+	cmp := bytes.Compare(h.nodes[i].data.Key(), h.nodes[j].data.Key())
+	if cmp != 0 {
+		if h.keys[0].desc() {
+			return cmp > 0
+		}
+		return cmp < 0
+	}
+
+	// documents without keys keep the order of the stream
+	return h.nodes[i].seq < h.nodes[j].seq
 }
 
-func (h *minHeap) Pop() interface{} {
-	old := *h
-	n := len(old)
-	x := old[n-1]
-	*h = old[0 : n-1]
-	return x
+// compare returns the position of a relative to b for the given key:
+// a negative number if a comes first, a positive number if b comes first,
+// and 0 if they are equal.
+func (h *sortHeap) compare(key SortKey, a, b document.Value) int {
+	// null values are placed at the requested position,
+	// regardless of the direction
+	if key.Nulls != NullsDefault {
+		aNull, bNull := a.Type == document.NullValue, b.Type == document.NullValue
+		if aNull != bNull {
+			if aNull == (key.Nulls == NullsFirst) {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	cmp, err := document.Compare(a, b)
+	if err != nil {
+		h.err = err
+	}
+
+	if key.desc() {
+		return -cmp
+	}
+
+	return cmp
 }
 
-type maxHeap struct {
-	minHeap
+func (h *sortHeap) Push(x interface{}) {
+	h.nodes = append(h.nodes, x.(heapNode))
 }
 
-func (h maxHeap) Less(i, j int) bool {
-	return bytes.Compare(h.minHeap[i].value, h.minHeap[j].value) > 0
+func (h *sortHeap) Pop() interface{} {
+	old := h.nodes
+	n := len(old)
+	x := old[n-1]
+	h.nodes = old[0 : n-1]
+	return x
 }
@@ -0,0 +1,199 @@
+package planner
+
+import (
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// subquery is a tree evaluated by an expression, like EXISTS or IN.
+type subquery struct {
+	tree *Tree
+	// name under which the document of the enclosing statement is read
+	name      string
+	optimized bool
+	// documents returned during the current execution of the statement, if the
+	// subquery didn't read the document of the enclosing statement.
+	// See bindNode.
+	cache *subqueryCache
+}
+
+// subqueryCache holds the documents returned by an uncorrelated subquery.
+type subqueryCache struct {
+	docs []document.Document
+	// complete is false if the subquery was interrupted
+	// before returning all of its documents.
+	complete bool
+}
+
+var _ expr.Subquery = (*subquery)(nil)
+
+// NewSubquery returns a subquery running t. Within t, name refers to the document
+// of the enclosing statement being evaluated, and fields which are not found in the
+// documents of t are looked up in that document.
+func NewSubquery(t *Tree, name string) expr.Subquery {
+	return &subquery{tree: t, name: name}
+}
+
+// SubqueryTree returns the tree run by s, or nil if s wasn't created by NewSubquery.
+func SubqueryTree(s expr.Subquery) *Tree {
+	if sq, ok := s.(*subquery); ok {
+		return sq.tree
+	}
+
+	return nil
+}
+
+// Iterate binds the tree to the transaction and parameters of the stack,
+// optimizes it the first time it is run and calls fn with the documents it returns.
+// If the subquery doesn't read the document of the stack, its result doesn't depend on it:
+// the documents it returned are kept until the next execution of the statement,
+// and subsequent calls return them without running the subquery.
+// If the subquery was interrupted, it is only run again if fn asks for more documents.
+func (s *subquery) Iterate(stack expr.EvalStack, fn func(d document.Document) error) error {
+	var replayed int
+	if c := s.cache; c != nil {
+		for _, d := range c.docs {
+			err := fn(d)
+			if err != nil {
+				return err
+			}
+		}
+
+		if c.complete {
+			return nil
+		}
+		replayed = len(c.docs)
+	}
+
+	err := Bind(s.tree, stack.Tx, stack.Params)
+	if err != nil {
+		return err
+	}
+
+	if !s.optimized {
+		s.tree, err = Optimize(s.tree)
+		if err != nil {
+			return err
+		}
+		s.optimized = true
+	}
+
+	if s.tree.Root == nil {
+		s.cache = &subqueryCache{complete: true}
+		return nil
+	}
+
+	memory := stack.Tx.MemoryAccount()
+	setMemoryAccount(s.tree.Root, memory)
+
+	cd := correlatedDocument{name: s.name, outer: stack.Document}
+	st, err := correlatedStream(s.tree.Root, &cd)
+	if err != nil {
+		return err
+	}
+
+	// documents are recorded until the subquery reads the document of the stack,
+	// or until they exceed the memory limit of the query.
+	var rec subqueryCache
+	var size int64
+	recording := true
+
+	var i int
+	var fnErr error
+	err = st.Iterate(func(d document.Document) error {
+		if recording && !cd.outerRead {
+			fb := document.NewFieldBuffer()
+			err := fb.Copy(d)
+			if err != nil {
+				return err
+			}
+
+			dsize := documentSize(fb)
+			if memory.Grow(dsize) == nil {
+				size += dsize
+				rec.docs = append(rec.docs, fb)
+				d = fb
+			} else {
+				recording = false
+			}
+		}
+
+		// documents already returned from the cache are skipped
+		i++
+		if i <= replayed {
+			return nil
+		}
+
+		fnErr = fn(d)
+		return fnErr
+	})
+	if err != nil {
+		memory.Shrink(size)
+		return err
+	}
+
+	// the recorded documents are kept in memory until the end of the statement
+	// if they can be reused
+	if recording && !cd.outerRead {
+		rec.complete = fnErr == nil
+		s.cache = &rec
+	} else {
+		memory.Shrink(size)
+	}
+
+	return nil
+}
+
+// SQL returns the SQL representation of the tree.
+func (s *subquery) SQL() string {
+	return s.tree.SQL()
+}
+
+// subqueries returns the subqueries evaluated by the expressions of n.
+func subqueries(n Node) []*subquery {
+	return exprSubqueries(Exprs(n)...)
+}
+
+// exprSubqueries returns the subqueries evaluated by the given expressions.
+func exprSubqueries(exprs ...expr.Expr) []*subquery {
+	var subs []*subquery
+
+	var walk func(e expr.Expr)
+	walk = func(e expr.Expr) {
+		switch t := e.(type) {
+		case expr.Exists:
+			if s, ok := t.Subquery.(*subquery); ok {
+				subs = append(subs, s)
+			}
+		case expr.ScalarSubquery:
+			if s, ok := t.Subquery.(*subquery); ok {
+				subs = append(subs, s)
+			}
+		case expr.ArraySubquery:
+			if s, ok := t.Subquery.(*subquery); ok {
+				subs = append(subs, s)
+			}
+		case expr.Parentheses:
+			walk(t.E)
+		case expr.CastFunc:
+			walk(t.Expr)
+		case expr.LiteralExprList:
+			for _, e := range t {
+				walk(e)
+			}
+		case expr.KVPairs:
+			for _, kv := range t {
+				walk(kv.V)
+			}
+		case expr.Operator:
+			walk(t.LeftHand())
+			walk(t.RightHand())
+		}
+	}
+
+	for _, e := range exprs {
+		walk(e)
+	}
+
+	return subs
+}
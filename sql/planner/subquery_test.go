@@ -0,0 +1,121 @@
+package planner_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/parser"
+	"github.com/genjidb/genji/sql/planner"
+	"github.com/genjidb/genji/sql/query/expr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubqueryCache(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec(ctx, `
+		CREATE TABLE test (k INTEGER PRIMARY KEY);
+		CREATE TABLE other;
+		INSERT INTO test (k) VALUES (1), (2), (3);
+		INSERT INTO other (a) VALUES (1), (2);
+	`)
+	require.NoError(t, err)
+
+	tx, err := db.Begin(true)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	// subquery parses q and counts the documents it returns
+	subquery := func(q string, count *int) *planner.Tree {
+		stmt, err := parser.ParseQuery(ctx, q)
+		require.NoError(t, err)
+
+		tree := stmt.Statements[0].(*planner.Tree)
+		tree.Root = planner.NewOperatorNode(tree.Root, "count", planner.OperatorFunc(func(d document.Document) (document.Document, error) {
+			*count++
+			return d, nil
+		}))
+		return tree
+	}
+
+	run := func(tree *planner.Tree) int {
+		res, err := tree.Run(ctx, tx.Transaction, nil)
+		require.NoError(t, err)
+		n, err := res.Count()
+		require.NoError(t, err)
+		return n
+	}
+
+	exists := func(sub *planner.Tree) *planner.Tree {
+		return planner.NewTree(
+			planner.NewProjectionNode(
+				planner.NewSelectionNode(planner.NewTableInputNode("test"), expr.Exists{Subquery: planner.NewSubquery(sub, "test")}),
+				[]planner.ProjectedField{planner.Wildcard{}},
+				"test",
+			))
+	}
+
+	lateral := func(sub *planner.Tree) *planner.Tree {
+		return planner.NewTree(
+			planner.NewProjectionNode(
+				planner.NewLateralNode(planner.NewTableInputNode("test"), "test", sub, "o"),
+				[]planner.ProjectedField{planner.Wildcard{}},
+				"test",
+			))
+	}
+
+	tests := []struct {
+		name     string
+		tree     func(sub *planner.Tree) *planner.Tree
+		subquery string
+		docs     int
+		runs     int
+	}{
+		// the subquery stops after its first document and is only run once
+		{"Exists", exists, "SELECT * FROM other", 3, 1},
+		{"Exists without documents", exists, "SELECT * FROM other WHERE a > 10", 0, 0},
+		{"Exists correlated", exists, "SELECT * FROM other WHERE a = test.k", 2, 2},
+		{"Exists unqualified outer field", exists, "SELECT * FROM other WHERE a = k", 2, 2},
+		{"Lateral", lateral, "SELECT * FROM other", 6, 2},
+		{"Lateral correlated", lateral, "SELECT * FROM other WHERE a <= test.k", 5, 5},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var count int
+			tree := test.tree(subquery(test.subquery, &count))
+
+			require.Equal(t, test.docs, run(tree))
+			require.Equal(t, test.runs, count)
+
+			// the result of the subquery is not kept across executions
+			err := tx.Exec(ctx, "INSERT INTO other (a) VALUES (100)")
+			require.NoError(t, err)
+			defer func() {
+				err := tx.Exec(ctx, "DELETE FROM other WHERE a = 100")
+				require.NoError(t, err)
+			}()
+
+			count = 0
+			n := run(tree)
+			require.GreaterOrEqual(t, n, test.docs)
+			require.GreaterOrEqual(t, count, test.runs)
+		})
+	}
+
+	t.Run("Executions", func(t *testing.T) {
+		var count int
+		tree := exists(subquery("SELECT * FROM other WHERE a = 100", &count))
+		require.Equal(t, 0, run(tree))
+
+		err := tx.Exec(ctx, "INSERT INTO other (a) VALUES (100)")
+		require.NoError(t, err)
+		require.Equal(t, 3, run(tree))
+	})
+}
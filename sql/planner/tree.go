@@ -8,6 +8,8 @@ package planner
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
@@ -42,6 +44,16 @@ const (
 	Set
 	// Unset is an operation that removes a path from every document of a stream
 	Unset
+	// Sample is an operation that selects a random subset of the documents of a stream.
+	Sample
+	// Lateral is an operation that runs a subquery for every document of a stream
+	// and adds the documents it returns to that document.
+	Lateral
+	// Join is an operation that combines every document of a stream with
+	// the documents of a table satisfying a condition.
+	Join
+	// Custom is an operation defined outside of this package. See NewOperatorNode.
+	Custom
 	// Group is an operation that groups documents based on a given path.
 )
 
@@ -49,6 +61,11 @@ const (
 // Each node will manipulate the stream using relational algebra operations.
 type Tree struct {
 	Root Node
+
+	// if true, Root has been optimized for the catalog
+	// of the database having this version.
+	optimized      bool
+	catalogVersion uint64
 }
 
 // NewTree creates a new tree with n as root.
@@ -56,88 +73,248 @@ func NewTree(n Node) *Tree {
 	return &Tree{Root: n}
 }
 
+// A RewriteFunc transforms a tree before it is bound and optimized,
+// for example to add a selection node filtering documents on every query.
+// It can either modify t and return it or return a new tree.
+type RewriteFunc func(t *Tree) (*Tree, error)
+
+// Rewrite calls every function with the tree returned by the previous one,
+// starting with t, and returns the last tree.
+func Rewrite(t *Tree, fns ...RewriteFunc) (*Tree, error) {
+	var err error
+
+	for _, fn := range fns {
+		t, err = fn(t)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
 // Run implements the query.Statement interface.
 // It binds the tree to the database resources and executes it.
+// The tree is only optimized the first time it is run, and again after
+// the catalog of the database has changed, so that running it repeatedly,
+// with different parameters, reuses the same plan.
 func (t *Tree) Run(ctx context.Context, tx *database.Transaction, params []expr.Param) (query.Result, error) {
 	err := Bind(t, tx, params)
 	if err != nil {
 		return query.Result{}, err
 	}
 
-	t, err = Optimize(t)
-	if err != nil {
-		return query.Result{}, err
+	version := tx.DB().CatalogVersion()
+	if !t.optimized || t.catalogVersion != version {
+		ot, err := Optimize(t)
+		if err != nil {
+			return query.Result{}, err
+		}
+
+		t.Root = ot.Root
+		t.optimized, t.catalogVersion = true, version
 	}
 
+	setMemoryAccount(t.Root, tx.MemoryAccount())
+
 	return t.execute()
 }
 
 func (t *Tree) execute() (query.Result, error) {
-	var st document.Stream
-	var err error
-
-	if t.Root.Left() != nil {
-		st, err = nodeToStream(t.Root.Left())
-		if err != nil {
-			return query.Result{}, err
-		}
-	}
-
-	st, err = t.Root.(operationNode).toStream(st)
+	st, err := nodeToStream(t.Root, nil)
 	if err != nil {
 		return query.Result{}, err
 	}
 
 	return query.Result{
-		Stream: st,
+		Stream:       st,
+		RowsAffected: rowsAffected(t.Root),
 	}, nil
 }
 
-func (t *Tree) String() string {
-	n := t.Root
+// A writerNode is a node modifying the documents of its stream.
+type writerNode interface {
+	// rowsAffected returns the number of documents modified
+	// when building the stream of the node.
+	rowsAffected() int64
+}
 
-	if n == nil {
+// rowsAffected returns the number of documents modified by the nodes of the tree.
+func rowsAffected(n Node) int64 {
+	var count int64
+
+	for ; n != nil; n = n.Left() {
+		if w, ok := n.(writerNode); ok {
+			count += w.rowsAffected()
+		}
+	}
+
+	return count
+}
+
+// String returns a representation of the tree, with one node per line
+// and each node indented below its parent, starting with the root.
+// Example:
+//
+//	∏(a)
+//	└── σ(cond: b > 10)
+//	    └── Table(test)
+func (t *Tree) String() string {
+	if t.Root == nil {
 		return ""
 	}
 
-	return nodeToString(t.Root)
+	var b strings.Builder
+	writeNode(&b, t.Root, "", "", nil)
+	return strings.TrimSuffix(b.String(), "\n")
 }
 
-func nodeToString(n Node) string {
-	var s string
+// writeNode writes n to b, then its children with the given prefix.
+// If suffix is not nil, its result is written after each node.
+func writeNode(b *strings.Builder, n Node, branch, prefix string, suffix func(n Node) string) {
+	fmt.Fprintf(b, "%s%v", branch, n)
+	if suffix != nil {
+		b.WriteString(suffix(n))
+	}
+	b.WriteByte('\n')
+
+	children := nodeChildren(n)
+	for i, c := range children {
+		if i == len(children)-1 {
+			writeNode(b, c, prefix+"└── ", prefix+"    ", suffix)
+		} else {
+			writeNode(b, c, prefix+"├── ", prefix+"│   ", suffix)
+		}
+	}
+}
+
+// Tables returns the names of the tables read by the tree and its subqueries,
+// in the order they appear.
+func (t *Tree) Tables() []string {
+	var names []string
+	seen := make(map[string]bool)
+
+	var walk func(n Node)
+	walk = func(n Node) {
+		var name string
+		switch in := n.(type) {
+		case *tableInputNode:
+			name = in.tableName
+		case *indexInputNode:
+			name = in.tableName
+		}
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+
+		for _, c := range nodeChildren(n) {
+			walk(c)
+		}
+		for _, s := range subqueries(n) {
+			if s.tree.Root != nil {
+				walk(s.tree.Root)
+			}
+		}
+	}
 
-	if n.Left() != nil {
-		s = nodeToString(n.Left())
+	if t.Root != nil {
+		walk(t.Root)
 	}
 
-	if s == "" {
-		return fmt.Sprintf("%v", n)
+	return names
+}
+
+func nodeChildren(n Node) []Node {
+	var children []Node
+
+	if l := n.Left(); l != nil {
+		children = append(children, l)
+	}
+	if r := n.Right(); r != nil {
+		children = append(children, r)
 	}
 
-	return fmt.Sprintf("%s -> %v", s, n)
+	return children
+}
+
+// DOT returns a description of the tree in the DOT language,
+// which can be rendered using Graphviz.
+// Edges follow the stream of documents, from the inputs to the root.
+func (t *Tree) DOT() string {
+	var b strings.Builder
+
+	b.WriteString("digraph plan {\n")
+	b.WriteString("\trankdir=BT;\n")
+	b.WriteString("\tnode [shape=box];\n")
+
+	if t.Root != nil {
+		var id int
+		writeDOTNode(&b, t.Root, &id)
+	}
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// writeDOTNode writes n and its children to b and returns the identifier of n.
+func writeDOTNode(b *strings.Builder, n Node, id *int) int {
+	nid := *id
+	*id++
+
+	fmt.Fprintf(b, "\tn%d [label=%s];\n", nid, strconv.Quote(fmt.Sprintf("%v", n)))
+
+	for _, c := range nodeChildren(n) {
+		cid := writeDOTNode(b, c, id)
+		fmt.Fprintf(b, "\tn%d -> n%d;\n", cid, nid)
+	}
+
+	return nid
 }
 
 // IsReadOnly implements the query.Statement interface.
+// A tree is read-only if none of its nodes deletes or replaces documents.
 func (t *Tree) IsReadOnly() bool {
-	return false
+	return t.Root == nil || isReadOnlyNode(t.Root)
 }
 
-func nodeToStream(n Node) (st document.Stream, err error) {
+func isReadOnlyNode(n Node) bool {
+	switch n.Operation() {
+	case Deletion, Replacement:
+		return false
+	}
+
+	for _, c := range nodeChildren(n) {
+		if !isReadOnlyNode(c) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// nodeToStream builds the stream of n. If p is not nil,
+// the stream of every node records its statistics in p.
+func nodeToStream(n Node, p *profiler) (st document.Stream, err error) {
 	l := n.Left()
 	if l != nil {
-		st, err = nodeToStream(l)
+		st, err = nodeToStream(l, p)
 		if err != nil {
 			return
 		}
 	}
 
 	switch t := n.(type) {
-	case inputNode:
-		st, err = t.buildStream()
-	case operationNode:
-		st, err = t.toStream(st)
+	case InputNode:
+		st, err = t.BuildStream()
+	case OperationNode:
+		st, err = t.ToStream(st)
 	default:
-		panic(fmt.Sprintf("incorrect node type %#v", n))
+		err = fmt.Errorf("incorrect node type %#v", n)
+	}
+
+	if err == nil && p != nil {
+		st = p.profile(n, st)
 	}
 
 	return
@@ -153,16 +330,24 @@ type Node interface {
 	Bind(tx *database.Transaction, params []expr.Param) error
 }
 
-type inputNode interface {
+// An InputNode is a node that creates a stream of documents,
+// for example by reading a table.
+type InputNode interface {
 	Node
 
-	buildStream() (document.Stream, error)
+	// BuildStream is called once the tree is bound and optimized
+	// and returns the stream read by the parent node.
+	BuildStream() (document.Stream, error)
 }
 
-type operationNode interface {
+// An OperationNode is a node that transforms the stream created by its left child.
+// Nodes that are neither InputNode nor OperationNode can't be executed.
+type OperationNode interface {
 	Node
 
-	toStream(st document.Stream) (document.Stream, error)
+	// ToStream is called once the tree is bound and optimized
+	// and returns a stream derived from st.
+	ToStream(st document.Stream) (document.Stream, error)
 }
 
 type node struct {
@@ -198,7 +383,7 @@ type selectionNode struct {
 	params []expr.Param
 }
 
-var _ operationNode = (*selectionNode)(nil)
+var _ OperationNode = (*selectionNode)(nil)
 
 // NewSelectionNode creates a node that filters documents of a stream, according to
 // the expression condition.
@@ -218,7 +403,7 @@ func (n *selectionNode) Bind(tx *database.Transaction, params []expr.Param) (err
 	return
 }
 
-func (n *selectionNode) toStream(st document.Stream) (document.Stream, error) {
+func (n *selectionNode) ToStream(st document.Stream) (document.Stream, error) {
 	if n.cond == nil {
 		return st, nil
 	}
@@ -255,7 +440,7 @@ type limitNode struct {
 	params []expr.Param
 }
 
-var _ operationNode = (*limitNode)(nil)
+var _ OperationNode = (*limitNode)(nil)
 
 // NewLimitNode creates a node that limits the number of documents processed by the stream.
 func NewLimitNode(n Node, limit int) Node {
@@ -274,7 +459,7 @@ func (n *limitNode) Bind(tx *database.Transaction, params []expr.Param) (err err
 	return
 }
 
-func (n *limitNode) toStream(st document.Stream) (document.Stream, error) {
+func (n *limitNode) ToStream(st document.Stream) (document.Stream, error) {
 	return st.Limit(n.limit), nil
 }
 
@@ -290,7 +475,7 @@ type offsetNode struct {
 	params []expr.Param
 }
 
-var _ operationNode = (*offsetNode)(nil)
+var _ OperationNode = (*offsetNode)(nil)
 
 // NewOffsetNode creates a node that skips a certain number of documents from the stream.
 func NewOffsetNode(n Node, offset int) Node {
@@ -313,7 +498,7 @@ func (n *offsetNode) Bind(tx *database.Transaction, params []expr.Param) (err er
 	return
 }
 
-func (n *offsetNode) toStream(st document.Stream) (document.Stream, error) {
+func (n *offsetNode) ToStream(st document.Stream) (document.Stream, error) {
 	return st.Offset(n.offset), nil
 }
 
@@ -327,7 +512,7 @@ type setNode struct {
 	params []expr.Param
 }
 
-var _ operationNode = (*setNode)(nil)
+var _ OperationNode = (*setNode)(nil)
 
 // NewSetNode creates a node that adds or replaces a path for every document of the stream.
 func NewSetNode(n Node, path document.ValuePath, e expr.Expr) Node {
@@ -351,7 +536,7 @@ func (n *setNode) String() string {
 	return fmt.Sprintf("Set(%s = %s)", n.path, n.e)
 }
 
-func (n *setNode) toStream(st document.Stream) (document.Stream, error) {
+func (n *setNode) ToStream(st document.Stream) (document.Stream, error) {
 	var fb document.FieldBuffer
 
 	stack := expr.EvalStack{
@@ -388,7 +573,7 @@ type unsetNode struct {
 	field string
 }
 
-var _ operationNode = (*unsetNode)(nil)
+var _ OperationNode = (*unsetNode)(nil)
 
 // NewUnsetNode creates a node that adds or replaces a path for every document of the stream.
 func NewUnsetNode(n Node, field string) Node {
@@ -405,7 +590,7 @@ func (n *unsetNode) Bind(tx *database.Transaction, params []expr.Param) error {
 	return nil
 }
 
-func (n *unsetNode) toStream(st document.Stream) (document.Stream, error) {
+func (n *unsetNode) ToStream(st document.Stream) (document.Stream, error) {
 	var fb document.FieldBuffer
 
 	return st.Map(func(d document.Document) (document.Document, error) {
@@ -447,7 +632,7 @@ type GroupingNode struct {
 	Params []expr.Param
 }
 
-var _ operationNode = (*GroupingNode)(nil)
+var _ OperationNode = (*GroupingNode)(nil)
 
 // NewGroupingNode creates a GroupingNode.
 func NewGroupingNode(n Node, e expr.Expr) Node {
@@ -467,7 +652,7 @@ func (n *GroupingNode) Bind(tx *database.Transaction, params []expr.Param) (err
 	return
 }
 
-func (n *GroupingNode) toStream(st document.Stream) (document.Stream, error) {
+func (n *GroupingNode) ToStream(st document.Stream) (document.Stream, error) {
 	return st.GroupBy(func(d document.Document) (document.Value, error) {
 		return n.Expr.Eval(expr.EvalStack{
 			Tx:       n.Tx,
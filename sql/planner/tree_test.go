@@ -0,0 +1,70 @@
+package planner_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/parser"
+	"github.com/genjidb/genji/sql/planner"
+	"github.com/genjidb/genji/sql/query/expr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTreeString(t *testing.T) {
+	require.Equal(t, "", planner.NewTree(nil).String())
+
+	n := planner.NewLimitNode(
+		planner.NewProjectionNode(
+			planner.NewSelectionNode(planner.NewTableInputNode("foo"), expr.Eq(expr.FieldSelector{document.ValuePathFragment{FieldName: "a"}}, expr.IntegerValue(1))),
+			[]planner.ProjectedField{planner.Wildcard{}},
+			"foo",
+		), 10)
+	// nodes with two children
+	n.Left().SetRight(planner.NewTableInputNode("bar"))
+
+	tree := planner.NewTree(n)
+	require.Equal(t, `Limit(10)
+└── ∏(*)
+    ├── σ(cond: a = 1)
+    │   └── Table(foo)
+    └── Table(bar)`, tree.String())
+
+	require.Equal(t, `digraph plan {
+	rankdir=BT;
+	node [shape=box];
+	n0 [label="Limit(10)"];
+	n1 [label="∏(*)"];
+	n2 [label="σ(cond: a = 1)"];
+	n3 [label="Table(foo)"];
+	n3 -> n2;
+	n2 -> n1;
+	n4 [label="Table(bar)"];
+	n4 -> n1;
+	n1 -> n0;
+}
+`, tree.DOT())
+}
+
+func TestTreeIsReadOnly(t *testing.T) {
+	tests := []struct {
+		query    string
+		readOnly bool
+	}{
+		{"SELECT * FROM test", true},
+		{"SELECT a, COUNT(*) FROM test WHERE a > 1 GROUP BY a ORDER BY a LIMIT 10", true},
+		{"SELECT * FROM test WHERE a IN (SELECT b FROM foo)", true},
+		{"DELETE FROM test WHERE a > 1", false},
+		{"UPDATE test SET a = 1", false},
+		{"UPDATE test UNSET a", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			q, err := parser.ParseQuery(context.Background(), test.query)
+			require.NoError(t, err)
+			require.Len(t, q.Statements, 1)
+			require.Equal(t, test.readOnly, q.Statements[0].IsReadOnly())
+		})
+	}
+}
@@ -12,6 +12,7 @@ import (
 type AlterStmt struct {
 	TableName    string
 	NewTableName string
+	IfExists     bool
 }
 
 // IsReadOnly always returns false. It implements the Statement interface.
@@ -37,5 +38,9 @@ func (stmt AlterStmt) Run(ctx context.Context, tx *database.Transaction, _ []exp
 	}
 
 	err := tx.RenameTable(stmt.TableName, stmt.NewTableName)
+	if stmt.IfExists && errors.Is(err, database.ErrTableNotFound) {
+		err = nil
+	}
+
 	return res, err
 }
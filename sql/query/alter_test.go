@@ -43,4 +43,16 @@ func TestAlterTable(t *testing.T) {
 	// Renaming a read-only table should fail
 	err = db.Exec(ctx, "ALTER TABLE __genji_tables RENAME TO bar")
 	require.Error(t, err)
+
+	// Renaming a table that doesn't exist should only fail without "IF EXISTS".
+	err = db.Exec(ctx, "ALTER TABLE foo RENAME TO baz")
+	require.EqualError(t, err, database.ErrTableNotFound.Error())
+
+	err = db.Exec(ctx, "ALTER TABLE IF EXISTS foo RENAME TO baz")
+	require.NoError(t, err)
+
+	err = db.Exec(ctx, "ALTER TABLE IF EXISTS bar RENAME TO baz")
+	require.NoError(t, err)
+	_, err = db.QueryDocument(ctx, "SELECT * FROM baz")
+	require.NoError(t, err)
 }
@@ -0,0 +1,40 @@
+package query
+
+import (
+	"context"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// AnalyzeStmt is a DSL that allows creating an ANALYZE statement.
+// It collects statistics about the values of the indexes, which are used
+// by the optimizer to choose the most selective index.
+type AnalyzeStmt struct {
+	TableOrIndexName string
+}
+
+// IsReadOnly always returns false. It implements the Statement interface.
+func (stmt AnalyzeStmt) IsReadOnly() bool {
+	return false
+}
+
+// Run runs the Analyze statement in the given transaction.
+// It implements the Statement interface.
+func (stmt AnalyzeStmt) Run(ctx context.Context, tx *database.Transaction, args []expr.Param) (Result, error) {
+	var res Result
+
+	if stmt.TableOrIndexName == "" {
+		return res, tx.AnalyzeAll()
+	}
+
+	t, err := tx.GetTable(stmt.TableOrIndexName)
+	if err == nil {
+		return res, t.Analyze()
+	}
+	if err != database.ErrTableNotFound {
+		return res, err
+	}
+
+	return res, tx.Analyze(stmt.TableOrIndexName)
+}
@@ -0,0 +1,59 @@
+package query
+
+import (
+	"context"
+	"errors"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// AttachStmt is a DSL that allows creating a full ATTACH DATABASE statement.
+type AttachStmt struct {
+	Path     string
+	Name     string
+	ReadOnly bool
+}
+
+// IsReadOnly always returns false. It implements the Statement interface.
+func (stmt AttachStmt) IsReadOnly() bool {
+	return false
+}
+
+// Run opens the database located at the given path and attaches it to the database of tx.
+// It implements the Statement interface.
+func (stmt AttachStmt) Run(ctx context.Context, tx *database.Transaction, args []expr.Param) (Result, error) {
+	var res Result
+
+	if stmt.Path == "" {
+		return res, errors.New("missing database path")
+	}
+
+	if stmt.Name == "" {
+		return res, errors.New("missing database name")
+	}
+
+	return res, tx.DB().AttachPath(stmt.Name, stmt.Path, stmt.ReadOnly)
+}
+
+// DetachStmt is a DSL that allows creating a full DETACH DATABASE statement.
+type DetachStmt struct {
+	Name string
+}
+
+// IsReadOnly always returns false. It implements the Statement interface.
+func (stmt DetachStmt) IsReadOnly() bool {
+	return false
+}
+
+// Run detaches the database attached under the given name.
+// It implements the Statement interface.
+func (stmt DetachStmt) Run(ctx context.Context, tx *database.Transaction, args []expr.Param) (Result, error) {
+	var res Result
+
+	if stmt.Name == "" {
+		return res, errors.New("missing database name")
+	}
+
+	return res, tx.DB().Detach(stmt.Name)
+}
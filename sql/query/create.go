@@ -3,6 +3,7 @@ package query
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
@@ -31,13 +32,30 @@ func (stmt CreateTableStmt) Run(ctx context.Context, tx *database.Transaction, a
 	}
 
 	err := tx.CreateTable(stmt.TableName, &stmt.Info)
-	if stmt.IfNotExists && err == database.ErrTableAlreadyExists {
+	if stmt.IfNotExists && errors.Is(err, database.ErrTableAlreadyExists) {
 		err = nil
 	}
 
 	return res, err
 }
 
+// ConstraintExpr is the expression of a field constraint,
+// like a CHECK constraint or a DEFAULT value.
+// It implements the database.Expr interface.
+type ConstraintExpr struct {
+	Expr expr.Expr
+}
+
+// Eval evaluates the expression against d.
+func (c ConstraintExpr) Eval(tx *database.Transaction, d document.Document) (document.Value, error) {
+	return c.Expr.Eval(expr.EvalStack{Tx: tx, Document: d})
+}
+
+// String returns the SQL representation of the expression.
+func (c ConstraintExpr) String() string {
+	return expr.Format(c.Expr)
+}
+
 // CreateIndexStmt is a DSL that allows creating a full CREATE INDEX statement.
 // It is typically created using the CreateIndex function.
 type CreateIndexStmt struct {
@@ -76,7 +94,87 @@ func (stmt CreateIndexStmt) Run(ctx context.Context, tx *database.Transaction, a
 		TableName: stmt.TableName,
 		Path:      stmt.Path,
 	})
-	if stmt.IfNotExists && err == database.ErrIndexAlreadyExists {
+	if stmt.IfNotExists && errors.Is(err, database.ErrIndexAlreadyExists) {
+		err = nil
+	}
+
+	return res, err
+}
+
+// CreateProcedureStmt is a DSL that allows creating a full CREATE PROCEDURE statement.
+type CreateProcedureStmt struct {
+	ProcedureName string
+	IfNotExists   bool
+	Params        []string
+	// Body is the SQL source of the statements of the procedure,
+	// parsed every time it is called.
+	Body string
+	// Statements of the body, as parsed when creating the procedure.
+	Statements []Statement
+}
+
+// IsReadOnly always returns false. It implements the Statement interface.
+func (stmt CreateProcedureStmt) IsReadOnly() bool {
+	return false
+}
+
+// Run runs the Create procedure statement in the given transaction.
+// It implements the Statement interface.
+func (stmt CreateProcedureStmt) Run(ctx context.Context, tx *database.Transaction, args []expr.Param) (Result, error) {
+	var res Result
+
+	if stmt.ProcedureName == "" {
+		return res, errors.New("missing procedure name")
+	}
+
+	err := tx.CreateProcedure(database.ProcedureConfig{
+		ProcedureName: stmt.ProcedureName,
+		Params:        stmt.Params,
+		Body:          stmt.Body,
+	})
+	if stmt.IfNotExists && errors.Is(err, database.ErrProcedureAlreadyExists) {
+		err = nil
+	}
+
+	return res, err
+}
+
+// CreateEventStmt is a DSL that allows creating a full CREATE EVENT statement.
+type CreateEventStmt struct {
+	EventName   string
+	IfNotExists bool
+	Interval    time.Duration
+	// Statement is the SQL source of the statement run by the event,
+	// parsed every time it is run.
+	Statement string
+	// Parsed is the statement, as parsed when creating the event.
+	Parsed Statement
+}
+
+// IsReadOnly always returns false. It implements the Statement interface.
+func (stmt CreateEventStmt) IsReadOnly() bool {
+	return false
+}
+
+// Run runs the Create event statement in the given transaction.
+// It implements the Statement interface.
+func (stmt CreateEventStmt) Run(ctx context.Context, tx *database.Transaction, args []expr.Param) (Result, error) {
+	var res Result
+
+	if stmt.EventName == "" {
+		return res, errors.New("missing event name")
+	}
+
+	if stmt.Interval <= 0 {
+		return res, errors.New("event interval must be positive")
+	}
+
+	err := tx.CreateEvent(database.EventConfig{
+		EventName: stmt.EventName,
+		Interval:  stmt.Interval,
+		Statement: stmt.Statement,
+	})
+	if stmt.IfNotExists && errors.Is(err, database.ErrEventAlreadyExists) {
 		err = nil
 	}
 
@@ -1,7 +1,12 @@
 package query_test
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/genjidb/genji"
@@ -120,6 +125,291 @@ func TestCreateTable(t *testing.T) {
 	})
 }
 
+func TestCheckConstraint(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "genji")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.db")
+
+	db, err := genji.Open(path)
+	require.NoError(t, err)
+
+	err = db.Exec(ctx, "CREATE TABLE test (a INTEGER CHECK (a >= 0 AND a < b), b)")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		query string
+		fails bool
+	}{
+		{"Valid", `INSERT INTO test (a, b) VALUES (1, 2)`, false},
+		{"Converted", `INSERT INTO test (a, b) VALUES (1.5, 2)`, false},
+		{"Missing field", `INSERT INTO test (b) VALUES (-1)`, false},
+		{"Null field", `INSERT INTO test (a) VALUES (NULL)`, false},
+		{"Violation", `INSERT INTO test (a, b) VALUES (-1, 2)`, true},
+		{"Violation with other field", `INSERT INTO test (a, b) VALUES (3, 2)`, true},
+		{"Update", `UPDATE test SET a = 10 WHERE a = 1`, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := db.Exec(ctx, test.query)
+			if !test.fails {
+				require.NoError(t, err)
+				return
+			}
+
+			var cerr *database.ConstraintViolationError
+			require.True(t, errors.As(err, &cerr), "%v", err)
+			require.Equal(t, database.ConstraintCheck, cerr.Constraint)
+			require.Equal(t, parsePath(t, "a"), cerr.Path)
+			require.Equal(t, database.CodeCheckViolation, cerr.Code())
+			require.EqualError(t, err, `field "a" violates check constraint a >= 0 AND a < b`)
+		})
+	}
+
+	// the constraint is read back from the catalog
+	require.NoError(t, db.Close())
+	db, err = genji.Open(path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec(ctx, `INSERT INTO test (a, b) VALUES (2, 3)`)
+	require.NoError(t, err)
+	err = db.Exec(ctx, `INSERT INTO test (a, b) VALUES (-2, 3)`)
+	require.Equal(t, database.CodeCheckViolation, database.CodeOf(err))
+}
+
+func TestDefaultValue(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "genji")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.db")
+
+	db, err := genji.Open(path)
+	require.NoError(t, err)
+
+	err = db.Exec(ctx, "CREATE TABLE test (id INTEGER PRIMARY KEY, a INTEGER DEFAULT (1 + 1.5), b NOT NULL DEFAULT 'x', c DEFAULT 'c')")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{"Missing fields", `INSERT INTO test (id) VALUES (1)`, `{"id": 1, "a": 2, "b": "x", "c": "c"}`},
+		{"Explicit values", `INSERT INTO test (id, a, b, c) VALUES (2, 5, 'y', 'z')`, `{"id": 2, "a": 5, "b": "y", "c": "z"}`},
+		{"Explicit null", `INSERT INTO test (id, a, b, c) VALUES (3, NULL, 'y', NULL)`, `{"id": 3, "a": null, "b": "y", "c": null}`},
+		{"Other fields", `INSERT INTO test (id, d) VALUES (4, {})`, `{"id": 4, "a": 2, "b": "x", "c": "c", "d": {}}`},
+		{"On conflict", `INSERT INTO test (id) VALUES (5), (5) ON CONFLICT DO NOTHING`, `{"id": 5, "a": 2, "b": "x", "c": "c"}`},
+	}
+
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := db.Exec(ctx, test.query)
+			require.NoError(t, err)
+
+			d, err := db.QueryDocument(ctx, "SELECT * FROM test WHERE id = ?", i+1)
+			require.NoError(t, err)
+			data, err := document.MarshalJSON(d)
+			require.NoError(t, err)
+			require.JSONEq(t, test.expected, string(data))
+		})
+	}
+
+	t.Run("Update", func(t *testing.T) {
+		err := db.Exec(ctx, "UPDATE test UNSET c WHERE id = 2")
+		require.NoError(t, err)
+
+		d, err := db.QueryDocument(ctx, "SELECT * FROM test WHERE id = 2")
+		require.NoError(t, err)
+		_, err = d.GetByField("c")
+		require.Equal(t, document.ErrFieldNotFound, err)
+	})
+
+	// the default values are read back from the catalog
+	require.NoError(t, db.Close())
+	db, err = genji.Open(path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec(ctx, "CREATE TABLE test2 (id INTEGER PRIMARY KEY, ts DEFAULT NOW())")
+	require.NoError(t, err)
+
+	tx, err := db.Begin(true)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	err = tx.Exec(ctx, "INSERT INTO test (id) VALUES (10); INSERT INTO test2 (id) VALUES (1)")
+	require.NoError(t, err)
+
+	d, err := tx.QueryDocument(ctx, "SELECT a, b, c FROM test WHERE id = 10")
+	require.NoError(t, err)
+	data, err := document.MarshalJSON(d)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"a": 2, "b": "x", "c": "c"}`, string(data))
+
+	// NOW() returns the time at which the transaction was started
+	d, err = tx.QueryDocument(ctx, "SELECT ts = NOW() AS ok FROM test2")
+	require.NoError(t, err)
+	v, err := d.GetByField("ok")
+	require.NoError(t, err)
+	require.Equal(t, document.NewBoolValue(true), v)
+}
+
+func TestUniqueConstraint(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec(ctx, `
+		CREATE TABLE test (id INTEGER PRIMARY KEY, a INTEGER UNIQUE, b.c UNIQUE);
+		INSERT INTO test (id, a, b) VALUES (1, 1, {c: 'x'}), (2, 2, {c: 'y'});
+	`)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		query string
+		path  string
+		value document.Value
+	}{
+		{"Insert", `INSERT INTO test (id, a, b) VALUES (3, 1, {c: 'z'})`, "a", document.NewIntegerValue(1)},
+		{"Insert converted", `INSERT INTO test (id, a, b) VALUES (3, 2.0, {c: 'z'})`, "a", document.NewIntegerValue(2)},
+		{"Insert nested", `INSERT INTO test (id, a, b) VALUES (3, 3, {c: 'x'})`, "b.c", document.NewTextValue("x")},
+		{"Update", `UPDATE test SET a = 2 WHERE id = 1`, "a", document.NewIntegerValue(2)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := db.Exec(ctx, test.query)
+
+			var cerr *database.ConstraintViolationError
+			require.True(t, errors.As(err, &cerr), "%v", err)
+			require.Equal(t, database.ConstraintUnique, cerr.Constraint)
+			require.Equal(t, parsePath(t, test.path), cerr.Path)
+			require.Equal(t, test.value, cerr.Value)
+			require.Equal(t, database.CodeUniqueViolation, cerr.Code())
+			require.True(t, errors.Is(err, database.ErrDuplicateDocument))
+		})
+	}
+
+	t.Run("Null and missing values", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, "CREATE TABLE test (id INTEGER PRIMARY KEY, a INTEGER UNIQUE, b UNIQUE)")
+		require.NoError(t, err)
+
+		// any number of documents can have a null or missing value
+		queries := []string{
+			`INSERT INTO test (id) VALUES (1), (2)`,
+			`INSERT INTO test (id, a, b) VALUES (3, NULL, NULL), (4, NULL, NULL)`,
+			`INSERT INTO test (id, a, b) VALUES (5, 5, 5)`,
+			`UPDATE test SET a = NULL, b = NULL WHERE id = 5`,
+			`UPDATE test SET a = 6, b = 6 WHERE id = 1`,
+			`UPDATE test UNSET a, b WHERE id = 1`,
+			`UPDATE test SET a = id, b = id`,
+			`UPDATE test UNSET a, b`,
+			`DELETE FROM test WHERE id > 3`,
+		}
+		for _, q := range queries {
+			err = db.Exec(ctx, q)
+			require.NoError(t, err, q)
+		}
+
+		d, err := db.QueryDocument(ctx, "SELECT COUNT(*) AS n FROM test")
+		require.NoError(t, err)
+		v, err := d.GetByField("n")
+		require.NoError(t, err)
+		require.Equal(t, document.NewIntegerValue(3), v)
+
+		// non-null values are still unique
+		err = db.Exec(ctx, `UPDATE test SET a = 1`)
+		require.Equal(t, database.CodeUniqueViolation, database.CodeOf(err))
+		err = db.Exec(ctx, `INSERT INTO test (id, b) VALUES (10, 'x'), (11, 'x')`)
+		require.Equal(t, database.CodeUniqueViolation, database.CodeOf(err))
+
+		// documents without a value are sorted like the others
+		res, err := db.Query(ctx, "SELECT id FROM test ORDER BY a")
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		err = res.WriteJSON(&buf)
+		require.NoError(t, err)
+		require.NoError(t, res.Close())
+		require.JSONEq(t, `[{"id": 1}, {"id": 2}, {"id": 3}]`, buf.String())
+
+		err = db.View(func(tx *genji.Tx) error {
+			tb, err := tx.GetTable("test")
+			require.NoError(t, err)
+			for _, name := range []string{"test_a_key", "test_b_key"} {
+				idx, err := tx.GetIndex(name)
+				require.NoError(t, err)
+				report, err := idx.Verify(tb)
+				require.NoError(t, err)
+				require.True(t, report.OK(), "%+v", report)
+			}
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("Error message", func(t *testing.T) {
+		err := db.Exec(ctx, `INSERT INTO test (id, a, b) VALUES (3, 1, {c: 'z'})`)
+		require.EqualError(t, err, `field "a" must be unique, value 1 already exists`)
+	})
+
+	t.Run("On conflict", func(t *testing.T) {
+		err := db.Exec(ctx, `INSERT INTO test (id, a, b) VALUES (3, 1, {c: 'z'}) ON CONFLICT DO NOTHING`)
+		require.NoError(t, err)
+
+		d, err := db.QueryDocument(ctx, "SELECT COUNT(*) AS n FROM test")
+		require.NoError(t, err)
+		v, err := d.GetByField("n")
+		require.NoError(t, err)
+		require.Equal(t, document.NewIntegerValue(2), v)
+	})
+
+	t.Run("Drop index", func(t *testing.T) {
+		err := db.Exec(ctx, "DROP INDEX test_a_key")
+		require.Equal(t, database.CodeDependentObjectsStillExist, database.CodeOf(err))
+	})
+
+	t.Run("Rename table", func(t *testing.T) {
+		err := db.Exec(ctx, "ALTER TABLE test RENAME TO test2")
+		require.NoError(t, err)
+
+		err = db.Exec(ctx, `INSERT INTO test2 (id, a, b) VALUES (3, 1, {c: 'z'})`)
+		require.Equal(t, database.CodeUniqueViolation, database.CodeOf(err))
+		err = db.Exec(ctx, `INSERT INTO test2 (id, a, b) VALUES (3, 3, {c: 'z'})`)
+		require.NoError(t, err)
+	})
+
+	t.Run("Drop table", func(t *testing.T) {
+		err := db.Exec(ctx, "DROP TABLE test2")
+		require.NoError(t, err)
+
+		res, err := db.Query(ctx, "SHOW INDEXES")
+		require.NoError(t, err)
+		defer res.Close()
+
+		var count int
+		err = res.Iterate(func(d document.Document) error {
+			count++
+			return nil
+		})
+		require.NoError(t, err)
+		require.Zero(t, count)
+	})
+}
+
 func TestCreateIndex(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -129,6 +419,9 @@ func TestCreateIndex(t *testing.T) {
 		{"Basic", "CREATE INDEX idx ON test (foo)", false},
 		{"If not exists", "CREATE INDEX IF NOT EXISTS idx ON test (foo.bar)", false},
 		{"Unique", "CREATE UNIQUE INDEX IF NOT EXISTS idx ON test (foo[1])", false},
+		{"If not exists, twice", "CREATE INDEX IF NOT EXISTS idx ON test (foo);CREATE INDEX IF NOT EXISTS idx ON test (foo)", false},
+		{"Twice", "CREATE INDEX idx ON test (foo);CREATE INDEX idx ON test (foo)", true},
+		{"If not exists, unknown table", "CREATE INDEX IF NOT EXISTS idx ON unknown (foo)", true},
 		{"No fields", "CREATE INDEX idx ON test", true},
 		{"More than 1 field", "CREATE INDEX idx ON test (foo, bar)", true},
 	}
@@ -29,7 +29,7 @@ func (stmt DropTableStmt) Run(ctx context.Context, tx *database.Transaction, arg
 	}
 
 	err := tx.DropTable(stmt.TableName)
-	if err == database.ErrTableNotFound && stmt.IfExists {
+	if stmt.IfExists && errors.Is(err, database.ErrTableNotFound) {
 		err = nil
 	}
 
@@ -57,7 +57,63 @@ func (stmt DropIndexStmt) Run(ctx context.Context, tx *database.Transaction, arg
 	}
 
 	err := tx.DropIndex(stmt.IndexName)
-	if err == database.ErrIndexNotFound && stmt.IfExists {
+	if stmt.IfExists && errors.Is(err, database.ErrIndexNotFound) {
+		err = nil
+	}
+
+	return res, err
+}
+
+// DropProcedureStmt is a DSL that allows creating a DROP PROCEDURE query.
+type DropProcedureStmt struct {
+	ProcedureName string
+	IfExists      bool
+}
+
+// IsReadOnly always returns false. It implements the Statement interface.
+func (stmt DropProcedureStmt) IsReadOnly() bool {
+	return false
+}
+
+// Run runs the DropProcedure statement in the given transaction.
+// It implements the Statement interface.
+func (stmt DropProcedureStmt) Run(ctx context.Context, tx *database.Transaction, args []expr.Param) (Result, error) {
+	var res Result
+
+	if stmt.ProcedureName == "" {
+		return res, errors.New("missing procedure name")
+	}
+
+	err := tx.DropProcedure(stmt.ProcedureName)
+	if stmt.IfExists && errors.Is(err, database.ErrProcedureNotFound) {
+		err = nil
+	}
+
+	return res, err
+}
+
+// DropEventStmt is a DSL that allows creating a DROP EVENT query.
+type DropEventStmt struct {
+	EventName string
+	IfExists  bool
+}
+
+// IsReadOnly always returns false. It implements the Statement interface.
+func (stmt DropEventStmt) IsReadOnly() bool {
+	return false
+}
+
+// Run runs the DropEvent statement in the given transaction.
+// It implements the Statement interface.
+func (stmt DropEventStmt) Run(ctx context.Context, tx *database.Transaction, args []expr.Param) (Result, error) {
+	var res Result
+
+	if stmt.EventName == "" {
+		return res, errors.New("missing event name")
+	}
+
+	err := tx.DropEvent(stmt.EventName)
+	if stmt.IfExists && errors.Is(err, database.ErrEventNotFound) {
 		err = nil
 	}
 
@@ -69,6 +69,14 @@ func TestDropIndex(t *testing.T) {
 	err = db.Exec(ctx, "DROP INDEX idx_test2_bar")
 	require.NoError(t, err)
 
+	err = db.Exec(ctx, "DROP INDEX IF EXISTS idx_test2_bar")
+	require.NoError(t, err)
+
+	// Dropping an index that doesn't exist without "IF EXISTS"
+	// should return an error.
+	err = db.Exec(ctx, "DROP INDEX idx_test2_bar")
+	require.Equal(t, database.ErrIndexNotFound, err)
+
 	// Assert that the good index has been dropped.
 	var indexes []*database.IndexConfig
 	err = db.View(func(tx *genji.Tx) error {
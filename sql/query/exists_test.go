@@ -0,0 +1,89 @@
+package query_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/document"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExists(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		query    string
+		fails    bool
+		expected string
+		params   []interface{}
+	}{
+		{"Exists", "SELECT name FROM authors WHERE EXISTS (SELECT * FROM books WHERE author = authors.id)", false, `[{"name":"a"},{"name":"b"}]`, nil},
+		{"Not exists", "SELECT name FROM authors WHERE NOT EXISTS (SELECT * FROM books WHERE author = authors.id)", false, `[{"name":"c"}]`, nil},
+		{"Unqualified outer field", "SELECT name FROM authors WHERE EXISTS (SELECT * FROM books WHERE author = id AND score > 8)", false, `[{"name":"a"}]`, nil},
+		{"Uncorrelated", "SELECT name FROM authors WHERE EXISTS (SELECT * FROM books WHERE score > 100)", false, `[]`, nil},
+		{"With other conditions", "SELECT name FROM authors WHERE id > 1 AND NOT EXISTS (SELECT * FROM books WHERE author = authors.id AND score < 5)", false, `[{"name":"c"}]`, nil},
+		{"Params", "SELECT name FROM authors WHERE EXISTS (SELECT * FROM books WHERE author = authors.id AND title = ?)", false, `[{"name":"b"}]`, []interface{}{"b1"}},
+		{"Projection", "SELECT name, EXISTS (SELECT * FROM books WHERE author = authors.id) AS e FROM authors", false, `[{"name":"a","e":true},{"name":"b","e":true},{"name":"c","e":false}]`, nil},
+		{"Short-circuit", "SELECT name FROM authors WHERE EXISTS (SELECT * FROM books WHERE CAST(title AS INTEGER) > 0)", false, `[{"name":"a"},{"name":"b"},{"name":"c"}]`, nil},
+		{"No short-circuit", "SELECT name FROM authors WHERE EXISTS (SELECT * FROM books WHERE CAST(title AS INTEGER) > 1)", true, ``, nil},
+		{"Unknown table", "SELECT name FROM authors WHERE EXISTS (SELECT * FROM unknown)", true, ``, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, err := genji.Open(":memory:")
+			require.NoError(t, err)
+			defer db.Close()
+
+			err = db.Exec(ctx, `
+				CREATE TABLE authors (id INTEGER PRIMARY KEY);
+				CREATE TABLE books;
+				INSERT INTO authors (id, name) VALUES (1, 'a'), (2, 'b'), (3, 'c');
+				INSERT INTO books (author, title, score) VALUES
+					(1, '1', 5), (1, 'a2', 9), (2, 'b1', 3);
+			`)
+			require.NoError(t, err)
+
+			st, err := db.Query(ctx, test.query, test.params...)
+			if test.fails {
+				if err == nil {
+					err = document.IteratorToJSONArray(new(bytes.Buffer), st)
+					st.Close()
+				}
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			defer st.Close()
+
+			var buf bytes.Buffer
+			err = document.IteratorToJSONArray(&buf, st)
+			require.NoError(t, err)
+			require.JSONEq(t, test.expected, buf.String())
+		})
+	}
+
+	t.Run("Delete", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `
+			CREATE TABLE authors (id INTEGER PRIMARY KEY);
+			CREATE TABLE books;
+			INSERT INTO authors (id) VALUES (1), (2);
+			INSERT INTO books (author) VALUES (1);
+			DELETE FROM authors WHERE NOT EXISTS (SELECT * FROM books WHERE author = authors.id);
+		`)
+		require.NoError(t, err)
+
+		d, err := db.QueryDocument(ctx, "SELECT COUNT(*) AS c FROM authors")
+		require.NoError(t, err)
+		var count int
+		require.NoError(t, document.Scan(d, &count))
+		require.Equal(t, 1, count)
+	})
+}
@@ -0,0 +1,392 @@
+package expr
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"sort"
+
+	"github.com/genjidb/genji/document"
+)
+
+// ApproxCountDistinctFunc is the APPROX_COUNT_DISTINCT aggregator function.
+// It estimates the number of distinct non-null values using a HyperLogLog sketch,
+// which uses a fixed amount of memory regardless of the number of values.
+type ApproxCountDistinctFunc struct {
+	Expr  Expr
+	Alias string
+}
+
+// Eval extracts the estimated count from the given document and returns it.
+func (a *ApproxCountDistinctFunc) Eval(ctx EvalStack) (document.Value, error) {
+	return ctx.Document.GetByField(a.String())
+}
+
+// SetAlias implements the planner.AggregatorBuilder interface.
+func (a *ApproxCountDistinctFunc) SetAlias(alias string) {
+	a.Alias = alias
+}
+
+// NewAggregator implements the planner.AggregatorBuilder interface.
+func (a *ApproxCountDistinctFunc) NewAggregator(group document.Value) document.Aggregator {
+	return &ApproxCountDistinctAggregator{
+		Fn: a,
+	}
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (a *ApproxCountDistinctFunc) IsEqual(other Expr) bool {
+	if other == nil {
+		return false
+	}
+
+	o, ok := other.(*ApproxCountDistinctFunc)
+	if !ok {
+		return false
+	}
+
+	return Equal(a.Expr, o.Expr)
+}
+
+// String returns the alias if non-zero, otherwise it returns a string representation
+// of the expression.
+func (a *ApproxCountDistinctFunc) String() string {
+	if a.Alias != "" {
+		return a.Alias
+	}
+
+	return fmt.Sprintf("APPROX_COUNT_DISTINCT(%v)", a.Expr)
+}
+
+// ApproxCountDistinctAggregator is an aggregator that estimates the number
+// of distinct non-null values.
+type ApproxCountDistinctAggregator struct {
+	Fn  *ApproxCountDistinctFunc
+	hll hyperLogLog
+}
+
+// Add hashes the value of the expression and adds it to the sketch.
+// Null values are ignored.
+func (a *ApproxCountDistinctAggregator) Add(d document.Document) error {
+	v, err := a.Fn.Expr.Eval(EvalStack{
+		Document: d,
+	})
+	if err != nil && err != document.ErrFieldNotFound {
+		return err
+	}
+	if v.Type == 0 || v.Type == document.NullValue {
+		return nil
+	}
+
+	h, err := hashValue(v)
+	if err != nil {
+		return err
+	}
+
+	a.hll.add(h)
+	return nil
+}
+
+// Aggregate adds a field to the given buffer with the estimated number of distinct values.
+func (a *ApproxCountDistinctAggregator) Aggregate(fb *document.FieldBuffer) error {
+	fb.Add(a.Fn.String(), document.NewIntegerValue(a.hll.count()))
+	return nil
+}
+
+// ApproxPercentileFunc is the APPROX_PERCENTILE aggregator function.
+// It estimates the value below which a given fraction of the numeric values fall,
+// using a t-digest sketch.
+type ApproxPercentileFunc struct {
+	Expr Expr
+	// Percentile is the expression of the fraction, between 0 and 1.
+	Percentile Expr
+	Alias      string
+
+	p float64
+}
+
+// NewApproxPercentileFunc returns an APPROX_PERCENTILE function.
+// The percentile must be a constant expression evaluating to a number between 0 and 1.
+func NewApproxPercentileFunc(e, percentile Expr) (*ApproxPercentileFunc, error) {
+	v, err := percentile.Eval(EvalStack{})
+	if err != nil {
+		return nil, err
+	}
+
+	if !v.Type.IsNumber() {
+		return nil, fmt.Errorf("percentile must be a number, got %q", v.Type)
+	}
+
+	v, err = v.CastAsDouble()
+	if err != nil {
+		return nil, err
+	}
+
+	p := v.V.(float64)
+	if p < 0 || p > 1 {
+		return nil, fmt.Errorf("percentile must be between 0 and 1, got %v", p)
+	}
+
+	return &ApproxPercentileFunc{Expr: e, Percentile: percentile, p: p}, nil
+}
+
+// Eval extracts the estimated percentile from the given document and returns it.
+func (a *ApproxPercentileFunc) Eval(ctx EvalStack) (document.Value, error) {
+	return ctx.Document.GetByField(a.String())
+}
+
+// SetAlias implements the planner.AggregatorBuilder interface.
+func (a *ApproxPercentileFunc) SetAlias(alias string) {
+	a.Alias = alias
+}
+
+// NewAggregator implements the planner.AggregatorBuilder interface.
+func (a *ApproxPercentileFunc) NewAggregator(group document.Value) document.Aggregator {
+	return &ApproxPercentileAggregator{
+		Fn:     a,
+		digest: newTDigest(defaultCompression),
+	}
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (a *ApproxPercentileFunc) IsEqual(other Expr) bool {
+	if other == nil {
+		return false
+	}
+
+	o, ok := other.(*ApproxPercentileFunc)
+	if !ok {
+		return false
+	}
+
+	return Equal(a.Expr, o.Expr) && Equal(a.Percentile, o.Percentile)
+}
+
+// String returns the alias if non-zero, otherwise it returns a string representation
+// of the expression.
+func (a *ApproxPercentileFunc) String() string {
+	if a.Alias != "" {
+		return a.Alias
+	}
+
+	return fmt.Sprintf("APPROX_PERCENTILE(%v, %v)", a.Expr, a.Percentile)
+}
+
+// ApproxPercentileAggregator is an aggregator that estimates a percentile
+// of the numeric values.
+type ApproxPercentileAggregator struct {
+	Fn     *ApproxPercentileFunc
+	digest *tDigest
+}
+
+// Add adds the value of the expression to the sketch if it is a number.
+func (a *ApproxPercentileAggregator) Add(d document.Document) error {
+	v, err := a.Fn.Expr.Eval(EvalStack{
+		Document: d,
+	})
+	if err != nil && err != document.ErrFieldNotFound {
+		return err
+	}
+
+	switch v.Type {
+	case document.IntegerValue:
+		a.digest.add(float64(v.V.(int64)))
+	case document.DoubleValue:
+		a.digest.add(v.V.(float64))
+	}
+
+	return nil
+}
+
+// Aggregate adds a field to the given buffer with the estimated percentile,
+// or null if no number was added.
+func (a *ApproxPercentileAggregator) Aggregate(fb *document.FieldBuffer) error {
+	if a.digest.count() == 0 {
+		fb.Add(a.Fn.String(), document.NewNullValue())
+		return nil
+	}
+
+	fb.Add(a.Fn.String(), document.NewDoubleValue(a.digest.quantile(a.Fn.p)))
+	return nil
+}
+
+// hashValue returns a 64 bit hash of v.
+// Equal values, like 1 and 1.0, have the same hash and are counted once.
+func hashValue(v document.Value) (uint64, error) {
+	x, err := document.Hash(v, fnv.New64a())
+	if err != nil {
+		return 0, err
+	}
+
+	// FNV doesn't distribute its bits evenly enough for HyperLogLog,
+	// which relies on the leading bits. Mix them with the finalizer of MurmurHash3.
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+
+	return x, nil
+}
+
+// hllPrecision is the number of bits of the hash used to select a register.
+// 2^14 registers give a standard error of about 0.8% using 16KB of memory.
+const hllPrecision = 14
+
+// hyperLogLog estimates the cardinality of a set of hashes.
+// Registers are allocated when the first hash is added.
+type hyperLogLog struct {
+	registers []uint8
+}
+
+func (h *hyperLogLog) add(x uint64) {
+	if h.registers == nil {
+		h.registers = make([]uint8, 1<<hllPrecision)
+	}
+
+	idx := x >> (64 - hllPrecision)
+	// the sentinel bit bounds the rank when the remaining bits are all zeros.
+	w := x<<hllPrecision | 1<<(hllPrecision-1)
+	rank := uint8(bits.LeadingZeros64(w) + 1)
+
+	if rank > h.registers[idx] {
+		h.registers[idx] = rank
+	}
+}
+
+func (h *hyperLogLog) count() int64 {
+	if h.registers == nil {
+		return 0
+	}
+
+	m := float64(len(h.registers))
+
+	var sum float64
+	var zeros int
+	for _, r := range h.registers {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	alpha := 0.7213 / (1 + 1.079/m)
+	e := alpha * m * m / sum
+
+	// use linear counting for small cardinalities,
+	// where the raw estimate is biased.
+	if e <= 2.5*m && zeros > 0 {
+		e = m * math.Log(m/float64(zeros))
+	}
+
+	return int64(math.Round(e))
+}
+
+// defaultCompression bounds the number of centroids of a t-digest
+// to a few hundreds.
+const defaultCompression = 100
+
+type centroid struct {
+	mean  float64
+	count float64
+}
+
+// tDigest is a merging t-digest. Values are buffered and periodically merged
+// into centroids, which are kept small at both ends of the distribution
+// to keep extreme percentiles accurate.
+type tDigest struct {
+	compression float64
+	centroids   []centroid
+	buffer      []centroid
+	total       float64
+	min, max    float64
+}
+
+func newTDigest(compression float64) *tDigest {
+	return &tDigest{
+		compression: compression,
+		min:         math.Inf(1),
+		max:         math.Inf(-1),
+	}
+}
+
+func (t *tDigest) add(x float64) {
+	t.buffer = append(t.buffer, centroid{mean: x, count: 1})
+	t.min = math.Min(t.min, x)
+	t.max = math.Max(t.max, x)
+
+	if len(t.buffer) >= int(5*t.compression) {
+		t.merge()
+	}
+}
+
+func (t *tDigest) count() float64 {
+	return t.total + float64(len(t.buffer))
+}
+
+func (t *tDigest) merge() {
+	if len(t.buffer) == 0 {
+		return
+	}
+
+	all := make([]centroid, 0, len(t.centroids)+len(t.buffer))
+	all = append(all, t.centroids...)
+	all = append(all, t.buffer...)
+	sort.Slice(all, func(i, j int) bool { return all[i].mean < all[j].mean })
+
+	total := t.count()
+	merged := make([]centroid, 0, len(t.centroids))
+	cur := all[0]
+	var soFar float64
+
+	for _, c := range all[1:] {
+		proposed := cur.count + c.count
+		q := (soFar + proposed/2) / total
+		if proposed <= 4*total*q*(1-q)/t.compression {
+			cur.mean += (c.mean - cur.mean) * c.count / proposed
+			cur.count = proposed
+			continue
+		}
+
+		merged = append(merged, cur)
+		soFar += cur.count
+		cur = c
+	}
+	merged = append(merged, cur)
+
+	t.centroids = merged
+	t.buffer = t.buffer[:0]
+	t.total = total
+}
+
+// quantile returns the estimated value at the given quantile, between 0 and 1.
+// Values are interpolated between the centers of the centroids.
+func (t *tDigest) quantile(q float64) float64 {
+	t.merge()
+
+	target := q * t.total
+	first, last := t.centroids[0], t.centroids[len(t.centroids)-1]
+
+	if target <= first.count/2 {
+		return t.min + (first.mean-t.min)*target/(first.count/2)
+	}
+
+	if target >= t.total-last.count/2 {
+		return last.mean + (t.max-last.mean)*(target-(t.total-last.count/2))/(last.count/2)
+	}
+
+	center := first.count / 2
+	for i := 1; i < len(t.centroids); i++ {
+		prev, c := t.centroids[i-1], t.centroids[i]
+		next := center + (prev.count+c.count)/2
+		if target <= next {
+			return prev.mean + (c.mean-prev.mean)*(target-center)/(next-center)
+		}
+		center = next
+	}
+
+	return last.mean
+}
@@ -19,6 +19,16 @@ func IsArithmeticOperator(op Operator) bool {
 	return false
 }
 
+// overflowMode returns the mode used by the arithmetic operators
+// to handle integer overflows, which is the one of the transaction, if any.
+func overflowMode(ctx EvalStack) document.OverflowMode {
+	if ctx.Tx == nil {
+		return document.OverflowPromote
+	}
+
+	return ctx.Tx.IntegerOverflowMode()
+}
+
 type addOp struct {
 	*simpleOperator
 }
@@ -34,7 +44,7 @@ func (op addOp) Eval(ctx EvalStack) (document.Value, error) {
 		return nullLitteral, err
 	}
 
-	return a.Add(b)
+	return overflowMode(ctx).Add(a, b)
 }
 
 func (op addOp) String() string {
@@ -56,7 +66,7 @@ func (op subOp) Eval(ctx EvalStack) (document.Value, error) {
 		return nullLitteral, err
 	}
 
-	return a.Sub(b)
+	return overflowMode(ctx).Sub(a, b)
 }
 
 func (op subOp) String() string {
@@ -78,7 +88,7 @@ func (op mulOp) Eval(ctx EvalStack) (document.Value, error) {
 		return nullLitteral, err
 	}
 
-	return a.Mul(b)
+	return overflowMode(ctx).Mul(a, b)
 }
 
 func (op mulOp) String() string {
@@ -100,7 +110,7 @@ func (op divOp) Eval(ctx EvalStack) (document.Value, error) {
 		return nullLitteral, err
 	}
 
-	return a.Div(b)
+	return overflowMode(ctx).Div(a, b)
 }
 
 func (op divOp) String() string {
@@ -122,7 +132,7 @@ func (op modOp) Eval(ctx EvalStack) (document.Value, error) {
 		return nullLitteral, err
 	}
 
-	return a.Mod(b)
+	return overflowMode(ctx).Mod(a, b)
 }
 
 func (op modOp) String() string {
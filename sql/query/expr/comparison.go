@@ -33,6 +33,8 @@ func Eq(a, b Expr) Expr {
 
 var errStop = errors.New("errStop")
 
+var errInExpectsArray = database.NewError(database.CodeDatatypeMismatch, "IN operator takes an array")
+
 func (op eqOp) IterateIndex(idx *database.Index, tb *database.Table, v document.Value, fn func(d document.Document) error) error {
 	err := idx.AscendGreaterOrEqual(v, func(val, key []byte, isEqual bool) error {
 		if isEqual {
@@ -73,7 +75,7 @@ func (op eqOp) IteratePK(tb *database.Table, v document.Value, pkType document.V
 
 		return err
 	}
-	return fn(tb.Tx().DB().Codec.NewDocument(val))
+	return fn(tb.Codec().NewDocument(val))
 }
 
 func (op eqOp) String() string {
@@ -147,7 +149,7 @@ func (op gtOp) IteratePK(tb *database.Table, v document.Value, pkType document.V
 			return nil
 		}
 
-		err = fn(tb.Tx().DB().Codec.NewDocument(buf))
+		err = fn(tb.Codec().NewDocument(buf))
 		if err != nil {
 			return err
 		}
@@ -207,7 +209,7 @@ func (op gteOp) IteratePK(tb *database.Table, v document.Value, pkType document.
 			return err
 		}
 
-		err = fn(tb.Tx().DB().Codec.NewDocument(buf))
+		err = fn(tb.Codec().NewDocument(buf))
 		if err != nil {
 			return err
 		}
@@ -230,22 +232,9 @@ func Lt(a, b Expr) Expr {
 }
 
 func (op ltOp) IterateIndex(idx *database.Index, tb *database.Table, v document.Value, fn func(d document.Document) error) error {
-	var err error
-
-	if v.Type == document.IntegerValue {
-		v, err = v.CastAsDouble()
-		if err != nil {
-			return err
-		}
-	}
-
-	enc, err := key.AppendValue(nil, v)
-	if err != nil {
-		return err
-	}
-	err = idx.AscendGreaterOrEqual(document.Value{Type: v.Type}, func(val, key []byte, isEqual bool) error {
-		if bytes.Compare(enc, val) <= 0 {
-			return errStop
+	return idx.Range(document.Value{}, v, false, func(val, key []byte, isMin, isMax bool) error {
+		if isMax {
+			return nil
 		}
 
 		d, err := tb.GetDocument(key)
@@ -255,12 +244,6 @@ func (op ltOp) IterateIndex(idx *database.Index, tb *database.Table, v document.
 
 		return fn(d)
 	})
-
-	if err != nil && err != errStop {
-		return err
-	}
-
-	return nil
 }
 
 func (op ltOp) IteratePK(tb *database.Table, v document.Value, pkType document.ValueType, fn func(d document.Document) error) error {
@@ -287,7 +270,7 @@ func (op ltOp) IteratePK(tb *database.Table, v document.Value, pkType document.V
 			break
 		}
 
-		err = fn(tb.Tx().DB().Codec.NewDocument(buf))
+		err = fn(tb.Codec().NewDocument(buf))
 		if err != nil {
 			return err
 		}
@@ -310,25 +293,7 @@ func Lte(a, b Expr) Expr {
 }
 
 func (op lteOp) IterateIndex(idx *database.Index, tb *database.Table, v document.Value, fn func(d document.Document) error) error {
-	var err error
-
-	if v.Type == document.IntegerValue {
-		v, err = v.CastAsDouble()
-		if err != nil {
-			return err
-		}
-	}
-
-	enc, err := key.AppendValue(nil, v)
-	if err != nil {
-		return err
-	}
-
-	err = idx.AscendGreaterOrEqual(document.Value{Type: v.Type}, func(val, key []byte, isEqual bool) error {
-		if bytes.Compare(enc, val) < 0 {
-			return errStop
-		}
-
+	return idx.Range(document.Value{}, v, false, func(val, key []byte, isMin, isMax bool) error {
 		d, err := tb.GetDocument(key)
 		if err != nil {
 			return err
@@ -336,12 +301,6 @@ func (op lteOp) IterateIndex(idx *database.Index, tb *database.Table, v document
 
 		return fn(d)
 	})
-
-	if err != nil && err != errStop {
-		return err
-	}
-
-	return nil
 }
 
 func (op lteOp) IteratePK(tb *database.Table, v document.Value, pkType document.ValueType, fn func(d document.Document) error) error {
@@ -368,7 +327,7 @@ func (op lteOp) IteratePK(tb *database.Table, v document.Value, pkType document.
 			break
 		}
 
-		err = fn(tb.Tx().DB().Codec.NewDocument(buf))
+		err = fn(tb.Codec().NewDocument(buf))
 		if err != nil {
 			return err
 		}
@@ -445,6 +404,12 @@ func IsOrOperator(e Expr) bool {
 	return ok
 }
 
+// IsIsNotOperator reports if e is the IS NOT operator.
+func IsIsNotOperator(e Expr) bool {
+	_, ok := e.(*isNotOp)
+	return ok
+}
+
 // IsInOperator reports if e is the IN operator.
 func IsInOperator(e Expr) bool {
 	_, ok := e.(inOp)
@@ -487,12 +452,25 @@ func (op inOp) Eval(ctx EvalStack) (document.Value, error) {
 
 func (op inOp) IterateIndex(idx *database.Index, tb *database.Table, v document.Value, fn func(d document.Document) error) error {
 	if v.Type != document.ArrayValue {
-		return errors.New("IN operator takes an array")
+		return errInExpectsArray
 	}
 
-	var eq eqOp
-	return v.V.(document.Array).Iterate(func(i int, value document.Value) error {
-		return eq.IterateIndex(idx, tb, value, fn)
+	var values []document.Value
+	err := v.V.(document.Array).Iterate(func(i int, value document.Value) error {
+		values = append(values, value)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return idx.SeekAll(values, func(val, key []byte) error {
+		d, err := tb.GetDocument(key)
+		if err != nil {
+			return err
+		}
+
+		return fn(d)
 	})
 }
 
@@ -500,7 +478,7 @@ func (op inOp) IterateIndex(idx *database.Index, tb *database.Table, v document.
 // iterates over it, and for each value, gets it from the underlying store of tb.
 func (op inOp) IteratePK(tb *database.Table, v document.Value, pkType document.ValueType, fn func(d document.Document) error) error {
 	if v.Type != document.ArrayValue {
-		return errors.New("IN operator takes an array")
+		return errInExpectsArray
 	}
 
 	return v.V.(document.Array).Iterate(func(i int, value document.Value) error {
@@ -522,7 +500,7 @@ func (op inOp) IteratePK(tb *database.Table, v document.Value, pkType document.V
 
 			return err
 		}
-		return fn(tb.Tx().DB().Codec.NewDocument(v))
+		return fn(tb.Codec().NewDocument(v))
 	})
 }
 
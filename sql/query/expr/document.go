@@ -0,0 +1,268 @@
+package expr
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/genjidb/genji/document"
+)
+
+// KeysFunc is the KEYS function. It returns the names of the fields
+// of a document as an array, in the order of the document.
+type KeysFunc struct {
+	Expr Expr
+}
+
+// Eval returns the field names of the document returned by the expression,
+// or NULL if it is not a document.
+func (k KeysFunc) Eval(ctx EvalStack) (document.Value, error) {
+	d, ok, err := evalDocument(k.Expr, ctx)
+	if err != nil || !ok {
+		return nullLitteral, err
+	}
+
+	var vb document.ValueBuffer
+	err = d.Iterate(func(field string, v document.Value) error {
+		vb = vb.Append(document.NewTextValue(field))
+		return nil
+	})
+	if err != nil {
+		return nullLitteral, err
+	}
+
+	return document.NewArrayValue(vb), nil
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (k KeysFunc) IsEqual(other Expr) bool {
+	o, ok := other.(KeysFunc)
+	return ok && Equal(k.Expr, o.Expr)
+}
+
+func (k KeysFunc) String() string {
+	return fmt.Sprintf("KEYS(%v)", k.Expr)
+}
+
+// ValuesFunc is the VALUES function. It returns the values of the fields
+// of a document as an array, in the order of the document.
+type ValuesFunc struct {
+	Expr Expr
+}
+
+// Eval returns the values of the document returned by the expression,
+// or NULL if it is not a document.
+func (v ValuesFunc) Eval(ctx EvalStack) (document.Value, error) {
+	d, ok, err := evalDocument(v.Expr, ctx)
+	if err != nil || !ok {
+		return nullLitteral, err
+	}
+
+	var vb document.ValueBuffer
+	err = d.Iterate(func(field string, v document.Value) error {
+		vb = vb.Append(v)
+		return nil
+	})
+	if err != nil {
+		return nullLitteral, err
+	}
+
+	return document.NewArrayValue(vb), nil
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (v ValuesFunc) IsEqual(other Expr) bool {
+	o, ok := other.(ValuesFunc)
+	return ok && Equal(v.Expr, o.Expr)
+}
+
+func (v ValuesFunc) String() string {
+	return fmt.Sprintf("VALUES(%v)", v.Expr)
+}
+
+// FlattenFunc is the FLATTEN function. It returns a copy of a document
+// where the fields of the nested documents are moved to the top level,
+// named after the path of the field in dot notation:
+// {"a": {"b": 1}} becomes {"a.b": 1}. Arrays and empty documents are kept as is.
+type FlattenFunc struct {
+	Expr Expr
+}
+
+// Eval returns the flattened document returned by the expression,
+// or NULL if it is not a document.
+func (f FlattenFunc) Eval(ctx EvalStack) (document.Value, error) {
+	d, ok, err := evalDocument(f.Expr, ctx)
+	if err != nil || !ok {
+		return nullLitteral, err
+	}
+
+	fb := document.NewFieldBuffer()
+	err = flatten(fb, "", d)
+	if err != nil {
+		return nullLitteral, err
+	}
+
+	return document.NewDocumentValue(fb), nil
+}
+
+func flatten(fb *document.FieldBuffer, prefix string, d document.Document) error {
+	return d.Iterate(func(field string, v document.Value) error {
+		name := prefix + field
+
+		if v.Type == document.DocumentValue {
+			sub := v.V.(document.Document)
+			n, err := document.Length(sub)
+			if err != nil {
+				return err
+			}
+			if n > 0 {
+				return flatten(fb, name+".", sub)
+			}
+		}
+
+		fb.Add(name, v)
+		return nil
+	})
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (f FlattenFunc) IsEqual(other Expr) bool {
+	o, ok := other.(FlattenFunc)
+	return ok && Equal(f.Expr, o.Expr)
+}
+
+func (f FlattenFunc) String() string {
+	return fmt.Sprintf("FLATTEN(%v)", f.Expr)
+}
+
+// Wildcard is the * argument of the KEYS, VALUES and FLATTEN functions.
+// It evaluates to the current document.
+type Wildcard struct{}
+
+// Eval returns the current document.
+func (w Wildcard) Eval(ctx EvalStack) (document.Value, error) {
+	if ctx.Document == nil {
+		return nullLitteral, errors.New("no table specified")
+	}
+
+	return document.NewDocumentValue(ctx.Document), nil
+}
+
+func (w Wildcard) String() string {
+	return "*"
+}
+
+// evalDocument evaluates e and returns the document it returns, if any.
+func evalDocument(e Expr, ctx EvalStack) (document.Document, bool, error) {
+	v, err := e.Eval(ctx)
+	if err != nil || v.Type != document.DocumentValue {
+		return nil, false, err
+	}
+
+	return v.V.(document.Document), true, nil
+}
+
+// PivotFunc is the PIVOT aggregator function. It turns the key/value pairs
+// of a group into a single document with one field per distinct key:
+// PIVOT(attr, val) returns {"color": "red", "size": 10} for a group
+// containing the documents {"attr": "color", "val": "red"} and {"attr": "size", "val": 10}.
+type PivotFunc struct {
+	Key   Expr
+	Value Expr
+	Alias string
+}
+
+// Eval extracts the pivoted document from the given document and returns it.
+func (p *PivotFunc) Eval(ctx EvalStack) (document.Value, error) {
+	return ctx.Document.GetByField(p.String())
+}
+
+// SetAlias implements the planner.AggregatorBuilder interface.
+func (p *PivotFunc) SetAlias(alias string) {
+	p.Alias = alias
+}
+
+// NewAggregator implements the planner.AggregatorBuilder interface.
+func (p *PivotFunc) NewAggregator(group document.Value) document.Aggregator {
+	return &PivotAggregator{
+		Fn:     p,
+		Fields: document.NewFieldBuffer(),
+	}
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (p *PivotFunc) IsEqual(other Expr) bool {
+	if other == nil {
+		return false
+	}
+
+	o, ok := other.(*PivotFunc)
+	if !ok {
+		return false
+	}
+
+	return Equal(p.Key, o.Key) && Equal(p.Value, o.Value)
+}
+
+// String returns the alias if non-zero, otherwise it returns a string representation
+// of the expression.
+func (p *PivotFunc) String() string {
+	if p.Alias != "" {
+		return p.Alias
+	}
+
+	return fmt.Sprintf("PIVOT(%v, %v)", p.Key, p.Value)
+}
+
+// PivotAggregator is an aggregator that builds a document from
+// the key/value pairs of a group.
+type PivotAggregator struct {
+	Fn     *PivotFunc
+	Fields *document.FieldBuffer
+}
+
+// Add evaluates the key and the value expressions and sets the corresponding field.
+// Keys that are not text are converted to text and null keys are ignored.
+// If a key appears more than once in the group, the last value is kept.
+func (p *PivotAggregator) Add(d document.Document) error {
+	k, err := p.Fn.Key.Eval(EvalStack{
+		Document: d,
+	})
+	if err != nil && err != document.ErrFieldNotFound {
+		return err
+	}
+	if k.Type == 0 || k.Type == document.NullValue {
+		return nil
+	}
+
+	k, err = k.CastAsText()
+	if err != nil {
+		return err
+	}
+
+	v, err := p.Fn.Value.Eval(EvalStack{
+		Document: d,
+	})
+	if err != nil && err != document.ErrFieldNotFound {
+		return err
+	}
+	if v.Type == 0 {
+		v = nullLitteral
+	}
+
+	field := k.V.(string)
+	if p.Fields.Replace(field, v) == document.ErrFieldNotFound {
+		p.Fields.Add(field, v)
+	}
+
+	return nil
+}
+
+// Aggregate adds a field to the given buffer with the pivoted document.
+func (p *PivotAggregator) Aggregate(fb *document.FieldBuffer) error {
+	fb.Add(p.Fn.String(), document.NewDocumentValue(p.Fields))
+	return nil
+}
@@ -0,0 +1,249 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/scanner"
+)
+
+// Format returns the canonical SQL representation of e.
+// Unlike String, the result can always be parsed back to an equivalent expression:
+// identifiers are quoted when necessary, strings are single quoted and doubles
+// always have a decimal point.
+// Blobs have no literal representation and are formatted using their String method.
+func Format(e Expr) string {
+	var b strings.Builder
+	writeExpr(&b, e)
+	return b.String()
+}
+
+// FormatTableName returns the SQL representation of a table name.
+// Names qualified by an attached database, like "other.users",
+// are formatted as two identifiers separated by a dot.
+func FormatTableName(name string) string {
+	if i := strings.IndexByte(name, '.'); i > 0 && i < len(name)-1 {
+		return FormatIdent(name[:i]) + "." + FormatIdent(name[i+1:])
+	}
+
+	return FormatIdent(name)
+}
+
+// FormatIdent returns the SQL representation of an identifier,
+// quoting it with backquotes if it is not a valid bare identifier or if it is a keyword.
+func FormatIdent(name string) string {
+	if isBareIdent(name) && scanner.Lookup(name) == scanner.IDENT {
+		return name
+	}
+
+	var b strings.Builder
+
+	b.WriteByte('`')
+	for _, r := range name {
+		switch r {
+		case '`', '\\':
+			b.WriteRune('\\')
+		case '\n':
+			b.WriteString("\\n")
+			continue
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('`')
+
+	return b.String()
+}
+
+// FormatPath returns the SQL representation of a path, quoting
+// field names only when necessary.
+func FormatPath(p document.ValuePath) string {
+	var b strings.Builder
+
+	for i, f := range p {
+		switch {
+		case f.Wildcard && f.IsArrayIndex():
+			b.WriteString("[*]")
+		case f.IsArrayIndex():
+			b.WriteString("[" + strconv.Itoa(f.ArrayIndex) + "]")
+		default:
+			if i != 0 {
+				b.WriteByte('.')
+			}
+			if f.Wildcard {
+				b.WriteString(f.FieldName)
+			} else {
+				b.WriteString(FormatIdent(f.FieldName))
+			}
+		}
+	}
+
+	return b.String()
+}
+
+func isBareIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for i, r := range s {
+		isLetter := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r == '_'
+		if !isLetter && (i == 0 || r < '0' || r > '9') {
+			return false
+		}
+	}
+
+	return true
+}
+
+func writeExpr(b *strings.Builder, e Expr) {
+	switch t := e.(type) {
+	case LiteralValue:
+		writeValue(b, document.Value(t))
+	case FieldSelector:
+		b.WriteString(FormatPath(document.ValuePath(t)))
+	case Parentheses:
+		b.WriteByte('(')
+		writeExpr(b, t.E)
+		b.WriteByte(')')
+	case LiteralExprList:
+		b.WriteByte('[')
+		for i, e := range t {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			writeExpr(b, e)
+		}
+		b.WriteByte(']')
+	case KVPairs:
+		b.WriteByte('{')
+		for i, p := range t {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(FormatIdent(p.K))
+			b.WriteString(": ")
+			writeExpr(b, p.V)
+		}
+		b.WriteByte('}')
+	case NamedParam:
+		b.WriteString("$" + string(t))
+	case PositionalParam:
+		b.WriteByte('?')
+	case PKFunc:
+		b.WriteString("pk()")
+	case Wildcard:
+		b.WriteByte('*')
+	case CastFunc:
+		b.WriteString("CAST(")
+		writeExpr(b, t.Expr)
+		b.WriteString(" AS ")
+		b.WriteString(strings.ToUpper(t.CastAs.String()))
+		b.WriteByte(')')
+	case KeysFunc:
+		writeFunc(b, "KEYS", t.Expr)
+	case ValuesFunc:
+		writeFunc(b, "VALUES", t.Expr)
+	case FlattenFunc:
+		writeFunc(b, "FLATTEN", t.Expr)
+	case *CountFunc:
+		if t.Wildcard {
+			b.WriteString("COUNT(*)")
+			return
+		}
+		writeFunc(b, "COUNT", t.Expr)
+	case *MinFunc:
+		writeFunc(b, "MIN", t.Expr)
+	case *MaxFunc:
+		writeFunc(b, "MAX", t.Expr)
+	case *SumFunc:
+		writeFunc(b, "SUM", t.Expr)
+	case *ApproxCountDistinctFunc:
+		writeFunc(b, "APPROX_COUNT_DISTINCT", t.Expr)
+	case *ApproxPercentileFunc:
+		writeFunc(b, "APPROX_PERCENTILE", t.Expr, t.Percentile)
+	case *PivotFunc:
+		writeFunc(b, "PIVOT", t.Key, t.Value)
+	case *FilterFunc:
+		writeExpr(b, t.Aggregate)
+		b.WriteString(" FILTER (WHERE ")
+		writeExpr(b, t.Cond)
+		b.WriteByte(')')
+	case Operator:
+		writeExpr(b, t.LeftHand())
+		b.WriteByte(' ')
+		b.WriteString(operatorKeyword(t))
+		b.WriteByte(' ')
+		writeExpr(b, t.RightHand())
+	default:
+		fmt.Fprintf(b, "%v", e)
+	}
+}
+
+func writeFunc(b *strings.Builder, name string, args ...Expr) {
+	b.WriteString(name)
+	b.WriteByte('(')
+	for i, arg := range args {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		writeExpr(b, arg)
+	}
+	b.WriteByte(')')
+}
+
+// operatorKeyword returns the SQL keyword of op.
+// IS, IS NOT and NOT IN don't have a token of their own.
+func operatorKeyword(op Operator) string {
+	switch op.(type) {
+	case *isOp:
+		return "IS"
+	case *isNotOp:
+		return "IS NOT"
+	case *notInOp:
+		return "NOT IN"
+	}
+
+	return op.Token().String()
+}
+
+func writeValue(b *strings.Builder, v document.Value) {
+	switch v.Type {
+	case document.NullValue:
+		b.WriteString("NULL")
+	case document.BoolValue:
+		if v.V.(bool) {
+			b.WriteString("true")
+		} else {
+			b.WriteString("false")
+		}
+	case document.IntegerValue:
+		b.WriteString(strconv.FormatInt(v.V.(int64), 10))
+	case document.DoubleValue:
+		s := strconv.FormatFloat(v.V.(float64), 'f', -1, 64)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+		b.WriteString(s)
+	case document.TextValue:
+		writeString(b, v.V.(string))
+	default:
+		b.WriteString(v.String())
+	}
+}
+
+func writeString(b *strings.Builder, s string) {
+	b.WriteByte('\'')
+	for _, r := range s {
+		switch r {
+		case '\'', '\\':
+			b.WriteRune('\\')
+		case '\n':
+			b.WriteString("\\n")
+			continue
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('\'')
+}
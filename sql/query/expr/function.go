@@ -1,52 +1,107 @@
 package expr
 
 import (
-	"encoding/binary"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
 )
 
 var functions = map[string]func(args ...Expr) (Expr, error){
 	"pk": func(args ...Expr) (Expr, error) {
 		if len(args) != 0 {
-			return nil, fmt.Errorf("pk() takes no arguments")
+			return nil, database.NewError(database.CodeUndefinedFunction, "pk() takes no arguments")
 		}
 		return new(PKFunc), nil
 	},
+	"now": func(args ...Expr) (Expr, error) {
+		if len(args) != 0 {
+			return nil, database.NewError(database.CodeUndefinedFunction, "NOW() takes no arguments")
+		}
+		return new(NowFunc), nil
+	},
+	"keys": func(args ...Expr) (Expr, error) {
+		if len(args) != 1 {
+			return nil, database.NewError(database.CodeUndefinedFunction, "KEYS() takes 1 argument")
+		}
+		return KeysFunc{Expr: args[0]}, nil
+	},
+	"values": func(args ...Expr) (Expr, error) {
+		if len(args) != 1 {
+			return nil, database.NewError(database.CodeUndefinedFunction, "VALUES() takes 1 argument")
+		}
+		return ValuesFunc{Expr: args[0]}, nil
+	},
+	"flatten": func(args ...Expr) (Expr, error) {
+		if len(args) != 1 {
+			return nil, database.NewError(database.CodeUndefinedFunction, "FLATTEN() takes 1 argument")
+		}
+		return FlattenFunc{Expr: args[0]}, nil
+	},
 	"count": func(args ...Expr) (Expr, error) {
 		if len(args) != 1 {
-			return nil, fmt.Errorf("COUNT() takes 1 argument")
+			return nil, database.NewError(database.CodeUndefinedFunction, "COUNT() takes 1 argument")
 		}
 		return &CountFunc{Expr: args[0]}, nil
 	},
 	"min": func(args ...Expr) (Expr, error) {
 		if len(args) != 1 {
-			return nil, fmt.Errorf("MIN() takes 1 argument")
+			return nil, database.NewError(database.CodeUndefinedFunction, "MIN() takes 1 argument")
 		}
 		return &MinFunc{Expr: args[0]}, nil
 	},
 	"max": func(args ...Expr) (Expr, error) {
 		if len(args) != 1 {
-			return nil, fmt.Errorf("MAX() takes 1 argument")
+			return nil, database.NewError(database.CodeUndefinedFunction, "MAX() takes 1 argument")
 		}
 		return &MaxFunc{Expr: args[0]}, nil
 	},
 	"sum": func(args ...Expr) (Expr, error) {
 		if len(args) != 1 {
-			return nil, fmt.Errorf("SUM() takes 1 argument")
+			return nil, database.NewError(database.CodeUndefinedFunction, "SUM() takes 1 argument")
 		}
 		return &SumFunc{Expr: args[0]}, nil
 	},
+	"approx_count_distinct": func(args ...Expr) (Expr, error) {
+		if len(args) != 1 {
+			return nil, database.NewError(database.CodeUndefinedFunction, "APPROX_COUNT_DISTINCT() takes 1 argument")
+		}
+		return &ApproxCountDistinctFunc{Expr: args[0]}, nil
+	},
+	"approx_percentile": func(args ...Expr) (Expr, error) {
+		if len(args) != 2 {
+			return nil, database.NewError(database.CodeUndefinedFunction, "APPROX_PERCENTILE() takes 2 arguments")
+		}
+		return NewApproxPercentileFunc(args[0], args[1])
+	},
+	"pivot": func(args ...Expr) (Expr, error) {
+		if len(args) != 2 {
+			return nil, database.NewError(database.CodeUndefinedFunction, "PIVOT() takes 2 arguments")
+		}
+		return &PivotFunc{Key: args[0], Value: args[1]}, nil
+	},
+}
+
+// Functions returns the names of all the functions, sorted alphabetically.
+func Functions() []string {
+	names := make([]string, 0, len(functions))
+	for name := range functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
 }
 
 // GetFunc return a function expression by name.
 func GetFunc(name string, args ...Expr) (Expr, error) {
 	fn, ok := functions[strings.ToLower(name)]
 	if !ok {
-		return nil, fmt.Errorf("no such function: %q", name)
+		return nil, database.NewError(database.CodeUndefinedFunction, fmt.Sprintf("no such function: %q", name))
 	}
 
 	return fn(args...)
@@ -67,9 +122,11 @@ func (k PKFunc) Eval(ctx EvalStack) (document.Value, error) {
 		return pk.Path.GetValue(ctx.Document)
 	}
 
-	i, _ := binary.Uvarint(ctx.Document.(document.Keyer).Key())
+	if ctx.Tx == nil {
+		return document.Value{}, errors.New("no transaction specified")
+	}
 
-	return document.NewIntegerValue(int64(i)), nil
+	return ctx.Tx.DB().KeyGenerator.KeyToValue(ctx.Document.(document.Keyer).Key())
 }
 
 // IsEqual compares this expression with the other expression and returns
@@ -83,6 +140,31 @@ func (k PKFunc) String() string {
 	return "pk()"
 }
 
+// NowFunc represents the NOW() function.
+// It returns the time at which the current transaction was started,
+// according to the clock of the database, as an RFC 3339 text in UTC.
+type NowFunc struct{}
+
+// Eval returns the time at which the current transaction was started.
+func (n NowFunc) Eval(ctx EvalStack) (document.Value, error) {
+	if ctx.Tx == nil {
+		return document.Value{}, errors.New("no transaction specified")
+	}
+
+	return document.NewTextValue(ctx.Tx.StartedAt().UTC().Format(time.RFC3339Nano)), nil
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (n NowFunc) IsEqual(other Expr) bool {
+	_, ok := other.(NowFunc)
+	return ok
+}
+
+func (n NowFunc) String() string {
+	return "NOW()"
+}
+
 // CastFunc represents the CAST expression.
 type CastFunc struct {
 	Expr   Expr
@@ -297,8 +379,15 @@ func (m *MinAggregator) Add(d document.Document) error {
 	return nil
 }
 
-// Aggregate adds a field to the given buffer with the minimum value.
+// Aggregate adds a field to the given buffer with the minimum value,
+// or NULL if there was no non-null value.
 func (m *MinAggregator) Aggregate(fb *document.FieldBuffer) error {
+	// the group may not contain any non-null value
+	if m.Min.Type == 0 {
+		fb.Add(m.Fn.String(), document.NewNullValue())
+		return nil
+	}
+
 	fb.Add(m.Fn.String(), m.Min)
 	return nil
 }
@@ -394,8 +483,15 @@ func (m *MaxAggregator) Add(d document.Document) error {
 	return nil
 }
 
-// Aggregate adds a field to the given buffer with the maximum value.
+// Aggregate adds a field to the given buffer with the maximum value,
+// or NULL if there was no non-null value.
 func (m *MaxAggregator) Aggregate(fb *document.FieldBuffer) error {
+	// the group may not contain any non-null value
+	if m.Max.Type == 0 {
+		fb.Add(m.Fn.String(), document.NewNullValue())
+		return nil
+	}
+
 	fb.Add(m.Fn.String(), m.Max)
 	return nil
 }
@@ -511,3 +607,100 @@ func (s *SumAggregator) Aggregate(fb *document.FieldBuffer) error {
 
 	return nil
 }
+
+// An AggregateFunc is a function aggregating the documents of each group,
+// like COUNT or MIN.
+type AggregateFunc interface {
+	Expr
+	document.AggregatorBuilder
+	SetAlias(alias string)
+}
+
+// FilterFunc represents an aggregate function followed by a FILTER clause.
+// Only the documents satisfying the condition are aggregated:
+// COUNT(a) FILTER (WHERE b > 10) counts the non-null values of a
+// of the documents whose b is greater than 10.
+type FilterFunc struct {
+	Aggregate AggregateFunc
+	Cond      Expr
+	Alias     string
+}
+
+// Eval extracts the result of the aggregate function from the given document and returns it.
+func (f *FilterFunc) Eval(ctx EvalStack) (document.Value, error) {
+	return f.Aggregate.Eval(ctx)
+}
+
+// SetAlias implements the planner.AggregatorBuilder interface.
+// The alias is also the one of the aggregate function, which names
+// the field of its result.
+func (f *FilterFunc) SetAlias(alias string) {
+	f.Alias = alias
+	f.Aggregate.SetAlias(alias)
+}
+
+// NewAggregator implements the planner.AggregatorBuilder interface.
+func (f *FilterFunc) NewAggregator(group document.Value) document.Aggregator {
+	return &FilterAggregator{
+		Fn:         f,
+		Aggregator: f.Aggregate.NewAggregator(group),
+	}
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (f *FilterFunc) IsEqual(other Expr) bool {
+	if other == nil {
+		return false
+	}
+
+	o, ok := other.(*FilterFunc)
+	if !ok {
+		return false
+	}
+
+	return Equal(f.Aggregate, o.Aggregate) && Equal(f.Cond, o.Cond)
+}
+
+// String returns the alias if non-zero, otherwise it returns a string representation
+// of the expression.
+func (f *FilterFunc) String() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+
+	return fmt.Sprintf("%v FILTER (WHERE %v)", f.Aggregate, f.Cond)
+}
+
+// FilterAggregator is an aggregator that only passes the documents
+// satisfying the condition of a FILTER clause to the aggregator of the function.
+type FilterAggregator struct {
+	Fn         *FilterFunc
+	Aggregator document.Aggregator
+}
+
+// Add passes d to the aggregator of the function if the condition is truthy.
+// Documents for which the condition is null are ignored.
+func (f *FilterAggregator) Add(d document.Document) error {
+	v, err := f.Fn.Cond.Eval(EvalStack{
+		Document: d,
+	})
+	if err == document.ErrFieldNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	ok, err := v.IsTruthy()
+	if err != nil || !ok {
+		return err
+	}
+
+	return f.Aggregator.Add(d)
+}
+
+// Aggregate adds the result of the aggregator of the function to the given buffer.
+func (f *FilterAggregator) Aggregate(fb *document.FieldBuffer) error {
+	return f.Aggregator.Aggregate(fb)
+}
@@ -1,10 +1,13 @@
 package expr_test
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/parser"
 	"github.com/genjidb/genji/sql/query/expr"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPkExpr(t *testing.T) {
@@ -25,3 +28,42 @@ func TestPkExpr(t *testing.T) {
 		})
 	}
 }
+
+func TestDocumentFuncs(t *testing.T) {
+	tests := []struct {
+		expr  string
+		res   string
+		fails bool
+	}{
+		{"KEYS(b)", `["foo bar"]`, false},
+		{`KEYS({a: 1, c: {d: 2}, b: 3})`, `["a", "c", "b"]`, false},
+		{"KEYS({})", `[]`, false},
+		{"KEYS(a)", `NULL`, false},
+		{"KEYS(d)", `NULL`, false},
+		{"VALUES(b)", `[[1, 2]]`, false},
+		{`VALUES({a: 1, c: {d: 2}, b: 'x'})`, `[1, {"d": 2}, "x"]`, false},
+		{"VALUES(c)", `NULL`, false},
+		{`FLATTEN({a: 1, b: {c: {d: 2, e: [1, {f: 3}]}, g: {}}})`, `{"a": 1, "b.c.d": 2, "b.c.e": [1, {"f": 3}], "b.g": {}}`, false},
+		{"FLATTEN(a)", `NULL`, false},
+		{"KEYS(*)", `["a", "b", "c"]`, false},
+		{"VALUES(*)", `[1, {"foo bar": [1, 2]}, [1, {"foo": "bar"}, [1, 2]]]`, false},
+		{"FLATTEN(*)", `{"a": 1, "b.foo bar": [1, 2], "c": [1, {"foo": "bar"}, [1, 2]]}`, false},
+		{"KEYS()", ``, true},
+		{"VALUES(a, b)", ``, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			e, _, err := parser.NewParser(strings.NewReader(test.expr)).ParseExpr()
+			if test.fails {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			v, err := e.Eval(stackWithDoc)
+			require.NoError(t, err)
+			require.Equal(t, test.res, v.String())
+		})
+	}
+}
@@ -3,6 +3,7 @@ package expr
 import (
 	"fmt"
 
+	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
 )
 
@@ -36,7 +37,7 @@ func (p NamedParam) extract(params []Param) (interface{}, error) {
 		}
 	}
 
-	return nil, fmt.Errorf("param %s not found", p)
+	return nil, database.NewError(database.CodeUndefinedParameter, fmt.Sprintf("param %s not found", p))
 }
 
 // IsEqual compares this expression with the other expression and returns
@@ -80,7 +81,7 @@ func (p PositionalParam) String() string {
 func (p PositionalParam) extract(params []Param) (interface{}, error) {
 	idx := int(p - 1)
 	if idx >= len(params) {
-		return nil, fmt.Errorf("cannot find param number %d", p)
+		return nil, database.NewError(database.CodeUndefinedParameter, fmt.Sprintf("cannot find param number %d", p))
 	}
 
 	return params[idx].Value, nil
@@ -0,0 +1,144 @@
+package expr
+
+import (
+	"errors"
+
+	"github.com/genjidb/genji/document"
+)
+
+// A Subquery is a query evaluated by an expression.
+type Subquery interface {
+	// Iterate runs the subquery and calls fn for every document it returns.
+	// The subquery is run within the transaction of the stack, using its parameters,
+	// and can read the fields of the document of the stack.
+	// If fn returns document.ErrStreamClosed, the subquery is interrupted.
+	Iterate(stack EvalStack, fn func(d document.Document) error) error
+	// SQL returns the SQL representation of the subquery.
+	SQL() string
+}
+
+// Exists is an expression that evaluates to true if the subquery returns at least one document,
+// or to false otherwise. If Not is true, the result is inverted.
+// The subquery is interrupted as soon as it returns its first document.
+type Exists struct {
+	Subquery Subquery
+	Not      bool
+}
+
+// Eval runs the subquery until it returns a document.
+func (e Exists) Eval(stack EvalStack) (document.Value, error) {
+	if stack.Tx == nil {
+		return nullLitteral, errors.New("EXISTS must be evaluated within a transaction")
+	}
+
+	var found bool
+	err := e.Subquery.Iterate(stack, func(d document.Document) error {
+		found = true
+		return document.ErrStreamClosed
+	})
+	if err != nil && err != document.ErrStreamClosed {
+		return nullLitteral, err
+	}
+
+	if found != e.Not {
+		return trueLitteral, nil
+	}
+	return falseLitteral, nil
+}
+
+// String returns the SQL representation of the expression.
+func (e Exists) String() string {
+	if e.Not {
+		return "NOT EXISTS (" + e.Subquery.SQL() + ")"
+	}
+
+	return "EXISTS (" + e.Subquery.SQL() + ")"
+}
+
+// ScalarSubquery is an expression that evaluates to the value of the single field
+// of the document returned by the subquery, or to null if it returns no document.
+// It returns an error if the subquery returns more than one document.
+type ScalarSubquery struct {
+	Subquery Subquery
+}
+
+// Eval runs the subquery and returns the value of its document.
+func (s ScalarSubquery) Eval(stack EvalStack) (document.Value, error) {
+	if stack.Tx == nil {
+		return nullLitteral, errors.New("subqueries must be evaluated within a transaction")
+	}
+
+	v := nullLitteral
+	var found bool
+	err := s.Subquery.Iterate(stack, func(d document.Document) error {
+		if found {
+			return errors.New("subquery returned more than one document")
+		}
+		found = true
+
+		var err error
+		v, err = subqueryValue(d)
+		return err
+	})
+	if err != nil {
+		return nullLitteral, err
+	}
+
+	return v, nil
+}
+
+// String returns the SQL representation of the expression.
+func (s ScalarSubquery) String() string {
+	return "(" + s.Subquery.SQL() + ")"
+}
+
+// ArraySubquery is an expression that evaluates to an array containing the value
+// of the single field of every document returned by the subquery.
+// It is the right-hand side of IN and NOT IN operators followed by a subquery.
+type ArraySubquery struct {
+	Subquery Subquery
+}
+
+// Eval runs the subquery and returns the values of its documents.
+func (s ArraySubquery) Eval(stack EvalStack) (document.Value, error) {
+	if stack.Tx == nil {
+		return nullLitteral, errors.New("subqueries must be evaluated within a transaction")
+	}
+
+	vb := document.NewValueBuffer()
+	err := s.Subquery.Iterate(stack, func(d document.Document) error {
+		v, err := subqueryValue(d)
+		if err != nil {
+			return err
+		}
+
+		vb = vb.Append(v)
+		return nil
+	})
+	if err != nil {
+		return nullLitteral, err
+	}
+
+	return document.NewArrayValue(vb), nil
+}
+
+// String returns the SQL representation of the expression.
+func (s ArraySubquery) String() string {
+	return "(" + s.Subquery.SQL() + ")"
+}
+
+// subqueryValue returns a copy of the value of the single field of d.
+func subqueryValue(d document.Document) (document.Value, error) {
+	var fb document.FieldBuffer
+	err := fb.Copy(d)
+	if err != nil {
+		return nullLitteral, err
+	}
+
+	fields := fb.Fields()
+	if len(fields) != 1 {
+		return nullLitteral, errors.New("subquery must return a single field")
+	}
+
+	return fb.GetByField(fields[0])
+}
@@ -15,8 +15,34 @@ type InsertStmt struct {
 	TableName  string
 	FieldNames []string
 	Values     expr.LiteralExprList
+	OnConflict OnConflictAction
+	// Returning is the RETURNING clause of the statement, if any.
+	// The stream of the result then contains the inserted documents, projected by it.
+	Returning Projector
 }
 
+// A Projector computes the documents returned by the RETURNING clause
+// of a statement from the documents written by the statement to a table.
+type Projector interface {
+	Project(tx *database.Transaction, tableName string, args []expr.Param, st document.Stream) (document.Stream, error)
+}
+
+// OnConflictAction defines what an INSERT statement does with documents
+// conflicting with existing ones, because they have the same primary key
+// or the same value for a unique index.
+type OnConflictAction int
+
+const (
+	// OnConflictAbort fails the statement with an error wrapping database.ErrDuplicateDocument.
+	OnConflictAbort OnConflictAction = iota
+	// OnConflictDoNothing skips the conflicting documents. They are counted in
+	// Result.RowsSkipped and the keys of the existing documents are returned in Result.ConflictKeys.
+	OnConflictDoNothing
+	// OnConflictDoReplace deletes the existing documents conflicting with a document
+	// before inserting it. The keys of the deleted documents are returned in Result.ConflictKeys.
+	OnConflictDoReplace
+)
+
 // IsReadOnly always returns false. It implements the Statement interface.
 func (stmt InsertStmt) IsReadOnly() bool {
 	return false
@@ -46,10 +72,54 @@ func (stmt InsertStmt) Run(ctx context.Context, tx *database.Transaction, args [
 	}
 
 	if len(stmt.FieldNames) > 0 {
-		return stmt.insertExprList(t, stack)
+		res, err = stmt.insertExprList(t, stack)
+	} else {
+		res, err = stmt.insertDocuments(t, stack)
+	}
+	if err != nil {
+		return res, err
+	}
+
+	if stmt.Returning != nil {
+		res.Stream, err = stmt.returning(tx, t, args, res.InsertKeys)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	if res.LastInsertKey == nil {
+		return res, nil
+	}
+
+	res.LastInsertPK, err = t.KeyToValue(res.LastInsertKey)
+	return res, err
+}
+
+// returning projects the documents stored at the given keys once every document is inserted.
+// Keys whose document was replaced by a later document of the statement are only returned once,
+// with the later document, and keys whose document was deleted are ignored.
+func (stmt InsertStmt) returning(tx *database.Transaction, t *database.Table, args []expr.Param, keys [][]byte) (document.Stream, error) {
+	docs := make([]document.Document, 0, len(keys))
+	seen := make(map[string]bool, len(keys))
+
+	for _, k := range keys {
+		if seen[string(k)] {
+			continue
+		}
+		seen[string(k)] = true
+
+		d, err := t.GetDocument(k)
+		if err == database.ErrDocumentNotFound {
+			continue
+		}
+		if err != nil {
+			return document.Stream{}, err
+		}
+
+		docs = append(docs, d)
 	}
 
-	return stmt.insertDocuments(t, stack)
+	return stmt.Returning.Project(tx, stmt.TableName, args, document.NewStream(document.NewIterator(docs...)))
 }
 
 func (stmt InsertStmt) insertDocuments(t *database.Table, stack expr.EvalStack) (Result, error) {
@@ -61,21 +131,126 @@ func (stmt InsertStmt) insertDocuments(t *database.Table, stack expr.EvalStack)
 			return res, err
 		}
 
-		if v.Type != document.DocumentValue {
-			return res, fmt.Errorf("expected document, got %s", v.Type)
+		// a parameter can be bound to a list of documents,
+		// in which case each of them is inserted.
+		if v.Type == document.ArrayValue && isParam(e) {
+			err = v.V.(document.Array).Iterate(func(i int, v document.Value) error {
+				return stmt.insertDocument(t, v, &res)
+			})
+			if err != nil {
+				return res, err
+			}
+
+			continue
 		}
 
-		res.LastInsertKey, err = t.Insert(v.V.(document.Document))
+		err = stmt.insertDocument(t, v, &res)
 		if err != nil {
 			return res, err
 		}
-
-		res.RowsAffected++
 	}
 
 	return res, nil
 }
 
+func (stmt InsertStmt) insertDocument(t *database.Table, v document.Value, res *Result) error {
+	if v.Type != document.DocumentValue {
+		return database.NewError(database.CodeDatatypeMismatch, fmt.Sprintf("expected document, got %s", v.Type))
+	}
+
+	return stmt.insert(t, v.V.(document.Document), res)
+}
+
+// insert d, unless it conflicts with an existing document and the statement skips conflicts.
+// If the statement replaces conflicts, the conflicting documents are deleted first.
+func (stmt InsertStmt) insert(t *database.Table, d document.Document, res *Result) error {
+	switch stmt.OnConflict {
+	case OnConflictDoNothing:
+		k, err := t.Conflict(d)
+		if err != nil {
+			return err
+		}
+		if k != nil {
+			res.ConflictKeys = append(res.ConflictKeys, k)
+			res.RowsSkipped++
+			return nil
+		}
+	case OnConflictDoReplace:
+		// d may conflict with a document by primary key and with others by unique index
+		for {
+			k, err := t.Conflict(d)
+			if err != nil {
+				return err
+			}
+			if k == nil {
+				break
+			}
+
+			err = t.Delete(k)
+			if err != nil {
+				return err
+			}
+			res.ConflictKeys = append(res.ConflictKeys, k)
+		}
+	}
+
+	key, err := t.Insert(d)
+	if err != nil {
+		return err
+	}
+
+	res.LastInsertKey = key
+	res.InsertKeys = append(res.InsertKeys, key)
+	res.RowsAffected++
+	return nil
+}
+
+// InferParamTypes gives the parameters of the values of the statement the type
+// of the fields they are inserted into. It implements the ParamTyper interface.
+func (stmt InsertStmt) InferParamTypes(tx *database.Transaction, types *ParamTypes) error {
+	if len(stmt.FieldNames) == 0 {
+		return nil
+	}
+
+	t, err := tx.GetTable(stmt.TableName)
+	if err == database.ErrTableNotFound {
+		// the table may be created by a previous statement
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	info, err := t.Info()
+	if err != nil {
+		return err
+	}
+
+	for _, l := range stmt.Values {
+		el, ok := l.(expr.LiteralExprList)
+		if !ok {
+			continue
+		}
+
+		for i, e := range el {
+			if isParam(e) && i < len(stmt.FieldNames) {
+				types.Add(e, info.FieldType(document.ValuePath{{FieldName: stmt.FieldNames[i]}}))
+			}
+		}
+	}
+
+	return nil
+}
+
+func isParam(e expr.Expr) bool {
+	switch e.(type) {
+	case expr.NamedParam, expr.PositionalParam:
+		return true
+	}
+
+	return false
+}
+
 func (stmt InsertStmt) insertExprList(t *database.Table, stack expr.EvalStack) (Result, error) {
 	var res Result
 
@@ -91,7 +266,7 @@ func (stmt InsertStmt) insertExprList(t *database.Table, stack expr.EvalStack) (
 		// each document must be a list of expressions
 		// (e1, e2, e3, ...) or [e1, e2, e2, ....]
 		if v.Type != document.ArrayValue {
-			return res, fmt.Errorf("expected array, got %s", v.Type)
+			return res, database.NewError(database.CodeDatatypeMismatch, fmt.Sprintf("expected array, got %s", v.Type))
 		}
 
 		// iterate over each value
@@ -105,12 +280,10 @@ func (stmt InsertStmt) insertExprList(t *database.Table, stack expr.EvalStack) (
 			return nil
 		})
 
-		res.LastInsertKey, err = t.Insert(&fb)
+		err = stmt.insert(t, &fb, &res)
 		if err != nil {
 			return res, err
 		}
-
-		res.RowsAffected++
 	}
 
 	return res, nil
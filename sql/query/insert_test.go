@@ -4,12 +4,15 @@ import (
 	"bytes"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"testing"
 
 	"github.com/genjidb/genji"
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/key"
+	"github.com/genjidb/genji/sql/query"
 	"github.com/stretchr/testify/require"
 )
 
@@ -98,7 +101,7 @@ func TestInsertStmt(t *testing.T) {
 		require.NoError(t, err)
 
 		err = db.Exec(ctx, `INSERT INTO test (bar, foo) VALUES (1, 2)`)
-		require.Equal(t, err, database.ErrDuplicateDocument)
+		require.True(t, errors.Is(err, database.ErrDuplicateDocument))
 	})
 
 	t.Run("with shadowing", func(t *testing.T) {
@@ -138,6 +141,40 @@ func TestInsertStmt(t *testing.T) {
 		require.JSONEq(t, `{"a": "a", "b-b": "b"}`, buf.String())
 	})
 
+	t.Run("with list of documents param", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, "CREATE TABLE test")
+		require.NoError(t, err)
+
+		type foo struct {
+			A int
+		}
+
+		docs := []document.Document{
+			document.NewFieldBuffer().Add("a", document.NewIntegerValue(1)),
+			document.NewFieldBuffer().Add("a", document.NewIntegerValue(2)),
+		}
+
+		err = db.Exec(ctx, "INSERT INTO test VALUES ?", []int{1, 2})
+		require.Error(t, err)
+
+		err = db.Exec(ctx, "INSERT INTO test VALUES ?, ?", docs, []foo{{A: 3}, {A: 4}})
+		require.NoError(t, err)
+
+		res, err := db.Query(ctx, "SELECT a FROM test")
+		require.NoError(t, err)
+		defer res.Close()
+
+		var buf bytes.Buffer
+		err = document.IteratorToJSONArray(&buf, res)
+		require.NoError(t, err)
+		require.JSONEq(t, `[{"a": 1}, {"a": 2}, {"a": 3}, {"a": 4}]`, buf.String())
+
+	})
+
 	t.Run("with types constraints", func(t *testing.T) {
 		// This test ensures that we can insert data into every supported types.
 		db, err := genji.Open(":memory:")
@@ -179,6 +216,27 @@ func TestInsertStmt(t *testing.T) {
 		  }`, buf.String())
 	})
 
+	t.Run("with field constraints, fields keep their order", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `CREATE TABLE test(a INTEGER, b INTEGER, c INTEGER)`)
+		require.NoError(t, err)
+
+		err = db.Exec(ctx, `INSERT INTO test (a, c, b) VALUES (1, 3, 2); INSERT INTO test (x, a, b) VALUES (0, 4, 5)`)
+		require.NoError(t, err)
+
+		res, err := db.Query(ctx, "SELECT * FROM test")
+		require.NoError(t, err)
+		defer res.Close()
+
+		var buf bytes.Buffer
+		err = document.IteratorToJSONArray(&buf, res)
+		require.NoError(t, err)
+		require.Equal(t, `[{"a": 1, "c": 3, "b": 2}, {"x": 0, "a": 4, "b": 5}]`, buf.String())
+	})
+
 	t.Run("with tests that require an error", func(t *testing.T) {
 		tests := []struct {
 			name            string
@@ -232,3 +290,117 @@ func TestInsertStmt(t *testing.T) {
 		}
 	})
 }
+
+func TestWriteResult(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	exec := func(q string, args ...interface{}) *query.Result {
+		res, err := db.Query(ctx, q, args...)
+		require.NoError(t, err)
+		require.NoError(t, res.Close())
+		return res
+	}
+
+	exec("CREATE TABLE test; CREATE TABLE withpk (a TEXT PRIMARY KEY)")
+
+	res := exec("INSERT INTO test (a) VALUES (1), (2), (3)")
+	require.EqualValues(t, 3, res.RowsAffected)
+	require.Len(t, res.InsertKeys, 3)
+	require.Equal(t, res.InsertKeys[2], res.LastInsertKey)
+	require.Equal(t, document.NewIntegerValue(3), res.LastInsertPK)
+
+	res = exec("INSERT INTO test VALUES ?", []document.Document{
+		document.NewFieldBuffer().Add("a", document.NewIntegerValue(4)),
+		document.NewFieldBuffer().Add("a", document.NewIntegerValue(5)),
+	})
+	require.EqualValues(t, 2, res.RowsAffected)
+	require.Len(t, res.InsertKeys, 2)
+	require.Equal(t, document.NewIntegerValue(5), res.LastInsertPK)
+
+	res = exec("INSERT INTO withpk VALUES {a: 'foo'}")
+	require.Equal(t, document.NewTextValue("foo"), res.LastInsertPK)
+
+	res = exec("UPDATE test SET b = 1 WHERE a > 2")
+	require.EqualValues(t, 3, res.RowsAffected)
+	require.Nil(t, res.InsertKeys)
+
+	res = exec("DELETE FROM test WHERE a < 3")
+	require.EqualValues(t, 2, res.RowsAffected)
+
+	res = exec("SELECT * FROM test")
+	require.Zero(t, res.RowsAffected)
+}
+
+func TestInsertOnConflictDoNothing(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec(ctx, "CREATE TABLE test (a INTEGER PRIMARY KEY); CREATE UNIQUE INDEX idx_b ON test (b)")
+	require.NoError(t, err)
+	err = db.Exec(ctx, "INSERT INTO test (a, b) VALUES (1, 'foo'), (2, 'bar')")
+	require.NoError(t, err)
+
+	// without the clause, the whole statement fails
+	err = db.Exec(ctx, "INSERT INTO test (a, b) VALUES (3, 'baz'), (1, 'qux')")
+	require.True(t, errors.Is(err, database.ErrDuplicateDocument))
+
+	res, err := db.Query(ctx, `
+		INSERT INTO test (a, b) VALUES (3, 'baz'), (1, 'qux'), (4, 'bar'), (5, 'baz'), (6, 'quux')
+		ON CONFLICT DO NOTHING`)
+	require.NoError(t, err)
+	require.NoError(t, res.Close())
+
+	require.EqualValues(t, 2, res.RowsAffected)
+	require.EqualValues(t, 3, res.RowsSkipped)
+	// conflicts with a, b and a document inserted by the same statement
+	require.Equal(t, [][]byte{key.AppendInt64(nil, 1), key.AppendInt64(nil, 2), key.AppendInt64(nil, 3)}, res.ConflictKeys)
+
+	st, err := db.Query(ctx, "SELECT a FROM test")
+	require.NoError(t, err)
+	defer st.Close()
+
+	var buf bytes.Buffer
+	err = document.IteratorToJSONArray(&buf, st)
+	require.NoError(t, err)
+	require.JSONEq(t, `[{"a": 1}, {"a": 2}, {"a": 3}, {"a": 6}]`, buf.String())
+}
+
+func TestInsertOnConflictDoReplace(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec(ctx, "CREATE TABLE test (a INTEGER PRIMARY KEY, b TEXT UNIQUE)")
+	require.NoError(t, err)
+	err = db.Exec(ctx, "INSERT INTO test (a, b, c) VALUES (1, 'foo', 1), (2, 'bar', 2), (3, 'baz', 3)")
+	require.NoError(t, err)
+
+	res, err := db.Query(ctx, `
+		INSERT INTO test (a, b) VALUES (1, 'qux'), (4, 'bar'), (3, 'qux'), (5, 'quux')
+		ON CONFLICT DO REPLACE`)
+	require.NoError(t, err)
+	require.NoError(t, res.Close())
+
+	require.EqualValues(t, 4, res.RowsAffected)
+	require.Zero(t, res.RowsSkipped)
+	// (3, 'qux') conflicts with a = 3 and with b = 'qux', inserted by the same statement
+	require.Equal(t, [][]byte{key.AppendInt64(nil, 1), key.AppendInt64(nil, 2), key.AppendInt64(nil, 3), key.AppendInt64(nil, 1)}, res.ConflictKeys)
+
+	st, err := db.Query(ctx, "SELECT * FROM test")
+	require.NoError(t, err)
+	defer st.Close()
+
+	var buf bytes.Buffer
+	err = document.IteratorToJSONArray(&buf, st)
+	require.NoError(t, err)
+	require.JSONEq(t, `[{"a": 3, "b": "qux"}, {"a": 4, "b": "bar"}, {"a": 5, "b": "quux"}]`, buf.String())
+}
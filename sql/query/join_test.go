@@ -0,0 +1,174 @@
+package query_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJoin(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		query    string
+		fails    bool
+		expected string
+		params   []interface{}
+	}{
+		{"Inner", "SELECT authors.name AS name, books.title AS title FROM authors JOIN books ON authors.id = books.author",
+			false, `[{"name":"a","title":"a1"},{"name":"a","title":"a2"},{"name":"b","title":"b1"}]`, nil},
+		{"INNER JOIN", "SELECT name, books.title AS title FROM authors INNER JOIN books ON books.author = id",
+			false, `[{"name":"a","title":"a1"},{"name":"a","title":"a2"},{"name":"b","title":"b1"}]`, nil},
+		{"Wildcard", "SELECT * FROM authors JOIN books ON authors.id = books.author WHERE books.title = 'b1'",
+			false, `[{"id":2,"name":"b","books":{"author":2,"title":"b1"}}]`, nil},
+		{"Left", "SELECT name, books.title AS title FROM authors LEFT JOIN books ON authors.id = books.author",
+			false, `[{"name":"a","title":"a1"},{"name":"a","title":"a2"},{"name":"b","title":"b1"},{"name":"c","title":null}]`, nil},
+		{"Left outer", "SELECT name FROM authors LEFT OUTER JOIN books ON authors.id = books.author WHERE books IS NULL",
+			false, `[{"name":"c"}]`, nil},
+		{"Non equi", "SELECT name, books.title AS title FROM authors JOIN books ON books.author < authors.id",
+			false, `[{"name":"b","title":"a1"},{"name":"b","title":"a2"},{"name":"c","title":"a1"},{"name":"c","title":"a2"},{"name":"c","title":"b1"}]`, nil},
+		{"Chained", "SELECT name, books.title AS title, reviews.stars AS stars FROM authors JOIN books ON authors.id = books.author JOIN reviews ON reviews.book = books.title",
+			false, `[{"name":"a","title":"a1","stars":4},{"name":"b","title":"b1","stars":2}]`, nil},
+		{"Params", "SELECT books.title AS title FROM authors JOIN books ON authors.id = books.author AND books.title != ? WHERE name = ?",
+			false, `[{"title":"a2"}]`, []interface{}{"a1", "a"}},
+		{"Limit", "SELECT name FROM authors JOIN books ON authors.id = books.author LIMIT 1",
+			false, `[{"name":"a"}]`, nil},
+		{"Group by", "SELECT COUNT(*) AS n FROM authors JOIN books ON authors.id = books.author GROUP BY name",
+			false, `[{"n":2},{"n":1}]`, nil},
+		{"Unqualified", "SELECT name, title FROM authors JOIN books ON id = author",
+			false, `[{"name":"a","title":"a1"},{"name":"a","title":"a2"},{"name":"b","title":"b1"}]`, nil},
+		{"Unqualified in index lookup", "SELECT COUNT(*) AS n FROM authors JOIN reviews ON reviews.book = book",
+			false, `[{"n":9}]`, nil},
+		{"Aliases", "SELECT x.name AS name, y.title AS title FROM authors AS x JOIN books AS y ON x.id = y.author",
+			false, `[{"name":"a","title":"a1"},{"name":"a","title":"a2"},{"name":"b","title":"b1"}]`, nil},
+		{"Aliases/Wildcard", "SELECT * FROM authors AS x LEFT JOIN books AS y ON x.id = y.author WHERE y.title = 'b1' OR y IS NULL",
+			false, `[{"id":2,"name":"b","y":{"author":2,"title":"b1"}},{"id":3,"name":"c","y":null}]`, nil},
+		{"Aliases/Subquery", "SELECT name, (SELECT COUNT(*) FROM books WHERE author = x.id) AS n FROM authors AS x WHERE (SELECT COUNT(*) FROM books WHERE author = x.id) < 2",
+			false, `[{"name":"b","n":1},{"name":"c","n":0}]`, nil},
+		{"Self join", "SELECT authors.name AS a, next.name AS b FROM authors JOIN authors AS next ON next.id = authors.id + 1",
+			false, `[{"a":"a","b":"b"},{"a":"b","b":"c"}]`, nil},
+		{"Unknown table", "SELECT * FROM authors JOIN unknown ON authors.id = unknown.a", true, ``, nil},
+		{"Missing ON", "SELECT * FROM authors JOIN books", true, ``, nil},
+		{"Same table", "SELECT * FROM authors JOIN authors ON authors.id = authors.id", true, ``, nil},
+		{"Ambiguous", "SELECT * FROM authors JOIN authors AS other ON id = other.id", true, ``, nil},
+		{"Conflicting field", "SELECT * FROM authors JOIN books AS name ON id = name.author", true, ``, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// results must not depend on the indexes
+			for _, withIndex := range []bool{false, true} {
+				db, err := genji.Open(":memory:")
+				require.NoError(t, err)
+				defer db.Close()
+
+				err = db.Exec(ctx, `
+					CREATE TABLE authors (id INTEGER PRIMARY KEY);
+					CREATE TABLE books;
+					CREATE TABLE reviews;
+				`)
+				require.NoError(t, err)
+
+				if withIndex {
+					err = db.Exec(ctx, "CREATE INDEX idx_books_author ON books (author); CREATE INDEX idx_reviews_book ON reviews (book)")
+					require.NoError(t, err)
+				}
+
+				err = db.Exec(ctx, `
+					INSERT INTO authors (id, name) VALUES (1, 'a'), (2, 'b'), (3, 'c');
+					INSERT INTO books (author, title) VALUES (1, 'a1'), (1, 'a2'), (2, 'b1');
+					INSERT INTO reviews (book, stars) VALUES ('a1', 4), ('b1', 2), ('x', 5);
+				`)
+				require.NoError(t, err)
+
+				// some errors are only returned while iterating
+				var buf bytes.Buffer
+				st, err := db.Query(ctx, test.query, test.params...)
+				if err == nil {
+					err = document.IteratorToJSONArray(&buf, st)
+					st.Close()
+				}
+				if test.fails {
+					require.Error(t, err)
+					continue
+				}
+				require.NoError(t, err)
+				require.JSONEq(t, test.expected, buf.String(), "with index: %v", withIndex)
+			}
+		})
+	}
+
+	t.Run("Index", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `
+			CREATE TABLE authors;
+			CREATE TABLE books;
+			CREATE INDEX idx_books_author ON books (author);
+		`)
+		require.NoError(t, err)
+
+		explain := func(q string) string {
+			d, err := db.QueryDocument(ctx, "EXPLAIN "+q)
+			require.NoError(t, err)
+			v, err := d.GetByField("plan")
+			require.NoError(t, err)
+			return v.V.(string)
+		}
+
+		require.Contains(t, explain("SELECT * FROM authors JOIN books ON authors.id = books.author"), "index: idx_books_author")
+		require.Contains(t, explain("SELECT * FROM authors JOIN books ON books.author = authors.id"), "index: idx_books_author")
+		// the other operand reads the joined table
+		require.NotContains(t, explain("SELECT * FROM authors JOIN books ON books.author = books.id"), "index")
+		// not an equality
+		require.NotContains(t, explain("SELECT * FROM authors JOIN books ON books.author > authors.id"), "index")
+		// not indexed
+		require.NotContains(t, explain("SELECT * FROM authors JOIN books ON authors.id = books.title"), "index")
+		// aliased tables
+		require.Contains(t, explain("SELECT * FROM authors AS a JOIN books AS b ON a.id = b.author"), "index: idx_books_author")
+		require.NotContains(t, explain("SELECT * FROM authors AS a JOIN books AS b ON a.id = books.author"), "index")
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `
+			CREATE TABLE authors;
+			CREATE TABLE books;
+			INSERT INTO authors (id, name) VALUES (1, 'a');
+			INSERT INTO books (id, author) VALUES (1, 1);
+		`)
+		require.NoError(t, err)
+
+		tests := []struct {
+			query string
+			code  database.Code
+		}{
+			{"SELECT * FROM authors JOIN books ON id = author", database.CodeAmbiguousColumn},
+			{"SELECT * FROM authors JOIN books ON authors.id = author WHERE id > 0", database.CodeAmbiguousColumn},
+			{"SELECT * FROM authors JOIN books AS name ON authors.id = name.author", database.CodeAmbiguousColumn},
+			{"SELECT * FROM authors JOIN authors ON authors.id = authors.id", database.CodeDuplicateAlias},
+			{"SELECT * FROM authors AS a JOIN books AS a ON a.id = a.author", database.CodeDuplicateAlias},
+		}
+
+		for _, test := range tests {
+			res, err := db.Query(ctx, test.query)
+			if err == nil {
+				_, err = res.Count()
+				res.Close()
+			}
+			require.Error(t, err, test.query)
+			require.Equal(t, test.code, database.CodeOf(err), test.query)
+		}
+	})
+}
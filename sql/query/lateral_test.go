@@ -0,0 +1,73 @@
+package query_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/document"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLateral(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		query    string
+		fails    bool
+		expected string
+		params   []interface{}
+	}{
+		{"Top N", "SELECT name, top.title AS title FROM authors, LATERAL (SELECT title FROM books WHERE author = authors.id ORDER BY score DESC LIMIT 2) AS top",
+			false, `[{"name":"a","title":"a2"},{"name":"a","title":"a3"},{"name":"b","title":"b1"}]`, nil},
+		{"Without AS", "SELECT name, top.title AS title FROM authors, LATERAL (SELECT title FROM books WHERE author = authors.id ORDER BY score DESC LIMIT 1) top",
+			false, `[{"name":"a","title":"a2"},{"name":"b","title":"b1"}]`, nil},
+		{"Wildcard", "SELECT * FROM authors, LATERAL (SELECT title FROM books WHERE author = authors.id AND score > 5) AS b",
+			false, `[{"id":1,"name":"a","b":{"title":"a2"}},{"id":1,"name":"a","b":{"title":"a3"}}]`, nil},
+		{"Unqualified outer field", "SELECT name, b.t AS t FROM authors, LATERAL (SELECT title AS t FROM books WHERE author = id AND score < 6) AS b",
+			false, `[{"name":"a","t":"a1"},{"name":"b","t":"b1"}]`, nil},
+		{"Where on subquery", "SELECT name FROM authors, LATERAL (SELECT COUNT(*) AS c FROM books WHERE author = authors.id) AS b WHERE b.c > 1",
+			false, `[{"name":"a"}]`, nil},
+		{"Params", "SELECT name, b.title AS title FROM authors, LATERAL (SELECT title FROM books WHERE author = authors.id AND score >= ?) AS b WHERE id = ?",
+			false, `[{"name":"a","title":"a2"},{"name":"a","title":"a3"}]`, []interface{}{6, 1}},
+		{"Limit", "SELECT name FROM authors, LATERAL (SELECT title FROM books WHERE author = authors.id) AS b LIMIT 2",
+			false, `[{"name":"a"},{"name":"a"}]`, nil},
+		{"Chained", "SELECT name, c.n AS n FROM authors, LATERAL (SELECT title FROM books WHERE author = authors.id ORDER BY score DESC LIMIT 1) AS b, LATERAL (SELECT COUNT(*) AS n FROM books WHERE author = authors.id AND title != b.title) AS c",
+			false, `[{"name":"a","n":2},{"name":"b","n":0}]`, nil},
+		{"Unknown table", "SELECT * FROM authors, LATERAL (SELECT * FROM unknown) AS b", true, ``, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, err := genji.Open(":memory:")
+			require.NoError(t, err)
+			defer db.Close()
+
+			err = db.Exec(ctx, `
+				CREATE TABLE authors (id INTEGER PRIMARY KEY);
+				CREATE TABLE books;
+				CREATE INDEX idx_books_author ON books (author);
+				INSERT INTO authors (id, name) VALUES (1, 'a'), (2, 'b'), (3, 'c');
+				INSERT INTO books (author, title, score) VALUES
+					(1, 'a1', 5), (1, 'a2', 9), (1, 'a3', 7),
+					(2, 'b1', 3);
+			`)
+			require.NoError(t, err)
+
+			st, err := db.Query(ctx, test.query, test.params...)
+			defer st.Close()
+			if test.fails {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			err = document.IteratorToJSONArray(&buf, st)
+			require.NoError(t, err)
+			require.JSONEq(t, test.expected, buf.String())
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package query
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// runRecovered runs stmt and returns an error wrapping database.ErrStatementPanic
+// if it panics, either while running or while its result is iterated,
+// so that a statement can't crash the program running it.
+func runRecovered(ctx context.Context, tx *database.Transaction, stmt Statement, args []expr.Param) (res Result, err error) {
+	defer recoverPanic(&err)
+
+	res, err = stmt.Run(ctx, tx, args)
+	if err == nil && !res.Stream.IsEmpty() {
+		res.Stream = recoverStream(res.Stream)
+	}
+
+	return res, err
+}
+
+// recoverStream returns a stream iterating over st which returns an error
+// wrapping database.ErrStatementPanic if st panics. Panics raised by the function
+// passed to Iterate are not caused by the statement and are propagated.
+func recoverStream(st document.Stream) document.Stream {
+	return document.NewStream(document.IteratorFunc(func(fn func(d document.Document) error) (err error) {
+		var inFn bool
+		defer func() {
+			if p := recover(); p != nil {
+				if inFn {
+					panic(p)
+				}
+				err = panicError(p)
+			}
+		}()
+
+		return st.Iterate(func(d document.Document) error {
+			inFn = true
+			err := fn(d)
+			inFn = false
+			return err
+		})
+	}))
+}
+
+// recoverPanic sets *err to an error wrapping database.ErrStatementPanic if the function
+// deferring it panics.
+func recoverPanic(err *error) {
+	if p := recover(); p != nil {
+		*err = panicError(p)
+	}
+}
+
+func panicError(p interface{}) error {
+	return fmt.Errorf("%w: %v", database.ErrStatementPanic, p)
+}
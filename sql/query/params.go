@@ -0,0 +1,77 @@
+package query
+
+import (
+	"sort"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// ParamTypes collects the parameters of a statement, i.e. its expr.NamedParam
+// and expr.PositionalParam expressions, with the type of the values they expect.
+type ParamTypes struct {
+	types map[expr.Expr]document.ValueType
+	// parameters expecting values of different types
+	mixed map[expr.Expr]bool
+}
+
+// NewParamTypes returns an empty set of parameters.
+func NewParamTypes() *ParamTypes {
+	return &ParamTypes{
+		types: make(map[expr.Expr]document.ValueType),
+		mixed: make(map[expr.Expr]bool),
+	}
+}
+
+// Add records that the parameter p expects values of type t, or that it is used
+// without an expected type if t is 0. A parameter expecting values of different types,
+// other than numbers, is given no type.
+func (pt *ParamTypes) Add(p expr.Expr, t document.ValueType) {
+	cur, ok := pt.types[p]
+	switch {
+	case !ok || cur == 0:
+		if !pt.mixed[p] {
+			pt.types[p] = t
+		}
+	case t == 0 || cur == t || (cur.IsNumber() && t.IsNumber()):
+	default:
+		pt.types[p] = 0
+		pt.mixed[p] = true
+	}
+}
+
+// Type returns the type expected by the parameter p, or 0 if it is unknown.
+func (pt *ParamTypes) Type(p expr.Expr) document.ValueType {
+	return pt.types[p]
+}
+
+// Params returns the parameters that were added, positional parameters
+// ordered by position, then named parameters ordered by name.
+func (pt *ParamTypes) Params() []expr.Expr {
+	params := make([]expr.Expr, 0, len(pt.types))
+	for p := range pt.types {
+		params = append(params, p)
+	}
+
+	sort.Slice(params, func(i, j int) bool {
+		pi, iok := params[i].(expr.PositionalParam)
+		pj, jok := params[j].(expr.PositionalParam)
+		switch {
+		case iok && jok:
+			return pi < pj
+		case iok != jok:
+			return iok
+		}
+
+		return params[i].(expr.NamedParam) < params[j].(expr.NamedParam)
+	})
+
+	return params
+}
+
+// A ParamTyper is a statement that can infer the types of its parameters,
+// usually from the field constraints of the tables it reads or writes.
+type ParamTyper interface {
+	InferParamTypes(tx *database.Transaction, types *ParamTypes) error
+}
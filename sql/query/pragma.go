@@ -0,0 +1,130 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// PragmaStmt is a DSL that allows creating a PRAGMA statement.
+// Without value, it returns one document per runtime setting, with the fields
+// "name", "value" and "persisted". Otherwise, it changes the value of the setting
+// until the database is closed.
+type PragmaStmt struct {
+	// If empty, all the settings are returned.
+	Name  string
+	Value expr.Expr
+}
+
+// IsReadOnly returns true if the statement doesn't change the setting.
+// It implements the Statement interface.
+func (stmt PragmaStmt) IsReadOnly() bool {
+	return stmt.Value == nil
+}
+
+// Run returns or changes the runtime settings of the database.
+// It implements the Statement interface.
+func (stmt PragmaStmt) Run(ctx context.Context, tx *database.Transaction, args []expr.Param) (Result, error) {
+	var res Result
+
+	if stmt.Value != nil {
+		v, err := stmt.Value.Eval(expr.EvalStack{Tx: tx, Params: args})
+		if err != nil {
+			return res, err
+		}
+
+		return res, tx.DB().SetSetting(stmt.Name, v)
+	}
+
+	settings := tx.DB().Settings()
+	if stmt.Name != "" {
+		s, err := tx.DB().GetSetting(stmt.Name)
+		if err != nil {
+			return res, err
+		}
+		settings = []database.Setting{s}
+	}
+
+	docs := make([]document.Document, len(settings))
+	for i, s := range settings {
+		docs[i] = document.NewFieldBuffer().
+			Add("name", document.NewTextValue(s.Name)).
+			Add("value", s.Value).
+			Add("persisted", document.NewBoolValue(s.Persisted))
+	}
+
+	return newDocumentsResult(docs), nil
+}
+
+// SetGlobalStmt is a DSL that allows creating a SET GLOBAL statement,
+// which changes the value of a runtime setting and stores it in the database,
+// to be restored the next time it is opened.
+type SetGlobalStmt struct {
+	Name  string
+	Value expr.Expr
+}
+
+// IsReadOnly always returns false. It implements the Statement interface.
+func (stmt SetGlobalStmt) IsReadOnly() bool {
+	return false
+}
+
+// Run stores the setting, which is changed once the transaction is committed.
+// It implements the Statement interface.
+func (stmt SetGlobalStmt) Run(ctx context.Context, tx *database.Transaction, args []expr.Param) (Result, error) {
+	var res Result
+
+	v, err := stmt.Value.Eval(expr.EvalStack{Tx: tx, Params: args})
+	if err != nil {
+		return res, err
+	}
+
+	return res, tx.PersistSetting(stmt.Name, v)
+}
+
+// SetStmt is a DSL that allows creating a SET statement, which changes the value
+// of a setting for the following statements of its query. Only statement_timeout
+// can be set this way, to a duration like '30s', or '0s' to disable the timeout.
+// The value cannot use parameters.
+type SetStmt struct {
+	Name  string
+	Value expr.Expr
+}
+
+// IsReadOnly always returns true. It implements the Statement interface.
+func (stmt SetStmt) IsReadOnly() bool {
+	return true
+}
+
+func (stmt SetStmt) alterQuery(db *database.Database, q *Query) error {
+	if stmt.Name != "statement_timeout" {
+		return database.NewError(database.CodeFeatureNotSupported, fmt.Sprintf("setting %q cannot be set for a query, use PRAGMA or SET GLOBAL", stmt.Name))
+	}
+
+	v, err := stmt.Value.Eval(expr.EvalStack{})
+	if err != nil {
+		return err
+	}
+
+	var d time.Duration
+	if v.Type == document.TextValue {
+		d, err = time.ParseDuration(v.V.(string))
+	}
+	if v.Type != document.TextValue || err != nil || d < 0 {
+		return fmt.Errorf("%w: statement_timeout must be a duration, like '30s'", database.ErrInvalidSettingValue)
+	}
+
+	q.timeout, q.timeoutSet = d, true
+	return nil
+}
+
+// Run returns an error, SET statements are applied by the query running them.
+// It implements the Statement interface.
+func (stmt SetStmt) Run(ctx context.Context, tx *database.Transaction, args []expr.Param) (Result, error) {
+	return Result{}, errors.New("SET statements can only be run by a query")
+}
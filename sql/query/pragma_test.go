@@ -0,0 +1,270 @@
+package query_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPragma(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		query    string
+		params   []interface{}
+		expected string
+		fails    error
+	}{
+		{"All", `PRAGMA`, nil, `[
+			{"name": "busy_mode", "value": "wait", "persisted": false},
+			{"name": "busy_timeout", "value": "0s", "persisted": false},
+			{"name": "integer_overflow", "value": "promote", "persisted": false},
+			{"name": "max_query_memory", "value": 0, "persisted": false},
+			{"name": "max_recursion", "value": 0, "persisted": false},
+			{"name": "max_statement_rate", "value": 0, "persisted": false},
+			{"name": "max_transaction_age", "value": "0s", "persisted": false},
+			{"name": "max_transaction_idle", "value": "0s", "persisted": false},
+			{"name": "max_write_rate", "value": 0, "persisted": false},
+			{"name": "scan_prefetch", "value": 0, "persisted": false},
+			{"name": "statement_timeout", "value": "0s", "persisted": false},
+			{"name": "throttle_timeout", "value": "0s", "persisted": false}
+		]`, nil},
+		{"One", `PRAGMA max_recursion`, nil, `[{"name": "max_recursion", "value": 0, "persisted": false}]`, nil},
+		{"Case insensitive", `PRAGMA MAX_RECURSION`, nil, `[{"name": "max_recursion", "value": 0, "persisted": false}]`, nil},
+		{"Set", `PRAGMA max_recursion = 10; PRAGMA max_recursion`, nil, `[{"name": "max_recursion", "value": 10, "persisted": false}]`, nil},
+		{"Set with params", `PRAGMA busy_timeout = ?; PRAGMA busy_timeout`, []interface{}{"1m30s"}, `[{"name": "busy_timeout", "value": "1m30s", "persisted": false}]`, nil},
+		{"Set global", `SET GLOBAL busy_mode = 'ERROR'; PRAGMA busy_mode`, nil, `[{"name": "busy_mode", "value": "error", "persisted": true}]`, nil},
+		{"Persisted settings", `SET GLOBAL max_query_memory = 1024; SELECT * FROM __genji_settings`, nil, `[{"name": "max_query_memory", "value": 1024}]`, nil},
+		{"Unknown", `PRAGMA foo`, nil, ``, database.ErrSettingNotFound},
+		{"Set unknown", `PRAGMA foo = 1`, nil, ``, database.ErrSettingNotFound},
+		{"Invalid value", `PRAGMA max_query_memory = 'a lot'`, nil, ``, database.ErrInvalidSettingValue},
+		{"Invalid duration", `SET GLOBAL busy_timeout = 10`, nil, ``, database.ErrInvalidSettingValue},
+		{"Negative duration", `PRAGMA max_transaction_age = '-1s'`, nil, ``, database.ErrInvalidSettingValue},
+		{"Set statement timeout", `SET statement_timeout = '10s'; PRAGMA statement_timeout`, nil, `[{"name": "statement_timeout", "value": "0s", "persisted": false}]`, nil},
+		{"Invalid statement timeout", `SET statement_timeout = 10`, nil, ``, database.ErrInvalidSettingValue},
+		{"Set integer overflow", `PRAGMA integer_overflow = 'ERROR'; PRAGMA integer_overflow`, nil, `[{"name": "integer_overflow", "value": "error", "persisted": false}]`, nil},
+		{"Invalid integer overflow", `PRAGMA integer_overflow = 'wrap'`, nil, ``, database.ErrInvalidSettingValue},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, err := genji.Open(":memory:")
+			require.NoError(t, err)
+			defer db.Close()
+
+			res, err := db.Query(ctx, test.query, test.params...)
+			if test.fails != nil {
+				require.True(t, errors.Is(err, test.fails), "unexpected error %v", err)
+				return
+			}
+			require.NoError(t, err)
+			defer res.Close()
+
+			var buf bytes.Buffer
+			err = document.IteratorToJSONArray(&buf, res)
+			require.NoError(t, err)
+			require.JSONEq(t, test.expected, buf.String())
+		})
+	}
+
+	t.Run("Settings take effect", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, "PRAGMA busy_mode = 'error'")
+		require.NoError(t, err)
+
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		_, err = db.Begin(true)
+		require.Equal(t, database.ErrBusy, err)
+	})
+
+	t.Run("Rolled back SET GLOBAL", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		err = tx.Exec(ctx, "SET GLOBAL max_recursion = 5")
+		require.NoError(t, err)
+		err = tx.Rollback()
+		require.NoError(t, err)
+
+		require.Equal(t, database.DefaultMaxRecursion, db.DB.RecursionLimit())
+	})
+}
+
+func TestIntegerOverflow(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := genji.OpenWithOptions(":memory:", &genji.Options{ResultCacheSize: 10})
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec(ctx, "CREATE TABLE test; INSERT INTO test (a, b) VALUES (9223372036854775807, -9223372036854775808)")
+	require.NoError(t, err)
+
+	queries := []string{
+		"SELECT a + 1 AS r FROM test",
+		"SELECT 9223372036854775807 + 1 AS r",
+	}
+
+	// overflows are promoted to doubles by default
+	for _, q := range queries {
+		d, err := db.QueryDocument(ctx, q)
+		require.NoError(t, err, q)
+		v, err := d.GetByField("r")
+		require.NoError(t, err)
+		require.Equal(t, document.NewDoubleValue(9223372036854775808), v)
+	}
+
+	// the mode of the database is used by the transactions started afterwards,
+	// without being affected by cached results or precalculated expressions
+	err = db.Exec(ctx, "PRAGMA integer_overflow = 'error'")
+	require.NoError(t, err)
+	for _, q := range queries {
+		_, err = db.QueryDocument(ctx, q)
+		require.Equal(t, document.ErrIntegerOverflow, err, q)
+	}
+
+	// the remainder of MinInt64 by -1 comes from a division that overflows
+	for _, q := range []string{"SELECT b % -1 AS r FROM test", "SELECT -9223372036854775808 % -1 AS r"} {
+		_, err = db.QueryDocument(ctx, q)
+		require.Equal(t, document.ErrIntegerOverflow, err, q)
+	}
+
+	// and can be overridden by each transaction
+	tx, err := db.Begin(false)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	tx.SetIntegerOverflowMode(document.OverflowPromote)
+	for _, q := range queries {
+		d, err := tx.QueryDocument(ctx, q)
+		require.NoError(t, err, q)
+		v, err := d.GetByField("r")
+		require.NoError(t, err)
+		require.Equal(t, document.NewDoubleValue(9223372036854775808), v)
+	}
+}
+
+func TestStatementTimeout(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := genji.OpenWithOptions(":memory:", &genji.Options{StatementTimeout: 20 * time.Millisecond})
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec(ctx, "CREATE TABLE a; CREATE TABLE b; CREATE TABLE c")
+	require.NoError(t, err)
+	for i := 0; i < 200; i++ {
+		err = db.Exec(ctx, "INSERT INTO a (x) VALUES (?); INSERT INTO b (y) VALUES (?); INSERT INTO c (z) VALUES (?)", i, i, i)
+		require.NoError(t, err)
+	}
+
+	slow := "SELECT COUNT(*) FROM a JOIN b ON x >= y JOIN c ON y >= z"
+
+	// SELECT statements are run while their result is read
+	run := func(ctx context.Context, q string) error {
+		_, err := db.QueryDocument(ctx, q)
+		return err
+	}
+
+	requireTimeout := func(t *testing.T, err error) {
+		t.Helper()
+		require.True(t, errors.Is(err, database.ErrStatementTimeout), "unexpected error %v", err)
+		require.Equal(t, database.CodeQueryCanceled, database.CodeOf(err))
+	}
+
+	t.Run("Default", func(t *testing.T) {
+		start := time.Now()
+		err := run(ctx, slow)
+		requireTimeout(t, err)
+		require.Less(t, int64(time.Since(start)), int64(time.Second))
+
+		// fast statements are not affected
+		d, err := db.QueryDocument(ctx, "SELECT COUNT(*) FROM a")
+		require.NoError(t, err)
+		v, err := d.GetByField("COUNT(*)")
+		require.NoError(t, err)
+		require.EqualValues(t, 200, v.V)
+	})
+
+	t.Run("Result", func(t *testing.T) {
+		// the timeout applies while the result is read
+		res, err := db.Query(ctx, "SELECT * FROM a")
+		require.NoError(t, err)
+		defer res.Close()
+
+		time.Sleep(30 * time.Millisecond)
+		err = res.Iterate(func(d document.Document) error { return nil })
+		requireTimeout(t, err)
+	})
+
+	t.Run("Transaction", func(t *testing.T) {
+		tx, err := db.Begin(false)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		_, err = tx.QueryDocument(ctx, slow)
+		requireTimeout(t, err)
+
+		// the transaction can still be used
+		_, err = tx.QueryDocument(ctx, "SELECT * FROM a")
+		require.NoError(t, err)
+	})
+
+	t.Run("Pragma", func(t *testing.T) {
+		err := db.Exec(ctx, "PRAGMA statement_timeout = '0s'")
+		require.NoError(t, err)
+		defer db.Exec(ctx, "PRAGMA statement_timeout = '20ms'")
+
+		res, err := db.Query(ctx, "SELECT * FROM a")
+		require.NoError(t, err)
+		defer res.Close()
+
+		time.Sleep(30 * time.Millisecond)
+		err = res.Iterate(func(d document.Document) error { return nil })
+		require.NoError(t, err)
+	})
+
+	t.Run("Set", func(t *testing.T) {
+		// the timeout applies to the following statements of the query
+		err := run(ctx, "SET statement_timeout = '10ms'; "+slow)
+		requireTimeout(t, err)
+		require.Contains(t, err.Error(), "after 10ms")
+
+		res, err := db.Query(ctx, "SET statement_timeout = '0s'; SELECT * FROM a")
+		require.NoError(t, err)
+		defer res.Close()
+
+		time.Sleep(30 * time.Millisecond)
+		err = res.Iterate(func(d document.Document) error { return nil })
+		require.NoError(t, err)
+
+		err = db.Exec(ctx, "SET max_recursion = 10")
+		require.Error(t, err)
+	})
+
+	t.Run("Context", func(t *testing.T) {
+		// deadlines of the context are not reported as statement timeouts
+		cctx, cancel := context.WithTimeout(ctx, 5*time.Millisecond)
+		defer cancel()
+
+		err := run(cctx, slow)
+		require.True(t, errors.Is(err, context.DeadlineExceeded), "unexpected error %v", err)
+		require.False(t, errors.Is(err, database.ErrStatementTimeout))
+	})
+}
@@ -0,0 +1,163 @@
+package query_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcedures(t *testing.T) {
+	ctx := context.Background()
+
+	procedures := `
+		CREATE PROCEDURE archive(threshold) BEGIN
+			FOR SELECT k FROM test WHERE size < $threshold DO
+				INSERT INTO archive (k) VALUES ($k);
+			END FOR;
+			DELETE FROM test WHERE size < $threshold;
+		END;
+		CREATE PROCEDURE grow(color, n) BEGIN
+			IF $n > 0 THEN
+				UPDATE test SET size = size + $n WHERE color = $color;
+			ELSE
+				UPDATE test SET size = 0 WHERE color = $color;
+			END IF;
+		END;
+		CREATE PROCEDURE sizes() BEGIN
+			FOR SELECT k, size * 2 AS doubled FROM test DO
+				IF NOT EXISTS (SELECT * FROM sizes WHERE k = $k) THEN
+					INSERT INTO sizes (k, size) VALUES ($k, $doubled);
+				END IF;
+			END FOR;
+		END;
+		CREATE PROCEDURE once() BEGIN
+			FOR SELECT k FROM test DO
+				IF NOT EXISTS (SELECT * FROM sizes) THEN
+					INSERT INTO sizes (k, size) VALUES ($k, 0);
+				END IF;
+			END FOR;
+		END;
+		CREATE PROCEDURE grow_all(n) BEGIN
+			FOR SELECT color FROM test DO
+				CALL grow($color, $n);
+			END FOR;
+		END;
+		CREATE PROCEDURE fail() BEGIN
+			UPDATE test SET size = 100;
+			INSERT INTO test (k) VALUES (1);
+		END;
+		CREATE PROCEDURE forever() BEGIN
+			CALL forever();
+		END;
+	`
+
+	tests := []struct {
+		name     string
+		query    string
+		fails    bool
+		expected string
+		params   []interface{}
+	}{
+		{"Statements", "CALL archive(5); SELECT k FROM archive", false, `[{"k":3}]`, nil},
+		{"If", "CALL grow('red', 2); SELECT k, size FROM test WHERE color = 'red'", false, `[{"k":1,"size":12}]`, nil},
+		{"Else", "CALL grow('red', 0); SELECT k, size FROM test WHERE color = 'red'", false, `[{"k":1,"size":0}]`, nil},
+		{"Arguments with params", "CALL grow(?, ? + 1); SELECT size FROM test WHERE color = 'blue'", false, `[{"size":13}]`, []interface{}{"blue", 2}},
+		{"For", "CALL sizes(); CALL sizes(); SELECT * FROM sizes", false, `[{"k":1,"size":20},{"k":2,"size":20},{"k":3,"size":2}]`, nil},
+		{"Conditions see previous statements", "CALL once(); SELECT k FROM sizes", false, `[{"k":1}]`, nil},
+		{"Nested calls", "CALL grow_all(1); SELECT size FROM test", false, `[{"size":11},{"size":11},{"size":2}]`, nil},
+		{"Last result", "CREATE PROCEDURE reds() BEGIN SELECT k FROM test WHERE color = 'red'; END; CALL reds()", false, `[{"k":1}]`, nil},
+		{"Failure rolls back", "CALL fail()", true, ``, nil},
+		{"Wrong number of arguments", "CALL grow('red')", true, ``, nil},
+		{"Unknown procedure", "CALL unknown()", true, ``, nil},
+		{"Recursion limit", "CALL forever()", true, ``, nil},
+		{"Already exists", "CREATE PROCEDURE sizes() BEGIN SELECT 1; END", true, ``, nil},
+		{"If not exists", "CREATE PROCEDURE IF NOT EXISTS sizes() BEGIN SELECT 1; END; CALL sizes(); SELECT COUNT(*) AS c FROM sizes", false, `[{"c":3}]`, nil},
+		{"Drop", "DROP PROCEDURE sizes; CALL sizes()", true, ``, nil},
+		{"Drop if exists", "DROP PROCEDURE IF EXISTS unknown; SELECT procedure_name FROM __genji_procedures WHERE procedure_name = 'grow'", false, `[{"procedure_name":"grow"}]`, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, err := genji.OpenWithOptions(":memory:", &genji.Options{MaxRecursion: 10})
+			require.NoError(t, err)
+			defer db.Close()
+
+			err = db.Exec(ctx, `
+				CREATE TABLE test (k INTEGER PRIMARY KEY);
+				CREATE TABLE archive (k INTEGER PRIMARY KEY);
+				CREATE TABLE sizes (k INTEGER PRIMARY KEY);
+				INSERT INTO test (k, color, size) VALUES (1, 'red', 10), (2, 'blue', 10), (3, 'green', 1);
+			`)
+			require.NoError(t, err)
+
+			err = db.Exec(ctx, procedures)
+			require.NoError(t, err)
+
+			st, err := db.Query(ctx, test.query, test.params...)
+			defer st.Close()
+			if test.fails {
+				require.Error(t, err)
+
+				// statements of failed procedures are rolled back
+				d, err := db.QueryDocument(ctx, "SELECT SUM(size) AS s FROM test")
+				require.NoError(t, err)
+				v, err := d.GetByField("s")
+				require.NoError(t, err)
+				require.EqualValues(t, 21, v.V)
+				return
+			}
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			err = document.IteratorToJSONArray(&buf, st)
+			require.NoError(t, err)
+			require.JSONEq(t, test.expected, buf.String())
+		})
+	}
+
+	t.Run("Errors", func(t *testing.T) {
+		db, err := genji.OpenWithOptions(":memory:", &genji.Options{MaxRecursion: 3})
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, procedures)
+		require.NoError(t, err)
+
+		err = db.Exec(ctx, "CALL forever()")
+		require.True(t, errors.Is(err, database.ErrRecursionLimitExceeded))
+
+		err = db.Exec(ctx, "CALL unknown()")
+		require.True(t, errors.Is(err, database.ErrProcedureNotFound))
+		require.Equal(t, database.CodeUndefinedFunction, database.CodeOf(err))
+
+		err = db.Exec(ctx, "CREATE PROCEDURE fail() BEGIN SELECT 1; END")
+		require.True(t, errors.Is(err, database.ErrProcedureAlreadyExists))
+	})
+
+	t.Run("Rows affected", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `
+			CREATE TABLE test (k INTEGER PRIMARY KEY);
+			CREATE TABLE archive (k INTEGER PRIMARY KEY);
+			CREATE TABLE sizes (k INTEGER PRIMARY KEY);
+			INSERT INTO test (k, color, size) VALUES (1, 'red', 10), (2, 'blue', 10), (3, 'green', 1);
+		`)
+		require.NoError(t, err)
+		err = db.Exec(ctx, procedures)
+		require.NoError(t, err)
+
+		res, err := db.Query(ctx, "CALL grow_all(1)")
+		require.NoError(t, err)
+		defer res.Close()
+		require.EqualValues(t, 3, res.RowsAffected)
+	})
+}
@@ -3,6 +3,7 @@ package query
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
@@ -10,18 +11,29 @@ import (
 )
 
 // ErrResultClosed is returned when trying to close an already closed result.
-var ErrResultClosed = errors.New("result already closed")
+var ErrResultClosed = database.NewError(database.CodeObjectNotInPrerequisiteState, "result already closed")
 
 // A Query can execute statements against the database. It can read or write data
 // from any table, or even alter the structure of the database.
 // Results are returned as streams.
 type Query struct {
 	Statements []Statement
+	// Source of the query, if known.
+	// It is attached to the transactions started by the query for diagnostics.
+	Source     string
 	tx         *database.Transaction
 	autoCommit bool
+	// if timeoutSet, the statement timeout set by SET statement_timeout,
+	// which overrides the one of the database.
+	timeout    time.Duration
+	timeoutSet bool
 }
 
 // Run executes all the statements in their own transaction and returns the last result.
+// If a transaction is attached to the database, statements are run within it instead
+// and each of them is atomic: if one of them fails, its changes are rolled back
+// and the transaction remains usable.
+// Statements panicking fail with an error wrapping database.ErrStatementPanic.
 func (q Query) Run(ctx context.Context, db *database.Database, args []expr.Param) (*Result, error) {
 	var res Result
 	var err error
@@ -54,20 +66,48 @@ func (q Query) Run(ctx context.Context, db *database.Database, args []expr.Param
 			continue
 		}
 
+		if q.autoCommit && isSavepointStmt(stmt) {
+			return nil, errNoTxSavepoint
+		}
+
+		err = db.ThrottleStatement(ctx)
+		if err != nil {
+			return nil, err
+		}
+
 		if q.tx == nil {
-			q.tx, err = db.Begin(!stmt.IsReadOnly())
+			q.tx, err = db.BeginTx(&database.TxOptions{
+				ReadOnly:  stmt.IsReadOnly(),
+				Statement: q.Source,
+			})
 			if err != nil {
 				return nil, err
 			}
 		}
 
-		res, err = stmt.Run(ctx, q.tx, args)
-		if err != nil {
-			if q.autoCommit {
+		sctx, release := q.statementContext(ctx, q.tx)
+		if q.autoCommit {
+			res, err = runRecovered(sctx, q.tx, stmt, args)
+			if err != nil {
+				err = statementError(sctx, err)
+				release()
 				q.tx.Rollback()
+				return nil, err
 			}
+		} else {
+			res, err = runStatement(sctx, q.tx, stmt, args)
+			if err != nil {
+				err = statementError(sctx, err)
+				release()
+				return nil, err
+			}
+		}
 
-			return nil, err
+		// the result of the last statement can be read until it is closed
+		if i+1 < len(q.Statements) {
+			release()
+		} else {
+			res.release = release
 		}
 
 		// it there is an opened transaction but there are still statements
@@ -98,26 +138,116 @@ func (q Query) Run(ctx context.Context, db *database.Database, args []expr.Param
 }
 
 // Exec the query within the given transaction.
+// Each statement is atomic: if one of them fails, its changes are rolled back
+// and the transaction remains usable.
 func (q Query) Exec(ctx context.Context, tx *database.Transaction, args []expr.Param) (*Result, error) {
 	var res Result
 	var err error
 
-	for _, stmt := range q.Statements {
+	for i, stmt := range q.Statements {
 		select {
 		case <-ctx.Done():
 			return nil, ctx.Err()
 		default:
 		}
 
-		res, err = stmt.Run(ctx, tx, args)
+		if st, ok := stmt.(SetStmt); ok {
+			err = st.alterQuery(tx.DB(), &q)
+			if err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		err = tx.DB().ThrottleStatement(ctx)
 		if err != nil {
 			return nil, err
 		}
+
+		sctx, release := q.statementContext(ctx, tx)
+		res, err = runStatement(sctx, tx, stmt, args)
+		if err != nil {
+			err = statementError(sctx, err)
+			release()
+			return nil, err
+		}
+
+		if i+1 < len(q.Statements) {
+			release()
+		} else {
+			res.release = release
+		}
 	}
 
 	return &res, nil
 }
 
+// statementContext returns the context of a statement run within tx, which is canceled
+// after the statement timeout of the query or of the database, and sets it as the context
+// of tx. The context carries the memory account shared by all the operators of the statement.
+// The returned function releases the context and must be called once the statement
+// and its result are done.
+func (q *Query) statementContext(ctx context.Context, tx *database.Transaction) (context.Context, func()) {
+	timeout := tx.DB().DefaultStatementTimeout()
+	if q.timeoutSet {
+		timeout = q.timeout
+	}
+
+	ctx, cancel := database.WithStatementTimeout(ctx, timeout)
+	ctx = database.WithMemoryAccount(ctx, database.NewMemoryAccount(tx.DB().QueryMemoryLimit()))
+	tx.SetContext(ctx)
+
+	return ctx, func() {
+		tx.SetContext(nil)
+		cancel()
+	}
+}
+
+// statementError returns an error wrapping database.ErrStatementTimeout
+// if err was caused by the timeout of ctx, or err otherwise.
+func statementError(ctx context.Context, err error) error {
+	if !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	if cerr := database.ContextErr(ctx); cerr != nil && cerr != context.DeadlineExceeded {
+		return cerr
+	}
+
+	return err
+}
+
+// runStatement runs stmt atomically within tx: if it fails,
+// its changes are rolled back and the transaction remains usable.
+// The previous values of the keys it modifies are kept in memory until it ends,
+// within the memory limit of queries.
+func runStatement(ctx context.Context, tx *database.Transaction, stmt Statement, args []expr.Param) (Result, error) {
+	if err := tx.Check(); err != nil {
+		return Result{}, err
+	}
+
+	if !tx.Writable() || stmt.IsReadOnly() || isSavepointStmt(stmt) {
+		return runRecovered(ctx, tx, stmt, args)
+	}
+
+	sp, err := tx.Savepoint()
+	if err != nil {
+		return Result{}, err
+	}
+
+	res, err := runRecovered(ctx, tx, stmt, args)
+	if err != nil {
+		if rerr := sp.Rollback(); rerr != nil {
+			return Result{}, rerr
+		}
+		return Result{}, err
+	}
+
+	sp.Release()
+	return res, nil
+}
+
 // New creates a new query with the given statements.
 func New(statements ...Statement) Query {
 	return Query{Statements: statements}
@@ -132,10 +262,28 @@ type Statement interface {
 // Result of a query.
 type Result struct {
 	document.Stream
-	RowsAffected  int64
+	// RowsAffected is the number of documents inserted, updated or deleted
+	// by the last statement.
+	RowsAffected int64
+	// LastInsertKey is the key of the last document inserted by the last statement.
 	LastInsertKey []byte
-	Tx            *database.Transaction
-	closed        bool
+	// InsertKeys contains the keys of the documents inserted by the last statement,
+	// in insertion order.
+	InsertKeys [][]byte
+	// LastInsertPK is the primary key of the last document inserted by the last statement,
+	// as returned by the pk() function.
+	LastInsertPK document.Value
+	// RowsSkipped is the number of documents not inserted by the last statement
+	// because of an ON CONFLICT DO NOTHING clause.
+	RowsSkipped int64
+	// ConflictKeys contains the keys of the existing documents that prevented
+	// the skipped documents from being inserted, or that were deleted
+	// because of an ON CONFLICT DO REPLACE clause.
+	ConflictKeys [][]byte
+	Tx           *database.Transaction
+	closed       bool
+	// release the context of the statement, see Query.statementContext.
+	release func()
 }
 
 // Close the result stream.
@@ -153,6 +301,10 @@ func (r *Result) Close() (err error) {
 
 	r.closed = true
 
+	if r.release != nil {
+		r.release()
+	}
+
 	if r.Tx != nil {
 		if r.Tx.Writable() {
 			err = r.Tx.Commit()
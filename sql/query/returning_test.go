@@ -0,0 +1,85 @@
+package query_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/document"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReturning(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name         string
+		query        string
+		fails        bool
+		expected     string
+		rowsAffected int64
+		stored       string
+	}{
+		{"Insert", "INSERT INTO test (a, b) VALUES (4, 'qux'), (5, '5') RETURNING *", false,
+			`[{"a": 4, "b": "qux", "c": 0}, {"a": 5, "b": "5", "c": 0}]`, 2, ``},
+		{"Insert / Expressions", "INSERT INTO test VALUES {a: 4, b: 'qux'} RETURNING pk() AS id, a + 1, c", false,
+			`[{"id": 4, "a + 1": 5, "c": 0}]`, 1, ``},
+		{"Insert / On conflict do nothing", "INSERT INTO test (a, b) VALUES (1, 'foo'), (4, 'qux') ON CONFLICT DO NOTHING RETURNING a", false,
+			`[{"a": 4}]`, 1, ``},
+		{"Insert / On conflict do replace", "INSERT INTO test (a, b) VALUES (1, 'foo'), (1, 'bar') ON CONFLICT DO REPLACE RETURNING a, b", false,
+			`[{"a": 1, "b": "bar"}]`, 2, ``},
+		{"Insert / Unknown function", "INSERT INTO test (a, b) VALUES (4, 'qux') RETURNING foo()", true, ``, 0,
+			`[{"a": 1, "b": "foo", "c": 1}, {"a": 2, "b": "bar", "c": 2}, {"a": 3, "b": "baz", "c": 3}]`},
+		{"Update", "UPDATE test SET c = c * 10 WHERE a > 1 RETURNING pk(), c", false,
+			`[{"pk()": 2, "c": 20}, {"pk()": 3, "c": 30}]`, 2,
+			`[{"a": 1, "b": "foo", "c": 1}, {"a": 2, "b": "bar", "c": 20}, {"a": 3, "b": "baz", "c": 30}]`},
+		{"Update / No match", "UPDATE test SET c = 10 WHERE a > 10 RETURNING *", false, `[]`, 0, ``},
+		{"Delete", "DELETE FROM test WHERE b != 'bar' RETURNING *", false,
+			`[{"a": 1, "b": "foo", "c": 1}, {"a": 3, "b": "baz", "c": 3}]`, 2,
+			`[{"a": 2, "b": "bar", "c": 2}]`},
+		{"Delete / Aggregate", "DELETE FROM test RETURNING COUNT(*) AS n, MAX(c)", false,
+			`[{"n": 3, "MAX(c)": 3}]`, 3, `[]`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, err := genji.Open(":memory:")
+			require.NoError(t, err)
+			defer db.Close()
+
+			err = db.Exec(ctx, `
+				CREATE TABLE test (a INTEGER PRIMARY KEY, b TEXT, c INTEGER DEFAULT 0);
+				INSERT INTO test (a, b, c) VALUES (1, 'foo', 1), (2, 'bar', 2), (3, 'baz', 3);
+			`)
+			require.NoError(t, err)
+
+			res, err := db.Query(ctx, test.query)
+			if test.fails {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+
+				var buf bytes.Buffer
+				err = document.IteratorToJSONArray(&buf, res)
+				require.NoError(t, err)
+				require.JSONEq(t, test.expected, buf.String())
+				require.Equal(t, test.rowsAffected, res.RowsAffected)
+				require.NoError(t, res.Close())
+			}
+
+			if test.stored == "" {
+				return
+			}
+
+			st, err := db.Query(ctx, "SELECT * FROM test")
+			require.NoError(t, err)
+			defer st.Close()
+
+			var buf bytes.Buffer
+			err = document.IteratorToJSONArray(&buf, st)
+			require.NoError(t, err)
+			require.JSONEq(t, test.stored, buf.String())
+		})
+	}
+}
@@ -41,11 +41,17 @@ func TestSelectStmt(t *testing.T) {
 		{"With gt op", "SELECT * FROM test WHERE size > 10", false, `[]`, nil},
 		{"With lt op", "SELECT * FROM test WHERE size < 15", false, `[{"k":1,"color":"red","size":10,"shape":"square"},{"k":2,"color":"blue","size":10,"weight":100}]`, nil},
 		{"With lte op", "SELECT * FROM test WHERE color <= 'salmon' ORDER BY k ASC", false, `[{"k":1,"color":"red","size":10,"shape":"square"},{"k":2,"color":"blue","size":10,"weight":100}]`, nil},
+		{"With range", "SELECT k FROM test WHERE weight > 100 AND weight <= 200", false, `[{"k":3}]`, nil},
+		{"With range, reversed operands", "SELECT k FROM test WHERE 50 < weight AND weight < 150", false, `[{"k":2}]`, nil},
+		{"With range on text", "SELECT k FROM test WHERE color >= 'blue' AND color <= 'red' ORDER BY k", false, `[{"k":1},{"k":2}]`, nil},
+		{"With empty range", "SELECT k FROM test WHERE weight > 200 AND weight < 100", false, `[]`, nil},
+		{"With range of different types", "SELECT k FROM test WHERE weight > 100 AND weight < 'a'", false, `[]`, nil},
 		{"With add op", "SELECT size + 10 AS s FROM test ORDER BY k", false, `[{"s":20},{"s":20},{"s":null}]`, nil},
 		{"With sub op", "SELECT size - 10 AS s FROM test ORDER BY k", false, `[{"s":0},{"s":0},{"s":null}]`, nil},
 		{"With mul op", "SELECT size * 10 AS s FROM test ORDER BY k", false, `[{"s":100},{"s":100},{"s":null}]`, nil},
 		{"With div op", "SELECT size / 10 AS s FROM test ORDER BY k", false, `[{"s":1},{"s":1},{"s":null}]`, nil},
 		{"With IN op", "SELECT color FROM test WHERE color IN ['red', 'purple'] ORDER BY k", false, `[{"color":"red"}]`, nil},
+		{"With IN op, duplicated values", "SELECT k FROM test WHERE weight IN [200, 100, 200.0, null]", false, `[{"k":2},{"k":3}]`, nil},
 		{"With IN op on PK", "SELECT color FROM test WHERE k IN [1.1, 1.0] ORDER BY k", false, `[{"color":"red"}]`, nil},
 		{"With NOT IN op", "SELECT color FROM test WHERE color NOT IN ['red', 'purple'] ORDER BY k", false, `[{"color":"blue"}]`, nil},
 		{"With field comparison", "SELECT * FROM test WHERE color < shape", false, `[{"k":1,"color":"red","size":10,"shape":"square"}]`, nil},
@@ -58,14 +64,22 @@ func TestSelectStmt(t *testing.T) {
 		{"With order by asc with limit 2", "SELECT * FROM test ORDER BY color LIMIT 2", false, `[{"k":3,"height":100,"weight":200},{"k":2,"color":"blue","size":10,"weight":100}]`, nil},
 		{"With order by asc with limit 1", "SELECT * FROM test ORDER BY color LIMIT 1", false, `[{"k":3,"height":100,"weight":200}]`, nil},
 		{"With order by asc with offset", "SELECT * FROM test ORDER BY color OFFSET 1", false, `[{"k":2,"color":"blue","size":10,"weight":100},{"k":1,"color":"red","size":10,"shape":"square"}]`, nil},
+		{"With sample percent", "SELECT k FROM test TABLESAMPLE 100 PERCENT", false, `[{"k":1},{"k":2},{"k":3}]`, nil},
+		{"With empty sample", "SELECT k FROM test TABLESAMPLE 0 PERCENT", false, `[]`, nil},
+		{"With sample rows larger than table", "SELECT k FROM test TABLESAMPLE 10 ROWS", false, `[{"k":1},{"k":2},{"k":3}]`, nil},
+		{"With sample percent out of range", "SELECT k FROM test TABLESAMPLE 101 PERCENT", true, ``, nil},
 		{"With order by asc with limit offset", "SELECT * FROM test ORDER BY color LIMIT 1 OFFSET 1", false, `[{"k":2,"color":"blue","size":10,"weight":100}]`, nil},
 		{"With order by desc", "SELECT * FROM test ORDER BY color DESC", false, `[{"k":1,"color":"red","size":10,"shape":"square"},{"k":2,"color":"blue","size":10,"weight":100},{"k":3,"height":100,"weight":200}]`, nil},
 		{"With order by desc numeric", "SELECT * FROM test ORDER BY weight DESC", false, `[{"k":3,"height":100,"weight":200},{"k":2,"color":"blue","size":10,"weight":100},{"k":1,"color":"red","size":10,"shape":"square"}]`, nil},
 		{"With order by desc with limit", "SELECT * FROM test ORDER BY color DESC LIMIT 2", false, `[{"k":1,"color":"red","size":10,"shape":"square"},{"k":2,"color":"blue","size":10,"weight":100}]`, nil},
 		{"With order by desc with offset", "SELECT * FROM test ORDER BY color DESC OFFSET 1", false, `[{"k":2,"color":"blue","size":10,"weight":100},{"k":3,"height":100,"weight":200}]`, nil},
+		{"With order by asc nulls last", "SELECT * FROM test ORDER BY color NULLS LAST", false, `[{"k":2,"color":"blue","size":10,"weight":100},{"k":1,"color":"red","size":10,"shape":"square"},{"k":3,"height":100,"weight":200}]`, nil},
+		{"With order by desc nulls first", "SELECT * FROM test ORDER BY color DESC NULLS FIRST", false, `[{"k":3,"height":100,"weight":200},{"k":1,"color":"red","size":10,"shape":"square"},{"k":2,"color":"blue","size":10,"weight":100}]`, nil},
 		{"With order by desc with limit offset", "SELECT * FROM test ORDER BY color DESC LIMIT 1 OFFSET 1", false, `[{"k":2,"color":"blue","size":10,"weight":100}]`, nil},
 		{"With order by pk asc", "SELECT * FROM test ORDER BY k ASC", false, `[{"k":1,"color":"red","size":10,"shape":"square"},{"k":2,"color":"blue","size":10,"weight":100},{"k":3,"height":100,"weight":200}]`, nil},
 		{"With order by pk desc", "SELECT * FROM test ORDER BY k DESC", false, `[{"k":3,"height":100,"weight":200},{"k":2,"color":"blue","size":10,"weight":100},{"k":1,"color":"red","size":10,"shape":"square"}]`, nil},
+		{"With order by multiple fields", "SELECT k FROM test ORDER BY size DESC, color", false, `[{"k":2},{"k":1},{"k":3}]`, nil},
+		{"With order by multiple fields nulls last", "SELECT k FROM test ORDER BY size, weight DESC NULLS LAST", false, `[{"k":3},{"k":2},{"k":1}]`, nil},
 		{"With order by and where", "SELECT * FROM test WHERE color != 'blue' ORDER BY color DESC LIMIT 1", false, `[{"k":1,"color":"red","size":10,"shape":"square"}]`, nil},
 		{"With limit", "SELECT * FROM test WHERE size = 10 LIMIT 1", false, `[{"k":1,"color":"red","size":10,"shape":"square"}]`, nil},
 		{"With offset", "SELECT *, pk() FROM test WHERE size = 10 OFFSET 1", false, `[{"pk()":2,"color":"blue","size":10,"weight":100,"k":2}]`, nil},
@@ -79,12 +93,25 @@ func TestSelectStmt(t *testing.T) {
 		{"With count", "SELECT COUNT(k) FROM test", false, `[{"COUNT(k)": 3}]`, nil},
 		{"With count wildcard", "SELECT COUNT(*) FROM test", false, `[{"COUNT(*)": 3}]`, nil},
 		{"With multiple counts", "SELECT COUNT(k), COUNT(color) FROM test", false, `[{"COUNT(k)": 3, "COUNT(color)": 2}]`, nil},
+		{"With count and no match", "SELECT COUNT(*), SUM(k) FROM test WHERE k > 10", false, `[{"COUNT(*)": 0, "SUM(k)": null}]`, nil},
+		{"With group by, count and no match", "SELECT COUNT(*) FROM test WHERE k > 10 GROUP BY size", false, `[]`, nil},
 		{"With min", "SELECT MIN(k) FROM test", false, `[{"MIN(k)": 1}]`, nil},
 		{"With multiple mins", "SELECT MIN(color), MIN(weight) FROM test", false, `[{"MIN(color)": "blue", "MIN(weight)": 100}]`, nil},
+		{"With min and max of nulls", "SELECT MIN(foo), MAX(foo) FROM test", false, `[{"MIN(foo)": null, "MAX(foo)": null}]`, nil},
 		{"With max", "SELECT MAX(k) FROM test", false, `[{"MAX(k)": 3}]`, nil},
 		{"With multiple maxs", "SELECT MAX(color), MAX(weight) FROM test", false, `[{"MAX(color)": "red", "MAX(weight)": 200}]`, nil},
 		{"With sum", "SELECT SUM(k) FROM test", false, `[{"SUM(k)": 6}]`, nil},
 		{"With multiple sums", "SELECT SUM(color), SUM(weight) FROM test", false, `[{"SUM(color)": null, "SUM(weight)": 300}]`, nil},
+		{"With approx count distinct", "SELECT APPROX_COUNT_DISTINCT(size), APPROX_COUNT_DISTINCT(k) FROM test", false, `[{"APPROX_COUNT_DISTINCT(size)": 1, "APPROX_COUNT_DISTINCT(k)": 3}]`, nil},
+		{"With approx percentile", "SELECT APPROX_PERCENTILE(k, 0.5), APPROX_PERCENTILE(weight, 1), APPROX_PERCENTILE(color, 0.5) FROM test", false, `[{"APPROX_PERCENTILE(k, 0.5)": 2.0, "APPROX_PERCENTILE(weight, 1)": 200.0, "APPROX_PERCENTILE(color, 0.5)": null}]`, nil},
+		{"With invalid approx percentile", "SELECT APPROX_PERCENTILE(k, 2) FROM test", true, ``, nil},
+		{"With filter", "SELECT COUNT(*) FILTER (WHERE size = 10), SUM(k) FILTER (WHERE color != 'red') AS s, MAX(k) FILTER (WHERE k > 10) FROM test", false, `[{"COUNT(*) FILTER (WHERE size = 10)": 2, "s": 2, "MAX(k) FILTER (WHERE k > 10)": null}]`, nil},
+		{"With group by and filter", "SELECT COUNT(k) FILTER (WHERE weight >= 100) AS n FROM test GROUP BY size", false, `[{"n":1},{"n":1}]`, nil},
+		{"With filter on non aggregate", "SELECT pk() FILTER (WHERE k = 1) FROM test", true, ``, nil},
+		{"With pivot", "SELECT PIVOT(color, k) AS p FROM test GROUP BY size", false, `[{"p":{"red":1,"blue":2}},{"p":{}}]`, nil},
+		{"With keys", "SELECT KEYS(*) AS keys FROM test ORDER BY k", false, `[{"keys":["k","color","size","shape"]},{"keys":["k","color","size","weight"]},{"keys":["k","height","weight"]}]`, nil},
+		{"With values", "SELECT VALUES(*) AS v FROM test WHERE k = 3", false, `[{"v":[3,100,200]}]`, nil},
+		{"With flatten", "SELECT FLATTEN({a: k, b: {c: color}}) AS f FROM test WHERE k = 1", false, `[{"f":{"a":1,"b.c":"red"}}]`, nil},
 		{"With two non existing idents, =", "SELECT * FROM test WHERE z = y", false, `[]`, nil},
 		{"With two non existing idents, >", "SELECT * FROM test WHERE z > y", false, `[]`, nil},
 		{"With two non existing idents, !=", "SELECT * FROM test WHERE z != y", false, `[]`, nil},
@@ -221,4 +248,170 @@ func TestSelectStmt(t *testing.T) {
 		require.NoError(t, err)
 		require.JSONEq(t, `[{"foo": true},{"foo": 1}, {"foo": 2},{"foo": "hello"}]`, buf.String())
 	})
+
+	t.Run("with sample", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, "CREATE TABLE test; CREATE INDEX idx_a ON test(a)")
+		require.NoError(t, err)
+
+		for i := 0; i < 100; i++ {
+			err = db.Exec(ctx, "INSERT INTO test (a) VALUES (?)", i%10)
+			require.NoError(t, err)
+		}
+
+		query := func(q string) string {
+			st, err := db.Query(ctx, q)
+			require.NoError(t, err)
+			defer st.Close()
+
+			var buf bytes.Buffer
+			err = document.IteratorToJSONArray(&buf, st)
+			require.NoError(t, err)
+			return buf.String()
+		}
+
+		count := func(q string) int {
+			d, err := db.QueryDocument(ctx, q)
+			require.NoError(t, err)
+			v, err := d.GetByField("COUNT(*)")
+			require.NoError(t, err)
+			return int(v.V.(int64))
+		}
+
+		require.Equal(t, 10, count("SELECT COUNT(*) FROM test TABLESAMPLE 10 ROWS"))
+		n := count("SELECT COUNT(*) FROM test TABLESAMPLE 50 PERCENT")
+		require.True(t, n > 0 && n < 100)
+
+		// the sample is taken before filtering
+		require.True(t, count("SELECT COUNT(*) FROM test TABLESAMPLE 20 ROWS WHERE a = 1") <= 10)
+
+		// a seed returns the same documents on every execution
+		for _, q := range []string{"SELECT pk() FROM test TABLESAMPLE 10 ROWS REPEATABLE (42)", "SELECT pk() FROM test TABLESAMPLE 10 PERCENT REPEATABLE (42)"} {
+			require.Equal(t, query(q), query(q))
+		}
+	})
+
+	t.Run("with pivot", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `
+			CREATE TABLE attrs;
+			INSERT INTO attrs (id, name, value) VALUES
+				(1, 'color', 'red'), (1, 'size', 10), (2, 'color', 'blue'),
+				(2, 'color', 'green'), (2, 3, true), (2, null, 'ignored');
+			INSERT INTO attrs (id, name) VALUES (3, 'size');
+		`)
+		require.NoError(t, err)
+
+		st, err := db.Query(ctx, "SELECT MIN(id) AS id, PIVOT(name, value) AS attrs FROM attrs GROUP BY id")
+		require.NoError(t, err)
+		defer st.Close()
+
+		var buf bytes.Buffer
+		err = document.IteratorToJSONArray(&buf, st)
+		require.NoError(t, err)
+		require.JSONEq(t, `[
+			{"id":1,"attrs":{"color":"red","size":10}},
+			{"id":2,"attrs":{"color":"green","3":true}},
+			{"id":3,"attrs":{"size":null}}
+		]`, buf.String())
+	})
+
+	t.Run("with approximate aggregates", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, "CREATE TABLE test")
+		require.NoError(t, err)
+
+		err = db.Update(func(tx *genji.Tx) error {
+			for i := 1; i <= 5000; i++ {
+				err := tx.Exec(ctx, "INSERT INTO test (a, b) VALUES (?, ?)", i%1000, float64(i))
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		require.NoError(t, err)
+
+		d, err := db.QueryDocument(ctx, "SELECT APPROX_COUNT_DISTINCT(a) AS n, APPROX_PERCENTILE(b, 0.5) AS p50, APPROX_PERCENTILE(b, 0.99) AS p99 FROM test")
+		require.NoError(t, err)
+
+		var res struct {
+			N   int
+			P50 float64
+			P99 float64
+		}
+		err = document.StructScan(d, &res)
+		require.NoError(t, err)
+
+		require.InEpsilon(t, 1000, res.N, 0.02)
+		require.InEpsilon(t, 2500, res.P50, 0.02)
+		require.InEpsilon(t, 4950, res.P99, 0.01)
+	})
+
+	t.Run("with typed integer index and double operands", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `
+			CREATE TABLE test (a INTEGER, b INTEGER PRIMARY KEY);
+			CREATE INDEX idx_a ON test (a);
+			INSERT INTO test (a, b) VALUES (-3, -3), (-2, -2), (-1, -1), (0, 0), (1, 1), (2, 2), (3, 3), (4, 4);
+		`)
+		require.NoError(t, err)
+
+		tests := []struct {
+			cond     string
+			expected []int64
+		}{
+			{"a IN [1.0, 2.5, 4]", []int64{1, 4}},
+			{"a IN [-0.5, 3.0]", []int64{3}},
+			{"b IN [1.0, 2.5, 4]", []int64{1, 4}},
+			{"a = 1.0", []int64{1}},
+			{"a = 1.5", nil},
+			{"a = -0.0", []int64{0}},
+			{"a > 3.5", []int64{4}},
+			{"a > -2.5", []int64{-2, -1, 0, 1, 2, 3, 4}},
+			{"a > 3.0", []int64{4}},
+			{"a >= 2.5", []int64{3, 4}},
+			{"a >= -2.0", []int64{-2, -1, 0, 1, 2, 3, 4}},
+			{"a < -0.5", []int64{-3, -2, -1}},
+			{"a < 1.5", []int64{-3, -2, -1, 0, 1}},
+			{"a < -2.0", []int64{-3}},
+			{"a <= -1.5", []int64{-3, -2}},
+			{"a <= 1.0", []int64{-3, -2, -1, 0, 1}},
+			{"a > 100000000000000000000.0", nil},
+			{"a < 100000000000000000000.0", []int64{-3, -2, -1, 0, 1, 2, 3, 4}},
+			{"a >= -100000000000000000000.0", []int64{-3, -2, -1, 0, 1, 2, 3, 4}},
+		}
+
+		for _, test := range tests {
+			t.Run(test.cond, func(t *testing.T) {
+				res, err := db.Query(ctx, "SELECT a FROM test WHERE "+test.cond+" ORDER BY a")
+				require.NoError(t, err)
+				defer res.Close()
+
+				var values []int64
+				err = res.Iterate(func(d document.Document) error {
+					v, err := d.GetByField("a")
+					if err != nil {
+						return err
+					}
+					values = append(values, v.V.(int64))
+					return nil
+				})
+				require.NoError(t, err)
+				require.Equal(t, test.expected, values)
+			})
+		}
+	})
 }
@@ -0,0 +1,147 @@
+package query
+
+import (
+	"context"
+	"errors"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// ShowTablesStmt is a DSL that allows creating a SHOW TABLES statement.
+// It returns one document per table, with a single "name" field.
+// System tables are not listed.
+type ShowTablesStmt struct{}
+
+// IsReadOnly always returns true. It implements the Statement interface.
+func (stmt ShowTablesStmt) IsReadOnly() bool {
+	return true
+}
+
+// Run returns the list of tables.
+// It implements the Statement interface.
+func (stmt ShowTablesStmt) Run(ctx context.Context, tx *database.Transaction, args []expr.Param) (Result, error) {
+	var docs []document.Document
+
+	for _, name := range tx.ListTables() {
+		if database.IsSystemTable(name) {
+			continue
+		}
+
+		docs = append(docs, document.NewFieldBuffer().Add("name", document.NewTextValue(name)))
+	}
+
+	return newDocumentsResult(docs), nil
+}
+
+// ShowIndexesStmt is a DSL that allows creating a SHOW INDEXES statement.
+// It returns one document per index, with the fields "name", "table_name",
+// "path" and "unique".
+type ShowIndexesStmt struct {
+	// If set, only the indexes of this table are returned.
+	TableName string
+}
+
+// IsReadOnly always returns true. It implements the Statement interface.
+func (stmt ShowIndexesStmt) IsReadOnly() bool {
+	return true
+}
+
+// Run returns the list of indexes.
+// It implements the Statement interface.
+func (stmt ShowIndexesStmt) Run(ctx context.Context, tx *database.Transaction, args []expr.Param) (Result, error) {
+	if stmt.TableName != "" {
+		// ensure the table exists
+		_, err := tx.GetTable(stmt.TableName)
+		if err != nil {
+			return Result{}, err
+		}
+	}
+
+	indexes, err := tx.ListIndexes()
+	if err != nil {
+		return Result{}, err
+	}
+
+	var docs []document.Document
+	for _, idx := range indexes {
+		if stmt.TableName != "" && idx.TableName != stmt.TableName {
+			continue
+		}
+
+		docs = append(docs, document.NewFieldBuffer().
+			Add("name", document.NewTextValue(idx.IndexName)).
+			Add("table_name", document.NewTextValue(idx.TableName)).
+			Add("path", document.NewTextValue(idx.Path.String())).
+			Add("unique", document.NewBoolValue(idx.Unique)))
+	}
+
+	return newDocumentsResult(docs), nil
+}
+
+// DescribeStmt is a DSL that allows creating a DESCRIBE statement.
+// It returns one document per field constraint of the table, with the fields
+// "path", "type", "primary_key" and "not_null".
+// Fields without a type have a null "type".
+type DescribeStmt struct {
+	TableName string
+}
+
+// IsReadOnly always returns true. It implements the Statement interface.
+func (stmt DescribeStmt) IsReadOnly() bool {
+	return true
+}
+
+// Run returns the field constraints of the table.
+// It implements the Statement interface.
+func (stmt DescribeStmt) Run(ctx context.Context, tx *database.Transaction, args []expr.Param) (Result, error) {
+	if stmt.TableName == "" {
+		return Result{}, errors.New("missing table name")
+	}
+
+	t, err := tx.GetTable(stmt.TableName)
+	if err != nil {
+		return Result{}, err
+	}
+
+	info, err := t.Info()
+	if err != nil {
+		return Result{}, err
+	}
+
+	var docs []document.Document
+	for _, fc := range info.FieldConstraints {
+		typ := document.NewNullValue()
+		if fc.Type != 0 {
+			typ = document.NewTextValue(fc.Type.String())
+		}
+
+		check := document.NewNullValue()
+		if fc.Check != nil {
+			check = document.NewTextValue(fc.Check.String())
+		}
+
+		defaultValue := document.NewNullValue()
+		if fc.DefaultValue != nil {
+			defaultValue = document.NewTextValue(fc.DefaultValue.String())
+		}
+
+		docs = append(docs, document.NewFieldBuffer().
+			Add("path", document.NewTextValue(fc.Path.String())).
+			Add("type", typ).
+			Add("primary_key", document.NewBoolValue(fc.IsPrimaryKey)).
+			Add("not_null", document.NewBoolValue(fc.IsNotNull)).
+			Add("unique", document.NewBoolValue(fc.IsUnique)).
+			Add("default", defaultValue).
+			Add("check", check))
+	}
+
+	return newDocumentsResult(docs), nil
+}
+
+func newDocumentsResult(docs []document.Document) Result {
+	return Result{
+		Stream: document.NewStream(document.NewIterator(docs...)),
+	}
+}
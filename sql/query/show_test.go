@@ -0,0 +1,70 @@
+package query_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShow(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+		fails    bool
+	}{
+		{"Tables", `SHOW TABLES`, `[{"name": "test1"}, {"name": "test2"}]`, false},
+		{"Indexes", `SHOW INDEXES`, `[
+			{"name": "idx_test1_a", "table_name": "test1", "path": "a", "unique": false},
+			{"name": "idx_test2_b", "table_name": "test2", "path": "b.c", "unique": true}
+		]`, false},
+		{"Indexes from", `SHOW INDEXES FROM test2`, `[{"name": "idx_test2_b", "table_name": "test2", "path": "b.c", "unique": true}]`, false},
+		{"Indexes from unknown", `SHOW INDEXES FROM foo`, ``, true},
+		{"Describe", `DESCRIBE test1`, `[
+			{"path": "a", "type": "integer", "primary_key": true, "not_null": false, "unique": false, "default": null, "check": null},
+			{"path": "b", "type": null, "primary_key": false, "not_null": true, "unique": false, "default": "'x'", "check": "b != ''"}
+		]`, false},
+		{"Describe without constraints", `DESCRIBE test2`, `[]`, false},
+		{"Describe unknown", `DESCRIBE foo`, ``, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, err := genji.Open(":memory:")
+			require.NoError(t, err)
+			defer db.Close()
+
+			err = db.Exec(ctx, `
+				CREATE TABLE test1 (a INTEGER PRIMARY KEY, b NOT NULL DEFAULT 'x' CHECK (b != ''));
+				CREATE TABLE test2;
+				CREATE INDEX idx_test1_a ON test1(a);
+				CREATE UNIQUE INDEX idx_test2_b ON test2(b.c);
+			`)
+			require.NoError(t, err)
+
+			res, err := db.Query(ctx, test.query)
+			if test.fails {
+				if err == nil {
+					err = res.Iterate(func(d document.Document) error { return nil })
+				}
+				require.Error(t, err)
+				require.Equal(t, database.CodeUndefinedTable, database.CodeOf(err))
+				return
+			}
+			require.NoError(t, err)
+			defer res.Close()
+
+			var buf bytes.Buffer
+			err = document.IteratorToJSONArray(&buf, res)
+			require.NoError(t, err)
+			require.JSONEq(t, test.expected, buf.String())
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package query_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/document"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubquery(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		query    string
+		fails    bool
+		expected string
+		params   []interface{}
+	}{
+		{"In", "SELECT name FROM authors WHERE id IN (SELECT author FROM books)", false, `[{"name":"a"},{"name":"b"}]`, nil},
+		{"Not in", "SELECT name FROM authors WHERE id NOT IN (SELECT author FROM books)", false, `[{"name":"c"}]`, nil},
+		{"In correlated", "SELECT name FROM authors WHERE 9 IN (SELECT score FROM books WHERE author = authors.id)", false, `[{"name":"a"}]`, nil},
+		{"In params", "SELECT name FROM authors WHERE id IN (SELECT author FROM books WHERE score > ?)", false, `[{"name":"a"}]`, []interface{}{4}},
+		{"Scalar", "SELECT name FROM authors WHERE id = (SELECT author FROM books WHERE title = 'b1')", false, `[{"name":"b"}]`, nil},
+		{"Scalar aggregate", "SELECT name FROM authors WHERE id < (SELECT MAX(author) FROM books)", false, `[{"name":"a"}]`, nil},
+		{"Scalar projection", "SELECT name, (SELECT MAX(score) FROM books WHERE author = authors.id) AS best FROM authors", false, `[{"name":"a","best":9},{"name":"b","best":3},{"name":"c","best":null}]`, nil},
+		{"Scalar no rows", "SELECT name, (SELECT title FROM books WHERE author = authors.id AND score > 8) AS t FROM authors", false, `[{"name":"a","t":"a2"},{"name":"b","t":null},{"name":"c","t":null}]`, nil},
+		{"Scalar multiple rows", "SELECT name FROM authors WHERE id = (SELECT author FROM books)", true, ``, nil},
+		{"Scalar multiple fields", "SELECT name FROM authors WHERE id = (SELECT author, title FROM books WHERE title = 'b1')", true, ``, nil},
+		{"Derived", "SELECT title FROM (SELECT * FROM books WHERE score > 4) AS good", false, `[{"title":"a1"},{"title":"a2"}]`, nil},
+		{"Derived without AS", "SELECT t FROM (SELECT title AS t FROM books) b WHERE t != 'a1'", false, `[{"t":"a2"},{"t":"b1"}]`, nil},
+		{"Derived aggregate", "SELECT MAX(n) AS m FROM (SELECT COUNT(*) AS n FROM books GROUP BY author) AS counts", false, `[{"m":2}]`, nil},
+		{"Derived wildcard", "SELECT * FROM (SELECT name FROM authors WHERE id IN (SELECT author FROM books)) AS a ORDER BY name DESC", false, `[{"name":"b"},{"name":"a"}]`, nil},
+		{"Derived params", "SELECT title FROM (SELECT * FROM books WHERE score < ?) AS bad", false, `[{"title":"b1"}]`, []interface{}{4}},
+		{"Derived without alias", "SELECT * FROM (SELECT * FROM books)", true, ``, nil},
+		{"Unknown table", "SELECT name FROM authors WHERE id IN (SELECT a FROM unknown)", true, ``, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, err := genji.Open(":memory:")
+			require.NoError(t, err)
+			defer db.Close()
+
+			err = db.Exec(ctx, `
+				CREATE TABLE authors (id INTEGER PRIMARY KEY);
+				CREATE TABLE books;
+				INSERT INTO authors (id, name) VALUES (1, 'a'), (2, 'b'), (3, 'c');
+				INSERT INTO books (author, title, score) VALUES
+					(1, 'a1', 5), (1, 'a2', 9), (2, 'b1', 3);
+			`)
+			require.NoError(t, err)
+
+			st, err := db.Query(ctx, test.query, test.params...)
+			if test.fails {
+				if err == nil {
+					err = document.IteratorToJSONArray(new(bytes.Buffer), st)
+					st.Close()
+				}
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			defer st.Close()
+
+			var buf bytes.Buffer
+			err = document.IteratorToJSONArray(&buf, st)
+			require.NoError(t, err)
+			require.JSONEq(t, test.expected, buf.String())
+		})
+	}
+}
@@ -2,12 +2,20 @@ package query
 
 import (
 	"context"
-	"errors"
 
 	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/sql/query/expr"
 )
 
+var (
+	errTxInProgress  = database.NewError(database.CodeActiveTransaction, "cannot begin a transaction within a transaction")
+	errNoTxRollback  = database.NewError(database.CodeNoActiveTransaction, "cannot rollback with no active transaction")
+	errNoTxCommit    = database.NewError(database.CodeNoActiveTransaction, "cannot commit with no active transaction")
+	errNoTxSavepoint = database.NewError(database.CodeNoActiveTransaction, "savepoints can only be used within a transaction")
+	errKillID        = database.NewError(database.CodeDatatypeMismatch, "the id of the transaction to kill must be an integer")
+)
+
 // BeginStmt is a statement that creates a new transaction.
 type BeginStmt struct {
 	Writable bool
@@ -15,13 +23,14 @@ type BeginStmt struct {
 
 func (stmt BeginStmt) alterQuery(db *database.Database, q *Query) error {
 	if q.tx != nil {
-		return errors.New("cannot begin a transaction within a transaction")
+		return errTxInProgress
 	}
 
 	var err error
 	q.tx, err = db.BeginTx(&database.TxOptions{
-		ReadOnly: !stmt.Writable,
-		Attached: true,
+		ReadOnly:  !stmt.Writable,
+		Attached:  true,
+		Statement: q.Source,
 	})
 	q.autoCommit = false
 	return err
@@ -32,7 +41,7 @@ func (stmt BeginStmt) IsReadOnly() bool {
 }
 
 func (stmt BeginStmt) Run(ctx context.Context, tx *database.Transaction, args []expr.Param) (Result, error) {
-	return Result{}, errors.New("cannot begin a transaction within a transaction")
+	return Result{}, errTxInProgress
 }
 
 // RollbackStmt is a statement that rollbacks the current active transaction.
@@ -40,14 +49,16 @@ type RollbackStmt struct{}
 
 func (stmt RollbackStmt) alterQuery(db *database.Database, q *Query) error {
 	if q.tx == nil || q.autoCommit == true {
-		return errors.New("cannot rollback with no active transaction")
+		return errNoTxRollback
 	}
 
 	err := q.tx.Rollback()
 	if err != nil {
 		return err
 	}
+	// the following statements run in their own transaction
 	q.tx = nil
+	q.autoCommit = true
 	return nil
 }
 
@@ -56,7 +67,7 @@ func (stmt RollbackStmt) IsReadOnly() bool {
 }
 
 func (stmt RollbackStmt) Run(ctx context.Context, tx *database.Transaction, args []expr.Param) (Result, error) {
-	return Result{}, errors.New("cannot rollback with no active transaction")
+	return Result{}, errNoTxRollback
 }
 
 // CommitStmt is a statement that commits the current active transaction.
@@ -64,14 +75,22 @@ type CommitStmt struct{}
 
 func (stmt CommitStmt) alterQuery(db *database.Database, q *Query) error {
 	if q.tx == nil || q.autoCommit == true {
-		return errors.New("cannot commit with no active transaction")
+		return errNoTxCommit
 	}
 
-	err := q.tx.Commit()
+	var err error
+	// read-only transactions have nothing to commit
+	if q.tx.Writable() {
+		err = q.tx.Commit()
+	} else {
+		err = q.tx.Rollback()
+	}
 	if err != nil {
 		return err
 	}
+	// the following statements run in their own transaction
 	q.tx = nil
+	q.autoCommit = true
 	return nil
 }
 
@@ -80,5 +99,87 @@ func (stmt CommitStmt) IsReadOnly() bool {
 }
 
 func (stmt CommitStmt) Run(ctx context.Context, tx *database.Transaction, args []expr.Param) (Result, error) {
-	return Result{}, errors.New("cannot commit with no active transaction")
+	return Result{}, errNoTxCommit
+}
+
+// SavepointStmt is a statement that creates a named savepoint in the current transaction.
+type SavepointStmt struct {
+	Name string
+}
+
+// IsReadOnly always returns false. It implements the Statement interface.
+func (stmt SavepointStmt) IsReadOnly() bool {
+	return false
+}
+
+// Run creates the savepoint. It implements the Statement interface.
+func (stmt SavepointStmt) Run(ctx context.Context, tx *database.Transaction, args []expr.Param) (Result, error) {
+	return Result{}, tx.CreateSavepoint(stmt.Name)
+}
+
+// RollbackToSavepointStmt is a statement that cancels the changes made to the current transaction
+// since the creation of a savepoint. The savepoint remains and the transaction is still usable.
+type RollbackToSavepointStmt struct {
+	Name string
+}
+
+// IsReadOnly always returns false. It implements the Statement interface.
+func (stmt RollbackToSavepointStmt) IsReadOnly() bool {
+	return false
+}
+
+// Run rolls back to the savepoint. It implements the Statement interface.
+func (stmt RollbackToSavepointStmt) Run(ctx context.Context, tx *database.Transaction, args []expr.Param) (Result, error) {
+	return Result{}, tx.RollbackToSavepoint(stmt.Name)
+}
+
+// ReleaseSavepointStmt is a statement that destroys a savepoint of the current transaction,
+// keeping the changes made since its creation.
+type ReleaseSavepointStmt struct {
+	Name string
+}
+
+// IsReadOnly always returns false. It implements the Statement interface.
+func (stmt ReleaseSavepointStmt) IsReadOnly() bool {
+	return false
+}
+
+// Run releases the savepoint. It implements the Statement interface.
+func (stmt ReleaseSavepointStmt) Run(ctx context.Context, tx *database.Transaction, args []expr.Param) (Result, error) {
+	return Result{}, tx.ReleaseSavepoint(stmt.Name)
+}
+
+// isSavepointStmt reports whether stmt manages the savepoints of the transaction,
+// in which case it must not be run within a savepoint of its own.
+func isSavepointStmt(stmt Statement) bool {
+	switch stmt.(type) {
+	case SavepointStmt, RollbackToSavepointStmt, ReleaseSavepointStmt:
+		return true
+	}
+
+	return false
+}
+
+// KillStmt is a statement that kills an open transaction, which is rolled back
+// the next time it is used. Open transactions are listed by the __genji_transactions table.
+type KillStmt struct {
+	TransactionID expr.Expr
+}
+
+// IsReadOnly always returns true. It implements the Statement interface.
+func (stmt KillStmt) IsReadOnly() bool {
+	return true
+}
+
+// Run kills the transaction. It implements the Statement interface.
+func (stmt KillStmt) Run(ctx context.Context, tx *database.Transaction, args []expr.Param) (Result, error) {
+	v, err := stmt.TransactionID.Eval(expr.EvalStack{Tx: tx, Params: args})
+	if err != nil {
+		return Result{}, err
+	}
+	if v.Type != document.IntegerValue {
+		return Result{}, errKillID
+	}
+
+	return Result{}, tx.DB().KillTransaction(v.V.(int64))
 }
@@ -2,9 +2,12 @@ package query_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
 	"github.com/stretchr/testify/require"
 )
 
@@ -24,6 +27,15 @@ func TestTransactionRun(t *testing.T) {
 		{"Multiple execs/ Double", []string{`BEGIN`, `COMMIT`, `BEGIN`, `COMMIT`}, false},
 		{"Multiple execs/ Begin then begin", []string{`BEGIN`, `BEGIN`}, true},
 		{"Multiple execs/ Nested", []string{`BEGIN`, `BEGIN`, `COMMIT`, `COMMIT`}, true},
+		{"Multiple execs/ Read only then commit", []string{`BEGIN READ ONLY`, `SELECT 1`, `COMMIT`}, false},
+		{"Multiple execs/ Read only then rollback", []string{`BEGIN READ ONLY`, `ROLLBACK`}, false},
+		{"Multiple execs/ Commit without begin", []string{`COMMIT`}, true},
+		{"Multiple execs/ Rollback without begin", []string{`ROLLBACK`}, true},
+		{"Multiple execs/ Savepoints", []string{`BEGIN`, `SAVEPOINT a`, `SAVEPOINT b`, `ROLLBACK TO a`, `RELEASE a`, `COMMIT`}, false},
+		{"Multiple execs/ Savepoint without begin", []string{`SAVEPOINT a`}, true},
+		{"Multiple execs/ Unknown savepoint", []string{`BEGIN`, `SAVEPOINT a`, `RELEASE a`, `ROLLBACK TO a`}, true},
+		{"Multiple execs/ Savepoint in read only transaction", []string{`BEGIN READ ONLY`, `SAVEPOINT a`}, true},
+		{"Same exec/ Savepoint after commit", []string{`BEGIN; SAVEPOINT a; COMMIT; SAVEPOINT a`}, true},
 	}
 
 	for _, test := range tests {
@@ -49,3 +61,189 @@ func TestTransactionRun(t *testing.T) {
 		})
 	}
 }
+
+func TestStatementAtomicity(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec(ctx, "CREATE TABLE test; CREATE UNIQUE INDEX idx_a ON test(a); INSERT INTO test (a) VALUES (1)")
+	require.NoError(t, err)
+
+	err = db.Exec(ctx, "BEGIN")
+	require.NoError(t, err)
+
+	// the second document violates the unique index,
+	// the first one must not be inserted.
+	err = db.Exec(ctx, "INSERT INTO test (a) VALUES (2), (1)")
+	require.Error(t, err)
+
+	// the transaction is still usable
+	err = db.Exec(ctx, "INSERT INTO test (a) VALUES (3)")
+	require.NoError(t, err)
+	err = db.Exec(ctx, "CREATE TABLE foo")
+	require.NoError(t, err)
+	err = db.Exec(ctx, "CREATE TABLE foo")
+	require.Error(t, err)
+	err = db.Exec(ctx, "COMMIT")
+	require.NoError(t, err)
+
+	res, err := db.Query(ctx, "SELECT a FROM test")
+	require.NoError(t, err)
+	var as []int
+	err = res.Iterate(func(d document.Document) error {
+		var a int
+		err := document.Scan(d, &a)
+		as = append(as, a)
+		return err
+	})
+	require.NoError(t, err)
+	require.NoError(t, res.Close())
+	require.Equal(t, []int{1, 3}, as)
+
+	err = db.Exec(ctx, "SELECT * FROM foo")
+	require.NoError(t, err)
+}
+
+func TestTransactionRollback(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec(ctx, "CREATE TABLE test; INSERT INTO test (a) VALUES (1)")
+	require.NoError(t, err)
+
+	err = db.Exec(ctx, "BEGIN")
+	require.NoError(t, err)
+	err = db.Exec(ctx, "INSERT INTO test (a) VALUES (2)")
+	require.NoError(t, err)
+	err = db.Exec(ctx, "CREATE TABLE foo")
+	require.NoError(t, err)
+
+	// the changes are visible within the transaction
+	d, err := db.QueryDocument(ctx, "SELECT COUNT(*) AS n FROM test")
+	require.NoError(t, err)
+	var n int
+	err = document.Scan(d, &n)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	err = db.Exec(ctx, "ROLLBACK")
+	require.NoError(t, err)
+
+	d, err = db.QueryDocument(ctx, "SELECT COUNT(*) AS n FROM test")
+	require.NoError(t, err)
+	err = document.Scan(d, &n)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	err = db.Exec(ctx, "SELECT * FROM foo")
+	require.Equal(t, database.ErrTableNotFound, err)
+
+	// read-only transactions reject writes
+	err = db.Exec(ctx, "BEGIN READ ONLY")
+	require.NoError(t, err)
+	err = db.Exec(ctx, "INSERT INTO test (a) VALUES (3)")
+	require.Error(t, err)
+	err = db.Exec(ctx, "COMMIT")
+	require.NoError(t, err)
+}
+
+func TestSavepoints(t *testing.T) {
+	ctx := context.Background()
+
+	count := func(t *testing.T, db *genji.DB) int {
+		d, err := db.QueryDocument(ctx, "SELECT COUNT(*) AS n FROM test")
+		require.NoError(t, err)
+		var n int
+		err = document.Scan(d, &n)
+		require.NoError(t, err)
+		return n
+	}
+
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec(ctx, "CREATE TABLE test; INSERT INTO test (a) VALUES (1)")
+	require.NoError(t, err)
+
+	err = db.Exec(ctx, "BEGIN; INSERT INTO test (a) VALUES (2); SAVEPOINT a")
+	require.NoError(t, err)
+	err = db.Exec(ctx, "INSERT INTO test (a) VALUES (3); CREATE TABLE foo; SAVEPOINT b")
+	require.NoError(t, err)
+	err = db.Exec(ctx, "INSERT INTO test (a) VALUES (4)")
+	require.NoError(t, err)
+	require.Equal(t, 4, count(t, db))
+
+	err = db.Exec(ctx, "ROLLBACK TO SAVEPOINT b")
+	require.NoError(t, err)
+	require.Equal(t, 3, count(t, db))
+
+	// failing to roll back to an unknown savepoint leaves the transaction usable
+	err = db.Exec(ctx, "ROLLBACK TO c")
+	require.Equal(t, database.ErrSavepointNotFound, err)
+
+	err = db.Exec(ctx, "ROLLBACK TO a")
+	require.NoError(t, err)
+	require.Equal(t, 2, count(t, db))
+	err = db.Exec(ctx, "SELECT * FROM foo")
+	require.Equal(t, database.ErrTableNotFound, err)
+
+	// b was destroyed by rolling back to a
+	err = db.Exec(ctx, "RELEASE b")
+	require.Equal(t, database.ErrSavepointNotFound, err)
+
+	err = db.Exec(ctx, "INSERT INTO test (a) VALUES (5); RELEASE SAVEPOINT a; COMMIT")
+	require.NoError(t, err)
+	require.Equal(t, 3, count(t, db))
+
+	err = db.Exec(ctx, "SAVEPOINT a")
+	require.Equal(t, database.CodeNoActiveTransaction, database.CodeOf(err))
+
+	// savepoints can be used within transactions created with the Go API
+	tx, err := db.Begin(true)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	err = tx.Exec(ctx, "SAVEPOINT a; DELETE FROM test; ROLLBACK TO a; INSERT INTO test (a) VALUES (6)")
+	require.NoError(t, err)
+	err = tx.Commit()
+	require.NoError(t, err)
+	require.Equal(t, 4, count(t, db))
+}
+
+func TestKill(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	tx, err := db.Begin(false)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	txs := db.DB.Transactions()
+	require.Len(t, txs, 1)
+
+	err = db.Exec(ctx, "KILL ?", txs[0].ID)
+	require.NoError(t, err)
+
+	require.True(t, db.DB.Transactions()[0].Killed)
+
+	// the transaction is rolled back the next time it is used
+	_, err = tx.QueryDocument(ctx, "SELECT 1")
+	require.True(t, errors.Is(err, database.ErrTransactionKilled))
+	require.Empty(t, db.DB.Transactions())
+
+	err = db.Exec(ctx, "KILL ?", txs[0].ID)
+	require.True(t, errors.Is(err, database.ErrTransactionNotFound))
+
+	err = db.Exec(ctx, "KILL 'a'")
+	require.Equal(t, database.CodeDatatypeMismatch, database.CodeOf(err))
+}
@@ -126,4 +126,83 @@ func TestUpdateStmt(t *testing.T) {
 			require.JSONEq(t, tt.expected, buf.String())
 		}
 	})
+
+	t.Run("with indexes", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `
+			CREATE TABLE foo;
+			CREATE INDEX idx_a ON foo (a);
+			CREATE INDEX idx_b_c ON foo (b.c);
+			CREATE INDEX idx_b_d ON foo (b.d);
+			CREATE INDEX idx_e ON foo (e[0]);
+			INSERT INTO foo (a, b, e) VALUES (1, {c: 1, d: 1}, [1]), (2, {c: 2, d: 2}, [2]);
+		`)
+		require.NoError(t, err)
+
+		// only the indexes of the modified paths are updated
+		for _, q := range []string{
+			`UPDATE foo SET b.c = 10`,
+			`UPDATE foo SET b = {d: 20} WHERE a = 1`,
+			`UPDATE foo SET e[0] = 30, a = 3 WHERE b.d = 2`,
+			`UPDATE foo UNSET b`,
+		} {
+			err = db.Exec(ctx, q)
+			require.NoError(t, err, q)
+
+			err = db.View(func(tx *genji.Tx) error {
+				tb, err := tx.GetTable("foo")
+				require.NoError(t, err)
+				for _, name := range []string{"idx_a", "idx_b_c", "idx_b_d", "idx_e"} {
+					idx, err := tx.GetIndex(name)
+					require.NoError(t, err)
+					report, err := idx.Verify(tb)
+					require.NoError(t, err)
+					require.True(t, report.OK(), "%s after %s: %+v", name, q, report)
+				}
+				return nil
+			})
+			require.NoError(t, err)
+		}
+
+		st, err := db.Query(ctx, "SELECT * FROM foo WHERE e[0] = 30")
+		require.NoError(t, err)
+		defer st.Close()
+
+		var buf bytes.Buffer
+		err = document.IteratorToJSONArray(&buf, st)
+		require.NoError(t, err)
+		require.JSONEq(t, `[{"a": 3, "e": [30]}]`, buf.String())
+	})
+}
+
+func TestUpdatePartitionedTable(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec(ctx, `
+		CREATE TABLE test (k INTEGER PRIMARY KEY, v TEXT) PARTITION BY RANGE (k) VALUES (20);
+		INSERT INTO test (k, v) VALUES (10, 'a'), (25, 'b');
+	`)
+	require.NoError(t, err)
+
+	// documents can't be moved to another partition
+	err = db.Exec(ctx, "UPDATE test SET k = 30 WHERE k = 10")
+	require.Error(t, err)
+
+	err = db.Exec(ctx, "UPDATE test SET v = 'c' WHERE k = 10")
+	require.NoError(t, err)
+
+	for _, q := range []string{"SELECT * FROM test WHERE k = 10", "SELECT * FROM test WHERE k < 20"} {
+		d, err := db.QueryDocument(ctx, q)
+		require.NoError(t, err)
+		data, err := document.MarshalJSON(d)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"k": 10, "v": "c"}`, string(data))
+	}
 }
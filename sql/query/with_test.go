@@ -0,0 +1,78 @@
+package query_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithStmt(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		query    string
+		fails    bool
+		expected string
+		params   []interface{}
+	}{
+		{"Non recursive", "WITH red AS (SELECT * FROM test WHERE color = 'red') SELECT k FROM red", false, `[{"k":1}]`, nil},
+		{"Shadowing a table", "WITH test AS (SELECT k FROM test WHERE k > 1) SELECT * FROM test", false, `[{"k":2},{"k":3}]`, nil},
+		{"Multiple", "WITH a AS (SELECT k FROM test), b AS (SELECT k * 2 AS k FROM a) SELECT * FROM b ORDER BY k DESC", false, `[{"k":6},{"k":4},{"k":2}]`, nil},
+		{"Recursive", "WITH RECURSIVE cnt AS (SELECT 1 AS n UNION ALL SELECT n + 1 AS n FROM cnt WHERE n < 5) SELECT n FROM cnt", false, `[{"n":1},{"n":2},{"n":3},{"n":4},{"n":5}]`, nil},
+		{"Recursive from table", "WITH RECURSIVE s AS (SELECT k, size FROM test WHERE size = 10 UNION ALL SELECT k, size / 2 AS size FROM s WHERE size > 3) SELECT * FROM s ORDER BY size", false, `[{"k":1,"size":2},{"k":2,"size":2},{"k":1,"size":5},{"k":2,"size":5},{"k":1,"size":10},{"k":2,"size":10}]`, nil},
+		{"Recursive with params", "WITH RECURSIVE cnt AS (SELECT k AS n FROM test WHERE k = ? UNION ALL SELECT n + 1 AS n FROM cnt WHERE n < ?) SELECT COUNT(*) AS c FROM cnt", false, `[{"c":4}]`, []interface{}{2, 5}},
+		{"Union discards duplicates", "WITH RECURSIVE c AS (SELECT 1 AS n UNION SELECT n % 3 + 1 AS n FROM c) SELECT n FROM c", false, `[{"n":1},{"n":2},{"n":3}]`, nil},
+		{"Recursion limit", "WITH RECURSIVE c AS (SELECT 1 AS n UNION ALL SELECT n + 1 AS n FROM c) SELECT n FROM c", true, ``, nil},
+		{"Unknown table", "WITH a AS (SELECT * FROM unknown) SELECT * FROM a", true, ``, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, err := genji.OpenWithOptions(":memory:", &genji.Options{MaxRecursion: 10})
+			require.NoError(t, err)
+			defer db.Close()
+
+			err = db.Exec(ctx, `
+				CREATE TABLE test (k INTEGER PRIMARY KEY);
+				INSERT INTO test (k, color, size) VALUES (1, 'red', 10), (2, 'blue', 10), (3, 'green', 1);
+			`)
+			require.NoError(t, err)
+
+			st, err := db.Query(ctx, test.query, test.params...)
+			defer st.Close()
+			if test.fails {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			err = document.IteratorToJSONArray(&buf, st)
+			require.NoError(t, err)
+			require.JSONEq(t, test.expected, buf.String())
+		})
+	}
+
+	t.Run("Recursion limit error", func(t *testing.T) {
+		db, err := genji.OpenWithOptions(":memory:", &genji.Options{MaxRecursion: 3})
+		require.NoError(t, err)
+		defer db.Close()
+
+		q := "WITH RECURSIVE c AS (SELECT 1 AS n UNION ALL SELECT n + 1 AS n FROM c WHERE n < ?) SELECT n FROM c"
+
+		// the last iteration returns no document
+		err = db.Exec(ctx, q, 3)
+		require.NoError(t, err)
+
+		err = db.Exec(ctx, q, 4)
+		require.True(t, errors.Is(err, database.ErrRecursionLimitExceeded))
+		require.Equal(t, database.CodeProgramLimitExceeded, database.CodeOf(err))
+	})
+}
@@ -0,0 +1,117 @@
+package query
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"io"
+
+	"github.com/genjidb/genji/document"
+)
+
+// WriteJSON writes the documents of the result to w as a JSON array.
+// Each document is written to w as soon as it is produced,
+// which allows serving large results without holding them in memory.
+func (r *Result) WriteJSON(w io.Writer) error {
+	_, err := io.WriteString(w, "[")
+	if err != nil {
+		return err
+	}
+
+	first := true
+	err = r.Iterate(func(d document.Document) error {
+		data, err := document.MarshalJSON(d)
+		if err != nil {
+			return err
+		}
+
+		if !first {
+			data = append([]byte(", "), data...)
+		}
+		first = false
+
+		_, err = w.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "]")
+	return err
+}
+
+// WriteCSV writes the documents of the result to w as CSV, one record per document.
+// The header is made of the fields of the first document. Fields of the following
+// documents are written in the same order, missing fields are left empty and
+// fields that are not part of the header are ignored.
+// Null values are written as empty strings, blobs are encoded in base64 and
+// documents and arrays are encoded in JSON.
+// Each record is flushed to w as soon as it is produced.
+func (r *Result) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	var header []string
+	var record []string
+	err := r.Iterate(func(d document.Document) error {
+		if header == nil {
+			header = []string{}
+			err := d.Iterate(func(field string, v document.Value) error {
+				header = append(header, field)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			err = cw.Write(header)
+			if err != nil {
+				return err
+			}
+			record = make([]string, len(header))
+		}
+
+		for i, field := range header {
+			v, err := d.GetByField(field)
+			if err == document.ErrFieldNotFound {
+				record[i] = ""
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			record[i], err = csvValue(v)
+			if err != nil {
+				return err
+			}
+		}
+
+		err := cw.Write(record)
+		if err != nil {
+			return err
+		}
+
+		cw.Flush()
+		return cw.Error()
+	})
+	if err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvValue(v document.Value) (string, error) {
+	switch v.Type {
+	case document.NullValue:
+		return "", nil
+	case document.TextValue:
+		return v.V.(string), nil
+	case document.BlobValue:
+		return base64.StdEncoding.EncodeToString(v.V.([]byte)), nil
+	}
+
+	data, err := v.MarshalJSON()
+	return string(data), err
+}
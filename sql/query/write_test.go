@@ -0,0 +1,57 @@
+package query_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultWrite(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec(ctx, `CREATE TABLE test;
+		INSERT INTO test (a, b, c) VALUES (1, 'foo, "bar"', {d: [1, 2]});
+		INSERT INTO test (a, c) VALUES (2.5, NULL);
+		INSERT INTO test (b, a, e) VALUES ('baz', 3, true)`)
+	require.NoError(t, err)
+
+	t.Run("JSON", func(t *testing.T) {
+		res, err := db.Query(ctx, "SELECT a, b FROM test")
+		require.NoError(t, err)
+		defer res.Close()
+
+		var buf bytes.Buffer
+		err = res.WriteJSON(&buf)
+		require.NoError(t, err)
+		require.JSONEq(t, `[{"a": 1, "b": "foo, \"bar\""}, {"a": 2.5, "b": null}, {"a": 3, "b": "baz"}]`, buf.String())
+	})
+
+	t.Run("JSON empty", func(t *testing.T) {
+		res, err := db.Query(ctx, "SELECT * FROM test WHERE a > 10")
+		require.NoError(t, err)
+		defer res.Close()
+
+		var buf bytes.Buffer
+		err = res.WriteJSON(&buf)
+		require.NoError(t, err)
+		require.Equal(t, `[]`, buf.String())
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		res, err := db.Query(ctx, "SELECT * FROM test")
+		require.NoError(t, err)
+		defer res.Close()
+
+		var buf bytes.Buffer
+		err = res.WriteCSV(&buf)
+		require.NoError(t, err)
+		require.Equal(t, "a,b,c\n1,\"foo, \"\"bar\"\"\",\"{\"\"d\"\": [1, 2]}\"\n2.5,,\n3,baz,\n", buf.String())
+	})
+}
@@ -2,6 +2,7 @@ package scanner_test
 
 import (
 	"reflect"
+	"sort"
 	"strings"
 	"testing"
 
@@ -121,6 +122,7 @@ func TestScanner_Scan(t *testing.T) {
 		{s: `ASC`, tok: scanner.ASC, raw: `ASC`},
 		{s: `BY`, tok: scanner.BY, raw: `BY`},
 		{s: `BEGIN`, tok: scanner.BEGIN, raw: `BEGIN`},
+		{s: `CALL`, tok: scanner.CALL, raw: `CALL`},
 		{s: `CAST`, tok: scanner.CAST, raw: `CAST`},
 		{s: `COMMIT`, tok: scanner.COMMIT, raw: `COMMIT`},
 		{s: `CREATE`, tok: scanner.CREATE, raw: `CREATE`},
@@ -132,15 +134,19 @@ func TestScanner_Scan(t *testing.T) {
 		{s: `GROUP`, tok: scanner.GROUP, raw: `GROUP`},
 		{s: `INSERT`, tok: scanner.INSERT, raw: `INSERT`},
 		{s: `INTO`, tok: scanner.INTO, raw: `INTO`},
+		{s: `KILL`, tok: scanner.KILL, raw: `KILL`},
 		{s: `LIMIT`, tok: scanner.LIMIT, raw: `LIMIT`},
 		{s: `ONLY`, tok: scanner.ONLY, raw: `ONLY`},
 		{s: `OFFSET`, tok: scanner.OFFSET, raw: `OFFSET`},
 		{s: `ORDER`, tok: scanner.ORDER, raw: `ORDER`},
+		{s: `PRAGMA`, tok: scanner.PRAGMA, raw: `PRAGMA`},
 		{s: `PRIMARY`, tok: scanner.PRIMARY, raw: `PRIMARY`},
 		{s: `READ`, tok: scanner.READ, raw: `READ`},
 		{s: `REINDEX`, tok: scanner.REINDEX, raw: `REINDEX`},
+		{s: `RELEASE`, tok: scanner.RELEASE, raw: `RELEASE`},
 		{s: `RENAME`, tok: scanner.RENAME, raw: `RENAME`},
 		{s: `ROLLBACK`, tok: scanner.ROLLBACK, raw: `ROLLBACK`},
+		{s: `SAVEPOINT`, tok: scanner.SAVEPOINT, raw: `SAVEPOINT`},
 		{s: `SELECT`, tok: scanner.SELECT, raw: `SELECT`},
 		{s: `SET`, tok: scanner.SET, raw: `SET`},
 		{s: `TABLE`, tok: scanner.TABLE, raw: `TABLE`},
@@ -150,6 +156,7 @@ func TestScanner_Scan(t *testing.T) {
 		{s: `UNSET`, tok: scanner.UNSET, raw: `UNSET`},
 		{s: `VALUES`, tok: scanner.VALUES, raw: `VALUES`},
 		{s: `WHERE`, tok: scanner.WHERE, raw: `WHERE`},
+		{s: `WITH`, tok: scanner.WITH, raw: `WITH`},
 		{s: `WRITE`, tok: scanner.WRITE, raw: `WRITE`},
 		{s: `seLECT`, tok: scanner.SELECT, raw: `seLECT`}, // case insensitive
 
@@ -288,3 +295,22 @@ func TestScanRegex(t *testing.T) {
 		}
 	}
 }
+
+func TestKeywords(t *testing.T) {
+	kws := scanner.Keywords()
+	if !sort.StringsAreSorted(kws) {
+		t.Fatalf("keywords are not sorted: %v", kws)
+	}
+
+	for _, kw := range kws {
+		if tok := scanner.Lookup(kw); !tok.IsKeyword() {
+			t.Errorf("%s: expected token %v to be a keyword", kw, tok)
+		}
+	}
+
+	for _, tok := range []scanner.Token{scanner.IDENT, scanner.EQ, scanner.STRING, scanner.EOF} {
+		if tok.IsKeyword() {
+			t.Errorf("expected token %v not to be a keyword", tok)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package scanner
 
 import (
+	"sort"
 	"strings"
 )
 
@@ -73,15 +74,22 @@ const (
 	keywordBeg
 	// ALL and the following are Genji SQL Keywords
 	ALTER
+	ANALYZE
 	AS
 	ASC
+	ATTACH
 	BEGIN
 	BY
+	CALL
 	CAST
 	COMMIT
+	CONFLICT
 	CREATE
 	DELETE
 	DESC
+	DESCRIBE
+	DETACH
+	DO
 	DROP
 	EXISTS
 	EXPLAIN
@@ -92,19 +100,25 @@ const (
 	INSERT
 	INTO
 	KEY
+	KILL
 	LIMIT
 	NOT
+	NOTHING
 	OFFSET
 	ON
 	ONLY
 	ORDER
+	PRAGMA
 	PRIMARY
 	READ
 	REINDEX
+	RELEASE
 	RENAME
 	ROLLBACK
+	SAVEPOINT
 	SELECT
 	SET
+	SHOW
 	TABLE
 	TO
 	TRANSACTION
@@ -113,6 +127,7 @@ const (
 	UPDATE
 	VALUES
 	WHERE
+	WITH
 	WRITE
 
 	TYPEARRAY
@@ -179,20 +194,28 @@ var tokens = [...]string{
 	DOT:         ".",
 
 	ALTER:       "ALTER",
+	ANALYZE:     "ANALYZE",
 	AS:          "AS",
 	ASC:         "ASC",
+	ATTACH:      "ATTACH",
 	BEGIN:       "BEGIN",
 	COMMIT:      "COMMIT",
+	CONFLICT:    "CONFLICT",
 	GROUP:       "GROUP",
 	BY:          "BY",
 	CREATE:      "CREATE",
+	CALL:        "CALL",
 	CAST:        "CAST",
 	DELETE:      "DELETE",
 	DESC:        "DESC",
+	DESCRIBE:    "DESCRIBE",
+	DETACH:      "DETACH",
+	DO:          "DO",
 	DROP:        "DROP",
 	EXISTS:      "EXISTS",
 	EXPLAIN:     "EXPLAIN",
 	KEY:         "KEY",
+	KILL:        "KILL",
 	FROM:        "FROM",
 	IF:          "IF",
 	INDEX:       "INDEX",
@@ -200,17 +223,22 @@ var tokens = [...]string{
 	INTO:        "INTO",
 	LIMIT:       "LIMIT",
 	NOT:         "NOT",
+	NOTHING:     "NOTHING",
 	OFFSET:      "OFFSET",
 	ON:          "ON",
 	ONLY:        "ONLY",
 	ORDER:       "ORDER",
+	PRAGMA:      "PRAGMA",
 	PRIMARY:     "PRIMARY",
 	READ:        "READ",
 	REINDEX:     "REINDEX",
+	RELEASE:     "RELEASE",
 	RENAME:      "RENAME",
 	ROLLBACK:    "ROLLBACK",
+	SAVEPOINT:   "SAVEPOINT",
 	SELECT:      "SELECT",
 	SET:         "SET",
+	SHOW:        "SHOW",
 	TABLE:       "TABLE",
 	TO:          "TO",
 	TRANSACTION: "TRANSACTION",
@@ -219,6 +247,7 @@ var tokens = [...]string{
 	UPDATE:      "UPDATE",
 	VALUES:      "VALUES",
 	WHERE:       "WHERE",
+	WITH:        "WITH",
 	WRITE:       "WRITE",
 
 	TYPEARRAY:    "ARRAY",
@@ -233,12 +262,15 @@ var tokens = [...]string{
 
 var keywords map[string]Token
 
+// reservedLiterals are operators and literals that are also reserved words.
+var reservedLiterals = []Token{AND, OR, TRUE, FALSE, NULL, IN, IS}
+
 func initKeywords() {
 	keywords = make(map[string]Token)
 	for tok := keywordBeg + 1; tok < keywordEnd; tok++ {
 		keywords[strings.ToLower(tokens[tok])] = tok
 	}
-	for _, tok := range []Token{AND, OR, TRUE, FALSE, NULL, IN, IS} {
+	for _, tok := range reservedLiterals {
 		keywords[strings.ToLower(tokens[tok])] = tok
 	}
 }
@@ -273,6 +305,37 @@ func (tok Token) Precedence() int {
 // IsOperator returns true for operator tokens.
 func (tok Token) IsOperator() bool { return tok > operatorBeg && tok < operatorEnd }
 
+// IsLiteral returns true for literal tokens, including identifiers and parameters.
+func (tok Token) IsLiteral() bool { return tok > literalBeg && tok < literalEnd }
+
+// IsKeyword returns true for reserved words, including keyword operators like AND or IN
+// and literals like TRUE or NULL.
+func (tok Token) IsKeyword() bool {
+	if tok > keywordBeg && tok < keywordEnd {
+		return true
+	}
+
+	for _, t := range reservedLiterals {
+		if tok == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Keywords returns the list of reserved words of the language, in uppercase and sorted
+// alphabetically. Reserved words must be quoted with backquotes to be used as identifiers.
+func Keywords() []string {
+	list := make([]string, 0, len(keywords))
+	for k := range keywords {
+		list = append(list, strings.ToUpper(k))
+	}
+	sort.Strings(list)
+
+	return list
+}
+
 // Tokstr returns a literal if provided, otherwise returns the token string.
 func Tokstr(tok Token, lit string) string {
 	if lit != "" {
@@ -0,0 +1,273 @@
+package genji
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/parser"
+	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// Statement is a query parsed by DB.Prepare, which can be run repeatedly
+// with different parameters without being parsed again. Its statements are only
+// planned the first time they are run, and their plans are reused afterwards,
+// as long as the catalog of the database doesn't change: once a table or an index
+// is created, altered or dropped, the query is parsed and planned again the next time it is run.
+// A Statement must not be run by multiple goroutines concurrently,
+// and the result of a run must be closed before running it again.
+type Statement struct {
+	db  *DB
+	sql string
+
+	q              query.Query
+	catalogVersion uint64
+	params         []Param
+
+	// if cacheable, the key and the tables used to cache the results of the statement.
+	cacheable bool
+	cacheKey  string
+	tables    []string
+}
+
+// Prepare parses the query and returns a statement that can be run repeatedly.
+// The rewrite functions and the statement restrictions of the database are applied once,
+// when the query is parsed. Positional and named parameters are bound every time
+// the statement is run.
+func (db *DB) Prepare(q string) (*Statement, error) {
+	s := Statement{db: db, sql: q}
+
+	err := s.parse(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// parse parses the query and records the version of the catalog it is planned for.
+func (s *Statement) parse(ctx context.Context) error {
+	// read the version first, the query is parsed again if the catalog changes in between
+	version := s.db.DB.CatalogVersion()
+
+	pq, err := s.db.ParseQuery(ctx, s.sql)
+	if err != nil {
+		return err
+	}
+
+	params, err := s.inferParams(pq)
+	if err != nil {
+		return err
+	}
+
+	s.q = pq
+	s.catalogVersion = version
+	s.params = params
+	s.cacheKey, s.tables, s.cacheable = statementCacheKey(pq)
+	return nil
+}
+
+// A Param describes a parameter of a prepared statement.
+type Param struct {
+	// Name of a named parameter, empty for positional parameters.
+	Name string
+	// Position of a positional parameter, starting at 1, 0 for named parameters.
+	Position int
+	// Type of the values expected by the parameter, inferred from the
+	// field constraints of the tables used by the statement, or 0 if it is unknown.
+	Type document.ValueType
+}
+
+// String returns the parameter as written in the query, e.g. $a, or its position, e.g. ?1.
+func (p Param) String() string {
+	if p.Name != "" {
+		return "$" + p.Name
+	}
+
+	return fmt.Sprintf("?%d", p.Position)
+}
+
+// Params returns the parameters of the statement, positional parameters ordered by position,
+// then named parameters ordered by name. The parameters of the statements of
+// procedures created by the statement are not reported.
+func (s *Statement) Params() []Param {
+	return s.params
+}
+
+// inferParams returns the parameters of the query and the types they expect.
+func (s *Statement) inferParams(pq query.Query) ([]Param, error) {
+	types := query.NewParamTypes()
+	parser.Inspect(pq, func(n interface{}) bool {
+		switch t := n.(type) {
+		case query.CreateProcedureStmt, query.CreateEventStmt:
+			// their parameters are bound when they are called
+			return false
+		case expr.NamedParam:
+			types.Add(t, 0)
+		case expr.PositionalParam:
+			types.Add(t, 0)
+		}
+
+		return true
+	})
+
+	tx := s.db.DB.GetAttachedTx()
+	if tx == nil {
+		var err error
+		tx, err = s.db.DB.Begin(false)
+		if err != nil {
+			return nil, err
+		}
+		defer tx.Rollback()
+	}
+
+	for _, stmt := range pq.Statements {
+		if pt, ok := stmt.(query.ParamTyper); ok {
+			err := pt.InferParamTypes(tx, types)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var params []Param
+	for _, p := range types.Params() {
+		param := Param{Type: types.Type(p)}
+		switch t := p.(type) {
+		case expr.NamedParam:
+			param.Name = string(t)
+		case expr.PositionalParam:
+			param.Position = int(t)
+		}
+		params = append(params, param)
+	}
+
+	return params, nil
+}
+
+// checkParams returns an error if a parameter of the statement is missing from params,
+// if params contains more positional parameters than the statement,
+// or if the value of a parameter cannot be converted to the type it expects.
+func (s *Statement) checkParams(params []expr.Param) error {
+	var positional, unnamed int
+	for _, p := range params {
+		if p.Name == "" {
+			unnamed++
+		}
+	}
+
+	for _, p := range s.params {
+		var arg expr.Param
+		var found bool
+		if p.Name != "" {
+			for _, a := range params {
+				if a.Name == p.Name {
+					arg, found = a, true
+					break
+				}
+			}
+		} else {
+			positional++
+			if p.Position <= len(params) {
+				arg, found = params[p.Position-1], true
+			}
+		}
+
+		if !found {
+			return database.NewError(database.CodeUndefinedParameter, fmt.Sprintf("param %s not found", p))
+		}
+
+		if p.Type == 0 {
+			continue
+		}
+
+		v, err := document.NewValue(arg.Value)
+		if err != nil {
+			return database.NewError(database.CodeDatatypeMismatch, fmt.Sprintf("param %s: %v", p, err))
+		}
+
+		if v.Type == document.NullValue {
+			continue
+		}
+
+		if _, err := v.CastAs(p.Type); err != nil {
+			return database.NewError(database.CodeDatatypeMismatch, fmt.Sprintf("param %s must be of type %s, got %s", p, p.Type, v.Type))
+		}
+	}
+
+	if unnamed > positional {
+		return database.NewError(database.CodeUndefinedParameter, fmt.Sprintf("statement has %d positional params, got %d", positional, unnamed))
+	}
+
+	return nil
+}
+
+// String returns the query of the statement.
+func (s *Statement) String() string {
+	return s.sql
+}
+
+// Query runs the statement with the given parameters and returns the result.
+// The returned result must always be closed after usage.
+func (s *Statement) Query(ctx context.Context, args ...interface{}) (*query.Result, error) {
+	if s.db.DB.CatalogVersion() != s.catalogVersion {
+		err := s.parse(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	params := argsToParams(args)
+	err := s.checkParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	// see DB.Query
+	if s.cacheable && s.db.cache != nil && s.db.DB.GetAttachedTx() == nil {
+		if key, ok := paramsCacheKey(s.cacheKey, params); ok {
+			return s.db.cache.query(ctx, s.db.DB, key, s.tables, s.q, params)
+		}
+	}
+
+	return s.q.Run(ctx, s.db.DB, params)
+}
+
+// QueryDocument runs the statement and returns the first document.
+// If the statement returns no document, QueryDocument returns database.ErrDocumentNotFound.
+func (s *Statement) QueryDocument(ctx context.Context, args ...interface{}) (document.Document, error) {
+	res, err := s.Query(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	r, err := res.First()
+	if err != nil {
+		return nil, err
+	}
+
+	if r == nil {
+		return nil, database.ErrDocumentNotFound
+	}
+
+	var fb document.FieldBuffer
+	err = fb.ScanDocument(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fb, nil
+}
+
+// Exec runs the statement without returning the result.
+func (s *Statement) Exec(ctx context.Context, args ...interface{}) error {
+	res, err := s.Query(ctx, args...)
+	if err != nil {
+		return err
+	}
+
+	return res.Close()
+}
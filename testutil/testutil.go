@@ -0,0 +1,220 @@
+// Package testutil provides helpers for writing tests of applications using Genji,
+// like running queries against a throwaway database and comparing their results
+// against golden files.
+//
+// Golden files contain the documents returned by a query as a JSON array, one document per line.
+// Unlike document.MarshalJSON, values keep their type: doubles always have a decimal point or an exponent,
+// blobs are written as {"$blob": "<base64>"} and non-finite doubles as {"$double": "NaN"},
+// so that a query returning 1.0 instead of 1, or a blob instead of a text, fails the comparison.
+// Run the tests with the -update-golden flag to write the golden files instead of comparing them.
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"flag"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/stretchr/testify/require"
+)
+
+// UpdateGolden makes RequireGolden write the golden files instead of comparing them.
+// It is set by the -update-golden flag.
+var UpdateGolden = flag.Bool("update-golden", false, "write the golden files compared by testutil.RequireGolden")
+
+// NewDB returns an in-memory database, which is closed at the end of the test.
+// If opts is nil, default options are used.
+func NewDB(t testing.TB, opts *genji.Options) *genji.DB {
+	t.Helper()
+
+	db, err := genji.NewWithOptions(memoryengine.NewEngine(), opts)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	return db
+}
+
+// Exec runs the given query, which can contain multiple statements, and fails the test on error.
+func Exec(t testing.TB, db *genji.DB, q string, args ...interface{}) {
+	t.Helper()
+
+	err := db.Exec(context.Background(), q, args...)
+	require.NoError(t, err, "query: %s", q)
+}
+
+// Query runs the given query and returns its documents in the format of the golden files.
+func Query(t testing.TB, db *genji.DB, q string, args ...interface{}) string {
+	t.Helper()
+
+	res, err := db.Query(context.Background(), q, args...)
+	require.NoError(t, err, "query: %s", q)
+	defer res.Close()
+
+	var buf bytes.Buffer
+	err = MarshalDocuments(&buf, res)
+	require.NoError(t, err, "query: %s", q)
+
+	return buf.String()
+}
+
+// RequireQuery runs the given query and compares its documents with expected,
+// written in the format of the golden files. Whitespace outside of strings is ignored.
+func RequireQuery(t testing.TB, db *genji.DB, expected string, q string, args ...interface{}) {
+	t.Helper()
+
+	require.Equal(t, compact(expected), compact(Query(t, db, q, args...)), "query: %s", q)
+}
+
+// RequireGolden runs the given query and compares its documents with the content
+// of the golden file at path, relative to the package directory, usually under testdata.
+// If UpdateGolden is true, the file is written instead.
+func RequireGolden(t testing.TB, db *genji.DB, path string, q string, args ...interface{}) {
+	t.Helper()
+
+	actual := Query(t, db, q, args...)
+
+	if *UpdateGolden {
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		require.NoError(t, err)
+		err = ioutil.WriteFile(path, []byte(actual), 0644)
+		require.NoError(t, err)
+		return
+	}
+
+	expected, err := ioutil.ReadFile(path)
+	require.NoError(t, err, "golden file missing, run the tests with -update-golden to create it")
+	require.Equal(t, string(expected), actual, "query: %s\ngolden file: %s", q, path)
+}
+
+// RequirePlan runs EXPLAIN on the given query and compares the fingerprint of its plan with expected,
+// to make sure the query keeps being executed the same way after upgrades or schema changes,
+// for example using an index. The plan is displayed if they differ.
+func RequirePlan(t testing.TB, db *genji.DB, expected string, q string, args ...interface{}) {
+	t.Helper()
+
+	d, err := db.QueryDocument(context.Background(), "EXPLAIN "+q, args...)
+	require.NoError(t, err, "query: %s", q)
+
+	plan, err := d.GetByField("plan")
+	require.NoError(t, err)
+	fingerprint, err := d.GetByField("fingerprint")
+	require.NoError(t, err)
+	require.Equal(t, expected, fingerprint.V, "query: %s\nplan:\n%s", q, plan.V)
+}
+
+// MarshalDocuments writes the documents of it in the format of the golden files.
+func MarshalDocuments(buf *bytes.Buffer, it document.Iterator) error {
+	buf.WriteString("[")
+	first := true
+	err := it.Iterate(func(d document.Document) error {
+		if !first {
+			buf.WriteString(",")
+		}
+		first = false
+
+		buf.WriteString("\n  ")
+		return marshalValue(buf, document.NewDocumentValue(d))
+	})
+	if err != nil {
+		return err
+	}
+
+	if !first {
+		buf.WriteString("\n")
+	}
+	buf.WriteString("]\n")
+	return nil
+}
+
+func marshalValue(buf *bytes.Buffer, v document.Value) error {
+	switch v.Type {
+	case document.DoubleValue:
+		f := v.V.(float64)
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			buf.WriteString(`{"$double": ` + strconv.Quote(strconv.FormatFloat(f, 'g', -1, 64)) + `}`)
+			return nil
+		}
+
+		data, err := v.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		if !bytes.ContainsAny(data, ".e") {
+			buf.WriteString(".0")
+		}
+	case document.BlobValue:
+		buf.WriteString(`{"$blob": "` + base64.StdEncoding.EncodeToString(v.V.([]byte)) + `"}`)
+	case document.ArrayValue:
+		buf.WriteString("[")
+		err := v.V.(document.Array).Iterate(func(i int, v document.Value) error {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			return marshalValue(buf, v)
+		})
+		if err != nil {
+			return err
+		}
+		buf.WriteString("]")
+	case document.DocumentValue:
+		buf.WriteString("{")
+		first := true
+		err := v.V.(document.Document).Iterate(func(field string, v document.Value) error {
+			if !first {
+				buf.WriteString(", ")
+			}
+			first = false
+
+			buf.WriteString(strconv.Quote(field) + ": ")
+			return marshalValue(buf, v)
+		})
+		if err != nil {
+			return err
+		}
+		buf.WriteString("}")
+	default:
+		data, err := v.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+	}
+
+	return nil
+}
+
+// compact removes the whitespace outside of strings.
+func compact(s string) string {
+	var sb strings.Builder
+	var inString, escaped bool
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case inString && r == '\\':
+			escaped = true
+		case r == '"':
+			inString = !inString
+		case !inString && (r == ' ' || r == '\n' || r == '\t' || r == '\r'):
+			continue
+		}
+		sb.WriteRune(r)
+	}
+
+	return sb.String()
+}
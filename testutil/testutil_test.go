@@ -0,0 +1,76 @@
+package testutil_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/genjidb/genji/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireGolden(t *testing.T) {
+	db := testutil.NewDB(t, nil)
+	testutil.Exec(t, db, `
+		CREATE TABLE test;
+		INSERT INTO test (a, b) VALUES (1, 1.0), (2, 2.5);
+	`)
+	testutil.Exec(t, db, `INSERT INTO test (a, b) VALUES (3, {c: [true, null, "foo"], d: ?})`, []byte{0xff, 0})
+
+	testutil.RequireGolden(t, db, "testdata/types.golden", "SELECT a, b FROM test")
+}
+
+func TestRequireQuery(t *testing.T) {
+	db := testutil.NewDB(t, nil)
+	testutil.Exec(t, db, "CREATE TABLE test; INSERT INTO test (a, b) VALUES (1, 1.0)")
+
+	testutil.RequireQuery(t, db, `[{"a": 1, "b": 1.0}]`, "SELECT a, b FROM test")
+	testutil.RequireQuery(t, db, `[]`, "SELECT * FROM test WHERE a > 1")
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"Double", `[{"a": 1, "b": 1}]`},
+		{"Integer", `[{"a": 1.0, "b": 1.0}]`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var ft fakeT
+			testutil.RequireQuery(&ft, db, test.expected, "SELECT a, b FROM test")
+			require.True(t, ft.failed)
+		})
+	}
+}
+
+// fakeT records failures instead of failing the test.
+type fakeT struct {
+	testing.T
+	failed bool
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.failed = true
+}
+
+func (t *fakeT) FailNow() {
+	t.failed = true
+}
+
+func TestRequirePlan(t *testing.T) {
+	db := testutil.NewDB(t, nil)
+	testutil.Exec(t, db, "CREATE TABLE test; CREATE INDEX idx_a ON test (a)")
+
+	d, err := db.QueryDocument(context.Background(), "EXPLAIN SELECT * FROM test WHERE a = 1")
+	require.NoError(t, err)
+	v, err := d.GetByField("fingerprint")
+	require.NoError(t, err)
+
+	testutil.RequirePlan(t, db, v.V.(string), "SELECT * FROM test WHERE a = ?", 10)
+
+	var ft fakeT
+	testutil.RequirePlan(&ft, db, v.V.(string), "SELECT * FROM test WHERE b = 1")
+	require.True(t, ft.failed)
+}